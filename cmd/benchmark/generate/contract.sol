@@ -573,4 +573,174 @@ contract Verifier {
             revert ProofInvalid();
         }
     }
+
+    /// Multiply a G1 point by a scalar.
+    /// @notice Reverts with ProofInvalid if the point is not on the curve
+    /// or its coordinates are not reduced.
+    /// @param x The X coordinate in Fp.
+    /// @param y The Y coordinate in Fp.
+    /// @param s The scalar.
+    /// @return rx The X coordinate of the product.
+    /// @return ry The Y coordinate of the product.
+    function g1Mul(uint256 x, uint256 y, uint256 s) internal view returns (uint256 rx, uint256 ry) {
+        bool success;
+        assembly ("memory-safe") {
+            let f := mload(0x40)
+            mstore(f, x)
+            mstore(add(f, 0x20), y)
+            mstore(add(f, 0x40), s)
+            success := staticcall(gas(), PRECOMPILE_MUL, f, 0x60, f, 0x40)
+            rx := mload(f)
+            ry := mload(add(f, 0x20))
+        }
+        if (!success) {
+            revert ProofInvalid();
+        }
+    }
+
+    /// Add two G1 points.
+    /// @notice Reverts with ProofInvalid if a point is not on the curve.
+    /// @return rx The X coordinate of the sum.
+    /// @return ry The Y coordinate of the sum.
+    function g1Add(uint256 x1, uint256 y1, uint256 x2, uint256 y2) internal view returns (uint256 rx, uint256 ry) {
+        bool success;
+        assembly ("memory-safe") {
+            let f := mload(0x40)
+            mstore(f, x1)
+            mstore(add(f, 0x20), y1)
+            mstore(add(f, 0x40), x2)
+            mstore(add(f, 0x60), y2)
+            success := staticcall(gas(), PRECOMPILE_ADD, f, 0x80, f, 0x40)
+            rx := mload(f)
+            ry := mload(add(f, 0x20))
+        }
+        if (!success) {
+            revert ProofInvalid();
+        }
+    }
+
+    /// Compute the weighted sum of the public input linear combinations of a batch.
+    /// @notice The constant term is weighted by the sum of the proof weights, the
+    /// public input points by the weighted sums of their inputs.
+    /// @param weightSum The sum of the proof weights in Fr.
+    /// @param scalars The weighted sums of each public input in Fr.
+    /// @return x The X coordinate of the resulting G1 point.
+    /// @return y The Y coordinate of the resulting G1 point.
+    function batchInputMSM(uint256 weightSum, uint256[6] memory scalars)
+    internal view returns (uint256 x, uint256 y) {
+        uint256 px;
+        uint256 py;
+        (x, y) = g1Mul(CONSTANT_X, CONSTANT_Y, weightSum);
+        (px, py) = g1Mul(PUB_0_X, PUB_0_Y, scalars[0]);
+        (x, y) = g1Add(x, y, px, py);
+        (px, py) = g1Mul(PUB_1_X, PUB_1_Y, scalars[1]);
+        (x, y) = g1Add(x, y, px, py);
+        (px, py) = g1Mul(PUB_2_X, PUB_2_Y, scalars[2]);
+        (x, y) = g1Add(x, y, px, py);
+        (px, py) = g1Mul(PUB_3_X, PUB_3_Y, scalars[3]);
+        (x, y) = g1Add(x, y, px, py);
+        (px, py) = g1Mul(PUB_4_X, PUB_4_Y, scalars[4]);
+        (x, y) = g1Add(x, y, px, py);
+        (px, py) = g1Mul(PUB_5_X, PUB_5_Y, scalars[5]);
+        (x, y) = g1Add(x, y, px, py);
+    }
+
+    /// Add the weighted public inputs of a proof to the batch sums.
+    /// @notice Reverts with PublicInputNotInField if an input is not reduced.
+    /// @param input The public inputs of the proof.
+    /// @param r The weight of the proof.
+    /// @param scalars The weighted sums of each public input.
+    function batchInputs(uint256[6] calldata input, uint256 r, uint256[6] memory scalars) internal pure {
+        for (uint256 j = 0; j < 6; j++) {
+            uint256 s = input[j];
+            if (s >= R) {
+                revert PublicInputNotInField();
+            }
+            scalars[j] = addmod(scalars[j], mulmod(r, s, R), R);
+        }
+    }
+
+    /// Write the pairing e(r⋅A, B) of a proof and add r⋅C to the batch sum.
+    /// @param proof The points (A, B, C) in EIP-197 format.
+    /// @param r The weight of the proof.
+    /// @param pairings The pairing input of the batch.
+    /// @param o The offset of the proof's pairing in pairings.
+    /// @param c The weighted sum of the C points.
+    function batchProof(uint256[8] calldata proof, uint256 r, uint256[] memory pairings, uint256 o, uint256[2] memory c)
+    internal view {
+        (pairings[o], pairings[o + 1]) = g1Mul(proof[0], proof[1], r);
+        pairings[o + 2] = proof[2];
+        pairings[o + 3] = proof[3];
+        pairings[o + 4] = proof[4];
+        pairings[o + 5] = proof[5];
+        (uint256 x, uint256 y) = g1Mul(proof[6], proof[7], r);
+        (c[0], c[1]) = g1Add(c[0], c[1], x, y);
+    }
+
+    /// Verify several uncompressed Groth16 proofs in one call.
+    /// @notice Reverts with InvalidProof if any proof is invalid or
+    /// with PublicInputNotInField if a public input is not reduced.
+    /// @notice There is no return value. If the function does not revert, every
+    /// proof was successfully verified.
+    /// @notice Each proof is weighted by a scalar derived from the hash of all
+    /// proofs and inputs, the first by one, and the weighted proofs are checked
+    /// with a single pairing product: n proofs take n + 3 pairings instead of 4n.
+    /// @param proofs the points (A, B, C) of each proof in EIP-197 format.
+    /// @param input the public inputs of each proof, in the order of proofs.
+    /// Elements must be reduced.
+    function verifyProofs(
+        uint256[8][] calldata proofs,
+        uint256[6][] calldata input
+    ) public view {
+        uint256 n = proofs.length;
+        if (n == 0 || input.length != n) {
+            revert ProofInvalid();
+        }
+
+        uint256 seed = uint256(keccak256(abi.encode(proofs, input)));
+        uint256[] memory pairings = new uint256[](6 * (n + 3));
+        uint256[6] memory scalars;
+        uint256[2] memory c;
+        uint256 weightSum;
+        for (uint256 i = 0; i < n; i++) {
+            uint256 r = i == 0 ? 1 : uint256(keccak256(abi.encode(seed, i))) % R;
+            weightSum = addmod(weightSum, r, R);
+            batchInputs(input[i], r, scalars);
+            batchProof(proofs[i], r, pairings, 6 * i, c);
+        }
+
+        // Note: The precompile expects the F2 coefficients in big-endian order.
+        uint256 o = 6 * n;
+        // e(Σ rᵢ⋅Cᵢ, -δ)
+        pairings[o] = c[0];
+        pairings[o + 1] = c[1];
+        pairings[o + 2] = DELTA_NEG_X_1;
+        pairings[o + 3] = DELTA_NEG_X_0;
+        pairings[o + 4] = DELTA_NEG_Y_1;
+        pairings[o + 5] = DELTA_NEG_Y_0;
+        // e((Σ rᵢ)⋅α, -β)
+        (pairings[o + 6], pairings[o + 7]) = g1Mul(ALPHA_X, ALPHA_Y, weightSum);
+        pairings[o + 8] = BETA_NEG_X_1;
+        pairings[o + 9] = BETA_NEG_X_0;
+        pairings[o + 10] = BETA_NEG_Y_1;
+        pairings[o + 11] = BETA_NEG_Y_0;
+        // e(Σ rᵢ⋅L_pubᵢ, -γ)
+        (pairings[o + 12], pairings[o + 13]) = batchInputMSM(weightSum, scalars);
+        pairings[o + 14] = GAMMA_NEG_X_1;
+        pairings[o + 15] = GAMMA_NEG_X_0;
+        pairings[o + 16] = GAMMA_NEG_Y_1;
+        pairings[o + 17] = GAMMA_NEG_Y_0;
+
+        // Check pairing equation.
+        bool success;
+        assembly ("memory-safe") {
+            success := staticcall(gas(), PRECOMPILE_VERIFY, add(pairings, 0x20), mul(mload(pairings), 0x20), 0x00, 0x20)
+            success := and(success, mload(0x00))
+        }
+        if (!success) {
+            // Either a proof or the verification key is invalid.
+            // We assume the contract is correctly generated, so the verification key is valid.
+            revert ProofInvalid();
+        }
+    }
 }
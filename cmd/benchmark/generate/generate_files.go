@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	zkCrypto "zkrollup/pkg/crypto"
+	"zkrollup/pkg/verifier"
 )
 
 func main() {
@@ -39,7 +40,8 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to create contract file: %v", err)
 		}
-		err = prover.VerifyingKey.ExportSolidity(f)
+		// The exported verifier also checks several proofs at once with verifyProofs
+		err = verifier.ExportSolidity(prover.VerifyingKey, f)
 		if err != nil {
 			log.Fatalf("Failed to export verifying key: %v", err)
 		}
@@ -3,11 +3,13 @@ package main
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 	"unsafe"
@@ -209,6 +211,36 @@ func (t *ExportSolidityTestSuite) TestVerifyProof() {
 }
 
 func main() {
+	throughput := flag.Bool("throughput", false, "Report batch proving throughput at each parallelism level instead of verifying a proof on-chain")
+	capacity := flag.Int("capacity", 10, "Transactions per batch circuit in throughput mode")
+	proofs := flag.Int("proofs", 8, "Proofs made at each parallelism level")
+	threads := flag.String("threads", strconv.Itoa(runtime.NumCPU()), "Comma-separated numbers of cores to prove on")
+	workers := flag.String("workers", "1", "Comma-separated numbers of batches proven in parallel")
+	gpu := flag.Bool("gpu", false, "Prove on the GPU, requires building with -tags icicle")
+	flag.Parse()
+
+	if *throughput {
+		threadLevels, err := parseLevels(*threads)
+		if err != nil {
+			log.Fatalf("invalid -threads: %v", err)
+		}
+		workerLevels, err := parseLevels(*workers)
+		if err != nil {
+			log.Fatalf("invalid -workers: %v", err)
+		}
+		err = RunThroughput(ThroughputConfig{
+			Capacity: *capacity,
+			Proofs:   *proofs,
+			Threads:  threadLevels,
+			Workers:  workerLevels,
+			GPU:      *gpu,
+		})
+		if err != nil {
+			log.Fatalf("throughput benchmark failed: %v", err)
+		}
+		return
+	}
+
 	suite := new(ExportSolidityTestSuite)
 	suite.SetupTest()
 	suite.TestVerifyProof()
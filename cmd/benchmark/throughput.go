@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/logger"
+
+	localCrypto "zkrollup/pkg/crypto"
+)
+
+// ThroughputConfig selects the circuit and parallelism levels of a throughput run
+type ThroughputConfig struct {
+	Capacity int   // Transactions per batch circuit
+	Proofs   int   // Proofs made at each level
+	Threads  []int // Cores proving at each level
+	Workers  []int // Batches proven in parallel at each level
+	GPU      bool
+}
+
+// RunThroughput proves batch commitment proofs through the proving service at every
+// combination of threads and workers and prints the proofs per second of each
+func RunThroughput(cfg ThroughputConfig) error {
+	if cfg.Capacity <= 0 || cfg.Proofs <= 0 {
+		return fmt.Errorf("capacity and proofs must be positive")
+	}
+
+	// Only the report is printed, not gnark's progress of every proof
+	logger.Disable()
+
+	fmt.Printf("Setting up the batch circuit of capacity %d\n", cfg.Capacity)
+	prover, err := localCrypto.NewBatchProver(cfg.Capacity)
+	if err != nil {
+		return fmt.Errorf("failed to create batch prover: %v", err)
+	}
+	fmt.Printf("Circuit has %d constraints, gpu=%v\n\n", prover.R1cs.GetNbConstraints(), cfg.GPU)
+
+	// GOMAXPROCS bounds the prover's MSMs, which split their work by core
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	fmt.Printf("%-8s %-8s %-8s %-12s %-12s %-12s\n", "threads", "workers", "proofs", "elapsed", "proofs/s", "avg proving")
+	for _, threads := range cfg.Threads {
+		runtime.GOMAXPROCS(threads)
+		levelProver := *prover
+		levelProver.Options = localCrypto.ProvingOptions{Threads: threads, GPU: cfg.GPU}
+		if err := levelProver.Options.Validate(); err != nil {
+			return err
+		}
+
+		for _, workers := range cfg.Workers {
+			elapsed, proving, err := proveLevel(&levelProver, cfg.Capacity, cfg.Proofs, workers)
+			if err != nil {
+				return fmt.Errorf("threads=%d workers=%d: %v", threads, workers, err)
+			}
+			fmt.Printf("%-8d %-8d %-8d %-12s %-12.3f %-12s\n", threads, workers, cfg.Proofs,
+				elapsed.Round(time.Millisecond), float64(cfg.Proofs)/elapsed.Seconds(),
+				(proving / time.Duration(cfg.Proofs)).Round(time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// proveLevel makes count proofs on workers parallel workers, after one untimed proof that
+// warms up the prover. It returns the wall time and the total time spent proving.
+func proveLevel(prover *localCrypto.Prover, capacity, count, workers int) (time.Duration, time.Duration, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := localCrypto.NewProvingService(ctx, workers, workers)
+
+	results := make(chan localCrypto.ProofResult, count+1)
+	submit := func(batchNumber uint64) error {
+		return service.Submit(ctx, localCrypto.ProofRequest{
+			BatchNumber: batchNumber,
+			Prover:      prover,
+			Witness:     func() (frontend.Circuit, error) { return randomBatch(capacity) },
+			Done:        func(result localCrypto.ProofResult) { results <- result },
+		})
+	}
+
+	if err := submit(0); err != nil {
+		return 0, 0, err
+	}
+	if result := <-results; result.Err != nil {
+		return 0, 0, result.Err
+	}
+
+	start := time.Now()
+	go func() {
+		for i := 1; i <= count; i++ {
+			if submit(uint64(i)) != nil {
+				return
+			}
+		}
+	}()
+
+	var proving time.Duration
+	for i := 0; i < count; i++ {
+		result := <-results
+		if result.Err != nil {
+			return 0, 0, result.Err
+		}
+		proving += result.ProvingTime
+	}
+	return time.Since(start), proving, nil
+}
+
+// randomBatch returns the assignment of a full batch of random transaction hashes
+func randomBatch(capacity int) (*localCrypto.BatchCommitmentCircuit, error) {
+	var oldRoot, newRoot [32]byte
+	rand.Read(oldRoot[:])
+	rand.Read(newRoot[:])
+	txHashes := make([][32]byte, capacity)
+	for i := range txHashes {
+		rand.Read(txHashes[i][:])
+	}
+	return localCrypto.BatchCommitmentAssignment(capacity, oldRoot, newRoot, txHashes)
+}
+
+// parseLevels parses a comma-separated list of positive counts
+func parseLevels(list string) ([]int, error) {
+	var levels []int
+	for _, field := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid parallelism level %q", field)
+		}
+		levels = append(levels, n)
+	}
+	return levels, nil
+}
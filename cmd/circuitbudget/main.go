@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/consensys/gnark/logger"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/crypto"
+)
+
+func main() {
+	// Parse command line flags, a config file sets the defaults of the others
+	configFile := flag.String("config", "", "Node config file to read the batch size and ceremony size from")
+	batchSize := flag.Int("batch-size", 0, "Transactions per batch circuit (overrides the config)")
+	fallback := flag.Int("fallback", -1, "Transactions per fallback circuit, 0 for none (overrides the config)")
+	power := flag.Int("power", 0, "Ceremony size as a power of 2 (overrides the config)")
+	flag.Parse()
+
+	config := core.DefaultConfig()
+	if *configFile != "" {
+		if err := config.LoadFile(*configFile); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+	if *batchSize > 0 {
+		config.BatchSize = uint64(*batchSize)
+	}
+	if *power > 0 {
+		config.CRSPowerSize = *power
+	}
+	fallbackCapacity := 0
+	if config.ProofDeadlinePolicy == "smaller-circuit" {
+		fallbackCapacity = config.ProofFallbackCapacity
+	}
+	if *fallback >= 0 {
+		fallbackCapacity = *fallback
+	}
+
+	// Only the report is printed, not gnark's compilation progress
+	logger.Disable()
+
+	circuits := crypto.RollupCircuits(int(config.BatchSize), fallbackCapacity)
+	budgets, err := crypto.CheckConstraintBudgets(circuits, config.CRSPowerSize)
+	for _, b := range budgets {
+		status := "ok"
+		if !b.Fits() {
+			status = "OVER BUDGET"
+		}
+		fmt.Printf("%-12s constraints=%-8d capacity=%-8d headroom=%-8d %s\n",
+			b.Circuit, b.Constraints, b.Capacity, b.Headroom(), status)
+	}
+
+	if err != nil {
+		if errors.Is(err, crypto.ErrConstraintBudgetExceeded) {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		log.Fatalf("Failed to check constraint budget: %v", err)
+	}
+}
@@ -2,27 +2,42 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"flag"
 	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/rs/zerolog/log"
 
+	"zkrollup/pkg/core"
 	"zkrollup/pkg/p2p"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
 )
 
 func main() {
 	// Parse command line flags
 	port := flag.Int("port", 9100, "Port to run the client on")
 	peerAddr := flag.String("peer", "", "Address of a sequencer node to connect to")
+	chainID := flag.Uint64("chain-id", core.DevChainID, "Chain ID of the rollup the transactions are signed for")
+	rollupContract := flag.String("rollup-contract", "", "Rollup contract the transactions are signed for, see rollup_chainId; dev chains have none")
 	flag.Parse()
 
 	if *peerAddr == "" {
 		log.Fatal().Msg("Please provide a peer address using the -peer flag")
 	}
+	domain := state.SigningDomain{ChainID: *chainID}
+	if *rollupContract != "" {
+		contract, err := types.ParseAddress(*rollupContract)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid -rollup-contract address")
+		}
+		domain.VerifyingContract = contract
+	}
 
 	// Create a P2P node for the client
 	ctx, cancel := context.WithCancel(context.Background())
@@ -44,8 +59,8 @@ func main() {
 			log.Info().Msg("Received batch update")
 			return nil
 		},
-		OnConsensus: func(msg []byte) error {
-			log.Info().Msg("Received consensus message")
+		OnConsensus: func(from peer.ID, msg []byte) error {
+			log.Info().Str("peer", from.String()).Msg("Received consensus message")
 			return nil
 		},
 	})
@@ -77,13 +92,13 @@ func main() {
 
 	// Display account information
 	for i, acc := range accounts {
-		fmt.Printf("Account %d: Address: %x, Balance: %s\n", i, acc.Address, acc.Balance.String())
+		fmt.Printf("Account %d: Address: %s, Balance: %s\n", i, acc.Address, acc.Balance.String())
 	}
 
 	// Send test transactions
 	for i := 0; i < 10; i++ {
 		// Create a random transaction
-		tx := createRandomTransaction(accounts)
+		tx := createRandomTransaction(accounts, domain)
 
 		// Ensure proper handling of zero values for consistent message hash computation
 		if tx.Amount.Sign() == 0 {
@@ -96,8 +111,8 @@ func main() {
 
 		// Broadcast the transaction
 		log.Info().
-			Str("from", fmt.Sprintf("%x", tx.From)).
-			Str("to", fmt.Sprintf("%x", tx.To)).
+			Str("from", tx.From.Hex()).
+			Str("to", tx.To.Hex()).
 			Str("amount", tx.Amount.String()).
 			Str("nonce_str", nonceStr). // Log nonce as string for consistency with circuit
 			Msg("Sending transaction")
@@ -118,13 +133,21 @@ func main() {
 	select {}
 }
 
-// generateTestAccounts creates test accounts with random addresses and balances
-func generateTestAccounts(count int) []*state.Account {
-	accounts := make([]*state.Account, count)
+// testAccount is a local account with the key signing its transactions
+type testAccount struct {
+	*state.Account
+	key *ecdsa.PrivateKey
+}
+
+// generateTestAccounts creates test accounts with random keys and balances
+func generateTestAccounts(count int) []*testAccount {
+	accounts := make([]*testAccount, count)
 
 	for i := 0; i < count; i++ {
-		var addr [20]byte
-		rand.Read(addr[:])
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to generate account key")
+		}
 
 		// Generate a random balance between 100 and 1000
 		max := big.NewInt(1000)
@@ -133,18 +156,21 @@ func generateTestAccounts(count int) []*state.Account {
 		randInt, _ := rand.Int(rand.Reader, diff)
 		balance := big.NewInt(0).Add(min, randInt)
 
-		accounts[i] = &state.Account{
-			Address: addr,
-			Balance: balance,
-			Nonce:   0,
+		accounts[i] = &testAccount{
+			Account: &state.Account{
+				Address: types.FromCommon(crypto.PubkeyToAddress(key.PublicKey)),
+				Balance: balance,
+				Nonce:   0,
+			},
+			key: key,
 		}
 	}
 
 	return accounts
 }
 
-// createRandomTransaction creates a random transaction between two accounts
-func createRandomTransaction(accounts []*state.Account) state.Transaction {
+// createRandomTransaction creates a random transaction between two accounts, signed in domain
+func createRandomTransaction(accounts []*testAccount, domain state.SigningDomain) state.Transaction {
 	// Select random from and to accounts
 	fromIdx, _ := rand.Int(rand.Reader, big.NewInt(int64(len(accounts))))
 	toIdx := fromIdx
@@ -181,11 +207,19 @@ func createRandomTransaction(accounts []*state.Account) state.Transaction {
 
 	// Create the transaction
 	tx := state.Transaction{
-		From:   from.Address,
-		To:     to.Address,
-		Amount: amount,
-		Nonce:  from.Nonce,
+		From:    from.Address,
+		To:      to.Address,
+		Amount:  amount,
+		Nonce:   from.Nonce,
+		ChainID: domain.ChainID,
+	}
+
+	hash := tx.SigningHash(domain.VerifyingContract)
+	signature, err := crypto.Sign(hash[:], from.key)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to sign transaction")
 	}
+	tx.Signature = signature
 
 	// Update the account nonce
 	from.Nonce++
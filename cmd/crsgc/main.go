@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"zkrollup/pkg/consensus"
+)
+
+func main() {
+	// Parse command line flags
+	dir := flag.String("dir", consensus.DefaultCRSCeremonyDir(), "CRS ceremony directory")
+	keep := flag.Int("keep", 0, "Number of final ptau files to keep (0 keeps all)")
+	intermediates := flag.Bool("intermediates", true, "Delete intermediate ptau files of finalized epochs")
+	verify := flag.Bool("verify", true, "Verify the final transcript before deleting intermediates")
+	dryRun := flag.Bool("dry-run", false, "Only list the files that would be removed")
+	list := flag.Bool("list", false, "List ceremony artifacts and exit")
+	flag.Parse()
+
+	if *list {
+		artifacts, err := consensus.ListCRSArtifacts(*dir)
+		if err != nil {
+			log.Fatalf("Failed to list CRS artifacts: %v", err)
+		}
+		for _, a := range artifacts {
+			step := fmt.Sprintf("%d", a.Step)
+			if a.Final {
+				step = "final"
+			}
+			fmt.Printf("epoch=%d step=%s size=%d %s\n", a.Epoch, step, a.Size, a.Path)
+		}
+		return
+	}
+
+	policy := consensus.CRSRetentionPolicy{
+		KeepFinalEpochs:     *keep,
+		DeleteIntermediates: *intermediates,
+		VerifyBeforeDelete:  *verify,
+	}
+
+	removed, err := consensus.PurgeCRSArtifacts(*dir, policy, *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to purge CRS artifacts: %v", err)
+	}
+
+	var freed int64
+	for _, a := range removed {
+		freed += a.Size
+		if *dryRun {
+			fmt.Printf("would remove %s\n", a.Path)
+		} else {
+			fmt.Printf("removed %s\n", a.Path)
+		}
+	}
+
+	fmt.Printf("%d artifacts, %d bytes\n", len(removed), freed)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/devnet"
+	"zkrollup/pkg/sequencer"
+)
+
+func main() {
+	// Parse command line flags
+	nodes := flag.Int("nodes", 3, "Number of sequencers to run")
+	accounts := flag.Int("accounts", 10, "Number of pre-funded rollup accounts")
+	blockTime := flag.Duration("blocktime", time.Second, "Interval between simulated L1 blocks")
+	flag.Parse()
+
+	config := core.DevConfig()
+	config.DevAccounts = *accounts
+
+	network, err := devnet.New(devnet.Options{
+		Nodes:  *nodes,
+		Config: config,
+		L1:     devnet.L1Options{BlockTime: *blockTime},
+	})
+	if err != nil {
+		log.Fatalf("Failed to start devnet: %v", err)
+	}
+
+	fmt.Printf("Simulated L1 chain %d, rollup contract %s, CRS manager %s\n", devnet.L1ChainID, network.L1.Rollup.Hex(), network.L1.CRSManager.Hex())
+	for i, node := range network.Nodes {
+		fmt.Printf("Node %d: RPC %s, peer %s\n", i, node.RPCURL, node.P2PAddr)
+	}
+	fmt.Printf("Dev chain %d, accounts funded with %s wei each:\n", config.ChainID, sequencer.DevAccountBalance)
+	for i, acc := range network.Accounts {
+		fmt.Printf("(%d) %s 0x%x\n", i, acc.Address, crypto.FromECDSA(acc.PrivateKey))
+	}
+
+	// Wait for shutdown signal
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+
+	fmt.Println("Stopping devnet")
+	network.Close()
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// resolveMethod finds the method to call. With an ABI file the method is looked up by name
+// or full signature, without one it is parsed from a signature such as
+// "transfer(address,uint256)", optionally followed by its outputs as in "get()(uint256)".
+func resolveMethod(abiFile, name string) (abi.Method, error) {
+	if abiFile == "" {
+		return parseSignature(name)
+	}
+
+	data, err := os.ReadFile(abiFile)
+	if err != nil {
+		return abi.Method{}, fmt.Errorf("failed to read ABI: %v", err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(data)))
+	if err != nil {
+		return abi.Method{}, fmt.Errorf("failed to parse ABI: %v", err)
+	}
+	if method, ok := parsed.Methods[name]; ok {
+		return method, nil
+	}
+	// Overloaded methods are only reachable by signature
+	for _, method := range parsed.Methods {
+		if method.Sig == name {
+			return method, nil
+		}
+	}
+	return abi.Method{}, fmt.Errorf("method %s not found in ABI", name)
+}
+
+// parseSignature builds a method from its signature. Methods declaring outputs are treated
+// as views so their result is decoded.
+func parseSignature(sig string) (abi.Method, error) {
+	open := strings.Index(sig, "(")
+	if open <= 0 {
+		return abi.Method{}, fmt.Errorf("method %q must be a signature such as set(uint256) when no ABI is given", sig)
+	}
+	name := sig[:open]
+
+	inputs, rest, err := parseTypeList(sig[open:])
+	if err != nil {
+		return abi.Method{}, fmt.Errorf("invalid signature %q: %v", sig, err)
+	}
+	var outputs abi.Arguments
+	if rest != "" {
+		if outputs, rest, err = parseTypeList(rest); err != nil || rest != "" {
+			return abi.Method{}, fmt.Errorf("invalid outputs in signature %q", sig)
+		}
+	}
+
+	mutability := "nonpayable"
+	if len(outputs) > 0 {
+		mutability = "view"
+	}
+	return abi.NewMethod(name, name, abi.Function, mutability, len(outputs) > 0, false, inputs, outputs), nil
+}
+
+// parseTypeList parses a parenthesized list of types and returns what follows it. Tuples
+// are not supported, an ABI file is needed for them.
+func parseTypeList(s string) (abi.Arguments, string, error) {
+	end := strings.Index(s, ")")
+	if !strings.HasPrefix(s, "(") || end < 0 {
+		return nil, "", fmt.Errorf("unbalanced parentheses")
+	}
+	list := strings.TrimSpace(s[1:end])
+
+	var args abi.Arguments
+	if list != "" {
+		for _, name := range strings.Split(list, ",") {
+			typ, err := abi.NewType(strings.TrimSpace(name), "", nil)
+			if err != nil {
+				return nil, "", err
+			}
+			args = append(args, abi.Argument{Type: typ})
+		}
+	}
+	return args, s[end+1:], nil
+}
+
+// parseArgs reads the -args flag. A JSON array is taken as is, so arrays and strings
+// with commas can be passed, anything else is split on commas.
+func parseArgs(raw string) ([]interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var values []interface{}
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&values); err != nil {
+			return nil, fmt.Errorf("invalid JSON arguments: %v", err)
+		}
+		return values, nil
+	}
+
+	var values []interface{}
+	for _, arg := range strings.Split(raw, ",") {
+		values = append(values, strings.TrimSpace(arg))
+	}
+	return values, nil
+}
+
+// encodeCall ABI-encodes a call of method with the given arguments, converted to the
+// method's parameter types
+func encodeCall(method abi.Method, values []interface{}) ([]byte, error) {
+	if len(values) != len(method.Inputs) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", method.Sig, len(method.Inputs), len(values))
+	}
+
+	args := make([]interface{}, len(values))
+	for i, input := range method.Inputs {
+		arg, err := convertArg(input.Type, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s): %v", i, input.Type.String(), err)
+		}
+		args[i] = arg.Interface()
+	}
+
+	packed, err := method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments: %v", err)
+	}
+	return append(append([]byte{}, method.ID...), packed...), nil
+}
+
+// convertArg converts a command line or JSON value to the Go type the ABI encoder expects
+// for typ
+func convertArg(typ abi.Type, value interface{}) (reflect.Value, error) {
+	switch typ.T {
+	case abi.UintTy, abi.IntTy:
+		n, err := parseInteger(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return convertInteger(typ, n)
+
+	case abi.BoolTy:
+		if b, ok := value.(bool); ok {
+			return reflect.ValueOf(b), nil
+		}
+		b, err := strconv.ParseBool(fmt.Sprint(value))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid bool %v", value)
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.StringTy:
+		return reflect.ValueOf(fmt.Sprint(value)), nil
+
+	case abi.AddressTy:
+		s := fmt.Sprint(value)
+		if !common.IsHexAddress(s) {
+			return reflect.Value{}, fmt.Errorf("invalid address %s", s)
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+
+	case abi.BytesTy:
+		b, err := hexutil.Decode(fmt.Sprint(value))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid bytes: %v", err)
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(fmt.Sprint(value))
+		if err != nil || len(b) > typ.Size {
+			return reflect.Value{}, fmt.Errorf("expected at most %d bytes of hex", typ.Size)
+		}
+		// bytesN values are left aligned
+		array := reflect.New(typ.GetType()).Elem()
+		reflect.Copy(array, reflect.ValueOf(b))
+		return array, nil
+
+	case abi.SliceTy, abi.ArrayTy:
+		items, err := arrayItems(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		var list reflect.Value
+		if typ.T == abi.ArrayTy {
+			if len(items) != typ.Size {
+				return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", typ.Size, len(items))
+			}
+			list = reflect.New(typ.GetType()).Elem()
+		} else {
+			list = reflect.MakeSlice(typ.GetType(), len(items), len(items))
+		}
+		for i, item := range items {
+			elem, err := convertArg(*typ.Elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %v", i, err)
+			}
+			list.Index(i).Set(elem)
+		}
+		return list, nil
+
+	default:
+		return reflect.Value{}, fmt.Errorf("type %s is not supported", typ.String())
+	}
+}
+
+// parseInteger reads a decimal or 0x-prefixed hex integer
+func parseInteger(value interface{}) (*big.Int, error) {
+	s := strings.TrimSpace(fmt.Sprint(value))
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %s", s)
+	}
+	return n, nil
+}
+
+// convertInteger converts n to the Go integer type of typ, rejecting values that don't fit
+func convertInteger(typ abi.Type, n *big.Int) (reflect.Value, error) {
+	fits := n.BitLen() <= typ.Size
+	if typ.T == abi.UintTy {
+		fits = fits && n.Sign() >= 0
+	} else {
+		fits = n.BitLen() < typ.Size
+	}
+	if !fits {
+		return reflect.Value{}, fmt.Errorf("%s does not fit in %s", n, typ.String())
+	}
+
+	goType := typ.GetType()
+	if goType == reflect.TypeOf(&big.Int{}) {
+		return reflect.ValueOf(n), nil
+	}
+	v := reflect.New(goType).Elem()
+	if typ.T == abi.UintTy {
+		v.SetUint(n.Uint64())
+	} else {
+		v.SetInt(n.Int64())
+	}
+	return v, nil
+}
+
+// arrayItems returns the elements of an array argument, given as a JSON array or, from
+// the comma separated form, as a JSON encoded string
+func arrayItems(value interface{}) ([]interface{}, error) {
+	if items, ok := value.([]interface{}); ok {
+		return items, nil
+	}
+	var items []interface{}
+	dec := json.NewDecoder(strings.NewReader(fmt.Sprint(value)))
+	dec.UseNumber()
+	if err := dec.Decode(&items); err != nil {
+		return nil, fmt.Errorf("expected a JSON array")
+	}
+	return items, nil
+}
+
+// decodeOutputs decodes a view call's return data and formats each value for display
+func decodeOutputs(method abi.Method, data []byte) ([]string, error) {
+	values, err := method.Outputs.Unpack(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode return data: %v", err)
+	}
+
+	lines := make([]string, len(values))
+	for i, value := range values {
+		name := method.Outputs[i].Name
+		if name == "" {
+			name = fmt.Sprintf("[%d]", i)
+		}
+		lines[i] = fmt.Sprintf("%s (%s): %s", name, method.Outputs[i].Type.String(), formatValue(reflect.ValueOf(value)))
+	}
+	return lines, nil
+}
+
+// formatValue renders a decoded value, bytes as hex and addresses checksummed
+func formatValue(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		return value.Hex()
+	case []byte:
+		return hexutil.Encode(value)
+	}
+
+	switch v.Kind() {
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -13,23 +13,29 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"zkrollup/pkg/evm"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
 )
 
 var (
 	privateKey = flag.String("key", "", "Private key (hex format without 0x prefix)")
 	rpcURL     = flag.String("rpc", "http://localhost:9000", "Rollup RPC URL")
-	action     = flag.String("action", "deploy", "Action to perform: deploy, call")
-	contractFile = flag.String("contract", "", "Contract bytecode file (for deploy) or address (for call)")
-	method     = flag.String("method", "", "Method to call (for call action)")
-	args       = flag.String("args", "", "Arguments for method call, comma separated")
+	action     = flag.String("action", "deploy", "Action to perform: deploy, call, view")
+	contractFile = flag.String("contract", "", "Contract bytecode file (for deploy) or address (for call and view)")
+	abiFile    = flag.String("abi", "", "Contract ABI JSON file, without it -method must be a signature such as set(uint256)")
+	method     = flag.String("method", "", "Method to call, a name from the ABI or a signature, get()(uint256) declares outputs")
+	args       = flag.String("args", "", "Arguments for method call, comma separated or a JSON array")
 	amount     = flag.String("amount", "0", "Amount to send with transaction")
 	gas        = flag.Uint64("gas", 1000000, "Gas limit")
+	salt       = flag.String("salt", "", "CREATE2 salt (hex, up to 32 bytes) to deploy at an address independent of the nonce")
 )
 
 func main() {
@@ -59,8 +65,7 @@ func main() {
 	log.Info().Str("address", address.Hex()).Msg("Using address")
 	
 	// Convert Ethereum address to rollup address format
-	var rollupAddr [20]byte
-	copy(rollupAddr[:], address.Bytes())
+	rollupAddr := types.FromCommon(address)
 	
 	// Parse amount
 	amountValue := new(big.Int)
@@ -71,19 +76,22 @@ func main() {
 	
 	// Create client
 	client := NewRollupClient(*rpcURL)
+	nonces := NewNonceManager(client)
 	
 	// Handle different actions
 	switch *action {
 	case "deploy":
-		deployContract(client, rollupAddr, privateKeyBytes, privKey, amountValue)
+		deployContract(client, nonces, rollupAddr, privateKeyBytes, privKey, amountValue)
 	case "call":
-		callContract(client, rollupAddr, privateKeyBytes, privKey, amountValue)
+		callContract(client, nonces, rollupAddr, privateKeyBytes, privKey, amountValue)
+	case "view":
+		viewContract(client, rollupAddr, amountValue)
 	default:
 		log.Fatal().Str("action", *action).Msg("Unknown action")
 	}
 }
 
-func deployContract(client *RollupClient, from [20]byte, privateKey []byte, privKey *ecdsa.PrivateKey, amount *big.Int) {
+func deployContract(client *RollupClient, nonces *NonceManager, from types.Address, privateKey []byte, privKey *ecdsa.PrivateKey, amount *big.Int) {
 	if *contractFile == "" {
 		log.Fatal().Msg("Contract file is required for deployment")
 	}
@@ -94,123 +102,159 @@ func deployContract(client *RollupClient, from [20]byte, privateKey []byte, priv
 		log.Fatal().Err(err).Str("file", *contractFile).Msg("Failed to read contract file")
 	}
 	
-	// Get nonce
-	nonce, err := client.GetNonce(from)
+	// Get the domain the signature is bound to, the nonce is set when sending
+	domain, err := client.SigningDomain()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to get nonce")
+		log.Fatal().Err(err).Msg("Failed to get signing domain")
 	}
 	
 	// Create transaction
 	tx := state.Transaction{
-		Type:   state.TxTypeContractDeploy,
-		From:   from,
-		To:     [20]byte{}, // Empty for contract deployment
-		Amount: amount,
-		Nonce:  nonce,
-		Data:   bytecode,
-		Gas:    *gas,
+		Type:    state.TxTypeContractDeploy,
+		From:    from,
+		To:      types.Address{}, // Empty for contract deployment
+		Amount:  amount,
+		Data:    bytecode,
+		Gas:     *gas,
+		ChainID: domain.ChainID,
+	}
+	
+	// With a salt the contract is deployed with CREATE2, its address is known in advance
+	var create2Addr *common.Address
+	if *salt != "" {
+		saltBytes, err := hex.DecodeString(strings.TrimPrefix(*salt, "0x"))
+		if err != nil || len(saltBytes) > state.SaltLength {
+			log.Fatal().Str("salt", *salt).Msg("Salt must be at most 32 bytes of hex")
+		}
+		var saltWord [32]byte
+		copy(saltWord[32-len(saltBytes):], saltBytes)
+		
+		tx.Type = state.TxTypeContractCreate
+		tx.Data = append(saltWord[:], bytecode...)
+		addr := evm.Create2Address(from.Common(), saltWord, bytecode)
+		create2Addr = &addr
 	}
 	
-	// Sign transaction
-	signature, err := crypto.Sign(getTransactionHash(tx), privKey)
+	// Sign and send transaction
+	nonce, err := nonces.Send(tx, signWith(privKey, domain.VerifyingContract))
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to sign transaction")
+		log.Fatal().Err(err).Msg("Failed to send transaction")
+	}
+	
+	// CREATE derives the address from the account nonce before the transaction
+	contractAddr := evm.CreateAddress(from.Common(), nonce-1)
+	if create2Addr != nil {
+		contractAddr = *create2Addr
+	}
+	
+	log.Info().Str("contract", contractAddr.Hex()).Msg("Contract deployment transaction sent successfully")
+}
+
+func callContract(client *RollupClient, nonces *NonceManager, from types.Address, privateKey []byte, privKey *ecdsa.PrivateKey, amount *big.Int) {
+	to, abiMethod, calldata := prepareCall()
+	
+	// Sending a transaction to a view function only burns gas, read its result instead
+	if abiMethod.IsConstant() {
+		log.Info().Str("method", abiMethod.Sig).Msg("Method is read-only, calling it as a view")
+		printView(client, from, to, amount, abiMethod, calldata)
+		return
 	}
-	tx.Signature = signature
 	
-	// Send transaction
-	err = client.SendTransaction(tx)
+	// Get the domain the signature is bound to, the nonce is set when sending
+	domain, err := client.SigningDomain()
 	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get signing domain")
+	}
+	
+	// Create transaction
+	tx := state.Transaction{
+		Type:    state.TxTypeContractCall,
+		From:    from,
+		To:      to,
+		Amount:  amount,
+		Data:    calldata,
+		Gas:     *gas,
+		ChainID: domain.ChainID,
+	}
+	
+	// Sign and send transaction
+	if _, err := nonces.Send(tx, signWith(privKey, domain.VerifyingContract)); err != nil {
 		log.Fatal().Err(err).Msg("Failed to send transaction")
 	}
 	
-	log.Info().Msg("Contract deployment transaction sent successfully")
+	log.Info().Msg("Contract call transaction sent successfully")
+}
+
+// viewContract calls a method without sending a transaction and prints its decoded result
+func viewContract(client *RollupClient, from types.Address, amount *big.Int) {
+	to, abiMethod, calldata := prepareCall()
+	printView(client, from, to, amount, abiMethod, calldata)
 }
 
-func callContract(client *RollupClient, from [20]byte, privateKey []byte, privKey *ecdsa.PrivateKey, amount *big.Int) {
+// prepareCall parses the contract address and ABI-encodes the method call from the flags
+func prepareCall() (types.Address, abi.Method, []byte) {
 	if *contractFile == "" || *method == "" {
 		log.Fatal().Msg("Contract address and method are required for contract call")
 	}
 	
 	// Parse contract address
-	contractAddr := common.HexToAddress(*contractFile)
-	var to [20]byte
-	copy(to[:], contractAddr.Bytes())
-	
-	// Get nonce
-	nonce, err := client.GetNonce(from)
+	to, err := types.ParseAddress(*contractFile)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to get nonce")
+		log.Fatal().Err(err).Msg("Invalid contract address")
 	}
 	
-	// Parse ABI and method arguments
-	// This is a simplified implementation - in a real-world scenario, you'd need to parse the ABI
-	methodSig := crypto.Keccak256([]byte(*method))[:4] // First 4 bytes of method signature
-	
-	// Parse arguments (simplified)
-	var calldata []byte
-	calldata = append(calldata, methodSig...)
-	
-	if *args != "" {
-		// Very simplified argument handling - in reality, you'd need proper ABI encoding
-		argsList := strings.Split(*args, ",")
-		for _, arg := range argsList {
-			// This is just a placeholder - real implementation would properly encode based on types
-			calldata = append(calldata, common.Hex2Bytes(arg)...)
-		}
+	abiMethod, err := resolveMethod(*abiFile, *method)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to resolve method")
 	}
-	
-	// Create transaction
-	tx := state.Transaction{
-		Type:   state.TxTypeContractCall,
-		From:   from,
-		To:     to,
-		Amount: amount,
-		Nonce:  nonce,
-		Data:   calldata,
-		Gas:    *gas,
+	values, err := parseArgs(*args)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse arguments")
 	}
-	
-	// Sign transaction
-	signature, err := crypto.Sign(getTransactionHash(tx), privKey)
+	calldata, err := encodeCall(abiMethod, values)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to sign transaction")
+		log.Fatal().Err(err).Str("method", abiMethod.Sig).Msg("Failed to encode call")
 	}
-	tx.Signature = signature
-	
-	// Send transaction
-	err = client.SendTransaction(tx)
+	return to, abiMethod, calldata
+}
+
+// printView runs a read-only call and prints its decoded outputs, or the raw return data
+// when the method declares none
+func printView(client *RollupClient, from, to types.Address, amount *big.Int, abiMethod abi.Method, calldata []byte) {
+	ret, err := client.Call(from, to, amount, *gas, calldata)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to send transaction")
+		log.Fatal().Err(err).Msg("Call failed")
+	}
+	if len(abiMethod.Outputs) == 0 {
+		fmt.Println(hexutil.Encode(ret))
+		return
+	}
+	lines, err := decodeOutputs(abiMethod, ret)
+	if err != nil {
+		log.Fatal().Err(err).Str("data", hexutil.Encode(ret)).Msg("Failed to decode result")
+	}
+	for _, line := range lines {
+		fmt.Println(line)
 	}
-	
-	log.Info().Msg("Contract call transaction sent successfully")
 }
 
-// getTransactionHash computes the hash of a transaction for signing
-func getTransactionHash(tx state.Transaction) []byte {
-	// This is a simplified implementation
-	// In a real implementation, you'd use RLP encoding or a similar approach
-	
-	// Special handling for zero values to ensure consistent hash computation
-	amountBytes := []byte{0}
-	if tx.Amount.Sign() > 0 {
-		amountBytes = tx.Amount.Bytes()
+// signWith returns a SignFunc signing transactions for the rollup contract rollup with privKey
+func signWith(privKey *ecdsa.PrivateKey, rollup types.Address) SignFunc {
+	return func(tx *state.Transaction) error {
+		signature, err := crypto.Sign(getTransactionHash(*tx, rollup), privKey)
+		if err != nil {
+			return err
+		}
+		tx.Signature = signature
+		return nil
 	}
-	
-	// Convert nonce to string for consistent hash computation
-	nonceStr := fmt.Sprintf("%d", tx.Nonce)
-	
-	// Compute hash
-	hash := crypto.Keccak256(
-		tx.From[:],
-		tx.To[:],
-		amountBytes,
-		[]byte(nonceStr),
-		tx.Data,
-	)
-	
-	return hash
+}
+
+// getTransactionHash computes the hash of a transaction for signing
+func getTransactionHash(tx state.Transaction, rollup types.Address) []byte {
+	// The sequencer verifies signatures over the transaction's signing hash
+	hash := tx.SigningHash(rollup)
+	return hash[:]
 }
 
 // RollupClient is a client for interacting with the ZK-Rollup
@@ -249,10 +293,15 @@ type RPCError struct {
 	Message string `json:"message"`
 }
 
-// GetNonce gets the current nonce for an address
-func (c *RollupClient) GetNonce(address [20]byte) (uint64, error) {
+// Error implements the error interface, the code is kept so callers can tell rejections apart
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("RPC error: %d - %s", e.Code, e.Message)
+}
+
+// GetNonce gets the current nonce for an address, its next transaction must carry one more
+func (c *RollupClient) GetNonce(address types.Address) (uint64, error) {
 	// Convert address to hex string
-	addrHex := fmt.Sprintf("0x%x", address)
+	addrHex := address.Hex()
 	
 	// Create RPC request
 	req := RPCRequest{
@@ -267,21 +316,47 @@ func (c *RollupClient) GetNonce(address [20]byte) (uint64, error) {
 		Nonce uint64 `json:"nonce"`
 	}
 	
-	err := c.call(req, &resp)
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to get nonce from rollup, using 0 as fallback")
-		return 0, nil
+	if err := c.call(req, &resp); err != nil {
+		return 0, fmt.Errorf("failed to get nonce: %w", err)
 	}
 	
 	return resp.Nonce, nil
 }
 
+// SigningDomain gets the chain ID and rollup contract transactions must be signed for
+func (c *RollupClient) SigningDomain() (state.SigningDomain, error) {
+	req := RPCRequest{
+		JSONRPC: "2.0",
+		Method:  "rollup_chainId",
+		Params:  []string{},
+		ID:      1,
+	}
+	
+	var resp struct {
+		ChainID           uint64 `json:"chainId"`
+		VerifyingContract string `json:"verifyingContract"`
+	}
+	if err := c.call(req, &resp); err != nil {
+		return state.SigningDomain{}, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	
+	domain := state.SigningDomain{ChainID: resp.ChainID}
+	if resp.VerifyingContract != "" {
+		contract, err := types.ParseAddress(resp.VerifyingContract)
+		if err != nil {
+			return state.SigningDomain{}, fmt.Errorf("invalid verifying contract: %w", err)
+		}
+		domain.VerifyingContract = contract
+	}
+	return domain, nil
+}
+
 // SendTransaction sends a transaction to the rollup
 func (c *RollupClient) SendTransaction(tx state.Transaction) error {
 	// Log transaction details
 	log.Info().
-		Str("from", fmt.Sprintf("%x", tx.From)).
-		Str("to", fmt.Sprintf("%x", tx.To)).
+		Str("from", tx.From.Hex()).
+		Str("to", tx.To.Hex()).
 		Str("amount", tx.Amount.String()).
 		Uint64("nonce", tx.Nonce).
 		Uint64("gas", tx.Gas).
@@ -291,14 +366,15 @@ func (c *RollupClient) SendTransaction(tx state.Transaction) error {
 	
 	// Convert transaction to JSON-friendly format
 	txParams := map[string]interface{}{
-		"from":      fmt.Sprintf("0x%x", tx.From),
-		"to":        fmt.Sprintf("0x%x", tx.To),
+		"from":      tx.From.Hex(),
+		"to":        tx.To.Hex(),
 		"amount":    tx.Amount.String(),
 		"nonce":     tx.Nonce,
 		"gas":       tx.Gas,
 		"data":      fmt.Sprintf("0x%x", tx.Data),
 		"signature": fmt.Sprintf("0x%x", tx.Signature),
 		"type":      uint8(tx.Type),
+		"chainId":   tx.ChainID,
 	}
 	
 	// Create RPC request
@@ -323,6 +399,30 @@ func (c *RollupClient) SendTransaction(tx state.Transaction) error {
 	return nil
 }
 
+// Call runs a read-only contract call against the latest rollup state and returns its output
+func (c *RollupClient) Call(from, to types.Address, value *big.Int, gas uint64, data []byte) ([]byte, error) {
+	req := RPCRequest{
+		JSONRPC: "2.0",
+		Method:  "rollup_call",
+		Params: []interface{}{map[string]interface{}{
+			"from":  from.Hex(),
+			"to":    to.Hex(),
+			"value": value.String(),
+			"gas":   gas,
+			"data":  hexutil.Encode(data),
+		}},
+		ID: 1,
+	}
+	
+	var resp struct {
+		Data string `json:"data"`
+	}
+	if err := c.call(req, &resp); err != nil {
+		return nil, err
+	}
+	return hexutil.Decode(resp.Data)
+}
+
 // call makes a JSON-RPC call to the rollup node
 func (c *RollupClient) call(req RPCRequest, result interface{}) error {
 	// Marshal request to JSON
@@ -366,7 +466,7 @@ func (c *RollupClient) call(req RPCRequest, result interface{}) error {
 	
 	// Check for RPC error
 	if rpcResp.Error != nil {
-		return fmt.Errorf("RPC error: %d - %s", rpcResp.Error.Code, rpcResp.Error.Message)
+		return rpcResp.Error
 	}
 	
 	// Unmarshal result
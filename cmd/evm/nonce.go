@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// invalidNonceCode is the JSON-RPC error code the rollup rejects a transaction's nonce with
+const invalidNonceCode = -32010
+
+// nonceRetries caps how often a send is retried after resyncing a rejected nonce
+const nonceRetries = 3
+
+// NonceManager hands out the nonces of accounts sending through a RollupClient. The next
+// nonce of each account is tracked locally so transactions can be sent without waiting for
+// the previous ones to execute, and is resynced from rollup_getNonce whenever the rollup
+// rejects a nonce as too low or too high. Sends from the same account are serialized, sends
+// from different accounts run concurrently.
+type NonceManager struct {
+	client *RollupClient
+
+	mu       sync.Mutex
+	accounts map[types.Address]*accountNonce
+}
+
+// accountNonce is the locally tracked nonce of one account
+type accountNonce struct {
+	mu     sync.Mutex // Held for a whole send
+	next   uint64
+	synced bool
+}
+
+// SignFunc signs a transaction once its nonce is set
+type SignFunc func(tx *state.Transaction) error
+
+// NewNonceManager creates a nonce manager sending through client
+func NewNonceManager(client *RollupClient) *NonceManager {
+	return &NonceManager{
+		client:   client,
+		accounts: make(map[types.Address]*accountNonce),
+	}
+}
+
+// account returns the tracked nonce of address, creating it unsynced
+func (m *NonceManager) account(address types.Address) *accountNonce {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	acc, ok := m.accounts[address]
+	if !ok {
+		acc = &accountNonce{}
+		m.accounts[address] = acc
+	}
+	return acc
+}
+
+// Send sets the next nonce of tx.From on tx, signs it with sign and sends it. A nonce the
+// rollup rejects is resynced and the transaction signed and sent again. It returns the
+// nonce the transaction was accepted with.
+func (m *NonceManager) Send(tx state.Transaction, sign SignFunc) (uint64, error) {
+	acc := m.account(tx.From)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if !acc.synced {
+			if err := m.resync(tx.From, acc); err != nil {
+				return 0, err
+			}
+		}
+
+		tx.Nonce = acc.next
+		if err := sign(&tx); err != nil {
+			return 0, fmt.Errorf("failed to sign transaction: %w", err)
+		}
+
+		err := m.client.SendTransaction(tx)
+		if err == nil {
+			acc.next++
+			return tx.Nonce, nil
+		}
+		if !isNonceError(err) || attempt >= nonceRetries {
+			return 0, err
+		}
+
+		log.Warn().Err(err).Str("from", tx.From.Hex()).Uint64("nonce", tx.Nonce).Msg("Nonce rejected, resyncing from rollup")
+		acc.synced = false
+	}
+}
+
+// Reset forgets the tracked nonce of address, its next send resyncs from the rollup first.
+// Use it after sending from the account by other means.
+func (m *NonceManager) Reset(address types.Address) {
+	acc := m.account(address)
+	acc.mu.Lock()
+	acc.synced = false
+	acc.mu.Unlock()
+}
+
+// resync reads the account nonce from the rollup, the next transaction carries one more
+func (m *NonceManager) resync(address types.Address, acc *accountNonce) error {
+	nonce, err := m.client.GetNonce(address)
+	if err != nil {
+		return err
+	}
+	acc.next = nonce + 1
+	acc.synced = true
+	return nil
+}
+
+// isNonceError reports whether the rollup refused a transaction for its nonce. Nodes send
+// invalidNonceCode, the messages cover Ethereum style "nonce too low/too high" errors.
+func isNonceError(err error) bool {
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	if rpcErr.Code == invalidNonceCode {
+		return true
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "nonce too high")
+}
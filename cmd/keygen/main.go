@@ -1,38 +1,65 @@
 package main
 
 import (
+	"crypto/rand"
 	"encoding/hex"
 	"flag"
 	"fmt"
 	"os"
 
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
 )
 
+// commands are the wallet subcommands, working on an encrypted keystore
+var commands = map[string]func(args []string){
+	"new":    cmdNew,
+	"import": cmdImport,
+	"list":   cmdList,
+	"export": cmdExport,
+	"sign":   cmdSign,
+}
+
 func main() {
 	// Configure logging
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
-	
+
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	// Parse flags
+	eddsaKey := flag.Bool("eddsa", false, "Generate an EdDSA/BN254 key for a rollup-native account")
+	flag.Usage = usage
 	flag.Parse()
-	
+
+	if *eddsaKey {
+		generateEdDSAKey()
+		return
+	}
+
 	// Generate a new private key
 	privateKey, err := crypto.GenerateKey()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to generate private key")
 	}
-	
+
 	// Get the private key in bytes
 	privateKeyBytes := crypto.FromECDSA(privateKey)
-	
+
 	// Convert to hex string (without 0x prefix)
 	privateKeyHex := hex.EncodeToString(privateKeyBytes)
-	
+
 	// Get the public address
 	address := crypto.PubkeyToAddress(privateKey.PublicKey)
-	
+
 	// Print the results
 	fmt.Println("Generated new Ethereum key")
 	fmt.Println("---------------------------")
@@ -40,4 +67,36 @@ func main() {
 	fmt.Printf("Address:     %s\n", address.Hex())
 	fmt.Println("\nTo use this key with the ZK-Rollup EVM client:")
 	fmt.Printf("./zkrollup-evm -key %s -action deploy -contract ./contracts/examples/SimpleStorage.sol\n", privateKeyHex)
+	fmt.Println("\nTo keep keys encrypted instead, use: keygen new")
+}
+
+// usage lists the wallet subcommands next to the plain key generation flags
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: keygen [-eddsa]\n")
+	fmt.Fprintf(out, "       keygen new|import|list|export|sign [flags]\n\n")
+	fmt.Fprintf(out, "Without a subcommand a new key is printed in the clear. The subcommands keep keys\n")
+	fmt.Fprintf(out, "in an encrypted keystore, see keygen <subcommand> -h.\n\n")
+	flag.PrintDefaults()
+}
+
+// generateEdDSAKey prints a new EdDSA/BN254 key and the rollup-native account it controls
+func generateEdDSAKey() {
+	privateKey, err := eddsa.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate EdDSA key")
+	}
+	publicKey := privateKey.PublicKey.Bytes()
+
+	address, err := state.EdDSAAddress(publicKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to derive account address")
+	}
+
+	fmt.Println("Generated new rollup-native EdDSA key")
+	fmt.Println("-------------------------------------")
+	fmt.Printf("Private Key: %s\n", hex.EncodeToString(privateKey.Bytes()))
+	fmt.Printf("Public Key:  0x%s\n", hex.EncodeToString(publicKey))
+	fmt.Printf("Address:     %s\n", address.Hex())
+	fmt.Println("\nRegister the key with rollup_registerKey before using the account.")
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// passwordEnv holds the keystore passphrase for scripted use
+const passwordEnv = "KEYSTORE_PASSWORD"
+
+// txTypes maps the -type names of the sign subcommand to transaction types
+var txTypes = map[string]state.TxType{
+	"transfer": state.TxTypeTransfer,
+	"deploy":   state.TxTypeContractDeploy,
+	"call":     state.TxTypeContractCall,
+	"create2":  state.TxTypeContractCreate,
+}
+
+// keystoreFlags are the flags every wallet subcommand takes
+type keystoreFlags struct {
+	dir          *string
+	passwordFile *string
+	light        *bool
+}
+
+func addKeystoreFlags(fs *flag.FlagSet) keystoreFlags {
+	dir := os.Getenv("KEYSTORE_DIR")
+	if dir == "" {
+		dir = "keystore"
+	}
+	return keystoreFlags{
+		dir:          fs.String("keystore", dir, "Keystore directory, defaults to $KEYSTORE_DIR"),
+		passwordFile: fs.String("password-file", "", "File holding the passphrase, otherwise $"+passwordEnv+" or a prompt"),
+		light:        fs.Bool("light", false, "Encrypt new keys with light scrypt parameters, faster but weaker"),
+	}
+}
+
+// open opens the keystore, encrypting new keys with standard or light scrypt parameters
+func (f keystoreFlags) open() *keystore.KeyStore {
+	if *f.light {
+		return keystore.NewKeyStore(*f.dir, keystore.LightScryptN, keystore.LightScryptP)
+	}
+	return keystore.NewKeyStore(*f.dir, keystore.StandardScryptN, keystore.StandardScryptP)
+}
+
+// password reads the passphrase from the password file, the environment or stdin
+func (f keystoreFlags) password(prompt string) string {
+	if *f.passwordFile != "" {
+		data, err := os.ReadFile(*f.passwordFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read password file")
+		}
+		return strings.TrimRight(string(data), "\r\n")
+	}
+	if password, ok := os.LookupEnv(passwordEnv); ok {
+		return password
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatal().Err(err).Msg("Failed to read passphrase")
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// cmdNew creates a key in the keystore
+func cmdNew(args []string) {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	ks := addKeystoreFlags(fs)
+	fs.Parse(args)
+
+	password := ks.password("Passphrase for the new key: ")
+	if password == "" {
+		log.Fatal().Msg("Refusing to store a key without a passphrase")
+	}
+	account, err := ks.open().NewAccount(password)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create key")
+	}
+
+	fmt.Printf("Address: %s\n", account.Address.Hex())
+	fmt.Printf("Keyfile: %s\n", account.URL.Path)
+}
+
+// cmdImport encrypts an existing hex private key into the keystore. The key is read from
+// a file or stdin so it never appears on the command line.
+func cmdImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	ks := addKeystoreFlags(fs)
+	keyFile := fs.String("key-file", "-", "File holding the hex private key, - reads stdin")
+	fs.Parse(args)
+
+	var data []byte
+	var err error
+	if *keyFile == "-" {
+		fmt.Fprint(os.Stderr, "Hex private key: ")
+		var line string
+		line, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		data = []byte(line)
+		if line != "" {
+			err = nil
+		}
+	} else {
+		data, err = os.ReadFile(*keyFile)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read private key")
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid private key")
+	}
+
+	password := ks.password("Passphrase to encrypt the key with: ")
+	if password == "" {
+		log.Fatal().Msg("Refusing to store a key without a passphrase")
+	}
+	account, err := ks.open().ImportECDSA(key, password)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to import key")
+	}
+
+	fmt.Printf("Address: %s\n", account.Address.Hex())
+	fmt.Printf("Keyfile: %s\n", account.URL.Path)
+}
+
+// cmdList prints the keystore's accounts and their key files
+func cmdList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	ks := addKeystoreFlags(fs)
+	fs.Parse(args)
+
+	for i, account := range ks.open().Accounts() {
+		fmt.Printf("#%d: %s %s\n", i, account.Address.Hex(), account.URL.Path)
+	}
+}
+
+// cmdExport prints the keystore's addresses, one per line or as a JSON array, for
+// funding scripts and configs. Keys never leave the keystore.
+func cmdExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	ks := addKeystoreFlags(fs)
+	asJSON := fs.Bool("json", false, "Print a JSON array")
+	fs.Parse(args)
+
+	addresses := []string{}
+	for _, account := range ks.open().Accounts() {
+		addresses = append(addresses, account.Address.Hex())
+	}
+	if *asJSON {
+		out, _ := json.Marshal(addresses)
+		fmt.Println(string(out))
+		return
+	}
+	for _, address := range addresses {
+		fmt.Println(address)
+	}
+}
+
+// cmdSign signs a transaction offline with a keystore key and prints it RLP encoded for
+// rollup_sendRawTransaction
+func cmdSign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	ks := addKeystoreFlags(fs)
+	from := fs.String("from", "", "Address of the signing key")
+	txType := fs.String("type", "transfer", "Transaction type: transfer, deploy, call or create2")
+	to := fs.String("to", "", "Recipient or contract address")
+	amount := fs.String("amount", "0", "Amount in wei")
+	nonce := fs.Uint64("nonce", 0, "Account nonce")
+	gas := fs.Uint64("gas", 21000, "Gas limit")
+	gasPrice := fs.String("gas-price", "0", "Gas price in wei")
+	data := fs.String("data", "", "Hex call data or init code, create2 prefixes it with the 32-byte salt")
+	chainID := fs.Uint64("chain-id", core.DevChainID, "Chain ID of the rollup, see rollup_chainId")
+	rollupContract := fs.String("rollup-contract", "", "Rollup contract the transaction is signed for, see rollup_chainId; dev chains have none")
+	fs.Parse(args)
+
+	if !common.IsHexAddress(*from) {
+		log.Fatal().Msg("A valid -from address is required")
+	}
+	kind, ok := txTypes[*txType]
+	if !ok {
+		log.Fatal().Str("type", *txType).Msg("Unknown transaction type")
+	}
+	tx := state.Transaction{
+		Type:    kind,
+		From:    types.FromCommon(common.HexToAddress(*from)),
+		Nonce:   *nonce,
+		Gas:     *gas,
+		Data:    common.FromHex(*data),
+		ChainID: *chainID,
+	}
+	if *to != "" {
+		address, err := types.ParseAddress(*to)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid -to address")
+		}
+		tx.To = address
+	}
+	if tx.Amount, ok = new(big.Int).SetString(*amount, 10); !ok {
+		log.Fatal().Str("amount", *amount).Msg("Invalid amount")
+	}
+	if tx.GasPrice, ok = new(big.Int).SetString(*gasPrice, 10); !ok {
+		log.Fatal().Str("gas_price", *gasPrice).Msg("Invalid gas price")
+	}
+
+	store := ks.open()
+	account, err := store.Find(accounts.Account{Address: tx.From.Common()})
+	if err != nil {
+		log.Fatal().Err(err).Str("address", *from).Msg("Key not found in keystore")
+	}
+	var rollup types.Address
+	if *rollupContract != "" {
+		address, err := types.ParseAddress(*rollupContract)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid -rollup-contract address")
+		}
+		rollup = address
+	}
+	hash := tx.SigningHash(rollup)
+	signature, err := store.SignHashWithPassphrase(account, ks.password("Passphrase: "), hash[:])
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to sign transaction")
+	}
+	tx.Signature = signature
+
+	raw, err := rlp.EncodeToBytes(&tx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to encode transaction")
+	}
+	txHash := tx.Hash()
+	fmt.Printf("Hash: 0x%x\n", txHash)
+	fmt.Printf("Raw:  0x%x\n", raw)
+}
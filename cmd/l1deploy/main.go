@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"zkrollup/pkg/crypto"
 	"zkrollup/pkg/l1"
 )
 
@@ -16,6 +21,13 @@ func main() {
 	privateKey := flag.String("privatekey", "", "Private key for Ethereum account (hex format without 0x prefix)")
 	rpcURL := flag.String("rpc", "http://localhost:8545", "Ethereum RPC URL")
 	chainID := flag.Int64("chainid", 1337, "Ethereum chain ID")
+	verifier := flag.String("verifier", "", "Address of the batch proof verifier contract, empty accepts batches unverified")
+	contract := flag.String("contract", "", "Register -verifier with a deployed ZK-Rollup contract instead of deploying one")
+	epoch := flag.Uint64("epoch", 0, "CRS epoch of the keys the verifier checks, with -contract")
+	circuitVersion := flag.Uint64("circuit-version", uint64(crypto.BatchCommitmentSchemaV1.Version), "Circuit version of the verifier's keys, with -contract")
+	aggregate := flag.Int("aggregate", 0, "Set -verifier as the verifier of proofs aggregating this many batches, with -contract")
+	addValidator := flag.String("add-validator", "", "Peer ID to add to the consensus validators, with -contract")
+	removeValidator := flag.String("remove-validator", "", "Peer ID to remove from the consensus validators, with -contract")
 	flag.Parse()
 
 	// Validate private key
@@ -23,11 +35,29 @@ func main() {
 		log.Fatal("Private key is required. Use -privatekey flag.")
 	}
 
+	if *verifier != "" && !common.IsHexAddress(*verifier) {
+		log.Fatal("Invalid verifier address.")
+	}
+
+	if *aggregate != 0 && (!common.IsHexAddress(*contract) || *verifier == "" || *aggregate < 2) {
+		log.Fatal("Setting the aggregate verifier needs a valid -contract, a -verifier and -aggregate of at least 2.")
+	}
+
+	changesValidators := *addValidator != "" || *removeValidator != ""
+	if changesValidators && !common.IsHexAddress(*contract) {
+		log.Fatal("Changing the validators needs a valid -contract.")
+	}
+
+	if !changesValidators && *aggregate == 0 && *contract != "" && (!common.IsHexAddress(*contract) || *verifier == "" || *epoch == 0) {
+		log.Fatal("Registering a verifier needs a valid -contract, a -verifier and an -epoch above 0.")
+	}
+
 	// Create L1 client config
 	config := &l1.Config{
-		EthereumRPC: *rpcURL,
-		ChainID:     *chainID,
-		PrivateKey:  *privateKey,
+		EthereumRPC:     *rpcURL,
+		ChainID:         *chainID,
+		ContractAddress: *contract,
+		PrivateKey:      *privateKey,
 	}
 
 	// Create L1 client
@@ -40,8 +70,35 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
+	if changesValidators {
+		changeValidators(ctx, client, *addValidator, *removeValidator)
+		return
+	}
+
+	if *aggregate != 0 {
+		setAggregateVerifier(ctx, client, common.HexToAddress(*verifier), *aggregate)
+		return
+	}
+
+	// Proofs are checked against the schema of the circuit the sequencer proves with
+	var inputSchema [32]byte
+	if *verifier != "" {
+		inputSchema = crypto.BatchCommitmentSchemaV1.Hash()
+		fmt.Printf("Public input schema %s: 0x%x\n", crypto.BatchCommitmentSchemaV1, inputSchema)
+	}
+
+	if *contract != "" {
+		registerVerifier(ctx, client, l1.VerifierEntry{
+			Epoch:          *epoch,
+			Verifier:       common.HexToAddress(*verifier),
+			InputSchema:    inputSchema,
+			CircuitVersion: *circuitVersion,
+		})
+		return
+	}
+
 	fmt.Println("Deploying ZK-Rollup contract to L1...")
-	address, err := client.DeployContract(ctx)
+	address, err := client.DeployContract(ctx, common.HexToAddress(*verifier), inputSchema)
 	if err != nil {
 		log.Fatalf("Failed to deploy contract: %v", err)
 	}
@@ -67,3 +124,72 @@ L1_BATCH_SUBMIT_PERIOD=300
 		fmt.Printf("  source %s && go run main.go\n", envFile)
 	}
 }
+
+// registerVerifier adds a verifier to the registry of a deployed contract, signed by the
+// contract's guardian
+func registerVerifier(ctx context.Context, client *l1.Client, entry l1.VerifierEntry) {
+	fmt.Printf("Registering verifier %s for epoch %d...\n", entry.Verifier.Hex(), entry.Epoch)
+	tx, err := client.RegisterVerifier(ctx, entry)
+	if err != nil {
+		log.Fatalf("Failed to register verifier: %v", err)
+	}
+	receipt, err := client.WaitForReceipt(ctx, tx)
+	if err != nil {
+		log.Fatalf("Failed to wait for registration: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Fatalf("Registration transaction %s reverted", tx.Hash().Hex())
+	}
+	fmt.Printf("Verifier registered for epoch %d, set proving_key_epoch: %d on the sequencers\n", entry.Epoch, entry.Epoch)
+}
+
+// setAggregateVerifier sets the verifier of aggregated proofs of a deployed contract, signed
+// by the contract's guardian
+func setAggregateVerifier(ctx context.Context, client *l1.Client, verifier common.Address, count int) {
+	schema := crypto.AggregationSchema(count)
+	fmt.Printf("Setting aggregate verifier %s for %d batches, public input schema %s: 0x%x\n", verifier.Hex(), count, schema, schema.Hash())
+	tx, err := client.SetAggregateVerifier(ctx, verifier, count)
+	if err != nil {
+		log.Fatalf("Failed to set aggregate verifier: %v", err)
+	}
+	receipt, err := client.WaitForReceipt(ctx, tx)
+	if err != nil {
+		log.Fatalf("Failed to wait for the transaction: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Fatalf("Transaction %s reverted", tx.Hash().Hex())
+	}
+	fmt.Printf("Aggregate verifier set, set proof_aggregation: %d on the sequencers\n", count)
+}
+
+// changeValidators adds and removes consensus validators on a deployed contract, signed by
+// the contract's guardian. Sequencers with validator_registry switch to the new set after
+// the next decided batch.
+func changeValidators(ctx context.Context, client *l1.Client, add, remove string) {
+	send := func(action, peerID string, call func(context.Context, string) (*types.Transaction, error)) {
+		fmt.Printf("Sending %s validator %s...\n", action, peerID)
+		tx, err := call(ctx, peerID)
+		if err != nil {
+			log.Fatalf("Failed to %s validator: %v", action, err)
+		}
+		receipt, err := client.WaitForReceipt(ctx, tx)
+		if err != nil {
+			log.Fatalf("Failed to wait for the transaction: %v", err)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			log.Fatalf("Transaction %s reverted", tx.Hash().Hex())
+		}
+	}
+	if add != "" {
+		send("add", add, client.AddValidator)
+	}
+	if remove != "" {
+		send("remove", remove, client.RemoveValidator)
+	}
+
+	validators, err := client.GetValidators(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read validators: %v", err)
+	}
+	fmt.Printf("Validators: %s\n", strings.Join(validators, ", "))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"zkrollup/pkg/metrics"
+)
+
+func main() {
+	// Parse command line flags
+	file := flag.String("file", "./proofstats.jsonl", "Proof stats file written by the sequencer")
+	last := flag.Int("last", 10, "Number of most recent records to print")
+	flag.Parse()
+
+	records, err := metrics.LoadProofRecords(*file)
+	if err != nil {
+		log.Fatalf("Failed to load proof stats: %v", err)
+	}
+
+	start := 0
+	if *last > 0 && len(records) > *last {
+		start = len(records) - *last
+	}
+	for _, r := range records[start:] {
+		fmt.Printf("batch=%d txs=%d proof_size=%d estimated_gas=%d gas_used=%d %s\n",
+			r.BatchNumber, r.TxCount, r.ProofSize, r.EstimatedGas, r.GasUsed, r.Timestamp.Format("2006-01-02 15:04:05"))
+	}
+
+	summary := metrics.SummarizeProofRecords(records)
+	fmt.Printf("\nBatches: %d\n", summary.Batches)
+	fmt.Printf("Proof size: avg %.0f bytes, max %d bytes\n", summary.AvgProofSize, summary.MaxProofSize)
+	fmt.Printf("L1 gas: avg %.0f, max %d\n", summary.AvgGasUsed, summary.MaxGasUsed)
+	if summary.GasTrend > 0 {
+		fmt.Printf("Gas trend (recent vs older batches): %+.1f%%\n", (summary.GasTrend-1)*100)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+func main() {
+	// Parse command line flags
+	backend := flag.String("backend", state.BackendLevelDB, "State backend (leveldb or pebble)")
+	path := flag.String("path", "./statedb", "Path to the state database")
+	account := flag.String("account", "", "Account address to print")
+	flag.Parse()
+
+	// Open read-only so a running sequencer's database is never modified
+	s, err := state.OpenState(*backend, *path, true)
+	if err != nil {
+		log.Fatalf("Failed to open state: %v", err)
+	}
+	defer s.Close()
+
+	fmt.Printf("Batch number: %d\n", s.GetBatchNumber())
+	fmt.Printf("State root: 0x%x\n", s.GetStateRoot())
+
+	if *account != "" {
+		addr, err := types.ParseAddress(*account)
+		if err != nil {
+			log.Fatalf("Invalid address: %v", err)
+		}
+
+		acc, err := s.GetAccount(addr)
+		if err != nil {
+			log.Fatalf("Failed to get account: %v", err)
+		}
+		fmt.Printf("Account %s: balance=%s nonce=%d\n", addr.Hex(), acc.Balance.String(), acc.Nonce)
+
+		if code, err := s.GetCode(addr); err == nil {
+			fmt.Printf("Code: %d bytes\n", len(code))
+		}
+	}
+}
@@ -7,18 +7,31 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
 )
 
 // This script deploys a contract to our simple RPC server
 // It ensures consistent handling of zero values and nonce formats
 // as required by the ZK-Rollup implementation
 
+// chainID is the chain ID of the local dev chain the transactions are signed for
+const chainID = core.DevChainID
+
+// rollupContract is the rollup contract the transactions are signed for, dev chains run
+// without one
+var rollupContract = types.Address{}
+
 func main() {
 	// Parse private key
 	privateKeyHex := "7478e3b73c7f4741dbc94a39dcca55778dab9fcd5eec42e71ad602f2bf67e15f"
@@ -56,18 +69,16 @@ func main() {
 	nonce++
 	fmt.Printf("Using nonce: %d\n", nonce)
 
-	// Create transaction message hash for signing
-	messageToSign := fmt.Sprintf("%s:%s:%s:%s:%s",
-		address.Hex(),
-		"0x",                          // to address (empty for contract deployment)
-		"0",                           // amount as string (zero value) - must be decimal string
-		strconv.FormatUint(nonce, 10), // nonce as string
-		"0x"+bytecodeStr,              // data
-	)
-
-	// Sign the message
-	messageHash := crypto.Keccak256Hash([]byte(messageToSign))
-	signature, err := crypto.Sign(messageHash.Bytes(), privKey)
+	// Sign the canonical encoding of the transaction the server will rebuild from the parameters
+	signature, err := state.SignTransaction(&state.Transaction{
+		Type:    state.TxType(0),
+		From:    types.FromCommon(address),
+		Amount:  big.NewInt(0),
+		Nonce:   nonce,
+		Data:    common.FromHex(bytecodeStr),
+		Gas:     1000000,
+		ChainID: chainID,
+	}, privateKeyBytes, rollupContract)
 	if err != nil {
 		log.Fatalf("Failed to sign transaction: %v", err)
 	}
@@ -82,6 +93,7 @@ func main() {
 		"data":      "0x" + bytecodeStr,
 		"signature": "0x" + hex.EncodeToString(signature), // Add signature
 		"type":      float64(0),                           // Default transaction type
+		"chainId":   float64(chainID),
 	}
 
 	// Send transaction
@@ -324,15 +336,8 @@ func sendJSONRPCRequest(url string, req map[string]interface{}) ([]byte, error)
 
 // generateContractAddress generates a contract address from the sender address and nonce
 func generateContractAddress(sender string, nonce uint64) string {
-	// Remove 0x prefix if present
-	if strings.HasPrefix(sender, "0x") {
-		sender = sender[2:]
-	}
-
 	// Parse sender address
-	senderBytes, _ := hex.DecodeString(sender)
-	var senderAddr [20]byte
-	copy(senderAddr[:], senderBytes)
+	senderAddr := types.MustParseAddress(sender)
 
 	// Generate contract address (simplified version)
 	// In Ethereum, this would be RLP([sender, nonce])
@@ -340,5 +345,5 @@ func generateContractAddress(sender string, nonce uint64) string {
 	contractAddr := crypto.Keccak256(data)
 
 	// Return the last 20 bytes as the contract address
-	return fmt.Sprintf("0x%x", contractAddr[12:])
+	return types.BytesToAddress(contractAddr).Hex()
 }
@@ -13,8 +13,19 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
 )
 
+// chainID is the chain ID of the local dev chain the transactions are signed for
+const chainID = core.DevChainID
+
+// rollupContract is the rollup contract the transactions are signed for, dev chains run
+// without one
+var rollupContract = types.Address{}
+
 // This script interacts with the deployed SimpleStorage contract
 func main() {
 	// Parse private key
@@ -64,18 +75,17 @@ func main() {
 		log.Fatalf("Failed to pack data: %v", err)
 	}
 
-	// Create message to sign for the 'set' function
-	messageToSign := fmt.Sprintf("%s:%s:%s:%s:%s",
-		address.Hex(),
-		contractAddress,
-		"0",                           // amount as string (zero value) - must be decimal string
-		strconv.FormatUint(nonce, 10), // nonce as string
-		"0x"+hex.EncodeToString(data), // data
-	)
-
-	// Sign the message
-	messageHash := crypto.Keccak256Hash([]byte(messageToSign))
-	signature, err := crypto.Sign(messageHash.Bytes(), privKey)
+	// Sign the canonical encoding of the 'set' call
+	signature, err := state.SignTransaction(&state.Transaction{
+		Type:    state.TxType(1),
+		From:    types.FromCommon(address),
+		To:      types.MustParseAddress(contractAddress),
+		Amount:  big.NewInt(0),
+		Nonce:   nonce,
+		Data:    data,
+		Gas:     100000,
+		ChainID: chainID,
+	}, privateKeyBytes, rollupContract)
 	if err != nil {
 		log.Fatalf("Failed to sign transaction: %v", err)
 	}
@@ -90,6 +100,7 @@ func main() {
 		"data":      "0x" + hex.EncodeToString(data),
 		"signature": "0x" + hex.EncodeToString(signature), // Add proper signature
 		"type":      float64(1),                           // Contract call
+		"chainId":   float64(chainID),
 	}
 
 	// Send transaction
@@ -108,18 +119,17 @@ func main() {
 		log.Fatalf("Failed to pack data: %v", err)
 	}
 
-	// Create message to sign for the 'get' function
-	messageToSign = fmt.Sprintf("%s:%s:%s:%s:%s",
-		address.Hex(),
-		contractAddress,
-		"0",                           // amount as string (zero value) - must be decimal string
-		strconv.FormatUint(nonce, 10), // nonce as string
-		"0x"+hex.EncodeToString(data), // data
-	)
-
-	// Sign the message
-	messageHash = crypto.Keccak256Hash([]byte(messageToSign))
-	signature, err = crypto.Sign(messageHash.Bytes(), privKey)
+	// Sign the canonical encoding of the 'get' call
+	signature, err = state.SignTransaction(&state.Transaction{
+		Type:    state.TxType(2),
+		From:    types.FromCommon(address),
+		To:      types.MustParseAddress(contractAddress),
+		Amount:  big.NewInt(0),
+		Nonce:   nonce,
+		Data:    data,
+		Gas:     100000,
+		ChainID: chainID,
+	}, privateKeyBytes, rollupContract)
 	if err != nil {
 		log.Fatalf("Failed to sign transaction: %v", err)
 	}
@@ -134,6 +144,7 @@ func main() {
 		"data":      "0x" + hex.EncodeToString(data),
 		"signature": "0x" + hex.EncodeToString(signature), // Add proper signature
 		"type":      float64(2),                           // Contract call (view function)
+		"chainId":   float64(chainID),
 	}
 
 	// Send transaction
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"zkrollup/pkg/l1"
+	"zkrollup/pkg/l1/contracts"
+)
+
+func main() {
+	// Parse command line flags
+	address := flag.String("address", "", "Address that withdrew on the rollup")
+	batch := flag.String("batch", "latest", "Rollup batch number to prove the withdrawal against, or latest")
+	rollupURL := flag.String("rollup", "http://localhost:9000", "Rollup RPC URL")
+	privateKey := flag.String("privatekey", "", "Private key of the L1 account paying for the claim (hex format without 0x prefix)")
+	rpcURL := flag.String("rpc", "http://localhost:8545", "Ethereum RPC URL")
+	chainID := flag.Int64("chainid", 1337, "Ethereum chain ID")
+	contract := flag.String("contract", "", "Address of the ZK-Rollup contract")
+	wait := flag.Duration("wait", 10*time.Minute, "How long to wait for the batch to be accepted on L1 and for the claim to be mined")
+	dryRun := flag.Bool("dry-run", false, "Print the claimWithdrawal calldata without submitting it")
+	flag.Parse()
+
+	if !common.IsHexAddress(*address) {
+		log.Fatal("Invalid address. Use -address flag.")
+	}
+
+	// Fetch the proof from the rollup node
+	var batchParam interface{} = "latest"
+	if *batch != "latest" {
+		n, ok := new(big.Int).SetString(*batch, 10)
+		if !ok || !n.IsUint64() {
+			log.Fatalf("Invalid batch number: %s", *batch)
+		}
+		batchParam = n.Uint64()
+	}
+
+	var proof withdrawalProof
+	if err := rollupCall(*rollupURL, "rollup_getWithdrawalProof", []interface{}{*address, batchParam}, &proof); err != nil {
+		log.Fatalf("Failed to get withdrawal proof: %v", err)
+	}
+
+	claim, err := proof.claim()
+	if err != nil {
+		log.Fatalf("Invalid withdrawal proof: %v", err)
+	}
+
+	calldata, err := claim.Calldata()
+	if err != nil {
+		log.Fatalf("Failed to encode claim: %v", err)
+	}
+
+	fmt.Printf("Account:     %s\n", claim.Account.Hex())
+	fmt.Printf("Withdrawn:   %s wei\n", claim.Amount)
+	fmt.Printf("Batch:       %d\n", claim.BatchNumber)
+	fmt.Printf("State root:  %s\n", proof.StateRoot)
+	fmt.Printf("Calldata:    %s\n", hexutil.Encode(calldata))
+
+	if *dryRun {
+		return
+	}
+
+	if *privateKey == "" {
+		log.Fatal("Private key is required. Use -privatekey flag.")
+	}
+	if !common.IsHexAddress(*contract) {
+		log.Fatal("Invalid contract address. Use -contract flag.")
+	}
+
+	client, err := l1.NewClient(&l1.Config{
+		EthereumRPC:     *rpcURL,
+		ChainID:         *chainID,
+		ContractAddress: *contract,
+		PrivateKey:      *privateKey,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create L1 client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *wait)
+	defer cancel()
+
+	withdrawn, err := client.Withdrawn(ctx, claim.Account)
+	if err != nil {
+		log.Fatalf("Failed to get claimed amount: %v", err)
+	}
+	if withdrawn.Cmp(claim.Amount) >= 0 {
+		fmt.Printf("Nothing to claim, %s wei already paid out on L1\n", withdrawn)
+		return
+	}
+
+	if err := waitForBatch(ctx, client, claim.BatchNumber, common.HexToHash(proof.StateRoot)); err != nil {
+		log.Fatalf("Batch %d is not claimable: %v", claim.BatchNumber, err)
+	}
+
+	tx, err := client.ClaimWithdrawal(ctx, claim)
+	if err != nil {
+		log.Fatalf("Failed to submit claim: %v", err)
+	}
+	fmt.Printf("Claim submitted: %s\n", tx.Hash().Hex())
+
+	receipt, err := client.WaitForReceipt(ctx, tx)
+	if err != nil {
+		log.Fatalf("Failed to wait for claim: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Fatalf("Claim reverted in block %d", receipt.BlockNumber)
+	}
+	fmt.Printf("Claimed %s wei in block %d\n", new(big.Int).Sub(claim.Amount, withdrawn), receipt.BlockNumber)
+}
+
+// waitForBatch polls L1 until the batch is accepted with the proven state root and, when
+// the contract has a verifier, until its proof is verified
+func waitForBatch(ctx context.Context, client *l1.Client, batchNumber uint64, stateRoot common.Hash) error {
+	needsProof, err := client.HasVerifier(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		status, err := client.GetBatchStatus(ctx, batchNumber)
+		if err != nil {
+			return err
+		}
+		if status.State != l1.BatchUnknown && status.StateRoot != stateRoot {
+			return fmt.Errorf("L1 state root %s does not match proof state root %s", status.StateRoot.Hex(), stateRoot.Hex())
+		}
+		if status.State >= l1.BatchVerified || (status.State == l1.BatchSubmitted && !needsProof) {
+			return nil
+		}
+
+		fmt.Printf("Waiting for batch %d on L1, currently %s\n", batchNumber, status.State)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// merkleProof is a sparse Merkle tree proof as returned by the rollup node
+type merkleProof struct {
+	Bitmap   string   `json:"bitmap"`
+	Siblings []string `json:"siblings"`
+}
+
+// withdrawalProof is the result of rollup_getWithdrawalProof
+type withdrawalProof struct {
+	Account           string      `json:"account"`
+	Amount            string      `json:"amount"`
+	BatchNumber       uint64      `json:"batchNumber"`
+	StateRoot         string      `json:"stateRoot"`
+	BridgeBalance     string      `json:"bridgeBalance"`
+	BridgeNonce       uint64      `json:"bridgeNonce"`
+	BridgeStorageRoot string      `json:"bridgeStorageRoot"`
+	BridgeCodeHash    string      `json:"bridgeCodeHash"`
+	AccountProof      merkleProof `json:"accountProof"`
+	StorageProof      merkleProof `json:"storageProof"`
+}
+
+// claim converts the proof to the arguments of the L1 claimWithdrawal method
+func (p *withdrawalProof) claim() (*l1.WithdrawalClaim, error) {
+	amount, ok := new(big.Int).SetString(p.Amount, 10)
+	if !ok || amount.Sign() == 0 {
+		return nil, fmt.Errorf("account %s has not withdrawn anything as of batch %d", p.Account, p.BatchNumber)
+	}
+	balance, ok := new(big.Int).SetString(p.BridgeBalance, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid bridge balance %q", p.BridgeBalance)
+	}
+
+	accountProof, err := p.AccountProof.decode()
+	if err != nil {
+		return nil, fmt.Errorf("invalid account proof: %v", err)
+	}
+	storageProof, err := p.StorageProof.decode()
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage proof: %v", err)
+	}
+
+	return &l1.WithdrawalClaim{
+		BatchNumber: p.BatchNumber,
+		Account:     common.HexToAddress(p.Account),
+		Amount:      amount,
+		Bridge: contracts.ZKRollupBridgeAccount{
+			Balance:     balance,
+			Nonce:       new(big.Int).SetUint64(p.BridgeNonce),
+			StorageRoot: common.HexToHash(p.BridgeStorageRoot),
+			CodeHash:    common.HexToHash(p.BridgeCodeHash),
+		},
+		AccountProof: accountProof,
+		StorageProof: storageProof,
+	}, nil
+}
+
+// decode converts the proof to its contract form
+func (p *merkleProof) decode() (contracts.ZKRollupMerkleProof, error) {
+	bitmap, err := hexutil.Decode(p.Bitmap)
+	if err != nil || len(bitmap) != 32 {
+		return contracts.ZKRollupMerkleProof{}, fmt.Errorf("invalid bitmap %q", p.Bitmap)
+	}
+	proof := contracts.ZKRollupMerkleProof{
+		Bitmap:   common.BytesToHash(bitmap),
+		Siblings: make([][32]byte, 0, len(p.Siblings)),
+	}
+	for _, s := range p.Siblings {
+		sibling, err := hexutil.Decode(s)
+		if err != nil || len(sibling) != 32 {
+			return contracts.ZKRollupMerkleProof{}, fmt.Errorf("invalid sibling %q", s)
+		}
+		proof.Siblings = append(proof.Siblings, common.BytesToHash(sibling))
+	}
+	return proof, nil
+}
+
+// rollupCall makes a JSON-RPC call to the rollup node
+func rollupCall(url, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return fmt.Errorf("failed to parse response: %w, body: %s", err, string(respBody))
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("RPC error: %d - %s", rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
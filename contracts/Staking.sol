@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.19;
+
+/**
+ * @title Staking
+ * @dev Bonds the stake of consensus validators. A validator stakes from the address that
+ * signs its consensus messages and names the libp2p peer ID of its node, nodes take every
+ * validator holding at least minStake as the consensus set. Reports of double signing are
+ * recorded by anyone with the conflicting messages, the slasher confirms them after
+ * checking the signatures off-chain and the stake is forfeited.
+ */
+contract Staking {
+    struct Validator {
+        string peerId;
+        uint256 stake;
+        uint256 unbondingAt; // Time the stake may be withdrawn, 0 while bonded
+        bool slashed;
+    }
+
+    // Stake a validator must hold to be in the consensus set
+    uint256 public immutable minStake;
+
+    // Time between requesting to unbond and withdrawing, evidence may arrive meanwhile
+    uint256 public immutable unbondingPeriod;
+
+    // Account confirming double signing reports, receives slashed stake
+    address public slasher;
+
+    // Validators by signing address, in staking order
+    mapping(address => Validator) public validators;
+    address[] public validatorList;
+
+    // Validator reported by the hash of each double signing evidence, and whether the
+    // report was acted on
+    mapping(bytes32 => address) public reports;
+    mapping(bytes32 => bool) public settled;
+
+    event Staked(address indexed validator, string peerId, uint256 amount, uint256 total);
+    event UnbondRequested(address indexed validator, uint256 withdrawableAt);
+    event Withdrawn(address indexed validator, uint256 amount);
+    event DoubleSignReported(address indexed validator, address indexed reporter, bytes32 indexed evidenceHash, bytes evidence);
+    event Slashed(address indexed validator, bytes32 indexed evidenceHash, uint256 amount);
+    event SlasherChanged(address indexed previousSlasher, address indexed newSlasher);
+
+    modifier onlySlasher() {
+        require(msg.sender == slasher, "Caller is not the slasher");
+        _;
+    }
+
+    constructor(uint256 _minStake, uint256 _unbondingPeriod, address _slasher) {
+        require(_slasher != address(0), "Invalid slasher");
+        minStake = _minStake;
+        unbondingPeriod = _unbondingPeriod;
+        slasher = _slasher;
+    }
+
+    /**
+     * @dev Bond the value sent to the caller's stake
+     * @param peerId The libp2p peer ID of the caller's node, only used by the first stake
+     */
+    function stake(string calldata peerId) external payable {
+        require(msg.value > 0, "No stake sent");
+        Validator storage v = validators[msg.sender];
+        require(!v.slashed, "Validator was slashed");
+        require(v.unbondingAt == 0, "Validator is unbonding");
+
+        if (bytes(v.peerId).length == 0) {
+            require(bytes(peerId).length > 0, "Invalid peer ID");
+            v.peerId = peerId;
+            validatorList.push(msg.sender);
+        }
+        v.stake += msg.value;
+        emit Staked(msg.sender, v.peerId, msg.value, v.stake);
+    }
+
+    /**
+     * @dev Leave the consensus set, the stake is withdrawable after the unbonding period
+     */
+    function requestUnbond() external {
+        Validator storage v = validators[msg.sender];
+        require(v.stake > 0, "Nothing staked");
+        require(v.unbondingAt == 0, "Already unbonding");
+
+        v.unbondingAt = block.timestamp + unbondingPeriod;
+        emit UnbondRequested(msg.sender, v.unbondingAt);
+    }
+
+    /**
+     * @dev Withdraw the stake once the unbonding period passed
+     */
+    function withdraw() external {
+        Validator storage v = validators[msg.sender];
+        require(v.unbondingAt != 0 && block.timestamp >= v.unbondingAt, "Stake is bonded");
+        require(!v.slashed, "Validator was slashed");
+
+        uint256 amount = v.stake;
+        v.stake = 0;
+        v.unbondingAt = 0;
+        (bool ok, ) = msg.sender.call{value: amount}("");
+        require(ok, "Transfer failed");
+        emit Withdrawn(msg.sender, amount);
+    }
+
+    /**
+     * @dev Return the validators in the consensus set: bonded, not slashed and holding at
+     * least minStake
+     */
+    function getValidators() external view returns (address[] memory addrs, string[] memory peerIds, uint256[] memory stakes) {
+        uint256 count;
+        for (uint256 i = 0; i < validatorList.length; i++) {
+            if (isActive(validatorList[i])) {
+                count++;
+            }
+        }
+
+        addrs = new address[](count);
+        peerIds = new string[](count);
+        stakes = new uint256[](count);
+        uint256 j;
+        for (uint256 i = 0; i < validatorList.length; i++) {
+            address a = validatorList[i];
+            if (isActive(a)) {
+                addrs[j] = a;
+                peerIds[j] = validators[a].peerId;
+                stakes[j] = validators[a].stake;
+                j++;
+            }
+        }
+    }
+
+    /**
+     * @dev Report a validator for signing two conflicting consensus messages. The evidence
+     * is published in full for the slasher and anyone else to check.
+     * @param validator Signing address of the validator
+     * @param evidence JSON encoded double signing evidence
+     */
+    function reportDoubleSign(address validator, bytes calldata evidence) external {
+        require(validators[validator].stake > 0, "Not a validator");
+        bytes32 evidenceHash = keccak256(evidence);
+        require(reports[evidenceHash] == address(0), "Already reported");
+
+        reports[evidenceHash] = validator;
+        emit DoubleSignReported(validator, msg.sender, evidenceHash, evidence);
+    }
+
+    /**
+     * @dev Confirm a double signing report, forfeiting the validator's whole stake
+     * @param evidenceHash Keccak256 hash of the reported evidence
+     */
+    function slash(bytes32 evidenceHash) external onlySlasher {
+        address validator = reports[evidenceHash];
+        require(validator != address(0), "Unknown report");
+        require(!settled[evidenceHash], "Report already settled");
+        settled[evidenceHash] = true;
+
+        Validator storage v = validators[validator];
+        uint256 amount = v.stake;
+        v.stake = 0;
+        v.slashed = true;
+        if (amount > 0) {
+            (bool ok, ) = slasher.call{value: amount}("");
+            require(ok, "Transfer failed");
+        }
+        emit Slashed(validator, evidenceHash, amount);
+    }
+
+    /**
+     * @dev Hand the slasher role to another account
+     */
+    function setSlasher(address newSlasher) external onlySlasher {
+        require(newSlasher != address(0), "Invalid slasher");
+        emit SlasherChanged(slasher, newSlasher);
+        slasher = newSlasher;
+    }
+
+    function isActive(address a) internal view returns (bool) {
+        Validator storage v = validators[a];
+        return !v.slashed && v.unbondingAt == 0 && v.stake >= minStake;
+    }
+}
@@ -1,6 +1,22 @@
 // SPDX-License-Identifier: MIT
 pragma solidity ^0.8.19;
 
+/**
+ * @dev Groth16 verifier exported by gnark for the batch circuit, reverts on an invalid proof
+ */
+interface IBatchVerifier {
+    function verifyProof(uint256[8] calldata proof, uint256[3] calldata input) external view;
+}
+
+/**
+ * @dev Groth16 verifier for the aggregation circuit, which checks the proofs of several
+ * consecutive batches at once. Its inputs are the old root, then each batch's new root, then
+ * each batch's commitment. Reverts on an invalid proof.
+ */
+interface IAggregateVerifier {
+    function verifyProof(uint256[8] calldata proof, uint256[] calldata input) external view;
+}
+
 /**
  * @title ZKRollup
  * @dev A ZK-Rollup contract that stores batch state roots and verifies ZK proofs
@@ -11,50 +27,602 @@ contract ZKRollup {
         bytes32 stateRoot;
         bool verified;
         uint256 timestamp;
+        uint256 l1Block;
     }
 
+    // BN254 scalar field, public inputs are state roots reduced into it
+    uint256 internal constant SNARK_SCALAR_FIELD =
+        21888242871839275222246405745257275088548364400416034343698204186575808495617;
+
+    // Verifier for batch proofs, the zero address accepts batches without verifying them
+    IBatchVerifier public immutable verifier;
+
+    // Hash of the schema fixing the order of the verifier's public inputs, the sequencer
+    // refuses to submit proofs generated under another schema
+    bytes32 public immutable publicInputSchema;
+
+    // Verifier registered for a CRS epoch, proofs name the epoch whose keys produced them
+    struct VerifierEntry {
+        IBatchVerifier verifier;
+        bytes32 inputSchema;
+        uint64 circuitVersion;
+        bool registered;
+    }
+
+    // Verifiers by CRS epoch, entries are never replaced so old proofs stay verifiable
+    mapping(uint256 => VerifierEntry) public verifierRegistry;
+
+    // Most recently registered epoch
+    uint256 public latestEpoch;
+
+    // Whether any epoch was registered
+    bool public hasRegisteredVerifier;
+
+    // Epoch of the verifier each batch was proven with through the registry
+    mapping(uint256 => uint256) public batchEpoch;
+
+    // Verifier for aggregated proofs, set by the guardian, the zero address disables aggregation
+    IAggregateVerifier public aggregateVerifier;
+
+    // Hash of the schema of the aggregate verifier's public inputs
+    bytes32 public aggregateInputSchema;
+
+    // Number of batches every aggregated proof covers
+    uint256 public aggregateBatchCount;
+
+    // Hash of each batch's published transactions: the keccak256 of the calldata posted with
+    // publishBatchData, or the versioned hash of the blob posted with publishBatchBlob
+    mapping(uint256 => bytes32) public batchDataHash;
+
+    // State root of the last accepted batch, the next proof must start from it
+    bytes32 public lastStateRoot;
+
     // Mapping from batch number to batch data
     mapping(uint256 => Batch) public batches;
     
     // Current batch number
     uint256 public currentBatchNumber;
 
+    // Number of deposits made, each deposit is credited on L2 exactly once by its nonce
+    uint256 public depositCount;
+
+    // Batch posted without a proof, the proof must start from the root it was posted on
+    struct PendingProof {
+        bool pending;
+        bool hasParent;
+        bytes32 parentRoot;
+    }
+
+    // Batches awaiting their proof by batch number
+    mapping(uint256 => PendingProof) internal pendingProofs;
+
+    // Rollup account receiving withdrawn funds, its storage holds the total each account withdrew
+    address internal constant WITHDRAWAL_ADDRESS = address(uint160(0xee));
+
+    // Levels of the rollup's sparse Merkle trees, one per key bit
+    uint256 internal constant SMT_DEPTH = 256;
+
+    // Fields of the withdrawal account as committed in the rollup state tree
+    struct BridgeAccount {
+        uint256 balance;
+        uint256 nonce;
+        bytes32 storageRoot;
+        bytes32 codeHash;
+    }
+
+    // Sparse Merkle proof, siblings run from the leaf upwards and bit i of the bitmap marks
+    // a non-empty sibling at height i
+    struct MerkleProof {
+        bytes32 bitmap;
+        bytes32[] siblings;
+    }
+
+    // Total paid out to each account, claims pay the difference to the proven total
+    mapping(address => uint256) public withdrawn;
+
+    // Account allowed to pause batch acceptance and freeze the bridge in an emergency
+    address public guardian;
+
+    // No batches or proofs are accepted while paused
+    bool public paused;
+
+    // No deposits or withdrawal claims are accepted while the bridge is frozen
+    bool public bridgeFrozen;
+
+    // Consensus validators by libp2p peer ID, managed by the guardian. Nodes reading the set
+    // from here switch to a new one at the next decided batch.
+    string[] private validators;
+    mapping(bytes32 => uint256) private validatorIndex; // keccak256(peerId) => index + 1
+
     // Events
     event BatchSubmitted(uint256 indexed batchNumber, bytes32 indexed stateRoot, uint256 timestamp);
     event BatchVerified(uint256 indexed batchNumber, bool indexed verified);
+    event Deposit(address indexed from, address indexed l2Recipient, uint256 amount, uint256 indexed depositNonce);
+    event WithdrawalClaimed(address indexed account, uint256 indexed batchNumber, uint256 amount);
+    event Paused(address indexed guardian);
+    event Unpaused(address indexed guardian);
+    event BridgeFrozen(address indexed guardian);
+    event BridgeUnfrozen(address indexed guardian);
+    event GuardianChanged(address indexed previousGuardian, address indexed newGuardian);
+    event ValidatorAdded(string peerId);
+    event ValidatorRemoved(string peerId);
+    event VerifierRegistered(uint256 indexed epoch, address verifier, bytes32 inputSchema, uint64 circuitVersion);
+    event AggregateVerifierChanged(address verifier, bytes32 inputSchema, uint256 batchCount);
+    event BatchDataPublished(uint256 indexed batchNumber, bytes32 dataHash, bool blob);
 
-    constructor() {
+    modifier onlyGuardian() {
+        require(msg.sender == guardian, "Caller is not the guardian");
+        _;
+    }
+
+    modifier whenNotPaused() {
+        require(!paused, "Batch acceptance is paused");
+        _;
+    }
+
+    modifier whenBridgeOpen() {
+        require(!bridgeFrozen, "Bridge is frozen");
+        _;
+    }
+
+    constructor(address verifierAddress, bytes32 inputSchema) {
         // Initialize batch number to 0
         currentBatchNumber = 0;
+        verifier = IBatchVerifier(verifierAddress);
+        require(verifierAddress == address(0) || inputSchema != bytes32(0), "Verifier requires a public input schema");
+        publicInputSchema = inputSchema;
+        guardian = msg.sender;
+    }
+
+    /**
+     * @dev Register the verifier for the keys of a new CRS epoch. Epochs must increase and an
+     * epoch's verifier cannot be replaced.
+     * @param epoch The CRS epoch the verifier's keys come from
+     * @param verifierAddress The verifier contract
+     * @param inputSchema Hash of the schema ordering the verifier's public inputs
+     * @param circuitVersion Version of the circuit the keys were generated for
+     */
+    function registerVerifier(
+        uint256 epoch,
+        address verifierAddress,
+        bytes32 inputSchema,
+        uint64 circuitVersion
+    ) external onlyGuardian {
+        require(verifierAddress != address(0), "Verifier address required");
+        require(inputSchema != bytes32(0), "Verifier requires a public input schema");
+        require(!hasRegisteredVerifier || epoch > latestEpoch, "Epoch already registered or older than the latest");
+
+        verifierRegistry[epoch] = VerifierEntry({
+            verifier: IBatchVerifier(verifierAddress),
+            inputSchema: inputSchema,
+            circuitVersion: circuitVersion,
+            registered: true
+        });
+        latestEpoch = epoch;
+        hasRegisteredVerifier = true;
+
+        emit VerifierRegistered(epoch, verifierAddress, inputSchema, circuitVersion);
+    }
+
+    /**
+     * @dev Set the verifier for aggregated proofs, the zero address disables aggregation
+     * @param verifierAddress The verifier contract of the aggregation circuit
+     * @param inputSchema Hash of the schema ordering the verifier's public inputs
+     * @param batchCount Number of batches each aggregated proof covers
+     */
+    function setAggregateVerifier(
+        address verifierAddress,
+        bytes32 inputSchema,
+        uint256 batchCount
+    ) external onlyGuardian {
+        require(verifierAddress == address(0) || (inputSchema != bytes32(0) && batchCount >= 2), "Aggregation requires a schema and at least 2 batches");
+
+        aggregateVerifier = IAggregateVerifier(verifierAddress);
+        aggregateInputSchema = inputSchema;
+        aggregateBatchCount = batchCount;
+
+        emit AggregateVerifierChanged(verifierAddress, inputSchema, batchCount);
+    }
+
+    /**
+     * @dev Stop accepting batches and proofs
+     */
+    function pause() external onlyGuardian {
+        paused = true;
+        emit Paused(msg.sender);
+    }
+
+    /**
+     * @dev Resume accepting batches and proofs
+     */
+    function unpause() external onlyGuardian {
+        paused = false;
+        emit Unpaused(msg.sender);
+    }
+
+    /**
+     * @dev Stop accepting deposits and withdrawal claims
+     */
+    function freezeBridge() external onlyGuardian {
+        bridgeFrozen = true;
+        emit BridgeFrozen(msg.sender);
+    }
+
+    /**
+     * @dev Resume accepting deposits and withdrawal claims
+     */
+    function unfreezeBridge() external onlyGuardian {
+        bridgeFrozen = false;
+        emit BridgeUnfrozen(msg.sender);
+    }
+
+    /**
+     * @dev Hand the guardian role to another account
+     * @param newGuardian The new guardian
+     */
+    function setGuardian(address newGuardian) external onlyGuardian {
+        require(newGuardian != address(0), "Invalid guardian");
+        emit GuardianChanged(guardian, newGuardian);
+        guardian = newGuardian;
+    }
+
+    /**
+     * @dev Add a node to the consensus validator set
+     * @param peerId The libp2p peer ID of the node
+     */
+    function addValidator(string calldata peerId) external onlyGuardian {
+        bytes32 key = keccak256(bytes(peerId));
+        require(bytes(peerId).length > 0, "Invalid peer ID");
+        require(validatorIndex[key] == 0, "Already a validator");
+
+        validators.push(peerId);
+        validatorIndex[key] = validators.length;
+        emit ValidatorAdded(peerId);
+    }
+
+    /**
+     * @dev Remove a node from the consensus validator set, which must keep one validator
+     * @param peerId The libp2p peer ID of the node
+     */
+    function removeValidator(string calldata peerId) external onlyGuardian {
+        bytes32 key = keccak256(bytes(peerId));
+        uint256 index = validatorIndex[key];
+        require(index != 0, "Not a validator");
+        require(validators.length > 1, "Cannot remove the last validator");
+
+        // Move the last validator into the freed slot
+        string memory last = validators[validators.length - 1];
+        validators[index - 1] = last;
+        validatorIndex[keccak256(bytes(last))] = index;
+        validators.pop();
+        delete validatorIndex[key];
+        emit ValidatorRemoved(peerId);
+    }
+
+    /**
+     * @dev The current consensus validator set
+     */
+    function getValidators() external view returns (string[] memory) {
+        return validators;
     }
 
     /**
-     * @dev Submit a new batch with state root and transaction hashes
+     * @dev Submit a new batch with state root and transaction hashes. The batch is only
+     * accepted if its Groth16 proof verifies against the previous and new state roots.
+     * Batch numbers must increase but may skip batches that are not posted, such as
+     * empty heartbeat batches, which leave the state root unchanged.
      * @param batchNumber The batch number
      * @param stateRoot The state root of the batch
      * @param txHashes The transaction hashes in the batch
-     * @param proof The ZK proof for the batch
+     * @param proof The Groth16 proof (Ar, Bs, Krs) for the batch
+     * @param publicInputs The proof's public inputs: old root, new root, batch commitment
      */
     function submitBatch(
         uint256 batchNumber,
         bytes32 stateRoot,
         bytes32[] memory txHashes,
-        bytes memory proof
-    ) external {
+        uint256[8] calldata proof,
+        uint256[3] calldata publicInputs
+    ) external whenNotPaused {
         // Validate batch number
-        uint256 expectedBatchNumber = currentBatchNumber + 1;
-        require(batchNumber > 0 && batchNumber == expectedBatchNumber, "Invalid batch configuration");
+        require(batchNumber > currentBatchNumber, "Invalid batch configuration");
+
+        bool verified = false;
+        if (address(verifier) != address(0)) {
+            // The proof must cover the transition from the last accepted root to the new one
+            if (currentBatchNumber > 0) {
+                require(publicInputs[0] == uint256(lastStateRoot) % SNARK_SCALAR_FIELD, "Proof does not start from the last state root");
+            }
+            require(publicInputs[1] == uint256(stateRoot) % SNARK_SCALAR_FIELD, "Proof does not match the state root");
+
+            // Reverts with ProofInvalid if the proof does not verify
+            verifier.verifyProof(proof, publicInputs);
+            verified = true;
+        }
 
-        // Verify the proof (in a real implementation, this would use a ZK verifier contract)
-        bool verified = verifyBatch(batchNumber);
-        
         // Store the batch
-        _storeBatch(batchNumber, stateRoot, true);
+        _storeBatch(batchNumber, stateRoot, verified);
+        lastStateRoot = stateRoot;
 
         // Emit event
         emit BatchSubmitted(batchNumber, stateRoot, block.timestamp);
     }
 
+    /**
+     * @dev Submit a batch proven with the keys of a registered CRS epoch
+     * @param batchNumber The batch number
+     * @param stateRoot The state root of the batch
+     * @param txHashes The transaction hashes in the batch
+     * @param epoch The CRS epoch whose verifier checks the proof
+     * @param proof The Groth16 proof (Ar, Bs, Krs) for the batch
+     * @param publicInputs The proof's public inputs: old root, new root, batch commitment
+     */
+    function submitBatchWithEpoch(
+        uint256 batchNumber,
+        bytes32 stateRoot,
+        bytes32[] memory txHashes,
+        uint256 epoch,
+        uint256[8] calldata proof,
+        uint256[3] calldata publicInputs
+    ) external whenNotPaused {
+        require(batchNumber > currentBatchNumber, "Invalid batch configuration");
+        VerifierEntry memory entry = verifierRegistry[epoch];
+        require(entry.registered, "No verifier registered for epoch");
+
+        if (currentBatchNumber > 0) {
+            require(publicInputs[0] == uint256(lastStateRoot) % SNARK_SCALAR_FIELD, "Proof does not start from the last state root");
+        }
+        require(publicInputs[1] == uint256(stateRoot) % SNARK_SCALAR_FIELD, "Proof does not match the state root");
+        entry.verifier.verifyProof(proof, publicInputs);
+
+        batchEpoch[batchNumber] = epoch;
+        _storeBatch(batchNumber, stateRoot, true);
+        lastStateRoot = stateRoot;
+
+        emit BatchSubmitted(batchNumber, stateRoot, block.timestamp);
+    }
+
+    /**
+     * @dev Submit consecutive batches with one proof aggregating their batch proofs, so the
+     * pairing check is paid once for all of them
+     * @param batchNumbers The batch numbers, increasing
+     * @param stateRoots The state root of each batch
+     * @param txHashes The transaction hashes of each batch
+     * @param proof The Groth16 proof (Ar, Bs, Krs) of the aggregation circuit
+     * @param publicInputs The old root, each batch's new root, then each batch's commitment
+     */
+    function submitAggregatedBatches(
+        uint256[] calldata batchNumbers,
+        bytes32[] calldata stateRoots,
+        bytes32[][] calldata txHashes,
+        uint256[8] calldata proof,
+        uint256[] calldata publicInputs
+    ) external whenNotPaused {
+        uint256 count = batchNumbers.length;
+        require(address(aggregateVerifier) != address(0), "Aggregation disabled");
+        require(count == aggregateBatchCount, "Wrong number of batches");
+        require(stateRoots.length == count && txHashes.length == count, "Batch arguments differ in length");
+        require(publicInputs.length == 1 + 2 * count, "Wrong number of public inputs");
+
+        if (currentBatchNumber > 0) {
+            require(publicInputs[0] == uint256(lastStateRoot) % SNARK_SCALAR_FIELD, "Proof does not start from the last state root");
+        }
+        for (uint256 i = 0; i < count; i++) {
+            require(publicInputs[1 + i] == uint256(stateRoots[i]) % SNARK_SCALAR_FIELD, "Proof does not match the state root");
+        }
+
+        // Reverts with ProofInvalid if the proof does not verify
+        aggregateVerifier.verifyProof(proof, publicInputs);
+
+        for (uint256 i = 0; i < count; i++) {
+            require(batchNumbers[i] > currentBatchNumber, "Invalid batch configuration");
+            _storeBatch(batchNumbers[i], stateRoots[i], true);
+            emit BatchSubmitted(batchNumbers[i], stateRoots[i], block.timestamp);
+        }
+        lastStateRoot = stateRoots[count - 1];
+    }
+
+    /**
+     * @dev Publish the compressed transactions of a batch ahead of the batch itself, so the
+     * rollup state can be rebuilt from L1 alone. The data stays in the transaction's calldata.
+     * @param batchNumber The batch the transactions belong to
+     * @param data The batch's transactions, encoded and compressed by the sequencer
+     */
+    function publishBatchData(uint256 batchNumber, bytes calldata data) external whenNotPaused {
+        require(batchNumber > currentBatchNumber, "Batch already accepted");
+
+        bytes32 dataHash = keccak256(data);
+        batchDataHash[batchNumber] = dataHash;
+        emit BatchDataPublished(batchNumber, dataHash, false);
+    }
+
+    /**
+     * @dev Publish the transactions of a batch in the first EIP-4844 blob of this transaction
+     * @param batchNumber The batch the transactions belong to
+     */
+    function publishBatchBlob(uint256 batchNumber) external whenNotPaused {
+        require(batchNumber > currentBatchNumber, "Batch already accepted");
+
+        bytes32 dataHash = blobhash(0);
+        require(dataHash != bytes32(0), "Transaction carries no blob");
+        batchDataHash[batchNumber] = dataHash;
+        emit BatchDataPublished(batchNumber, dataHash, true);
+    }
+
+    /**
+     * @dev Post a batch whose proof missed its deadline. The batch is stored unverified and
+     * becomes verified once proveBatch supplies its proof.
+     * @param batchNumber The batch number
+     * @param stateRoot The state root of the batch
+     * @param txHashes The transaction hashes in the batch
+     */
+    function submitBatchData(
+        uint256 batchNumber,
+        bytes32 stateRoot,
+        bytes32[] memory txHashes
+    ) external whenNotPaused {
+        require(batchNumber > currentBatchNumber, "Invalid batch configuration");
+
+        pendingProofs[batchNumber] = PendingProof({
+            pending: true,
+            hasParent: currentBatchNumber > 0,
+            parentRoot: lastStateRoot
+        });
+
+        _storeBatch(batchNumber, stateRoot, false);
+        lastStateRoot = stateRoot;
+
+        emit BatchSubmitted(batchNumber, stateRoot, block.timestamp);
+    }
+
+    /**
+     * @dev Supply the proof of a batch posted through submitBatchData
+     * @param batchNumber The batch number
+     * @param proof The Groth16 proof (Ar, Bs, Krs) for the batch
+     * @param publicInputs The proof's public inputs: old root, new root, batch commitment
+     */
+    function proveBatch(
+        uint256 batchNumber,
+        uint256[8] calldata proof,
+        uint256[3] calldata publicInputs
+    ) external whenNotPaused {
+        PendingProof memory pendingProof = pendingProofs[batchNumber];
+        require(pendingProof.pending, "Batch not pending proof");
+        require(address(verifier) != address(0), "No verifier configured");
+
+        if (pendingProof.hasParent) {
+            require(publicInputs[0] == uint256(pendingProof.parentRoot) % SNARK_SCALAR_FIELD, "Proof does not start from the parent state root");
+        }
+        require(publicInputs[1] == uint256(batches[batchNumber].stateRoot) % SNARK_SCALAR_FIELD, "Proof does not match the state root");
+
+        // Reverts with ProofInvalid if the proof does not verify
+        verifier.verifyProof(proof, publicInputs);
+
+        delete pendingProofs[batchNumber];
+        batches[batchNumber].verified = true;
+        emit BatchVerified(batchNumber, true);
+    }
+
+    /**
+     * @dev Supply the proof of a batch posted through submitBatchData, verified by the
+     * verifier of the epoch whose keys produced it
+     * @param batchNumber The batch number
+     * @param epoch The CRS epoch whose verifier checks the proof
+     * @param proof The Groth16 proof (Ar, Bs, Krs) for the batch
+     * @param publicInputs The proof's public inputs: old root, new root, batch commitment
+     */
+    function proveBatchWithEpoch(
+        uint256 batchNumber,
+        uint256 epoch,
+        uint256[8] calldata proof,
+        uint256[3] calldata publicInputs
+    ) external whenNotPaused {
+        PendingProof memory pendingProof = pendingProofs[batchNumber];
+        require(pendingProof.pending, "Batch not pending proof");
+        VerifierEntry memory entry = verifierRegistry[epoch];
+        require(entry.registered, "No verifier registered for epoch");
+
+        if (pendingProof.hasParent) {
+            require(publicInputs[0] == uint256(pendingProof.parentRoot) % SNARK_SCALAR_FIELD, "Proof does not start from the parent state root");
+        }
+        require(publicInputs[1] == uint256(batches[batchNumber].stateRoot) % SNARK_SCALAR_FIELD, "Proof does not match the state root");
+        entry.verifier.verifyProof(proof, publicInputs);
+
+        delete pendingProofs[batchNumber];
+        batchEpoch[batchNumber] = epoch;
+        batches[batchNumber].verified = true;
+        emit BatchVerified(batchNumber, true);
+    }
+
+    /**
+     * @dev Lock ETH on L1 to be credited to l2Recipient on the rollup
+     * @param l2Recipient The L2 account receiving the deposit
+     */
+    function deposit(address l2Recipient) external payable whenBridgeOpen {
+        require(msg.value > 0, "Deposit amount must be positive");
+
+        uint256 depositNonce = depositCount;
+        depositCount = depositNonce + 1;
+
+        emit Deposit(msg.sender, l2Recipient, msg.value, depositNonce);
+    }
+
+    /**
+     * @dev Pay out funds withdrawn on the rollup. The storage proof shows the total account
+     * withdrew as of a verified batch, the difference to what was already paid is sent.
+     * @param batchNumber The batch whose state root the proofs are against
+     * @param account The account that withdrew on the rollup and receives the funds
+     * @param amount The total account withdrew as of the batch
+     * @param bridge The withdrawal account's fields in the state tree
+     * @param accountProof Proof of the withdrawal account against the batch state root
+     * @param storageProof Proof of the account's withdrawal slot against bridge.storageRoot
+     */
+    function claimWithdrawal(
+        uint256 batchNumber,
+        address account,
+        uint256 amount,
+        BridgeAccount calldata bridge,
+        MerkleProof calldata accountProof,
+        MerkleProof calldata storageProof
+    ) external whenBridgeOpen {
+        Batch memory batch = batches[batchNumber];
+        require(batch.l1Block != 0, "Unknown batch");
+        require(batch.verified || address(verifier) == address(0), "Batch not verified");
+        require(amount > withdrawn[account], "Nothing to claim");
+
+        // The account's slot holds its total withdrawn, like a Solidity mapping entry
+        bytes32 slot = keccak256(abi.encode(account));
+        bytes32 storageRoot = _smtRoot(sha256(abi.encodePacked(slot, bytes32(0))), bytes32(amount), storageProof);
+        require(storageRoot == bridge.storageRoot, "Invalid storage proof");
+
+        bytes32 addrWord = bytes32(uint256(uint160(WITHDRAWAL_ADDRESS)));
+        bytes32 leaf = sha256(abi.encodePacked(
+            sha256(abi.encodePacked(addrWord, bytes32(bridge.balance))),
+            sha256(abi.encodePacked(sha256(abi.encodePacked(bytes32(bridge.nonce), bridge.storageRoot)), bridge.codeHash))
+        ));
+        bytes32 stateRoot = _smtRoot(sha256(abi.encodePacked(addrWord, bytes32(0))), leaf, accountProof);
+        require(stateRoot == batch.stateRoot, "Invalid account proof");
+
+        uint256 payout = amount - withdrawn[account];
+        withdrawn[account] = amount;
+
+        (bool sent, ) = account.call{value: payout}("");
+        require(sent, "Transfer failed");
+
+        emit WithdrawalClaimed(account, batchNumber, payout);
+    }
+
+    /**
+     * @dev Compute the root of a sparse Merkle tree from a leaf and its proof
+     * @param key The leaf's key, bit i from the bottom picks the side at height i
+     * @param leaf The leaf value
+     * @param proof The non-empty siblings of the leaf's path
+     */
+    function _smtRoot(bytes32 key, bytes32 leaf, MerkleProof calldata proof) internal pure returns (bytes32) {
+        bytes32 node = leaf;
+        bytes32 empty = bytes32(0);
+        uint256 next = 0;
+
+        for (uint256 height = 0; height < SMT_DEPTH; height++) {
+            bytes32 sibling = empty;
+            if (uint8(proof.bitmap[height / 8]) & (1 << (height % 8)) != 0) {
+                require(next < proof.siblings.length, "Invalid proof");
+                sibling = proof.siblings[next];
+                next++;
+            }
+
+            if ((uint256(key) >> height) & 1 == 1) {
+                node = sha256(abi.encodePacked(sibling, node));
+            } else {
+                node = sha256(abi.encodePacked(node, sibling));
+            }
+            empty = sha256(abi.encodePacked(empty, empty));
+        }
+
+        require(next == proof.siblings.length, "Invalid proof");
+        return node;
+    }
+
     /**
      * @dev Store a batch in the contract
      * @param batchNumber The batch number
@@ -70,7 +638,8 @@ contract ZKRollup {
         batches[batchNumber] = Batch({
             stateRoot: stateRoot,
             verified: verified,
-            timestamp: block.timestamp
+            timestamp: block.timestamp,
+            l1Block: block.number
         });
 
         // Update current batch number
@@ -86,8 +655,7 @@ contract ZKRollup {
      * @return Whether the batch is verified
      */
     function verifyBatch(uint256 batchNumber) public view returns (bool) {
-        // In a real implementation, this would verify the ZK proof
-        // For now, we just return the verified status
+        // Proofs are verified on submission, so this reports the stored result
         return batches[batchNumber].verified;
     }
 }
@@ -1,43 +1,365 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"zkrollup/pkg/admin"
 	"zkrollup/pkg/core"
+	"zkrollup/pkg/metrics"
 	"zkrollup/pkg/rpc"
 	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/ui"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "state" {
+		runStateCommand(os.Args[2:])
+		return
+	}
+
+	configFile := flag.String("config", "", "YAML config file, environment variables override its settings")
+	dev := flag.Bool("dev", false, "Run a single-node dev chain with instant batches and pre-funded accounts")
+	devAccounts := flag.Int("dev.accounts", 10, "Number of pre-funded dev accounts")
+	devTimestamp := flag.Uint64("dev.timestamp", 0, "Fixed unix timestamp of the first dev batch, 0 uses the clock")
+	identity := flag.String("identity", "", "File holding the node's libp2p key, created if missing")
+	flag.Parse()
+
 	config := core.DefaultConfig()
+	if *dev {
+		config = core.DevConfig()
+	}
 
-	// Get port from environment variable or use default
-	port, err := strconv.Atoi(os.Getenv("SEQUENCER_PORT"))
-	if err != nil {
-		log.Printf("Failed to parse sequencer port, using default port: %v", err)
-		port = config.SequencerPort
+	// Settings are layered: defaults, then the config file, then environment variables,
+	// then command line flags
+	if *configFile != "" {
+		if err := config.LoadFile(*configFile); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
 	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "dev.accounts":
+			config.DevAccounts = *devAccounts
+		case "dev.timestamp":
+			config.DevTimestamp = *devTimestamp
+		case "identity":
+			config.IdentityFile = *identity
+		}
+	})
 
-	// Get RPC port from environment variable or use default
-	rpcPort := port + 1000
+	// Get port from environment variable or use the configured one
+	if sequencerPort := os.Getenv("SEQUENCER_PORT"); sequencerPort != "" {
+		if port, err := strconv.Atoi(sequencerPort); err == nil {
+			config.SequencerPort = port
+		} else {
+			log.Printf("Failed to parse sequencer port, using port %d: %v", config.SequencerPort, err)
+		}
+	}
 
 	// Get bootstrap peers from environment variable
-	var bootstrapPeers []string
 	if peers := os.Getenv("BOOTSTRAP_PEERS"); peers != "" {
-		bootstrapPeers = strings.Split(peers, ",")
+		config.BootstrapPeers = strings.Split(peers, ",")
+	}
+
+	// Get consensus peer allowlist from environment variable
+	if peers := os.Getenv("CONSENSUS_PEERS"); peers != "" {
+		config.ConsensusPeers = strings.Split(peers, ",")
+	}
+
+	// Get the consensus validator set from environment variables
+	if validators := os.Getenv("VALIDATORS"); validators != "" {
+		config.Validators = strings.Split(validators, ",")
+	}
+	if registry := os.Getenv("VALIDATOR_REGISTRY"); registry != "" {
+		config.ValidatorRegistry = registry == "true"
+	}
+	if staking := os.Getenv("STAKING_CONTRACT"); staking != "" {
+		config.StakingContract = staking
+	}
+
+	// Get the node identity from environment variables, --identity takes precedence
+	if identityFile := os.Getenv("IDENTITY_FILE"); identityFile != "" && *identity == "" {
+		config.IdentityFile = identityFile
+	}
+	if passphrase, ok := os.LookupEnv("IDENTITY_PASSPHRASE"); ok {
+		config.IdentityPassphrase = passphrase
 	}
 
 	// Check if this node is a leader
-	isLeader := os.Getenv("IS_LEADER") == "true"
+	if leader := os.Getenv("IS_LEADER"); leader != "" {
+		config.IsLeader = leader == "true"
+	}
+
+	// State storage configuration
+	if backend := os.Getenv("STATE_BACKEND"); backend != "" {
+		config.StateBackend = backend
+	}
+	if hash := os.Getenv("STATE_HASH"); hash != "" {
+		config.StateHash = hash
+	}
+	if dbPath := os.Getenv("STATE_DB_PATH"); dbPath != "" {
+		config.StateDBPath = dbPath
+	}
+	if policy := os.Getenv("STATE_COMMIT_POLICY"); policy != "" {
+		config.StateCommitPolicy = policy
+	}
+	if interval := os.Getenv("STATE_COMMIT_INTERVAL"); interval != "" {
+		if n, err := strconv.Atoi(interval); err == nil {
+			config.StateCommitInterval = n
+		}
+	}
+
+	if archive := os.Getenv("STATE_ARCHIVE"); archive != "" {
+		config.StateArchive = archive == "true"
+	}
+	if retain := os.Getenv("STATE_ARCHIVE_RETAIN"); retain != "" {
+		if n, err := strconv.ParseUint(retain, 10, 64); err == nil {
+			config.StateArchiveRetain = n
+		}
+	}
+
+	if warmup := os.Getenv("SYNC_WARMUP_SECONDS"); warmup != "" {
+		if seconds, err := strconv.Atoi(warmup); err == nil {
+			config.SyncWarmupSeconds = seconds
+		}
+	}
+	if stateSync := os.Getenv("STATE_SYNC"); stateSync != "" {
+		config.StateSyncEnabled = stateSync == "true"
+	}
+	if fastSync := os.Getenv("FAST_SYNC"); fastSync != "" {
+		config.FastSync = fastSync == "true"
+	}
+	if interval := os.Getenv("FAST_SYNC_SNAPSHOT_INTERVAL"); interval != "" {
+		if n, err := strconv.ParseUint(interval, 10, 64); err == nil {
+			config.FastSyncSnapshotInterval = n
+		}
+	}
+	if heartbeat := os.Getenv("HEARTBEAT_INTERVAL_SECONDS"); heartbeat != "" {
+		if seconds, err := strconv.Atoi(heartbeat); err == nil {
+			config.HeartbeatIntervalSeconds = seconds
+		}
+	}
+	if postToL1 := os.Getenv("HEARTBEAT_POST_TO_L1"); postToL1 != "" {
+		config.HeartbeatPostToL1 = postToL1 == "true"
+	}
+
+	if threshold := os.Getenv("BATCH_GAS_THRESHOLD"); threshold != "" {
+		if gas, err := strconv.ParseUint(threshold, 10, 64); err == nil {
+			config.BatchGasThreshold = gas
+		}
+	}
+	if minInterval := os.Getenv("MIN_BATCH_INTERVAL_MS"); minInterval != "" {
+		if ms, err := strconv.Atoi(minInterval); err == nil {
+			config.MinBatchIntervalMillis = ms
+		}
+	}
+
+	if workers := os.Getenv("PROVER_WORKERS"); workers != "" {
+		if n, err := strconv.Atoi(workers); err == nil {
+			config.ProverWorkers = n
+		}
+	}
+	if threads := os.Getenv("PROVER_THREADS"); threads != "" {
+		if n, err := strconv.Atoi(threads); err == nil {
+			config.ProverThreads = n
+		}
+	}
+	if gpu := os.Getenv("PROVER_GPU"); gpu != "" {
+		config.ProverGPU = gpu == "true"
+	}
+
+	if pk, ok := os.LookupEnv("PROVING_KEY_FILE"); ok {
+		config.ProvingKeyFile = pk
+	}
+	if vk, ok := os.LookupEnv("VERIFYING_KEY_FILE"); ok {
+		config.VerifyingKeyFile = vk
+	}
+	if epoch := os.Getenv("PROVING_KEY_EPOCH"); epoch != "" {
+		if n, err := strconv.ParseUint(epoch, 10, 64); err == nil {
+			config.ProvingKeyEpoch = n
+		}
+	}
+
+	if deadline := os.Getenv("PROOF_DEADLINE_SECONDS"); deadline != "" {
+		if seconds, err := strconv.Atoi(deadline); err == nil {
+			config.ProofDeadlineSeconds = seconds
+		}
+	}
+	if policy := os.Getenv("PROOF_DEADLINE_POLICY"); policy != "" {
+		config.ProofDeadlinePolicy = policy
+	}
+	if capacity := os.Getenv("PROOF_FALLBACK_CAPACITY"); capacity != "" {
+		if n, err := strconv.Atoi(capacity); err == nil {
+			config.ProofFallbackCapacity = n
+		}
+	}
+
+	if aggregation := os.Getenv("PROOF_AGGREGATION"); aggregation != "" {
+		if n, err := strconv.Atoi(aggregation); err == nil {
+			config.ProofAggregation = n
+		}
+	}
+
+	if outbox, ok := os.LookupEnv("OUTBOX_FILE"); ok {
+		config.OutboxFile = outbox
+	}
+
+	// Fee floor and admin API
+	if minGasPrice := os.Getenv("MIN_GAS_PRICE"); minGasPrice != "" {
+		if price, err := strconv.ParseInt(minGasPrice, 10, 64); err == nil {
+			config.MinGasPrice = price
+		}
+	}
+	if recipient, ok := os.LookupEnv("FEE_RECIPIENT"); ok {
+		config.FeeRecipient = recipient
+	}
+	if ordering := os.Getenv("BATCH_ORDERING"); ordering != "" {
+		config.BatchOrdering = ordering
+	}
+	if preconfBatches := os.Getenv("PRECONFIRMATION_BATCHES"); preconfBatches != "" {
+		if n, err := strconv.ParseUint(preconfBatches, 10, 64); err == nil {
+			config.PreconfirmationBatches = n
+		}
+	}
+	if stream := os.Getenv("MEMPOOL_STREAM"); stream != "" {
+		config.MempoolStream = stream
+	}
+	if maxBody := os.Getenv("RPC_MAX_BODY_BYTES"); maxBody != "" {
+		if n, err := strconv.ParseInt(maxBody, 10, 64); err == nil {
+			config.RPCMaxBodyBytes = n
+		}
+	}
+	if maxBatch := os.Getenv("RPC_MAX_BATCH_SIZE"); maxBatch != "" {
+		if n, err := strconv.Atoi(maxBatch); err == nil {
+			config.RPCMaxBatchSize = n
+		}
+	}
+	if timeout := os.Getenv("RPC_TIMEOUT_SECONDS"); timeout != "" {
+		if n, err := strconv.Atoi(timeout); err == nil {
+			config.RPCTimeoutSeconds = n
+		}
+	}
+	if chainID := os.Getenv("ROLLUP_CHAIN_ID"); chainID != "" {
+		if id, err := strconv.ParseInt(chainID, 10, 64); err == nil {
+			config.RollupChainID = id
+		}
+	}
+	if unprotected := os.Getenv("ALLOW_UNPROTECTED_TXS"); unprotected != "" {
+		config.AllowUnprotectedTxs = unprotected == "true"
+	}
+	if rate := os.Getenv("RPC_RATE_LIMIT"); rate != "" {
+		if n, err := strconv.ParseFloat(rate, 64); err == nil {
+			config.RPCRateLimit = n
+		}
+	}
+	if burst := os.Getenv("RPC_RATE_BURST"); burst != "" {
+		if n, err := strconv.Atoi(burst); err == nil {
+			config.RPCRateBurst = n
+		}
+	}
+	if origins, ok := os.LookupEnv("RPC_CORS_ORIGINS"); ok {
+		config.RPCCORSOrigins = nil
+		if origins != "" {
+			config.RPCCORSOrigins = strings.Split(origins, ",")
+		}
+	}
+	if cert := os.Getenv("RPC_TLS_CERT_FILE"); cert != "" {
+		config.RPCTLSCertFile = cert
+	}
+	if key := os.Getenv("RPC_TLS_KEY_FILE"); key != "" {
+		config.RPCTLSKeyFile = key
+	}
+	if http2 := os.Getenv("RPC_HTTP2"); http2 != "" {
+		config.RPCHTTP2 = http2 == "true"
+	}
+	if perSender := os.Getenv("MEMPOOL_MAX_PER_SENDER"); perSender != "" {
+		if n, err := strconv.Atoi(perSender); err == nil {
+			config.MempoolMaxPerSender = n
+		}
+	}
+	if journal, ok := os.LookupEnv("MEMPOOL_JOURNAL"); ok {
+		config.MempoolJournal = journal
+	}
+	if announce := os.Getenv("MEMPOOL_ANNOUNCE_SECONDS"); announce != "" {
+		if n, err := strconv.Atoi(announce); err == nil {
+			config.MempoolAnnounceSeconds = n
+		}
+	}
+	if token, ok := os.LookupEnv("ADMIN_TOKEN"); ok {
+		config.AdminToken = token
+	}
+	if adminPort := os.Getenv("ADMIN_PORT"); adminPort != "" {
+		if port, err := strconv.Atoi(adminPort); err == nil {
+			config.AdminPort = port
+		}
+	}
+	if explorerPort := os.Getenv("EXPLORER_PORT"); explorerPort != "" {
+		if port, err := strconv.Atoi(explorerPort); err == nil {
+			config.ExplorerPort = port
+		}
+	}
+
+	if metricsPort := os.Getenv("METRICS_PORT"); metricsPort != "" {
+		if port, err := strconv.Atoi(metricsPort); err == nil {
+			config.MetricsPort = port
+		}
+	}
+
+	if stuck := os.Getenv("CONSENSUS_STUCK_SECONDS"); stuck != "" {
+		if n, err := strconv.Atoi(stuck); err == nil {
+			config.ConsensusStuckSeconds = n
+		}
+	}
+	if interval := os.Getenv("CONSENSUS_CHECKPOINT_INTERVAL"); interval != "" {
+		if n, err := strconv.Atoi(interval); err == nil {
+			config.ConsensusCheckpointInterval = n
+		}
+	}
+	if checkpointFile, ok := os.LookupEnv("CONSENSUS_CHECKPOINT_FILE"); ok {
+		config.ConsensusCheckpointFile = checkpointFile
+	}
+
+	// Remote signing service holding the node's keys
+	if signerURL := os.Getenv("SIGNER_URL"); signerURL != "" {
+		config.SignerURL = signerURL
+	}
+	if signerAPI := os.Getenv("SIGNER_API"); signerAPI != "" {
+		config.SignerAPI = signerAPI
+	}
+	if signerAddress := os.Getenv("CONSENSUS_SIGNER_ADDRESS"); signerAddress != "" {
+		config.ConsensusSignerAddress = signerAddress
+	}
+	if consensusKey := os.Getenv("CONSENSUS_PRIVATE_KEY"); consensusKey != "" {
+		config.ConsensusPrivateKey = consensusKey
+	}
+
+	// Emergency operators, every operator must sign unless a threshold is given
+	if operators := os.Getenv("EMERGENCY_OPERATORS"); operators != "" {
+		config.EmergencyOperators = strings.Split(operators, ",")
+		config.EmergencyThreshold = len(config.EmergencyOperators)
+		if threshold := os.Getenv("EMERGENCY_THRESHOLD"); threshold != "" {
+			if n, err := strconv.Atoi(threshold); err == nil {
+				config.EmergencyThreshold = n
+			}
+		}
+	}
 
 	// L1 integration configuration
-	if l1Enabled := os.Getenv("L1_ENABLED"); l1Enabled == "true" {
-		config.L1Enabled = true
+	if l1Enabled := os.Getenv("L1_ENABLED"); l1Enabled != "" {
+		config.L1Enabled = l1Enabled == "true"
+	}
+	if config.L1Enabled {
 		log.Printf("L1 integration enabled")
 
 		// Get L1 configuration from environment variables
@@ -55,19 +377,105 @@ func main() {
 			config.ContractAddress = contractAddr
 		}
 
+		if crsManager, ok := os.LookupEnv("CRS_MANAGER_ADDRESS"); ok {
+			config.CRSManagerAddress = crsManager
+		}
+
 		if privateKey := os.Getenv("L1_PRIVATE_KEY"); privateKey != "" {
 			config.L1PrivateKey = privateKey
 		}
 
+		if signerAddress := os.Getenv("L1_SIGNER_ADDRESS"); signerAddress != "" {
+			config.L1SignerAddress = signerAddress
+		}
+
+		if keystoreFile := os.Getenv("L1_KEYSTORE_FILE"); keystoreFile != "" {
+			config.L1KeystoreFile = keystoreFile
+		}
+		if passwordFile := os.Getenv("L1_KEYSTORE_PASSWORD_FILE"); passwordFile != "" {
+			config.L1KeystorePasswordFile = passwordFile
+		}
+
+		if keyID := os.Getenv("L1_KMS_KEY_ID"); keyID != "" {
+			config.L1KMSKeyID = keyID
+		}
+		if region := os.Getenv("KMS_REGION"); region != "" {
+			config.KMSRegion = region
+		}
+		if endpoint := os.Getenv("KMS_ENDPOINT"); endpoint != "" {
+			config.KMSEndpoint = endpoint
+		}
+
+		if startBlock := os.Getenv("L1_DEPOSIT_START_BLOCK"); startBlock != "" {
+			if block, err := strconv.ParseUint(startBlock, 10, 64); err == nil {
+				config.L1DepositStartBlock = block
+			}
+		}
+
+		if depth := os.Getenv("L1_FINALITY_DEPTH"); depth != "" {
+			if n, err := strconv.ParseUint(depth, 10, 64); err == nil {
+				config.L1FinalityDepth = n
+			}
+		}
+
+		if maxFee := os.Getenv("L1_MAX_FEE_GWEI"); maxFee != "" {
+			if n, err := strconv.ParseUint(maxFee, 10, 64); err == nil {
+				config.L1MaxFeeGwei = n
+			}
+		}
+
+		if stuck := os.Getenv("L1_STUCK_TX_SECONDS"); stuck != "" {
+			if n, err := strconv.Atoi(stuck); err == nil {
+				config.L1StuckTxSeconds = n
+			}
+		}
+
+		if confirmations := os.Getenv("L1_DEPOSIT_CONFIRMATIONS"); confirmations != "" {
+			if n, err := strconv.ParseUint(confirmations, 10, 64); err == nil {
+				config.L1DepositConfirmations = n
+			}
+		}
+
+		if statsFile, ok := os.LookupEnv("PROOF_STATS_FILE"); ok {
+			config.ProofStatsFile = statsFile
+		}
+
 		if submitPeriod := os.Getenv("L1_BATCH_SUBMIT_PERIOD"); submitPeriod != "" {
 			if period, err := strconv.Atoi(submitPeriod); err == nil {
 				config.L1BatchSubmitPeriod = period
 			}
 		}
+
+		if dataAvailability := os.Getenv("L1_DATA_AVAILABILITY"); dataAvailability != "" {
+			config.L1DataAvailability = dataAvailability
+		}
+	}
+
+	// A dev chain runs alone, leads every batch and never talks to L1
+	if config.DevMode {
+		config.IsLeader = true
+		config.BootstrapPeers = nil
+		config.L1Enabled = false
+		config.ValidatorRegistry = false
+		config.StakingContract = ""
+		config.FastSync = false
+	}
+
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if config.DevMode {
+		printDevAccounts(config)
+	}
+
+	port := config.SequencerPort
+	rpcPort := config.RPCPort
+	if rpcPort == 0 {
+		rpcPort = port + 1000
 	}
 
 	// Initialize sequencer
-	seq, err := sequencer.NewSequencer(config, port, bootstrapPeers, isLeader)
+	seq, err := sequencer.NewSequencer(config, port, config.BootstrapPeers, config.IsLeader)
 	if err != nil {
 		log.Fatalf("Failed to create sequencer: %v", err)
 	}
@@ -76,12 +484,60 @@ func main() {
 		log.Fatalf("Failed to start sequencer: %v", err)
 	}
 
+	// Derived gauges operators can alert on directly
+	stuckAfter := time.Duration(config.ConsensusStuckSeconds) * time.Second
+	if err := metrics.RegisterAlertGauges(prometheus.DefaultRegisterer, seq, stuckAfter); err != nil {
+		log.Fatalf("Failed to register alert metrics: %v", err)
+	}
+	if err := seq.Metrics().Register(prometheus.DefaultRegisterer, seq); err != nil {
+		log.Fatalf("Failed to register node metrics: %v", err)
+	}
+
+	// Prometheus scrape endpoint
+	var metricsServer *metrics.Server
+	if config.MetricsPort > 0 {
+		metricsServer = metrics.NewServer(prometheus.DefaultGatherer, config.MetricsPort)
+		if err := metricsServer.Start(); err != nil {
+			log.Fatalf("Failed to start metrics server: %v", err)
+		}
+	}
+
 	// Initialize and start RPC server
 	rpcServer := rpc.NewServer(seq, rpcPort)
+	rpcServer.SetAdminToken(config.AdminToken)
+	rpcServer.SetMempoolStream(config.MempoolStream)
+	rpcServer.SetRequestLimits(config.RPCMaxBodyBytes, config.RPCMaxBatchSize, time.Duration(config.RPCTimeoutSeconds)*time.Second)
+	rpcServer.SetRateLimit(config.RPCRateLimit, config.RPCRateBurst)
+	rpcServer.SetMethodConcurrency(config.RPCMethodConcurrency)
+	rpcServer.SetCORSOrigins(config.RPCCORSOrigins)
+	rpcServer.SetHTTP2(config.RPCHTTP2)
+	if config.RPCTLSCertFile != "" {
+		if err := rpcServer.SetTLS(config.RPCTLSCertFile, config.RPCTLSKeyFile); err != nil {
+			log.Fatalf("Failed to configure RPC TLS: %v", err)
+		}
+	}
 	if err := rpcServer.Start(); err != nil {
 		log.Fatalf("Failed to start RPC server: %v", err)
 	}
 
+	// Optional dashboard for devnet operators
+	var explorer *ui.Server
+	if config.ExplorerPort > 0 {
+		explorer = ui.NewServer(seq, config.ExplorerPort)
+		if err := explorer.Start(); err != nil {
+			log.Fatalf("Failed to start explorer: %v", err)
+		}
+	}
+
+	// Operator controls on their own port
+	var adminServer *admin.Server
+	if config.AdminPort > 0 {
+		adminServer = admin.NewServer(seq, config.AdminPort, config.AdminToken)
+		if err := adminServer.Start(); err != nil {
+			log.Fatalf("Failed to start admin API: %v", err)
+		}
+	}
+
 	log.Printf("ZK-Rollup node started with RPC server on port %d", rpcPort)
 
 	// Wait for shutdown signal
@@ -90,6 +546,23 @@ func main() {
 	<-sigCh
 
 	// Graceful shutdown
+	if adminServer != nil {
+		adminServer.Stop()
+	}
+	if explorer != nil {
+		explorer.Stop()
+	}
+	if metricsServer != nil {
+		metricsServer.Stop()
+	}
 	rpcServer.Stop()
 	seq.Stop()
 }
+
+// printDevAccounts lists the pre-funded dev accounts and their keys
+func printDevAccounts(config *core.Config) {
+	fmt.Printf("Dev chain %d, accounts funded with %s wei each:\n", config.ChainID, sequencer.DevAccountBalance)
+	for i, acc := range sequencer.DevAccounts(config.DevAccounts) {
+		fmt.Printf("(%d) %s 0x%x\n", i, acc.Address, crypto.FromECDSA(acc.PrivateKey))
+	}
+}
@@ -0,0 +1,345 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+)
+
+// Server exposes operator controls of a running node over REST on its own port. Every
+// request must carry the admin token as a bearer token.
+type Server struct {
+	sequencer *sequencer.Sequencer
+	port      int
+	token     string
+	server    *http.Server
+	mu        sync.Mutex
+}
+
+// poolView is the JSON form of the pool's contents
+type poolView struct {
+	Ready        int                 `json:"ready"`
+	Queued       int                 `json:"queued"`
+	MinGasPrice  string              `json:"minGasPrice"`
+	Transactions []state.Transaction `json:"transactions"`
+}
+
+// peerView is the JSON form of sequencer.PeerInfo
+type peerView struct {
+	ID    string   `json:"id"`
+	Addrs []string `json:"addrs"`
+}
+
+// consensusView is this node's view of consensus
+type consensusView struct {
+	Leader           bool    `json:"leader"`
+	Validators       int     `json:"validators"`
+	Quorum           int     `json:"quorum"`
+	BatchNumber      uint64  `json:"batchNumber"`
+	HighestSeenBatch uint64  `json:"highestSeenBatch"`
+	PendingRoundSecs float64 `json:"pendingRoundSecs"`
+	Syncing          bool    `json:"syncing"`
+	Halted           bool    `json:"halted"`
+	BatchesPaused    bool    `json:"batchesPaused"`
+
+	ValidatorSet        []string `json:"validatorSet"`
+	ScheduledValidators []string `json:"scheduledValidators,omitempty"`
+
+	PendingRounds    int     `json:"pendingRounds"`
+	StableCheckpoint *uint64 `json:"stableCheckpoint"`
+}
+
+// l1View is the state of L1 submission
+type l1View struct {
+	Enabled          bool   `json:"enabled"`
+	Backlog          int    `json:"backlog"`
+	SubmissionPaused bool   `json:"submissionPaused"`
+	ContractPaused   bool   `json:"contractPaused"`
+	BridgeFrozen     bool   `json:"bridgeFrozen"`
+	LastBatch        uint64 `json:"lastBatch"`
+	LastKind         string `json:"lastKind,omitempty"`
+	LastAt           int64  `json:"lastAt,omitempty"` // Unix seconds
+	LastError        string `json:"lastError,omitempty"`
+}
+
+// keysView describes the batch circuit keys this node proves with
+type keysView struct {
+	Epoch       uint64                 `json:"epoch"`
+	Fingerprint string                 `json:"fingerprint"`
+	Capacity    int                    `json:"capacity"`
+	Scheduled   *scheduledKeysView     `json:"scheduled,omitempty"`
+	Mismatches  map[string]peerKeyView `json:"mismatches"`
+}
+
+// scheduledKeysView is a key rotation waiting for its first batch
+type scheduledKeysView struct {
+	Epoch       uint64 `json:"epoch"`
+	Fingerprint string `json:"fingerprint"`
+	ActivateAt  uint64 `json:"activateAt"`
+}
+
+// peerKeyView describes the keys of a peer that differ from ours
+type peerKeyView struct {
+	Epoch       uint64 `json:"epoch"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// rotateKeysRequest schedules the keys of a CRS epoch
+type rotateKeysRequest struct {
+	Epoch      uint64 `json:"epoch"`
+	ActivateAt uint64 `json:"activateAt"`
+}
+
+// NewServer creates an admin server accepting requests authenticated with token
+func NewServer(seq *sequencer.Sequencer, port int, token string) *Server {
+	return &Server{
+		sequencer: seq,
+		port:      port,
+		token:     token,
+	}
+}
+
+// Start starts serving the admin API
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" {
+		return fmt.Errorf("admin API requires an admin token")
+	}
+
+	s.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.port),
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Info().Int("port", s.port).Msg("Starting admin API")
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Admin API server error")
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the admin API
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		log.Info().Msg("Stopping admin API")
+		return s.server.Close()
+	}
+	return nil
+}
+
+// handler routes the admin endpoints behind the token check
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /mempool", s.handleMempool)
+	mux.HandleFunc("GET /peers", s.handlePeers)
+	mux.HandleFunc("GET /consensus", s.handleConsensus)
+	mux.HandleFunc("POST /batches/trigger", s.handleTriggerBatch)
+	mux.HandleFunc("POST /batches/pause", s.handlePauseBatches)
+	mux.HandleFunc("POST /batches/resume", s.handleResumeBatches)
+	mux.HandleFunc("GET /l1", s.handleL1)
+	mux.HandleFunc("POST /l1/pause", s.handlePauseL1)
+	mux.HandleFunc("POST /l1/resume", s.handleResumeL1)
+	mux.HandleFunc("POST /l1/resubmit/{batch}", s.handleResubmit)
+	mux.HandleFunc("GET /keys", s.handleKeys)
+	mux.HandleFunc("POST /keys/rotate", s.handleRotateKeys)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorize(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorize checks the request's bearer token against the admin token
+func (s *Server) authorize(r *http.Request) bool {
+	if s.token == "" {
+		return false
+	}
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) == 1
+}
+
+// handleMempool lists every pending transaction, including private ones
+func (s *Server) handleMempool(w http.ResponseWriter, r *http.Request) {
+	txs := s.sequencer.PoolTxs()
+	for i := range txs {
+		txs[i].TraceID = ""
+	}
+
+	status := s.sequencer.Status()
+	writeJSON(w, poolView{
+		Ready:        status.PoolSize,
+		Queued:       status.PoolQueued,
+		MinGasPrice:  s.sequencer.MinGasPrice().String(),
+		Transactions: txs,
+	})
+}
+
+// handlePeers lists the connected peers
+func (s *Server) handlePeers(w http.ResponseWriter, r *http.Request) {
+	peers := s.sequencer.Peers()
+	views := make([]peerView, 0, len(peers))
+	for _, p := range peers {
+		views = append(views, peerView{ID: p.ID, Addrs: p.Addrs})
+	}
+	writeJSON(w, views)
+}
+
+// handleConsensus reports this node's consensus and batch production state
+func (s *Server) handleConsensus(w http.ResponseWriter, r *http.Request) {
+	status := s.sequencer.Status()
+	writeJSON(w, consensusView{
+		Leader:           status.Consensus.Leader,
+		Validators:       status.Consensus.Validators,
+		Quorum:           status.Consensus.Quorum,
+		BatchNumber:      status.BatchNumber,
+		HighestSeenBatch: status.Consensus.HighestSeenBatch,
+		PendingRoundSecs: status.Consensus.PendingRound.Seconds(),
+		Syncing:          status.Sync.Syncing,
+		Halted:           status.Halted,
+		BatchesPaused:    status.BatchesPaused,
+
+		ValidatorSet:        status.Consensus.ValidatorSet,
+		ScheduledValidators: status.Consensus.ScheduledValidators,
+
+		PendingRounds:    status.Consensus.PendingRounds,
+		StableCheckpoint: status.Consensus.StableCheckpoint,
+	})
+}
+
+// handleTriggerBatch has the leader propose a batch now
+func (s *Server) handleTriggerBatch(w http.ResponseWriter, r *http.Request) {
+	if err := s.sequencer.ForceBatch(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, map[string]bool{"triggered": true})
+}
+
+// handlePauseBatches stops this node from proposing batches
+func (s *Server) handlePauseBatches(w http.ResponseWriter, r *http.Request) {
+	s.sequencer.PauseBatches()
+	writeJSON(w, map[string]bool{"batchesPaused": true})
+}
+
+// handleResumeBatches lets this node propose batches again
+func (s *Server) handleResumeBatches(w http.ResponseWriter, r *http.Request) {
+	s.sequencer.ResumeBatches()
+	writeJSON(w, map[string]bool{"batchesPaused": false})
+}
+
+// handleL1 reports the state of L1 submission
+func (s *Server) handleL1(w http.ResponseWriter, r *http.Request) {
+	status := s.sequencer.Status().L1
+	view := l1View{
+		Enabled:          status.Enabled,
+		Backlog:          status.Backlog,
+		SubmissionPaused: status.SubmissionPaused,
+		ContractPaused:   status.Paused,
+		BridgeFrozen:     status.BridgeFrozen,
+		LastBatch:        status.LastBatch,
+		LastKind:         status.LastKind,
+		LastError:        status.LastError,
+	}
+	if !status.LastAt.IsZero() {
+		view.LastAt = status.LastAt.Unix()
+	}
+	writeJSON(w, view)
+}
+
+// handlePauseL1 holds L1 submissions
+func (s *Server) handlePauseL1(w http.ResponseWriter, r *http.Request) {
+	s.sequencer.PauseL1Submission()
+	writeJSON(w, map[string]bool{"submissionPaused": true})
+}
+
+// handleResumeL1 releases held L1 submissions
+func (s *Server) handleResumeL1(w http.ResponseWriter, r *http.Request) {
+	s.sequencer.ResumeL1Submission()
+	writeJSON(w, map[string]bool{"submissionPaused": false})
+}
+
+// handleResubmit queues a finalized batch for L1 submission again
+func (s *Server) handleResubmit(w http.ResponseWriter, r *http.Request) {
+	batchNumber, err := strconv.ParseUint(r.PathValue("batch"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid batch number", http.StatusBadRequest)
+		return
+	}
+	if err := s.sequencer.ResubmitToL1(batchNumber); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, map[string]uint64{"requeued": batchNumber})
+}
+
+// handleKeys reports the batch circuit keys and the peers proving with other keys
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	status := s.sequencer.KeyStatus()
+	if status == nil {
+		http.Error(w, "proof generation is disabled", http.StatusConflict)
+		return
+	}
+
+	view := keysView{
+		Epoch:       status.Epoch,
+		Fingerprint: status.Fingerprint,
+		Capacity:    status.Capacity,
+		Mismatches:  make(map[string]peerKeyView, len(status.Mismatches)),
+	}
+	if next := status.Scheduled; next != nil {
+		view.Scheduled = &scheduledKeysView{Epoch: next.Epoch, Fingerprint: next.Fingerprint, ActivateAt: next.ActivateAt}
+	}
+	for id, info := range status.Mismatches {
+		view.Mismatches[id] = peerKeyView{Epoch: info.Epoch, Fingerprint: info.Fingerprint}
+	}
+	writeJSON(w, view)
+}
+
+// handleRotateKeys schedules the batch circuit keys of a CRS epoch from a given batch on
+func (s *Server) handleRotateKeys(w http.ResponseWriter, r *http.Request) {
+	var req rotateKeysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	next, err := s.sequencer.RotateKeys(req.Epoch, req.ActivateAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, scheduledKeysView{Epoch: next.Epoch, Fingerprint: next.Fingerprint, ActivateAt: next.ActivateAt})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to encode admin response")
+	}
+}
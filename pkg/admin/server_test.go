@@ -0,0 +1,40 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRequiresToken(t *testing.T) {
+	// Unauthorized requests never reach the sequencer
+	handler := NewServer(nil, 0, "secret").handler()
+
+	for _, auth := range []string{"", "secret", "Bearer wrong", "Basic secret"} {
+		req := httptest.NewRequest(http.MethodPost, "/batches/pause", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code, auth)
+	}
+
+	// Without a token the API stays closed
+	req := httptest.NewRequest(http.MethodGet, "/peers", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	NewServer(nil, 0, "").handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Authorized requests are routed, unknown paths are not found
+	req = httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.Error(t, NewServer(nil, 0, "").Start())
+}
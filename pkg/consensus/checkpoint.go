@@ -0,0 +1,207 @@
+package consensus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// DefaultCheckpointInterval is how many decided batches separate two checkpoints
+const DefaultCheckpointInterval = 10
+
+// StableCheckpoint is a decided batch a quorum of validators reported with matching hash
+// and state root. Consensus state of batches up to it is discarded.
+type StableCheckpoint struct {
+	BatchNumber uint64
+	BatchHash   string
+	StateRoot   string
+	View        int64
+
+	// Signed Checkpoint messages of the quorum, the certificate of the checkpoint
+	Certificate []*ConsensusMessage
+}
+
+// SetCheckpointInterval sets how many decided batches separate two checkpoints, 0
+// disables checkpointing and with it the pruning of old rounds
+func (p *PBFT) SetCheckpointInterval(interval uint64) {
+	p.statesLock.Lock()
+	defer p.statesLock.Unlock()
+	p.checkpointInterval = interval
+}
+
+// SetCheckpointFile persists stable checkpoints at path and resumes from the one already
+// there: its view is restored and older rounds are refused
+func (p *PBFT) SetCheckpointFile(path string) error {
+	p.statesLock.Lock()
+	defer p.statesLock.Unlock()
+
+	p.checkpointFile = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	var cp StableCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("failed to decode checkpoint: %v", err)
+	}
+	p.stableCheckpoint = &cp
+	if cp.View > p.view {
+		p.view = cp.View
+	}
+	p.observeBatch(cp.BatchNumber)
+
+	log.Info().Uint64("batch_number", cp.BatchNumber).Int64("view", cp.View).Msg("Resumed from stable checkpoint")
+	return nil
+}
+
+// LatestCheckpoint returns the latest stable checkpoint, nil before the first one
+func (p *PBFT) LatestCheckpoint() *StableCheckpoint {
+	p.statesLock.RLock()
+	defer p.statesLock.RUnlock()
+
+	if p.stableCheckpoint == nil {
+		return nil
+	}
+	cp := *p.stableCheckpoint
+	return &cp
+}
+
+// PendingRounds returns how many consensus rounds are held in memory
+func (p *PBFT) PendingRounds() int {
+	p.statesLock.RLock()
+	defer p.statesLock.RUnlock()
+	return len(p.states)
+}
+
+// checkpointLocked announces a checkpoint when a decided batch closes an interval. Must be
+// called with statesLock held.
+func (p *PBFT) checkpointLocked(batch *state.Batch) {
+	if p.checkpointInterval == 0 || (batch.BatchNumber+1)%p.checkpointInterval != 0 {
+		return
+	}
+
+	msg := &ConsensusMessage{
+		Type:        Checkpoint,
+		View:        p.view,
+		BatchHash:   BatchHash(batch),
+		BatchNumber: batch.BatchNumber,
+		StateRoot:   fmt.Sprintf("%x", batch.StateRoot),
+		NodeID:      p.nodeID,
+		Timestamp:   time.Now(),
+	}
+	if err := p.broadcast(msg); err != nil {
+		log.Error().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to broadcast checkpoint")
+	}
+	p.addCheckpointVoteLocked(msg)
+}
+
+// handleCheckpoint records a validator's checkpoint message
+func (p *PBFT) handleCheckpoint(msg *ConsensusMessage) error {
+	p.statesLock.Lock()
+	defer p.statesLock.Unlock()
+
+	p.addCheckpointVoteLocked(msg)
+	return nil
+}
+
+// addCheckpointVoteLocked counts a checkpoint message and makes the checkpoint stable once
+// a quorum reported the same batch. Must be called with statesLock held.
+func (p *PBFT) addCheckpointVoteLocked(msg *ConsensusMessage) {
+	if p.stableCheckpoint != nil && msg.BatchNumber <= p.stableCheckpoint.BatchNumber {
+		return
+	}
+
+	votes := p.checkpointVotes[msg.BatchNumber]
+	if votes == nil {
+		votes = make(map[string]*ConsensusMessage)
+		p.checkpointVotes[msg.BatchNumber] = votes
+	}
+	votes[msg.NodeID] = msg
+
+	var certificate []*ConsensusMessage
+	for _, vote := range votes {
+		if vote.BatchHash == msg.BatchHash && vote.StateRoot == msg.StateRoot {
+			certificate = append(certificate, vote)
+		}
+	}
+	if !HasQuorum(len(certificate), p.TotalNodes()) {
+		return
+	}
+
+	p.stabilizeLocked(&StableCheckpoint{
+		BatchNumber: msg.BatchNumber,
+		BatchHash:   msg.BatchHash,
+		StateRoot:   msg.StateRoot,
+		View:        p.view,
+		Certificate: certificate,
+	})
+}
+
+// stabilizeLocked makes cp the stable checkpoint, discards the rounds and checkpoint votes
+// it covers and persists it. Must be called with statesLock held.
+func (p *PBFT) stabilizeLocked(cp *StableCheckpoint) {
+	p.stableCheckpoint = cp
+
+	pruned := 0
+	for hash, st := range p.states {
+		if number, ok := st.batchNumber(); ok && number <= cp.BatchNumber {
+			delete(p.states, hash)
+			pruned++
+		}
+	}
+	for number := range p.checkpointVotes {
+		if number <= cp.BatchNumber {
+			delete(p.checkpointVotes, number)
+		}
+	}
+	p.observeBatch(cp.BatchNumber)
+
+	log.Info().Uint64("batch_number", cp.BatchNumber).Str("state_root", cp.StateRoot).Int("pruned_rounds", pruned).Msg("Checkpoint is stable")
+
+	if err := p.saveCheckpointLocked(); err != nil {
+		log.Error().Err(err).Str("file", p.checkpointFile).Msg("Failed to persist checkpoint")
+	}
+}
+
+// saveCheckpointLocked writes the stable checkpoint to the checkpoint file, if any. Must
+// be called with statesLock held.
+func (p *PBFT) saveCheckpointLocked() error {
+	if p.checkpointFile == "" || p.stableCheckpoint == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(p.stableCheckpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+
+	tmp := p.checkpointFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp, p.checkpointFile); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// batchNumber returns the number of the batch a round decides, from its body or the
+// pre-prepare announcing it
+func (st *ConsensusState) batchNumber() (uint64, bool) {
+	if st.Batch != nil {
+		return st.Batch.BatchNumber, true
+	}
+	if st.PrePrepareMsg != nil {
+		return st.PrePrepareMsg.BatchNumber, true
+	}
+	return 0, false
+}
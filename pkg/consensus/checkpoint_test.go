@@ -0,0 +1,56 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/state"
+)
+
+func TestStableCheckpoint(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "checkpoint.json")
+	p := NewPBFT(nil, "a", false)
+	p.SetValidators([]string{"a", "b", "c", "d"})
+	require.NoError(t, p.SetCheckpointFile(file))
+	p.view = 3
+
+	batches := map[uint64]*state.Batch{}
+	for _, number := range []uint64{3, 9, 12} {
+		batch := &state.Batch{BatchNumber: number, StateRoot: [32]byte{byte(number)}}
+		batches[number] = batch
+		st := NewConsensusState(p.view, int64(number), batch)
+		p.states[st.BatchHash] = st
+	}
+
+	vote := func(node string, batch *state.Batch, root string) {
+		require.NoError(t, p.handleCheckpoint(&ConsensusMessage{Type: Checkpoint, NodeID: node, BatchNumber: batch.BatchNumber, BatchHash: BatchHash(batch), StateRoot: root}))
+	}
+	root := "09"
+
+	// Votes disagreeing on the state root do not add up
+	vote("b", batches[9], root)
+	vote("c", batches[9], "ff")
+	vote("d", batches[9], root)
+	require.Nil(t, p.LatestCheckpoint())
+	require.Equal(t, 3, p.PendingRounds())
+
+	vote("a", batches[9], root)
+	cp := p.LatestCheckpoint()
+	require.NotNil(t, cp)
+	require.Equal(t, uint64(9), cp.BatchNumber)
+	require.Len(t, cp.Certificate, 3)
+	require.Equal(t, 1, p.PendingRounds())
+	require.Equal(t, uint64(9), p.HighestSeenBatch())
+
+	// Votes for covered batches are ignored
+	vote("b", batches[3], "03")
+	require.Empty(t, p.checkpointVotes)
+
+	// A restarted node resumes from the persisted checkpoint
+	restarted := NewPBFT(nil, "a", false)
+	require.NoError(t, restarted.SetCheckpointFile(file))
+	require.Equal(t, uint64(9), restarted.LatestCheckpoint().BatchNumber)
+	require.Equal(t, int64(3), restarted.view)
+}
@@ -0,0 +1,202 @@
+package consensus
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/rs/zerolog/log"
+)
+
+// Ceremonies are recorded on the CRS Manager contract so their transcript can be audited
+// from L1. The contract only accepts a participant's own transactions, so every validator
+// registers itself right before its turn, which keeps the contract's contribution order
+// that of the ceremony. It commits to the SHA-256 digest of its transcript and then reveals
+// the digest as its contribution, the transcripts themselves are too large for L1. The node
+// finalizing the ceremony closes the round once every contribution is recorded.
+
+const (
+	// crsAnchorTimeout bounds the L1 transactions of one contribution or finalization
+	crsAnchorTimeout = 5 * time.Minute
+
+	// crsAnchorPollInterval is how often the contract is read while waiting for a transaction
+	crsAnchorPollInterval = 2 * time.Second
+)
+
+// CRSTransactOpts returns options for a transaction signed by this node's L1 account
+type CRSTransactOpts func(ctx context.Context) (*bind.TransactOpts, error)
+
+// CRSCommitment is what a participant commits to before contributing digest
+func CRSCommitment(digest [32]byte) [32]byte {
+	return sha256.Sum256(digest[:])
+}
+
+// anchorCRSContribution records this node's contribution at step of the ceremony on L1.
+// Failures are logged, the ceremony itself does not depend on L1.
+func (p *PBFT) anchorCRSContribution(step int, transcript []byte) {
+	if p.crsManager == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, crsAnchorTimeout)
+	defer cancel()
+
+	digest := sha256.Sum256(transcript)
+	if err := p.recordCRSContribution(ctx, step, digest); err != nil {
+		log.Error().Err(err).Int64("epoch", p.currentEpoch).Int("step", step).Msg("Failed to record CRS contribution on L1")
+		return
+	}
+	log.Info().Int64("epoch", p.currentEpoch).Int("step", step).Hex("digest", digest[:]).Msg("Recorded CRS contribution on L1")
+}
+
+// recordCRSContribution registers this node as participant step of the current round,
+// commits to digest and contributes it, waiting for each transaction to take effect
+func (p *PBFT) recordCRSContribution(ctx context.Context, step int, digest [32]byte) error {
+	auth, err := p.crsTransactOpts(ctx)
+	if err != nil {
+		return err
+	}
+	self := auth.From
+
+	participants, err := p.crsManager.GetRegisteredParticipants(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get registered participants: %v", err)
+	}
+	switch idx := slices.Index(participants, self); {
+	case idx == step:
+		// Registered by an earlier attempt
+	case idx >= 0:
+		return fmt.Errorf("registered as participant %d of the round, the ceremony is at step %d", idx, step)
+	case len(participants) != step:
+		return fmt.Errorf("round has %d participants, the ceremony is at step %d", len(participants), step)
+	default:
+		if err := p.crsManager.Register(auth); err != nil {
+			return fmt.Errorf("failed to register: %v", err)
+		}
+		err := p.waitCRSManager(ctx, func() (bool, error) {
+			participants, err := p.crsManager.GetRegisteredParticipants(ctx)
+			return slices.Contains(participants, self), err
+		})
+		if err != nil {
+			return fmt.Errorf("registration not recorded: %v", err)
+		}
+	}
+
+	round, err := p.crsManager.CurrentRound(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current round: %v", err)
+	}
+	committed, err := p.crsManager.HasCommitted(ctx, round, self)
+	if err != nil {
+		return fmt.Errorf("failed to get commitment: %v", err)
+	}
+	if !committed {
+		if auth, err = p.crsTransactOpts(ctx); err != nil {
+			return err
+		}
+		if err := p.crsManager.SubmitCommitment(auth, CRSCommitment(digest)); err != nil {
+			return fmt.Errorf("failed to submit commitment: %v", err)
+		}
+		err := p.waitCRSManager(ctx, func() (bool, error) {
+			return p.crsManager.HasCommitted(ctx, round, self)
+		})
+		if err != nil {
+			return fmt.Errorf("commitment not recorded: %v", err)
+		}
+	}
+
+	if auth, err = p.crsTransactOpts(ctx); err != nil {
+		return err
+	}
+	if err := p.crsManager.ContributeCRS(auth, digest[:]); err != nil {
+		return fmt.Errorf("failed to contribute: %v", err)
+	}
+	err = p.waitCRSManager(ctx, func() (bool, error) {
+		idx, err := p.crsManager.ContributorIndex(ctx)
+		return idx > uint64(step), err
+	})
+	if err != nil {
+		return fmt.Errorf("contribution not recorded: %v", err)
+	}
+	return nil
+}
+
+// finalizeCRSOnL1 closes the contract's round of the ceremony of epoch once every
+// participant's contribution is recorded
+func (p *PBFT) finalizeCRSOnL1(epoch int64) {
+	if p.crsManager == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, crsAnchorTimeout)
+	defer cancel()
+
+	round, err := p.closeCRSRound(ctx)
+	if err != nil {
+		log.Error().Err(err).Int64("epoch", epoch).Msg("Failed to finalize CRS round on L1")
+		return
+	}
+	log.Info().Int64("epoch", epoch).Uint64("round", round).Msg("Finalized CRS round on L1")
+}
+
+// closeCRSRound finalizes the contract's current round and returns its number
+func (p *PBFT) closeCRSRound(ctx context.Context) (uint64, error) {
+	participants, err := p.crsManager.GetRegisteredParticipants(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get registered participants: %v", err)
+	}
+	if len(participants) == 0 {
+		return 0, fmt.Errorf("no contributions recorded")
+	}
+	// The last participant records its contribution before announcing it, a contribution
+	// still missing was not recorded at all
+	idx, err := p.crsManager.ContributorIndex(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get contributor index: %v", err)
+	}
+	if idx != uint64(len(participants)) {
+		return 0, fmt.Errorf("%d of %d participants contributed", idx, len(participants))
+	}
+
+	round, err := p.crsManager.CurrentRound(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get current round: %v", err)
+	}
+	auth, err := p.crsTransactOpts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := p.crsManager.FinalizeCRS(auth); err != nil {
+		return 0, fmt.Errorf("failed to finalize: %v", err)
+	}
+	err = p.waitCRSManager(ctx, func() (bool, error) {
+		current, err := p.crsManager.CurrentRound(ctx)
+		return current > round, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("finalization not recorded: %v", err)
+	}
+	return round, nil
+}
+
+// waitCRSManager polls the contract until done reports that a transaction took effect
+func (p *PBFT) waitCRSManager(ctx context.Context, done func() (bool, error)) error {
+	ticker := time.NewTicker(crsAnchorPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
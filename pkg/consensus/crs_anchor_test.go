@@ -0,0 +1,101 @@
+package consensus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/contracts/bindings"
+	"zkrollup/pkg/l1"
+)
+
+func TestCRSCeremonyRecordedOnL1(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for simulated L1 blocks")
+	}
+
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := types.GenesisAlloc{}
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		keys[i] = key
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = types.Account{Balance: big.NewInt(1e18)}
+	}
+	backend := simulated.NewBackend(alloc)
+	defer backend.Close()
+	chainID := big.NewInt(1337)
+
+	deployer, err := bind.NewKeyedTransactorWithChainID(keys[0], chainID)
+	require.NoError(t, err)
+	address, _, _, err := bindings.DeployCRSManager(deployer, backend.Client(), big.NewInt(3600), big.NewInt(4))
+	require.NoError(t, err)
+	backend.Commit()
+
+	// Seal blocks in the background as a live L1 would
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				backend.Commit()
+			}
+		}
+	}()
+
+	nodes := make([]*PBFT, len(keys))
+	for i, key := range keys {
+		manager, err := l1.NewCRSManager(address, backend.Client())
+		require.NoError(t, err)
+		nodes[i] = &PBFT{ctx: ctx}
+		nodes[i].SetCRSManager(manager, func(ctx context.Context) (*bind.TransactOpts, error) {
+			auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+			if err != nil {
+				return nil, err
+			}
+			auth.Context = ctx
+			return auth, nil
+		})
+	}
+
+	// A contribution out of the ceremony's order is not recorded
+	require.Error(t, nodes[1].recordCRSContribution(ctx, 1, [32]byte{}))
+
+	digests := [][32]byte{sha256.Sum256([]byte("step 0")), sha256.Sum256([]byte("step 1"))}
+	for step, node := range nodes {
+		require.NoError(t, node.recordCRSContribution(ctx, step, digests[step]))
+	}
+
+	round, err := nodes[0].closeCRSRound(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), round)
+
+	crs, _, participants, err := nodes[0].crsManager.GetLatestCRS(ctx)
+	require.NoError(t, err)
+	require.Equal(t, digests[1][:], crs)
+	require.Equal(t, []common.Address{crypto.PubkeyToAddress(keys[0].PublicKey), crypto.PubkeyToAddress(keys[1].PublicKey)}, participants)
+
+	// Each participant committed to the digest it revealed
+	caller, err := bindings.NewCRSManagerCaller(address, backend.Client())
+	require.NoError(t, err)
+	for i, key := range keys {
+		commitment, err := caller.Commitments(nil, big.NewInt(1), crypto.PubkeyToAddress(key.PublicKey))
+		require.NoError(t, err)
+		require.Equal(t, CRSCommitment(digests[i]), commitment.Commitment)
+	}
+}
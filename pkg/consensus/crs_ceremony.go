@@ -1,18 +1,35 @@
 package consensus
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
-// PTauCeremonyState tracks the state of an ongoing Powers of Tau ceremony
-// for generating SRS compatible with Circom and SnarkJS
+// ptauBeacon is the public value whose hash makes the last contribution of every ceremony
+var ptauBeacon = []byte{
+	0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+	0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+}
+
+// DefaultCRSPowerSize is the ceremony size used when none is configured, enough for
+// circuits of 4096 constraints
+const DefaultCRSPowerSize = 12
+
+// ptauBeaconIterationsExp is the number of times the beacon is hashed, as a power of 2
+const ptauBeaconIterationsExp = 10
+
+// ceremonyStateFile is the file in the ceremony directory the state of the latest ceremony
+// is persisted to
+const ceremonyStateFile = "ceremony.json"
+
+// PTauCeremonyState tracks the state of an ongoing Powers of Tau ceremony, run in-process
+// on gnark's phase 1 MPC transcripts
 type PTauCeremonyState struct {
 	EpochNumber  int64
 	Participants []string // Ordered list of participant NodeIDs
@@ -20,7 +37,17 @@ type PTauCeremonyState struct {
 	PTauPath     string   // Path to the current ptau file
 	PowerSize    int      // Power of 2 size of the ceremony (e.g., 12 for 2^12 constraints)
 	Completed    bool
-	Mutex        sync.Mutex
+	Mutex        sync.Mutex `json:"-"`
+
+	// Node that started the ceremony, the only one that may skip a participant
+	Leader string
+
+	// Contribution, skip and completion messages in the order they were applied, replayed
+	// to nodes catching up after a restart
+	Log []*ConsensusMessage
+
+	dir         string    // Directory holding the transcript of every step
+	stepStarted time.Time // When the current participant's turn began
 }
 
 // PTauContributionMessage is sent between participants during the Powers of Tau ceremony
@@ -28,7 +55,8 @@ type PTauContributionMessage struct {
 	EpochNumber   int64
 	Step          int
 	PTauFilePath  string
-	PTauFileData  []byte // The actual PTau file data
+	PTauFileData  []byte // The actual PTau file data, left out by nodes that announce it by hash
+	PTauFileHash  string // Content hash the PTau file is fetched by when not embedded
 	ContributorID string
 	Signature     []byte
 }
@@ -40,14 +68,10 @@ func NewPTauCeremonyState(epochNumber int64, participants []string, powerSize in
 		return nil, fmt.Errorf("failed to create output directory: %v", err)
 	}
 
-	// Initialize the ceremony with a new ptau file
-	ptauPath := filepath.Join(outputDir, fmt.Sprintf("pot_epoch%d_0.ptau", epochNumber))
-
-	// Use snarkjs to start a new Powers of Tau ceremony
-	cmd := exec.Command("npx", "snarkjs", "powersoftau", "new", "bn128", fmt.Sprintf("%d", powerSize), ptauPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize Powers of Tau: %v\n%s", err, output)
+	// Every participant starts from the same initial transcript
+	ptauPath := ptauStepPath(outputDir, epochNumber, 0)
+	if _, err := writePTau(ptauPath, initPTau(powerSize)); err != nil {
+		return nil, fmt.Errorf("failed to initialize Powers of Tau: %v", err)
 	}
 
 	return &PTauCeremonyState{
@@ -57,9 +81,64 @@ func NewPTauCeremonyState(epochNumber int64, participants []string, powerSize in
 		PTauPath:     ptauPath,
 		PowerSize:    powerSize,
 		Completed:    false,
+		dir:          outputDir,
+		stepStarted:  time.Now(),
 	}, nil
 }
 
+// LoadPTauCeremonyState returns the ceremony persisted in dir, nil when there is none
+func LoadPTauCeremonyState(dir string) (*PTauCeremonyState, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ceremonyStateFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ceremony state: %v", err)
+	}
+
+	var s PTauCeremonyState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode ceremony state: %v", err)
+	}
+	if _, err := os.Stat(s.PTauPath); err != nil {
+		return nil, fmt.Errorf("transcript of epoch %d step %d is missing: %v", s.EpochNumber, s.CurrentStep, err)
+	}
+	s.dir = dir
+	s.stepStarted = time.Now()
+	return &s, nil
+}
+
+// Save persists the ceremony to its directory, where LoadPTauCeremonyState finds it after
+// a restart
+func (s *PTauCeremonyState) Save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode ceremony state: %v", err)
+	}
+
+	path := filepath.Join(s.dir, ceremonyStateFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write ceremony state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write ceremony state: %v", err)
+	}
+	return nil
+}
+
+// Skip drops the participant whose turn it is, the next one contributes on the same
+// transcript
+func (s *PTauCeremonyState) Skip(step int, nodeID string) error {
+	if step != s.CurrentStep || !s.CheckTurn(nodeID) {
+		return fmt.Errorf("%s does not contribute step %d", nodeID, step)
+	}
+	s.Participants = append(append([]string{}, s.Participants[:step]...), s.Participants[step+1:]...)
+	s.Completed = s.CurrentStep >= len(s.Participants)
+	s.stepStarted = time.Now()
+	return nil
+}
+
 // CheckTurn returns true if the given nodeID is the current contributor
 func (s *PTauCeremonyState) CheckTurn(nodeID string) bool {
 	// Make sure we don't try to access an index that's out of range
@@ -69,38 +148,36 @@ func (s *PTauCeremonyState) CheckTurn(nodeID string) bool {
 	return s.Participants[s.CurrentStep] == nodeID
 }
 
-// AddContribution adds a contribution to the Powers of Tau ceremony
+// AddContribution adds a contribution to the Powers of Tau ceremony, its secrets derived
+// from entropy mixed with fresh randomness
 func (s *PTauCeremonyState) AddContribution(contributorID, entropy string) (*PTauContributionMessage, error) {
 	// Check if it's this node's turn to contribute
 	if !s.CheckTurn(contributorID) {
 		return nil, fmt.Errorf("not %s's turn to contribute", contributorID)
 	}
 
+	phase1, err := readPTau(s.PTauPath)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := randomSeed(entropy)
+	if err != nil {
+		return nil, err
+	}
+	contributePTau(phase1, seed)
+
 	// Create the output ptau file path
 	nextStep := s.CurrentStep + 1
-	outputPath := filepath.Join(filepath.Dir(s.PTauPath), fmt.Sprintf("pot_epoch%d_%d.ptau", s.EpochNumber, nextStep))
-
-	cmd := exec.Command("npx", "snarkjs", "powersoftau", "contribute",
-		s.PTauPath, outputPath,
-		"--name="+fmt.Sprintf("Contribution from %s", contributorID),
-		"-v",
-		"-e="+entropy)
-
-	// Run the command
-	output, err := cmd.CombinedOutput()
+	outputPath := ptauStepPath(s.dir, s.EpochNumber, nextStep)
+	ptauData, err := writePTau(outputPath, phase1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add contribution: %v\n%s", err, output)
+		return nil, fmt.Errorf("failed to add contribution: %v", err)
 	}
 
 	// Update the state
 	s.PTauPath = outputPath
 	s.CurrentStep = nextStep
-
-	// Read the PTau file data
-	ptauData, err := os.ReadFile(outputPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read PTau file: %v", err)
-	}
+	s.stepStarted = time.Now()
 
 	// Create the contribution message
 	msg := &PTauContributionMessage{
@@ -114,13 +191,19 @@ func (s *PTauCeremonyState) AddContribution(contributorID, entropy string) (*PTa
 	return msg, nil
 }
 
-// VerifyContribution verifies a contribution message from another participant
+// VerifyContribution verifies a contribution message from another participant against the
+// transcript it builds on, and points PTauPath at where the contribution is to be saved
 func (s *PTauCeremonyState) VerifyContribution(msg *PTauContributionMessage) error {
 	// Verify that the message is for the current epoch
 	if msg.EpochNumber != s.EpochNumber {
 		return fmt.Errorf("unexpected epoch: got %d, expected %d", msg.EpochNumber, s.EpochNumber)
 	}
 
+	// Contributions are made in the order of the participants, skipped ones included
+	if msg.Step < 0 || msg.Step >= len(s.Participants) || s.Participants[msg.Step] != msg.ContributorID {
+		return fmt.Errorf("%s does not contribute step %d", msg.ContributorID, msg.Step)
+	}
+
 	// Allow any step number as long as it's the expected one or the next one
 	// This is needed because different nodes may have different views of the current step
 	if msg.Step != s.CurrentStep && msg.Step != s.CurrentStep-1 && msg.Step != s.CurrentStep+1 {
@@ -128,30 +211,21 @@ func (s *PTauCeremonyState) VerifyContribution(msg *PTauContributionMessage) err
 			msg.Step, s.CurrentStep-1, s.CurrentStep, s.CurrentStep+1)
 	}
 
-	// Verify the contribution using snarkjs
-	tmpFile, err := os.CreateTemp("", "ptau-*.ptau")
+	// The contribution must build on the transcript of the step before it
+	prev, err := readPTau(ptauStepPath(s.dir, s.EpochNumber, msg.Step))
 	if err != nil {
-		return fmt.Errorf("failed to create temporary ptau file: %v", err)
+		return fmt.Errorf("missing transcript of step %d: %v", msg.Step, err)
 	}
-	defer os.Remove(tmpFile.Name())
-
-	if _, err := tmpFile.Write(msg.PTauFileData); err != nil {
-		return fmt.Errorf("failed to write ptau file: %v", err)
-	}
-	tmpFile.Close()
-
-	cmd := exec.Command("npx", "snarkjs", "powersoftau", "verify", tmpFile.Name())
-	output, err := cmd.CombinedOutput()
+	next, err := decodePTau(msg.PTauFileData)
 	if err != nil {
-		return fmt.Errorf("failed to verify contribution: %v\n%s", err, output)
+		return err
+	}
+	if err := verifyPTauStep(prev, next); err != nil {
+		return fmt.Errorf("contribution verification failed: %v", err)
 	}
 
 	// If verification succeeds, update the state
-	s.PTauPath = tmpFile.Name()
-
-	if !strings.Contains(string(output), "Powers of Tau Ok!") {
-		return fmt.Errorf("contribution verification failed")
-	}
+	s.PTauPath = ptauStepPath(s.dir, s.EpochNumber, msg.Step+1)
 
 	return nil
 }
@@ -164,22 +238,18 @@ func (s *PTauCeremonyState) FinalizeCeremony() (string, error) {
 	}
 
 	// Create the final PTau file path
-	finalPath := filepath.Join(filepath.Dir(s.PTauPath), fmt.Sprintf("pot_epoch%d_final.ptau", s.EpochNumber))
-
-	// Add a random beacon contribution
-	// The beacon hash is a random 64-character hex string
-	beaconHash := "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
-	numIterationsExp := 10 // Number of iterations as a power of 2 (2^10 = 1024 iterations)
+	finalPath := ptauFinalPath(s.dir, s.EpochNumber)
 
 	log.Info().Msgf("Adding random beacon to PTau file: %s", s.PTauPath)
 
-	// Use snarkjs to add a random beacon
-	cmd := exec.Command("npx", "snarkjs", "powersoftau", "beacon",
-		s.PTauPath, finalPath, beaconHash, fmt.Sprintf("%d", numIterationsExp))
-
-	output, err := cmd.CombinedOutput()
+	// The beacon is a contribution anyone can recompute from the public beacon value
+	phase1, err := readPTau(s.PTauPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to add beacon: %v\n%s", err, output)
+		return "", err
+	}
+	contributePTau(phase1, beaconSeed(ptauBeacon, ptauBeaconIterationsExp))
+	if _, err := writePTau(finalPath, phase1); err != nil {
+		return "", fmt.Errorf("failed to add beacon: %v", err)
 	}
 
 	// Update the state
@@ -187,102 +257,3 @@ func (s *PTauCeremonyState) FinalizeCeremony() (string, error) {
 
 	return finalPath, nil
 }
-
-// GenerateCircomKeys generates the proving and verification keys for a specific Circom circuit
-// using the finalized Powers of Tau ceremony output
-func GenerateCircomKeys(ptauPath, circuitPath, outputDir string) (string, string, error) {
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return "", "", fmt.Errorf("failed to create output directory: %v", err)
-	}
-
-	// Get the circuit name from the path
-	circuitName := filepath.Base(circuitPath)
-	circuitName = strings.TrimSuffix(circuitName, filepath.Ext(circuitName))
-
-	// Compile the circuit
-	r1csPath := filepath.Join(outputDir, circuitName+".r1cs")
-	compileCmd := exec.Command("npx", "circom", circuitPath,
-		"--r1cs", "--output", outputDir)
-
-	compileOutput, err := compileCmd.CombinedOutput()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to compile circuit: %v\n%s", err, compileOutput)
-	}
-
-	// Generate the zkey file (proving key)
-	zkeyPath := filepath.Join(outputDir, circuitName+".zkey")
-	zkeyCmd := exec.Command("npx", "snarkjs", "groth16", "setup",
-		r1csPath, ptauPath, zkeyPath)
-
-	zkeyOutput, err := zkeyCmd.CombinedOutput()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate zkey: %v\n%s", err, zkeyOutput)
-	}
-
-	// Export the verification key
-	vkeyPath := filepath.Join(outputDir, circuitName+"_verification_key.json")
-	vkeyCmd := exec.Command("npx", "snarkjs", "zkey", "export", "verificationkey",
-		zkeyPath, vkeyPath)
-
-	vkeyOutput, err := vkeyCmd.CombinedOutput()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to export verification key: %v\n%s", err, vkeyOutput)
-	}
-
-	return zkeyPath, vkeyPath, nil
-}
-
-// GenerateProof generates a proof for a Circom circuit using the provided inputs
-func GenerateProof(zkeyPath, inputsPath, outputDir string) (string, string, error) {
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return "", "", fmt.Errorf("failed to create output directory: %v", err)
-	}
-
-	// Get the circuit name from the zkey path
-	zkeyName := filepath.Base(zkeyPath)
-	zkeyName = strings.TrimSuffix(zkeyName, filepath.Ext(zkeyName))
-
-	// Generate the witness
-	wtnsPath := filepath.Join(outputDir, zkeyName+".wtns")
-	wtnsCmd := exec.Command("npx", "snarkjs", "wtns", "calculate",
-		zkeyPath, inputsPath, wtnsPath)
-
-	wtnsOutput, err := wtnsCmd.CombinedOutput()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate witness: %v\n%s", err, wtnsOutput)
-	}
-
-	// Generate the proof
-	proofPath := filepath.Join(outputDir, zkeyName+"_proof.json")
-	publicPath := filepath.Join(outputDir, zkeyName+"_public.json")
-	proofCmd := exec.Command("npx", "snarkjs", "groth16", "prove",
-		zkeyPath, wtnsPath, proofPath, publicPath)
-
-	proofOutput, err := proofCmd.CombinedOutput()
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate proof: %v\n%s", err, proofOutput)
-	}
-
-	return proofPath, publicPath, nil
-}
-
-// VerifyCircomProof verifies a proof generated for a Circom circuit
-func VerifyCircomProof(vkeyPath, proofPath, publicPath string) (bool, error) {
-	// Verify the proof
-	verifyCmd := exec.Command("npx", "snarkjs", "groth16", "verify",
-		vkeyPath, publicPath, proofPath)
-
-	verifyOutput, err := verifyCmd.CombinedOutput()
-	if err != nil {
-		return false, fmt.Errorf("failed to verify proof: %v\n%s", err, verifyOutput)
-	}
-
-	// Check if the verification was successful
-	if strings.Contains(string(verifyOutput), "OK") {
-		return true, nil
-	}
-
-	return false, nil
-}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sync"
 	"testing"
@@ -27,11 +26,6 @@ func init() {
 
 // TestCRSCeremonyIntegration tests the CRS ceremony with real p2p nodes
 func TestCRSCeremonyIntegration(t *testing.T) {
-	// Skip test if snarkjs is not installed
-	if _, err := exec.LookPath("npx"); err != nil {
-		t.Skip("Skipping test because npx is not installed")
-	}
-
 	// Create a temporary directory for the CRS ceremony files
 	tempDir, err := os.MkdirTemp("", "crs-ceremony-integration-test-*")
 	require.NoError(t, err)
@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/rs/zerolog/log"
+)
+
+// CRSKeyActivationDelay is how many batches past the network head the keys of a new CRS
+// epoch take over, leaving every node time to derive them
+const CRSKeyActivationDelay = 20
+
+// CRSKeySetup runs the circuit-specific setup on the final transcript of a ceremony
+type CRSKeySetup interface {
+	// ContributePhase2 runs the phase 2 of the circuit on the final transcript with a
+	// random contribution and returns its transcript
+	ContributePhase2(epoch int64, finalPTau string) ([]byte, error)
+
+	// ApplyPhase2 verifies a phase 2 transcript against the final transcript, keeps the
+	// keys it yields and proves with them from batch activateAt on. It returns the
+	// fingerprint of the verifying key.
+	ApplyPhase2(epoch int64, finalPTau string, phase2 []byte, activateAt uint64) (string, error)
+}
+
+// SetCRSKeySetup sets what derives circuit keys from finished ceremonies. The node that
+// finalizes a ceremony contributes the phase 2 and announces it, every node then switches
+// to the keys at the same batch.
+func (p *PBFT) SetCRSKeySetup(setup CRSKeySetup) {
+	p.crsKeySetup = setup
+}
+
+// setupCRSKeys contributes the phase 2 of a finished ceremony, switches to its keys and
+// announces them
+func (p *PBFT) setupCRSKeys(epoch int64, finalPath string) {
+	if p.crsKeySetup == nil {
+		return
+	}
+
+	phase2, err := p.crsKeySetup.ContributePhase2(epoch, finalPath)
+	if err != nil {
+		log.Error().Err(err).Int64("epoch", epoch).Msg("Failed to run circuit setup on CRS ceremony output")
+		return
+	}
+	activateAt := p.HighestSeenBatch() + CRSKeyActivationDelay
+	fingerprint, err := p.crsKeySetup.ApplyPhase2(epoch, finalPath, phase2, activateAt)
+	if err != nil {
+		log.Error().Err(err).Int64("epoch", epoch).Msg("Failed to apply circuit setup")
+		return
+	}
+
+	msg := &ConsensusMessage{
+		Type:           CRSKeys,
+		View:           p.view,
+		NodeID:         p.nodeID,
+		Timestamp:      time.Now(),
+		EpochNumber:    epoch,
+		Phase2Hash:     p.node.ProvideContent(phase2),
+		KeyFingerprint: fingerprint,
+		ActivateAt:     activateAt,
+	}
+	log.Info().Int64("epoch", epoch).Uint64("activate_at", activateAt).Str("fingerprint", fingerprint).Msg("Broadcasting circuit keys of CRS epoch")
+	if err := p.broadcast(msg); err != nil {
+		log.Error().Err(err).Int64("epoch", epoch).Msg("Failed to broadcast circuit keys")
+	}
+}
+
+// handleCRSKeys derives the circuit keys a peer announced for a ceremony this node holds
+// the final transcript of, and switches to them at the announced batch
+func (p *PBFT) handleCRSKeys(msg *ConsensusMessage) error {
+	log.Info().Int64("epoch", msg.EpochNumber).Str("from", msg.NodeID).Uint64("activate_at", msg.ActivateAt).Msg("Received circuit keys of CRS epoch")
+
+	if p.crsKeySetup == nil {
+		return nil
+	}
+	if msg.Phase2Hash == "" {
+		return fmt.Errorf("circuit keys message without phase 2 transcript")
+	}
+	finalPath := ptauFinalPath(p.crsCeremonyDir, msg.EpochNumber)
+	if _, err := os.Stat(finalPath); err != nil {
+		return fmt.Errorf("no final transcript of epoch %d: %v", msg.EpochNumber, err)
+	}
+
+	provider, _ := peer.Decode(msg.NodeID)
+	phase2, err := p.node.FetchContent(p.ctx, msg.Phase2Hash, provider)
+	if err != nil {
+		return fmt.Errorf("failed to fetch phase 2 transcript: %v", err)
+	}
+
+	// Deriving the keys takes a while, the consensus handler must not wait for it
+	go func() {
+		fingerprint, err := p.crsKeySetup.ApplyPhase2(msg.EpochNumber, finalPath, phase2, msg.ActivateAt)
+		if err != nil {
+			log.Error().Err(err).Int64("epoch", msg.EpochNumber).Msg("Failed to apply circuit setup")
+			return
+		}
+		if fingerprint != msg.KeyFingerprint {
+			log.Error().Int64("epoch", msg.EpochNumber).Str("fingerprint", fingerprint).Str("announced", msg.KeyFingerprint).Msg("Derived circuit keys differ from the announced ones")
+		}
+	}()
+	return nil
+}
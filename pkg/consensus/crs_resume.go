@@ -0,0 +1,396 @@
+package consensus
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// A ceremony survives crashes of its participants. Its state is persisted with a log of
+// the contribution, skip and completion messages applied so far. The ceremony leader skips
+// a participant that does not contribute in time with a signed CRSSkip message, the next
+// participant then contributes on the same transcript. A restarted node asks its peers for
+// the messages it missed with CRSRejoin, replays the CRSCatchUp answer through the regular
+// handlers and rejoins at the step it reaches.
+
+// DefaultCRSContributionTimeout is how long the ceremony leader waits for a participant's
+// contribution before skipping it
+const DefaultCRSContributionTimeout = 10 * time.Minute
+
+const (
+	// crsSkipCheckInterval is how often the ceremony leader looks for a stalled participant
+	crsSkipCheckInterval = 15 * time.Second
+
+	// crsRejoinInterval and crsRejoinAttempts bound how long a restarted node asks its
+	// peers for the ceremony messages it missed
+	crsRejoinInterval = 15 * time.Second
+	crsRejoinAttempts = 20
+)
+
+// SetCRSContributionTimeout sets how long the ceremony leader waits for a participant's
+// contribution before skipping it, 0 waits forever
+func (p *PBFT) SetCRSContributionTimeout(timeout time.Duration) {
+	p.crsContributionTimeout = timeout
+}
+
+// recordCRSMessageLocked appends a ceremony message to the log of the current ceremony and
+// persists it. Must be called with ptauStateLock held.
+func (p *PBFT) recordCRSMessageLocked(msg *ConsensusMessage) {
+	if p.ptauState == nil || p.ptauState.EpochNumber != msg.EpochNumber {
+		return
+	}
+	p.ptauState.Log = append(p.ptauState.Log, ceremonyLogEntry(msg))
+	if err := p.ptauState.Save(); err != nil {
+		log.Error().Err(err).Int64("epoch", msg.EpochNumber).Msg("Failed to persist CRS ceremony state")
+	}
+}
+
+// ceremonyLogEntry copies a ceremony message without the transcript it carries, which is
+// fetched by hash when the message is replayed
+func ceremonyLogEntry(msg *ConsensusMessage) *ConsensusMessage {
+	entry := *msg
+	entry.PTauFileData = nil
+	if msg.ContributionMsg != nil {
+		contribution := *msg.ContributionMsg
+		contribution.PTauFileData = nil
+		entry.ContributionMsg = &contribution
+	}
+	return &entry
+}
+
+// ceremonyLogStep returns the step a logged ceremony message applies to, completion
+// messages come after every step
+func ceremonyLogStep(msg *ConsensusMessage) int {
+	switch msg.Type {
+	case CRSContribution:
+		return msg.ContributionMsg.Step
+	case CRSSkip:
+		return msg.Step
+	default:
+		return math.MaxInt
+	}
+}
+
+// resumeCRSCeremony restores the ceremony persisted in the ceremony directory and rejoins
+// it when it was interrupted
+func (p *PBFT) resumeCRSCeremony() {
+	st, err := LoadPTauCeremonyState(p.crsCeremonyDir)
+	if err != nil {
+		log.Error().Err(err).Str("dir", p.crsCeremonyDir).Msg("Failed to restore CRS ceremony")
+		return
+	}
+	if st == nil {
+		return
+	}
+
+	p.ptauStateLock.Lock()
+	p.ptauState = st
+	p.currentEpoch = st.EpochNumber
+	p.ptauStateLock.Unlock()
+
+	if st.PTauPath == ptauFinalPath(st.dir, st.EpochNumber) {
+		log.Info().Int64("epoch", st.EpochNumber).Msg("Restored finished CRS ceremony")
+		return
+	}
+	log.Info().Int64("epoch", st.EpochNumber).Int("step", st.CurrentStep).Int("participants", len(st.Participants)).Msg("Restored unfinished CRS ceremony, rejoining")
+	go p.rejoinCRSCeremony(st.EpochNumber)
+}
+
+// rejoinCRSCeremony asks peers for the messages of the ceremony of epoch this node missed
+// until one answers
+func (p *PBFT) rejoinCRSCeremony(epoch int64) {
+	p.crsRejoining.Store(true)
+	defer p.crsRejoining.Store(false)
+
+	for attempt := 0; attempt < crsRejoinAttempts; attempt++ {
+		p.ptauStateLock.RLock()
+		st := p.ptauState
+		current := st != nil && st.EpochNumber == epoch
+		step := 0
+		if current {
+			step = st.CurrentStep
+		}
+		p.ptauStateLock.RUnlock()
+		if !current || !p.crsRejoining.Load() {
+			return
+		}
+
+		// Peers are only connected a while after startup
+		if len(p.node.GetPeers()) > 0 {
+			msg := &ConsensusMessage{
+				Type:        CRSRejoin,
+				View:        p.view,
+				NodeID:      p.nodeID,
+				Timestamp:   time.Now(),
+				EpochNumber: epoch,
+				Step:        step,
+			}
+			if err := p.broadcast(msg); err != nil {
+				log.Warn().Err(err).Int64("epoch", epoch).Msg("Failed to ask peers for missed CRS ceremony messages")
+			}
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(crsRejoinInterval):
+		}
+	}
+	log.Warn().Int64("epoch", epoch).Msg("No peer answered, CRS ceremony not rejoined")
+}
+
+// handleCRSRejoin sends a restarted node the ceremony messages from its recorded step on.
+// The ceremony leader answers participants and participants answer the leader.
+func (p *PBFT) handleCRSRejoin(msg *ConsensusMessage) error {
+	p.ptauStateLock.RLock()
+	st := p.ptauState
+	if st == nil || st.EpochNumber != msg.EpochNumber || (st.Leader != p.nodeID && msg.NodeID != st.Leader) {
+		p.ptauStateLock.RUnlock()
+		return nil
+	}
+	var evidence []*ConsensusMessage
+	for _, entry := range st.Log {
+		if ceremonyLogStep(entry) >= msg.Step {
+			evidence = append(evidence, entry)
+		}
+	}
+	dir := st.dir
+	p.ptauStateLock.RUnlock()
+
+	// The announcements of the transcripts may be long gone
+	for _, entry := range evidence {
+		path := ptauFinalPath(dir, entry.EpochNumber)
+		switch entry.Type {
+		case CRSContribution:
+			path = ptauStepPath(dir, entry.EpochNumber, entry.ContributionMsg.Step+1)
+		case CRSSkip:
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			p.node.ProvideContent(data)
+		}
+	}
+
+	log.Info().Int64("epoch", msg.EpochNumber).Str("node", msg.NodeID).Int("step", msg.Step).Int("messages", len(evidence)).Msg("Helping node rejoin CRS ceremony")
+	return p.broadcast(&ConsensusMessage{
+		Type:        CRSCatchUp,
+		View:        p.view,
+		NodeID:      p.nodeID,
+		Timestamp:   time.Now(),
+		EpochNumber: msg.EpochNumber,
+		Step:        msg.Step,
+		Evidence:    evidence,
+	})
+}
+
+// handleCRSCatchUp replays the ceremony messages a peer sent after this node restarted and
+// continues the ceremony from where it stands
+func (p *PBFT) handleCRSCatchUp(msg *ConsensusMessage) error {
+	if !p.crsRejoining.Load() {
+		return nil
+	}
+
+	p.crsCatchingUp.Store(true)
+	err := p.replayCRSMessages(msg.EpochNumber, msg.Evidence)
+	p.crsCatchingUp.Store(false)
+	if err != nil {
+		return fmt.Errorf("failed to catch up with CRS ceremony: %v", err)
+	}
+	p.crsRejoining.Store(false)
+
+	log.Info().Int64("epoch", msg.EpochNumber).Str("from", msg.NodeID).Int("messages", len(msg.Evidence)).Msg("Caught up with CRS ceremony")
+	return p.continueCRSCeremony()
+}
+
+// replayCRSMessages applies the logged ceremony messages of epoch this node has not applied.
+// They are relayed, so only their signatures authenticate them.
+func (p *PBFT) replayCRSMessages(epoch int64, evidence []*ConsensusMessage) error {
+	p.ptauStateLock.RLock()
+	st := p.ptauState
+	if st == nil || st.EpochNumber != epoch {
+		p.ptauStateLock.RUnlock()
+		return fmt.Errorf("no ceremony of epoch %d", epoch)
+	}
+	applied := make(map[string]bool, len(st.Log))
+	for _, entry := range st.Log {
+		applied[entry.Hash()] = true
+	}
+	p.ptauStateLock.RUnlock()
+
+	for _, relayed := range evidence {
+		entry := ceremonyLogEntry(relayed)
+		if entry.EpochNumber != epoch {
+			return fmt.Errorf("%s message of epoch %d in catch-up of epoch %d", entry.Type, entry.EpochNumber, epoch)
+		}
+		if applied[entry.Hash()] {
+			continue
+		}
+		if !p.isValidator(entry.NodeID) {
+			return fmt.Errorf("%s message from %s, which is not a validator", entry.Type, entry.NodeID)
+		}
+		if err := p.verifyMessage(entry); err != nil {
+			return err
+		}
+
+		var err error
+		switch entry.Type {
+		case CRSContribution:
+			if entry.ContributionMsg == nil {
+				return fmt.Errorf("CRS contribution message without contribution")
+			}
+			err = p.handleCRSContribution(entry)
+		case CRSSkip:
+			err = p.handleCRSSkip(entry)
+		case CRSCeremonyComplete:
+			err = p.handleCRSCeremonyComplete(entry)
+		default:
+			err = fmt.Errorf("unexpected %s message", entry.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// continueCRSCeremony contributes when it is this node's turn, or finalizes a ceremony
+// every participant contributed to when this node leads
+func (p *PBFT) continueCRSCeremony() error {
+	p.ptauStateLock.RLock()
+	st := p.ptauState
+	if st == nil {
+		p.ptauStateLock.RUnlock()
+		return nil
+	}
+	myTurn := st.CheckTurn(p.nodeID)
+	unfinalized := st.CurrentStep >= len(st.Participants) && st.PTauPath != ptauFinalPath(st.dir, st.EpochNumber)
+	p.ptauStateLock.RUnlock()
+
+	if myTurn {
+		return p.contributeToCRSCeremony()
+	}
+	if unfinalized && p.isLeader {
+		return p.finalizeCRSCeremony()
+	}
+	return nil
+}
+
+// watchCRSContributions has the ceremony leader skip participants that stall the ceremony
+func (p *PBFT) watchCRSContributions() {
+	ticker := time.NewTicker(crsSkipCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.skipStalledParticipant()
+		}
+	}
+}
+
+// skipStalledParticipant skips the participant whose turn it is if it has not contributed
+// within the contribution timeout, and this node leads the ceremony
+func (p *PBFT) skipStalledParticipant() {
+	if p.crsContributionTimeout <= 0 {
+		return
+	}
+
+	p.ptauStateLock.RLock()
+	st := p.ptauState
+	if st == nil || st.Leader != p.nodeID || st.CurrentStep >= len(st.Participants) ||
+		time.Since(st.stepStarted) < p.crsContributionTimeout {
+		p.ptauStateLock.RUnlock()
+		return
+	}
+	epoch, step, stalled := st.EpochNumber, st.CurrentStep, st.Participants[st.CurrentStep]
+	p.ptauStateLock.RUnlock()
+
+	// The leader's own contribution is made as soon as its turn comes
+	if stalled == p.nodeID {
+		return
+	}
+
+	msg := &ConsensusMessage{
+		Type:        CRSSkip,
+		View:        p.view,
+		NodeID:      p.nodeID,
+		Timestamp:   time.Now(),
+		EpochNumber: epoch,
+		Step:        step,
+		SkippedNode: stalled,
+	}
+	log.Warn().Int64("epoch", epoch).Int("step", step).Str("participant", stalled).Dur("timeout", p.crsContributionTimeout).Msg("Skipping stalled CRS ceremony participant")
+	if err := p.broadcast(msg); err != nil {
+		log.Error().Err(err).Int64("epoch", epoch).Msg("Failed to broadcast CRS skip")
+		return
+	}
+	if err := p.applyCRSSkip(msg); err != nil {
+		log.Error().Err(err).Int64("epoch", epoch).Msg("Failed to skip CRS ceremony participant")
+		return
+	}
+	if err := p.continueCRSCeremony(); err != nil {
+		log.Error().Err(err).Int64("epoch", epoch).Msg("Failed to continue CRS ceremony")
+	}
+}
+
+// handleCRSSkip applies a skip the ceremony leader signed
+func (p *PBFT) handleCRSSkip(msg *ConsensusMessage) error {
+	log.Info().Int64("epoch", msg.EpochNumber).Int("step", msg.Step).Str("participant", msg.SkippedNode).Msg("Received CRS skip message")
+
+	p.ptauStateLock.RLock()
+	st := p.ptauState
+	leader := ""
+	if st != nil && st.EpochNumber == msg.EpochNumber {
+		leader = st.Leader
+	}
+	p.ptauStateLock.RUnlock()
+	if leader == "" {
+		return fmt.Errorf("no matching CRS ceremony for epoch %d", msg.EpochNumber)
+	}
+	if msg.NodeID != leader {
+		return fmt.Errorf("skip from %s, only the ceremony leader %s may skip participants", msg.NodeID, leader)
+	}
+
+	if err := p.applyCRSSkip(msg); err != nil {
+		return err
+	}
+	if p.crsCatchingUp.Load() {
+		return nil
+	}
+	return p.continueCRSCeremony()
+}
+
+// applyCRSSkip drops the participant a skip message names from the current ceremony
+func (p *PBFT) applyCRSSkip(msg *ConsensusMessage) error {
+	p.ptauStateLock.Lock()
+	defer p.ptauStateLock.Unlock()
+
+	st := p.ptauState
+	if st == nil || st.EpochNumber != msg.EpochNumber {
+		return fmt.Errorf("no matching CRS ceremony for epoch %d", msg.EpochNumber)
+	}
+
+	// A contribution that arrived here before the leader's skip is dropped, the leader's
+	// order is the one every node follows
+	if n := len(st.Log); n > 0 && st.CurrentStep == msg.Step+1 {
+		last := st.Log[n-1]
+		if last.Type == CRSContribution && last.ContributionMsg.Step == msg.Step && last.NodeID == msg.SkippedNode {
+			log.Warn().Int64("epoch", st.EpochNumber).Int("step", msg.Step).Str("participant", msg.SkippedNode).Msg("Dropping contribution made after the participant was skipped")
+			st.Log = st.Log[:n-1]
+			st.CurrentStep = msg.Step
+			st.PTauPath = ptauStepPath(st.dir, st.EpochNumber, msg.Step)
+		}
+	}
+
+	if err := st.Skip(msg.Step, msg.SkippedNode); err != nil {
+		return err
+	}
+	log.Warn().Int64("epoch", st.EpochNumber).Int("step", msg.Step).Str("participant", msg.SkippedNode).Int("remaining", len(st.Participants)-st.CurrentStep).Msg("Skipped CRS ceremony participant")
+	p.recordCRSMessageLocked(msg)
+	return nil
+}
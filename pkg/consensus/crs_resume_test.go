@@ -0,0 +1,66 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTauCeremonyStatePersists(t *testing.T) {
+	dir := t.TempDir()
+	st, err := NewPTauCeremonyState(1, []string{"a", "b"}, 3, dir)
+	require.NoError(t, err)
+	st.Leader = "a"
+	_, err = st.AddContribution("a", "entropy")
+	require.NoError(t, err)
+	require.NoError(t, st.Save())
+
+	restored, err := LoadPTauCeremonyState(dir)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), restored.EpochNumber)
+	require.Equal(t, "a", restored.Leader)
+	require.Equal(t, 1, restored.CurrentStep)
+	require.Equal(t, ptauStepPath(dir, 1, 1), restored.PTauPath)
+	require.True(t, restored.CheckTurn("b"))
+
+	// A directory without a ceremony has nothing to restore
+	none, err := LoadPTauCeremonyState(t.TempDir())
+	require.NoError(t, err)
+	require.Nil(t, none)
+}
+
+func TestCRSSkipParticipant(t *testing.T) {
+	dir := t.TempDir()
+	p := NewPBFT(nil, "a", true)
+	p.crsCeremonyDir = dir
+	st, err := NewPTauCeremonyState(1, []string{"b", "c", "a"}, 3, dir)
+	require.NoError(t, err)
+	st.Leader = "a"
+	p.ptauState = st
+	p.currentEpoch = 1
+
+	// Only the ceremony leader may skip, and only the participant whose turn it is
+	require.Error(t, p.handleCRSSkip(&ConsensusMessage{Type: CRSSkip, NodeID: "c", EpochNumber: 1, Step: 0, SkippedNode: "b"}))
+	require.Error(t, p.applyCRSSkip(&ConsensusMessage{Type: CRSSkip, NodeID: "a", EpochNumber: 1, Step: 0, SkippedNode: "c"}))
+
+	require.NoError(t, p.applyCRSSkip(&ConsensusMessage{Type: CRSSkip, NodeID: "a", EpochNumber: 1, Step: 0, SkippedNode: "b"}))
+	require.Equal(t, []string{"c", "a"}, st.Participants)
+	require.True(t, st.CheckTurn("c"))
+
+	// A contribution that raced the skip is rolled back
+	contribution, err := st.AddContribution("c", "entropy")
+	require.NoError(t, err)
+	p.recordCRSMessageLocked(&ConsensusMessage{Type: CRSContribution, NodeID: "c", EpochNumber: 1, ContributionMsg: contribution})
+	require.NoError(t, p.applyCRSSkip(&ConsensusMessage{Type: CRSSkip, NodeID: "a", EpochNumber: 1, Step: 0, SkippedNode: "c"}))
+	require.Equal(t, []string{"a"}, st.Participants)
+	require.Equal(t, 0, st.CurrentStep)
+	require.Equal(t, ptauStepPath(dir, 1, 0), st.PTauPath)
+
+	// The skips are the ceremony's record after a restart
+	restored, err := LoadPTauCeremonyState(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, restored.Participants)
+	require.Len(t, restored.Log, 2)
+	require.Equal(t, CRSSkip, restored.Log[1].Type)
+	require.True(t, restored.CheckTurn("a"))
+}
@@ -0,0 +1,198 @@
+package consensus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ptauFilePattern matches the ceremony files written by PTauCeremonyState,
+// e.g. pot_epoch3_1.ptau for an intermediate step or pot_epoch3_final.ptau
+var ptauFilePattern = regexp.MustCompile(`^pot_epoch(\d+)_(\d+|final)\.ptau$`)
+
+// CRSRetentionPolicy controls which Powers of Tau artifacts are kept in the ceremony directory
+type CRSRetentionPolicy struct {
+	// KeepFinalEpochs is the number of most recent final ptau files to keep (0 keeps all of
+	// them). Proofs of an earlier CRS epoch are verified against its final file, so pruning
+	// finals is opt-in.
+	KeepFinalEpochs int
+	// DeleteIntermediates removes the per-step ptau files of an epoch once its final file exists
+	DeleteIntermediates bool
+	// VerifyBeforeDelete only removes intermediates after the final transcript passes verification
+	VerifyBeforeDelete bool
+}
+
+// DefaultCRSRetentionPolicy keeps the final file of every epoch and drops verified intermediates
+func DefaultCRSRetentionPolicy() CRSRetentionPolicy {
+	return CRSRetentionPolicy{
+		DeleteIntermediates: true,
+		VerifyBeforeDelete:  true,
+	}
+}
+
+// CRSArtifact describes a ptau file found in the ceremony directory
+type CRSArtifact struct {
+	Path  string
+	Epoch int64
+	Step  int // -1 for the final file
+	Final bool
+	Size  int64
+}
+
+// DefaultCRSCeremonyDir returns the directory used for ceremony files when none is configured
+func DefaultCRSCeremonyDir() string {
+	return filepath.Join(os.TempDir(), "zkrollup", "crs")
+}
+
+// ListCRSArtifacts returns the ceremony files in dir ordered by epoch and step
+func ListCRSArtifacts(dir string) ([]CRSArtifact, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ceremony directory: %v", err)
+	}
+
+	var artifacts []CRSArtifact
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		matches := ptauFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		epoch, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		artifact := CRSArtifact{
+			Path:  filepath.Join(dir, entry.Name()),
+			Epoch: epoch,
+			Step:  -1,
+			Final: matches[2] == "final",
+		}
+		if !artifact.Final {
+			if artifact.Step, err = strconv.Atoi(matches[2]); err != nil {
+				continue
+			}
+		}
+		if info, err := entry.Info(); err == nil {
+			artifact.Size = info.Size()
+		}
+
+		artifacts = append(artifacts, artifact)
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		if artifacts[i].Epoch != artifacts[j].Epoch {
+			return artifacts[i].Epoch < artifacts[j].Epoch
+		}
+		// Final files sort after every intermediate step of the same epoch
+		if artifacts[i].Final != artifacts[j].Final {
+			return !artifacts[i].Final
+		}
+		return artifacts[i].Step < artifacts[j].Step
+	})
+
+	return artifacts, nil
+}
+
+// VerifyFinalTranscript checks a finalized ptau file together with the intermediate steps
+// of its epoch, which must still be alongside it
+func VerifyFinalTranscript(path string) error {
+	matches := ptauFilePattern.FindStringSubmatch(filepath.Base(path))
+	if matches == nil || matches[2] != "final" {
+		return fmt.Errorf("%s is not a final ptau file", path)
+	}
+	epoch, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid epoch in %s: %v", path, err)
+	}
+
+	if err := VerifyPTauTranscript(filepath.Dir(path), epoch); err != nil {
+		return fmt.Errorf("transcript verification failed for %s: %v", path, err)
+	}
+	return nil
+}
+
+// PurgeCRSArtifacts applies the retention policy to dir and returns the artifacts that were
+// removed. With dryRun set nothing is deleted and the returned list is what would be removed.
+func PurgeCRSArtifacts(dir string, policy CRSRetentionPolicy, dryRun bool) ([]CRSArtifact, error) {
+	artifacts, err := ListCRSArtifacts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index final files by epoch
+	finals := make(map[int64]CRSArtifact)
+	var finalEpochs []int64
+	for _, a := range artifacts {
+		if a.Final {
+			finals[a.Epoch] = a
+			finalEpochs = append(finalEpochs, a.Epoch)
+		}
+	}
+
+	// Epochs whose final file falls outside the retention window are dropped entirely
+	expired := make(map[int64]bool)
+	if policy.KeepFinalEpochs > 0 && len(finalEpochs) > policy.KeepFinalEpochs {
+		for _, epoch := range finalEpochs[:len(finalEpochs)-policy.KeepFinalEpochs] {
+			expired[epoch] = true
+		}
+	}
+
+	// Intermediates may only go once the final transcript of their epoch is trusted
+	verified := make(map[int64]bool)
+	var removed []CRSArtifact
+	for _, a := range artifacts {
+		remove := false
+		switch {
+		case expired[a.Epoch]:
+			remove = true
+		case !a.Final && policy.DeleteIntermediates:
+			final, ok := finals[a.Epoch]
+			if !ok {
+				// Ceremony still in progress for this epoch
+				continue
+			}
+			if policy.VerifyBeforeDelete {
+				ok, checked := verified[a.Epoch]
+				if !checked {
+					if err := VerifyFinalTranscript(final.Path); err != nil {
+						log.Warn().Err(err).Int64("epoch", a.Epoch).Msg("Keeping CRS intermediates, final transcript did not verify")
+					} else {
+						ok = true
+					}
+					verified[a.Epoch] = ok
+				}
+				if !ok {
+					continue
+				}
+			}
+			remove = true
+		}
+
+		if !remove {
+			continue
+		}
+
+		if !dryRun {
+			if err := os.Remove(a.Path); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("failed to remove %s: %v", a.Path, err)
+			}
+		}
+		removed = append(removed, a)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,58 @@
+package consensus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeCRSArtifacts(t *testing.T) {
+	dir := t.TempDir()
+
+	files := []string{
+		"pot_epoch1_0.ptau", "pot_epoch1_1.ptau", "pot_epoch1_final.ptau",
+		"pot_epoch2_0.ptau", "pot_epoch2_1.ptau", "pot_epoch2_final.ptau",
+		"pot_epoch3_0.ptau", // ceremony still running
+		"unrelated.txt",
+	}
+	for _, name := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("ptau"), 0644))
+	}
+
+	policy := CRSRetentionPolicy{KeepFinalEpochs: 1, DeleteIntermediates: true}
+
+	// A dry run reports without touching the directory
+	removed, err := PurgeCRSArtifacts(dir, policy, true)
+	require.NoError(t, err)
+	require.Len(t, removed, 5)
+	artifacts, err := ListCRSArtifacts(dir)
+	require.NoError(t, err)
+	require.Len(t, artifacts, 7)
+
+	removed, err = PurgeCRSArtifacts(dir, policy, false)
+	require.NoError(t, err)
+	require.Len(t, removed, 5)
+
+	artifacts, err = ListCRSArtifacts(dir)
+	require.NoError(t, err)
+	require.Len(t, artifacts, 2)
+	require.Equal(t, "pot_epoch2_final.ptau", filepath.Base(artifacts[0].Path))
+	require.Equal(t, "pot_epoch3_0.ptau", filepath.Base(artifacts[1].Path))
+
+	_, err = os.Stat(filepath.Join(dir, "unrelated.txt"))
+	require.NoError(t, err)
+}
+
+func TestDefaultCRSRetentionKeepsFinals(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"pot_epoch1_final.ptau", "pot_epoch2_final.ptau", "pot_epoch3_final.ptau", "pot_epoch4_final.ptau"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("ptau"), 0644))
+	}
+
+	// Proofs of earlier epochs are verified against their final file
+	removed, err := PurgeCRSArtifacts(dir, DefaultCRSRetentionPolicy(), false)
+	require.NoError(t, err)
+	require.Empty(t, removed)
+}
@@ -0,0 +1,131 @@
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/signer"
+)
+
+// equivocationWindow is how many batches below the highest seen one the signed pre-prepares
+// of each node are kept to detect double signing
+const equivocationWindow = 64
+
+// DoubleSignEvidence shows a validator signed pre-prepares for two different batches with
+// the same number in the same view. Both messages carry their signatures, so anyone can
+// check the evidence with Verify without trusting the node that collected it.
+type DoubleSignEvidence struct {
+	Signer common.Address   `json:"signer"` // Address both messages were signed with
+	First  ConsensusMessage `json:"first"`
+	Second ConsensusMessage `json:"second"`
+}
+
+// EvidenceHandler receives the evidence of every double signing detected in HandleMessage
+type EvidenceHandler func(evidence *DoubleSignEvidence)
+
+// signedProposal is the first signed pre-prepare a node sent for a batch number
+type signedProposal struct {
+	msg      *ConsensusMessage
+	reported bool // Evidence against the node was produced for this batch number
+}
+
+// Verify checks both messages are conflicting pre-prepares signed by the evidence's signer
+func (e *DoubleSignEvidence) Verify() error {
+	first, second := &e.First, &e.Second
+	if first.Type != PrePrepare || second.Type != PrePrepare {
+		return fmt.Errorf("evidence messages are %s and %s, not pre-prepares", first.Type, second.Type)
+	}
+	if first.NodeID != second.NodeID {
+		return fmt.Errorf("evidence messages are from %s and %s", first.NodeID, second.NodeID)
+	}
+	if first.View != second.View {
+		return fmt.Errorf("evidence messages are from views %d and %d", first.View, second.View)
+	}
+	if proposedBatchNumber(first) != proposedBatchNumber(second) {
+		return fmt.Errorf("evidence messages propose batches %d and %d", proposedBatchNumber(first), proposedBatchNumber(second))
+	}
+	if first.BatchHash == second.BatchHash {
+		return fmt.Errorf("evidence messages propose the same batch %s", first.BatchHash)
+	}
+
+	for _, msg := range []*ConsensusMessage{first, second} {
+		from, err := signer.RecoverText([]byte(msg.Hash()), msg.Signature)
+		if err != nil {
+			return fmt.Errorf("invalid signature on evidence message: %v", err)
+		}
+		if from != e.Signer {
+			return fmt.Errorf("evidence message signed by %s, expected %s", from.Hex(), e.Signer.Hex())
+		}
+	}
+	return nil
+}
+
+// SetEvidenceHandler sets the hook receiving evidence of double signing. Only nodes with a
+// pinned signer can be caught, their messages are the only ones known to be signed.
+func (p *PBFT) SetEvidenceHandler(handler EvidenceHandler) {
+	p.onEvidence = handler
+}
+
+// detectDoubleSign remembers the first signed pre-prepare of each node per batch number and
+// returns evidence when msg proposes a different batch with the same number. Evidence is
+// produced once per node and batch number.
+func (p *PBFT) detectDoubleSign(msg *ConsensusMessage) *DoubleSignEvidence {
+	if msg.Type != PrePrepare || len(msg.Signature) == 0 {
+		return nil
+	}
+	address, ok := p.pinnedSigner(msg.NodeID)
+	if !ok {
+		return nil
+	}
+	batchNumber := proposedBatchNumber(msg)
+
+	p.proposalsLock.Lock()
+	defer p.proposalsLock.Unlock()
+
+	if highest := p.highestSeenBatch.Load(); highest > equivocationWindow {
+		for number := range p.proposals {
+			if number < highest-equivocationWindow {
+				delete(p.proposals, number)
+			}
+		}
+		if batchNumber < highest-equivocationWindow {
+			return nil
+		}
+	}
+
+	byNode, ok := p.proposals[batchNumber]
+	if !ok {
+		byNode = make(map[string]*signedProposal)
+		p.proposals[batchNumber] = byNode
+	}
+	seen, ok := byNode[msg.NodeID]
+	if !ok {
+		first := *msg
+		byNode[msg.NodeID] = &signedProposal{msg: &first}
+		return nil
+	}
+	if seen.reported || seen.msg.View != msg.View || seen.msg.BatchHash == msg.BatchHash {
+		return nil
+	}
+	seen.reported = true
+
+	evidence := &DoubleSignEvidence{Signer: address, First: *seen.msg, Second: *msg}
+	log.Warn().
+		Str("node", msg.NodeID).
+		Str("signer", address.Hex()).
+		Uint64("batch_number", batchNumber).
+		Str("first_hash", seen.msg.BatchHash).
+		Str("second_hash", msg.BatchHash).
+		Msg("Validator signed conflicting pre-prepares")
+	return evidence
+}
+
+// proposedBatchNumber returns the number of the batch a pre-prepare proposes
+func proposedBatchNumber(msg *ConsensusMessage) uint64 {
+	if msg.Batch != nil {
+		return msg.Batch.BatchNumber
+	}
+	return msg.BatchNumber
+}
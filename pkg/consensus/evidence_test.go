@@ -0,0 +1,56 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/signer"
+)
+
+func TestDetectDoubleSign(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	validator := signer.NewLocalSigner(key)
+
+	p := NewPBFT(nil, "a", false)
+	p.SetMessageSigners(map[string]common.Address{"b": validator.Address()})
+
+	prePrepare := func(batchHash string) *ConsensusMessage {
+		msg := &ConsensusMessage{
+			Type:        PrePrepare,
+			BatchHash:   batchHash,
+			NodeID:      "b",
+			Timestamp:   time.Now(),
+			BatchNumber: 7,
+		}
+		msg.Signature, err = validator.SignText(context.Background(), []byte(msg.Hash()))
+		require.NoError(t, err)
+		return msg
+	}
+
+	// A resent proposal is not a conflict
+	first := prePrepare("0x01")
+	require.Nil(t, p.detectDoubleSign(first))
+	require.Nil(t, p.detectDoubleSign(prePrepare("0x01")))
+
+	evidence := p.detectDoubleSign(prePrepare("0x02"))
+	require.NotNil(t, evidence)
+	require.Equal(t, validator.Address(), evidence.Signer)
+	require.NoError(t, evidence.Verify())
+
+	// Evidence is produced once per node and batch number
+	require.Nil(t, p.detectDoubleSign(prePrepare("0x03")))
+
+	// Evidence cannot be pinned on another signer, or made from a single proposal
+	forged := *evidence
+	forged.Signer = common.Address{1}
+	require.Error(t, forged.Verify())
+	forged = *evidence
+	forged.Second = *first
+	require.Error(t, forged.Verify())
+}
@@ -3,20 +3,44 @@ package consensus
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/rs/zerolog/log"
 
 	"zkrollup/pkg/l1"
 	"zkrollup/pkg/p2p"
+	"zkrollup/pkg/signer"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
 )
 
+// maxCachedBatchBodies caps the batch bodies kept while waiting for their pre-prepare
+const maxCachedBatchBodies = 16
+
+// BatchValidator checks a proposed batch, returning an error if this node must not vote for it
+type BatchValidator func(batch *state.Batch) error
+
+// ErrBatchNotReady is returned by a BatchValidator for a batch following batches this node
+// has not applied yet. The round is kept until ResumeRounds validates it again.
+var ErrBatchNotReady = errors.New("earlier batches not applied yet")
+
+// LeaderChangeHandler is told whether this node leads after a peer announced a leader
+// rotation. Rounds of the previous view are abandoned by the rotation.
+type LeaderChangeHandler func(leader bool)
+
+// RoundObserver receives how long a round took from when this node first saw it until it
+// was decided
+type RoundObserver func(d time.Duration)
+
 // PBFT represents a PBFT consensus instance
 type PBFT struct {
 	node         *p2p.Node
@@ -25,6 +49,8 @@ type PBFT struct {
 	sequence     int64
 	states       map[string]*ConsensusState // Map from batch hash to consensus state
 	statesLock   sync.RWMutex
+	bodies       map[string]*state.Batch // Batch bodies that arrived before their pre-prepare, guarded by statesLock
+	bodyOrder    []string                // Hashes of bodies, oldest first
 	isLeader     bool
 	totalNodes   int
 	decidedBatch chan *state.Batch
@@ -33,13 +59,60 @@ type PBFT struct {
 	nodeIDs      []string     // List of all node IDs in the network
 	nodeIDsLock  sync.RWMutex // Lock for nodeIDs
 
+	// Set once the validator set was fixed by SetValidators, nodeIDs then no longer grows
+	validatorsPinned bool
+
+	// Validator set taking over once the next batch is decided, nil when none is scheduled
+	scheduledValidators []string
+
+	highestSeenBatch atomic.Uint64 // Highest batch number proposed by any peer
+
+	// validateBatch checks a proposed batch before this node votes for it
+	validateBatch BatchValidator
+
+	// observeRound is told the duration of every decided round
+	observeRound RoundObserver
+
+	// onLeaderChange is run after a leader rotation announced by a peer
+	onLeaderChange LeaderChangeHandler
+
+	// Key signing outgoing messages, and the addresses nodes must sign with
+	signer      signer.Signer
+	signers     map[string]common.Address
+	signersLock sync.RWMutex
+
+	// Signed pre-prepares by batch number and node, kept to catch validators proposing two
+	// batches with the same number. onEvidence receives the evidence of each one caught.
+	proposals     map[uint64]map[string]*signedProposal
+	proposalsLock sync.Mutex
+	onEvidence    EvidenceHandler
+
 	// CRS Ceremony related fields
-	crsManager      *l1.CRSManager     // L1 CRS Manager client
+	crsManager      *l1.CRSManager     // L1 CRS Manager client, nil when ceremonies are not anchored
+	crsTransactOpts CRSTransactOpts    // Signs this node's CRS Manager transactions
 	ptauState       *PTauCeremonyState // Current Powers of Tau ceremony state
 	ptauStateLock   sync.RWMutex       // Lock for ptauState
 	crsCeremonyDir  string             // Directory to store CRS ceremony files
 	currentEpoch    int64              // Current epoch number
 	crsCeremonyDone chan bool          // Channel to signal when CRS ceremony is complete
+	crsRetention    CRSRetentionPolicy // Retention policy for ceremony artifacts
+	crsPowerSize    int                // Ceremonies support circuits of up to 2^crsPowerSize constraints
+	crsKeySetup     CRSKeySetup        // Derives circuit keys from finished ceremonies, nil to skip it
+
+	// The ceremony leader skips a participant that has not contributed within
+	// crsContributionTimeout, 0 waits forever. crsRejoining is set while a restarted node
+	// waits for the ceremony messages it missed, crsCatchingUp holds back its own
+	// contribution while it replays them.
+	crsContributionTimeout time.Duration
+	crsRejoining           atomic.Bool
+	crsCatchingUp          atomic.Bool
+
+	// Checkpointing, guarded by statesLock: a checkpoint is announced every checkpointInterval
+	// decided batches and becomes stable once a quorum reported it, then persisted
+	checkpointInterval uint64
+	checkpointFile     string
+	checkpointVotes    map[uint64]map[string]*ConsensusMessage
+	stableCheckpoint   *StableCheckpoint
 }
 
 // NewPBFT creates a new PBFT consensus instance
@@ -51,21 +124,80 @@ func NewPBFT(node *p2p.Node, nodeID string, isLeader bool) *PBFT {
 		view:            0,
 		sequence:        0,
 		states:          make(map[string]*ConsensusState),
+		bodies:          make(map[string]*state.Batch),
 		isLeader:        isLeader,
 		totalNodes:      1, // Will be updated as nodes join
 		decidedBatch:    make(chan *state.Batch),
 		ctx:             ctx,
 		cancel:          cancel,
 		nodeIDs:         []string{nodeID}, // Initialize with self
-		crsCeremonyDir:  filepath.Join(os.TempDir(), "zkrollup", "crs"),
+		crsCeremonyDir:  DefaultCRSCeremonyDir(),
 		currentEpoch:    0,
 		crsCeremonyDone: make(chan bool),
+		crsRetention:    DefaultCRSRetentionPolicy(),
+		crsPowerSize:    DefaultCRSPowerSize,
+
+		crsContributionTimeout: DefaultCRSContributionTimeout,
+
+		checkpointInterval: DefaultCheckpointInterval,
+		checkpointVotes:    make(map[uint64]map[string]*ConsensusMessage),
+		proposals:          make(map[uint64]map[string]*signedProposal),
 	}
 }
 
-// SetCRSManager sets the L1 CRS Manager client
-func (p *PBFT) SetCRSManager(crsManager *l1.CRSManager) {
+// SetBatchValidator sets the hook run on every proposal before voting Prepare
+func (p *PBFT) SetBatchValidator(validator BatchValidator) {
+	p.validateBatch = validator
+}
+
+// SetRoundObserver sets the hook told the duration of every decided round
+func (p *PBFT) SetRoundObserver(observer RoundObserver) {
+	p.observeRound = observer
+}
+
+// SetLeaderChangeHandler sets the hook run after a peer announced a leader rotation
+func (p *PBFT) SetLeaderChangeHandler(handler LeaderChangeHandler) {
+	p.onLeaderChange = handler
+}
+
+// SetCRSManager has ceremonies recorded on the CRS Manager contract, with this node's
+// transactions signed by the options transactOpts returns
+func (p *PBFT) SetCRSManager(crsManager *l1.CRSManager, transactOpts CRSTransactOpts) {
 	p.crsManager = crsManager
+	p.crsTransactOpts = transactOpts
+}
+
+// SetCRSRetentionPolicy sets the retention policy applied to ceremony artifacts after each epoch
+func (p *PBFT) SetCRSRetentionPolicy(policy CRSRetentionPolicy) {
+	p.crsRetention = policy
+}
+
+// SetCRSCeremonyDir sets the directory used to store CRS ceremony files
+func (p *PBFT) SetCRSCeremonyDir(dir string) {
+	p.crsCeremonyDir = dir
+}
+
+// SetCRSPowerSize sets the size of the ceremonies this node starts or joins, every
+// participant must use the same one
+func (p *PBFT) SetCRSPowerSize(powerSize int) {
+	p.crsPowerSize = powerSize
+}
+
+// PurgeCRSArtifacts applies the retention policy to the ceremony directory immediately
+func (p *PBFT) PurgeCRSArtifacts(dryRun bool) ([]CRSArtifact, error) {
+	return PurgeCRSArtifacts(p.crsCeremonyDir, p.crsRetention, dryRun)
+}
+
+// collectCRSGarbage removes ceremony artifacts that fall outside the retention policy
+func (p *PBFT) collectCRSGarbage() {
+	removed, err := p.PurgeCRSArtifacts(false)
+	if err != nil {
+		log.Error().Err(err).Str("dir", p.crsCeremonyDir).Msg("Failed to garbage collect CRS artifacts")
+		return
+	}
+	if len(removed) > 0 {
+		log.Info().Int("removed", len(removed)).Str("dir", p.crsCeremonyDir).Msg("Garbage collected CRS artifacts")
+	}
 }
 
 // Start starts the consensus process
@@ -86,6 +218,10 @@ func (p *PBFT) Start() {
 		log.Error().Err(err).Str("dir", p.crsCeremonyDir).Msg("Failed to create CRS ceremony directory")
 	}
 
+	// Pick up a ceremony interrupted by a restart and watch for stalled participants
+	p.resumeCRSCeremony()
+	go p.watchCRSContributions()
+
 	// Log the handlers we're using
 	fmt.Printf("PBFT consensus started with handlers - OnTransaction: %v, OnBatch: %v, OnConsensus: %v\n",
 		newHandlers.OnTransaction != nil, newHandlers.OnBatch != nil, newHandlers.OnConsensus != nil)
@@ -101,6 +237,11 @@ func (p *PBFT) ProposeBatch(batch *state.Batch) error {
 	if !p.isLeader {
 		return fmt.Errorf("only leader can propose batches")
 	}
+	if p.rotationScheduled() {
+		if proposer := p.ProposerFor(batch.BatchNumber); proposer != p.nodeID {
+			return fmt.Errorf("batch %d is proposed by %s", batch.BatchNumber, proposer)
+		}
+	}
 
 	log.Info().Msg("Leader proposing new batch for consensus")
 
@@ -110,18 +251,24 @@ func (p *PBFT) ProposeBatch(batch *state.Batch) error {
 	p.states[state.BatchHash] = state
 	p.statesLock.Unlock()
 
+	// Send the batch body once, consensus messages only refer to it by hash
+	if err := p.node.BroadcastBatch(p.ctx, batch); err != nil {
+		log.Error().Err(err).Msg("Failed to broadcast batch body")
+		return fmt.Errorf("failed to broadcast batch: %v", err)
+	}
+
 	// Create and broadcast pre-prepare message
 	msg := &ConsensusMessage{
-		Type:      PrePrepare,
-		View:      p.view,
-		Sequence:  p.sequence,
-		BatchHash: state.BatchHash,
-		NodeID:    p.nodeID,
-		Timestamp: time.Now(),
-		Batch:     batch,
+		Type:        PrePrepare,
+		View:        p.view,
+		Sequence:    p.sequence,
+		BatchHash:   state.BatchHash,
+		NodeID:      p.nodeID,
+		Timestamp:   time.Now(),
+		BatchNumber: batch.BatchNumber,
 	}
 
-	log.Info().Str("batch_hash", state.BatchHash).Msg("Broadcasting pre-prepare message")
+	log.Info().Str("batch_hash", state.BatchHash).Strs(trace.BatchField, batch.TraceIDs()).Msg("Broadcasting pre-prepare message")
 
 	// Broadcast the message with explicit error handling
 	if err := p.broadcast(msg); err != nil {
@@ -130,10 +277,17 @@ func (p *PBFT) ProposeBatch(batch *state.Batch) error {
 	}
 
 	// Check if we're in standalone mode (no peers)
-	if p.totalNodes <= 1 {
+	if p.TotalNodes() <= 1 {
 		log.Info().Str("batch_hash", state.BatchHash).Msg("Running in standalone mode, automatically committing batch")
 		// In standalone mode, we can automatically commit the batch
+		p.statesLock.Lock()
 		state.CommitCount[p.nodeID] = true
+		state.Decided = true
+		p.checkpointLocked(batch)
+		p.statesLock.Unlock()
+		if p.observeRound != nil {
+			p.observeRound(time.Since(state.Started))
+		}
 		// Send the batch to the decided channel
 		p.decidedBatch <- batch
 	}
@@ -163,8 +317,9 @@ func (p *PBFT) ProposeBatch(batch *state.Batch) error {
 	return nil
 }
 
-// HandleMessage handles incoming consensus messages
-func (p *PBFT) HandleMessage(data []byte) error {
+// HandleMessage handles incoming consensus messages. from is the authenticated peer that
+// sent the message, which must be the node it claims to come from.
+func (p *PBFT) HandleMessage(from peer.ID, data []byte) error {
 	log.Info().Int("data_size", len(data)).Msg("Received consensus message")
 
 	// Handle empty messages
@@ -184,8 +339,33 @@ func (p *PBFT) HandleMessage(data []byte) error {
 		Str("batch_hash", msg.BatchHash).
 		Msg("Processing consensus message")
 
+	if msg.NodeID != from.String() {
+		return fmt.Errorf("consensus message claims to be from %s but was sent by %s", msg.NodeID, from)
+	}
+
 	// Add the node ID to our list if it's not already there
 	p.addNodeID(msg.NodeID)
+	if !p.isValidator(msg.NodeID) {
+		return fmt.Errorf("node %s is not in the validator set", msg.NodeID)
+	}
+	if err := p.verifyMessage(&msg); err != nil {
+		return err
+	}
+	if evidence := p.detectDoubleSign(&msg); evidence != nil {
+		if p.onEvidence != nil {
+			p.onEvidence(evidence)
+		}
+		return fmt.Errorf("node %s signed conflicting pre-prepares for batch %d", msg.NodeID, proposedBatchNumber(&msg))
+	}
+
+	// Track the network head so a restarting node knows how far behind it is
+	if msg.Type == PrePrepare {
+		batchNumber := msg.BatchNumber
+		if msg.Batch != nil {
+			batchNumber = msg.Batch.BatchNumber
+		}
+		p.observeBatch(batchNumber)
+	}
 
 	// Handle CRS ceremony messages
 	switch msg.Type {
@@ -195,23 +375,24 @@ func (p *PBFT) HandleMessage(data []byte) error {
 		return p.handleCRSContribution(&msg)
 	case CRSCeremonyComplete:
 		return p.handleCRSCeremonyComplete(&msg)
+	case CRSKeys:
+		return p.handleCRSKeys(&msg)
+	case CRSSkip:
+		return p.handleCRSSkip(&msg)
+	case CRSRejoin:
+		return p.handleCRSRejoin(&msg)
+	case CRSCatchUp:
+		return p.handleCRSCatchUp(&msg)
 	}
 
 	// Handle leader rotation messages separately as they don't depend on batch state
 	if msg.Type == LeaderRotation {
-		log.Info().Str("from", msg.NodeID).Str("next_leader", msg.NextLeader).Msg("Received leader rotation message")
-
-		// Update our leader status based on the leader's decision
-		p.isLeader = (msg.NextLeader == p.nodeID)
-		p.view = msg.View + 1 // Set view to match the leader's next view
-
-		if p.isLeader {
-			log.Info().Str("node_id", p.nodeID).Int64("view", p.view).Msg("This node is now the leader based on leader rotation message")
-		} else {
-			log.Info().Str("node_id", p.nodeID).Str("new_leader", msg.NextLeader).Int64("view", p.view).Msg("Leadership transferred based on leader rotation message")
-		}
+		return p.handleLeaderRotation(&msg)
+	}
 
-		return nil
+	// Checkpoints are reported by every validator independently of the view
+	if msg.Type == Checkpoint {
+		return p.handleCheckpoint(&msg)
 	}
 
 	// Verify message basics
@@ -226,9 +407,24 @@ func (p *PBFT) HandleMessage(data []byte) error {
 	state, exists := p.states[msg.BatchHash]
 	if !exists {
 		if msg.Type == PrePrepare {
+			// Batches up to the stable checkpoint are decided, their rounds are over
+			if cp := p.stableCheckpoint; cp != nil {
+				batchNumber := msg.BatchNumber
+				if msg.Batch != nil {
+					batchNumber = msg.Batch.BatchNumber
+				}
+				if batchNumber <= cp.BatchNumber {
+					return fmt.Errorf("pre-prepare for batch %d at or below stable checkpoint %d", batchNumber, cp.BatchNumber)
+				}
+			}
+
+			if err := p.checkProposer(&msg); err != nil {
+				return err
+			}
+
 			// Only create new state for PrePrepare messages
 			log.Info().Msg("Creating new consensus state for pre-prepare message")
-			state = NewConsensusState(msg.View, msg.Sequence, msg.Batch)
+			state = newConsensusState(msg.View, msg.Sequence, msg.BatchHash)
 			state.PrePrepareMsg = &msg
 			p.states[msg.BatchHash] = state
 		} else {
@@ -251,28 +447,23 @@ func (p *PBFT) HandleMessage(data []byte) error {
 		// Store the pre-prepare message
 		state.PrePrepareMsg = &msg
 
-		// Send prepare message
-		prepare := &ConsensusMessage{
-			Type:      Prepare,
-			View:      p.view,
-			Sequence:  msg.Sequence,
-			BatchHash: msg.BatchHash,
-			NodeID:    p.nodeID,
-			Timestamp: time.Now(),
-		}
-
-		log.Info().Str("batch_hash", msg.BatchHash).Msg("Sending prepare message")
-
-		// Add our prepare message to the state
-		state.PrepareCount[p.nodeID] = true
-
-		// Broadcast the prepare message
-		if err := p.broadcast(prepare); err != nil {
-			log.Error().Err(err).Msg("Failed to broadcast prepare message")
-			return fmt.Errorf("failed to broadcast prepare: %v", err)
+		// Older leaders embed the body, otherwise it is gossiped separately and may still be
+		// on its way
+		switch {
+		case msg.Batch != nil:
+			if BatchHash(msg.Batch) != msg.BatchHash {
+				delete(p.states, msg.BatchHash)
+				return fmt.Errorf("pre-prepare batch does not match hash %s", msg.BatchHash)
+			}
+			state.Batch = msg.Batch
+		case p.bodies[msg.BatchHash] != nil:
+			state.Batch = p.takeBodyLocked(msg.BatchHash)
+		default:
+			log.Info().Str("batch_hash", msg.BatchHash).Msg("Waiting for batch body")
+			return nil
 		}
 
-		state.Phase = Prepare
+		return p.prepareLocked(state)
 
 	case Prepare:
 		// Validate the prepare message
@@ -283,75 +474,192 @@ func (p *PBFT) HandleMessage(data []byte) error {
 		// Add the prepare message to the state
 		state.PrepareCount[msg.NodeID] = true
 
-		// Check if we have enough prepare messages to move to commit phase
-		if len(state.PrepareCount) >= 2*(p.totalNodes/3)+1 && !state.SentCommit {
-			// Send commit message
-			commit := &ConsensusMessage{
-				Type:      Commit,
-				View:      p.view,
-				Sequence:  msg.Sequence,
-				BatchHash: msg.BatchHash,
-				NodeID:    p.nodeID,
-				Timestamp: time.Now(),
-			}
+		return p.commitLocked(state)
 
-			log.Info().Str("batch_hash", msg.BatchHash).Msg("Sending commit message")
+	case Commit:
+		// Add the commit message to the state
+		state.CommitCount[msg.NodeID] = true
 
-			// Add our commit message to the state
-			state.CommitCount[p.nodeID] = true
-			state.SentCommit = true
+		p.decideLocked(state)
+	}
 
-			// Broadcast the commit message
-			if err := p.broadcast(commit); err != nil {
-				log.Error().Err(err).Msg("Failed to broadcast commit message")
-				return fmt.Errorf("failed to broadcast commit: %v", err)
+	return nil
+}
+
+// HandleBatchBody takes a batch body gossiped by the proposer. It resumes the round
+// waiting for it, or is kept until the pre-prepare referring to it arrives.
+func (p *PBFT) HandleBatchBody(batch *state.Batch) error {
+	hash := BatchHash(batch)
+
+	p.statesLock.Lock()
+	defer p.statesLock.Unlock()
+
+	state, exists := p.states[hash]
+	if !exists {
+		if _, cached := p.bodies[hash]; !cached {
+			p.bodies[hash] = batch
+			p.bodyOrder = append(p.bodyOrder, hash)
+			if len(p.bodyOrder) > maxCachedBatchBodies {
+				delete(p.bodies, p.bodyOrder[0])
+				p.bodyOrder = p.bodyOrder[1:]
 			}
+		}
+		return nil
+	}
+	if state.Batch != nil {
+		return nil
+	}
 
-			state.Phase = Commit
+	log.Info().Str("batch_hash", hash).Msg("Received batch body of pending round")
+	state.Batch = batch
+	if state.Phase == PrePrepare && state.PrePrepareMsg != nil {
+		if err := p.prepareLocked(state); err != nil {
+			return err
 		}
+	}
+	p.decideLocked(state)
+	return nil
+}
 
-	case Commit:
-		// Add the commit message to the state
-		state.CommitCount[msg.NodeID] = true
+// takeBodyLocked removes a cached batch body. Must be called with statesLock held.
+func (p *PBFT) takeBodyLocked(hash string) *state.Batch {
+	batch := p.bodies[hash]
+	delete(p.bodies, hash)
+	for i, h := range p.bodyOrder {
+		if h == hash {
+			p.bodyOrder = append(p.bodyOrder[:i], p.bodyOrder[i+1:]...)
+			break
+		}
+	}
+	return batch
+}
 
-		// Check if we have enough commit messages to decide
-		if len(state.CommitCount) >= 2*(p.totalNodes/3)+1 && !state.Decided {
-			log.Info().Str("batch_hash", msg.BatchHash).Msg("Batch decided")
-			state.Decided = true
-
-			// If we're the leader, we should rotate leadership
-			if p.isLeader {
-				nextLeader := p.rotateLeader()
-				log.Info().Str("next_leader", nextLeader).Msg("Rotating leadership")
-
-				// Send leader rotation message
-				rotation := &ConsensusMessage{
-					Type:       LeaderRotation,
-					View:       p.view,
-					Sequence:   p.sequence,
-					BatchHash:  "",
-					NodeID:     p.nodeID,
-					Timestamp:  time.Now(),
-					NextLeader: nextLeader,
-				}
+// prepareLocked validates the batch of a pre-prepared round and votes for it. Must be
+// called with statesLock held.
+func (p *PBFT) prepareLocked(state *ConsensusState) error {
+	msg := state.PrePrepareMsg
+
+	// Only vote for batches that execute cleanly to the declared state root
+	if p.validateBatch != nil {
+		if err := p.validateBatch(state.Batch); errors.Is(err, ErrBatchNotReady) {
+			log.Info().Err(err).Str("batch_hash", state.BatchHash).Msg("Waiting for earlier batches before voting")
+			return nil
+		} else if err != nil {
+			log.Warn().Err(err).Str("batch_hash", state.BatchHash).Str("leader", msg.NodeID).Strs(trace.BatchField, state.Batch.TraceIDs()).Msg("Refusing to vote for invalid batch")
+			delete(p.states, state.BatchHash)
+			return fmt.Errorf("invalid batch: %v", err)
+		}
+	}
 
-				if err := p.broadcast(rotation); err != nil {
-					log.Error().Err(err).Msg("Failed to broadcast leader rotation message")
-				}
+	// Send prepare message
+	prepare := &ConsensusMessage{
+		Type:      Prepare,
+		View:      p.view,
+		Sequence:  msg.Sequence,
+		BatchHash: state.BatchHash,
+		NodeID:    p.nodeID,
+		Timestamp: time.Now(),
+	}
 
-				// Update our leader status
-				p.isLeader = (nextLeader == p.nodeID)
-				p.view++
-			}
+	log.Info().Str("batch_hash", state.BatchHash).Msg("Sending prepare message")
+
+	// Add our prepare message to the state
+	state.PrepareCount[p.nodeID] = true
+
+	// Broadcast the prepare message
+	if err := p.broadcast(prepare); err != nil {
+		log.Error().Err(err).Msg("Failed to broadcast prepare message")
+		return fmt.Errorf("failed to broadcast prepare: %v", err)
+	}
 
-			// Send the batch to the decided channel
-			p.decidedBatch <- state.Batch
+	state.Phase = Prepare
+
+	// Prepares of the other nodes may have arrived while the body was on its way
+	return p.commitLocked(state)
+}
+
+// ResumeRounds validates again the proposals kept while the batches before them were not
+// applied, voting for those that are valid now
+func (p *PBFT) ResumeRounds() {
+	p.statesLock.Lock()
+	defer p.statesLock.Unlock()
+
+	for _, state := range p.states {
+		if state.Phase != PrePrepare || state.PrePrepareMsg == nil || state.Batch == nil {
+			continue
+		}
+		if err := p.prepareLocked(state); err != nil {
+			log.Error().Err(err).Str("batch_hash", state.BatchHash).Msg("Failed to resume consensus round")
+			continue
 		}
+		p.decideLocked(state)
 	}
+}
+
+// commitLocked sends this node's commit once enough nodes prepared a batch it holds. Must
+// be called with statesLock held.
+func (p *PBFT) commitLocked(state *ConsensusState) error {
+	// Check if we have enough prepare messages to move to commit phase
+	if !HasQuorum(len(state.PrepareCount), p.TotalNodes()) || state.SentCommit || state.Batch == nil {
+		return nil
+	}
+
+	// Send commit message
+	commit := &ConsensusMessage{
+		Type:      Commit,
+		View:      p.view,
+		Sequence:  state.Sequence,
+		BatchHash: state.BatchHash,
+		NodeID:    p.nodeID,
+		Timestamp: time.Now(),
+	}
+
+	log.Info().Str("batch_hash", state.BatchHash).Msg("Sending commit message")
+
+	// Add our commit message to the state
+	state.CommitCount[p.nodeID] = true
+	state.SentCommit = true
 
+	// Broadcast the commit message
+	if err := p.broadcast(commit); err != nil {
+		log.Error().Err(err).Msg("Failed to broadcast commit message")
+		return fmt.Errorf("failed to broadcast commit: %v", err)
+	}
+
+	state.Phase = Commit
 	return nil
 }
 
+// decideLocked delivers the batch once enough nodes committed to it and its body is
+// known. Must be called with statesLock held.
+func (p *PBFT) decideLocked(state *ConsensusState) {
+	// Check if we have enough commit messages to decide
+	if !HasQuorum(len(state.CommitCount), p.TotalNodes()) || state.Decided {
+		return
+	}
+	if state.Batch == nil {
+		log.Info().Str("batch_hash", state.BatchHash).Msg("Batch committed, waiting for its body")
+		return
+	}
+
+	log.Info().Str("batch_hash", state.BatchHash).Strs(trace.BatchField, state.Batch.TraceIDs()).Msg("Batch decided")
+	state.Decided = true
+
+	// Every validator decides the same batches, so they all switch to a scheduled validator
+	// set at the same point, before the leader rotates within it
+	p.applyScheduledValidators()
+	if p.observeRound != nil && !state.Started.IsZero() {
+		p.observeRound(time.Since(state.Started))
+	}
+
+	p.rotateLeaderLocked(state.Batch.BatchNumber)
+
+	p.checkpointLocked(state.Batch)
+
+	// Send the batch to the decided channel
+	p.decidedBatch <- state.Batch
+}
+
 // StartCRSCeremony initiates a new CRS ceremony
 func (p *PBFT) StartCRSCeremony() error {
 	if !p.isLeader {
@@ -373,10 +681,14 @@ func (p *PBFT) StartCRSCeremony() error {
 	log.Info().Strs("participants", sortedNodeIDs).Msg("Ordered participants for CRS ceremony")
 
 	// Create a new PTau ceremony state
-	ptauState, err := NewPTauCeremonyState(p.currentEpoch, sortedNodeIDs, 12, p.crsCeremonyDir)
+	ptauState, err := NewPTauCeremonyState(p.currentEpoch, sortedNodeIDs, p.crsPowerSize, p.crsCeremonyDir)
 	if err != nil {
 		return fmt.Errorf("failed to create PTau ceremony state: %v", err)
 	}
+	ptauState.Leader = p.nodeID
+	if err := ptauState.Save(); err != nil {
+		log.Error().Err(err).Msg("Failed to persist CRS ceremony state")
+	}
 
 	p.ptauStateLock.Lock()
 	p.ptauState = ptauState
@@ -434,6 +746,14 @@ func (p *PBFT) contributeToCRSCeremony() error {
 		return fmt.Errorf("failed to add contribution: %v", err)
 	}
 
+	// The next participant registers on L1 once it sees this contribution, so it must be
+	// recorded there first for the contract's order to match the ceremony's
+	p.anchorCRSContribution(contributionMsg.Step, contributionMsg.PTauFileData)
+
+	// The transcript is too large to embed, peers pull it by hash
+	contributionMsg.PTauFileHash = p.node.ProvideContent(contributionMsg.PTauFileData)
+	contributionMsg.PTauFileData = nil
+
 	// Create and broadcast CRS contribution message
 	msg := &ConsensusMessage{
 		Type:            CRSContribution,
@@ -443,7 +763,6 @@ func (p *PBFT) contributeToCRSCeremony() error {
 		Timestamp:       time.Now(),
 		EpochNumber:     p.currentEpoch,
 		ContributionMsg: contributionMsg,
-		PTauFileData:    contributionMsg.PTauFileData,
 	}
 
 	log.Info().Int64("epoch", p.currentEpoch).Int("step", contributionMsg.Step).Msg("Broadcasting CRS contribution message")
@@ -454,6 +773,12 @@ func (p *PBFT) contributeToCRSCeremony() error {
 		return fmt.Errorf("failed to broadcast CRS contribution: %v", err)
 	}
 
+	// Only a contribution peers have seen is persisted, a node that crashes before that
+	// contributes again after the restart
+	p.ptauStateLock.Lock()
+	p.recordCRSMessageLocked(msg)
+	p.ptauStateLock.Unlock()
+
 	return nil
 }
 
@@ -467,10 +792,14 @@ func (p *PBFT) handleCRSCeremonyStart(msg *ConsensusMessage) error {
 	}
 
 	// Create a new PTau ceremony state using the participants list from the message
-	ptauState, err := NewPTauCeremonyState(msg.EpochNumber, msg.Participants, 12, p.crsCeremonyDir)
+	ptauState, err := NewPTauCeremonyState(msg.EpochNumber, msg.Participants, p.crsPowerSize, p.crsCeremonyDir)
 	if err != nil {
 		return fmt.Errorf("failed to create PTau ceremony state: %v", err)
 	}
+	ptauState.Leader = msg.NodeID
+	if err := ptauState.Save(); err != nil {
+		log.Error().Err(err).Msg("Failed to persist CRS ceremony state")
+	}
 
 	p.ptauStateLock.Lock()
 	p.ptauState = ptauState
@@ -489,6 +818,18 @@ func (p *PBFT) handleCRSCeremonyStart(msg *ConsensusMessage) error {
 func (p *PBFT) handleCRSContribution(msg *ConsensusMessage) error {
 	log.Info().Int64("epoch", msg.EpochNumber).Str("from", msg.NodeID).Msg("Received CRS contribution message")
 
+	if msg.ContributionMsg == nil {
+		return fmt.Errorf("CRS contribution message without contribution")
+	}
+	if msg.ContributionMsg.ContributorID != msg.NodeID {
+		return fmt.Errorf("contribution of %s sent by %s", msg.ContributionMsg.ContributorID, msg.NodeID)
+	}
+	data, err := p.fetchPTau(msg.NodeID, msg.ContributionMsg.PTauFileData, msg.ContributionMsg.PTauFileHash)
+	if err != nil {
+		return err
+	}
+	msg.ContributionMsg.PTauFileData = data
+
 	// Check if we have an active CRS ceremony
 	p.ptauStateLock.RLock()
 	if p.ptauState == nil {
@@ -515,7 +856,7 @@ func (p *PBFT) handleCRSContribution(msg *ConsensusMessage) error {
 	if err != nil {
 		return fmt.Errorf("failed to create PTau file: %v", err)
 	}
-	if _, err := tauFile.Write(msg.PTauFileData); err != nil {
+	if _, err := tauFile.Write(msg.ContributionMsg.PTauFileData); err != nil {
 		return fmt.Errorf("failed to write PTau file: %v", err)
 	}
 	tauFile.Close()
@@ -523,17 +864,24 @@ func (p *PBFT) handleCRSContribution(msg *ConsensusMessage) error {
 
 	// Update the ceremony state
 	p.ptauState.CurrentStep = msg.ContributionMsg.Step + 1
+	p.ptauState.stepStarted = time.Now()
 
 	// Check if the ceremony is complete
 	isComplete := p.ptauState.CurrentStep >= len(p.ptauState.Participants)
 	if isComplete {
 		p.ptauState.Completed = true
 	}
+	p.recordCRSMessageLocked(msg)
 
 	// Check if it's this node's turn to contribute
 	isMyTurn := p.ptauState.CheckTurn(p.nodeID)
 	p.ptauStateLock.Unlock()
 
+	// A node catching up contributes once it has replayed every step it missed
+	if p.crsCatchingUp.Load() {
+		return nil
+	}
+
 	// If it's this node's turn, contribute to the ceremony
 	if isMyTurn {
 		p.contributeToCRSCeremony()
@@ -586,7 +934,7 @@ func (p *PBFT) finalizeCRSCeremony() error {
 		NodeID:       p.nodeID,
 		Timestamp:    time.Now(),
 		EpochNumber:  p.currentEpoch,
-		PTauFileData: finalData,
+		PTauFileHash: p.node.ProvideContent(finalData),
 	}
 
 	log.Info().Int64("epoch", p.currentEpoch).Msg("Broadcasting CRS ceremony complete message")
@@ -597,12 +945,25 @@ func (p *PBFT) finalizeCRSCeremony() error {
 		return fmt.Errorf("failed to broadcast CRS ceremony complete: %v", err)
 	}
 
+	p.ptauStateLock.Lock()
+	p.recordCRSMessageLocked(msg)
+	p.ptauStateLock.Unlock()
+
 	// Signal that the CRS ceremony is complete
 	select {
 	case p.crsCeremonyDone <- true:
 	default:
 	}
 
+	// Drop artifacts that are no longer needed now that the epoch is final
+	go p.collectCRSGarbage()
+
+	// Derive the circuit keys of the epoch for every node to switch to
+	go p.setupCRSKeys(p.currentEpoch, finalPath)
+
+	// Close the round on L1, which records its transcript and participants
+	go p.finalizeCRSOnL1(p.currentEpoch)
+
 	return nil
 }
 
@@ -610,8 +971,24 @@ func (p *PBFT) finalizeCRSCeremony() error {
 func (p *PBFT) handleCRSCeremonyComplete(msg *ConsensusMessage) error {
 	log.Info().Int64("epoch", msg.EpochNumber).Msg("Received CRS ceremony complete message")
 
+	data, err := p.fetchPTau(msg.NodeID, msg.PTauFileData, msg.PTauFileHash)
+	if err != nil {
+		return err
+	}
+	msg.PTauFileData = data
+
+	// The beacon must be applied to the last contribution this node verified
+	p.ptauStateLock.RLock()
+	if p.ptauState != nil && p.currentEpoch == msg.EpochNumber {
+		if err := verifyPTauFinal(p.ptauState.PTauPath, msg.PTauFileData); err != nil {
+			p.ptauStateLock.RUnlock()
+			return fmt.Errorf("invalid final PTau file: %v", err)
+		}
+	}
+	p.ptauStateLock.RUnlock()
+
 	// Save the final PTau file
-	finalPath := filepath.Join(p.crsCeremonyDir, fmt.Sprintf("pot_epoch%d_final.ptau", msg.EpochNumber))
+	finalPath := ptauFinalPath(p.crsCeremonyDir, msg.EpochNumber)
 	if err := os.WriteFile(finalPath, msg.PTauFileData, 0644); err != nil {
 		return fmt.Errorf("failed to save final PTau file: %v", err)
 	}
@@ -620,6 +997,7 @@ func (p *PBFT) handleCRSCeremonyComplete(msg *ConsensusMessage) error {
 	if p.ptauState != nil && p.currentEpoch == msg.EpochNumber {
 		p.ptauState.Completed = true
 		p.ptauState.PTauPath = finalPath
+		p.recordCRSMessageLocked(msg)
 	}
 	p.ptauStateLock.Unlock()
 
@@ -629,9 +1007,27 @@ func (p *PBFT) handleCRSCeremonyComplete(msg *ConsensusMessage) error {
 	default:
 	}
 
+	// Drop artifacts that are no longer needed now that the epoch is final
+	go p.collectCRSGarbage()
+
 	return nil
 }
 
+// fetchPTau returns a PTau file embedded in a message by older nodes, or pulls the file
+// announced by hash from its sender or any peer that has verified it
+func (p *PBFT) fetchPTau(sender string, data []byte, hash string) ([]byte, error) {
+	if len(data) > 0 || hash == "" {
+		return data, nil
+	}
+
+	provider, _ := peer.Decode(sender)
+	data, err := p.node.FetchContent(p.ctx, hash, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PTau file: %v", err)
+	}
+	return data, nil
+}
+
 // GetCRSCeremonyDoneChan returns the channel that signals when a CRS ceremony is complete
 func (p *PBFT) GetCRSCeremonyDoneChan() <-chan bool {
 	return p.crsCeremonyDone
@@ -639,6 +1035,10 @@ func (p *PBFT) GetCRSCeremonyDoneChan() <-chan bool {
 
 // broadcast sends a consensus message to all peers
 func (p *PBFT) broadcast(msg *ConsensusMessage) error {
+	if err := p.signMessage(msg); err != nil {
+		return err
+	}
+
 	// Marshal the message to JSON
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -653,6 +1053,46 @@ func (p *PBFT) broadcast(msg *ConsensusMessage) error {
 	return nil
 }
 
+// observeBatch records a batch number seen on the network
+func (p *PBFT) observeBatch(batchNumber uint64) {
+	for {
+		current := p.highestSeenBatch.Load()
+		if batchNumber <= current || p.highestSeenBatch.CompareAndSwap(current, batchNumber) {
+			return
+		}
+	}
+}
+
+// HighestSeenBatch returns the highest batch number proposed by any peer
+func (p *PBFT) HighestSeenBatch() uint64 {
+	return p.highestSeenBatch.Load()
+}
+
+// TotalNodes returns the number of nodes counted towards the quorum
+func (p *PBFT) TotalNodes() int {
+	p.nodeIDsLock.RLock()
+	defer p.nodeIDsLock.RUnlock()
+	return p.totalNodes
+}
+
+// OldestPendingRound returns how long the oldest undecided round has been open, 0 if every
+// round this node saw was decided
+func (p *PBFT) OldestPendingRound() time.Duration {
+	p.statesLock.RLock()
+	defer p.statesLock.RUnlock()
+
+	var oldest time.Duration
+	for _, st := range p.states {
+		if st.Decided || st.Started.IsZero() {
+			continue
+		}
+		if age := time.Since(st.Started); age > oldest {
+			oldest = age
+		}
+	}
+	return oldest
+}
+
 // GetDecidedBatchChan returns the channel that receives decided batches
 func (p *PBFT) GetDecidedBatchChan() <-chan *state.Batch {
 	return p.decidedBatch
@@ -660,14 +1100,111 @@ func (p *PBFT) GetDecidedBatchChan() <-chan *state.Batch {
 
 // UpdateTotalNodes updates the total number of nodes in the network
 func (p *PBFT) UpdateTotalNodes(count int) {
+	p.nodeIDsLock.Lock()
+	defer p.nodeIDsLock.Unlock()
+
+	// A pinned validator set decides the quorum regardless of connected peers
+	if p.validatorsPinned {
+		return
+	}
 	p.totalNodes = count
 }
 
+// SetValidators replaces the validator set. Only listed nodes are counted towards the
+// quorum and messages from other nodes are rejected.
+func (p *PBFT) SetValidators(nodeIDs []string) {
+	p.nodeIDsLock.Lock()
+	defer p.nodeIDsLock.Unlock()
+
+	p.nodeIDs = append([]string(nil), nodeIDs...)
+	p.totalNodes = len(p.nodeIDs)
+	p.validatorsPinned = true
+	p.scheduledValidators = nil
+
+	log.Info().Strs("validators", p.nodeIDs).Msg("Replaced validator set")
+}
+
+// ScheduleValidators replaces the validator set once the next batch is decided, so that
+// every validator switches between the same two rounds. Without a pinned set yet the set
+// applies right away.
+func (p *PBFT) ScheduleValidators(nodeIDs []string) {
+	if len(nodeIDs) == 0 {
+		return
+	}
+	next := append([]string(nil), nodeIDs...)
+	sort.Strings(next)
+
+	p.nodeIDsLock.Lock()
+	if !p.validatorsPinned {
+		p.nodeIDsLock.Unlock()
+		p.SetValidators(next)
+		return
+	}
+	current := append([]string(nil), p.nodeIDs...)
+	sort.Strings(current)
+	if slices.Equal(current, next) {
+		p.scheduledValidators = nil
+		p.nodeIDsLock.Unlock()
+		return
+	}
+	if slices.Equal(p.scheduledValidators, next) {
+		p.nodeIDsLock.Unlock()
+		return
+	}
+	p.scheduledValidators = next
+	p.nodeIDsLock.Unlock()
+
+	log.Info().Strs("validators", next).Msg("Scheduled validator set change for the next decided batch")
+}
+
+// applyScheduledValidators switches to the scheduled validator set, if any
+func (p *PBFT) applyScheduledValidators() {
+	p.nodeIDsLock.Lock()
+	next := p.scheduledValidators
+	p.nodeIDsLock.Unlock()
+
+	if next != nil {
+		p.SetValidators(next)
+	}
+}
+
+// Validators returns the current validator set, and the set scheduled to replace it
+func (p *PBFT) Validators() (current, scheduled []string) {
+	p.nodeIDsLock.RLock()
+	defer p.nodeIDsLock.RUnlock()
+	return append([]string(nil), p.nodeIDs...), append([]string(nil), p.scheduledValidators...)
+}
+
+// Quorum returns how many validators must vote for a batch to be decided
+func (p *PBFT) Quorum() int {
+	return QuorumSize(p.TotalNodes())
+}
+
+// isValidator reports whether a node may take part in consensus
+func (p *PBFT) isValidator(nodeID string) bool {
+	p.nodeIDsLock.RLock()
+	defer p.nodeIDsLock.RUnlock()
+
+	if !p.validatorsPinned {
+		return true
+	}
+	for _, id := range p.nodeIDs {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
 // addNodeID adds a node ID to the list if it's not already there
 func (p *PBFT) addNodeID(nodeID string) {
 	p.nodeIDsLock.Lock()
 	defer p.nodeIDsLock.Unlock()
 
+	if p.validatorsPinned {
+		return
+	}
+
 	// Check if the node ID is already in the list
 	for _, id := range p.nodeIDs {
 		if id == nodeID {
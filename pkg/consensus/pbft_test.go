@@ -0,0 +1,85 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuorumSize(t *testing.T) {
+	for n, want := range map[int]int{1: 1, 2: 2, 3: 2, 4: 3, 5: 4, 6: 4, 7: 5, 10: 7} {
+		require.Equal(t, want, QuorumSize(n), "n=%d", n)
+	}
+	require.True(t, HasQuorum(3, 4))
+	require.False(t, HasQuorum(2, 4))
+}
+
+func TestScheduleValidators(t *testing.T) {
+	p := NewPBFT(nil, "a", true)
+
+	// The first set applies right away
+	p.ScheduleValidators([]string{"b", "a", "c", "d"})
+	current, scheduled := p.Validators()
+	require.ElementsMatch(t, []string{"a", "b", "c", "d"}, current)
+	require.Empty(t, scheduled)
+	require.Equal(t, 4, p.TotalNodes())
+	require.Equal(t, 3, p.Quorum())
+	require.False(t, p.isValidator("e"))
+
+	// Later changes wait for the next decided batch
+	p.ScheduleValidators([]string{"a", "b", "c", "d", "e"})
+	current, scheduled = p.Validators()
+	require.Len(t, current, 4)
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, scheduled)
+
+	p.applyScheduledValidators()
+	current, scheduled = p.Validators()
+	require.Len(t, current, 5)
+	require.Empty(t, scheduled)
+	require.Equal(t, 4, p.Quorum())
+	require.True(t, p.isValidator("e"))
+
+	// Scheduling the current set cancels a pending change
+	p.ScheduleValidators([]string{"a"})
+	p.ScheduleValidators([]string{"e", "d", "c", "b", "a"})
+	_, scheduled = p.Validators()
+	require.Empty(t, scheduled)
+}
+
+func TestProposerSchedule(t *testing.T) {
+	p := NewPBFT(nil, "b", true)
+
+	// Without a pinned validator set the configured leader proposes
+	leader, scheduled := p.AlignLeader(5)
+	require.True(t, leader)
+	require.False(t, scheduled)
+	require.NoError(t, p.checkProposer(&ConsensusMessage{Type: PrePrepare, NodeID: "x", BatchNumber: 5}))
+
+	// Validators take turns in sorted order
+	p.SetValidators([]string{"c", "a", "b"})
+	for n, want := range []string{"a", "b", "c", "a", "b"} {
+		require.Equal(t, want, p.ProposerFor(uint64(n)))
+	}
+	leader, scheduled = p.AlignLeader(5)
+	require.False(t, leader)
+	require.True(t, scheduled)
+	leader, _ = p.AlignLeader(4)
+	require.True(t, leader)
+
+	// Only the validator whose turn it is may propose
+	require.Error(t, p.checkProposer(&ConsensusMessage{Type: PrePrepare, NodeID: "a", BatchNumber: 4}))
+	require.NoError(t, p.checkProposer(&ConsensusMessage{Type: PrePrepare, NodeID: "b", BatchNumber: 4}))
+
+	// Deciding a batch hands the next one to its proposer
+	p.isLeader = false
+	p.rotateLeaderLocked(3)
+	require.True(t, p.IsLeader())
+	require.Equal(t, int64(1), p.view)
+
+	// A rotation is only taken from the proposer of the decided batch, and brings a node
+	// that missed the round up to its view
+	require.Error(t, p.handleLeaderRotation(&ConsensusMessage{Type: LeaderRotation, NodeID: "a", View: 4, BatchNumber: 4, NextLeader: "a"}))
+	require.NoError(t, p.handleLeaderRotation(&ConsensusMessage{Type: LeaderRotation, NodeID: "b", View: 4, BatchNumber: 4, NextLeader: "b"}))
+	require.False(t, p.IsLeader())
+	require.Equal(t, int64(5), p.view)
+}
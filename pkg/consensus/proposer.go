@@ -0,0 +1,137 @@
+package consensus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// With a pinned validator set the validators take turns proposing: batch n is proposed by
+// validator n mod len in the sorted set. Every validator advances the schedule itself when
+// it decides a batch and pre-prepares from any other node are refused. The proposer still
+// announces the rotation, which brings validators that missed the round up to its view.
+// Without a pinned set nodes may disagree on who the validators are, so the leader keeps
+// handing over to the next node it knows of with a LeaderRotation message.
+
+// rotationScheduled reports whether proposal rights follow the proposer schedule
+func (p *PBFT) rotationScheduled() bool {
+	p.nodeIDsLock.RLock()
+	defer p.nodeIDsLock.RUnlock()
+	return p.validatorsPinned && len(p.nodeIDs) > 0
+}
+
+// ProposerFor returns the validator proposing batch batchNumber, empty without validators
+func (p *PBFT) ProposerFor(batchNumber uint64) string {
+	p.nodeIDsLock.RLock()
+	defer p.nodeIDsLock.RUnlock()
+
+	if len(p.nodeIDs) == 0 {
+		return ""
+	}
+	sortedNodeIDs := make([]string, len(p.nodeIDs))
+	copy(sortedNodeIDs, p.nodeIDs)
+	sort.Strings(sortedNodeIDs)
+	return sortedNodeIDs[batchNumber%uint64(len(sortedNodeIDs))]
+}
+
+// AlignLeader makes this node the leader if it proposes batch nextBatch, for a node that
+// applied batches without deciding them. It returns whether the node leads, and false for
+// scheduled when leadership is handed over by announcement instead.
+func (p *PBFT) AlignLeader(nextBatch uint64) (leader, scheduled bool) {
+	if !p.rotationScheduled() {
+		return p.isLeader, false
+	}
+	p.isLeader = p.ProposerFor(nextBatch) == p.nodeID
+	return p.isLeader, true
+}
+
+// checkProposer refuses a pre-prepare from a node whose turn it is not
+func (p *PBFT) checkProposer(msg *ConsensusMessage) error {
+	if !p.rotationScheduled() {
+		return nil
+	}
+	batchNumber := msg.BatchNumber
+	if msg.Batch != nil {
+		batchNumber = msg.Batch.BatchNumber
+	}
+	if proposer := p.ProposerFor(batchNumber); msg.NodeID != proposer {
+		return fmt.Errorf("batch %d is proposed by %s, not %s", batchNumber, proposer, msg.NodeID)
+	}
+	return nil
+}
+
+// rotateLeaderLocked hands leadership on once batchNumber is decided. Must be called with
+// statesLock held.
+func (p *PBFT) rotateLeaderLocked(batchNumber uint64) {
+	if !p.rotationScheduled() {
+		if p.isLeader {
+			p.announceRotationLocked(batchNumber, p.rotateLeader())
+			p.view++
+		}
+		return
+	}
+
+	next := p.ProposerFor(batchNumber + 1)
+	if p.isLeader {
+		p.announceRotationLocked(batchNumber, next)
+	}
+	p.isLeader = next == p.nodeID
+	p.view++
+	log.Info().Uint64("next_batch", batchNumber+1).Str("proposer", next).Int64("view", p.view).Msg("Following proposer schedule")
+}
+
+// announceRotationLocked broadcasts that nextLeader proposes the batch after batchNumber
+// and steps down unless it is this node
+func (p *PBFT) announceRotationLocked(batchNumber uint64, nextLeader string) {
+	log.Info().Str("next_leader", nextLeader).Msg("Rotating leadership")
+
+	rotation := &ConsensusMessage{
+		Type:        LeaderRotation,
+		View:        p.view,
+		Sequence:    p.sequence,
+		BatchHash:   "",
+		NodeID:      p.nodeID,
+		Timestamp:   time.Now(),
+		NextLeader:  nextLeader,
+		BatchNumber: batchNumber,
+	}
+
+	if err := p.broadcast(rotation); err != nil {
+		log.Error().Err(err).Msg("Failed to broadcast leader rotation message")
+	}
+
+	p.isLeader = (nextLeader == p.nodeID)
+}
+
+// handleLeaderRotation follows a rotation announced by the proposer of a decided batch
+func (p *PBFT) handleLeaderRotation(msg *ConsensusMessage) error {
+	log.Info().Str("from", msg.NodeID).Str("next_leader", msg.NextLeader).Msg("Received leader rotation message")
+
+	if p.rotationScheduled() {
+		if proposer := p.ProposerFor(msg.BatchNumber); msg.NodeID != proposer {
+			return fmt.Errorf("leader rotation after batch %d from %s, not its proposer %s", msg.BatchNumber, msg.NodeID, proposer)
+		}
+		// Validators that decided the batch already moved on
+		if msg.View < p.view {
+			return nil
+		}
+		p.isLeader = p.ProposerFor(msg.BatchNumber+1) == p.nodeID
+	} else {
+		// Update our leader status based on the leader's decision
+		p.isLeader = (msg.NextLeader == p.nodeID)
+	}
+	p.view = msg.View + 1 // Set view to match the leader's next view
+
+	if p.isLeader {
+		log.Info().Str("node_id", p.nodeID).Int64("view", p.view).Msg("This node is now the leader based on leader rotation message")
+	} else {
+		log.Info().Str("node_id", p.nodeID).Str("new_leader", msg.NextLeader).Int64("view", p.view).Msg("Leadership transferred based on leader rotation message")
+	}
+
+	if p.onLeaderChange != nil {
+		p.onLeaderChange(p.isLeader)
+	}
+	return nil
+}
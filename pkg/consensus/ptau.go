@@ -0,0 +1,411 @@
+package consensus
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+)
+
+// Powers of Tau transcripts are gnark phase 1 MPC states (mpcsetup.Phase1), each file holding
+// the parameters after one contribution together with the proof of knowledge of its secrets.
+// Contributions are made here rather than with Phase1.Contribute so that they can mix in the
+// contributor's entropy and so the final beacon can be recomputed by every node.
+
+// Domain separation tags of the τ, α and β proofs of knowledge, as used by mpcsetup
+const (
+	dstTau   = 1
+	dstAlpha = 2
+	dstBeta  = 3
+)
+
+// initPTau returns the initial transcript of a ceremony over 2^power constraints. The keys
+// mpcsetup.InitPhase1 signs its identity secrets with are random, they are replaced with
+// fixed ones so every node starts from, and verifiers can rebuild, the same transcript.
+func initPTau(power int) *mpcsetup.Phase1 {
+	phase1 := mpcsetup.InitPhase1(power)
+
+	var one fr.Element
+	one.SetOne()
+	seed := []byte("zkrollup ptau initial transcript")
+	phase1.PublicKeys.Tau = ptauPublicKey(one, seedScalar(seed, "tau-key"), nil, dstTau)
+	phase1.PublicKeys.Alpha = ptauPublicKey(one, seedScalar(seed, "alpha-key"), nil, dstAlpha)
+	phase1.PublicKeys.Beta = ptauPublicKey(one, seedScalar(seed, "beta-key"), nil, dstBeta)
+	phase1.Hash = ptauHash(&phase1)
+	return &phase1
+}
+
+// decodePTau parses a Powers of Tau transcript
+func decodePTau(data []byte) (*mpcsetup.Phase1, error) {
+	var phase1 mpcsetup.Phase1
+	if _, err := phase1.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to decode ptau transcript: %v", err)
+	}
+	return &phase1, nil
+}
+
+// readPTau loads a Powers of Tau transcript from path
+func readPTau(path string) (*mpcsetup.Phase1, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ptau file: %v", err)
+	}
+	return decodePTau(data)
+}
+
+// writePTau stores a Powers of Tau transcript at path and returns its encoding
+func writePTau(path string, phase1 *mpcsetup.Phase1) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := phase1.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode ptau transcript: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write ptau file: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// contributePTau mixes the secrets derived from seed into the transcript. A random
+// contribution hashes fresh randomness into its seed, the beacon uses a public one.
+func contributePTau(phase1 *mpcsetup.Phase1, seed []byte) {
+	n := len(phase1.Parameters.G2.Tau)
+
+	tau := seedScalar(seed, "tau")
+	alpha := seedScalar(seed, "alpha")
+	beta := seedScalar(seed, "beta")
+
+	// Prove knowledge of the secrets against the previous transcript
+	challenge := phase1.Hash
+	phase1.PublicKeys.Tau = ptauPublicKey(tau, seedScalar(seed, "tau-key"), challenge, dstTau)
+	phase1.PublicKeys.Alpha = ptauPublicKey(alpha, seedScalar(seed, "alpha-key"), challenge, dstAlpha)
+	phase1.PublicKeys.Beta = ptauPublicKey(beta, seedScalar(seed, "beta-key"), challenge, dstBeta)
+
+	// Scale the powers of τ, ατ and βτ
+	taus := make([]fr.Element, 2*n-1)
+	taus[0].SetOne()
+	for i := 1; i < len(taus); i++ {
+		taus[i].Mul(&taus[i-1], &tau)
+	}
+	alphaTaus := make([]fr.Element, n)
+	betaTaus := make([]fr.Element, n)
+	for i := 0; i < n; i++ {
+		alphaTaus[i].Mul(&taus[i], &alpha)
+		betaTaus[i].Mul(&taus[i], &beta)
+	}
+
+	scaleG1(phase1.Parameters.G1.Tau, taus)
+	scaleG1(phase1.Parameters.G1.AlphaTau, alphaTaus)
+	scaleG1(phase1.Parameters.G1.BetaTau, betaTaus)
+	scaleG2(phase1.Parameters.G2.Tau, taus[:n])
+	var betaInt big.Int
+	beta.BigInt(&betaInt)
+	phase1.Parameters.G2.Beta.ScalarMultiplication(&phase1.Parameters.G2.Beta, &betaInt)
+
+	phase1.Hash = ptauHash(phase1)
+}
+
+// randomSeed mixes a contributor's entropy with fresh randomness, either alone suffices to
+// keep the contribution's secrets unknown
+func randomSeed(entropy string) ([]byte, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to read randomness: %v", err)
+	}
+	h := sha256.New()
+	h.Write(random)
+	h.Write([]byte(entropy))
+	return h.Sum(nil), nil
+}
+
+// beaconSeed hashes the public beacon value 2^iterationsExp times, so its secrets can be
+// recomputed by anyone but not chosen ahead of the beacon
+func beaconSeed(beacon []byte, iterationsExp int) []byte {
+	seed := append([]byte{}, beacon...)
+	for i := 0; i < 1<<iterationsExp; i++ {
+		sum := sha256.Sum256(seed)
+		seed = sum[:]
+	}
+	return seed
+}
+
+// verifyPTauStep checks that next is a valid contribution on top of prev
+func verifyPTauStep(prev, next *mpcsetup.Phase1) (err error) {
+	if len(next.Parameters.G2.Tau) != len(prev.Parameters.G2.Tau) ||
+		len(next.Parameters.G1.Tau) != len(prev.Parameters.G1.Tau) ||
+		len(next.Parameters.G1.AlphaTau) != len(prev.Parameters.G1.AlphaTau) ||
+		len(next.Parameters.G1.BetaTau) != len(prev.Parameters.G1.BetaTau) {
+		return fmt.Errorf("contribution has a different size than the transcript")
+	}
+
+	// The pairing checks panic on points outside the subgroup
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid contribution: %v", r)
+		}
+	}()
+	if err := checkPTauPoints(next); err != nil {
+		return err
+	}
+	if err := mpcsetup.VerifyPhase1(prev, next); err != nil {
+		return err
+	}
+	return checkPTauConsistency(next)
+}
+
+// checkPTauPoints refuses the degenerate points VerifyPhase1 does not catch. A proof of
+// knowledge of a zero secret is made of points at infinity and passes every pairing check,
+// which would let a contributor replace [β]₂ freely or erase the powers. The powers must
+// also start from the generators.
+func checkPTauPoints(phase1 *mpcsetup.Phase1) error {
+	keys := []*mpcsetup.PublicKey{&phase1.PublicKeys.Tau, &phase1.PublicKeys.Alpha, &phase1.PublicKeys.Beta}
+	for i, name := range []string{"τ", "α", "β"} {
+		if keys[i].SG.IsInfinity() || keys[i].SXG.IsInfinity() || keys[i].XR.IsInfinity() {
+			return fmt.Errorf("public key of %s has a point at infinity", name)
+		}
+	}
+
+	params := &phase1.Parameters
+	if len(params.G1.Tau) < 2 || len(params.G2.Tau) < 2 || len(params.G1.AlphaTau) == 0 || len(params.G1.BetaTau) == 0 {
+		return fmt.Errorf("transcript has no powers")
+	}
+	_, _, g1, g2 := curve.Generators()
+	if !params.G1.Tau[0].Equal(&g1) || !params.G2.Tau[0].Equal(&g2) {
+		return fmt.Errorf("powers of τ do not start from the generators")
+	}
+	if params.G1.Tau[1].IsInfinity() || params.G2.Tau[1].IsInfinity() ||
+		params.G1.AlphaTau[0].IsInfinity() || params.G1.BetaTau[0].IsInfinity() || params.G2.Beta.IsInfinity() {
+		return fmt.Errorf("transcript has a zero secret")
+	}
+	return nil
+}
+
+// checkPTauConsistency checks that the G1 and G2 halves of a transcript hold the same secrets,
+// VerifyPhase1 checks each against the previous transcript but not against each other
+func checkPTauConsistency(phase1 *mpcsetup.Phase1) error {
+	params := &phase1.Parameters
+	_, _, g1, g2 := curve.Generators()
+	if !sameRatio(params.G1.Tau[1], g1, g2, params.G2.Tau[1]) {
+		return fmt.Errorf("[τ]₁ and [τ]₂ differ")
+	}
+	if !sameRatio(params.G1.BetaTau[0], g1, g2, params.G2.Beta) {
+		return fmt.Errorf("[β]₁ and [β]₂ differ")
+	}
+	return nil
+}
+
+// sameRatio reports whether e(a1, a2) = e(b1, b2), that is a1/b1 and b2/a2 share a
+// discrete log
+func sameRatio(a1, b1 curve.G1Affine, a2, b2 curve.G2Affine) bool {
+	var na2 curve.G2Affine
+	na2.Neg(&a2)
+	ok, err := curve.PairingCheck([]curve.G1Affine{a1, b1}, []curve.G2Affine{na2, b2})
+	return err == nil && ok
+}
+
+// verifyPTauBeacon checks that final is the beacon contribution on top of prev
+func verifyPTauBeacon(prev, final *mpcsetup.Phase1, beacon []byte, iterationsExp int) error {
+	if err := verifyPTauStep(prev, final); err != nil {
+		return err
+	}
+
+	expected := clonePTau(prev)
+	contributePTau(expected, beaconSeed(beacon, iterationsExp))
+	if !bytes.Equal(expected.Hash, final.Hash) {
+		return fmt.Errorf("final transcript does not apply the beacon")
+	}
+	return nil
+}
+
+// verifyPTauFinal checks that data is the beacon contribution on top of the transcript at prevPath
+func verifyPTauFinal(prevPath string, data []byte) error {
+	prev, err := readPTau(prevPath)
+	if err != nil {
+		return err
+	}
+	final, err := decodePTau(data)
+	if err != nil {
+		return err
+	}
+	return verifyPTauBeacon(prev, final, ptauBeacon, ptauBeaconIterationsExp)
+}
+
+// VerifyPTauTranscript checks every step of an epoch's ceremony in dir, from the initial
+// transcript through each contribution to the beacon of the final file
+func VerifyPTauTranscript(dir string, epoch int64) error {
+	artifacts, err := ListCRSArtifacts(dir)
+	if err != nil {
+		return err
+	}
+
+	var steps []CRSArtifact
+	var final *CRSArtifact
+	for i := range artifacts {
+		a := artifacts[i]
+		switch {
+		case a.Epoch != epoch:
+		case a.Final:
+			final = &a
+		default:
+			if a.Step != len(steps) {
+				return fmt.Errorf("epoch %d transcript is missing step %d", epoch, len(steps))
+			}
+			steps = append(steps, a)
+		}
+	}
+	if final == nil || len(steps) == 0 {
+		return fmt.Errorf("epoch %d has no complete transcript", epoch)
+	}
+
+	prev, err := readPTau(steps[0].Path)
+	if err != nil {
+		return err
+	}
+	power := 0
+	for 1<<power < len(prev.Parameters.G2.Tau) {
+		power++
+	}
+	initial := initPTau(power)
+	if !bytes.Equal(initial.Hash, prev.Hash) || 1<<power != len(prev.Parameters.G2.Tau) {
+		return fmt.Errorf("epoch %d does not start from the initial transcript", epoch)
+	}
+
+	for _, step := range steps[1:] {
+		next, err := readPTau(step.Path)
+		if err != nil {
+			return err
+		}
+		if err := verifyPTauStep(prev, next); err != nil {
+			return fmt.Errorf("step %d of epoch %d: %v", step.Step, epoch, err)
+		}
+		prev = next
+	}
+
+	last, err := readPTau(final.Path)
+	if err != nil {
+		return err
+	}
+	if err := verifyPTauBeacon(prev, last, ptauBeacon, ptauBeaconIterationsExp); err != nil {
+		return fmt.Errorf("beacon of epoch %d: %v", epoch, err)
+	}
+	return nil
+}
+
+// ptauStepPath returns the path of the transcript after step contributions of an epoch
+func ptauStepPath(dir string, epoch int64, step int) string {
+	return filepath.Join(dir, fmt.Sprintf("pot_epoch%d_%d.ptau", epoch, step))
+}
+
+// ptauFinalPath returns the path of an epoch's final transcript
+func ptauFinalPath(dir string, epoch int64) string {
+	return filepath.Join(dir, fmt.Sprintf("pot_epoch%d_final.ptau", epoch))
+}
+
+// ptauPublicKey proves knowledge of x bound to the previous transcript hash, the same way
+// mpcsetup does, with s blinding the proof
+func ptauPublicKey(x, s fr.Element, challenge []byte, dst byte) mpcsetup.PublicKey {
+	var pk mpcsetup.PublicKey
+	var sInt, xInt big.Int
+	s.BigInt(&sInt)
+	x.BigInt(&xInt)
+
+	pk.SG.ScalarMultiplicationBase(&sInt)
+	pk.SXG.ScalarMultiplication(&pk.SG, &xInt)
+
+	var buf bytes.Buffer
+	buf.Write(pk.SG.Marshal())
+	buf.Write(pk.SXG.Marshal())
+	buf.Write(challenge)
+	r, err := curve.HashToG2(buf.Bytes(), []byte{dst})
+	if err != nil {
+		// Hashing to the curve only fails on an oversized domain tag
+		panic(err)
+	}
+	pk.XR.ScalarMultiplication(&r, &xInt)
+	return pk
+}
+
+// ptauHash is the transcript hash later contributions are bound to
+func ptauHash(phase1 *mpcsetup.Phase1) []byte {
+	// WriteTo appends the stored hash, which must not be part of its own input
+	stored := phase1.Hash
+	phase1.Hash = nil
+	h := sha256.New()
+	phase1.WriteTo(h)
+	phase1.Hash = stored
+	return h.Sum(nil)
+}
+
+// clonePTau returns a deep copy of a transcript
+func clonePTau(phase1 *mpcsetup.Phase1) *mpcsetup.Phase1 {
+	c := *phase1
+	c.Parameters.G1.Tau = append([]curve.G1Affine{}, phase1.Parameters.G1.Tau...)
+	c.Parameters.G1.AlphaTau = append([]curve.G1Affine{}, phase1.Parameters.G1.AlphaTau...)
+	c.Parameters.G1.BetaTau = append([]curve.G1Affine{}, phase1.Parameters.G1.BetaTau...)
+	c.Parameters.G2.Tau = append([]curve.G2Affine{}, phase1.Parameters.G2.Tau...)
+	c.Hash = append([]byte{}, phase1.Hash...)
+	return &c
+}
+
+// seedScalar derives a nonzero field element from seed, 64 bytes of hash keep it uniform
+func seedScalar(seed []byte, label string) fr.Element {
+	var x fr.Element
+	for counter := byte(0); x.IsZero(); counter++ {
+		h := sha512.New()
+		h.Write(seed)
+		h.Write([]byte(label))
+		h.Write([]byte{counter})
+		x.SetBytes(h.Sum(nil))
+	}
+	return x
+}
+
+// scaleG1 multiplies each point by its scalar in parallel
+func scaleG1(points []curve.G1Affine, scalars []fr.Element) {
+	parallelize(len(points), func(start, end int) {
+		var s big.Int
+		for i := start; i < end; i++ {
+			scalars[i].BigInt(&s)
+			points[i].ScalarMultiplication(&points[i], &s)
+		}
+	})
+}
+
+// scaleG2 multiplies each point by its scalar in parallel
+func scaleG2(points []curve.G2Affine, scalars []fr.Element) {
+	parallelize(len(points), func(start, end int) {
+		var s big.Int
+		for i := start; i < end; i++ {
+			scalars[i].BigInt(&s)
+			points[i].ScalarMultiplication(&points[i], &s)
+		}
+	})
+}
+
+// parallelize splits [0, n) into one chunk per CPU
+func parallelize(n int, work func(start, end int)) {
+	chunk := (n + runtime.NumCPU() - 1) / runtime.NumCPU()
+	if chunk == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := min(start+chunk, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work(start, end)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,99 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTauCeremonyRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	participants := []string{"node-a", "node-b"}
+
+	ceremony, err := NewPTauCeremonyState(1, participants, 3, dir)
+	require.NoError(t, err)
+
+	// A second node follows the ceremony from its own directory
+	follower, err := NewPTauCeremonyState(1, participants, 3, t.TempDir())
+	require.NoError(t, err)
+
+	for _, id := range participants {
+		msg, err := ceremony.AddContribution(id, "entropy of "+id)
+		require.NoError(t, err)
+
+		require.NoError(t, follower.VerifyContribution(msg))
+		_, err = writePTau(follower.PTauPath, mustDecodePTau(t, msg.PTauFileData))
+		require.NoError(t, err)
+		follower.CurrentStep = msg.Step + 1
+	}
+
+	ceremony.Completed = true
+	finalPath, err := ceremony.FinalizeCeremony()
+	require.NoError(t, err)
+	require.Equal(t, ptauFinalPath(dir, 1), finalPath)
+
+	require.NoError(t, VerifyFinalTranscript(finalPath))
+}
+
+func TestPTauRejectsTamperedContribution(t *testing.T) {
+	ceremony, err := NewPTauCeremonyState(1, []string{"node-a"}, 3, t.TempDir())
+	require.NoError(t, err)
+	msg, err := ceremony.AddContribution("node-a", "entropy")
+	require.NoError(t, err)
+
+	// Replacing a power of tau breaks the consistency of the transcript
+	tampered := mustDecodePTau(t, msg.PTauFileData)
+	tampered.Parameters.G1.Tau[2] = tampered.Parameters.G1.Tau[1]
+	prev, err := readPTau(ptauStepPath(ceremony.dir, 1, 0))
+	require.NoError(t, err)
+	require.Error(t, verifyPTauStep(prev, tampered))
+
+	// A contribution that skips the beacon is not accepted as the final transcript
+	ceremony.Completed = true
+	finalPath, err := ceremony.FinalizeCeremony()
+	require.NoError(t, err)
+	last, err := readPTau(ptauStepPath(ceremony.dir, 1, 1))
+	require.NoError(t, err)
+	contributePTau(last, []byte("not the beacon"))
+	_, err = writePTau(finalPath, last)
+	require.NoError(t, err)
+	require.Error(t, VerifyFinalTranscript(finalPath))
+}
+
+func TestPTauRejectsDegenerateContribution(t *testing.T) {
+	prev := initPTau(3)
+
+	// A zero β proves knowledge with points at infinity, which passes the pairing checks of
+	// VerifyPhase1 and leaves [β]₂ unconstrained
+	poisoned := clonePTau(prev)
+	contributePTau(poisoned, []byte("seed"))
+	poisoned.PublicKeys.Beta = mpcsetup.PublicKey{}
+	for i := range poisoned.Parameters.G1.BetaTau {
+		poisoned.Parameters.G1.BetaTau[i] = curve.G1Affine{}
+	}
+	poisoned.Parameters.G2.Beta.ScalarMultiplicationBase(big.NewInt(7))
+	poisoned.Hash = ptauHash(poisoned)
+	require.ErrorContains(t, verifyPTauStep(prev, poisoned), "infinity")
+
+	// Powers moved off the generators
+	shifted := clonePTau(prev)
+	contributePTau(shifted, []byte("seed"))
+	shifted.Parameters.G1.Tau[0] = shifted.Parameters.G1.Tau[1]
+	shifted.Hash = ptauHash(shifted)
+	require.ErrorContains(t, verifyPTauStep(prev, shifted), "generators")
+
+	// An honest contribution passes every check
+	honest := clonePTau(prev)
+	contributePTau(honest, []byte("seed"))
+	require.NoError(t, verifyPTauStep(prev, honest))
+}
+
+func mustDecodePTau(t *testing.T, data []byte) *mpcsetup.Phase1 {
+	t.Helper()
+	p, err := decodePTau(data)
+	require.NoError(t, err)
+	return p
+}
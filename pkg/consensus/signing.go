@@ -0,0 +1,67 @@
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"zkrollup/pkg/signer"
+)
+
+// SetMessageSigner sets the key signing every consensus message this node sends, messages
+// are sent unsigned without one
+func (p *PBFT) SetMessageSigner(s signer.Signer) {
+	p.signer = s
+}
+
+// SetMessageSigners pins the address each listed node signs with. Messages from a listed
+// node are rejected unless signed by its address, other nodes are not checked. It may be
+// called again while running to follow a changing validator set.
+func (p *PBFT) SetMessageSigners(signers map[string]common.Address) {
+	p.signersLock.Lock()
+	defer p.signersLock.Unlock()
+	p.signers = signers
+}
+
+// pinnedSigner returns the address a node must sign with, if one is pinned
+func (p *PBFT) pinnedSigner(nodeID string) (common.Address, bool) {
+	p.signersLock.RLock()
+	defer p.signersLock.RUnlock()
+	address, ok := p.signers[nodeID]
+	return address, ok
+}
+
+// signMessage signs msg with the message signer, if any
+func (p *PBFT) signMessage(msg *ConsensusMessage) error {
+	if p.signer == nil {
+		return nil
+	}
+
+	msg.Signature = nil
+	signature, err := p.signer.SignText(p.ctx, []byte(msg.Hash()))
+	if err != nil {
+		return fmt.Errorf("failed to sign consensus message: %v", err)
+	}
+	msg.Signature = signature
+	return nil
+}
+
+// verifyMessage checks the signature of a message from a node with a pinned signer
+func (p *PBFT) verifyMessage(msg *ConsensusMessage) error {
+	expected, ok := p.pinnedSigner(msg.NodeID)
+	if !ok {
+		return nil
+	}
+	if len(msg.Signature) == 0 {
+		return fmt.Errorf("unsigned consensus message from %s", msg.NodeID)
+	}
+
+	from, err := signer.RecoverText([]byte(msg.Hash()), msg.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature on consensus message from %s: %v", msg.NodeID, err)
+	}
+	if from != expected {
+		return fmt.Errorf("consensus message from %s signed by %s, expected %s", msg.NodeID, from.Hex(), expected.Hex())
+	}
+	return nil
+}
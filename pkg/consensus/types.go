@@ -22,6 +22,11 @@ const (
 	CRSCeremonyStart
 	CRSContribution
 	CRSCeremonyComplete
+	Checkpoint
+	CRSKeys
+	CRSSkip
+	CRSRejoin
+	CRSCatchUp
 )
 
 func (m MessageType) String() string {
@@ -42,6 +47,16 @@ func (m MessageType) String() string {
 		return "CRSContribution"
 	case CRSCeremonyComplete:
 		return "CRSCeremonyComplete"
+	case Checkpoint:
+		return "Checkpoint"
+	case CRSKeys:
+		return "CRSKeys"
+	case CRSSkip:
+		return "CRSSkip"
+	case CRSRejoin:
+		return "CRSRejoin"
+	case CRSCatchUp:
+		return "CRSCatchUp"
 	default:
 		return "Unknown"
 	}
@@ -56,14 +71,33 @@ type ConsensusMessage struct {
 	NodeID     string       `json:"node_id"`    // ID of the node sending this message
 	Timestamp  time.Time    `json:"timestamp"`
 	Signature  []byte       `json:"signature"`             // Signature of the message
-	Batch      *state.Batch `json:"batch,omitempty"`       // Only included in PrePrepare
+	Batch      *state.Batch `json:"batch,omitempty"`       // PrePrepare body, left out when gossiped separately
 	NextLeader string       `json:"next_leader,omitempty"` // ID of the next leader (only in Commit messages)
+
+	// Number of the batch a PrePrepare proposes, lets nodes track the network head without the body
+	BatchNumber uint64 `json:"batch_number,omitempty"`
+
+	// State root after the batch a Checkpoint message reports, hex encoded
+	StateRoot string `json:"state_root,omitempty"`
 	
 	// CRS Ceremony fields
 	EpochNumber     int64                   `json:"epoch_number,omitempty"`     // Epoch number for CRS ceremony
 	PTauFileData    []byte                  `json:"ptau_file_data,omitempty"`   // PTau file data for CRS ceremony
+	PTauFileHash    string                  `json:"ptau_file_hash,omitempty"`   // Content hash of the PTau file when it is fetched out of band
 	ContributionMsg *PTauContributionMessage `json:"contribution_msg,omitempty"` // CRS contribution message
 	Participants    []string                `json:"participants,omitempty"`     // Ordered list of participants for CRS ceremony
+
+	// Circuit keys derived from a ceremony: content hash of the phase 2 transcript, fingerprint
+	// of the verifying key it yields and the first batch proven with the keys
+	Phase2Hash     string `json:"phase2_hash,omitempty"`
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+	ActivateAt     uint64 `json:"activate_at,omitempty"`
+
+	// Ceremony recovery: the step a participant is skipped at or a restarted node resumes
+	// from, the participant skipped, and the ceremony messages a node catching up replays
+	Step        int                 `json:"step,omitempty"`
+	SkippedNode string              `json:"skipped_node,omitempty"`
+	Evidence    []*ConsensusMessage `json:"evidence,omitempty"`
 }
 
 // Hash returns the SHA256 hash of the message's contents
@@ -129,11 +163,34 @@ type ConsensusState struct {
 	Decided       bool
 	SentCommit    bool // Tracks if we've already sent a commit message
 	PrePrepareMsg *ConsensusMessage
-	NextLeader    string // ID of the next leader
+	NextLeader    string    // ID of the next leader
+	Started       time.Time // When this node first saw the round
 }
 
 // NewConsensusState creates a new consensus state
 func NewConsensusState(view, sequence int64, batch *state.Batch) *ConsensusState {
+	st := newConsensusState(view, sequence, BatchHash(batch))
+	st.Batch = batch
+	return st
+}
+
+// newConsensusState creates the state of a round whose batch body may not have arrived yet
+func newConsensusState(view, sequence int64, batchHash string) *ConsensusState {
+	return &ConsensusState{
+		View:         view,
+		Sequence:     sequence,
+		Phase:        PrePrepare,
+		PrepareCount: make(map[string]bool),
+		CommitCount:  make(map[string]bool),
+		BatchHash:    batchHash,
+		Decided:      false,
+		SentCommit:   false,
+		Started:      time.Now(),
+	}
+}
+
+// BatchHash returns the hash consensus messages use to refer to a batch
+func BatchHash(batch *state.Batch) string {
 	// First marshal the batch to JSON
 	batchData, _ := json.Marshal(batch)
 
@@ -142,18 +199,7 @@ func NewConsensusState(view, sequence int64, batch *state.Batch) *ConsensusState
 	if err := json.Unmarshal(batchData, &batchMap); err != nil {
 		// Fallback to direct hashing if unmarshaling fails
 		hash := sha256.Sum256(batchData)
-		batchHash := fmt.Sprintf("%x", hash)
-
-		return &ConsensusState{
-			View:         view,
-			Sequence:     sequence,
-			Phase:        PrePrepare,
-			PrepareCount: make(map[string]bool),
-			CommitCount:  make(map[string]bool),
-			Batch:        batch,
-			BatchHash:    batchHash,
-			Decided:      false,
-		}
+		return fmt.Sprintf("%x", hash)
 	}
 
 	// Handle transactions specially for consistent hashing
@@ -173,24 +219,18 @@ func NewConsensusState(view, sequence int64, batch *state.Batch) *ConsensusState
 	// Re-marshal with the consistent format
 	consistentBatchData, _ := json.Marshal(batchMap)
 	hash := sha256.Sum256(consistentBatchData)
-	batchHash := fmt.Sprintf("%x", hash)
+	return fmt.Sprintf("%x", hash)
+}
 
-	return &ConsensusState{
-		View:         view,
-		Sequence:     sequence,
-		Phase:        PrePrepare,
-		PrepareCount: make(map[string]bool),
-		CommitCount:  make(map[string]bool),
-		Batch:        batch,
-		BatchHash:    batchHash,
-		Decided:      false,
-		SentCommit:   false,
-	}
+// QuorumSize returns how many of n validators must vote for a batch. With up to
+// f = (n-1)/3 faulty validators, any two quorums of this size share an honest validator.
+// It is 2f+1 when n = 3f+1.
+func QuorumSize(n int) int {
+	f := (n - 1) / 3
+	return (n+f)/2 + 1
 }
 
-// HasQuorum returns true if the number of messages received is greater than 2f+1
-// where f is the maximum number of faulty nodes the system can tolerate
+// HasQuorum reports whether count votes out of totalNodes validators form a quorum
 func HasQuorum(count int, totalNodes int) bool {
-	f := (totalNodes - 1) / 3
-	return count >= 2*f+1
+	return count >= QuorumSize(totalNodes)
 }
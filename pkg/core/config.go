@@ -2,45 +2,375 @@ package core
 
 type Config struct {
 	// Ethereum network configuration
-	EthereumRPC     string
-	ChainID         int64
-	ContractAddress string
+	EthereumRPC     string `yaml:"ethereum_rpc"`
+	ChainID         int64  `yaml:"chain_id"`
+	ContractAddress string `yaml:"contract_address"`
+
+	// Chain ID of the rollup, which transactions sign over and the EVM's CHAINID returns,
+	// 0 uses ChainID. Rollups sharing an L1 must set distinct ones. Transactions signed
+	// without a chain ID are replayable across rollups and refused unless AllowUnprotectedTxs.
+	RollupChainID       int64 `yaml:"rollup_chain_id"`
+	AllowUnprotectedTxs bool  `yaml:"allow_unprotected_txs"`
 
 	// Sequencer configuration
-	SequencerPort    int
-	SequencerPeerKey string
-	BootstrapPeers   []string
+	SequencerPort    int      `yaml:"sequencer_port"`
+	SequencerPeerKey string   `yaml:"sequencer_peer_key"`
+	BootstrapPeers   []string `yaml:"bootstrap_peers"`
+	IsLeader         bool     `yaml:"is_leader"` // Propose batches from startup, validators of a pinned set take turns instead
+	RPCPort          int      `yaml:"rpc_port"`  // JSON-RPC port, 0 uses SequencerPort + 1000
+
+	// Peer IDs allowed to send consensus messages and batches, empty accepts any peer
+	ConsensusPeers []string `yaml:"consensus_peers"`
+
+	// Consensus validators by peer ID, which alone vote and decide the quorum. With
+	// ValidatorRegistry the set is read from the rollup contract instead, where the guardian
+	// adds and removes validators. With StakingContract it is the validators bonded on that
+	// Staking contract, whose messages must be signed by the address they staked from.
+	// Without any, every peer that sends a consensus message is counted as a validator.
+	Validators        []string `yaml:"validators"`
+	ValidatorRegistry bool     `yaml:"validator_registry"`
+	StakingContract   string   `yaml:"staking_contract"`
+
+	// File holding the node's libp2p key, created on first start, so the peer ID survives
+	// restarts. The key is encrypted when IdentityPassphrase is set. Empty uses a new
+	// random peer ID on every start.
+	IdentityFile       string `yaml:"identity_file"`
+	IdentityPassphrase string `yaml:"identity_passphrase"`
 
 	// Rollup configuration
-	BatchSize       uint64
-	ProofGeneration bool
-	StateDBPath     string
+	BatchSize            uint64 `yaml:"batch_size"`
+	BatchIntervalSeconds int    `yaml:"batch_interval_seconds"` // How often the leader checks whether a batch is due
+	ProofGeneration      bool   `yaml:"proof_generation"`
+	ProverWorkers        int    `yaml:"prover_workers"` // Batches proven in parallel
+	ProverThreads        int    `yaml:"prover_threads"` // Goroutines solving the witness of one proof, 0 uses every core
+	ProverGPU            bool   `yaml:"prover_gpu"`     // Prove on the GPU, needs a binary built with -tags icicle
+	StateDBPath          string `yaml:"state_db_path"`
+	StateBackend         string `yaml:"state_backend"` // "memory", "leveldb" or "pebble"
+
+	// Node hash of the account and storage trees: "sha256", which the L1 escape hatch
+	// verifies, or "mimc" over BN254, which circuits can constrain. Every node must agree
+	// and a state holding batches keeps the hash it was built with.
+	StateHash string `yaml:"state_hash"`
+
+	// When the state changes of finalized batches are written to the database: "always"
+	// (write and sync every batch), "batches" (every StateCommitInterval batches in one
+	// write) or "async" (write every batch in the background without syncing)
+	StateCommitPolicy   string `yaml:"state_commit_policy"`
+	StateCommitInterval int    `yaml:"state_commit_interval"`
+
+	// Keep the account and storage state after each of the last StateArchiveRetain batches
+	// queryable, 0 keeps every batch since the node started
+	StateArchive       bool   `yaml:"state_archive"`
+	StateArchiveRetain uint64 `yaml:"state_archive_retain"`
+
+	// A batch is cut as soon as the pool holds BatchSize transactions or BatchGasThreshold
+	// gas (0 disables the gas trigger), without waiting for the batch interval. Batches cut
+	// this way are at least MinBatchIntervalMillis apart.
+	BatchGasThreshold      uint64 `yaml:"batch_gas_threshold"`
+	MinBatchIntervalMillis int    `yaml:"min_batch_interval_ms"`
+
+	// Seconds a batch proof may take before it is escalated, 0 disables the deadline. The
+	// policy is "alert", "data-only" (post the batch to L1 pending its proof) or
+	// "smaller-circuit" (reprove with a circuit of ProofFallbackCapacity transactions, which
+	// the L1 verifier must accept, posting data-only when the batch does not fit)
+	ProofDeadlineSeconds  int    `yaml:"proof_deadline_seconds"`
+	ProofDeadlinePolicy   string `yaml:"proof_deadline_policy"`
+	ProofFallbackCapacity int    `yaml:"proof_fallback_capacity"`
+
+	// Number of consecutive proven batches whose proofs are aggregated into one before L1
+	// submission, 0 submits every batch with its own proof. The contract's aggregate
+	// verifier must be set for the same count.
+	ProofAggregation int `yaml:"proof_aggregation"`
+
+	// Delete accounts with no balance, nonce, code or storage after each batch
+	PruneEmptyAccounts bool `yaml:"prune_empty_accounts"`
+
+	// Seconds to listen for the network head before accepting transactions
+	SyncWarmupSeconds int `yaml:"sync_warmup_seconds"`
+
+	// Download the account state and batch history from a peer when behind the network head
+	// instead of waiting for every missed batch to be announced
+	StateSyncEnabled bool `yaml:"state_sync_enabled"`
+
+	// Fast sync downloads only the newest snapshot a peer took at a batch final on L1,
+	// checked against the state root L1 finalized, and replays the batches after it. Nodes
+	// following L1 take a snapshot to serve every FastSyncSnapshotInterval batches, 0 serves
+	// none.
+	FastSync                 bool   `yaml:"fast_sync"`
+	FastSyncSnapshotInterval uint64 `yaml:"fast_sync_snapshot_interval"`
+
+	// Empty heartbeat batches are proposed when no batch was finalized for this many
+	// seconds, 0 disables them. Heartbeats are only posted to L1 if HeartbeatPostToL1 is set.
+	HeartbeatIntervalSeconds int  `yaml:"heartbeat_interval_seconds"`
+	HeartbeatPostToL1        bool `yaml:"heartbeat_post_to_l1"`
+
+	// A consensus round open for longer than this is reported as stuck, 0 disables the indicator
+	ConsensusStuckSeconds int `yaml:"consensus_stuck_seconds"`
+
+	// Validators agree on a checkpoint every this many decided batches, after which the
+	// consensus rounds it covers are discarded; 0 disables checkpoints. The latest stable
+	// checkpoint is kept in ConsensusCheckpointFile so a restarted node resumes from it,
+	// empty keeps it in memory.
+	ConsensusCheckpointInterval int    `yaml:"consensus_checkpoint_interval"`
+	ConsensusCheckpointFile     string `yaml:"consensus_checkpoint_file"`
+
+	// File persisting finalized batches not yet delivered to any peer, empty keeps them in memory
+	OutboxFile string `yaml:"outbox_file"`
+
+	// Mempool limits
+	MempoolMaxSize          int   `yaml:"mempool_max_size"`
+	MempoolMaxPerSender     int   `yaml:"mempool_max_per_sender"`
+	MempoolPriceBumpPercent int   `yaml:"mempool_price_bump_percent"` // Minimum gas price increase to replace a pending transaction
+	MinGasPrice             int64 `yaml:"min_gas_price"`              // Fee floor in wei, adjustable at runtime through the admin API
+
+	// File journaling the transactions admitted to the pool, replayed and re-validated
+	// once the node is synced after a restart; empty disables it. The journal is rewritten
+	// with the pending transactions every MempoolRejournalSeconds, 0 only at startup.
+	MempoolJournal          string `yaml:"mempool_journal"`
+	MempoolRejournalSeconds int    `yaml:"mempool_rejournal_seconds"`
+
+	// Announce the hashes of every pending transaction to the peers every
+	// MempoolAnnounceSeconds, so nodes that missed an announcement pull what they lack; 0
+	// only announces transactions as they arrive
+	MempoolAnnounceSeconds int `yaml:"mempool_announce_seconds"`
+
+	// Account credited with the gas fees senders pay, gas used times the transaction's gas
+	// price. Every node must agree, empty burns the fees.
+	FeeRecipient string `yaml:"fee_recipient"`
+
+	// Order of the transactions within a batch, which every node must agree on: "fee"
+	// (highest gas price first), "fifo" (earliest admission to the proposer's pool first) or
+	// "fair" (by transaction hash committed to the parent state root)
+	BatchOrdering string `yaml:"batch_ordering"`
+
+	// Batches within which a transaction accepted over RPC is promised to be included. The
+	// promise is returned as a preconfirmation signed with the consensus key, a node
+	// without one or with 0 issues none.
+	PreconfirmationBatches uint64 `yaml:"preconfirmation_batches"`
+
+	// Publish pending transactions on the RPC port at /mempool and /mempool/stream
+	// (WebSocket): "off", "hashes" or "full". Transactions sent through the
+	// rollup_sendPrivate* methods are never published.
+	MempoolStream string `yaml:"mempool_stream"`
+
+	// JSON-RPC limits: the largest request body, the most calls in a batch array, and how
+	// long each call may run, 0 seconds for no deadline
+	RPCMaxBodyBytes   int64 `yaml:"rpc_max_body_bytes"`
+	RPCMaxBatchSize   int   `yaml:"rpc_max_batch_size"`
+	RPCTimeoutSeconds int   `yaml:"rpc_timeout_seconds"`
 
-	// ZK-SNARK configuration
-	CircuitFile      string
-	ProvingKeyFile   string
-	VerifyingKeyFile string
-	MerkleTreeDepth  int
+	// Calls per second each client IP may make and how many may burst, calls past either
+	// are refused with error -32005, a 0 rate disables the limit. RPCMethodConcurrency
+	// caps how many calls of a method run at once across all clients.
+	RPCRateLimit         float64        `yaml:"rpc_rate_limit"`
+	RPCRateBurst         int            `yaml:"rpc_rate_burst"`
+	RPCMethodConcurrency map[string]int `yaml:"rpc_method_concurrency"`
+
+	// Origins of the browser pages allowed to call the RPC server, "*" for any. A
+	// certificate and key in PEM serve the endpoint over HTTPS, and RPCHTTP2 speaks HTTP/2,
+	// negotiated over TLS or as cleartext h2c without a certificate.
+	RPCCORSOrigins []string `yaml:"rpc_cors_origins"`
+	RPCTLSCertFile string   `yaml:"rpc_tls_cert_file"`
+	RPCTLSKeyFile  string   `yaml:"rpc_tls_key_file"`
+	RPCHTTP2       bool     `yaml:"rpc_http2"`
+
+	// Bearer token required for admin_* RPC methods, empty disables them
+	AdminToken string `yaml:"admin_token"`
+
+	// Port of the operator REST API (mempool, peers, consensus, batch and L1 submission
+	// controls), authenticated with AdminToken, 0 disables it
+	AdminPort int `yaml:"admin_port"`
+
+	// Port of the web dashboard showing recent batches, peers, consensus and L1 status, 0
+	// disables it
+	ExplorerPort int `yaml:"explorer_port"`
+
+	// Port serving Prometheus metrics on /metrics, 0 disables it
+	MetricsPort int `yaml:"metrics_port"`
+
+	// Addresses allowed to sign emergency operations (halt, validator rotation, state root
+	// override) and how many of them must sign, no operators disables those operations
+	EmergencyOperators []string `yaml:"emergency_operators"`
+	EmergencyThreshold int      `yaml:"emergency_threshold"`
+
+	// EVM execution limits
+	EVMTimeoutMs    int    `yaml:"evm_timeout_ms"` // Per-call timeout in milliseconds when building a batch, 0 disables. Slow calls are dropped, not charged.
+	EVMMaxSteps     uint64 `yaml:"evm_max_steps"`  // Instructions per call, 0 disables. Calls exceeding it fail and pay for all their gas.
+	EVMMaxMemory    uint64 `yaml:"evm_max_memory"` // Memory in bytes per call frame
+	EVMMaxStack     int    `yaml:"evm_max_stack"`  // Stack items per call frame
+	EVMMaxCallDepth int    `yaml:"evm_max_call_depth"`
+	EVMMaxCodeSize  int    `yaml:"evm_max_code_size"`
+
+	// ZK-SNARK configuration. The batch circuit keys are loaded from ProvingKeyFile and
+	// VerifyingKeyFile, or set up locally and written there when neither file exists.
+	CircuitFile      string `yaml:"circuit_file"`
+	ProvingKeyFile   string `yaml:"proving_key_file"`
+	VerifyingKeyFile string `yaml:"verifying_key_file"`
+	MerkleTreeDepth  int    `yaml:"merkle_tree_depth"`
+
+	// CRS epoch the proving key was generated from. Proofs are checked on L1 by the verifier
+	// registered for that epoch, 0 uses the verifier the contract was deployed with.
+	ProvingKeyEpoch uint64 `yaml:"proving_key_epoch"`
+
+	// CRS ceremony configuration
+	CRSCeremonyDir         string `yaml:"crs_ceremony_dir"`
+	CRSPowerSize           int    `yaml:"crs_power_size"`           // Ceremonies support circuits of up to 2^CRSPowerSize constraints
+	CRSRetainEpochs        int    `yaml:"crs_retain_epochs"`        // Number of final ptau files to keep, 0 keeps all
+	CRSDeleteIntermediates bool   `yaml:"crs_delete_intermediates"` // Remove intermediate ptau files once the final transcript verifies
+
+	// Seconds the ceremony leader waits for a participant's contribution before skipping
+	// it, 0 waits forever
+	CRSContributionTimeoutSeconds int `yaml:"crs_contribution_timeout_seconds"`
+
+	// CRSManager contract ceremonies are recorded on, empty keeps them off L1. Every
+	// validator then needs L1 enabled with a funded account.
+	CRSManagerAddress string `yaml:"crs_manager_address"`
 
 	// L1 integration configuration
-	L1Enabled           bool
-	L1PrivateKey        string
-	L1BatchSubmitPeriod int // in seconds
-	L1GasLimit          uint64
-	L1GasPrice          int64 // in gwei
+	L1Enabled           bool   `yaml:"l1_enabled"`
+	L1PrivateKey        string `yaml:"l1_private_key"`
+	L1PrivateKeyFile    string `yaml:"l1_private_key_file"`    // File holding L1PrivateKey in hex, read when the config is loaded
+	L1BatchSubmitPeriod int    `yaml:"l1_batch_submit_period"` // in seconds
+	L1GasLimit          uint64 `yaml:"l1_gas_limit"`
+	L1GasPrice          int64  `yaml:"l1_gas_price"`         // in gwei
+	L1FinalityDepth     uint64 `yaml:"l1_finality_depth"`    // L1 blocks a verified batch must be buried under to be final
+	L1DataAvailability  string `yaml:"l1_data_availability"` // Publishes batch transactions to L1: "off", "calldata" or "blob"
+
+	// L1 transactions pay EIP-1559 fees of twice the base fee plus the suggested tip, at
+	// most L1MaxFeeGwei per gas (0 for no cap), and are replaced with higher fees once
+	// pending for L1StuckTxSeconds
+	L1MaxFeeGwei     uint64 `yaml:"l1_max_fee_gwei"`
+	L1StuckTxSeconds int    `yaml:"l1_stuck_tx_seconds"`
+
+	// Keys held by a remote signing service speaking eth_sign and eth_signTransaction, such
+	// as Web3Signer, so they never enter the node. L1SignerAddress replaces L1PrivateKey and
+	// ConsensusSignerAddress signs consensus messages, which may instead be signed with
+	// ConsensusPrivateKey. Messages from nodes listed in ConsensusSigners (node ID to
+	// address) are rejected unless signed by that address.
+	SignerURL              string            `yaml:"signer_url"`
+	L1SignerAddress        string            `yaml:"l1_signer_address"`
+	ConsensusSignerAddress string            `yaml:"consensus_signer_address"`
+	ConsensusPrivateKey    string            `yaml:"consensus_private_key"`
+	ConsensusSigners       map[string]string `yaml:"consensus_signers"`
+	SignerAPI              string            `yaml:"signer_api"` // Methods of the signing service: "eth" (Web3Signer) or "clef"
+
+	// Other places the L1 key may live instead of L1PrivateKey: an encrypted JSON keystore
+	// unlocked with the password in L1KeystorePasswordFile, or an ECC_SECG_P256K1 key in
+	// AWS KMS, authenticated with the standard AWS_* environment credentials
+	L1KeystoreFile         string `yaml:"l1_keystore_file"`
+	L1KeystorePasswordFile string `yaml:"l1_keystore_password_file"`
+	L1KMSKeyID             string `yaml:"l1_kms_key_id"`
+	KMSRegion              string `yaml:"kms_region"`   // AWS_REGION when empty
+	KMSEndpoint            string `yaml:"kms_endpoint"` // Overrides the regional KMS endpoint
+
+	// L1 deposit and batch event watchers
+	L1DepositStartBlock    uint64 `yaml:"l1_deposit_start_block"`   // First L1 block scanned for deposits and batch events
+	L1DepositConfirmations uint64 `yaml:"l1_deposit_confirmations"` // Blocks a deposit must be buried under before it is credited
+
+	// JSON lines file recording proof size and L1 gas per batch, empty (the default) disables
+	ProofStatsFile string `yaml:"proof_stats_file"`
+
+	// Single-node dev chain: DevAccounts deterministic accounts are funded at genesis, each
+	// transaction is sealed in a batch as soon as it arrives and peer discovery is off.
+	// DevTimestamp fixes the timestamp of batch 0, later batches add one second per batch, 0
+	// uses the clock.
+	DevMode      bool   `yaml:"dev_mode"`
+	DevAccounts  int    `yaml:"dev_accounts"`
+	DevTimestamp uint64 `yaml:"dev_timestamp"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		EthereumRPC:         "http://localhost:8545",
-		ChainID:             1337, // Local network
-		SequencerPort:       9000,
-		BatchSize:           1,
-		ProofGeneration:     true,
-		StateDBPath:         "./statedb",
-		L1Enabled:           false, // Disabled by default
-		L1BatchSubmitPeriod: 300,   // 5 minutes
-		L1GasLimit:          3000000,
-		L1GasPrice:          20, // 20 gwei
+		EthereumRPC:              "http://localhost:8545",
+		ChainID:                  1337, // Local network
+		SequencerPort:            9000,
+		BatchSize:                1,
+		BatchIntervalSeconds:     15,
+		MinBatchIntervalMillis:   500,
+		ProofGeneration:          true,
+		ProverWorkers:            2,
+		ProofDeadlineSeconds:     600,
+		ProofDeadlinePolicy:      "alert",
+		StateDBPath:              "./statedb",
+		StateBackend:             "memory",
+		StateHash:                "sha256",
+		StateCommitPolicy:        "always",
+		StateCommitInterval:      10,
+		StateArchiveRetain:       128,
+		PruneEmptyAccounts:       true,
+		SyncWarmupSeconds:        10,
+		StateSyncEnabled:         true,
+		FastSyncSnapshotInterval: 100,
+		ConsensusStuckSeconds:    60,
+		OutboxFile:               "./outbox.json",
+		MempoolMaxSize:           10000,
+		MempoolMaxPerSender:      64,
+		MempoolPriceBumpPercent:  10,
+		MempoolStream:            "off",
+		RPCMaxBodyBytes:          5 * 1024 * 1024,
+		RPCMaxBatchSize:          100,
+		RPCTimeoutSeconds:        30,
+		RPCRateLimit:             100,
+		RPCRateBurst:             200,
+		RPCMethodConcurrency:     map[string]int{"rollup_call": 16, "rollup_estimateGas": 4, "rollup_getProof": 4},
+		RPCHTTP2:                 true,
+		EVMTimeoutMs:             5000,
+		EVMMaxSteps:              30_000_000,
+		EVMMaxMemory:             32 * 1024 * 1024,
+		EVMMaxStack:              1024,
+		EVMMaxCallDepth:          1024,
+		EVMMaxCodeSize:           24576,
+		CRSPowerSize:             12,
+		CRSDeleteIntermediates:   true,
+		L1Enabled:                false, // Disabled by default
+		L1BatchSubmitPeriod:      300,   // 5 minutes
+		L1GasLimit:               3000000,
+		L1GasPrice:               20, // 20 gwei
+		L1FinalityDepth:          12,
+		L1DataAvailability:       "off",
+		L1StuckTxSeconds:         180,
+		L1DepositConfirmations:   2,
+
+		ConsensusCheckpointInterval:   10,
+		ConsensusCheckpointFile:       "./checkpoint.json",
+		MempoolJournal:                "./mempool.journal",
+		MempoolRejournalSeconds:       3600,
+		ProvingKeyFile:                "./batch.pk",
+		VerifyingKeyFile:              "./batch.vk",
+		CRSContributionTimeoutSeconds: 600,
+		MempoolAnnounceSeconds:        30,
+		BatchOrdering:                 "fee",
+		PreconfirmationBatches:        3,
 	}
 }
+
+// L2ChainID returns the chain ID of the rollup
+func (c *Config) L2ChainID() uint64 {
+	if c.RollupChainID != 0 {
+		return uint64(c.RollupChainID)
+	}
+	return uint64(c.ChainID)
+}
+
+// DevChainID is the chain ID of dev chains
+const DevChainID = 1337
+
+// DevConfig returns the configuration of a single-node dev chain with instant finality,
+// no proofs and no L1
+func DevConfig() *Config {
+	config := DefaultConfig()
+	config.DevMode = true
+	config.DevAccounts = 10
+	config.ChainID = DevChainID
+	config.BatchSize = 1
+	config.MinBatchIntervalMillis = 0
+	config.ProofGeneration = false
+	config.StateBackend = "memory"
+	config.SyncWarmupSeconds = 0
+	config.StateSyncEnabled = false
+	config.OutboxFile = ""
+	config.ConsensusCheckpointFile = ""
+	config.MempoolJournal = ""
+	config.L1Enabled = false
+	return config
+}
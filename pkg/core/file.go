@@ -0,0 +1,242 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile overlays the settings of a YAML config file on c, keys missing from the file
+// keep their current value and unknown keys are rejected. The L1 private key is read from
+// L1PrivateKeyFile when one is set.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	if c.L1PrivateKeyFile != "" {
+		key, err := os.ReadFile(c.L1PrivateKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read L1 private key file: %v", err)
+		}
+		c.L1PrivateKey = strings.TrimPrefix(strings.TrimSpace(string(key)), "0x")
+	}
+	return nil
+}
+
+// Validate checks that the settings are consistent, reporting the first problem found
+func (c *Config) Validate() error {
+	ports := []struct {
+		name string
+		port int
+	}{
+		{"sequencer_port", c.SequencerPort},
+		{"rpc_port", c.RPCPort},
+		{"explorer_port", c.ExplorerPort},
+		{"metrics_port", c.MetricsPort},
+		{"admin_port", c.AdminPort},
+	}
+	for _, p := range ports {
+		if p.port < 0 || p.port > 65535 {
+			return fmt.Errorf("%s %d out of range", p.name, p.port)
+		}
+	}
+	if c.SequencerPort == 0 {
+		return fmt.Errorf("sequencer_port is required")
+	}
+	if c.AdminPort > 0 && c.AdminToken == "" {
+		return fmt.Errorf("admin_port requires admin_token")
+	}
+
+	if c.BatchSize == 0 {
+		return fmt.Errorf("batch_size must be positive")
+	}
+	if c.BatchIntervalSeconds <= 0 {
+		return fmt.Errorf("batch_interval_seconds must be positive")
+	}
+	if c.MinBatchIntervalMillis < 0 {
+		return fmt.Errorf("min_batch_interval_ms must not be negative")
+	}
+	if c.ProverWorkers < 0 {
+		return fmt.Errorf("prover_workers must not be negative")
+	}
+	if c.ProverThreads < 0 {
+		return fmt.Errorf("prover_threads must not be negative")
+	}
+	if (c.ProvingKeyFile == "") != (c.VerifyingKeyFile == "") {
+		return fmt.Errorf("proving_key_file and verifying_key_file must be set together")
+	}
+
+	switch c.StateBackend {
+	case "memory", "leveldb", "pebble":
+	default:
+		return fmt.Errorf("unknown state_backend %q", c.StateBackend)
+	}
+	switch c.StateHash {
+	case "", "sha256", "mimc":
+	default:
+		return fmt.Errorf("unknown state_hash %q, expected sha256 or mimc", c.StateHash)
+	}
+	if (c.L1SignerAddress != "" || c.ConsensusSignerAddress != "") && c.SignerURL == "" {
+		return fmt.Errorf("signer_url is required for remote signing")
+	}
+	switch c.SignerAPI {
+	case "", "eth", "clef":
+	default:
+		return fmt.Errorf("signer_api must be eth or clef, not %q", c.SignerAPI)
+	}
+	l1Signers := 0
+	for _, set := range []bool{c.L1SignerAddress != "", c.L1KeystoreFile != "", c.L1KMSKeyID != ""} {
+		if set {
+			l1Signers++
+		}
+	}
+	if l1Signers > 1 {
+		return fmt.Errorf("l1_signer_address, l1_keystore_file and l1_kms_key_id are mutually exclusive")
+	}
+	if c.ConsensusSignerAddress != "" && c.ConsensusPrivateKey != "" {
+		return fmt.Errorf("consensus_signer_address and consensus_private_key are mutually exclusive")
+	}
+
+	switch c.StateCommitPolicy {
+	case "always", "async":
+	case "batches":
+		if c.StateCommitInterval <= 0 {
+			return fmt.Errorf("state_commit_interval must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown state_commit_policy %q", c.StateCommitPolicy)
+	}
+	if c.RollupChainID < 0 || (c.RollupChainID == 0 && c.ChainID <= 0) {
+		return fmt.Errorf("rollup_chain_id must be positive")
+	}
+	if c.RPCMaxBodyBytes <= 0 {
+		return fmt.Errorf("rpc_max_body_bytes must be positive")
+	}
+	if c.RPCMaxBatchSize <= 0 {
+		return fmt.Errorf("rpc_max_batch_size must be positive")
+	}
+	if c.RPCTimeoutSeconds < 0 {
+		return fmt.Errorf("rpc_timeout_seconds must not be negative")
+	}
+	if c.RPCRateLimit < 0 {
+		return fmt.Errorf("rpc_rate_limit must not be negative")
+	}
+	if c.RPCRateLimit > 0 && c.RPCRateBurst <= 0 {
+		return fmt.Errorf("rpc_rate_burst must be positive")
+	}
+	for method, n := range c.RPCMethodConcurrency {
+		if n < 0 {
+			return fmt.Errorf("rpc_method_concurrency of %s must not be negative", method)
+		}
+	}
+	if (c.RPCTLSCertFile == "") != (c.RPCTLSKeyFile == "") {
+		return fmt.Errorf("rpc_tls_cert_file and rpc_tls_key_file must be set together")
+	}
+	if c.MempoolMaxPerSender < 0 {
+		return fmt.Errorf("mempool_max_per_sender must not be negative")
+	}
+	if c.MempoolRejournalSeconds < 0 {
+		return fmt.Errorf("mempool_rejournal_seconds must not be negative")
+	}
+	if c.MempoolAnnounceSeconds < 0 {
+		return fmt.Errorf("mempool_announce_seconds must not be negative")
+	}
+	if c.FeeRecipient != "" && !common.IsHexAddress(c.FeeRecipient) {
+		return fmt.Errorf("invalid fee_recipient %q", c.FeeRecipient)
+	}
+	switch c.BatchOrdering {
+	case "fee", "fifo", "fair":
+	default:
+		return fmt.Errorf("unknown batch_ordering %q", c.BatchOrdering)
+	}
+	switch c.MempoolStream {
+	case "off", "hashes", "full":
+	default:
+		return fmt.Errorf("unknown mempool_stream %q", c.MempoolStream)
+	}
+	switch c.ProofDeadlinePolicy {
+	case "alert", "data-only", "smaller-circuit":
+	default:
+		return fmt.Errorf("unknown proof_deadline_policy %q", c.ProofDeadlinePolicy)
+	}
+	if c.ProofDeadlinePolicy == "smaller-circuit" && c.ProofFallbackCapacity <= 0 {
+		return fmt.Errorf("proof_fallback_capacity is required by the smaller-circuit policy")
+	}
+
+	if c.ProofAggregation == 1 || c.ProofAggregation < 0 {
+		return fmt.Errorf("proof_aggregation must be 0 or at least 2")
+	}
+
+	// BN254 has FFT domains of up to 2^28 elements
+	if c.CRSPowerSize < 1 || c.CRSPowerSize > 28 {
+		return fmt.Errorf("crs_power_size %d out of range [1, 28]", c.CRSPowerSize)
+	}
+	if c.CRSContributionTimeoutSeconds < 0 {
+		return fmt.Errorf("crs_contribution_timeout_seconds must not be negative")
+	}
+
+	if c.HeartbeatIntervalSeconds < 0 || c.ConsensusStuckSeconds < 0 || c.SyncWarmupSeconds < 0 {
+		return fmt.Errorf("consensus timeouts must not be negative")
+	}
+	if c.ConsensusCheckpointInterval < 0 {
+		return fmt.Errorf("consensus_checkpoint_interval must not be negative")
+	}
+
+	if len(c.EmergencyOperators) > 0 && (c.EmergencyThreshold < 1 || c.EmergencyThreshold > len(c.EmergencyOperators)) {
+		return fmt.Errorf("emergency_threshold must be between 1 and %d", len(c.EmergencyOperators))
+	}
+
+	if c.ValidatorRegistry && (len(c.Validators) > 0 || !c.L1Enabled || c.ContractAddress == "") {
+		return fmt.Errorf("validator_registry needs L1 with a contract_address and no validators list")
+	}
+	if c.FastSync && (!c.L1Enabled || c.ContractAddress == "") {
+		return fmt.Errorf("fast_sync needs L1 with a contract_address")
+	}
+
+	if c.StakingContract != "" {
+		if !common.IsHexAddress(c.StakingContract) {
+			return fmt.Errorf("invalid staking_contract %q", c.StakingContract)
+		}
+		if len(c.Validators) > 0 || c.ValidatorRegistry || !c.L1Enabled {
+			return fmt.Errorf("staking_contract needs L1 and neither a validators list nor validator_registry")
+		}
+	}
+
+	if c.L1Enabled {
+		if c.EthereumRPC == "" {
+			return fmt.Errorf("ethereum_rpc is required when L1 is enabled")
+		}
+		if c.ContractAddress != "" && !common.IsHexAddress(c.ContractAddress) {
+			return fmt.Errorf("invalid contract_address %q", c.ContractAddress)
+		}
+		if c.CRSManagerAddress != "" && !common.IsHexAddress(c.CRSManagerAddress) {
+			return fmt.Errorf("invalid crs_manager_address %q", c.CRSManagerAddress)
+		}
+		if c.L1BatchSubmitPeriod <= 0 {
+			return fmt.Errorf("l1_batch_submit_period must be positive")
+		}
+		if c.L1StuckTxSeconds <= 0 {
+			return fmt.Errorf("l1_stuck_tx_seconds must be positive")
+		}
+		switch c.L1DataAvailability {
+		case "", "off", "calldata", "blob":
+		default:
+			return fmt.Errorf("l1_data_availability must be off, calldata or blob, not %q", c.L1DataAvailability)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package crypto
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	nativemimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark/frontend"
+	gnarkeddsa "github.com/consensys/gnark/std/signature/eddsa"
+)
+
+// AccountTree is the out-of-circuit counterpart of the tree constrained by BatchCircuit:
+// a fixed depth MiMC Merkle tree indexed by account number whose leaves are
+// MiMC(owner.X, owner.Y, balance, nonce), owner being the EdDSA/BN254 key authorizing the
+// account's transfers. Untouched accounts have no owner, a zero balance and nonce. It is
+// not the address-keyed sparse Merkle tree of pkg/state.
+type AccountTree struct {
+	depth    int
+	accounts map[uint64]treeAccount
+	nodes    map[treeNode]fr.Element
+	defaults []fr.Element // defaults[h] is the root of an untouched subtree of height h
+}
+
+type treeAccount struct {
+	owner   *eddsa.PublicKey // nil for untouched accounts
+	balance *big.Int
+	nonce   uint64
+}
+
+type treeNode struct {
+	height int
+	index  uint64
+}
+
+// NewAccountTree creates a tree of the given depth holding 2^depth accounts
+func NewAccountTree(depth int) *AccountTree {
+	t := &AccountTree{
+		depth:    depth,
+		accounts: make(map[uint64]treeAccount),
+		nodes:    make(map[treeNode]fr.Element),
+		defaults: make([]fr.Element, depth+1),
+	}
+	t.defaults[0] = accountLeafHash(nil, new(big.Int), 0)
+	for h := 1; h <= depth; h++ {
+		t.defaults[h] = mimcHash(t.defaults[h-1], t.defaults[h-1])
+	}
+	return t
+}
+
+// Size returns the number of account slots
+func (t *AccountTree) Size() uint64 {
+	return 1 << uint(t.depth)
+}
+
+// Root returns the tree root
+func (t *AccountTree) Root() fr.Element {
+	return t.node(t.depth, 0)
+}
+
+// Account returns the balance and nonce at index
+func (t *AccountTree) Account(index uint64) (*big.Int, uint64) {
+	acc, ok := t.accounts[index]
+	if !ok {
+		return new(big.Int), 0
+	}
+	return new(big.Int).Set(acc.balance), acc.nonce
+}
+
+// Owner returns the key owning the account at index, nil when it has none
+func (t *AccountTree) Owner(index uint64) *eddsa.PublicKey {
+	return t.accounts[index].owner
+}
+
+// SetAccount sets the account at index and rehashes its path to the root
+func (t *AccountTree) SetAccount(index uint64, owner *eddsa.PublicKey, balance *big.Int, nonce uint64) {
+	t.accounts[index] = treeAccount{owner: owner, balance: new(big.Int).Set(balance), nonce: nonce}
+
+	node := accountLeafHash(owner, balance, nonce)
+	t.setNode(0, index, node)
+	for h := 0; h < t.depth; h++ {
+		sibling := t.node(h, index^1)
+		if index&1 == 1 {
+			node = mimcHash(sibling, node)
+		} else {
+			node = mimcHash(node, sibling)
+		}
+		index >>= 1
+		t.setNode(h+1, index, node)
+	}
+}
+
+func (t *AccountTree) node(height int, index uint64) fr.Element {
+	if node, ok := t.nodes[treeNode{height: height, index: index}]; ok {
+		return node
+	}
+	return t.defaults[height]
+}
+
+func (t *AccountTree) setNode(height int, index uint64, node fr.Element) {
+	if node.Equal(&t.defaults[height]) {
+		delete(t.nodes, treeNode{height: height, index: index})
+		return
+	}
+	t.nodes[treeNode{height: height, index: index}] = node
+}
+
+// pathBits returns the bits of index, least significant first, as circuit variables
+func (t *AccountTree) pathBits(index uint64) []frontend.Variable {
+	bits := make([]frontend.Variable, t.depth)
+	for h := range bits {
+		bits[h] = (index >> uint(h)) & 1
+	}
+	return bits
+}
+
+// siblingVariables returns the authentication path of index from the leaf upwards
+func (t *AccountTree) siblingVariables(index uint64) []frontend.Variable {
+	siblings := make([]frontend.Variable, t.depth)
+	for h := range siblings {
+		sibling := t.node(h, (index>>uint(h))^1)
+		siblings[h] = sibling.String()
+	}
+	return siblings
+}
+
+// paddingTransfer returns a disabled slot with a consistent zero-amount self transfer on
+// account 0. The circuit checks the signature of every slot, so padding is signed by
+// paddingKey, which owns no account.
+func (t *AccountTree) paddingTransfer() (BatchTransfer, error) {
+	balance, nonce := t.Account(0)
+	padding := Transfer{From: 0, To: 0, Amount: new(big.Int), Nonce: nonce + 1}
+	if err := SignTransfer(&padding, paddingKey); err != nil {
+		return BatchTransfer{}, err
+	}
+
+	slot := BatchTransfer{
+		Enabled:          0,
+		Amount:           0,
+		SenderOwner:      ownerVariable(&paddingKey.PublicKey),
+		SenderBalance:    balance.String(),
+		SenderNonce:      nonce,
+		SenderPath:       t.pathBits(0),
+		SenderSiblings:   t.siblingVariables(0),
+		ReceiverOwner:    ownerVariable(t.Owner(0)),
+		ReceiverBalance:  balance.String(),
+		ReceiverNonce:    nonce,
+		ReceiverPath:     t.pathBits(0),
+		ReceiverSiblings: t.siblingVariables(0),
+	}
+	slot.Signature.Assign(tedwards.BN254, padding.Signature)
+	return slot, nil
+}
+
+// ownerVariable returns an account owner as circuit variables, the point (0, 0) standing
+// for no owner
+func ownerVariable(owner *eddsa.PublicKey) gnarkeddsa.PublicKey {
+	var pub gnarkeddsa.PublicKey
+	if owner == nil {
+		pub.A.X, pub.A.Y = 0, 0
+		return pub
+	}
+	pub.A.X, pub.A.Y = owner.A.X.String(), owner.A.Y.String()
+	return pub
+}
+
+// accountLeafHash is the leaf committing to an account, nil owner meaning none
+func accountLeafHash(owner *eddsa.PublicKey, balance *big.Int, nonce uint64) fr.Element {
+	var x, y, b, n fr.Element
+	if owner != nil {
+		x, y = owner.A.X, owner.A.Y
+	}
+	b.SetBigInt(balance)
+	n.SetUint64(nonce)
+	return mimcHash(x, y, b, n)
+}
+
+// mimcHash hashes field elements like the in-circuit MiMC
+func mimcHash(elements ...fr.Element) fr.Element {
+	h := nativemimc.NewMiMC()
+	for _, e := range elements {
+		b := e.Bytes()
+		h.Write(b[:])
+	}
+
+	var out fr.Element
+	out.SetBytes(h.Sum(nil))
+	return out
+}
@@ -0,0 +1,190 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+	"github.com/consensys/gnark/std/math/emulated"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+)
+
+// AggregationCircuit proves that the proofs of consecutive batches verify against the
+// batch circuit's verifying key and chain from one state root to the next. L1 verifies it
+// once for all of them, checking each batch's state root and commitment among its public
+// inputs. The batch proofs are BN254 like the aggregate, so they are verified with BN254
+// arithmetic emulated over its own scalar field.
+type AggregationCircuit struct {
+	// Public inputs
+	OldRoot     frontend.Variable   `gnark:",public"`
+	NewRoots    []frontend.Variable `gnark:",public"`
+	Commitments []frontend.Variable `gnark:",public"`
+
+	// Private inputs, the batch proofs and their public inputs
+	Proofs []stdgroth16.Proof[sw_bn254.G1Affine, sw_bn254.G2Affine]
+	Inputs []stdgroth16.Witness[sw_bn254.ScalarField]
+
+	// Verifying key of the batch circuit, fixed when the circuit is compiled
+	BatchKey stdgroth16.VerifyingKey[sw_bn254.G1Affine, sw_bn254.G2Affine, sw_bn254.GTEl] `gnark:"-"`
+}
+
+// AggregationSchema describes the public inputs of an AggregationCircuit over count batches
+func AggregationSchema(count int) *PublicInputSchema {
+	inputs := []string{"OldRoot"}
+	for i := 0; i < count; i++ {
+		inputs = append(inputs, fmt.Sprintf("NewRoots[%d]", i))
+	}
+	for i := 0; i < count; i++ {
+		inputs = append(inputs, fmt.Sprintf("Commitments[%d]", i))
+	}
+	return &PublicInputSchema{
+		Circuit: fmt.Sprintf("batch-aggregation-%d", count),
+		Version: 1,
+		Inputs:  inputs,
+	}
+}
+
+// NewAggregationCircuit returns a circuit definition aggregating count proofs of the batch
+// circuit compiled as batchCS with verifying key batchVK
+func NewAggregationCircuit(batchCS constraint.ConstraintSystem, batchVK groth16.VerifyingKey, count int) (*AggregationCircuit, error) {
+	key, err := stdgroth16.ValueOfVerifyingKeyFixed[sw_bn254.G1Affine, sw_bn254.G2Affine, sw_bn254.GTEl](batchVK)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch verifying key: %v", err)
+	}
+
+	c := &AggregationCircuit{
+		NewRoots:    make([]frontend.Variable, count),
+		Commitments: make([]frontend.Variable, count),
+		Proofs:      make([]stdgroth16.Proof[sw_bn254.G1Affine, sw_bn254.G2Affine], count),
+		Inputs:      make([]stdgroth16.Witness[sw_bn254.ScalarField], count),
+		BatchKey:    key,
+	}
+	for i := 0; i < count; i++ {
+		c.Proofs[i] = stdgroth16.PlaceholderProof[sw_bn254.G1Affine, sw_bn254.G2Affine](batchCS)
+		c.Inputs[i] = stdgroth16.PlaceholderWitness[sw_bn254.ScalarField](batchCS)
+	}
+	return c, nil
+}
+
+// Define implements the circuit logic for proof aggregation
+func (c *AggregationCircuit) Define(api frontend.API) error {
+	verifier, err := stdgroth16.NewVerifier[sw_bn254.ScalarField, sw_bn254.G1Affine, sw_bn254.G2Affine, sw_bn254.GTEl](api)
+	if err != nil {
+		return err
+	}
+	field, err := emulated.NewField[sw_bn254.ScalarField](api)
+	if err != nil {
+		return err
+	}
+
+	// The batch proofs' inputs are emulated, their canonical bits recompose the native value
+	input := func(w stdgroth16.Witness[sw_bn254.ScalarField], name string) frontend.Variable {
+		return api.FromBinary(field.ToBitsCanonical(&w.Public[BatchCommitmentSchemaV1.Index(name)])...)
+	}
+
+	root := c.OldRoot
+	for i := range c.Proofs {
+		if err := verifier.AssertProof(c.BatchKey, c.Proofs[i], c.Inputs[i]); err != nil {
+			return err
+		}
+		api.AssertIsEqual(input(c.Inputs[i], "OldRoot"), root)
+		api.AssertIsEqual(input(c.Inputs[i], "NewRoot"), c.NewRoots[i])
+		api.AssertIsEqual(input(c.Inputs[i], "Commitment"), c.Commitments[i])
+		root = c.NewRoots[i]
+	}
+	return nil
+}
+
+// NewAggregationProver compiles the aggregation circuit for count proofs of batchProver's
+// circuit and runs a local Groth16 setup
+func NewAggregationProver(batchProver *Prover, count int) (*Prover, error) {
+	if count < 2 {
+		return nil, fmt.Errorf("aggregation needs at least 2 batches, got %d", count)
+	}
+
+	circuit, err := NewAggregationCircuit(batchProver.R1cs, batchProver.VerifyingKey, count)
+	if err != nil {
+		return nil, err
+	}
+	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile aggregation circuit: %v", err)
+	}
+
+	pk, vk, err := groth16.Setup(r1cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup keys: %v", err)
+	}
+
+	return &Prover{
+		ProvingKey:   pk,
+		VerifyingKey: vk,
+		R1cs:         r1cs,
+	}, nil
+}
+
+// AggregationAssignment builds the witness aggregating serialized batch proofs, given in
+// batch order together with their public inputs
+func AggregationAssignment(proofs, publicInputs [][]byte) (*AggregationCircuit, error) {
+	if len(proofs) != len(publicInputs) {
+		return nil, fmt.Errorf("%d proofs with %d sets of public inputs", len(proofs), len(publicInputs))
+	}
+
+	count := len(proofs)
+	assignment := &AggregationCircuit{
+		NewRoots:    make([]frontend.Variable, count),
+		Commitments: make([]frontend.Variable, count),
+		Proofs:      make([]stdgroth16.Proof[sw_bn254.G1Affine, sw_bn254.G2Affine], count),
+		Inputs:      make([]stdgroth16.Witness[sw_bn254.ScalarField], count),
+	}
+	for i := range proofs {
+		proof, err := parseBatchProof(proofs[i])
+		if err != nil {
+			return nil, fmt.Errorf("proof %d: %v", i, err)
+		}
+		if assignment.Proofs[i], err = stdgroth16.ValueOfProof[sw_bn254.G1Affine, sw_bn254.G2Affine](proof); err != nil {
+			return nil, fmt.Errorf("proof %d: %v", i, err)
+		}
+
+		values, err := BatchCommitmentSchemaV1.Split(publicInputs[i])
+		if err != nil {
+			return nil, fmt.Errorf("proof %d: %v", i, err)
+		}
+		for _, v := range values {
+			assignment.Inputs[i].Public = append(assignment.Inputs[i].Public, emulated.ValueOf[sw_bn254.ScalarField](v))
+		}
+
+		if i == 0 {
+			assignment.OldRoot = values[BatchCommitmentSchemaV1.Index("OldRoot")]
+		}
+		assignment.NewRoots[i] = values[BatchCommitmentSchemaV1.Index("NewRoot")]
+		assignment.Commitments[i] = values[BatchCommitmentSchemaV1.Index("Commitment")]
+	}
+	return assignment, nil
+}
+
+// parseBatchProof decodes a proof serialized by SerializeProof: Ar, Bs and Krs uncompressed
+func parseBatchProof(raw []byte) (*groth16bn254.Proof, error) {
+	const g1, g2 = curve.SizeOfG1AffineUncompressed, curve.SizeOfG2AffineUncompressed
+	if len(raw) != 2*g1+g2 {
+		return nil, fmt.Errorf("invalid proof length: got %d, want %d", len(raw), 2*g1+g2)
+	}
+
+	var proof groth16bn254.Proof
+	if _, err := proof.Ar.SetBytes(raw[:g1]); err != nil {
+		return nil, fmt.Errorf("invalid proof point: %v", err)
+	}
+	if _, err := proof.Bs.SetBytes(raw[g1 : g1+g2]); err != nil {
+		return nil, fmt.Errorf("invalid proof point: %v", err)
+	}
+	if _, err := proof.Krs.SetBytes(raw[g1+g2:]); err != nil {
+		return nil, fmt.Errorf("invalid proof point: %v", err)
+	}
+	return &proof, nil
+}
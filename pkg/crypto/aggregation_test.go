@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregationSchema(t *testing.T) {
+	schema := AggregationSchema(2)
+	require.Equal(t, []string{"OldRoot", "NewRoots[0]", "NewRoots[1]", "Commitments[0]", "Commitments[1]"}, schema.Inputs)
+	require.NotEqual(t, schema.Hash(), AggregationSchema(3).Hash())
+
+	_, err := AggregationAssignment([][]byte{make([]byte, 256)}, nil)
+	require.Error(t, err)
+	_, err = AggregationAssignment([][]byte{make([]byte, 255)}, [][]byte{make([]byte, 96)})
+	require.Error(t, err)
+}
+
+func TestAggregationCircuit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("verifies a BN254 proof with emulated arithmetic")
+	}
+
+	prover, err := NewBatchProver(1)
+	require.NoError(t, err)
+	batch, err := BatchCommitmentAssignment(1, [32]byte{1}, [32]byte{2}, [][32]byte{{9}})
+	require.NoError(t, err)
+	proof, publicInputs, err := prover.ProveSerialized(batch)
+	require.NoError(t, err)
+
+	circuit, err := NewAggregationCircuit(prover.R1cs, prover.VerifyingKey, 1)
+	require.NoError(t, err)
+	assignment, err := AggregationAssignment([][]byte{proof}, [][]byte{publicInputs})
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()))
+
+	// The aggregated batches must start from the claimed root
+	assignment.OldRoot = batch.NewRoot
+	require.Error(t, test.IsSolved(circuit, assignment, ecc.BN254.ScalarField()))
+}
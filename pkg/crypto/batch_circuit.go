@@ -0,0 +1,304 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	nativemimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+	gnarkeddsa "github.com/consensys/gnark/std/signature/eddsa"
+)
+
+// BalanceBits bounds balances and amounts proven by the batch circuit
+const BalanceBits = 128
+
+// paddingKey signs the disabled slots of a batch. It owns no account, so its signatures
+// authorize nothing.
+var paddingKey = func() *eddsa.PrivateKey {
+	seed := sha256.Sum256([]byte("zkrollup batch circuit padding"))
+	key, err := eddsa.GenerateKey(bytes.NewReader(seed[:]))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// BatchTransfer is one transfer slot of a BatchCircuit. Path bits give the leaf index,
+// least significant bit first, and siblings are ordered from the leaf upwards. Disabled
+// slots pad the batch and leave the root unchanged.
+type BatchTransfer struct {
+	Enabled   frontend.Variable
+	Amount    frontend.Variable
+	Signature gnarkeddsa.Signature // Sender owner's signature of the transfer message
+
+	SenderOwner    gnarkeddsa.PublicKey
+	SenderBalance  frontend.Variable
+	SenderNonce    frontend.Variable
+	SenderPath     []frontend.Variable
+	SenderSiblings []frontend.Variable
+
+	ReceiverOwner    gnarkeddsa.PublicKey
+	ReceiverBalance  frontend.Variable
+	ReceiverNonce    frontend.Variable
+	ReceiverPath     []frontend.Variable
+	ReceiverSiblings []frontend.Variable
+}
+
+// BatchCircuit proves that applying a batch of signed transfers to the account tree with
+// root OldRoot yields NewRoot. Leaves are MiMC(owner.X, owner.Y, balance, nonce), each
+// transfer must be signed by the EdDSA key owning the sender's leaf and is checked against
+// the root left by the previous one.
+//
+// It is a reference circuit, not the production prover: the sequencer proves batches with
+// BatchCommitmentCircuit, and this circuit's dense tree indexed by account number does not
+// match the address-keyed sparse Merkle tree of pkg/state, so its roots are not the state
+// roots posted to L1.
+type BatchCircuit struct {
+	// Public inputs
+	OldRoot frontend.Variable `gnark:",public"`
+	NewRoot frontend.Variable `gnark:",public"`
+
+	// Private inputs
+	Transfers []BatchTransfer `gnark:",secret"`
+}
+
+// NewBatchCircuit returns a circuit definition for size transfers over a tree of the given depth
+func NewBatchCircuit(size, depth int) *BatchCircuit {
+	c := &BatchCircuit{Transfers: make([]BatchTransfer, size)}
+	for i := range c.Transfers {
+		c.Transfers[i] = BatchTransfer{
+			SenderPath:       make([]frontend.Variable, depth),
+			SenderSiblings:   make([]frontend.Variable, depth),
+			ReceiverPath:     make([]frontend.Variable, depth),
+			ReceiverSiblings: make([]frontend.Variable, depth),
+		}
+	}
+	return c
+}
+
+// Define implements the state transition constraints
+func (c *BatchCircuit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	hash := func(elements ...frontend.Variable) frontend.Variable {
+		h.Reset()
+		h.Write(elements...)
+		return h.Sum()
+	}
+
+	curve, err := twistededwards.NewEdCurve(api, tedwards.BN254)
+	if err != nil {
+		return err
+	}
+	sigHash, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	// merkleRoot recomputes the root from a leaf and its authentication path
+	merkleRoot := func(leaf frontend.Variable, path, siblings []frontend.Variable) frontend.Variable {
+		node := leaf
+		for i := range path {
+			left := api.Select(path[i], siblings[i], node)
+			right := api.Select(path[i], node, siblings[i])
+			node = hash(left, right)
+		}
+		return node
+	}
+
+	root := c.OldRoot
+	for _, t := range c.Transfers {
+		api.AssertIsBoolean(t.Enabled)
+		for i := range t.SenderPath {
+			api.AssertIsBoolean(t.SenderPath[i])
+			api.AssertIsBoolean(t.ReceiverPath[i])
+		}
+
+		// Range checks keep balance arithmetic from wrapping around the field
+		api.ToBinary(t.Amount, BalanceBits)
+		api.ToBinary(t.SenderBalance, BalanceBits)
+		api.ToBinary(t.ReceiverBalance, BalanceBits)
+		api.AssertIsLessOrEqual(t.Amount, t.SenderBalance)
+
+		// The sender's owner signs the accounts, the amount and the nonce the transfer
+		// consumes. Padding slots are signed too, by a key owning no leaf.
+		nonce := api.Add(t.SenderNonce, 1)
+		msg := hash(api.FromBinary(t.SenderPath...), api.FromBinary(t.ReceiverPath...), t.Amount, nonce)
+		sigHash.Reset()
+		if err := gnarkeddsa.Verify(curve, t.Signature, msg, t.SenderOwner, &sigHash); err != nil {
+			return err
+		}
+
+		// Debit the sender and bump its nonce
+		sender := hash(t.SenderOwner.A.X, t.SenderOwner.A.Y, t.SenderBalance, t.SenderNonce)
+		senderRoot := merkleRoot(sender, t.SenderPath, t.SenderSiblings)
+		api.AssertIsEqual(api.Mul(t.Enabled, api.Sub(senderRoot, root)), 0)
+
+		debited := hash(t.SenderOwner.A.X, t.SenderOwner.A.Y, api.Sub(t.SenderBalance, t.Amount), nonce)
+		root = api.Select(t.Enabled, merkleRoot(debited, t.SenderPath, t.SenderSiblings), root)
+
+		// Credit the receiver against the root left by the debit
+		receiver := hash(t.ReceiverOwner.A.X, t.ReceiverOwner.A.Y, t.ReceiverBalance, t.ReceiverNonce)
+		receiverRoot := merkleRoot(receiver, t.ReceiverPath, t.ReceiverSiblings)
+		api.AssertIsEqual(api.Mul(t.Enabled, api.Sub(receiverRoot, root)), 0)
+
+		credited := hash(t.ReceiverOwner.A.X, t.ReceiverOwner.A.Y, api.Add(t.ReceiverBalance, t.Amount), t.ReceiverNonce)
+		root = api.Select(t.Enabled, merkleRoot(credited, t.ReceiverPath, t.ReceiverSiblings), root)
+	}
+
+	api.AssertIsEqual(root, c.NewRoot)
+	return nil
+}
+
+// NewBatchCircuitProver compiles the batch circuit for size transfers over a tree of the
+// given depth and runs a local Groth16 setup
+func NewBatchCircuitProver(size, depth int) (*Prover, error) {
+	if size <= 0 || depth <= 0 {
+		return nil, fmt.Errorf("batch size and tree depth must be positive")
+	}
+
+	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, NewBatchCircuit(size, depth))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile batch circuit: %v", err)
+	}
+
+	pk, vk, err := groth16.Setup(r1cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup keys: %v", err)
+	}
+
+	return &Prover{
+		ProvingKey:   pk,
+		VerifyingKey: vk,
+		R1cs:         r1cs,
+	}, nil
+}
+
+// Transfer moves Amount from the account at leaf From to the account at leaf To. Nonce is
+// the sender's nonce after the transfer and Signature the sender owner's EdDSA signature
+// of Message.
+type Transfer struct {
+	From      uint64
+	To        uint64
+	Amount    *big.Int
+	Nonce     uint64
+	Signature []byte
+}
+
+// Message is the transfer's MiMC hash the sender signs, as the circuit computes it
+func (tr *Transfer) Message() []byte {
+	var from, to, amount, nonce fr.Element
+	from.SetUint64(tr.From)
+	to.SetUint64(tr.To)
+	amount.SetBigInt(tr.Amount)
+	nonce.SetUint64(tr.Nonce)
+	msg := mimcHash(from, to, amount, nonce)
+	b := msg.Bytes()
+	return b[:]
+}
+
+// SignTransfer signs tr with the EdDSA/BN254 key owning its sender
+func SignTransfer(tr *Transfer, key *eddsa.PrivateKey) error {
+	if tr.Amount == nil {
+		return fmt.Errorf("transfer has no amount")
+	}
+	signature, err := key.Sign(tr.Message(), nativemimc.NewMiMC())
+	if err != nil {
+		return fmt.Errorf("failed to sign transfer: %v", err)
+	}
+	tr.Signature = signature
+	return nil
+}
+
+// BuildBatchAssignment applies transfers to tree and returns the witness proving the
+// transition, padded to size slots. The tree is left in the post-batch state; on error
+// it may hold part of the batch.
+func BuildBatchAssignment(tree *AccountTree, transfers []Transfer, size int) (*BatchCircuit, error) {
+	if len(transfers) > size {
+		return nil, fmt.Errorf("batch has %d transfers, circuit size is %d", len(transfers), size)
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), BalanceBits)
+	oldRoot := tree.Root()
+	assignment := &BatchCircuit{
+		OldRoot:   oldRoot.String(),
+		Transfers: make([]BatchTransfer, size),
+	}
+
+	for i := range assignment.Transfers {
+		if i >= len(transfers) {
+			slot, err := tree.paddingTransfer()
+			if err != nil {
+				return nil, err
+			}
+			assignment.Transfers[i] = slot
+			continue
+		}
+
+		tr := transfers[i]
+		if tr.From >= tree.Size() || tr.To >= tree.Size() {
+			return nil, fmt.Errorf("transfer %d: account index out of range", i)
+		}
+		if tr.Amount == nil || tr.Amount.Sign() < 0 || tr.Amount.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("transfer %d: invalid amount", i)
+		}
+
+		senderBalance, senderNonce := tree.Account(tr.From)
+		if senderBalance.Cmp(tr.Amount) < 0 {
+			return nil, fmt.Errorf("transfer %d: insufficient balance", i)
+		}
+		if tr.Nonce != senderNonce+1 {
+			return nil, fmt.Errorf("transfer %d: invalid nonce %d, account nonce is %d", i, tr.Nonce, senderNonce)
+		}
+		owner := tree.Owner(tr.From)
+		if owner == nil {
+			return nil, fmt.Errorf("transfer %d: sender has no owner key", i)
+		}
+		if ok, err := owner.Verify(tr.Signature, tr.Message(), nativemimc.NewMiMC()); err != nil || !ok {
+			return nil, fmt.Errorf("transfer %d: invalid signature", i)
+		}
+
+		slot := BatchTransfer{
+			Enabled:        1,
+			Amount:         tr.Amount.String(),
+			SenderOwner:    ownerVariable(owner),
+			SenderBalance:  senderBalance.String(),
+			SenderNonce:    senderNonce,
+			SenderPath:     tree.pathBits(tr.From),
+			SenderSiblings: tree.siblingVariables(tr.From),
+		}
+		slot.Signature.Assign(tedwards.BN254, tr.Signature)
+		tree.SetAccount(tr.From, owner, new(big.Int).Sub(senderBalance, tr.Amount), tr.Nonce)
+
+		receiverBalance, receiverNonce := tree.Account(tr.To)
+		credited := new(big.Int).Add(receiverBalance, tr.Amount)
+		if credited.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("transfer %d: receiver balance overflows", i)
+		}
+		slot.ReceiverOwner = ownerVariable(tree.Owner(tr.To))
+		slot.ReceiverBalance = receiverBalance.String()
+		slot.ReceiverNonce = receiverNonce
+		slot.ReceiverPath = tree.pathBits(tr.To)
+		slot.ReceiverSiblings = tree.siblingVariables(tr.To)
+		tree.SetAccount(tr.To, tree.Owner(tr.To), credited, receiverNonce)
+
+		assignment.Transfers[i] = slot
+	}
+
+	newRoot := tree.Root()
+	assignment.NewRoot = newRoot.String()
+	return assignment, nil
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/test"
+)
+
+func TestBatchCircuit(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	alice, err := eddsa.GenerateKey(rand.Reader)
+	assert.NoError(err)
+	bob, err := eddsa.GenerateKey(rand.Reader)
+	assert.NoError(err)
+
+	const size, depth = 2, 3
+	tree := NewAccountTree(depth)
+	tree.SetAccount(1, &alice.PublicKey, big.NewInt(100), 0)
+	tree.SetAccount(5, &bob.PublicKey, big.NewInt(7), 3)
+
+	transfer := Transfer{From: 1, To: 5, Amount: big.NewInt(40), Nonce: 1}
+	assert.NoError(SignTransfer(&transfer, alice))
+	oldRoot := tree.Root()
+	assignment, err := BuildBatchAssignment(tree, []Transfer{transfer}, size)
+	assert.NoError(err)
+	assert.ProverSucceeded(NewBatchCircuit(size, depth), assignment, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+
+	balance, nonce := tree.Account(1)
+	assert.Equal(int64(60), balance.Int64())
+	assert.Equal(uint64(1), nonce)
+	balance, _ = tree.Account(5)
+	assert.Equal(int64(47), balance.Int64())
+
+	// A different post-state root must not satisfy the circuit
+	forged := *assignment
+	forged.NewRoot = assignment.OldRoot
+	assert.ProverFailed(NewBatchCircuit(size, depth), &forged, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+
+	// The transfer must be signed by the key bound to the sender's leaf: neither another
+	// key's signature nor a larger amount under the owner's signature satisfies it
+	forged = *assignment
+	forged.Transfers = append([]BatchTransfer(nil), assignment.Transfers...)
+	stolen := transfer
+	assert.NoError(SignTransfer(&stolen, bob))
+	forged.Transfers[0].Signature.Assign(tedwards.BN254, stolen.Signature)
+	assert.ProverFailed(NewBatchCircuit(size, depth), &forged, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+
+	forged.Transfers[0] = assignment.Transfers[0]
+	forged.Transfers[0].SenderOwner = ownerVariable(&bob.PublicKey)
+	forged.Transfers[0].Signature.Assign(tedwards.BN254, stolen.Signature)
+	assert.ProverFailed(NewBatchCircuit(size, depth), &forged, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+
+	forged.Transfers[0] = assignment.Transfers[0]
+	forged.Transfers[0].Amount = 41
+	assert.ProverFailed(NewBatchCircuit(size, depth), &forged, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+
+	// Unsigned, wrongly signed and replayed transfers are refused when building the witness
+	replay := NewAccountTree(depth)
+	replay.SetAccount(1, &alice.PublicKey, big.NewInt(100), 0)
+	replay.SetAccount(5, &bob.PublicKey, big.NewInt(7), 3)
+	assert.Equal(oldRoot, replay.Root())
+	_, err = BuildBatchAssignment(replay, []Transfer{{From: 1, To: 5, Amount: big.NewInt(40), Nonce: 1}}, size)
+	assert.Error(err)
+	_, err = BuildBatchAssignment(replay, []Transfer{stolen}, size)
+	assert.Error(err)
+	_, err = BuildBatchAssignment(tree, []Transfer{transfer}, size)
+	assert.Error(err)
+
+	// Accounts without an owner key cannot send
+	_, err = BuildBatchAssignment(tree, []Transfer{{From: 2, To: 1, Amount: big.NewInt(0), Nonce: 1}}, size)
+	assert.Error(err)
+	_, err = BuildBatchAssignment(tree, make([]Transfer, size+1), size)
+	assert.Error(err)
+}
@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	nativemimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// BatchCommitmentCircuit proves knowledge of the transactions of a batch that, together
+// with the state roots before and after it, hash to the public commitment. Unused
+// transaction slots are zero.
+type BatchCommitmentCircuit struct {
+	// Public inputs
+	OldRoot    frontend.Variable `gnark:",public"`
+	NewRoot    frontend.Variable `gnark:",public"`
+	Commitment frontend.Variable `gnark:",public"`
+
+	// Private inputs
+	TxHashes []frontend.Variable `gnark:",secret"`
+}
+
+// NewBatchCommitmentCircuit returns a circuit definition for batches of up to capacity transactions
+func NewBatchCommitmentCircuit(capacity int) *BatchCommitmentCircuit {
+	return &BatchCommitmentCircuit{
+		TxHashes: make([]frontend.Variable, capacity),
+	}
+}
+
+// Define implements the circuit logic for batch commitments
+func (c *BatchCommitmentCircuit) Define(api frontend.API) error {
+	mimc, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	mimc.Write(c.OldRoot, c.NewRoot)
+	mimc.Write(c.TxHashes...)
+	api.AssertIsEqual(mimc.Sum(), c.Commitment)
+
+	return nil
+}
+
+// CompileBatchCircuit compiles the batch commitment circuit for the given capacity. Each
+// capacity is compiled once, every prover of it shares the constraint system.
+func CompileBatchCircuit(capacity int) (constraint.ConstraintSystem, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("batch capacity must be positive")
+	}
+
+	return batchCircuits.Get(fmt.Sprintf("batch-%d", capacity), func() (constraint.ConstraintSystem, error) {
+		r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, NewBatchCommitmentCircuit(capacity))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile batch circuit: %v", err)
+		}
+		return r1cs, nil
+	})
+}
+
+// NewBatchProver compiles the batch commitment circuit for the given capacity and runs a
+// local Groth16 setup
+func NewBatchProver(capacity int) (*Prover, error) {
+	r1cs, err := CompileBatchCircuit(capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, vk, err := groth16.Setup(r1cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup keys: %v", err)
+	}
+
+	return &Prover{
+		ProvingKey:   pk,
+		VerifyingKey: vk,
+		R1cs:         r1cs,
+	}, nil
+}
+
+// BatchCommitmentAssignment builds the witness for a batch. Roots and hashes are reduced
+// into the BN254 scalar field.
+func BatchCommitmentAssignment(capacity int, oldRoot, newRoot [32]byte, txHashes [][32]byte) (*BatchCommitmentCircuit, error) {
+	if len(txHashes) > capacity {
+		return nil, fmt.Errorf("batch has %d transactions, circuit capacity is %d", len(txHashes), capacity)
+	}
+
+	elements := make([]fr.Element, 0, capacity+2)
+	elements = append(elements, toField(oldRoot), toField(newRoot))
+	for _, h := range txHashes {
+		elements = append(elements, toField(h))
+	}
+	for len(elements) < capacity+2 {
+		elements = append(elements, fr.Element{})
+	}
+
+	h := nativemimc.NewMiMC()
+	for i := range elements {
+		b := elements[i].Bytes()
+		h.Write(b[:])
+	}
+	var commitment fr.Element
+	commitment.SetBytes(h.Sum(nil))
+
+	assignment := &BatchCommitmentCircuit{
+		OldRoot:    elements[0].String(),
+		NewRoot:    elements[1].String(),
+		Commitment: commitment.String(),
+		TxHashes:   make([]frontend.Variable, capacity),
+	}
+	for i := range assignment.TxHashes {
+		assignment.TxHashes[i] = elements[i+2].String()
+	}
+
+	return assignment, nil
+}
+
+// toField reduces a 32-byte hash modulo the scalar field
+func toField(b [32]byte) fr.Element {
+	var e fr.Element
+	e.SetBigInt(new(big.Int).SetBytes(b[:]))
+	return e
+}
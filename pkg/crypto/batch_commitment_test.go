@@ -0,0 +1,50 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/test"
+)
+
+func TestBatchCommitmentCircuit(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	oldRoot := [32]byte{1}
+	newRoot := [32]byte{2}
+	txHashes := [][32]byte{{0xaa}, {0xff, 0xff}}
+
+	assignment, err := BatchCommitmentAssignment(4, oldRoot, newRoot, txHashes)
+	assert.NoError(err)
+	assert.ProverSucceeded(NewBatchCommitmentCircuit(4), assignment, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+
+	// A commitment to different transactions must not satisfy the circuit
+	forged, err := BatchCommitmentAssignment(4, oldRoot, newRoot, [][32]byte{{0xab}})
+	assert.NoError(err)
+	forged.TxHashes = assignment.TxHashes
+	assert.ProverFailed(NewBatchCommitmentCircuit(4), forged, test.WithCurves(ecc.BN254), test.WithBackends(backend.GROTH16))
+
+	_, err = BatchCommitmentAssignment(1, oldRoot, newRoot, txHashes)
+	assert.Error(err)
+}
+
+func TestBatchProver(t *testing.T) {
+	prover, err := NewBatchProver(2)
+	if err != nil {
+		t.Fatalf("failed to create batch prover: %v", err)
+	}
+
+	assignment, err := BatchCommitmentAssignment(2, [32]byte{1}, [32]byte{2}, [][32]byte{{3}})
+	if err != nil {
+		t.Fatalf("failed to build assignment: %v", err)
+	}
+
+	proof, publicWitness, err := prover.ProveSerialized(assignment)
+	if err != nil {
+		t.Fatalf("failed to prove batch: %v", err)
+	}
+	if len(proof) == 0 || len(publicWitness) == 0 {
+		t.Fatal("empty proof or public witness")
+	}
+}
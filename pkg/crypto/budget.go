@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// ErrConstraintBudgetExceeded is returned for a circuit with more constraints than the
+// Powers of Tau ceremony its keys are set up from supports
+var ErrConstraintBudgetExceeded = errors.New("circuit exceeds constraint budget")
+
+// MaxCRSPowerSize is the largest ceremony the BN254 scalar field has FFT domains for
+const MaxCRSPowerSize = 28
+
+// ConstraintBudget reports the size of a compiled circuit against a ceremony of 2^PowerSize
+type ConstraintBudget struct {
+	Circuit     string
+	Constraints int
+	PowerSize   int
+	Capacity    int // Constraints the ceremony supports
+}
+
+// Fits reports whether keys for the circuit can be set up from the ceremony
+func (b ConstraintBudget) Fits() bool {
+	return b.Constraints <= b.Capacity
+}
+
+// Headroom returns the constraints the circuit may still grow by, negative when over budget
+func (b ConstraintBudget) Headroom() int {
+	return b.Capacity - b.Constraints
+}
+
+// BudgetCircuit is a circuit checked against the ceremony
+type BudgetCircuit struct {
+	Name    string
+	Circuit frontend.Circuit
+}
+
+// RollupCircuits returns the circuits whose proofs are submitted to L1: the batch circuit
+// for batchCapacity transactions and, when fallbackCapacity is positive, the smaller circuit
+// batches are reproved with after a missed proof deadline
+func RollupCircuits(batchCapacity, fallbackCapacity int) []BudgetCircuit {
+	circuits := []BudgetCircuit{
+		{Name: fmt.Sprintf("batch-%d", batchCapacity), Circuit: NewBatchCommitmentCircuit(batchCapacity)},
+	}
+	if fallbackCapacity > 0 {
+		circuits = append(circuits, BudgetCircuit{
+			Name:    fmt.Sprintf("batch-%d", fallbackCapacity),
+			Circuit: NewBatchCommitmentCircuit(fallbackCapacity),
+		})
+	}
+	return circuits
+}
+
+// CheckConstraintBudget compiles a circuit and reports it against a ceremony of 2^powerSize.
+// The report is returned with ErrConstraintBudgetExceeded when the circuit does not fit.
+func CheckConstraintBudget(name string, circuit frontend.Circuit, powerSize int) (*ConstraintBudget, error) {
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s circuit: %v", name, err)
+	}
+	return constraintBudget(name, cs, powerSize)
+}
+
+// CheckConstraintBudgets reports every circuit against a ceremony of 2^powerSize, failing
+// with ErrConstraintBudgetExceeded when any of them does not fit
+func CheckConstraintBudgets(circuits []BudgetCircuit, powerSize int) ([]ConstraintBudget, error) {
+	budgets := make([]ConstraintBudget, 0, len(circuits))
+	var over []string
+	for _, c := range circuits {
+		budget, err := CheckConstraintBudget(c.Name, c.Circuit, powerSize)
+		if err != nil && !errors.Is(err, ErrConstraintBudgetExceeded) {
+			return budgets, err
+		}
+		budgets = append(budgets, *budget)
+		if !budget.Fits() {
+			over = append(over, c.Name)
+		}
+	}
+
+	if len(over) > 0 {
+		return budgets, fmt.Errorf("%w of 2^%d: %v", ErrConstraintBudgetExceeded, powerSize, over)
+	}
+	return budgets, nil
+}
+
+// ConstraintBudget reports the prover's circuit against a ceremony of 2^powerSize
+func (p *Prover) ConstraintBudget(name string, powerSize int) (*ConstraintBudget, error) {
+	return constraintBudget(name, p.R1cs, powerSize)
+}
+
+func constraintBudget(name string, cs constraint.ConstraintSystem, powerSize int) (*ConstraintBudget, error) {
+	if powerSize < 1 || powerSize > MaxCRSPowerSize {
+		return nil, fmt.Errorf("ceremony power size %d out of range [1, %d]", powerSize, MaxCRSPowerSize)
+	}
+
+	budget := &ConstraintBudget{
+		Circuit:     name,
+		Constraints: cs.GetNbConstraints(),
+		PowerSize:   powerSize,
+		Capacity:    1 << powerSize,
+	}
+	if !budget.Fits() {
+		return budget, fmt.Errorf("%w: %s has %d constraints, 2^%d ceremony supports %d",
+			ErrConstraintBudgetExceeded, name, budget.Constraints, powerSize, budget.Capacity)
+	}
+	return budget, nil
+}
@@ -0,0 +1,28 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintBudgetFitsCeremony(t *testing.T) {
+	budget, err := CheckConstraintBudget("batch-1", NewBatchCommitmentCircuit(1), 12)
+	require.NoError(t, err)
+	require.True(t, budget.Fits())
+	require.Equal(t, 1<<12, budget.Capacity)
+	require.Equal(t, budget.Capacity-budget.Constraints, budget.Headroom())
+}
+
+func TestConstraintBudgetRejectsOversizedCircuit(t *testing.T) {
+	budgets, err := CheckConstraintBudgets(RollupCircuits(2, 1), 10)
+	require.True(t, errors.Is(err, ErrConstraintBudgetExceeded))
+	require.Len(t, budgets, 2)
+	require.False(t, budgets[0].Fits())
+	require.True(t, budgets[1].Fits())
+
+	_, err = CheckConstraintBudget("batch-1", NewBatchCommitmentCircuit(1), MaxCRSPowerSize+1)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrConstraintBudgetExceeded))
+}
@@ -0,0 +1,64 @@
+package crypto
+
+import (
+	"sync"
+
+	"github.com/consensys/gnark/constraint"
+)
+
+// CircuitCache compiles each circuit once and shares its constraint system between every
+// prover of the circuit. Proving only reads a constraint system, so concurrent workers and
+// the keys of every CRS epoch use the same one.
+type CircuitCache struct {
+	mu       sync.Mutex
+	circuits map[string]*cachedCircuit
+}
+
+// cachedCircuit is a constraint system being or done compiling
+type cachedCircuit struct {
+	ready chan struct{} // Closed once cs and err are set
+	cs    constraint.ConstraintSystem
+	err   error
+}
+
+// NewCircuitCache creates an empty cache
+func NewCircuitCache() *CircuitCache {
+	return &CircuitCache{circuits: make(map[string]*cachedCircuit)}
+}
+
+// batchCircuits caches the batch commitment circuit of every capacity
+var batchCircuits = NewCircuitCache()
+
+// Get returns the constraint system cached under name, compiling it with compile on first
+// use. Concurrent callers wait for the same compilation. A failed compilation is not
+// cached, the next call tries again.
+func (c *CircuitCache) Get(name string, compile func() (constraint.ConstraintSystem, error)) (constraint.ConstraintSystem, error) {
+	c.mu.Lock()
+	entry, ok := c.circuits[name]
+	if !ok {
+		entry = &cachedCircuit{ready: make(chan struct{})}
+		c.circuits[name] = entry
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-entry.ready
+		return entry.cs, entry.err
+	}
+
+	entry.cs, entry.err = compile()
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.circuits, name)
+		c.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.cs, entry.err
+}
+
+// Len returns the number of cached constraint systems
+func (c *CircuitCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.circuits)
+}
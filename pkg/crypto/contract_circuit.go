@@ -0,0 +1,613 @@
+package crypto
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	nativemimc "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/hash/mimc"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// addressBits is the size of an account address
+const addressBits = 160
+
+// emptyStateRoot is the root of an empty MiMC state tree, the storage root of accounts
+// without storage, and stateTreeDefaults[h] the root of an empty subtree of height h
+var emptyStateRoot, stateTreeDefaults = func() ([32]byte, [state.SMTDepth + 1][32]byte) {
+	var defaults [state.SMTDepth + 1][32]byte
+	for h := 1; h <= state.SMTDepth; h++ {
+		defaults[h] = state.MiMCHash(defaults[h-1], defaults[h-1])
+	}
+	return defaults[state.SMTDepth], defaults
+}()
+
+// ContractAccount is an account updated by a ContractCallCircuit: its fields before the
+// update and the authentication path of its leaf in the state tree, from the leaf upwards
+type ContractAccount struct {
+	Balance     frontend.Variable
+	Nonce       frontend.Variable
+	StorageRoot frontend.Variable
+	CodeHashHi  frontend.Variable
+	CodeHashLo  frontend.Variable
+	Siblings    []frontend.Variable
+}
+
+// StorageSlotUpdate is one storage write slot of a ContractCallCircuit. Keys and values
+// are 32-byte words given as their 128-bit halves, high half first. The sibling of the
+// leaf is another storage value and is given the same way, the siblings above it are tree
+// nodes. Disabled slots pad the call and leave the storage root unchanged.
+type StorageSlotUpdate struct {
+	Enabled       frontend.Variable
+	KeyHi         frontend.Variable
+	KeyLo         frontend.Variable
+	OldHi         frontend.Variable
+	OldLo         frontend.Variable
+	NewHi         frontend.Variable
+	NewLo         frontend.Variable
+	LeafSiblingHi frontend.Variable
+	LeafSiblingLo frontend.Variable
+	Siblings      []frontend.Variable
+}
+
+// ContractCallCircuit proves the state transition of a contract deployment or call on the
+// MiMC state tree without running the EVM in the circuit. The execution is committed to
+// instead: the transaction fields, the gas it used and the storage slots it wrote hash to
+// Commitment, and the circuit checks that applying exactly these effects to the tree with
+// root OldRoot yields NewRoot. The writes move the contract's storage root, the contract
+// receives the amount, a deployment sets the code of an unused address and a call keeps
+// it. The sender's nonce becomes the transaction's, it must hold the amount and the fee
+// of the gas limit and pays the amount and the fee of the gas used, which is credited to
+// the fee recipient or burned. Anyone holding the transaction checks the commitment by
+// re-executing it. Effects on other accounts, such as internal transfers or writes to
+// the storage of another contract, are not covered.
+//
+// The circuit is a standalone building block: the sequencer proves batches with the batch
+// circuit only and neither sets up nor distributes keys for this one.
+type ContractCallCircuit struct {
+	// Public inputs
+	OldRoot    frontend.Variable `gnark:",public"`
+	NewRoot    frontend.Variable `gnark:",public"`
+	Commitment frontend.Variable `gnark:",public"`
+
+	// Private inputs committed to by Commitment, in hash order
+	Deploy       frontend.Variable
+	From         frontend.Variable
+	To           frontend.Variable // Called or deployed contract
+	Amount       frontend.Variable
+	Nonce        frontend.Variable
+	GasPrice     frontend.Variable
+	GasLimit     frontend.Variable
+	GasUsed      frontend.Variable
+	CodeHashHi   frontend.Variable // Code hash of the contract after the transaction
+	CodeHashLo   frontend.Variable
+	FeeRecipient frontend.Variable
+	FeeCredited  frontend.Variable // Zero when the fee is burned
+	Writes       []StorageSlotUpdate
+
+	// Private inputs, updated in this order
+	Contract  ContractAccount
+	Sender    ContractAccount
+	Recipient ContractAccount
+}
+
+// NewContractCallCircuit returns a circuit definition for calls writing up to writes storage slots
+func NewContractCallCircuit(writes int) *ContractCallCircuit {
+	c := &ContractCallCircuit{
+		Writes:    make([]StorageSlotUpdate, writes),
+		Contract:  ContractAccount{Siblings: make([]frontend.Variable, state.SMTDepth)},
+		Sender:    ContractAccount{Siblings: make([]frontend.Variable, state.SMTDepth)},
+		Recipient: ContractAccount{Siblings: make([]frontend.Variable, state.SMTDepth)},
+	}
+	for i := range c.Writes {
+		c.Writes[i].Siblings = make([]frontend.Variable, state.SMTDepth-1)
+	}
+	return c
+}
+
+// Define implements the state transition constraints
+func (c *ContractCallCircuit) Define(api frontend.API) error {
+	api.AssertIsBoolean(c.Deploy)
+	api.AssertIsBoolean(c.FeeCredited)
+
+	// Range checks keep balance and fee arithmetic from wrapping around the field
+	api.ToBinary(c.Amount, BalanceBits)
+	api.ToBinary(c.GasPrice, BalanceBits)
+	api.ToBinary(c.Nonce, 64)
+	api.ToBinary(c.GasLimit, 64)
+	api.ToBinary(c.GasUsed, 64)
+	api.ToBinary(c.CodeHashHi, wordHalfBits)
+	api.ToBinary(c.CodeHashLo, wordHalfBits)
+	api.AssertIsLessOrEqual(c.GasUsed, c.GasLimit)
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Deploy, c.From, c.To, c.Amount, c.Nonce, c.GasPrice, c.GasLimit, c.GasUsed, c.CodeHashHi, c.CodeHashLo, c.FeeRecipient, c.FeeCredited)
+	for _, w := range c.Writes {
+		h.Write(w.Enabled, w.KeyHi, w.KeyLo, w.NewHi, w.NewLo)
+	}
+	api.AssertIsEqual(h.Sum(), c.Commitment)
+
+	// Each write is checked against the storage root left by the previous one
+	storageRoot := c.Contract.StorageRoot
+	for _, w := range c.Writes {
+		api.AssertIsBoolean(w.Enabled)
+		for _, half := range []frontend.Variable{w.KeyHi, w.KeyLo, w.OldHi, w.OldLo, w.NewHi, w.NewLo, w.LeafSiblingHi, w.LeafSiblingLo} {
+			api.ToBinary(half, wordHalfBits)
+		}
+
+		key, err := StateHashWords(api, w.KeyHi, w.KeyLo, 0, 0)
+		if err != nil {
+			return err
+		}
+		path := treePath(api, key)
+		siblingsHi, siblingsLo := splitSiblings(api, w.Siblings)
+		siblingsHi = append([]frontend.Variable{w.LeafSiblingHi}, siblingsHi...)
+		siblingsLo = append([]frontend.Variable{w.LeafSiblingLo}, siblingsLo...)
+
+		oldRoot, err := treeRoot(api, w.OldHi, w.OldLo, path, siblingsHi, siblingsLo)
+		if err != nil {
+			return err
+		}
+		api.AssertIsEqual(api.Mul(w.Enabled, api.Sub(oldRoot, storageRoot)), 0)
+
+		newRoot, err := treeRoot(api, w.NewHi, w.NewLo, path, siblingsHi, siblingsLo)
+		if err != nil {
+			return err
+		}
+		storageRoot = api.Select(w.Enabled, newRoot, storageRoot)
+	}
+
+	// A deployment needs an address without code, nonce or storage and sets its nonce to one
+	contract := c.Contract
+	emptyRoot := new(big.Int).SetBytes(emptyStateRoot[:])
+	api.AssertIsEqual(api.Mul(c.Deploy, contract.Nonce), 0)
+	api.AssertIsEqual(api.Mul(c.Deploy, contract.CodeHashHi), 0)
+	api.AssertIsEqual(api.Mul(c.Deploy, contract.CodeHashLo), 0)
+	api.AssertIsEqual(api.Mul(c.Deploy, api.Sub(contract.StorageRoot, emptyRoot)), 0)
+	api.AssertIsEqual(api.Mul(api.Sub(1, c.Deploy), api.Sub(contract.CodeHashHi, c.CodeHashHi)), 0)
+	api.AssertIsEqual(api.Mul(api.Sub(1, c.Deploy), api.Sub(contract.CodeHashLo, c.CodeHashLo)), 0)
+
+	updated := contract
+	updated.Balance = api.Add(contract.Balance, c.Amount)
+	updated.Nonce = api.Select(c.Deploy, 1, contract.Nonce)
+	updated.StorageRoot = storageRoot
+	updated.CodeHashHi = c.CodeHashHi
+	updated.CodeHashLo = c.CodeHashLo
+	root, err := updateAccount(api, c.OldRoot, 1, c.To, contract, updated)
+	if err != nil {
+		return err
+	}
+
+	// The sender pays against the root left by the contract, which it may be itself
+	sender := c.Sender
+	fee := api.Mul(c.GasUsed, c.GasPrice)
+	api.AssertIsEqual(api.Add(sender.Nonce, 1), c.Nonce)
+	api.AssertIsLessOrEqual(api.Add(c.Amount, api.Mul(c.GasLimit, c.GasPrice)), sender.Balance)
+
+	updated = sender
+	updated.Balance = api.Sub(sender.Balance, c.Amount, fee)
+	updated.Nonce = c.Nonce
+	root, err = updateAccount(api, root, 1, c.From, sender, updated)
+	if err != nil {
+		return err
+	}
+
+	recipient := c.Recipient
+	updated = recipient
+	updated.Balance = api.Add(recipient.Balance, fee)
+	root, err = updateAccount(api, root, c.FeeCredited, c.FeeRecipient, recipient, updated)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(root, c.NewRoot)
+	return nil
+}
+
+// updateAccount replaces the leaf of the account at address, whose fields are old, with
+// one committing to updated and returns the new root. When enabled is zero root is
+// returned unchanged and old need not be in the tree.
+func updateAccount(api frontend.API, root, enabled, address frontend.Variable, old, updated ContractAccount) (frontend.Variable, error) {
+	bits := api.ToBinary(address, addressBits)
+	addrHi := api.FromBinary(bits[wordHalfBits:]...)
+	addrLo := api.FromBinary(bits[:wordHalfBits]...)
+
+	api.ToBinary(old.Balance, BalanceBits)
+	api.ToBinary(old.Nonce, 64)
+	api.ToBinary(old.CodeHashHi, wordHalfBits)
+	api.ToBinary(old.CodeHashLo, wordHalfBits)
+	api.ToBinary(api.Select(enabled, updated.Balance, 0), BalanceBits)
+
+	key, err := StateHashWords(api, addrHi, addrLo, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	path := treePath(api, key)
+	siblingsHi, siblingsLo := splitSiblings(api, old.Siblings)
+
+	oldLeaf, err := accountLeafVariable(api, addrHi, addrLo, old)
+	if err != nil {
+		return nil, err
+	}
+	leafHi, leafLo := splitNode(api, oldLeaf)
+	oldRoot, err := treeRoot(api, leafHi, leafLo, path, siblingsHi, siblingsLo)
+	if err != nil {
+		return nil, err
+	}
+	api.AssertIsEqual(api.Mul(enabled, api.Sub(oldRoot, root)), 0)
+
+	newLeaf, err := accountLeafVariable(api, addrHi, addrLo, updated)
+	if err != nil {
+		return nil, err
+	}
+	leafHi, leafLo = splitNode(api, newLeaf)
+	newRoot, err := treeRoot(api, leafHi, leafLo, path, siblingsHi, siblingsLo)
+	if err != nil {
+		return nil, err
+	}
+	return api.Select(enabled, newRoot, root), nil
+}
+
+// accountLeafVariable constrains the state tree leaf of an account, zero for an empty
+// account, like the state computes it. Balances fit the low half of their word.
+func accountLeafVariable(api frontend.API, addrHi, addrLo frontend.Variable, acc ContractAccount) (frontend.Variable, error) {
+	addrBalance, err := StateHashWords(api, addrHi, addrLo, 0, acc.Balance)
+	if err != nil {
+		return nil, err
+	}
+	rootHi, rootLo := splitNode(api, acc.StorageRoot)
+	nonceRoot, err := StateHashWords(api, 0, acc.Nonce, rootHi, rootLo)
+	if err != nil {
+		return nil, err
+	}
+	nonceRootHi, nonceRootLo := splitNode(api, nonceRoot)
+	nonceRootCode, err := StateHashWords(api, nonceRootHi, nonceRootLo, acc.CodeHashHi, acc.CodeHashLo)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := StateHash(api, addrBalance, nonceRootCode)
+	if err != nil {
+		return nil, err
+	}
+
+	emptyRoot := new(big.Int).SetBytes(emptyStateRoot[:])
+	empty := api.Mul(
+		api.IsZero(acc.Balance),
+		api.IsZero(acc.Nonce),
+		api.IsZero(acc.CodeHashHi),
+		api.IsZero(acc.CodeHashLo),
+		api.IsZero(api.Sub(acc.StorageRoot, emptyRoot)),
+	)
+	return api.Select(empty, 0, leaf), nil
+}
+
+// treePath returns the path bits of a state tree key, least significant first. Keys are
+// hashes and so field elements, the two top bits of the 256-bit path are zero.
+func treePath(api frontend.API, key frontend.Variable) []frontend.Variable {
+	path := api.ToBinary(key, 2*wordHalfBits-2)
+	return append(path, 0, 0)
+}
+
+// splitSiblings decomposes tree nodes into their 128-bit halves
+func splitSiblings(api frontend.API, siblings []frontend.Variable) ([]frontend.Variable, []frontend.Variable) {
+	hi := make([]frontend.Variable, len(siblings))
+	lo := make([]frontend.Variable, len(siblings))
+	for i := range siblings {
+		hi[i], lo[i] = splitNode(api, siblings[i])
+	}
+	return hi, lo
+}
+
+// treeRoot recomputes the root of a state tree from a leaf and its authentication path,
+// all given as 128-bit halves
+func treeRoot(api frontend.API, leafHi, leafLo frontend.Variable, path, siblingsHi, siblingsLo []frontend.Variable) (frontend.Variable, error) {
+	hi, lo := leafHi, leafLo
+	var node frontend.Variable
+	for i := range path {
+		if i > 0 {
+			hi, lo = splitNode(api, node)
+		}
+		var err error
+		node, err = StateHashWords(api,
+			api.Select(path[i], siblingsHi[i], hi),
+			api.Select(path[i], siblingsLo[i], lo),
+			api.Select(path[i], hi, siblingsHi[i]),
+			api.Select(path[i], lo, siblingsLo[i]),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// NewContractCallProver compiles the contract call circuit for calls writing up to writes
+// storage slots and runs a local Groth16 setup. Its keys are not bound to any CRS ceremony
+// or L1 verifier.
+func NewContractCallProver(writes int) (*Prover, error) {
+	if writes <= 0 {
+		return nil, fmt.Errorf("storage write slots must be positive")
+	}
+
+	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, NewContractCallCircuit(writes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile contract call circuit: %v", err)
+	}
+
+	pk, vk, err := groth16.Setup(r1cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup keys: %v", err)
+	}
+
+	return &Prover{
+		ProvingKey:   pk,
+		VerifyingKey: vk,
+		R1cs:         r1cs,
+	}, nil
+}
+
+// StorageWrite sets the storage slot Key to Value
+type StorageWrite struct {
+	Key   [32]byte
+	Value [32]byte
+}
+
+// ContractCall is the outcome of executing a contract deployment or call
+type ContractCall struct {
+	Tx           *state.Transaction
+	Contract     types.Address  // Called contract, or the address the code was deployed at
+	Code         []byte         // Deployed code, nil for calls
+	GasUsed      uint64         // Gas charged to the sender
+	FeeRecipient *types.Address // Credited with the fee, nil when fees are burned
+	Writes       []StorageWrite // Storage slots of Contract the transaction changed
+}
+
+// BuildContractCallAssignment applies call to st and returns the witness proving the
+// transition, with the writes padded to writes slots. st must hash its trees with MiMC.
+// It is left in the post-call state; on error it may hold part of the call.
+func BuildContractCallAssignment(st *state.State, call *ContractCall, writes int) (*ContractCallCircuit, error) {
+	if version := st.RootVersion(); version != state.RootVersionMiMC {
+		return nil, fmt.Errorf("contract call circuit proves %s state roots, state uses %s", state.RootVersionMiMC, version)
+	}
+
+	tx := call.Tx
+	if tx == nil {
+		return nil, fmt.Errorf("contract call has no transaction")
+	}
+	deploy := tx.Type == state.TxTypeContractDeploy || tx.Type == state.TxTypeContractCreate
+	if !deploy && tx.Type != state.TxTypeContractCall {
+		return nil, fmt.Errorf("transaction type %d is not a contract deployment or call", tx.Type)
+	}
+	if !deploy && call.Contract != tx.To {
+		return nil, fmt.Errorf("call to %s executed contract %s", tx.To.Hex(), call.Contract.Hex())
+	}
+	if len(call.Writes) > writes {
+		return nil, fmt.Errorf("call writes %d storage slots, circuit has %d", len(call.Writes), writes)
+	}
+	if call.GasUsed > tx.Gas {
+		return nil, fmt.Errorf("call used %d gas, limit is %d", call.GasUsed, tx.Gas)
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), BalanceBits)
+	amount, gasPrice := new(big.Int), new(big.Int)
+	if tx.Amount != nil {
+		amount.Set(tx.Amount)
+	}
+	if tx.GasPrice != nil {
+		gasPrice.Set(tx.GasPrice)
+	}
+	if amount.Sign() < 0 || amount.Cmp(limit) >= 0 || gasPrice.Sign() < 0 || gasPrice.Cmp(limit) >= 0 {
+		return nil, fmt.Errorf("invalid amount or gas price")
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(call.GasUsed), gasPrice)
+
+	assignment := &ContractCallCircuit{
+		OldRoot:      wordVariable(st.GetStateRoot()),
+		Deploy:       0,
+		From:         new(big.Int).SetBytes(tx.From[:]),
+		To:           new(big.Int).SetBytes(call.Contract[:]),
+		Amount:       amount.String(),
+		Nonce:        tx.Nonce,
+		GasPrice:     gasPrice.String(),
+		GasLimit:     tx.Gas,
+		GasUsed:      call.GasUsed,
+		FeeRecipient: 0,
+		FeeCredited:  0,
+		Writes:       make([]StorageSlotUpdate, writes),
+	}
+
+	// Storage writes, each proven against the storage tree left by the previous one
+	contract := st.GetAccountProof(call.Contract)
+	for i := range assignment.Writes {
+		if i >= len(call.Writes) {
+			assignment.Writes[i] = paddingSlotUpdate()
+			continue
+		}
+
+		w := call.Writes[i]
+		_, proof := st.GetStorageProof(call.Contract, w.Key)
+		siblings := expandSiblings(proof.Proof)
+		slot := StorageSlotUpdate{Enabled: 1, Siblings: nodeVariables(siblings[1:])}
+		slot.KeyHi, slot.KeyLo = wordHalves(w.Key)
+		slot.OldHi, slot.OldLo = wordHalves(proof.Value)
+		slot.NewHi, slot.NewLo = wordHalves(w.Value)
+		slot.LeafSiblingHi, slot.LeafSiblingLo = wordHalves(siblings[0])
+		st.SetStorage(call.Contract, w.Key, w.Value)
+
+		assignment.Writes[i] = slot
+	}
+
+	// The contract, against the root before the writes since they only change its own leaf
+	codeHash := contract.CodeHash
+	nonce := contract.Nonce
+	if deploy {
+		if contract.Nonce != 0 || contract.CodeHash != ([32]byte{}) || (contract.Exists && contract.StorageRoot != emptyStateRoot) {
+			return nil, fmt.Errorf("contract address %s is already in use", call.Contract.Hex())
+		}
+		assignment.Deploy = 1
+		codeHash = state.CodeHash(call.Code)
+		nonce = 1
+		st.SetCode(call.Contract, call.Code)
+	}
+	assignment.CodeHashHi, assignment.CodeHashLo = wordHalves(codeHash)
+	assignment.Contract = accountVariables(contract)
+
+	balance := new(big.Int).Add(contract.Balance, amount)
+	if balance.Cmp(limit) >= 0 {
+		return nil, fmt.Errorf("contract balance overflows")
+	}
+	if err := setBalanceNonce(st, call.Contract, balance, nonce); err != nil {
+		return nil, err
+	}
+
+	// The sender, which must have sent the transaction with its next nonce
+	sender := st.GetAccountProof(tx.From)
+	if sender.Nonce+1 != tx.Nonce {
+		return nil, fmt.Errorf("transaction nonce %d, sender nonce is %d", tx.Nonce, sender.Nonce)
+	}
+	maxCost := new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas), gasPrice)
+	if maxCost.Add(maxCost, amount).Cmp(sender.Balance) > 0 {
+		return nil, fmt.Errorf("insufficient balance")
+	}
+	assignment.Sender = accountVariables(sender)
+	balance = new(big.Int).Sub(sender.Balance, amount)
+	if err := setBalanceNonce(st, tx.From, balance.Sub(balance, fee), tx.Nonce); err != nil {
+		return nil, err
+	}
+
+	// The fee recipient, a zero account at the zero address when the fee is burned
+	if call.FeeRecipient != nil {
+		recipient := st.GetAccountProof(*call.FeeRecipient)
+		balance = new(big.Int).Add(recipient.Balance, fee)
+		if balance.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("fee recipient balance overflows")
+		}
+		assignment.FeeRecipient = new(big.Int).SetBytes(call.FeeRecipient[:])
+		assignment.FeeCredited = 1
+		assignment.Recipient = accountVariables(recipient)
+		if err := setBalanceNonce(st, *call.FeeRecipient, balance, recipient.Nonce); err != nil {
+			return nil, err
+		}
+	} else {
+		assignment.Recipient = accountVariables(&state.AccountProof{Balance: new(big.Int), StorageRoot: emptyStateRoot})
+	}
+
+	assignment.NewRoot = wordVariable(st.GetStateRoot())
+	assignment.Commitment = contractCallCommitment(assignment)
+	return assignment, nil
+}
+
+// setBalanceNonce updates the balance and nonce of the account at address, creating it if needed
+func setBalanceNonce(st *state.State, address types.Address, balance *big.Int, nonce uint64) error {
+	if balance.Sign() < 0 {
+		return fmt.Errorf("account %s balance underflows", address.Hex())
+	}
+	account := state.Account{Address: address}
+	if existing, err := st.GetAccount(address); err == nil {
+		account = *existing
+	}
+	account.Balance = balance
+	account.Nonce = nonce
+	st.SetAccount(&account)
+	return nil
+}
+
+// contractCallCommitment hashes the committed fields of an assignment like the circuit
+func contractCallCommitment(c *ContractCallCircuit) string {
+	values := []frontend.Variable{c.Deploy, c.From, c.To, c.Amount, c.Nonce, c.GasPrice, c.GasLimit, c.GasUsed, c.CodeHashHi, c.CodeHashLo, c.FeeRecipient, c.FeeCredited}
+	for _, w := range c.Writes {
+		values = append(values, w.Enabled, w.KeyHi, w.KeyLo, w.NewHi, w.NewLo)
+	}
+
+	h := nativemimc.NewMiMC()
+	for _, v := range values {
+		var e fr.Element
+		if _, err := e.SetInterface(v); err != nil {
+			panic(fmt.Sprintf("invalid committed value %v: %v", v, err))
+		}
+		b := e.Bytes()
+		h.Write(b[:])
+	}
+	var commitment fr.Element
+	commitment.SetBytes(h.Sum(nil))
+	return commitment.String()
+}
+
+// accountVariables returns the circuit fields of a proven account
+func accountVariables(proof *state.AccountProof) ContractAccount {
+	storageRoot := proof.StorageRoot
+	if !proof.Exists {
+		storageRoot = emptyStateRoot
+	}
+	acc := ContractAccount{
+		Balance:     proof.Balance.String(),
+		Nonce:       proof.Nonce,
+		StorageRoot: wordVariable(storageRoot),
+		Siblings:    nodeVariables(make([][32]byte, state.SMTDepth)),
+	}
+	acc.CodeHashHi, acc.CodeHashLo = wordHalves(proof.CodeHash)
+	if proof.Proof != nil {
+		acc.Siblings = nodeVariables(expandSiblings(proof.Proof))
+	}
+	return acc
+}
+
+// paddingSlotUpdate returns a disabled storage write slot
+func paddingSlotUpdate() StorageSlotUpdate {
+	return StorageSlotUpdate{
+		Enabled:       0,
+		KeyHi:         0,
+		KeyLo:         0,
+		OldHi:         0,
+		OldLo:         0,
+		NewHi:         0,
+		NewLo:         0,
+		LeafSiblingHi: 0,
+		LeafSiblingLo: 0,
+		Siblings:      nodeVariables(make([][32]byte, state.SMTDepth-1)),
+	}
+}
+
+// expandSiblings returns every sibling of a MiMC state tree proof from the leaf upwards,
+// filling in the empty subtrees the proof leaves out
+func expandSiblings(proof *state.MerkleProof) [][32]byte {
+	siblings := make([][32]byte, state.SMTDepth)
+	next := 0
+	for h := range siblings {
+		siblings[h] = stateTreeDefaults[h]
+		if proof.Bitmap[h/8]&(1<<(h%8)) != 0 && next < len(proof.Siblings) {
+			siblings[h] = proof.Siblings[next]
+			next++
+		}
+	}
+	return siblings
+}
+
+// nodeVariables returns tree nodes as circuit variables
+func nodeVariables(nodes [][32]byte) []frontend.Variable {
+	vars := make([]frontend.Variable, len(nodes))
+	for i := range nodes {
+		vars[i] = wordVariable(nodes[i])
+	}
+	return vars
+}
+
+// wordVariable returns a 32-byte word that is a field element as a circuit variable
+func wordVariable(word [32]byte) *big.Int {
+	return new(big.Int).SetBytes(word[:])
+}
+
+// wordHalves returns the high and low 128-bit halves of a 32-byte word
+func wordHalves(word [32]byte) (*big.Int, *big.Int) {
+	return new(big.Int).SetBytes(word[:16]), new(big.Int).SetBytes(word[16:])
+}
@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+func TestContractCallSchemaMatchesCircuit(t *testing.T) {
+	require.Equal(t, ContractCallSchemaV1.Inputs, CircuitPublicInputs(NewContractCallCircuit(1)))
+	require.NotEqual(t, BatchCommitmentSchemaV1.Hash(), ContractCallSchemaV1.Hash())
+}
+
+func TestContractCallCircuit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("hashes several paths of the 256-level state tree")
+	}
+
+	const writes = 2
+	sender := types.Address{1}
+	contract := types.Address{2}
+	feeRecipient := types.Address{3}
+
+	st := state.NewState()
+	require.NoError(t, st.SetRootVersion(state.RootVersionMiMC))
+	st.SetAccount(&state.Account{Address: sender, Balance: big.NewInt(1_000_000), Nonce: 4})
+	st.SetCode(contract, []byte{0x60, 0x00})
+	st.SetStorage(contract, [32]byte{1}, [32]byte{0xaa})
+
+	// A call overwriting a slot, the second write slot is padding
+	call := &ContractCall{
+		Tx: &state.Transaction{
+			Type:     state.TxTypeContractCall,
+			From:     sender,
+			To:       contract,
+			Amount:   big.NewInt(10),
+			Nonce:    5,
+			Gas:      50_000,
+			GasPrice: big.NewInt(2),
+		},
+		Contract:     contract,
+		GasUsed:      30_000,
+		FeeRecipient: &feeRecipient,
+		Writes:       []StorageWrite{{Key: [32]byte{1}, Value: [32]byte{0xff, 0xff}}},
+	}
+	assignment, err := BuildContractCallAssignment(st, call, writes)
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(NewContractCallCircuit(writes), assignment, ecc.BN254.ScalarField()))
+
+	account, err := st.GetAccount(sender)
+	require.NoError(t, err)
+	require.Equal(t, int64(1_000_000-10-60_000), account.Balance.Int64())
+	account, err = st.GetAccount(feeRecipient)
+	require.NoError(t, err)
+	require.Equal(t, int64(60_000), account.Balance.Int64())
+
+	// Claiming less gas than was charged breaks the commitment
+	forged := *assignment
+	forged.GasUsed = 20_000
+	require.Error(t, test.IsSolved(NewContractCallCircuit(writes), &forged, ecc.BN254.ScalarField()))
+
+	// A write other than the committed one does not reach the new root
+	forged = *assignment
+	forged.Writes = append([]StorageSlotUpdate(nil), assignment.Writes...)
+	forged.Writes[0].NewLo = 1
+	forged.Commitment = contractCallCommitment(&forged)
+	require.Error(t, test.IsSolved(NewContractCallCircuit(writes), &forged, ecc.BN254.ScalarField()))
+
+	// A deployment with fees burned, at an address already funded
+	deployed := types.Address{4}
+	st.SetAccount(&state.Account{Address: deployed, Balance: big.NewInt(7)})
+	deploy := &ContractCall{
+		Tx: &state.Transaction{
+			Type:     state.TxTypeContractDeploy,
+			From:     sender,
+			Nonce:    6,
+			Gas:      100_000,
+			GasPrice: big.NewInt(1),
+		},
+		Contract: deployed,
+		Code:     []byte{0x60, 0x01},
+		GasUsed:  80_000,
+		Writes:   []StorageWrite{{Key: [32]byte{2}, Value: [32]byte{31: 1}}, {Key: [32]byte{3}, Value: [32]byte{31: 2}}},
+	}
+	assignment, err = BuildContractCallAssignment(st, deploy, writes)
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(NewContractCallCircuit(writes), assignment, ecc.BN254.ScalarField()))
+
+	// The address now holds code and cannot be deployed to again
+	deploy.Tx.Nonce = 7
+	_, err = BuildContractCallAssignment(st, deploy, writes)
+	require.Error(t, err)
+
+	_, err = BuildContractCallAssignment(state.NewState(), call, writes)
+	require.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BatchKeys are the keys batch proofs are made with
+type BatchKeys struct {
+	Prover      *Prover
+	Epoch       uint64   // CRS epoch the keys come from, 0 for locally set up keys
+	Fingerprint [32]byte // Of the verifying key
+	ActivateAt  uint64   // First batch proven with the keys
+}
+
+// KeyManager holds the batch circuit keys and switches to the keys of a later CRS epoch
+// at the batch scheduled for it, so every node rotates at the same point of the chain
+type KeyManager struct {
+	active   *BatchKeys
+	next     *BatchKeys
+	previous *BatchKeys // Replaced by active, still proving batches from before the rotation
+	mu       sync.Mutex
+}
+
+// NewKeyManager creates a key manager proving with prover, whose keys come from epoch
+func NewKeyManager(prover *Prover, epoch uint64) (*KeyManager, error) {
+	fingerprint, err := prover.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+	return &KeyManager{active: &BatchKeys{Prover: prover, Epoch: epoch, Fingerprint: fingerprint}}, nil
+}
+
+// Keys returns the keys that prove batchNumber, activating the scheduled keys once their
+// batch is reached. Batches from before the rotation are still proven with the old keys.
+func (m *KeyManager) Keys(batchNumber uint64) *BatchKeys {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.next != nil && batchNumber >= m.next.ActivateAt {
+		m.previous, m.active, m.next = m.active, m.next, nil
+	}
+	if batchNumber < m.active.ActivateAt && m.previous != nil {
+		return m.previous
+	}
+	return m.active
+}
+
+// Current returns the keys in use
+func (m *KeyManager) Current() *BatchKeys {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// Scheduled returns the keys waiting to replace the current ones, nil when none are
+func (m *KeyManager) Scheduled() *BatchKeys {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.next
+}
+
+// Schedule replaces the keys with those of a later CRS epoch from batch activateAt on,
+// overriding any rotation scheduled before. The new keys must be for the same circuit.
+func (m *KeyManager) Schedule(prover *Prover, epoch, activateAt uint64) (*BatchKeys, error) {
+	fingerprint, err := prover.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if epoch <= m.active.Epoch {
+		return nil, fmt.Errorf("keys of epoch %d do not follow the current epoch %d", epoch, m.active.Epoch)
+	}
+	if activateAt < m.active.ActivateAt {
+		return nil, fmt.Errorf("keys of epoch %d would activate at batch %d, before the current keys", epoch, activateAt)
+	}
+	if prover.VerifyingKey.NbPublicWitness() != m.active.Prover.VerifyingKey.NbPublicWitness() {
+		return nil, fmt.Errorf("keys of epoch %d are for another circuit", epoch)
+	}
+
+	m.next = &BatchKeys{Prover: prover, Epoch: epoch, Fingerprint: fingerprint, ActivateAt: activateAt}
+	return m.next, nil
+}
@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/constraint"
+)
+
+// KeyFingerprint returns the SHA-256 of a verifying key's encoding. Nodes whose keys share
+// a fingerprint produce proofs the same verifier accepts.
+func KeyFingerprint(vk groth16.VerifyingKey) ([32]byte, error) {
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		return [32]byte{}, fmt.Errorf("failed to encode verifying key: %v", err)
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
+
+// Fingerprint returns the fingerprint of the prover's verifying key
+func (p *Prover) Fingerprint() ([32]byte, error) {
+	return KeyFingerprint(p.VerifyingKey)
+}
+
+// OpenBatchProver returns a batch circuit prover with the keys stored at pkPath and vkPath.
+// When neither file exists the keys are set up locally and written there, so they survive
+// restarts and can be copied to the other nodes. Empty paths set up keys that are not kept.
+func OpenBatchProver(capacity int, pkPath, vkPath string) (prover *Prover, created bool, err error) {
+	if pkPath == "" || vkPath == "" {
+		prover, err := NewBatchProver(capacity)
+		return prover, true, err
+	}
+
+	pkExists, err := fileExists(pkPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read proving key: %v", err)
+	}
+	vkExists, err := fileExists(vkPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read verifying key: %v", err)
+	}
+
+	if pkExists || vkExists {
+		cs, err := CompileBatchCircuit(capacity)
+		if err != nil {
+			return nil, false, err
+		}
+		prover, err := LoadProver(cs, pkPath, vkPath)
+		return prover, false, err
+	}
+
+	prover, err = NewBatchProver(capacity)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := prover.WriteKeys(pkPath, vkPath); err != nil {
+		return nil, false, err
+	}
+	return prover, true, nil
+}
+
+// LoadProver returns a prover of the compiled circuit cs with the keys stored at pkPath and
+// vkPath. Keys set up for a circuit of another shape are refused.
+func LoadProver(cs constraint.ConstraintSystem, pkPath, vkPath string) (*Prover, error) {
+	pk := groth16.NewProvingKey(ecc.BN254)
+	if err := readKey(pkPath, pk); err != nil {
+		return nil, fmt.Errorf("failed to read proving key: %v", err)
+	}
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if err := readKey(vkPath, vk); err != nil {
+		return nil, fmt.Errorf("failed to read verifying key: %v", err)
+	}
+
+	// The constant one wire is not part of the public witness
+	if want := cs.GetNbPublicVariables() - 1; vk.NbPublicWitness() != want {
+		return nil, fmt.Errorf("verifying key has %d public inputs, the circuit has %d", vk.NbPublicWitness(), want)
+	}
+	if key, ok := pk.(*groth16bn254.ProvingKey); ok {
+		if want := fft.NewDomain(uint64(cs.GetNbConstraints())).Cardinality; key.Domain.Cardinality != want {
+			return nil, fmt.Errorf("proving key has domain size %d, the circuit needs %d", key.Domain.Cardinality, want)
+		}
+	}
+
+	return &Prover{
+		ProvingKey:   pk,
+		VerifyingKey: vk,
+		R1cs:         cs,
+	}, nil
+}
+
+// WriteKeys stores the prover's keys at pkPath and vkPath
+func (p *Prover) WriteKeys(pkPath, vkPath string) error {
+	if err := writeKey(pkPath, p.ProvingKey); err != nil {
+		return fmt.Errorf("failed to write proving key: %v", err)
+	}
+	if err := writeKey(vkPath, p.VerifyingKey); err != nil {
+		return fmt.Errorf("failed to write verifying key: %v", err)
+	}
+	return nil
+}
+
+// EpochKeyPaths returns where the batch circuit keys of a CRS epoch are kept under dir
+func EpochKeyPaths(dir string, epoch uint64, capacity int) (pkPath, vkPath string) {
+	base := filepath.Join(dir, fmt.Sprintf("epoch-%d", epoch), fmt.Sprintf("batch-%d", capacity))
+	return base + ".pk", base + ".vk"
+}
+
+// readKey decodes a key from path, checking its points are on the curve
+func readKey(path string, key io.ReaderFrom) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = key.ReadFrom(bufio.NewReader(file))
+	return err
+}
+
+// writeKey writes a key to path atomically, creating its directory
+func writeKey(path string, key io.WriterTo) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if _, err := key.WriteTo(&buf); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
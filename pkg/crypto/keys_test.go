@@ -0,0 +1,65 @@
+package crypto
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenBatchProverPersistsKeys(t *testing.T) {
+	dir := t.TempDir()
+	pkPath, vkPath := filepath.Join(dir, "batch.pk"), filepath.Join(dir, "batch.vk")
+
+	prover, created, err := OpenBatchProver(2, pkPath, vkPath)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	// A restart loads the same keys instead of setting up new ones
+	reopened, created, err := OpenBatchProver(2, pkPath, vkPath)
+	require.NoError(t, err)
+	require.False(t, created)
+
+	fingerprint, err := prover.Fingerprint()
+	require.NoError(t, err)
+	reloaded, err := reopened.Fingerprint()
+	require.NoError(t, err)
+	require.Equal(t, fingerprint, reloaded)
+
+	assignment, err := BatchCommitmentAssignment(2, [32]byte{1}, [32]byte{2}, [][32]byte{{3}})
+	require.NoError(t, err)
+	proof, publicInputs, err := reopened.ProveSerialized(assignment)
+	require.NoError(t, err)
+	ok, err := prover.VerifyProof(proof, publicInputs)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Keys of another circuit size are refused
+	_, _, err = OpenBatchProver(8, pkPath, vkPath)
+	require.Error(t, err)
+}
+
+func TestKeyManagerRotation(t *testing.T) {
+	first, err := NewBatchProver(1)
+	require.NoError(t, err)
+	second, err := NewBatchProver(1)
+	require.NoError(t, err)
+
+	keys, err := NewKeyManager(first, 1)
+	require.NoError(t, err)
+
+	_, err = keys.Schedule(second, 1, 10)
+	require.Error(t, err, "keys of the current epoch must not be scheduled")
+
+	next, err := keys.Schedule(second, 2, 10)
+	require.NoError(t, err)
+	require.NotEqual(t, keys.Current().Fingerprint, next.Fingerprint)
+
+	require.Same(t, first, keys.Keys(9).Prover)
+	require.Same(t, second, keys.Keys(10).Prover)
+	require.Nil(t, keys.Scheduled())
+	require.Equal(t, uint64(2), keys.Current().Epoch)
+
+	// A batch from before the rotation proven late keeps the old keys
+	require.Same(t, first, keys.Keys(9).Prover)
+}
@@ -0,0 +1,124 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/consensys/gnark/constraint"
+	csbn254 "github.com/consensys/gnark/constraint/bn254"
+)
+
+// The Groth16 keys of a circuit are derived from a finished Powers of Tau ceremony in a
+// circuit-specific phase 2. One node contributes the secret δ and shares the resulting
+// transcript, every node verifies it against the phase 2 it derives from the ceremony
+// output itself and extracts the same keys.
+
+// ContributePhase2 runs the phase 2 of circuit cs on the ceremony transcript at ptauPath with
+// a random δ and returns the encoded transcript
+func ContributePhase2(cs constraint.ConstraintSystem, ptauPath string) ([]byte, error) {
+	_, phase2, _, err := initPhase2(cs, ptauPath)
+	if err != nil {
+		return nil, err
+	}
+	phase2.Contribute()
+
+	var buf bytes.Buffer
+	if _, err := phase2.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode phase 2 transcript: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ProverFromPhase2 verifies a phase 2 transcript made by ContributePhase2 for circuit cs on
+// the ceremony transcript at ptauPath and returns a prover with the keys it yields
+func ProverFromPhase2(cs constraint.ConstraintSystem, ptauPath string, transcript []byte) (*Prover, error) {
+	phase1, initial, evals, err := initPhase2(cs, ptauPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var contributed mpcsetup.Phase2
+	if _, err := contributed.ReadFrom(bytes.NewReader(transcript)); err != nil {
+		return nil, fmt.Errorf("failed to decode phase 2 transcript: %v", err)
+	}
+	if len(contributed.Parameters.G1.L) != len(initial.Parameters.G1.L) || len(contributed.Parameters.G1.Z) != len(initial.Parameters.G1.Z) {
+		return nil, fmt.Errorf("phase 2 transcript is for another circuit")
+	}
+	if err := verifyPhase2(initial, &contributed); err != nil {
+		return nil, err
+	}
+
+	pk, vk := mpcsetup.ExtractKeys(phase1, &contributed, evals, cs.GetNbConstraints())
+	return &Prover{
+		ProvingKey:   &pk,
+		VerifyingKey: &vk,
+		R1cs:         cs,
+	}, nil
+}
+
+// verifyPhase2 checks that next is a valid contribution on top of prev
+func verifyPhase2(prev, next *mpcsetup.Phase2) (err error) {
+	// The pairing checks panic on points outside the subgroup
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid phase 2 contribution: %v", r)
+		}
+	}()
+	if err := mpcsetup.VerifyPhase2(prev, next); err != nil {
+		return fmt.Errorf("invalid phase 2 contribution: %v", err)
+	}
+	return nil
+}
+
+// initPhase2 returns the ceremony transcript at ptauPath cut down to the domain of circuit cs,
+// with the initial phase 2 and the circuit's evaluations on it
+func initPhase2(cs constraint.ConstraintSystem, ptauPath string) (*mpcsetup.Phase1, *mpcsetup.Phase2, *mpcsetup.Phase2Evaluations, error) {
+	r1cs, ok := cs.(*csbn254.R1CS)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("phase 2 needs a BN254 R1CS")
+	}
+
+	data, err := os.ReadFile(ptauPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read ptau file: %v", err)
+	}
+	var phase1 mpcsetup.Phase1
+	if _, err := phase1.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to decode ptau transcript: %v", err)
+	}
+
+	// The keys are computed over the circuit's own evaluation domain, the powers beyond it
+	// are not needed
+	size := int(fft.NewDomain(uint64(cs.GetNbConstraints())).Cardinality)
+	if len(phase1.Parameters.G1.AlphaTau) < size {
+		return nil, nil, nil, fmt.Errorf("circuit needs a ceremony over %d constraints, the transcript has %d", size, len(phase1.Parameters.G1.AlphaTau))
+	}
+	phase1.Parameters.G1.Tau = phase1.Parameters.G1.Tau[:2*size-1]
+	phase1.Parameters.G1.AlphaTau = phase1.Parameters.G1.AlphaTau[:size]
+	phase1.Parameters.G1.BetaTau = phase1.Parameters.G1.BetaTau[:size]
+	phase1.Parameters.G2.Tau = phase1.Parameters.G2.Tau[:size]
+
+	phase2, evals := mpcsetup.InitPhase2(r1cs, &phase1)
+
+	// InitPhase2 signs the initial δ with a random key, which is dropped so that every node
+	// derives the same initial transcript and the contribution's proof binds to it
+	phase2.PublicKey = mpcsetup.PublicKey{}
+	phase2.Hash = phase2Hash(&phase2)
+
+	return &phase1, &phase2, &evals, nil
+}
+
+// phase2Hash is the transcript hash a contribution is bound to
+func phase2Hash(phase2 *mpcsetup.Phase2) []byte {
+	// WriteTo appends the stored hash, which must not be part of its own input
+	stored := phase2.Hash
+	phase2.Hash = nil
+	h := sha256.New()
+	phase2.WriteTo(h)
+	phase2.Hash = stored
+	return h.Sum(nil)
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProverFromPhase2(t *testing.T) {
+	// A ceremony larger than the circuit needs, cut down to its domain
+	phase1 := mpcsetup.InitPhase1(11)
+	phase1.Contribute()
+	ptauPath := filepath.Join(t.TempDir(), "final.ptau")
+	file, err := os.Create(ptauPath)
+	require.NoError(t, err)
+	_, err = phase1.WriteTo(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	cs, err := CompileBatchCircuit(1)
+	require.NoError(t, err)
+
+	transcript, err := ContributePhase2(cs, ptauPath)
+	require.NoError(t, err)
+
+	// Every node derives the same keys from the transcript
+	prover, err := ProverFromPhase2(cs, ptauPath, transcript)
+	require.NoError(t, err)
+	other, err := ProverFromPhase2(cs, ptauPath, transcript)
+	require.NoError(t, err)
+	fingerprint, err := prover.Fingerprint()
+	require.NoError(t, err)
+	otherFingerprint, err := other.Fingerprint()
+	require.NoError(t, err)
+	require.Equal(t, fingerprint, otherFingerprint)
+
+	assignment, err := BatchCommitmentAssignment(1, [32]byte{1}, [32]byte{2}, [][32]byte{{3}})
+	require.NoError(t, err)
+	proof, publicInputs, err := prover.ProveSerialized(assignment)
+	require.NoError(t, err)
+	ok, err := other.VerifyProof(proof, publicInputs)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A transcript not built on this ceremony's phase 2 is refused
+	tampered := append([]byte{}, transcript...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = ProverFromPhase2(cs, ptauPath, tampered)
+	require.Error(t, err)
+
+	_, err = ProverFromPhase2(cs, ptauPath, transcript[:len(transcript)/2])
+	require.Error(t, err)
+}
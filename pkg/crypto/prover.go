@@ -6,7 +6,10 @@ import (
 	"math/big"
 
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
@@ -19,6 +22,7 @@ type Prover struct {
 	ProvingKey   groth16.ProvingKey
 	VerifyingKey groth16.VerifyingKey
 	R1cs         constraint.ConstraintSystem
+	Options      ProvingOptions // Parallelism and device of every proof
 }
 
 // NewProver creates a new prover with the necessary keys
@@ -97,7 +101,7 @@ func (p *Prover) GenerateProof(w *TransactionCircuit) (groth16.Proof, witness.Wi
 	}
 
 	// Generate proof
-	proof, err := groth16.Prove(p.R1cs, p.ProvingKey, witness)
+	proof, err := groth16.Prove(p.R1cs, p.ProvingKey, witness, p.Options.proverOptions()...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate proof: %v", err)
 	}
@@ -112,6 +116,12 @@ func (p *Prover) GenerateProof(w *TransactionCircuit) (groth16.Proof, witness.Wi
 }
 
 func (p *Prover) GenerateProofSerialized(w *TransactionCircuit) ([]byte, []byte, error) {
+	return p.ProveSerialized(w)
+}
+
+// ProveSerialized generates a proof for an assignment of the prover's circuit and returns
+// the serialized proof and public witness
+func (p *Prover) ProveSerialized(w frontend.Circuit) ([]byte, []byte, error) {
 	// Create witness
 	witness, err := frontend.NewWitness(w, ecc.BN254.ScalarField())
 	if err != nil {
@@ -119,7 +129,7 @@ func (p *Prover) GenerateProofSerialized(w *TransactionCircuit) ([]byte, []byte,
 	}
 
 	// Generate proof
-	proof, err := groth16.Prove(p.R1cs, p.ProvingKey, witness)
+	proof, err := groth16.Prove(p.R1cs, p.ProvingKey, witness, p.Options.proverOptions()...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate proof: %v", err)
 	}
@@ -167,21 +177,35 @@ func (p *Prover) SerializePublicWitness(publicWitness witness.Witness) ([]byte,
 	return publicWitnessBuf.Bytes()[12:], nil
 }
 
-// VerifyProof verifies a proof against the given witness
+// VerifyProof verifies a proof and public witness as serialized by SerializeProof and
+// SerializePublicWitness
 func (p *Prover) VerifyProof(proofBytes, publicWitnessBytes []byte) (bool, error) {
-	// Create public witness
+	// The public witness is serialized without its header, one field element per input
+	if len(publicWitnessBytes)%fr.Bytes != 0 {
+		return false, fmt.Errorf("public witness of %d bytes is not a list of field elements", len(publicWitnessBytes))
+	}
+	nbPublic := len(publicWitnessBytes) / fr.Bytes
+	values := make(chan any, nbPublic)
+	for i := 0; i < nbPublic; i++ {
+		values <- new(big.Int).SetBytes(publicWitnessBytes[i*fr.Bytes : (i+1)*fr.Bytes])
+	}
+	close(values)
+
 	publicWitness, err := witness.New(ecc.BN254.ScalarField())
 	if err != nil {
 		return false, fmt.Errorf("failed to create witness: %v", err)
 	}
+	if err := publicWitness.Fill(nbPublic, 0, values); err != nil {
+		return false, fmt.Errorf("failed to read public witness: %v", err)
+	}
 
-	publicWitness.ReadFrom(bytes.NewReader(publicWitnessBytes))
-
-	// Deserialize the proof
-	proof := groth16.NewProof(ecc.BN254)
-	_, err = proof.ReadFrom(bytes.NewReader(proofBytes))
-	if err != nil {
-		return false, fmt.Errorf("failed to deserialize proof: %v", err)
+	// The proof is serialized as its points Ar, Bs and Krs, without the empty commitments
+	proof := new(groth16_bn254.Proof)
+	dec := bn254.NewDecoder(bytes.NewReader(proofBytes))
+	for _, point := range []interface{}{&proof.Ar, &proof.Bs, &proof.Krs} {
+		if err := dec.Decode(point); err != nil {
+			return false, fmt.Errorf("failed to deserialize proof: %v", err)
+		}
 	}
 
 	// Verify the proof
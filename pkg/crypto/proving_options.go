@@ -0,0 +1,48 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/backend"
+	icicle_bn254 "github.com/consensys/gnark/backend/groth16/bn254/icicle"
+	"github.com/consensys/gnark/constraint/solver"
+)
+
+// ProvingOptions control how many cores and which device a prover uses
+type ProvingOptions struct {
+	// Goroutines solving the witness of one proof, 0 uses every core. The MSMs of the
+	// prover always split their work by core, bound them with GOMAXPROCS.
+	Threads int
+
+	// Prove on the GPU through ICICLE, which requires a binary built with the icicle tag.
+	// Keys set up or loaded by such a binary are copied to the device on first use.
+	GPU bool
+}
+
+// GPUAvailable reports whether the binary was built with the icicle tag
+func GPUAvailable() bool {
+	return icicle_bn254.HasIcicle
+}
+
+// Validate checks the options can be met by this binary
+func (o ProvingOptions) Validate() error {
+	if o.Threads < 0 {
+		return fmt.Errorf("proving threads must not be negative")
+	}
+	if o.GPU && !GPUAvailable() {
+		return fmt.Errorf("GPU proving requires a binary built with -tags icicle")
+	}
+	return nil
+}
+
+// proverOptions returns the gnark options applying o
+func (o ProvingOptions) proverOptions() []backend.ProverOption {
+	var opts []backend.ProverOption
+	if o.Threads > 0 {
+		opts = append(opts, backend.WithSolverOptions(solver.WithNbTasks(o.Threads)))
+	}
+	if o.GPU {
+		opts = append(opts, backend.WithIcicleAcceleration())
+	}
+	return opts
+}
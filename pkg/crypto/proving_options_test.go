@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvingOptions(t *testing.T) {
+	require.NoError(t, ProvingOptions{}.Validate())
+	require.Error(t, ProvingOptions{Threads: -1}.Validate())
+	if GPUAvailable() {
+		require.NoError(t, ProvingOptions{GPU: true}.Validate())
+	} else {
+		require.Error(t, ProvingOptions{GPU: true}.Validate())
+	}
+
+	// A prover limited to one thread makes the same public inputs
+	prover, err := NewBatchProver(1)
+	require.NoError(t, err)
+	assignment, err := BatchCommitmentAssignment(1, [32]byte{1}, [32]byte{2}, nil)
+	require.NoError(t, err)
+	_, inputs, err := prover.ProveSerialized(assignment)
+	require.NoError(t, err)
+
+	prover.Options = ProvingOptions{Threads: 1}
+	proof, single, err := prover.ProveSerialized(assignment)
+	require.NoError(t, err)
+	require.Len(t, proof, 256)
+	require.Equal(t, inputs, single)
+}
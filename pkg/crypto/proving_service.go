@@ -0,0 +1,193 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// ErrAlreadyProving is returned when a batch is submitted while its proof is in progress
+var ErrAlreadyProving = errors.New("batch is already being proven")
+
+// ProofStage is how far the proof of a batch has progressed
+type ProofStage string
+
+const (
+	ProofQueued  ProofStage = "queued"  // Waiting for a worker
+	ProofWitness ProofStage = "witness" // Building the witness
+	ProofProving ProofStage = "proving" // Running the prover
+)
+
+// ProofRequest asks for the proof of one batch. The witness is built by the worker that
+// proves it, so the caller only pays for queueing the request.
+type ProofRequest struct {
+	BatchNumber uint64
+	Prover      *Prover
+	Witness     func() (frontend.Circuit, error)
+	Done        func(ProofResult) // Called on the worker once the proof is made or failed
+}
+
+// ProofResult is the outcome of a ProofRequest
+type ProofResult struct {
+	BatchNumber  uint64
+	Proof        []byte
+	PublicInputs []byte
+	Err          error
+	WitnessTime  time.Duration
+	ProvingTime  time.Duration
+}
+
+// ProofProgress reports a batch whose proof is queued or in progress
+type ProofProgress struct {
+	BatchNumber uint64
+	Stage       ProofStage
+	Enqueued    time.Time
+	Started     time.Time // Of the current stage, zero while queued
+}
+
+// ProvingService proves batches on a bounded pool of workers. Requests wait in a queue of
+// fixed size, at most one per batch; submitting to a full queue waits for room, so batches
+// are never produced faster than they are proven.
+type ProvingService struct {
+	ctx   context.Context
+	queue chan *proofTask
+
+	mu    sync.Mutex
+	tasks map[uint64]*proofTask
+}
+
+// proofTask is a request and its progress
+type proofTask struct {
+	req      ProofRequest
+	stage    ProofStage
+	enqueued time.Time
+	started  time.Time
+}
+
+// NewProvingService starts workers proving requests from a queue of queueSize until ctx is done
+func NewProvingService(ctx context.Context, workers, queueSize int) *ProvingService {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = workers
+	}
+
+	s := &ProvingService{
+		ctx:   ctx,
+		queue: make(chan *proofTask, queueSize),
+		tasks: make(map[uint64]*proofTask),
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// Submit queues a request, waiting while the queue is full. It fails if ctx or the service
+// is done first, or the batch is already queued or being proven.
+func (s *ProvingService) Submit(ctx context.Context, req ProofRequest) error {
+	if req.Prover == nil || req.Witness == nil {
+		return fmt.Errorf("proof request for batch %d has no prover or witness", req.BatchNumber)
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	task := &proofTask{req: req, stage: ProofQueued, enqueued: time.Now()}
+	s.mu.Lock()
+	if _, ok := s.tasks[req.BatchNumber]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: batch %d", ErrAlreadyProving, req.BatchNumber)
+	}
+	s.tasks[req.BatchNumber] = task
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- task:
+		return nil
+	case <-ctx.Done():
+		s.forget(task)
+		return ctx.Err()
+	case <-s.ctx.Done():
+		s.forget(task)
+		return s.ctx.Err()
+	}
+}
+
+// Progress returns the batches queued or being proven, by batch number
+func (s *ProvingService) Progress() []ProofProgress {
+	s.mu.Lock()
+	progress := make([]ProofProgress, 0, len(s.tasks))
+	for n, task := range s.tasks {
+		progress = append(progress, ProofProgress{BatchNumber: n, Stage: task.stage, Enqueued: task.enqueued, Started: task.started})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(progress, func(i, j int) bool { return progress[i].BatchNumber < progress[j].BatchNumber })
+	return progress
+}
+
+// Pending returns the number of batches queued or being proven
+func (s *ProvingService) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tasks)
+}
+
+// work proves queued requests until the service is done
+func (s *ProvingService) work() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case task := <-s.queue:
+			result := s.prove(task)
+			s.forget(task)
+			if task.req.Done != nil {
+				task.req.Done(result)
+			}
+		}
+	}
+}
+
+// prove builds the witness of a task and proves it
+func (s *ProvingService) prove(task *proofTask) ProofResult {
+	result := ProofResult{BatchNumber: task.req.BatchNumber}
+
+	start := s.advance(task, ProofWitness)
+	assignment, err := task.req.Witness()
+	result.WitnessTime = time.Since(start)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to build witness: %v", err)
+		return result
+	}
+
+	start = s.advance(task, ProofProving)
+	result.Proof, result.PublicInputs, result.Err = task.req.Prover.ProveSerialized(assignment)
+	result.ProvingTime = time.Since(start)
+	return result
+}
+
+// advance moves a task to stage and returns when it started
+func (s *ProvingService) advance(task *proofTask, stage ProofStage) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.stage = stage
+	task.started = time.Now()
+	return task.started
+}
+
+// forget drops a finished or abandoned task
+func (s *ProvingService) forget(task *proofTask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tasks[task.req.BatchNumber] == task {
+		delete(s.tasks, task.req.BatchNumber)
+	}
+}
@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileBatchCircuitIsCached(t *testing.T) {
+	cs, err := CompileBatchCircuit(3)
+	require.NoError(t, err)
+	again, err := CompileBatchCircuit(3)
+	require.NoError(t, err)
+	require.True(t, cs == again, "capacity compiled twice")
+
+	other, err := CompileBatchCircuit(4)
+	require.NoError(t, err)
+	require.False(t, cs == other)
+}
+
+func TestProvingService(t *testing.T) {
+	prover, err := NewBatchProver(1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewProvingService(ctx, 2, 2)
+
+	results := make(chan ProofResult, 3)
+	release := make(chan struct{})
+	building := make(chan struct{})
+	request := func(batchNumber uint64, wait bool) ProofRequest {
+		return ProofRequest{
+			BatchNumber: batchNumber,
+			Prover:      prover,
+			Witness: func() (frontend.Circuit, error) {
+				if wait {
+					close(building)
+					<-release
+				}
+				return BatchCommitmentAssignment(1, [32]byte{byte(batchNumber)}, [32]byte{2}, nil)
+			},
+			Done: func(result ProofResult) { results <- result },
+		}
+	}
+
+	// A batch is proven once at a time, its progress shows the stage it is in
+	require.NoError(t, service.Submit(ctx, request(1, true)))
+	<-building
+	require.ErrorIs(t, service.Submit(ctx, request(1, false)), ErrAlreadyProving)
+	progress := service.Progress()
+	require.Len(t, progress, 1)
+	require.Equal(t, ProofWitness, progress[0].Stage)
+	require.False(t, progress[0].Started.IsZero())
+
+	require.NoError(t, service.Submit(ctx, request(2, false)))
+	result := <-results
+	require.Equal(t, uint64(2), result.BatchNumber)
+	require.NoError(t, result.Err)
+	require.Len(t, result.Proof, 256)
+	require.Len(t, result.PublicInputs, 32*len(BatchCommitmentSchemaV1.Inputs))
+
+	close(release)
+	result = <-results
+	require.Equal(t, uint64(1), result.BatchNumber)
+	require.NoError(t, result.Err)
+	require.Eventually(t, func() bool { return service.Pending() == 0 }, time.Second, 10*time.Millisecond)
+
+	// A witness that cannot be built fails the proof
+	bad := request(3, false)
+	bad.Witness = func() (frontend.Circuit, error) {
+		return BatchCommitmentAssignment(1, [32]byte{}, [32]byte{}, make([][32]byte, 2))
+	}
+	require.NoError(t, service.Submit(ctx, bad))
+	require.Error(t, (<-results).Err)
+
+	// A stopped service accepts no requests
+	cancel()
+	require.Error(t, service.Submit(context.Background(), request(4, false)))
+}
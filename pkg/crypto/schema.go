@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// PublicInputSchema fixes the order and meaning of the public inputs of one version of a
+// circuit. Proofs, batches and the verifier deployment carry its hash so inputs are never
+// read in the wrong order.
+type PublicInputSchema struct {
+	Circuit string
+	Version uint32
+	Inputs  []string // Public inputs in witness order, named after the circuit fields
+}
+
+// BatchCommitmentSchemaV1 describes the public inputs of BatchCommitmentCircuit
+var BatchCommitmentSchemaV1 = &PublicInputSchema{
+	Circuit: "batch-commitment",
+	Version: 1,
+	Inputs:  []string{"OldRoot", "NewRoot", "Commitment"},
+}
+
+// ContractCallSchemaV1 describes the public inputs of ContractCallCircuit
+var ContractCallSchemaV1 = &PublicInputSchema{
+	Circuit: "contract-call",
+	Version: 1,
+	Inputs:  []string{"OldRoot", "NewRoot", "Commitment"},
+}
+
+// schemas holds every known schema by hash
+var schemas = map[[32]byte]*PublicInputSchema{
+	BatchCommitmentSchemaV1.Hash(): BatchCommitmentSchemaV1,
+	ContractCallSchemaV1.Hash():    ContractCallSchemaV1,
+}
+
+// LookupSchema returns the schema with the given hash
+func LookupSchema(hash [32]byte) (*PublicInputSchema, error) {
+	schema, ok := schemas[hash]
+	if !ok {
+		return nil, fmt.Errorf("unknown public input schema %x", hash)
+	}
+	return schema, nil
+}
+
+// Hash returns the identifier of the schema, the SHA256 of its circuit name, version and
+// input names
+func (s *PublicInputSchema) Hash() [32]byte {
+	h := sha256.New()
+	h.Write([]byte(s.Circuit))
+	h.Write([]byte{0})
+	var version [4]byte
+	binary.BigEndian.PutUint32(version[:], s.Version)
+	h.Write(version[:])
+	for _, name := range s.Inputs {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+	}
+
+	var hash [32]byte
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// String returns the circuit name and version
+func (s *PublicInputSchema) String() string {
+	return fmt.Sprintf("%s/v%d", s.Circuit, s.Version)
+}
+
+// Index returns the position of the named input, or -1 if the schema has no such input
+func (s *PublicInputSchema) Index(name string) int {
+	for i, input := range s.Inputs {
+		if input == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Split splits serialized public inputs of 32 bytes each into field elements, checking
+// that there is exactly one per input of the schema
+func (s *PublicInputSchema) Split(publicInputs []byte) ([]*big.Int, error) {
+	if len(publicInputs) != 32*len(s.Inputs) {
+		return nil, fmt.Errorf("invalid public inputs length for %s: got %d, want %d", s, len(publicInputs), 32*len(s.Inputs))
+	}
+
+	inputs := make([]*big.Int, len(s.Inputs))
+	for i := range inputs {
+		inputs[i] = new(big.Int).SetBytes(publicInputs[32*i : 32*(i+1)])
+	}
+	return inputs, nil
+}
+
+// CircuitPublicInputs returns the names of the public fields of a circuit in the order
+// gnark lays them out in the witness
+func CircuitPublicInputs(circuit frontend.Circuit) []string {
+	t := reflect.TypeOf(circuit)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("gnark")
+		if !strings.Contains(tag, ",public") {
+			continue
+		}
+		name := field.Name
+		if alias, _, _ := strings.Cut(tag, ","); alias != "" {
+			name = alias
+		}
+		names = append(names, name)
+	}
+	return names
+}
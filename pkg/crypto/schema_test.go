@@ -0,0 +1,38 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchCommitmentSchemaMatchesCircuit(t *testing.T) {
+	// Reordering or renaming the circuit's public fields must come with a new schema version
+	require.Equal(t, BatchCommitmentSchemaV1.Inputs, CircuitPublicInputs(NewBatchCommitmentCircuit(1)))
+
+	schema, err := LookupSchema(BatchCommitmentSchemaV1.Hash())
+	require.NoError(t, err)
+	require.Same(t, BatchCommitmentSchemaV1, schema)
+
+	_, err = LookupSchema([32]byte{1})
+	require.Error(t, err)
+
+	next := &PublicInputSchema{Circuit: "batch-commitment", Version: 2, Inputs: BatchCommitmentSchemaV1.Inputs}
+	require.NotEqual(t, BatchCommitmentSchemaV1.Hash(), next.Hash())
+}
+
+func TestPublicInputSchemaSplit(t *testing.T) {
+	publicInputs := make([]byte, 96)
+	publicInputs[31] = 1
+	publicInputs[63] = 2
+	publicInputs[95] = 3
+
+	inputs, err := BatchCommitmentSchemaV1.Split(publicInputs)
+	require.NoError(t, err)
+	require.Len(t, inputs, 3)
+	require.Equal(t, int64(2), inputs[BatchCommitmentSchemaV1.Index("NewRoot")].Int64())
+	require.Equal(t, -1, BatchCommitmentSchemaV1.Index("missing"))
+
+	_, err = BatchCommitmentSchemaV1.Split(publicInputs[:64])
+	require.Error(t, err)
+}
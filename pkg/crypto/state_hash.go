@@ -0,0 +1,36 @@
+package crypto
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// wordHalfBits is the size of the halves each 32-byte node is absorbed as
+const wordHalfBits = 128
+
+// StateHash constrains the node hash of the MiMC state tree (state.MiMCHash) over two
+// nodes that are themselves hashes, i.e. field elements. Each node is split into its
+// 128-bit halves as the native hash absorbs it.
+func StateHash(api frontend.API, left, right frontend.Variable) (frontend.Variable, error) {
+	leftHi, leftLo := splitNode(api, left)
+	rightHi, rightLo := splitNode(api, right)
+	return StateHashWords(api, leftHi, leftLo, rightHi, rightLo)
+}
+
+// StateHashWords constrains the node hash of the MiMC state tree over two 32-byte words
+// given as their 128-bit halves, high half first. Words that are not field elements,
+// such as storage values and code hashes, are hashed this way.
+func StateHashWords(api frontend.API, leftHi, leftLo, rightHi, rightLo frontend.Variable) (frontend.Variable, error) {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(leftHi, leftLo, rightHi, rightLo)
+	return h.Sum(), nil
+}
+
+// splitNode decomposes a field element into its high and low 128-bit halves
+func splitNode(api frontend.API, v frontend.Variable) (frontend.Variable, frontend.Variable) {
+	bits := api.ToBinary(v, 2*wordHalfBits-2)
+	return api.FromBinary(bits[wordHalfBits:]...), api.FromBinary(bits[:wordHalfBits]...)
+}
@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/state"
+)
+
+// stateHashCircuit hashes two child nodes and a node with a raw word like a storage leaf
+type stateHashCircuit struct {
+	Left, Right    frontend.Variable
+	WordHi, WordLo frontend.Variable
+	Node, Leaf     frontend.Variable `gnark:",public"`
+}
+
+func (c *stateHashCircuit) Define(api frontend.API) error {
+	node, err := StateHash(api, c.Left, c.Right)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(node, c.Node)
+
+	leaf, err := StateHashWords(api, 0, 0, c.WordHi, c.WordLo)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(leaf, c.Leaf)
+	return nil
+}
+
+func TestStateHashMatchesNative(t *testing.T) {
+	left := state.MiMCHash([32]byte{1}, [32]byte{2})
+	right := state.MiMCHash([32]byte{3}, [32]byte{})
+	node := state.MiMCHash(left, right)
+
+	// A word above the field modulus is hashed without reduction
+	var word [32]byte
+	for i := range word {
+		word[i] = 0xff
+	}
+	leaf := state.MiMCHash([32]byte{}, word)
+	require.NotEqual(t, leaf, state.MiMCHash([32]byte{}, [32]byte{}))
+
+	assignment := &stateHashCircuit{
+		Left:   new(big.Int).SetBytes(left[:]),
+		Right:  new(big.Int).SetBytes(right[:]),
+		WordHi: new(big.Int).SetBytes(word[:16]),
+		WordLo: new(big.Int).SetBytes(word[16:]),
+		Node:   new(big.Int).SetBytes(node[:]),
+		Leaf:   new(big.Int).SetBytes(leaf[:]),
+	}
+	require.NoError(t, test.IsSolved(&stateHashCircuit{}, assignment, ecc.BN254.ScalarField()))
+
+	assignment.Node = new(big.Int).SetBytes(left[:])
+	require.Error(t, test.IsSolved(&stateHashCircuit{}, assignment, ecc.BN254.ScalarField()))
+}
@@ -0,0 +1,359 @@
+package devnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/p2p"
+	"zkrollup/pkg/rpc"
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+	rollupTypes "zkrollup/pkg/types"
+)
+
+// Options configures a devnet, zero values select the defaults
+type Options struct {
+	Nodes  int          // Sequencers to run, 3 by default
+	Config *core.Config // Settings every node starts from, DevConfig when nil. Ports, peers and L1 settings are overwritten.
+	L1     L1Options
+}
+
+// Node is one sequencer of a devnet with its RPC server
+type Node struct {
+	Sequencer *sequencer.Sequencer
+	Config    *core.Config
+	PeerID    peer.ID
+	P2PAddr   string // Multiaddr other nodes dial, including the peer ID
+	RPCURL    string
+
+	rpc *rpc.Server
+}
+
+// Devnet runs several sequencers validating each other's batches against a simulated L1.
+// Every node funds the same deterministic dev accounts at genesis, the validator set is
+// pinned to the devnet's nodes and each node dials the ones started before it. The first
+// node submits batches to L1.
+type Devnet struct {
+	L1       *L1
+	Nodes    []*Node
+	Accounts []sequencer.DevAccount
+
+	dir string // Node identities and state
+}
+
+// New starts a devnet and waits until every node is connected to all others, gossips with
+// them and accepts transactions
+func New(opts Options) (*Devnet, error) {
+	if opts.Nodes == 0 {
+		opts.Nodes = 3
+	}
+	template := opts.Config
+	if template == nil {
+		template = core.DevConfig()
+	}
+	opts.L1.FinalityDepth = template.L1FinalityDepth
+
+	dir, err := os.MkdirTemp("", "zkrollup-devnet-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create devnet directory: %v", err)
+	}
+	chain, err := StartL1(opts.L1)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	d := &Devnet{L1: chain, dir: dir}
+
+	if err := d.configureNodes(template, opts.Nodes); err != nil {
+		d.Close()
+		return nil, err
+	}
+	for i, node := range d.Nodes {
+		if err := d.startNode(i, node); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+	if template.DevMode {
+		d.Accounts = sequencer.DevAccounts(template.DevAccounts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err = waitFor(ctx, func() bool {
+		for _, node := range d.Nodes {
+			// Messages gossiped before a peer's subscriptions arrive do not reach it
+			if !node.Sequencer.IsSynced() || node.Sequencer.GossipPeerCount() < len(d.Nodes)-1 {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		d.Close()
+		return nil, fmt.Errorf("devnet did not connect: %v", err)
+	}
+	return d, nil
+}
+
+// configureNodes gives every node its own identity, ports and state directory, and pins
+// the validator set to their peer IDs
+func (d *Devnet) configureNodes(template *core.Config, count int) error {
+	validators := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		nodeDir := filepath.Join(d.dir, fmt.Sprintf("node-%d", i))
+		if err := os.MkdirAll(nodeDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create node directory: %v", err)
+		}
+
+		identityFile := filepath.Join(nodeDir, "identity.key")
+		identity, err := p2p.LoadOrCreateIdentity(identityFile, "")
+		if err != nil {
+			return fmt.Errorf("failed to create node identity: %v", err)
+		}
+		peerID, err := peer.IDFromPrivateKey(identity)
+		if err != nil {
+			return fmt.Errorf("failed to derive peer ID: %v", err)
+		}
+		seqPort, err := FreePort()
+		if err != nil {
+			return err
+		}
+		rpcPort, err := FreePort()
+		if err != nil {
+			return err
+		}
+
+		config := *template
+		config.IdentityFile = identityFile
+		config.IdentityPassphrase = ""
+		config.StateDBPath = filepath.Join(nodeDir, "state")
+		config.SequencerPort = seqPort
+		config.RPCPort = rpcPort
+		config.IsLeader = i == 0
+		config.ContractAddress = d.L1.Rollup.Hex()
+		config.L1Enabled = false // The first node is handed the L1 client directly
+		config.BootstrapPeers = nil
+		for _, prev := range d.Nodes {
+			config.BootstrapPeers = append(config.BootstrapPeers, prev.P2PAddr)
+		}
+
+		d.Nodes = append(d.Nodes, &Node{
+			Config:  &config,
+			PeerID:  peerID,
+			P2PAddr: fmt.Sprintf("/ip4/127.0.0.1/tcp/%d/p2p/%s", seqPort, peerID),
+			RPCURL:  fmt.Sprintf("http://127.0.0.1:%d", rpcPort),
+		})
+		validators = append(validators, peerID.String())
+	}
+
+	for _, node := range d.Nodes {
+		node.Config.Validators = validators
+	}
+	return nil
+}
+
+// startNode starts the sequencer of node i and its RPC server
+func (d *Devnet) startNode(i int, node *Node) error {
+	seq, err := sequencer.NewSequencer(node.Config, node.Config.SequencerPort, node.Config.BootstrapPeers, node.Config.IsLeader)
+	if err != nil {
+		return fmt.Errorf("failed to create sequencer %d: %v", i, err)
+	}
+	if i == 0 {
+		seq.SetL1Client(d.L1.Client)
+	}
+	if err := seq.Start(); err != nil {
+		seq.Stop()
+		return fmt.Errorf("failed to start sequencer %d: %v", i, err)
+	}
+	node.Sequencer = seq
+
+	node.rpc = rpc.NewServer(seq, node.Config.RPCPort)
+	if err := node.rpc.Start(); err != nil {
+		return fmt.Errorf("failed to start RPC server %d: %v", i, err)
+	}
+	return nil
+}
+
+// Close stops every node and the L1 and removes the devnet's files
+func (d *Devnet) Close() {
+	for i := len(d.Nodes) - 1; i >= 0; i-- {
+		node := d.Nodes[i]
+		if node.rpc != nil {
+			node.rpc.Stop()
+		}
+		if node.Sequencer != nil {
+			node.Sequencer.Stop()
+		}
+	}
+	d.L1.Close()
+	os.RemoveAll(d.dir)
+}
+
+// WaitForBatch waits until every node has finalized batches up to batchNumber
+func (d *Devnet) WaitForBatch(ctx context.Context, batchNumber uint64) error {
+	err := waitFor(ctx, func() bool {
+		for _, node := range d.Nodes {
+			if node.Sequencer.BatchNumber() < batchNumber {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("batch %d not finalized on every node: %v", batchNumber, err)
+	}
+	return nil
+}
+
+// CheckStateRoots compares the state roots every node holds for the batches all of them
+// finalized
+func (d *Devnet) CheckStateRoots() error {
+	head := d.Nodes[0].Sequencer.BatchNumber()
+	for _, node := range d.Nodes[1:] {
+		head = min(head, node.Sequencer.BatchNumber())
+	}
+
+	want := d.Nodes[0].Sequencer.GetBatches(0, head)
+	for i, node := range d.Nodes[1:] {
+		got := node.Sequencer.GetBatches(0, head)
+		if len(got) != len(want) {
+			return fmt.Errorf("node %d returned %d batches, node 0 %d", i+1, len(got), len(want))
+		}
+		for j := range want {
+			if got[j].StateRoot != want[j].StateRoot {
+				return fmt.Errorf("batch %d: node %d state root %x, node 0 %x", want[j].BatchNumber, i+1, got[j].StateRoot, want[j].StateRoot)
+			}
+		}
+	}
+	return nil
+}
+
+// Transfer signs a transfer and sends it through the node's RPC server, returning its hash
+func (n *Node) Transfer(from sequencer.DevAccount, to rollupTypes.Address, amount *big.Int, nonce uint64) (common.Hash, error) {
+	return Transfer(n.RPCURL, n.Config.L2ChainID(), n.Sequencer.SigningContract(), from, to, amount, nonce)
+}
+
+// Transfer signs a transfer for the rollup with chainID and contract rollup and sends it
+// to the node at url, returning its hash
+func Transfer(url string, chainID uint64, rollup rollupTypes.Address, from sequencer.DevAccount, to rollupTypes.Address, amount *big.Int, nonce uint64) (common.Hash, error) {
+	return Send(url, chainID, rollup, from, state.Transaction{
+		Type:   state.TxTypeTransfer,
+		To:     to,
+		Amount: amount,
+		Nonce:  nonce,
+	})
+}
+
+// Send signs tx as from for the rollup with chainID and contract rollup and sends it to the
+// node at url, returning its hash. The sender and chain ID of tx are set here.
+func Send(url string, chainID uint64, rollup rollupTypes.Address, from sequencer.DevAccount, tx state.Transaction) (common.Hash, error) {
+	tx.From = from.Address
+	tx.ChainID = chainID
+	signature, err := state.SignTransaction(&tx, crypto.FromECDSA(from.PrivateKey), rollup)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tx.Signature = signature
+
+	encoded, err := rlp.EncodeToBytes(&tx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to encode transaction: %v", err)
+	}
+
+	var result struct {
+		TxHash string `json:"txHash"`
+	}
+	if err := Call(url, "rollup_sendRawTransaction", []string{hexutil.Encode(encoded)}, &result); err != nil {
+		return common.Hash{}, err
+	}
+	return common.HexToHash(result.TxHash), nil
+}
+
+// Call sends a JSON-RPC request to the node and decodes its result into result
+func (n *Node) Call(method string, params interface{}, result interface{}) error {
+	return Call(n.RPCURL, method, params, result)
+}
+
+// Call sends a JSON-RPC request to the node at url and decodes its result into result
+func Call(url, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return fmt.Errorf("failed to parse response: %v, body: %s", err, string(respBody))
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s failed: %d - %s", method, rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// waitFor polls done until it reports true or ctx expires
+func waitFor(ctx context.Context, done func() bool) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !done() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// FreePort returns a TCP port nothing is listening on
+func FreePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,143 @@
+package devnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+
+	"zkrollup/contracts/bindings"
+	"zkrollup/pkg/l1"
+)
+
+// L1ChainID is the chain ID of the simulated L1
+const L1ChainID = 1337
+
+// l1Funding is the balance of the operator account on the simulated L1, 1000 ether
+var l1Funding = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+
+// L1Options configures a simulated L1, zero values select the defaults
+type L1Options struct {
+	BlockTime     time.Duration // Interval between blocks, 100ms by default
+	FinalityDepth uint64        // Finality depth of the bound client
+
+	// CRS round settings of the deployed CRSManager
+	CRSRoundDuration   int64
+	CRSMaxParticipants int64
+}
+
+// L1 is an in-process L1 chain with the rollup contract and the CRS manager deployed,
+// mining a block every BlockTime until it is closed
+type L1 struct {
+	Backend    *simulated.Backend
+	Client     *l1.Client // Bound to the rollup contract with the operator key
+	Rollup     common.Address
+	CRSManager common.Address
+	Operator   *ecdsa.PrivateKey // Funded account that deployed the contracts and submits batches
+
+	stop   chan struct{}
+	mining sync.WaitGroup
+}
+
+// StartL1 starts a simulated L1 and deploys the rollup contracts. Batches are accepted
+// unverified, the verifier contract is generated for a fixed key while nodes run their own
+// circuit setup.
+func StartL1(opts L1Options) (*L1, error) {
+	if opts.BlockTime == 0 {
+		opts.BlockTime = 100 * time.Millisecond
+	}
+	if opts.CRSRoundDuration == 0 {
+		opts.CRSRoundDuration = 3600
+	}
+	if opts.CRSMaxParticipants == 0 {
+		opts.CRSMaxParticipants = 16
+	}
+
+	operator, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate operator key: %v", err)
+	}
+	chain := &L1{
+		Backend: simulated.NewBackend(types.GenesisAlloc{
+			crypto.PubkeyToAddress(operator.PublicKey): {Balance: l1Funding},
+		}),
+		Operator: operator,
+		stop:     make(chan struct{}),
+	}
+	if err := chain.deployContracts(opts); err != nil {
+		chain.Backend.Close()
+		return nil, err
+	}
+
+	chain.mining.Add(1)
+	go chain.mine(opts.BlockTime)
+	return chain, nil
+}
+
+// deployContracts deploys the rollup contract and the CRS manager and binds the client
+func (c *L1) deployContracts(opts L1Options) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%x", crypto.FromECDSA(c.Operator))
+
+	deployer, err := l1.NewClientWithBackend(c.Backend.Client(), &l1.Config{ChainID: L1ChainID, PrivateKey: key})
+	if err != nil {
+		return fmt.Errorf("failed to create L1 client: %v", err)
+	}
+	if c.Rollup, err = deployer.DeployContract(ctx, common.Address{}, [32]byte{}); err != nil {
+		return err
+	}
+	c.Backend.Commit()
+
+	auth, err := bind.NewKeyedTransactorWithChainID(c.Operator, big.NewInt(L1ChainID))
+	if err != nil {
+		return fmt.Errorf("failed to create transactor: %v", err)
+	}
+	c.CRSManager, _, _, err = bindings.DeployCRSManager(auth, c.Backend.Client(), big.NewInt(opts.CRSRoundDuration), big.NewInt(opts.CRSMaxParticipants))
+	if err != nil {
+		return fmt.Errorf("failed to deploy CRS manager: %v", err)
+	}
+	c.Backend.Commit()
+
+	c.Client, err = l1.NewClientWithBackend(c.Backend.Client(), &l1.Config{
+		ChainID:         L1ChainID,
+		ContractAddress: c.Rollup.Hex(),
+		PrivateKey:      key,
+		FinalityDepth:   opts.FinalityDepth,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to bind rollup contract: %v", err)
+	}
+	return nil
+}
+
+// mine seals a block every interval until the chain closes
+func (c *L1) mine(interval time.Duration) {
+	defer c.mining.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.Backend.Commit()
+		}
+	}
+}
+
+// Close stops mining and shuts the chain down
+func (c *L1) Close() {
+	close(c.stop)
+	c.mining.Wait()
+	c.Backend.Close()
+}
@@ -0,0 +1,211 @@
+package e2e
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/devnet"
+	"zkrollup/pkg/l1"
+	"zkrollup/pkg/rpc"
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+	rollupTypes "zkrollup/pkg/types"
+)
+
+// L1ChainID is the chain ID of the simulated L1
+const L1ChainID = devnet.L1ChainID
+
+// Options configures a harness, zero values select the defaults
+type Options struct {
+	Config    *core.Config  // Node settings, DevConfig when nil. L1 settings are overwritten.
+	BlockTime time.Duration // Interval between simulated L1 blocks, 100ms by default
+
+	// CRS round settings of the deployed CRSManager
+	CRSRoundDuration   int64
+	CRSMaxParticipants int64
+}
+
+// Harness runs a single rollup node against a simulated L1 chain with the rollup contracts
+// deployed, the node's RPC server listening and L1 blocks mined in the background. It is
+// meant for end-to-end tests, here and in projects building on the rollup.
+type Harness struct {
+	Backend    *simulated.Backend
+	L1         *l1.Client // Bound to the rollup contract with the operator key
+	Sequencer  *sequencer.Sequencer
+	RPCURL     string
+	Rollup     common.Address
+	CRSManager common.Address
+	Operator   *ecdsa.PrivateKey // Funded L1 account submitting batches
+	Accounts   []sequencer.DevAccount
+
+	config *core.Config
+	rpc    *rpc.Server
+	chain  *devnet.L1
+}
+
+// New starts a node on a fresh simulated L1 and waits until it accepts transactions
+func New(opts Options) (*Harness, error) {
+	config := opts.Config
+	if config == nil {
+		config = core.DevConfig()
+	}
+
+	chain, err := devnet.StartL1(devnet.L1Options{
+		BlockTime:          opts.BlockTime,
+		FinalityDepth:      config.L1FinalityDepth,
+		CRSRoundDuration:   opts.CRSRoundDuration,
+		CRSMaxParticipants: opts.CRSMaxParticipants,
+	})
+	if err != nil {
+		return nil, err
+	}
+	h := &Harness{
+		Backend:    chain.Backend,
+		L1:         chain.Client,
+		Rollup:     chain.Rollup,
+		CRSManager: chain.CRSManager,
+		Operator:   chain.Operator,
+		config:     config,
+		chain:      chain,
+	}
+	if err := h.startNode(); err != nil {
+		chain.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := h.waitFor(ctx, h.Sequencer.IsSynced); err != nil {
+		h.Close()
+		return nil, fmt.Errorf("node did not sync: %v", err)
+	}
+	return h, nil
+}
+
+// startNode starts the sequencer and its RPC server on free ports
+func (h *Harness) startNode() error {
+	seqPort, err := devnet.FreePort()
+	if err != nil {
+		return err
+	}
+	rpcPort, err := devnet.FreePort()
+	if err != nil {
+		return err
+	}
+
+	h.config.SequencerPort = seqPort
+	h.config.RPCPort = rpcPort
+	h.config.IsLeader = true
+	h.config.BootstrapPeers = nil
+	h.config.ContractAddress = h.Rollup.Hex()
+	h.config.L1Enabled = false // The harness hands the node its L1 client itself
+
+	h.Sequencer, err = sequencer.NewSequencer(h.config, seqPort, nil, true)
+	if err != nil {
+		return fmt.Errorf("failed to create sequencer: %v", err)
+	}
+	h.Sequencer.SetL1Client(h.L1)
+	if err := h.Sequencer.Start(); err != nil {
+		h.Sequencer.Stop()
+		return fmt.Errorf("failed to start sequencer: %v", err)
+	}
+	if h.config.DevMode {
+		h.Accounts = sequencer.DevAccounts(h.config.DevAccounts)
+	}
+
+	h.rpc = rpc.NewServer(h.Sequencer, rpcPort)
+	if err := h.rpc.Start(); err != nil {
+		h.Sequencer.Stop()
+		return fmt.Errorf("failed to start RPC server: %v", err)
+	}
+	h.RPCURL = fmt.Sprintf("http://127.0.0.1:%d", rpcPort)
+	return nil
+}
+
+// Close stops the node and the simulated L1
+func (h *Harness) Close() {
+	h.rpc.Stop()
+	h.Sequencer.Stop()
+	h.chain.Close()
+}
+
+// Transfer signs a transfer and sends it through the node's RPC server, returning its hash
+func (h *Harness) Transfer(from sequencer.DevAccount, to rollupTypes.Address, amount *big.Int, nonce uint64) (common.Hash, error) {
+	return devnet.Transfer(h.RPCURL, h.config.L2ChainID(), h.Sequencer.SigningContract(), from, to, amount, nonce)
+}
+
+// Send signs tx as from and sends it through the node's RPC server, returning its hash
+func (h *Harness) Send(from sequencer.DevAccount, tx state.Transaction) (common.Hash, error) {
+	return devnet.Send(h.RPCURL, h.config.L2ChainID(), h.Sequencer.SigningContract(), from, tx)
+}
+
+// Call sends a JSON-RPC request to the node and decodes its result into result
+func (h *Harness) Call(method string, params interface{}, result interface{}) error {
+	return devnet.Call(h.RPCURL, method, params, result)
+}
+
+// WaitForL1Batch waits until the contract accepted the batch with the given local number
+func (h *Harness) WaitForL1Batch(ctx context.Context, batchNumber uint64) (*l1.BatchStatus, error) {
+	var status *l1.BatchStatus
+	err := h.waitFor(ctx, func() bool {
+		s, err := h.L1.GetBatchStatus(ctx, batchNumber)
+		if err != nil || s.State == l1.BatchUnknown {
+			return false
+		}
+		status = s
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("batch %d not accepted on L1: %v", batchNumber, err)
+	}
+	return status, nil
+}
+
+// CheckStateRoots compares the state root of every finalized batch with transactions
+// against the root the contract stored for it. Batches without transactions are not
+// posted to L1 unless heartbeats are.
+func (h *Harness) CheckStateRoots(ctx context.Context) error {
+	batches := h.Sequencer.GetBatches(0, h.Sequencer.BatchNumber())
+	checked := 0
+	for i := range batches {
+		batch := &batches[i]
+		if len(batch.Transactions) == 0 && !h.config.HeartbeatPostToL1 {
+			continue
+		}
+
+		status, err := h.WaitForL1Batch(ctx, batch.BatchNumber)
+		if err != nil {
+			return err
+		}
+		if status.StateRoot != common.Hash(batch.StateRoot) {
+			return fmt.Errorf("batch %d: L1 state root %s, L2 state root %x", batch.BatchNumber, status.StateRoot.Hex(), batch.StateRoot)
+		}
+		checked++
+	}
+	if checked == 0 {
+		return fmt.Errorf("no batches were posted to L1")
+	}
+	return nil
+}
+
+// waitFor polls done until it reports true or ctx expires
+func (h *Harness) waitFor(ctx context.Context, done func() bool) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !done() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package evm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CreateAddress returns the address of the contract deployed by caller with the given
+// account nonce, keccak256(rlp([caller, nonce]))[12:] as on Ethereum
+func CreateAddress(caller common.Address, nonce uint64) common.Address {
+	return crypto.CreateAddress(caller, nonce)
+}
+
+// Create2Address returns the address of the contract deployed by caller from initCode with
+// the given salt, keccak256(0xff ++ caller ++ salt ++ keccak256(initCode))[12:] (EIP-1014).
+// It does not depend on the caller's nonce, so the address is known before deployment.
+func Create2Address(caller common.Address, salt [32]byte, initCode []byte) common.Address {
+	return crypto.CreateAddress2(caller, salt, crypto.Keccak256(initCode))
+}
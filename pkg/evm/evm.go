@@ -1,41 +1,135 @@
 package evm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/rs/zerolog/log"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+
+	"zkrollup/pkg/trace"
 )
 
+// ErrExecutionFailed wraps the error of code that ran and failed, by reverting, running out
+// of gas or an invalid instruction. Its state changes are reverted but the gas it used is not
+// returned.
+var ErrExecutionFailed = errors.New("execution failed")
+
 // EVMExecutor handles EVM execution in the ZK-Rollup
-type EVMExecutor struct {}
+type EVMExecutor struct {
+	limits      ExecutionLimits
+	chainConfig *params.ChainConfig // Every fork up to Prague is active from genesis
+}
 
 // NewEVMExecutor creates a new EVM executor
 func NewEVMExecutor() *EVMExecutor {
-	return &EVMExecutor{}
+	return &EVMExecutor{limits: DefaultExecutionLimits(), chainConfig: newChainConfig(nil)}
+}
+
+// NewEVMExecutorWithLimits creates a new EVM executor with custom resource limits
+func NewEVMExecutorWithLimits(limits ExecutionLimits) (*EVMExecutor, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid execution limits: %w", err)
+	}
+	return &EVMExecutor{limits: limits, chainConfig: newChainConfig(nil)}, nil
+}
+
+// newChainConfig returns the chain rules contracts run under, with the given chain ID
+// returned by the CHAINID opcode
+func newChainConfig(chainID *big.Int) *params.ChainConfig {
+	config := *params.AllDevChainProtocolChanges
+	if chainID != nil {
+		config.ChainID = new(big.Int).Set(chainID)
+	}
+	return &config
+}
+
+// SetChainID sets the chain ID returned by the CHAINID opcode
+func (e *EVMExecutor) SetChainID(chainID *big.Int) {
+	e.chainConfig = newChainConfig(chainID)
 }
 
-// StateDB interface for our simplified EVM to interact with the rollup state
+// Limits returns the resource limits applied to each call
+func (e *EVMExecutor) Limits() ExecutionLimits {
+	return e.limits
+}
+
+// StateDB is the state the EVM runs against. Besides the go-ethereum interface it
+// exposes the batch being executed and control over when changes reach the rollup state.
 type StateDB interface {
-	GetBalance(common.Address) *big.Int
-	SetBalance(common.Address, *big.Int)
-	GetNonce(common.Address) uint64
-	SetNonce(common.Address, uint64)
-	GetCode(common.Address) []byte
-	SetCode(common.Address, []byte)
-	GetState(common.Address, common.Hash) common.Hash
-	SetState(common.Address, common.Hash, common.Hash)
-	SubBalance(common.Address, *big.Int)
-	AddBalance(common.Address, *big.Int)
-	ApplyChanges() // Apply all pending changes to the rollup state
-}
-
-// ExecuteContract executes a smart contract call
-// This is a simplified implementation for the ZK-Rollup
+	vm.StateDB
+	BatchNumber() uint64 // Number of the batch being executed, returned by NUMBER
+	ApplyChanges()       // Apply all pending changes to the rollup state
+	DiscardChanges()     // Drop all pending changes, e.g. after a timed-out call
+}
+
+// newEVM creates an interpreter for a transaction sent by origin, and returns it with the
+// chain rules it runs under and the limiter enforcing the executor's limits on it
+func (e *EVMExecutor) newEVM(stateDB StateDB, origin common.Address, gas uint64) (*vm.EVM, params.Rules, *limiter) {
+	blockCtx := vm.BlockContext{
+		CanTransfer: canTransfer,
+		Transfer:    transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		GasLimit:    gas,
+		BlockNumber: new(big.Int).SetUint64(stateDB.BatchNumber()),
+		Difficulty:  new(big.Int),
+		BaseFee:     new(big.Int),
+		BlobBaseFee: new(big.Int),
+		Random:      &common.Hash{},
+	}
+	lim := &limiter{limits: e.limits}
+	vmenv := vm.NewEVM(blockCtx, stateDB, e.chainConfig, vm.Config{Tracer: lim.hooks()})
+	vmenv.SetPrecompiles(precompiles)
+	vmenv.SetTxContext(vm.TxContext{Origin: origin, GasPrice: new(big.Int)})
+	lim.vmenv = vmenv
+
+	rules := e.chainConfig.Rules(blockCtx.BlockNumber, true, blockCtx.Time)
+	return vmenv, rules, lim
+}
+
+// canTransfer reports whether addr holds at least amount
+func canTransfer(db vm.StateDB, addr common.Address, amount *uint256.Int) bool {
+	return db.GetBalance(addr).Cmp(amount) >= 0
+}
+
+// transfer moves amount from sender to recipient
+func transfer(db vm.StateDB, sender, recipient common.Address, amount *uint256.Int) {
+	db.SubBalance(sender, amount, tracing.BalanceChangeTransfer)
+	db.AddBalance(recipient, amount, tracing.BalanceChangeTransfer)
+}
+
+// toUint256 converts a call value, nil meaning zero
+func toUint256(value *big.Int) (*uint256.Int, error) {
+	if value == nil {
+		return new(uint256.Int), nil
+	}
+	v, overflow := uint256.FromBig(value)
+	if overflow || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid value %s", value.String())
+	}
+	return v, nil
+}
+
+// finish credits the gas refund to the remaining gas, capped at a fifth of the gas used
+// (EIP-3529), and ends the transaction
+func finish(stateDB StateDB, gas, remaining uint64) uint64 {
+	refund := (gas - remaining) / params.RefundQuotientEIP3529
+	if r := stateDB.GetRefund(); r < refund {
+		refund = r
+	}
+	stateDB.Finalise(true)
+	return remaining + refund
+}
+
+// ExecuteContract executes a smart contract call. The caller's nonce is incremented and
+// the pending changes are left in stateDB for the caller to apply.
 func (e *EVMExecutor) ExecuteContract(
+	ctx context.Context,
 	stateDB StateDB,
 	caller common.Address,
 	contract common.Address,
@@ -43,88 +137,124 @@ func (e *EVMExecutor) ExecuteContract(
 	gas uint64,
 	input []byte,
 ) ([]byte, uint64, error) {
-	// Check if the contract exists
-	code := stateDB.GetCode(contract)
-	if len(code) == 0 {
-		return nil, 0, errors.New("contract not found")
-	}
-
-	// Check if caller has sufficient balance
-	callerBalance := stateDB.GetBalance(caller)
-	if callerBalance.Cmp(value) < 0 {
-		return nil, 0, errors.New("insufficient balance")
+	amount, err := toUint256(value)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Handle zero values consistently as per memory requirements
-	transferAmount := new(big.Int).Set(value)
-	if transferAmount.Cmp(big.NewInt(0)) == 0 {
-		transferAmount = big.NewInt(0)
-	}
-
-	// Transfer value from caller to contract
-	stateDB.SubBalance(caller, transferAmount)
-	stateDB.AddBalance(contract, transferAmount)
-
-	// In a real implementation, we would execute the contract code here
-	// For now, we'll just return a simple response
-	returnData := []byte("contract executed")
+	vmenv, rules, lim := e.newEVM(stateDB, caller, gas)
+	res := e.runWithLimits(ctx, stateDB, vmenv, func() execResult {
+		// Check if the contract exists, precompiles have no code
+		if stateDB.GetCodeSize(contract) == 0 && !isPrecompile(contract) {
+			return execResult{err: errors.New("contract not found")}
+		}
 
-	// Apply state changes
-	stateDB.ApplyChanges()
+		stateDB.Prepare(rules, caller, vmenv.Context.Coinbase, &contract, precompileAddresses, nil)
+		stateDB.SetNonce(caller, stateDB.GetNonce(caller)+1, tracing.NonceChangeEoACall)
 
-	// Calculate gas used (simplified)
-	gasUsed := gas / 2 // Assume half the gas was used
-	remaining := gas - gasUsed
+		ret, remaining, err := vmenv.Call(caller, contract, input, gas, amount)
+		if lim.err != nil {
+			// The cancelled interpreter stopped without reverting, drop what it changed
+			stateDB.DiscardChanges()
+			return lim.failure()
+		}
+		if err != nil {
+			return execResult{ret: ret, remaining: remaining, err: fmt.Errorf("%w: %w", ErrExecutionFailed, err)}
+		}
+		return execResult{ret: ret, remaining: finish(stateDB, gas, remaining)}
+	})
+	if res.err != nil {
+		if errors.Is(res.err, ErrExecutionTimeout) {
+			trace.Logger(ctx).Warn().Str("caller", caller.Hex()).Str("contract", contract.Hex()).Dur("timeout", e.limits.Timeout).Msg("Contract call timed out")
+		}
+		return res.ret, res.remaining, res.err
+	}
 
-	log.Info().Str("caller", caller.Hex()).Str("contract", contract.Hex()).Msg("Contract executed")
-	return returnData, remaining, nil
+	trace.Logger(ctx).Info().Str("caller", caller.Hex()).Str("contract", contract.Hex()).Msg("Contract executed")
+	return res.ret, res.remaining, nil
 }
 
-// DeployContract deploys a new smart contract
-// This is a simplified implementation for the ZK-Rollup
+// DeployContract deploys a new smart contract by running its init code. The contract's
+// address is derived from the caller and its nonce like an Ethereum CREATE. The caller's
+// nonce is incremented and the pending changes are left in stateDB for the caller to apply.
 func (e *EVMExecutor) DeployContract(
+	ctx context.Context,
 	stateDB StateDB,
 	caller common.Address,
 	value *big.Int,
 	gas uint64,
 	code []byte,
 ) (common.Address, uint64, error) {
-	// Check if caller has sufficient balance
-	callerBalance := stateDB.GetBalance(caller)
-	if callerBalance.Cmp(value) < 0 {
-		return common.Address{}, 0, errors.New("insufficient balance")
-	}
+	return e.deploy(ctx, stateDB, caller, value, gas, code, nil)
+}
 
-	// Generate contract address (simplified)
-	// In Ethereum, this would be based on sender and nonce
-	nonce := stateDB.GetNonce(caller)
-	contractAddr := crypto.CreateAddress(caller, nonce)
+// DeployContract2 deploys a new smart contract at the address derived from the caller,
+// salt and init code like an Ethereum CREATE2, so the address does not depend on the
+// caller's nonce
+func (e *EVMExecutor) DeployContract2(
+	ctx context.Context,
+	stateDB StateDB,
+	caller common.Address,
+	value *big.Int,
+	gas uint64,
+	code []byte,
+	salt [32]byte,
+) (common.Address, uint64, error) {
+	return e.deploy(ctx, stateDB, caller, value, gas, code, &salt)
+}
 
-	// Handle zero values consistently as per memory requirements
-	transferAmount := new(big.Int).Set(value)
-	if transferAmount.Cmp(big.NewInt(0)) == 0 {
-		transferAmount = big.NewInt(0)
+// deploy runs init code with CREATE, or CREATE2 when a salt is given
+func (e *EVMExecutor) deploy(
+	ctx context.Context,
+	stateDB StateDB,
+	caller common.Address,
+	value *big.Int,
+	gas uint64,
+	code []byte,
+	salt *[32]byte,
+) (common.Address, uint64, error) {
+	if len(code) > e.limits.MaxCodeSize {
+		return common.Address{}, 0, ErrCodeSizeExceeded
+	}
+	amount, err := toUint256(value)
+	if err != nil {
+		return common.Address{}, 0, err
 	}
 
-	// Transfer value from caller to contract
-	stateDB.SubBalance(caller, transferAmount)
-	stateDB.AddBalance(contractAddr, transferAmount)
-
-	// Store contract code
-	stateDB.SetCode(contractAddr, code)
-
-	// Increment nonce
-	stateDB.SetNonce(caller, nonce+1)
-
-	// Apply state changes
-	stateDB.ApplyChanges()
+	vmenv, rules, lim := e.newEVM(stateDB, caller, gas)
+	res := e.runWithLimits(ctx, stateDB, vmenv, func() execResult {
+		stateDB.Prepare(rules, caller, vmenv.Context.Coinbase, nil, precompileAddresses, nil)
 
-	// Calculate gas used (simplified)
-	gasUsed := gas / 2 // Assume half the gas was used
-	remaining := gas - gasUsed
+		var (
+			contractAddr common.Address
+			remaining    uint64
+			err          error
+		)
+		if salt == nil {
+			_, contractAddr, remaining, err = vmenv.Create(caller, code, gas, amount)
+		} else {
+			_, contractAddr, remaining, err = vmenv.Create2(caller, code, gas, amount, new(uint256.Int).SetBytes32(salt[:]))
+		}
+		if lim.err != nil {
+			// The cancelled interpreter stopped without reverting, drop what it changed
+			stateDB.DiscardChanges()
+			return lim.failure()
+		}
+		if err != nil {
+			return execResult{remaining: remaining, err: fmt.Errorf("%w: %w", ErrExecutionFailed, err)}
+		}
+		return execResult{addr: contractAddr, remaining: finish(stateDB, gas, remaining)}
+	})
+	if res.err != nil {
+		if errors.Is(res.err, ErrExecutionTimeout) {
+			trace.Logger(ctx).Warn().Str("caller", caller.Hex()).Dur("timeout", e.limits.Timeout).Msg("Contract deployment timed out")
+		}
+		return common.Address{}, res.remaining, res.err
+	}
 
-	log.Info().Str("caller", caller.Hex()).Str("contract", contractAddr.Hex()).Msg("Contract deployed successfully")
-	return contractAddr, remaining, nil
+	contractAddr := res.addr
+	trace.Logger(ctx).Info().Str("caller", caller.Hex()).Str("contract", contractAddr.Hex()).Msg("Contract deployed successfully")
+	return contractAddr, res.remaining, nil
 }
 
 // FormatBigInt ensures consistent formatting of big.Int values
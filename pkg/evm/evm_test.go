@@ -0,0 +1,268 @@
+package evm
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// counterRuntime increments storage slot 0 on every call
+var counterRuntime = common.FromHex("600160005401600055" + "00")
+
+// counterInit returns counterRuntime as the code of the new contract
+var counterInit = append(common.FromHex("600a600c600039600a6000f3"), counterRuntime...)
+
+func TestDeployAndCall(t *testing.T) {
+	st := state.NewState()
+	caller := common.Address{1}
+	st.SetAccount(&state.Account{Address: types.FromCommon(caller), Balance: big.NewInt(1000)})
+
+	executor := NewEVMExecutor()
+	adapter := NewStateAdapter(st)
+	contract, remaining, err := executor.DeployContract(context.Background(), adapter, caller, big.NewInt(10), 100000, counterInit)
+	require.NoError(t, err)
+	require.Less(t, remaining, uint64(100000))
+	adapter.ApplyChanges()
+
+	require.Equal(t, crypto.CreateAddress(caller, 0), contract)
+	code, err := st.GetCode(types.FromCommon(contract))
+	require.NoError(t, err)
+	require.Equal(t, counterRuntime, code)
+
+	for i := 0; i < 2; i++ {
+		adapter := NewStateAdapter(st)
+		_, _, err := executor.ExecuteContract(context.Background(), adapter, caller, contract, big.NewInt(0), 100000, nil)
+		require.NoError(t, err)
+		adapter.ApplyChanges()
+	}
+
+	slot, err := st.GetStorage(types.FromCommon(contract), [32]byte{})
+	require.NoError(t, err)
+	require.Equal(t, common.BigToHash(big.NewInt(2)), common.Hash(slot))
+
+	account, err := st.GetAccount(types.FromCommon(caller))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), account.Nonce)
+	require.Equal(t, "990", account.Balance.String())
+	contractAccount, err := st.GetAccount(types.FromCommon(contract))
+	require.NoError(t, err)
+	require.Equal(t, "10", contractAccount.Balance.String())
+	require.Equal(t, uint64(1), contractAccount.Nonce)
+}
+
+func TestDeployWithSalt(t *testing.T) {
+	st := state.NewState()
+	caller := common.Address{1}
+	st.SetAccount(&state.Account{Address: types.FromCommon(caller), Balance: big.NewInt(1000)})
+	executor := NewEVMExecutor()
+	salt := [32]byte{0xaa}
+
+	adapter := NewStateAdapter(st)
+	contract, _, err := executor.DeployContract2(context.Background(), adapter, caller, big.NewInt(0), 100000, counterInit, salt)
+	require.NoError(t, err)
+	adapter.ApplyChanges()
+	require.Equal(t, Create2Address(caller, salt, counterInit), contract)
+
+	// The address does not depend on the nonce, so the same salt and code cannot be deployed twice
+	_, _, err = executor.DeployContract2(context.Background(), NewStateAdapter(st), caller, big.NewInt(0), 100000, counterInit, salt)
+	require.Error(t, err)
+}
+
+func TestExecutionLimits(t *testing.T) {
+	st := state.NewState()
+	caller := common.Address{1}
+	st.SetAccount(&state.Account{Address: types.FromCommon(caller), Balance: big.NewInt(1000)})
+
+	// Each contract stresses one limit: storing at offset 0x10000 expands memory past 64 KiB,
+	// twenty pushes grow the stack and the last one calls itself until it runs out of gas
+	contracts := map[string][]byte{
+		"memory":  common.FromHex("6000620100005200"),
+		"stack":   common.FromHex(strings.Repeat("6000", 20) + "00"),
+		"nesting": common.FromHex("60006000600060006000305af100"),
+	}
+	for i, name := range []string{"memory", "stack", "nesting"} {
+		addr := types.Address{0xc0, byte(i)}
+		st.SetAccount(&state.Account{Address: addr, Balance: big.NewInt(0)})
+		st.SetCode(addr, contracts[name])
+	}
+
+	limits := DefaultExecutionLimits()
+	limits.MaxMemoryBytes = 4096
+	limits.MaxStackItems = 16
+	limits.MaxCallDepth = 4
+	limited, err := NewEVMExecutorWithLimits(limits)
+	require.NoError(t, err)
+
+	call := func(executor *EVMExecutor, i int) (uint64, error) {
+		_, remaining, err := executor.ExecuteContract(context.Background(), NewStateAdapter(st), caller, common.Address{0xc0, byte(i)}, big.NewInt(0), 1000000, nil)
+		return remaining, err
+	}
+	for i, want := range []error{ErrMemoryLimit, ErrStackLimit, ErrCallDepthExceeded} {
+		_, err := call(NewEVMExecutor(), i)
+		require.NoError(t, err)
+
+		// Exceeding a limit fails the call and consumes all of its gas
+		remaining, err := call(limited, i)
+		require.ErrorIs(t, err, want)
+		require.ErrorIs(t, err, ErrExecutionFailed)
+		require.Zero(t, remaining)
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	st := state.NewState()
+	caller := common.Address{1}
+	st.SetAccount(&state.Account{Address: types.FromCommon(caller), Balance: big.NewInt(1000)})
+	// Jumps back to its start forever, given enough gas
+	loop := types.Address{0xc1}
+	st.SetAccount(&state.Account{Address: loop, Balance: big.NewInt(0)})
+	st.SetCode(loop, common.FromHex("5b600056"))
+
+	limits := DefaultExecutionLimits()
+	limits.MaxSteps = 1000
+	executor, err := NewEVMExecutorWithLimits(limits)
+	require.NoError(t, err)
+
+	// The budget counts instructions, not time, so every run stops at the same point and
+	// consumes all of its gas
+	for i := 0; i < 2; i++ {
+		adapter := NewStateAdapter(st)
+		_, remaining, err := executor.ExecuteContract(context.Background(), adapter, caller, common.Address(loop), big.NewInt(0), 1000000, nil)
+		require.ErrorIs(t, err, ErrStepLimit)
+		require.ErrorIs(t, err, ErrExecutionFailed)
+		require.Zero(t, remaining)
+	}
+}
+
+func TestExecutionCancelled(t *testing.T) {
+	st := state.NewState()
+	caller := common.Address{1}
+	st.SetAccount(&state.Account{Address: types.FromCommon(caller), Balance: big.NewInt(1000)})
+	// Jumps back to its start forever, given enough gas
+	loop := types.Address{0xc1}
+	st.SetAccount(&state.Account{Address: loop, Balance: big.NewInt(0)})
+	st.SetCode(loop, common.FromHex("5b600056"))
+
+	// The wall-clock limit only applies through the caller's context
+	limits := DefaultExecutionLimits()
+	limits.MaxSteps = 0
+	limits.Timeout = 50 * time.Millisecond
+	executor, err := NewEVMExecutorWithLimits(limits)
+	require.NoError(t, err)
+
+	ctx, cancel := executor.WithTimeout(context.Background())
+	defer cancel()
+	adapter := NewStateAdapter(st)
+	start := time.Now()
+	_, remaining, err := executor.ExecuteContract(ctx, adapter, caller, common.Address(loop), big.NewInt(0), 1<<50, nil)
+	require.ErrorIs(t, err, ErrExecutionTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotErrorIs(t, err, ErrExecutionFailed)
+	require.Zero(t, remaining)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// Nothing the cancelled call did is applied
+	adapter.ApplyChanges()
+	account, err := st.GetAccount(types.FromCommon(caller))
+	require.NoError(t, err)
+	require.Zero(t, account.Nonce)
+}
+
+func TestSnapshotRevert(t *testing.T) {
+	st := state.NewState()
+	addr := common.Address{2}
+	st.SetAccount(&state.Account{Address: types.FromCommon(addr), Balance: big.NewInt(5)})
+	st.SetStorage(types.FromCommon(addr), [32]byte{}, common.BigToHash(big.NewInt(7)))
+
+	adapter := NewStateAdapter(st)
+	slot := common.Hash{}
+	one := common.BigToHash(big.NewInt(1))
+
+	id := adapter.Snapshot()
+	adapter.SetState(addr, slot, one)
+	adapter.SetTransientState(addr, slot, one)
+	adapter.AddRefund(100)
+	adapter.AddAddressToAccessList(addr)
+	adapter.CreateAccount(common.Address{3})
+	adapter.SelfDestruct(addr)
+	require.True(t, adapter.HasSelfDestructed(addr))
+	require.True(t, adapter.GetBalance(addr).IsZero())
+	require.Equal(t, common.BigToHash(big.NewInt(7)), adapter.GetCommittedState(addr, slot))
+
+	adapter.RevertToSnapshot(id)
+	require.Equal(t, common.BigToHash(big.NewInt(7)), adapter.GetState(addr, slot))
+	require.Equal(t, common.Hash{}, adapter.GetTransientState(addr, slot))
+	require.Zero(t, adapter.GetRefund())
+	require.False(t, adapter.AddressInAccessList(addr))
+	require.False(t, adapter.Exist(common.Address{3}))
+	require.False(t, adapter.HasSelfDestructed(addr))
+	require.Equal(t, uint64(5), adapter.GetBalance(addr).Uint64())
+
+	// Only contracts created in the same transaction are deleted (EIP-6780)
+	_, deleted := adapter.SelfDestruct6780(addr)
+	require.False(t, deleted)
+
+	// A self-destructed account is removed with its storage once finalised and applied
+	adapter.SelfDestruct(addr)
+	adapter.Finalise(true)
+	require.False(t, adapter.Exist(addr))
+	adapter.ApplyChanges()
+	_, err := st.GetAccount(types.FromCommon(addr))
+	require.ErrorIs(t, err, state.ErrAccountNotFound)
+	_, err = st.GetStorage(types.FromCommon(addr), [32]byte{})
+	require.ErrorIs(t, err, state.ErrStorageNotFound)
+}
+
+func TestPrecompiles(t *testing.T) {
+	st := state.NewState()
+	caller := common.Address{1}
+	st.SetAccount(&state.Account{Address: types.FromCommon(caller), Balance: big.NewInt(1000)})
+	executor := NewEVMExecutor()
+	call := func(to common.Address, input []byte) ([]byte, error) {
+		ret, _, err := executor.ExecuteContract(context.Background(), NewStateAdapter(st), caller, to, big.NewInt(0), 1000000, input)
+		return ret, err
+	}
+
+	// ecrecover returns the signer of a hash
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	hash := crypto.Keccak256([]byte("message"))
+	sig, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+	input := append(append(append(hash, common.LeftPadBytes([]byte{sig[64] + 27}, 32)...), sig[:32]...), sig[32:64]...)
+	ret, err := call(common.BytesToAddress([]byte{0x1}), input)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), common.BytesToAddress(ret))
+
+	// An empty BN254 pairing check succeeds
+	ret, err = call(common.BytesToAddress([]byte{0x8}), nil)
+	require.NoError(t, err)
+	require.Equal(t, common.LeftPadBytes([]byte{1}, 32), ret)
+
+	// Precompiles of later forks are not available
+	_, err = call(common.BytesToAddress([]byte{0xa}), nil)
+	require.Error(t, err)
+
+	// Contracts reach precompiles too: this one returns the SHA256 of its calldata
+	runtime := common.FromHex("3660006000376020600036600060025afa5060206000f3")
+	init := append(common.FromHex("6017600c60003960176000f3"), runtime...)
+	adapter := NewStateAdapter(st)
+	contract, _, err := executor.DeployContract(context.Background(), adapter, caller, big.NewInt(0), 100000, init)
+	require.NoError(t, err)
+	adapter.ApplyChanges()
+
+	ret, err = call(contract, []byte("abc"))
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("abc"))
+	require.Equal(t, digest[:], ret)
+}
@@ -0,0 +1,160 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Execution limit errors
+var (
+	ErrExecutionTimeout  = errors.New("execution timed out")
+	ErrStepLimit         = errors.New("max execution steps exceeded")
+	ErrCallDepthExceeded = errors.New("max call depth exceeded")
+	ErrMemoryLimit       = errors.New("memory limit exceeded")
+	ErrStackLimit        = errors.New("stack limit exceeded")
+	ErrCodeSizeExceeded  = errors.New("max code size exceeded")
+)
+
+// ExecutionLimits bounds the resources a single EVM call may consume so a
+// pathological contract cannot stall batch processing. Every limit but Timeout is
+// deterministic, a call exceeding one fails the same way on every node.
+type ExecutionLimits struct {
+	MaxMemoryBytes uint64        // Upper bound on memory a call frame may expand to
+	MaxStackItems  int           // Maximum number of items on a call frame's stack
+	MaxCallDepth   int           // Maximum nesting of calls and creates
+	MaxCodeSize    int           // Maximum size of deployed bytecode
+	MaxSteps       uint64        // Instructions a top-level call may run across all its frames, 0 disables
+	Timeout        time.Duration // Wall-clock budget per top-level call while building a batch, 0 disables
+}
+
+// DefaultExecutionLimits returns limits matching Ethereum mainnet where applicable
+func DefaultExecutionLimits() ExecutionLimits {
+	return ExecutionLimits{
+		MaxMemoryBytes: 32 * 1024 * 1024, // 32 MiB
+		MaxStackItems:  1024,
+		MaxCallDepth:   1024,
+		MaxCodeSize:    24576, // EIP-170
+		MaxSteps:       30_000_000,
+		Timeout:        5 * time.Second,
+	}
+}
+
+// Validate checks that the limits are usable
+func (l ExecutionLimits) Validate() error {
+	if l.MaxMemoryBytes == 0 {
+		return fmt.Errorf("max memory must be positive")
+	}
+	if l.MaxStackItems <= 0 {
+		return fmt.Errorf("max stack items must be positive")
+	}
+	if l.MaxCallDepth <= 0 {
+		return fmt.Errorf("max call depth must be positive")
+	}
+	if l.MaxCodeSize <= 0 {
+		return fmt.Errorf("max code size must be positive")
+	}
+	if l.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
+
+// execResult carries the outcome of a call run under the executor's limits
+type execResult struct {
+	ret       []byte
+	addr      common.Address
+	remaining uint64
+	err       error
+}
+
+// limiter enforces the memory, stack, call depth and step limits from inside the interpreter.
+// Tracing hooks cannot fail an instruction, so a violation is recorded and the interpreter
+// cancelled. The call then fails as a whole and consumes all of its gas.
+type limiter struct {
+	limits ExecutionLimits
+	vmenv  *vm.EVM
+	steps  uint64
+	err    error
+}
+
+// hooks returns the tracing hooks checking the limits
+func (l *limiter) hooks() *tracing.Hooks {
+	return &tracing.Hooks{OnEnter: l.onEnter, OnOpcode: l.onOpcode}
+}
+
+// onEnter checks the depth of every call and create, the top-level call being at depth 0
+func (l *limiter) onEnter(depth int, typ byte, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if depth > l.limits.MaxCallDepth {
+		l.fail(ErrCallDepthExceeded)
+	}
+}
+
+// onOpcode counts each instruction and checks the memory and stack of the running frame
+// before it runs
+func (l *limiter) onOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	l.steps++
+	if l.limits.MaxSteps > 0 && l.steps > l.limits.MaxSteps {
+		l.fail(ErrStepLimit)
+	} else if uint64(len(scope.MemoryData())) > l.limits.MaxMemoryBytes {
+		l.fail(ErrMemoryLimit)
+	} else if len(scope.StackData()) > l.limits.MaxStackItems {
+		l.fail(ErrStackLimit)
+	}
+}
+
+// fail records the first violated limit and stops the interpreter
+func (l *limiter) fail(err error) {
+	if l.err == nil {
+		l.err = err
+		l.vmenv.Cancel()
+	}
+}
+
+// failure returns the outcome of a call that violated a limit, which consumes all of its gas
+func (l *limiter) failure() execResult {
+	return execResult{remaining: 0, err: fmt.Errorf("%w: %w", ErrExecutionFailed, l.err)}
+}
+
+// WithTimeout returns a context expiring after the configured Timeout. How long a call runs
+// differs between nodes, so only a node building a batch may apply it, to drop a slow call
+// locally. Replicas must execute without it.
+func (e *EVMExecutor) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.limits.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.limits.Timeout)
+}
+
+// runWithLimits runs fn until ctx is done. When ctx is done first the interpreter is
+// stopped, the pending state changes are discarded and the call fails with the context's
+// error, wrapped in ErrExecutionTimeout when its deadline passed.
+func (e *EVMExecutor) runWithLimits(ctx context.Context, stateDB StateDB, vmenv *vm.EVM, fn func() execResult) execResult {
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	done := make(chan execResult, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case res := <-done:
+		return res
+	case <-ctx.Done():
+		vmenv.Cancel()
+		stateDB.DiscardChanges()
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %w", ErrExecutionTimeout, err)
+		}
+		return execResult{remaining: 0, err: err}
+	}
+}
@@ -0,0 +1,29 @@
+package evm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// precompiles are the precompiled contracts at addresses 0x01 to 0x09: ecrecover, SHA256,
+// RIPEMD160, identity, modexp, BN254 addition, scalar multiplication and pairing, and
+// BLAKE2 F. The set is pinned rather than following the active fork so a go-ethereum
+// upgrade cannot change it under running contracts. The KZG point evaluation and BLS12-381
+// precompiles of later forks are left out, the rollup carries no blobs.
+var precompiles = vm.PrecompiledContractsBerlin
+
+// precompileAddresses lists the addresses of precompiles, warm from the start of every
+// transaction (EIP-2929)
+var precompileAddresses = func() []common.Address {
+	addrs := make([]common.Address, 0, len(precompiles))
+	for i := byte(1); i <= 9; i++ {
+		addrs = append(addrs, common.BytesToAddress([]byte{i}))
+	}
+	return addrs
+}()
+
+// isPrecompile reports whether addr is a precompiled contract
+func isPrecompile(addr common.Address) bool {
+	_, ok := precompiles[addr]
+	return ok
+}
@@ -1,36 +1,112 @@
 package evm
 
 import (
+	"bytes"
 	"math/big"
+	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	gethstate "github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/holiman/uint256"
+
 	"zkrollup/pkg/state"
 )
 
-// StateAdapter adapts the ZK-Rollup state to the EVM StateDB interface
+// stateObject is an account as seen by the running transaction, loaded from the rollup
+// state on first access
+type stateObject struct {
+	balance *uint256.Int
+	nonce   uint64
+	code    []byte
+	storage map[common.Hash]common.Hash // Slots written by the transaction
+	origin  map[common.Hash]common.Hash // Committed values of the slots read so far
+
+	// Values in the rollup state, only the fields that differ are written back
+	origBalance *uint256.Int
+	origNonce   uint64
+	codeDirty   bool
+
+	fresh          bool // Created by the transaction, the committed storage is empty
+	wipe           bool // Replaces an account that must be removed before writing this one
+	newContract    bool // Created as a contract by the transaction (EIP-6780)
+	selfDestructed bool
+	deleted        bool // Self-destructed and finalised
+}
+
+func newStateObject(balance *uint256.Int, nonce uint64, code []byte) *stateObject {
+	return &stateObject{
+		balance:     balance,
+		nonce:       nonce,
+		code:        code,
+		storage:     make(map[common.Hash]common.Hash),
+		origin:      make(map[common.Hash]common.Hash),
+		origBalance: new(uint256.Int).Set(balance),
+		origNonce:   nonce,
+	}
+}
+
+// empty reports whether the account is empty as defined by EIP-161
+func (o *stateObject) empty() bool {
+	return o.nonce == 0 && o.balance.IsZero() && len(o.code) == 0
+}
+
+// revision marks the journal length a snapshot can be reverted to
+type revision struct {
+	id           int
+	journalIndex int
+}
+
+// StateAdapter adapts the ZK-Rollup state to the go-ethereum vm.StateDB interface.
+// Changes are buffered and journaled so the EVM can revert them to any snapshot, and are
+// only written to the rollup state by ApplyChanges.
 type StateAdapter struct {
 	rollupState *state.State
-	
-	// Track changes that need to be applied to the rollup state
-	balanceChanges map[common.Address]*big.Int
-	nonceChanges   map[common.Address]uint64
-	codeChanges    map[common.Address][]byte
-	storageChanges map[common.Address]map[common.Hash]common.Hash
-	
+
+	objects map[common.Address]*stateObject
+	logs    []*types.Log
+	refund  uint64
+
+	// Per-transaction state reset by Prepare
+	accessAddrs map[common.Address]struct{}
+	accessSlots map[common.Address]map[common.Hash]struct{}
+	transient   map[common.Address]map[common.Hash]common.Hash
+
+	// Undo operations of every change since the last Finalise
+	journal        []func()
+	revisions      []revision
+	nextRevisionID int
+
+	// Set once the pending changes have been discarded; later applies are ignored
+	discarded bool
+
 	// Mutex for concurrent access
-	mu sync.RWMutex
+	mu sync.Mutex
 }
 
 // NewStateAdapter creates a new state adapter
 func NewStateAdapter(rollupState *state.State) *StateAdapter {
-	return &StateAdapter{
-		rollupState:    rollupState,
-		balanceChanges: make(map[common.Address]*big.Int),
-		nonceChanges:   make(map[common.Address]uint64),
-		codeChanges:    make(map[common.Address][]byte),
-		storageChanges: make(map[common.Address]map[common.Hash]common.Hash),
-	}
+	s := &StateAdapter{rollupState: rollupState}
+	s.reset()
+	return s
+}
+
+// reset drops every pending change
+func (s *StateAdapter) reset() {
+	s.objects = make(map[common.Address]*stateObject)
+	s.logs = nil
+	s.refund = 0
+	s.accessAddrs = make(map[common.Address]struct{})
+	s.accessSlots = make(map[common.Address]map[common.Hash]struct{})
+	s.transient = make(map[common.Address]map[common.Hash]common.Hash)
+	s.journal = nil
+	s.revisions = nil
 }
 
 // Convert Ethereum address to rollup address
@@ -45,220 +121,677 @@ func (s *StateAdapter) toEthAddress(addr [20]byte) common.Address {
 	return common.BytesToAddress(addr[:])
 }
 
-// GetBalance returns the balance of the given account
-func (s *StateAdapter) GetBalance(addr common.Address) *big.Int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	
-	// Check if we have a pending balance change
-	if balance, exists := s.balanceChanges[addr]; exists {
-		return balance
-	}
-	
-	// Get from rollup state
+// BatchNumber returns the number of the batch being executed
+func (s *StateAdapter) BatchNumber() uint64 {
+	return s.rollupState.GetBatchNumber()
+}
+
+// loadObject reads an account from the rollup state, nil when it does not exist
+func (s *StateAdapter) loadObject(addr common.Address) *stateObject {
 	rollupAddr := s.toRollupAddress(addr)
 	account, err := s.rollupState.GetAccount(rollupAddr)
-	if err != nil || account == nil {
-		return big.NewInt(0)
+	if err != nil {
+		account = nil
+	}
+	code, _ := s.rollupState.GetCode(rollupAddr)
+	if account == nil && len(code) == 0 {
+		return nil
+	}
+
+	balance := new(uint256.Int)
+	var nonce uint64
+	if account != nil {
+		if account.Balance != nil {
+			balance, _ = uint256.FromBig(account.Balance)
+		}
+		nonce = account.Nonce
+	}
+	return newStateObject(balance, nonce, code)
+}
+
+// getObject returns the live account at addr, nil when it does not exist
+func (s *StateAdapter) getObject(addr common.Address) *stateObject {
+	if obj, ok := s.objects[addr]; ok {
+		if obj.deleted {
+			return nil
+		}
+		return obj
+	}
+	obj := s.loadObject(addr)
+	if obj != nil {
+		s.objects[addr] = obj
+	}
+	return obj
+}
+
+// getOrNewObject returns the account at addr, creating it when it does not exist
+func (s *StateAdapter) getOrNewObject(addr common.Address) *stateObject {
+	if obj := s.getObject(addr); obj != nil {
+		return obj
 	}
-	
-	// Return a copy to avoid modifications
-	return new(big.Int).Set(account.Balance)
+	return s.createObject(addr)
+}
+
+// createObject replaces the account at addr with a new, empty one
+func (s *StateAdapter) createObject(addr common.Address) *stateObject {
+	prev, loaded := s.objects[addr]
+	if !loaded {
+		prev = s.loadObject(addr)
+	}
+
+	obj := newStateObject(new(uint256.Int), 0, nil)
+	obj.fresh = true
+	obj.wipe = prev != nil
+	s.objects[addr] = obj
+
+	s.journal = append(s.journal, func() {
+		if loaded {
+			s.objects[addr] = prev
+		} else {
+			delete(s.objects, addr)
+		}
+	})
+	return obj
+}
+
+// CreateAccount creates a new, empty account at addr
+func (s *StateAdapter) CreateAccount(addr common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.createObject(addr)
+}
+
+// CreateContract marks the account at addr as a contract created by this transaction
+func (s *StateAdapter) CreateContract(addr common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getOrNewObject(addr)
+	if !obj.newContract {
+		obj.newContract = true
+		s.journal = append(s.journal, func() { obj.newContract = false })
+	}
+}
+
+// setBalance journals and sets the balance of an account
+func (s *StateAdapter) setBalance(obj *stateObject, amount *uint256.Int) {
+	prev := obj.balance
+	obj.balance = new(uint256.Int).Set(amount)
+	s.journal = append(s.journal, func() { obj.balance = prev })
+}
+
+// GetBalance returns the balance of the given account
+func (s *StateAdapter) GetBalance(addr common.Address) *uint256.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if obj := s.getObject(addr); obj != nil {
+		return new(uint256.Int).Set(obj.balance)
+	}
+	return new(uint256.Int)
+}
+
+// AddBalance adds amount to the account balance and returns the previous balance
+func (s *StateAdapter) AddBalance(addr common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getOrNewObject(addr)
+	prev := *obj.balance
+	if !amount.IsZero() {
+		s.setBalance(obj, new(uint256.Int).Add(obj.balance, amount))
+	}
+	return prev
+}
+
+// SubBalance subtracts amount from the account balance and returns the previous balance
+func (s *StateAdapter) SubBalance(addr common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getOrNewObject(addr)
+	prev := *obj.balance
+	if !amount.IsZero() {
+		s.setBalance(obj, new(uint256.Int).Sub(obj.balance, amount))
+	}
+	return prev
 }
 
 // GetNonce returns the nonce of the given account
 func (s *StateAdapter) GetNonce(addr common.Address) uint64 {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	
-	// Check if we have a pending nonce change
-	if nonce, exists := s.nonceChanges[addr]; exists {
-		return nonce
-	}
-	
-	// Get from rollup state
-	rollupAddr := s.toRollupAddress(addr)
-	account, err := s.rollupState.GetAccount(rollupAddr)
-	if err != nil || account == nil {
-		return 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if obj := s.getObject(addr); obj != nil {
+		return obj.nonce
 	}
-	return account.Nonce
+	return 0
 }
 
+// SetNonce sets the nonce of the given account
+func (s *StateAdapter) SetNonce(addr common.Address, nonce uint64, _ tracing.NonceChangeReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	obj := s.getOrNewObject(addr)
+	prev := obj.nonce
+	obj.nonce = nonce
+	s.journal = append(s.journal, func() { obj.nonce = prev })
+}
 
 // GetCode returns the code of the given account
 func (s *StateAdapter) GetCode(addr common.Address) []byte {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	
-	// Check if we have pending code changes
-	if code, exists := s.codeChanges[addr]; exists {
-		return code
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if obj := s.getObject(addr); obj != nil {
+		return obj.code
+	}
+	return nil
+}
+
+// GetCodeSize returns the size of the code of the given account
+func (s *StateAdapter) GetCodeSize(addr common.Address) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if obj := s.getObject(addr); obj != nil {
+		return len(obj.code)
+	}
+	return 0
+}
+
+// GetCodeHash returns the code hash of the given account, zero when it does not exist
+func (s *StateAdapter) GetCodeHash(addr common.Address) common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getObject(addr)
+	if obj == nil {
+		return common.Hash{}
+	}
+	if len(obj.code) == 0 {
+		return types.EmptyCodeHash
 	}
-	
-	// In our current implementation, code is stored in storage
-	// This could be enhanced to store code directly in the account
-	return []byte{}
+	return crypto.Keccak256Hash(obj.code)
 }
 
+// SetCode sets the code of the given account and returns the previous code
+func (s *StateAdapter) SetCode(addr common.Address, code []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getOrNewObject(addr)
+	prev, prevDirty := obj.code, obj.codeDirty
+	obj.code = code
+	obj.codeDirty = true
+	s.journal = append(s.journal, func() {
+		obj.code = prev
+		obj.codeDirty = prevDirty
+	})
+	return prev
+}
+
+// committedState returns the value of a slot at the start of the transaction
+func (s *StateAdapter) committedState(addr common.Address, obj *stateObject, key common.Hash) common.Hash {
+	if value, ok := obj.origin[key]; ok {
+		return value
+	}
+	var value common.Hash
+	if !obj.fresh {
+		if stored, err := s.rollupState.GetStorage(s.toRollupAddress(addr), key); err == nil {
+			value = stored
+		}
+	}
+	obj.origin[key] = value
+	return value
+}
+
+// GetCommittedState returns the value of the given key as of the start of the transaction
+func (s *StateAdapter) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	obj := s.getObject(addr)
+	if obj == nil {
+		return common.Hash{}
+	}
+	return s.committedState(addr, obj, key)
+}
 
 // GetState returns the value of the given key in the account's storage
 func (s *StateAdapter) GetState(addr common.Address, key common.Hash) common.Hash {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	
-	// Check if we have pending storage changes
-	if storage, exists := s.storageChanges[addr]; exists {
-		if value, exists := storage[key]; exists {
-			return value
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getObject(addr)
+	if obj == nil {
+		return common.Hash{}
+	}
+	if value, ok := obj.storage[key]; ok {
+		return value
+	}
+	return s.committedState(addr, obj, key)
+}
+
+// SetState sets the value of the given key in the account's storage and returns the
+// previous value
+func (s *StateAdapter) SetState(addr common.Address, key common.Hash, value common.Hash) common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getOrNewObject(addr)
+	prev, dirty := obj.storage[key]
+	if !dirty {
+		prev = s.committedState(addr, obj, key)
+	}
+	if prev == value {
+		return prev
+	}
+
+	obj.storage[key] = value
+	s.journal = append(s.journal, func() {
+		if dirty {
+			obj.storage[key] = prev
+		} else {
+			delete(obj.storage, key)
 		}
+	})
+	return prev
+}
+
+// GetStorageRoot returns the storage root of the given account, the empty root when it
+// has no storage
+func (s *StateAdapter) GetStorageRoot(addr common.Address) common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := s.getObject(addr)
+	if obj == nil || obj.fresh {
+		return types.EmptyRootHash
+	}
+	if root, ok := s.rollupState.StorageRoot(s.toRollupAddress(addr)); ok {
+		return root
 	}
-	
-	// In our current implementation, we don't have direct storage access
-	// This would need to be enhanced for full EVM support
-	return common.Hash{}
+	return types.EmptyRootHash
 }
 
-// SetBalance sets the balance of the given account
-func (s *StateAdapter) SetBalance(addr common.Address, amount *big.Int) {
+// GetTransientState returns a transient storage value (EIP-1153)
+func (s *StateAdapter) GetTransientState(addr common.Address, key common.Hash) common.Hash {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	
-	// Handle zero values consistently as per memory requirements
-	if amount.Cmp(big.NewInt(0)) == 0 {
-		amount = big.NewInt(0)
+
+	return s.transient[addr][key]
+}
+
+// SetTransientState sets a transient storage value (EIP-1153)
+func (s *StateAdapter) SetTransientState(addr common.Address, key, value common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.transient[addr][key]
+	if prev == value {
+		return
 	}
-	
-	// Store the balance change
-	s.balanceChanges[addr] = new(big.Int).Set(amount)
+	s.setTransient(addr, key, value)
+	s.journal = append(s.journal, func() { s.setTransient(addr, key, prev) })
 }
 
-// SetNonce sets the nonce of the given account
-func (s *StateAdapter) SetNonce(addr common.Address, nonce uint64) {
+func (s *StateAdapter) setTransient(addr common.Address, key, value common.Hash) {
+	slots, ok := s.transient[addr]
+	if !ok {
+		slots = make(map[common.Hash]common.Hash)
+		s.transient[addr] = slots
+	}
+	slots[key] = value
+}
+
+// AddRefund adds gas to the refund counter
+func (s *StateAdapter) AddRefund(gas uint64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	
-	// Store the nonce change
-	s.nonceChanges[addr] = nonce
+
+	prev := s.refund
+	s.refund += gas
+	s.journal = append(s.journal, func() { s.refund = prev })
 }
 
-// SetCode sets the code of the given account
-func (s *StateAdapter) SetCode(addr common.Address, code []byte) {
+// SubRefund removes gas from the refund counter, which never drops below zero
+func (s *StateAdapter) SubRefund(gas uint64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	
-	// Store the code change
-	s.codeChanges[addr] = code
+
+	prev := s.refund
+	if gas > s.refund {
+		s.refund = 0
+	} else {
+		s.refund -= gas
+	}
+	s.journal = append(s.journal, func() { s.refund = prev })
 }
 
-// SetState sets the value of the given key in the account's storage
-func (s *StateAdapter) SetState(addr common.Address, key common.Hash, value common.Hash) {
+// GetRefund returns the current value of the refund counter
+func (s *StateAdapter) GetRefund() uint64 {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	
-	// Initialize storage map if needed
-	if _, exists := s.storageChanges[addr]; !exists {
-		s.storageChanges[addr] = make(map[common.Hash]common.Hash)
+
+	return s.refund
+}
+
+// selfDestruct zeroes the balance of an account and marks it for deletion
+func (s *StateAdapter) selfDestruct(obj *stateObject) uint256.Int {
+	prev := *obj.balance
+	if !obj.balance.IsZero() {
+		s.setBalance(obj, new(uint256.Int))
+	}
+	if !obj.selfDestructed {
+		obj.selfDestructed = true
+		s.journal = append(s.journal, func() { obj.selfDestructed = false })
 	}
-	
-	// Store the storage change
-	s.storageChanges[addr][key] = value
+	return prev
 }
 
+// SelfDestruct marks the given account for deletion at the end of the transaction and
+// returns its balance before it was cleared
+func (s *StateAdapter) SelfDestruct(addr common.Address) uint256.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	obj := s.getObject(addr)
+	if obj == nil {
+		return uint256.Int{}
+	}
+	return s.selfDestruct(obj)
+}
 
+// SelfDestruct6780 only deletes accounts created in the same transaction (EIP-6780)
+func (s *StateAdapter) SelfDestruct6780(addr common.Address) (uint256.Int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	obj := s.getObject(addr)
+	if obj == nil {
+		return uint256.Int{}, false
+	}
+	if obj.newContract {
+		return s.selfDestruct(obj), true
+	}
+	return *obj.balance, false
+}
 
+// HasSelfDestructed reports whether the given account self-destructed in this transaction
+func (s *StateAdapter) HasSelfDestructed(addr common.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	obj := s.getObject(addr)
+	return obj != nil && obj.selfDestructed
+}
 
+// Exist reports whether the given account exists, self-destructed accounts included
+func (s *StateAdapter) Exist(addr common.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	return s.getObject(addr) != nil
+}
+
+// Empty reports whether the given account does not exist or is empty (EIP-161)
+func (s *StateAdapter) Empty(addr common.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	obj := s.getObject(addr)
+	return obj == nil || obj.empty()
+}
 
+// AddressInAccessList reports whether the address is warm (EIP-2929)
+func (s *StateAdapter) AddressInAccessList(addr common.Address) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	_, ok := s.accessAddrs[addr]
+	return ok
+}
 
+// SlotInAccessList reports whether the address and the slot are warm (EIP-2929)
+func (s *StateAdapter) SlotInAccessList(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-// SubBalance subtracts amount from the account balance
-func (s *StateAdapter) SubBalance(addr common.Address, amount *big.Int) {
-	balance := s.GetBalance(addr)
-	newBalance := new(big.Int).Sub(balance, amount)
-	s.SetBalance(addr, newBalance)
+	_, addressOk = s.accessAddrs[addr]
+	_, slotOk = s.accessSlots[addr][slot]
+	return addressOk, slotOk
 }
 
-// AddBalance adds amount to the account balance
-func (s *StateAdapter) AddBalance(addr common.Address, amount *big.Int) {
-	balance := s.GetBalance(addr)
-	newBalance := new(big.Int).Add(balance, amount)
-	s.SetBalance(addr, newBalance)
+// AddAddressToAccessList warms an address
+func (s *StateAdapter) AddAddressToAccessList(addr common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addAccessAddress(addr)
 }
 
-// ApplyChanges applies all pending changes to the rollup state
-func (s *StateAdapter) ApplyChanges() {
+// AddSlotToAccessList warms a slot and its address
+func (s *StateAdapter) AddSlotToAccessList(addr common.Address, slot common.Hash) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	
-	// Apply balance changes
-	for addr, balance := range s.balanceChanges {
-		rollupAddr := s.toRollupAddress(addr)
-		account, err := s.rollupState.GetAccount(rollupAddr)
-		
-		// Create account if it doesn't exist
-		if err != nil || account == nil {
-			account = &state.Account{
-				Address: rollupAddr,
-				Balance: balance,
-				Nonce:   0,
+
+	s.addAccessAddress(addr)
+	slots, ok := s.accessSlots[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		s.accessSlots[addr] = slots
+	}
+	if _, ok := slots[slot]; ok {
+		return
+	}
+	slots[slot] = struct{}{}
+	s.journal = append(s.journal, func() { delete(slots, slot) })
+}
+
+func (s *StateAdapter) addAccessAddress(addr common.Address) {
+	if _, ok := s.accessAddrs[addr]; ok {
+		return
+	}
+	s.accessAddrs[addr] = struct{}{}
+	s.journal = append(s.journal, func() { delete(s.accessAddrs, addr) })
+}
+
+// Prepare resets the access list and transient storage for a new transaction. With
+// EIP-2929 the sender, destination, precompiles and the transaction's access list start
+// warm, and the coinbase too from Shanghai (EIP-3651).
+func (s *StateAdapter) Prepare(rules params.Rules, sender, coinbase common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rules.IsEIP2929 {
+		s.accessAddrs = make(map[common.Address]struct{})
+		s.accessSlots = make(map[common.Address]map[common.Hash]struct{})
+
+		warm := []common.Address{sender}
+		if dest != nil {
+			warm = append(warm, *dest)
+		}
+		warm = append(warm, precompiles...)
+		if rules.IsShanghai {
+			warm = append(warm, coinbase)
+		}
+		for _, addr := range warm {
+			s.accessAddrs[addr] = struct{}{}
+		}
+		for _, tuple := range txAccesses {
+			s.accessAddrs[tuple.Address] = struct{}{}
+			if s.accessSlots[tuple.Address] == nil {
+				s.accessSlots[tuple.Address] = make(map[common.Hash]struct{})
+			}
+			for _, key := range tuple.StorageKeys {
+				s.accessSlots[tuple.Address][key] = struct{}{}
 			}
-		} else {
-			account.Balance = balance
 		}
-		
-		s.rollupState.SetAccount(account)
 	}
-	
-	// Apply nonce changes
-	for addr, nonce := range s.nonceChanges {
-		rollupAddr := s.toRollupAddress(addr)
-		account, err := s.rollupState.GetAccount(rollupAddr)
-		
-		// Create account if it doesn't exist
-		if err != nil || account == nil {
-			account = &state.Account{
-				Address: rollupAddr,
-				Balance: big.NewInt(0),
-				Nonce:   nonce,
-			}
-		} else {
-			account.Nonce = nonce
+	s.transient = make(map[common.Address]map[common.Hash]common.Hash)
+}
+
+// Snapshot returns an identifier of the current state that RevertToSnapshot can return to
+func (s *StateAdapter) Snapshot() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextRevisionID
+	s.nextRevisionID++
+	s.revisions = append(s.revisions, revision{id: id, journalIndex: len(s.journal)})
+	return id
+}
+
+// RevertToSnapshot undoes every change made since the snapshot was taken
+func (s *StateAdapter) RevertToSnapshot(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := sort.Search(len(s.revisions), func(i int) bool {
+		return s.revisions[i].id >= id
+	})
+	// Snapshots taken before the changes were discarded no longer exist
+	if idx == len(s.revisions) || s.revisions[idx].id != id {
+		return
+	}
+
+	target := s.revisions[idx].journalIndex
+	for i := len(s.journal) - 1; i >= target; i-- {
+		s.journal[i]()
+	}
+	s.journal = s.journal[:target]
+	s.revisions = s.revisions[:idx]
+}
+
+// AddLog buffers a log until the changes are applied
+func (s *StateAdapter) AddLog(log *types.Log) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.logs)
+	s.logs = append(s.logs, log)
+	s.journal = append(s.journal, func() { s.logs = s.logs[:n] })
+}
+
+// AddPreimage is a no-op, preimages are not recorded
+func (s *StateAdapter) AddPreimage(common.Hash, []byte) {}
+
+// PointCache returns nil, verkle trees are not used
+func (s *StateAdapter) PointCache() *utils.PointCache { return nil }
+
+// Witness returns nil, stateless witnesses are not collected
+func (s *StateAdapter) Witness() *stateless.Witness { return nil }
+
+// AccessEvents returns nil, verkle access events are not tracked
+func (s *StateAdapter) AccessEvents() *gethstate.AccessEvents { return nil }
+
+// Finalise ends the transaction: self-destructed accounts are deleted and the journal and
+// refund counter are cleared, so earlier snapshots can no longer be reverted to. Empty
+// accounts are left to state.PruneEmptyAccounts.
+func (s *StateAdapter) Finalise(bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, obj := range s.objects {
+		if obj.selfDestructed {
+			obj.deleted = true
 		}
-		
-		s.rollupState.SetAccount(account)
 	}
-	
-	// Apply code changes
-	for addr, code := range s.codeChanges {
-		rollupAddr := s.toRollupAddress(addr)
-		s.rollupState.SetCode(rollupAddr, code)
+	s.journal = nil
+	s.revisions = nil
+	s.refund = 0
+}
+
+// DiscardChanges drops all pending changes. A call still running in the background
+// (for example one that exceeded its timeout) can no longer apply its writes.
+func (s *StateAdapter) DiscardChanges() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.discarded = true
+	s.reset()
+}
+
+// ApplyChanges applies all pending changes to the rollup state. Accounts are written in
+// address order, and only the fields the EVM changed, so updates made to the rollup state
+// since they were loaded are kept.
+func (s *StateAdapter) ApplyChanges() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.discarded {
+		return
+	}
+
+	addrs := make([]common.Address, 0, len(s.objects))
+	for addr := range s.objects {
+		addrs = append(addrs, addr)
 	}
-	
-	// Apply storage changes
-	for addr, storage := range s.storageChanges {
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+
+	for _, addr := range addrs {
+		obj := s.objects[addr]
 		rollupAddr := s.toRollupAddress(addr)
-		
-		for k, v := range storage {
-			var key, value [32]byte
-			copy(key[:], k.Bytes())
-			copy(value[:], v.Bytes())
-			s.rollupState.SetStorage(rollupAddr, key, value)
+
+		if obj.deleted || obj.wipe {
+			s.rollupState.DestroyAccount(rollupAddr)
+		}
+		if obj.deleted {
+			continue
+		}
+
+		balanceChanged := !obj.balance.Eq(obj.origBalance)
+		if balanceChanged || obj.nonce != obj.origNonce {
+			account, err := s.rollupState.GetAccount(rollupAddr)
+
+			// Create account if it doesn't exist
+			if err != nil || account == nil {
+				account = &state.Account{
+					Address: rollupAddr,
+					Balance: big.NewInt(0),
+				}
+			} else {
+				updated := *account
+				account = &updated
+			}
+			if balanceChanged {
+				account.Balance = obj.balance.ToBig()
+			}
+			if obj.nonce != obj.origNonce {
+				account.Nonce = obj.nonce
+			}
+			s.rollupState.SetAccount(account)
+		}
+
+		if obj.codeDirty {
+			s.rollupState.SetCode(rollupAddr, obj.code)
+		}
+
+		for k, v := range obj.storage {
+			if v == obj.origin[k] {
+				continue
+			}
+			s.rollupState.SetStorage(rollupAddr, k, v)
 		}
 	}
-	
-	// Clear all pending changes
-	s.balanceChanges = make(map[common.Address]*big.Int)
-	s.nonceChanges = make(map[common.Address]uint64)
-	s.codeChanges = make(map[common.Address][]byte)
-	s.storageChanges = make(map[common.Address]map[common.Hash]common.Hash)
-}
 
+	// Record logs in the order they were emitted
+	for _, l := range s.logs {
+		rollupLog := state.Log{
+			Address: s.toRollupAddress(l.Address),
+			Data:    l.Data,
+		}
+		for _, topic := range l.Topics {
+			rollupLog.Topics = append(rollupLog.Topics, topic)
+		}
+		s.rollupState.AddLog(rollupLog)
+	}
 
+	// Clear all pending changes
+	s.reset()
+}
@@ -0,0 +1,112 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/crypto"
+	"zkrollup/pkg/state"
+)
+
+// AggregateVerifier is the verifier the contract checks aggregated proofs with
+type AggregateVerifier struct {
+	Verifier    common.Address // Zero when aggregation is disabled
+	InputSchema [32]byte
+	BatchCount  int // Batches each aggregated proof covers
+}
+
+// GetAggregateVerifier reads the verifier for aggregated proofs
+func (c *Client) GetAggregateVerifier(ctx context.Context) (*AggregateVerifier, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	verifier, err := c.rollupContract.AggregateVerifier(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregate verifier: %v", err)
+	}
+	schema, err := c.rollupContract.AggregateInputSchema(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregate input schema: %v", err)
+	}
+	count, err := c.rollupContract.AggregateBatchCount(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregate batch count: %v", err)
+	}
+
+	return &AggregateVerifier{
+		Verifier:    verifier,
+		InputSchema: schema,
+		BatchCount:  int(count.Int64()),
+	}, nil
+}
+
+// SetAggregateVerifier sets the verifier of the aggregation circuit over count batches,
+// the zero address disables aggregation. Only the guardian may call it.
+func (c *Client) SetAggregateVerifier(ctx context.Context, verifier common.Address, count int) (*types.Transaction, error) {
+	var schema [32]byte
+	if verifier != (common.Address{}) {
+		schema = crypto.AggregationSchema(count).Hash()
+	}
+	return c.guardianCall(ctx, "set aggregate verifier", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.SetAggregateVerifier(opts, verifier, schema, big.NewInt(int64(count)))
+	})
+}
+
+// SubmitAggregatedBatches submits consecutive batches with one proof aggregating their
+// batch proofs. The contract verifies it once and accepts every batch.
+func (c *Client) SubmitAggregatedBatches(ctx context.Context, batches []state.Batch, proof, publicInputs []byte) (*types.Transaction, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+	if len(batches) < 2 {
+		return nil, fmt.Errorf("aggregation needs at least 2 batches, got %d", len(batches))
+	}
+
+	deployed, err := c.rollupContract.AggregateInputSchema(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregate input schema: %v", err)
+	}
+	schema := crypto.AggregationSchema(len(batches))
+	if deployed != schema.Hash() {
+		return nil, fmt.Errorf("%w: %s has schema %x, contract expects %x", ErrInputSchemaMismatch, schema, schema.Hash(), deployed)
+	}
+
+	var words [8]*big.Int
+	if len(proof) != 32*len(words) {
+		return nil, fmt.Errorf("invalid proof length: got %d, want %d", len(proof), 32*len(words))
+	}
+	for i := range words {
+		words[i] = new(big.Int).SetBytes(proof[32*i : 32*(i+1)])
+	}
+	inputs, err := schema.Split(publicInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	batchNumbers := make([]*big.Int, len(batches))
+	stateRoots := make([][32]byte, len(batches))
+	txHashes := make([][][32]byte, len(batches))
+	for i := range batches {
+		batchNumbers[i], stateRoots[i], txHashes[i] = submitBatchArgs(&batches[i])
+	}
+
+	auth, err := c.getTransactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := c.rollupContract.SubmitAggregatedBatches(auth, batchNumbers, stateRoots, txHashes, words, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit aggregated batches: %v", err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Uint64("first_batch", batches[0].BatchNumber).Int("batches", len(batches)).Msg("Submitted aggregated batches to L1")
+	return tx, nil
+}
@@ -0,0 +1,299 @@
+package l1
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/l1/contracts"
+	"zkrollup/pkg/state"
+)
+
+// DataAvailability selects how the transactions of a batch are published on L1
+type DataAvailability string
+
+const (
+	// DataAvailabilityOff publishes only transaction hashes with the batch
+	DataAvailabilityOff DataAvailability = "off"
+	// DataAvailabilityCalldata publishes the compressed transactions as calldata
+	DataAvailabilityCalldata DataAvailability = "calldata"
+	// DataAvailabilityBlob publishes the compressed transactions in an EIP-4844 blob
+	DataAvailabilityBlob DataAvailability = "blob"
+)
+
+// batchDataVersion prefixes encoded batch data so the format can change
+const batchDataVersion = 1
+
+// blobDataCapacity is the number of data bytes a blob holds: the first byte of each field
+// element is left zero to keep it below the BLS12-381 modulus, and 4 bytes hold the length
+const blobDataCapacity = len(kzg4844.Blob{})/32*31 - 4
+
+// EncodeBatchData encodes the transactions of a batch for publication on L1: a version
+// byte followed by the DEFLATE-compressed RLP list of the signed transactions
+func EncodeBatchData(txs []state.Transaction) ([]byte, error) {
+	payload, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transactions: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(batchDataVersion)
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, fmt.Errorf("failed to compress transactions: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress transactions: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeBatchData recovers the transactions of a batch from data made by EncodeBatchData
+func DecodeBatchData(data []byte) ([]state.Transaction, error) {
+	if len(data) == 0 || data[0] != batchDataVersion {
+		return nil, fmt.Errorf("unsupported batch data version")
+	}
+
+	payload, err := io.ReadAll(flate.NewReader(bytes.NewReader(data[1:])))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress transactions: %v", err)
+	}
+	var txs []state.Transaction
+	if err := rlp.DecodeBytes(payload, &txs); err != nil {
+		return nil, fmt.Errorf("failed to decode transactions: %v", err)
+	}
+	return txs, nil
+}
+
+// EncodeBlob packs data into a blob, 31 bytes per field element after a 4 byte length
+func EncodeBlob(data []byte) (*kzg4844.Blob, error) {
+	if len(data) > blobDataCapacity {
+		return nil, fmt.Errorf("batch data of %d bytes exceeds the blob capacity of %d", len(data), blobDataCapacity)
+	}
+
+	packed := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(packed, uint32(len(data)))
+	packed = append(packed, data...)
+
+	var blob kzg4844.Blob
+	for i := 0; len(packed) > 0; i++ {
+		n := copy(blob[32*i+1:32*(i+1)], packed)
+		packed = packed[n:]
+	}
+	return &blob, nil
+}
+
+// DecodeBlob returns the data packed into a blob by EncodeBlob
+func DecodeBlob(blob *kzg4844.Blob) ([]byte, error) {
+	packed := make([]byte, 0, blobDataCapacity+4)
+	for i := 0; i < len(blob)/32; i++ {
+		packed = append(packed, blob[32*i+1:32*(i+1)]...)
+	}
+
+	size := binary.BigEndian.Uint32(packed)
+	if int(size) > blobDataCapacity {
+		return nil, fmt.Errorf("invalid blob data length %d", size)
+	}
+	return packed[4 : 4+size], nil
+}
+
+// PublishBatchData publishes the transactions of a batch before the batch is submitted,
+// so the rollup state can be rebuilt from L1 alone
+func (c *Client) PublishBatchData(ctx context.Context, batch *state.Batch, mode DataAvailability) (*types.Transaction, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	data, err := EncodeBatchData(batch.Transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx *types.Transaction
+	switch mode {
+	case DataAvailabilityCalldata:
+		auth, err := c.getTransactOpts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tx, err = c.rollupContract.PublishBatchData(auth, l1BatchNumber(batch.BatchNumber), data); err != nil {
+			return nil, fmt.Errorf("failed to publish batch data: %v", err)
+		}
+	case DataAvailabilityBlob:
+		if tx, err = c.publishBlob(ctx, l1BatchNumber(batch.BatchNumber), data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown data availability mode %q", mode)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Uint64("batch_number", batch.BatchNumber).Str("mode", string(mode)).Int("size", len(data)).Msg("Published batch data to L1")
+	return tx, nil
+}
+
+// publishBlob sends a blob transaction carrying data that records the blob for batchNumber
+func (c *Client) publishBlob(ctx context.Context, batchNumber *big.Int, data []byte) (*types.Transaction, error) {
+	blob, err := EncodeBlob(data)
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := kzg4844.BlobToCommitment(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit to blob: %v", err)
+	}
+	proof, err := kzg4844.ComputeBlobProof(blob, commitment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove blob: %v", err)
+	}
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{*blob},
+		Commitments: []kzg4844.Commitment{commitment},
+		Proofs:      []kzg4844.Proof{proof},
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(contracts.ZKRollupABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rollup ABI: %v", err)
+	}
+	input, err := parsed.Pack("publishBatchBlob", batchNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack blob publication: %v", err)
+	}
+
+	tip, feeCap, _, err := c.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if feeCap == nil {
+		return nil, fmt.Errorf("blob transactions need a London chain")
+	}
+	blobFeeCap := new(big.Int).Mul(c.blobBaseFee(ctx), big.NewInt(2))
+
+	gas, err := c.ethClient.EstimateGas(ctx, ethereum.CallMsg{
+		From:          c.address,
+		To:            &c.rollupAddress,
+		Data:          input,
+		GasFeeCap:     feeCap,
+		GasTipCap:     tip,
+		BlobGasFeeCap: blobFeeCap,
+		BlobHashes:    sidecar.BlobHashes(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %v", err)
+	}
+
+	nonce, err := c.txs.reserveNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig(c.chainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.MustFromBig(tip),
+		GasFeeCap:  uint256.MustFromBig(feeCap),
+		Gas:        gas,
+		To:         c.rollupAddress,
+		Value:      new(uint256.Int),
+		Data:       input,
+		BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	})
+	signed, err := c.signer.SignTx(ctx, tx, c.chainID)
+	if err != nil {
+		c.txs.release(nonce)
+		return nil, fmt.Errorf("failed to sign blob transaction: %v", err)
+	}
+	if err := c.ethClient.SendTransaction(ctx, signed); err != nil {
+		return nil, fmt.Errorf("failed to publish batch blob: %v", err)
+	}
+	return signed, nil
+}
+
+// blobBaseFee returns the current blob base fee, or the protocol minimum when the
+// connection cannot report it
+func (c *Client) blobBaseFee(ctx context.Context) *big.Int {
+	if reader, ok := c.txs.backend.(interface {
+		BlobBaseFee(context.Context) (*big.Int, error)
+	}); ok {
+		if fee, err := reader.BlobBaseFee(ctx); err == nil {
+			return fee
+		}
+	}
+	return big.NewInt(params.BlobTxMinBlobGasprice)
+}
+
+// FetchBatchData recovers the transactions of a batch from the calldata they were
+// published in. Blobs are pruned by L1 nodes after a few weeks and are only served by
+// beacon nodes, so blob-published batches are decoded with DecodeBlob instead.
+func (c *Client) FetchBatchData(ctx context.Context, batchNumber uint64) ([]state.Transaction, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(contracts.ZKRollupABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rollup ABI: %v", err)
+	}
+	event := parsed.Events["BatchDataPublished"]
+
+	logs, err := c.ethClient.FilterLogs(ctx, ethereum.FilterQuery{
+		Addresses: []common.Address{c.rollupAddress},
+		Topics:    [][]common.Hash{{event.ID}, {common.BigToHash(l1BatchNumber(batchNumber))}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter batch data: %v", err)
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("no data published for batch %d", batchNumber)
+	}
+
+	// A later publication replaces an earlier one, as in the contract
+	published := logs[len(logs)-1]
+	values, err := event.Inputs.NonIndexed().Unpack(published.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode batch data event: %v", err)
+	}
+	dataHash, blob := values[0].([32]byte), values[1].(bool)
+	if blob {
+		return nil, fmt.Errorf("batch %d was published in blob %x, fetch it from a beacon node", batchNumber, dataHash)
+	}
+
+	tx, _, err := c.ethClient.TransactionByHash(ctx, published.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get publication transaction %s: %v", published.TxHash.Hex(), err)
+	}
+	method := parsed.Methods["publishBatchData"]
+	if len(tx.Data()) < 4 || !bytes.Equal(tx.Data()[:4], method.ID) {
+		return nil, fmt.Errorf("transaction %s does not publish batch data", published.TxHash.Hex())
+	}
+	args, err := method.Inputs.Unpack(tx.Data()[4:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode batch data: %v", err)
+	}
+	data := args[1].([]byte)
+	if crypto.Keccak256Hash(data) != dataHash {
+		return nil, fmt.Errorf("published data of batch %d does not match its hash", batchNumber)
+	}
+
+	return DecodeBatchData(data)
+}
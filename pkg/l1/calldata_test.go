@@ -0,0 +1,45 @@
+package l1
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+func TestBatchDataRoundTrip(t *testing.T) {
+	txs := make([]state.Transaction, 50)
+	for i := range txs {
+		txs[i] = state.Transaction{
+			From:      types.Address{1},
+			To:        types.Address{2},
+			Amount:    big.NewInt(int64(i + 1)),
+			Nonce:     uint64(i),
+			Data:      []byte{0xaa},
+			Gas:       21000,
+			GasPrice:  big.NewInt(1),
+			Signature: bytes.Repeat([]byte{byte(i)}, 65),
+		}
+	}
+
+	data, err := EncodeBatchData(txs)
+	require.NoError(t, err)
+	decoded, err := DecodeBatchData(data)
+	require.NoError(t, err)
+	require.Equal(t, txs, decoded)
+
+	blob, err := EncodeBlob(data)
+	require.NoError(t, err)
+	unpacked, err := DecodeBlob(blob)
+	require.NoError(t, err)
+	require.Equal(t, data, unpacked)
+
+	_, err = EncodeBlob(make([]byte, blobDataCapacity+1))
+	require.Error(t, err)
+	_, err = DecodeBatchData(append([]byte{batchDataVersion + 1}, data[1:]...))
+	require.Error(t, err)
+}
@@ -2,27 +2,46 @@ package l1
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/rs/zerolog/log"
 
+	zkCrypto "zkrollup/pkg/crypto"
 	"zkrollup/pkg/l1/contracts"
+	"zkrollup/pkg/signer"
 	"zkrollup/pkg/state"
 )
 
+// Backend is the L1 node connection, an ethclient.Client or a simulated backend
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	ethereum.BlockNumberReader
+	ethereum.TransactionReader
+}
+
 // Client represents an Ethereum L1 client for the ZK-Rollup
 type Client struct {
-	ethClient      *ethclient.Client
+	ethClient      Backend
 	rollupContract *contracts.ZKRollup
-	privateKey     *ecdsa.PrivateKey
+	rollupAddress  common.Address
+	signer         signer.Signer
 	address        common.Address
 	chainID        *big.Int
+	finalityDepth  uint64
+	gasLimit       uint64
+	maxFeePerGas   *big.Int      // Nil for no cap
+	stuckAfter     time.Duration // Pending time before a transaction is replaced
+	txs            *txManager
 }
 
 // Config represents the configuration for the L1 client
@@ -31,6 +50,11 @@ type Config struct {
 	ChainID         int64
 	ContractAddress string
 	PrivateKey      string
+	Signer          signer.Signer // Signs submissions instead of PrivateKey when set
+	FinalityDepth   uint64        // L1 blocks a verified batch must be buried under to be final
+	GasLimit        uint64        // Gas limit of contract calls, 3000000 when zero
+	MaxFeePerGas    *big.Int      // Cap on the fee per gas paid, nil for no cap
+	StuckAfter      time.Duration // Pending time before a transaction is replaced with higher fees, 3 minutes when zero
 }
 
 // NewClient creates a new L1 client
@@ -40,25 +64,42 @@ func NewClient(config *Config) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Ethereum node: %v", err)
 	}
+	return NewClientWithBackend(ethClient, config)
+}
 
-	// Load private key
-	privateKey, err := crypto.HexToECDSA(config.PrivateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load private key: %v", err)
+// NewClientWithBackend creates an L1 client on an existing connection, config.EthereumRPC
+// is ignored
+func NewClientWithBackend(ethClient Backend, config *Config) (*Client, error) {
+	// Sign with the configured signer, or with the private key in memory
+	txSigner := config.Signer
+	if txSigner == nil {
+		local, err := signer.NewLocalSignerFromHex(config.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		txSigner = local
 	}
 
-	// Get account address
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("failed to cast public key to ECDSA")
+	// Nonces of the signing account are managed locally so concurrent submissions do not
+	// collide and stuck ones can be replaced
+	txs := newTxManager(ethClient, txSigner.Address())
+	ethClient = &trackedBackend{Backend: ethClient, txs: txs}
+
+	gasLimit := config.GasLimit
+	if gasLimit == 0 {
+		gasLimit = 3000000
+	}
+	stuckAfter := config.StuckAfter
+	if stuckAfter == 0 {
+		stuckAfter = defaultStuckAfter
 	}
-	address := crypto.PubkeyToAddress(*publicKeyECDSA)
 
 	// Load rollup contract if address is provided
 	var rollupContract *contracts.ZKRollup
+	var contractAddress common.Address
+	var err error
 	if config.ContractAddress != "" {
-		contractAddress := common.HexToAddress(config.ContractAddress)
+		contractAddress = common.HexToAddress(config.ContractAddress)
 		rollupContract, err = contracts.NewZKRollup(contractAddress, ethClient)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load rollup contract: %v", err)
@@ -68,21 +109,31 @@ func NewClient(config *Config) (*Client, error) {
 	return &Client{
 		ethClient:      ethClient,
 		rollupContract: rollupContract,
-		privateKey:     privateKey,
-		address:        address,
+		rollupAddress:  contractAddress,
+		signer:         txSigner,
+		address:        txSigner.Address(),
 		chainID:        big.NewInt(config.ChainID),
+		finalityDepth:  config.FinalityDepth,
+		gasLimit:       gasLimit,
+		maxFeePerGas:   config.MaxFeePerGas,
+		stuckAfter:     stuckAfter,
+		txs:            txs,
 	}, nil
 }
 
-// DeployContract deploys the ZK-Rollup contract to L1
-func (c *Client) DeployContract(ctx context.Context) (common.Address, error) {
+// DeployContract deploys the ZK-Rollup contract to L1. Batch proofs are verified by the
+// verifier contract at verifier, the zero address accepts batches unverified, and must lay
+// out their public inputs by the schema with hash inputSchema.
+func (c *Client) DeployContract(ctx context.Context, verifier common.Address, inputSchema [32]byte) (common.Address, error) {
 	auth, err := c.getTransactOpts(ctx)
 	if err != nil {
 		return common.Address{}, err
 	}
+	// The contract code costs more to deploy than the gas limit of calls, so the gas is estimated
+	auth.GasLimit = 0
 
 	// Deploy contract using the safe deployment function
-	address, tx, err := contracts.DeployZKRollupSafe(auth, c.ethClient)
+	address, tx, err := contracts.DeployZKRollupSafe(auth, c.ethClient, verifier, inputSchema)
 	if err != nil {
 		return common.Address{}, fmt.Errorf("failed to deploy contract: %v", err)
 	}
@@ -91,19 +142,161 @@ func (c *Client) DeployContract(ctx context.Context) (common.Address, error) {
 	return address, nil
 }
 
-// SubmitBatch submits a batch to the L1 contract
-func (c *Client) SubmitBatch(ctx context.Context, batch *state.Batch, proof []byte) error {
+// SubmitBatch submits a batch with its Groth16 proof and public inputs to the L1 contract,
+// which verifies the proof before accepting the batch, and returns the submission transaction.
+// A proof made with the keys of a registered CRS epoch is checked by that epoch's verifier.
+func (c *Client) SubmitBatch(ctx context.Context, batch *state.Batch, proof, publicInputs []byte) (*types.Transaction, error) {
 	if c.rollupContract == nil {
-		return fmt.Errorf("rollup contract not initialized")
+		return nil, fmt.Errorf("rollup contract not initialized")
 	}
 
 	auth, err := c.getTransactOpts(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Convert batch to contract format
-	batchNumber := big.NewInt(int64(batch.BatchNumber))
+	batchNumber, stateRoot, txHashes := submitBatchArgs(batch)
+	proofWords, inputs, err := c.batchProofArgs(ctx, proof, publicInputs, batch.InputSchema, batch.VerifierEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Submit batch to L1
+	var tx *types.Transaction
+	if batch.VerifierEpoch != 0 && len(proof) > 0 {
+		tx, err = c.rollupContract.SubmitBatchWithEpoch(auth, batchNumber, stateRoot, txHashes, new(big.Int).SetUint64(batch.VerifierEpoch), proofWords, inputs)
+	} else {
+		tx, err = c.rollupContract.SubmitBatch(auth, batchNumber, stateRoot, txHashes, proofWords, inputs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit batch: %v", err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Uint64("batch_number", batch.BatchNumber).Msg("Submitted batch to L1")
+	return tx, nil
+}
+
+// SubmitBatchData posts a batch without its proof. The contract stores it unverified until
+// ProveBatch supplies the proof.
+func (c *Client) SubmitBatchData(ctx context.Context, batch *state.Batch) (*types.Transaction, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	auth, err := c.getTransactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batchNumber, stateRoot, txHashes := submitBatchArgs(batch)
+	tx, err := c.rollupContract.SubmitBatchData(auth, batchNumber, stateRoot, txHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit batch data: %v", err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Uint64("batch_number", batch.BatchNumber).Msg("Submitted batch data to L1 pending proof")
+	return tx, nil
+}
+
+// ProveBatch supplies the proof of a batch posted with SubmitBatchData, checked by the
+// verifier for the batch's key epoch
+func (c *Client) ProveBatch(ctx context.Context, batch *state.Batch) (*types.Transaction, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+	if len(batch.Proof) == 0 {
+		return nil, fmt.Errorf("missing proof for batch %d", batch.BatchNumber)
+	}
+
+	auth, err := c.getTransactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proofWords, inputs, err := c.batchProofArgs(ctx, batch.Proof, batch.PublicInputs, batch.InputSchema, batch.VerifierEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx *types.Transaction
+	if batch.VerifierEpoch != 0 {
+		tx, err = c.rollupContract.ProveBatchWithEpoch(auth, l1BatchNumber(batch.BatchNumber), new(big.Int).SetUint64(batch.VerifierEpoch), proofWords, inputs)
+	} else {
+		tx, err = c.rollupContract.ProveBatch(auth, l1BatchNumber(batch.BatchNumber), proofWords, inputs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove batch: %v", err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Uint64("batch_number", batch.BatchNumber).Uint64("verifier_epoch", batch.VerifierEpoch).Msg("Submitted batch proof to L1")
+	return tx, nil
+}
+
+// EstimateSubmitGas estimates the L1 gas needed to submit and verify a batch
+func (c *Client) EstimateSubmitGas(ctx context.Context, batch *state.Batch, proof, publicInputs []byte) (uint64, error) {
+	if c.rollupContract == nil {
+		return 0, fmt.Errorf("rollup contract not initialized")
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(contracts.ZKRollupABI))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse rollup ABI: %v", err)
+	}
+
+	batchNumber, stateRoot, txHashes := submitBatchArgs(batch)
+	proofWords, inputs, err := c.batchProofArgs(ctx, proof, publicInputs, batch.InputSchema, batch.VerifierEpoch)
+	if err != nil {
+		return 0, err
+	}
+	var data []byte
+	if batch.VerifierEpoch != 0 && len(proof) > 0 {
+		data, err = parsed.Pack("submitBatchWithEpoch", batchNumber, stateRoot, txHashes, new(big.Int).SetUint64(batch.VerifierEpoch), proofWords, inputs)
+	} else {
+		data, err = parsed.Pack("submitBatch", batchNumber, stateRoot, txHashes, proofWords, inputs)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack batch submission: %v", err)
+	}
+
+	gas, err := c.ethClient.EstimateGas(ctx, ethereum.CallMsg{
+		From: c.address,
+		To:   &c.rollupAddress,
+		Data: data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas: %v", err)
+	}
+
+	return gas, nil
+}
+
+// WaitForReceipt blocks until tx or a replacement of it is mined and returns the receipt
+func (c *Client) WaitForReceipt(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		// Replacements are sent while waiting, any attempt may be the one mined
+		for _, hash := range c.txs.attempts(tx) {
+			receipt, err := c.ethClient.TransactionReceipt(ctx, hash)
+			if err == nil && receipt != nil {
+				c.txs.mined(tx.Nonce())
+				return receipt, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to wait for transaction %s: %v", tx.Hash().Hex(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// submitBatchArgs converts a batch to the arguments of the submitBatch contract method
+func submitBatchArgs(batch *state.Batch) (*big.Int, [32]byte, [][32]byte) {
+	batchNumber := l1BatchNumber(batch.BatchNumber)
 	stateRoot := common.BytesToHash(batch.StateRoot[:])
 	txHashes := make([][32]byte, len(batch.Transactions))
 
@@ -111,14 +304,47 @@ func (c *Client) SubmitBatch(ctx context.Context, batch *state.Batch, proof []by
 		txHashes[i] = tx.Hash()
 	}
 
-	// Submit batch to L1
-	tx, err := c.rollupContract.SubmitBatch(auth, batchNumber, stateRoot, txHashes, proof)
-	if err != nil {
-		return fmt.Errorf("failed to submit batch: %v", err)
+	return batchNumber, stateRoot, txHashes
+}
+
+// l1BatchNumber maps a rollup batch number to the contract's numbering, which starts at 1
+func l1BatchNumber(batchNumber uint64) *big.Int {
+	return new(big.Int).SetUint64(batchNumber + 1)
+}
+
+// proofArgs splits a raw Groth16 proof (Ar, Bs, Krs) and its public inputs, laid out by
+// schema, into the 32 byte words the verifier contract takes. A missing proof is sent as
+// zeros, which only a contract deployed without a verifier accepts.
+func proofArgs(proof, publicInputs []byte, schema *zkCrypto.PublicInputSchema) ([8]*big.Int, [3]*big.Int, error) {
+	var words [8]*big.Int
+	var inputs [3]*big.Int
+
+	for i := range words {
+		words[i] = new(big.Int)
+	}
+	for i := range inputs {
+		inputs[i] = new(big.Int)
+	}
+	if len(proof) == 0 {
+		return words, inputs, nil
 	}
 
-	log.Info().Str("tx_hash", tx.Hash().Hex()).Uint64("batch_number", batch.BatchNumber).Msg("Submitted batch to L1")
-	return nil
+	if len(proof) != 32*len(words) {
+		return words, inputs, fmt.Errorf("invalid proof length: got %d, want %d", len(proof), 32*len(words))
+	}
+	for i := range words {
+		words[i].SetBytes(proof[32*i : 32*(i+1)])
+	}
+
+	values, err := schema.Split(publicInputs)
+	if err != nil {
+		return words, inputs, err
+	}
+	if len(values) != len(inputs) {
+		return words, inputs, fmt.Errorf("%w: %s has %d public inputs, the contract takes %d", ErrInputSchemaMismatch, schema, len(values), len(inputs))
+	}
+	copy(inputs[:], values)
+	return words, inputs, nil
 }
 
 // VerifyBatch verifies a batch on L1
@@ -130,7 +356,7 @@ func (c *Client) VerifyBatch(ctx context.Context, batchNumber uint64) (bool, err
 	// Call the verify method
 	verified, err := c.rollupContract.VerifyBatch(&bind.CallOpts{
 		Context: ctx,
-	}, big.NewInt(int64(batchNumber)))
+	}, l1BatchNumber(batchNumber))
 
 	if err != nil {
 		return false, fmt.Errorf("failed to verify batch: %v", err)
@@ -139,26 +365,123 @@ func (c *Client) VerifyBatch(ctx context.Context, batchNumber uint64) (bool, err
 	return verified, nil
 }
 
-// getTransactOpts creates transaction options for sending transactions
-func (c *Client) getTransactOpts(ctx context.Context) (*bind.TransactOpts, error) {
-	nonce, err := c.ethClient.PendingNonceAt(ctx, c.address)
+// CurrentBatchNumber returns the number of batches the L1 contract has accepted
+func (c *Client) CurrentBatchNumber(ctx context.Context) (uint64, error) {
+	if c.rollupContract == nil {
+		return 0, fmt.Errorf("rollup contract not initialized")
+	}
+
+	count, err := c.rollupContract.CurrentBatchNumber(&bind.CallOpts{
+		Context: ctx,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get nonce: %v", err)
+		return 0, fmt.Errorf("failed to get current batch number: %v", err)
 	}
 
-	gasPrice, err := c.ethClient.SuggestGasPrice(ctx)
+	return count.Uint64(), nil
+}
+
+// BatchState is how far a batch has progressed on L1
+type BatchState int
+
+const (
+	BatchUnknown   BatchState = iota // Not accepted by the contract
+	BatchSubmitted                   // Accepted without proof verification
+	BatchVerified                    // Proof verified, not yet buried under the finality depth
+	BatchFinalized                   // Proof verified and buried under the finality depth
+)
+
+// String returns the name of the state
+func (s BatchState) String() string {
+	switch s {
+	case BatchSubmitted:
+		return "submitted"
+	case BatchVerified:
+		return "verified"
+	case BatchFinalized:
+		return "finalized"
+	default:
+		return "unknown"
+	}
+}
+
+// BatchStatus is the L1 view of a rollup batch
+type BatchStatus struct {
+	BatchNumber   uint64
+	State         BatchState
+	StateRoot     common.Hash
+	L1Block       uint64
+	Confirmations uint64
+}
+
+// GetBatchStatus reports whether a batch was accepted on L1, whether its proof was
+// verified and whether the verification is final
+func (c *Client) GetBatchStatus(ctx context.Context, batchNumber uint64) (*BatchStatus, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	stored, err := c.rollupContract.Batches(&bind.CallOpts{Context: ctx}, l1BatchNumber(batchNumber))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %v", err)
+	}
+
+	status := &BatchStatus{
+		BatchNumber: batchNumber,
+		StateRoot:   common.Hash(stored.StateRoot),
+	}
+	if stored.L1Block == nil || stored.L1Block.Sign() == 0 {
+		return status, nil
+	}
+	status.L1Block = stored.L1Block.Uint64()
+
+	head, err := c.BlockNumber(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to suggest gas price: %v", err)
+		return nil, err
 	}
+	if head >= status.L1Block {
+		status.Confirmations = head - status.L1Block
+	}
+
+	switch {
+	case !stored.Verified:
+		status.State = BatchSubmitted
+	case status.Confirmations >= c.finalityDepth:
+		status.State = BatchFinalized
+	default:
+		status.State = BatchVerified
+	}
+	return status, nil
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(c.privateKey, c.chainID)
+// getTransactOpts creates transaction options for sending transactions. The nonce is
+// reserved by the transaction manager when the transaction is built, fees come from the
+// fee oracle.
+func (c *Client) getTransactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	tip, feeCap, gasPrice, err := c.SuggestFees(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create transactor: %v", err)
+		return nil, err
 	}
 
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)      // No ether transfer
-	auth.GasLimit = uint64(3000000) // Gas limit
+	auth := &bind.TransactOpts{
+		From: c.address,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != c.address {
+				return nil, bind.ErrNotAuthorized
+			}
+			signed, err := c.signer.SignTx(ctx, tx, c.chainID)
+			if err != nil {
+				c.txs.release(tx.Nonce())
+				return nil, err
+			}
+			return signed, nil
+		},
+		Context: ctx,
+	}
+	auth.Value = big.NewInt(0) // No ether transfer
+	auth.GasLimit = c.gasLimit
+	auth.GasTipCap = tip
+	auth.GasFeeCap = feeCap
 	auth.GasPrice = gasPrice
 
 	return auth, nil
@@ -0,0 +1,146 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package contracts
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StakingABI is the input ABI used to generate the binding from.
+const StakingABI = "[{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"_minStake\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"_unbondingPeriod\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"_slasher\",\"type\":\"address\"}],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\",\"indexed\":true},{\"internalType\":\"address\",\"name\":\"reporter\",\"type\":\"address\",\"indexed\":true},{\"internalType\":\"bytes32\",\"name\":\"evidenceHash\",\"type\":\"bytes32\",\"indexed\":true},{\"internalType\":\"bytes\",\"name\":\"evidence\",\"type\":\"bytes\",\"indexed\":false}],\"name\":\"DoubleSignReported\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\",\"indexed\":true},{\"internalType\":\"bytes32\",\"name\":\"evidenceHash\",\"type\":\"bytes32\",\"indexed\":true},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\",\"indexed\":false}],\"name\":\"Slashed\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"internalType\":\"address\",\"name\":\"previousSlasher\",\"type\":\"address\",\"indexed\":true},{\"internalType\":\"address\",\"name\":\"newSlasher\",\"type\":\"address\",\"indexed\":true}],\"name\":\"SlasherChanged\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\",\"indexed\":true},{\"internalType\":\"string\",\"name\":\"peerId\",\"type\":\"string\",\"indexed\":false},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\",\"indexed\":false},{\"internalType\":\"uint256\",\"name\":\"total\",\"type\":\"uint256\",\"indexed\":false}],\"name\":\"Staked\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\",\"indexed\":true},{\"internalType\":\"uint256\",\"name\":\"withdrawableAt\",\"type\":\"uint256\",\"indexed\":false}],\"name\":\"UnbondRequested\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\",\"indexed\":true},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\",\"indexed\":false}],\"name\":\"Withdrawn\",\"type\":\"event\"},{\"inputs\":[],\"name\":\"getValidators\",\"outputs\":[{\"internalType\":\"address[]\",\"name\":\"addrs\",\"type\":\"address[]\"},{\"internalType\":\"string[]\",\"name\":\"peerIds\",\"type\":\"string[]\"},{\"internalType\":\"uint256[]\",\"name\":\"stakes\",\"type\":\"uint256[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"minStake\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"validator\",\"type\":\"address\"},{\"internalType\":\"bytes\",\"name\":\"evidence\",\"type\":\"bytes\"}],\"name\":\"reportDoubleSign\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"name\":\"reports\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"requestUnbond\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"newSlasher\",\"type\":\"address\"}],\"name\":\"setSlasher\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"name\":\"settled\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"evidenceHash\",\"type\":\"bytes32\"}],\"name\":\"slash\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"slasher\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"peerId\",\"type\":\"string\"}],\"name\":\"stake\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"unbondingPeriod\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"withdraw\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
+
+// Staking is an auto generated Go binding around an Ethereum contract.
+type Staking struct {
+	StakingCaller     // Read-only binding to the contract
+	StakingTransactor // Write-only binding to the contract
+}
+
+// StakingCaller is an auto generated read-only Go binding around an Ethereum contract.
+type StakingCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// StakingTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type StakingTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// NewStaking creates a new instance of Staking, bound to a specific deployed contract.
+func NewStaking(address common.Address, backend bind.ContractBackend) (*Staking, error) {
+	contract, err := bindStaking(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &Staking{StakingCaller: StakingCaller{contract: contract}, StakingTransactor: StakingTransactor{contract: contract}}, nil
+}
+
+// bindStaking binds a generic wrapper to an already deployed contract.
+func bindStaking(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(StakingABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// GetValidators is a free data retrieval call binding the contract method 0xb7ab4db5.
+func (_Staking *StakingCaller) GetValidators(opts *bind.CallOpts) (struct {
+	Addrs   []common.Address
+	PeerIds []string
+	Stakes  []*big.Int
+}, error) {
+	var out []interface{}
+	err := _Staking.contract.Call(opts, &out, "getValidators")
+
+	outstruct := new(struct {
+		Addrs   []common.Address
+		PeerIds []string
+		Stakes  []*big.Int
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.Addrs = *abi.ConvertType(out[0], new([]common.Address)).(*[]common.Address)
+	outstruct.PeerIds = *abi.ConvertType(out[1], new([]string)).(*[]string)
+	outstruct.Stakes = *abi.ConvertType(out[2], new([]*big.Int)).(*[]*big.Int)
+
+	return *outstruct, err
+}
+
+// MinStake is a free data retrieval call binding the contract method 0x375b3c0a.
+func (_Staking *StakingCaller) MinStake(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _Staking.contract.Call(opts, &out, "minStake")
+	if err != nil {
+		return new(big.Int), err
+	}
+	return out[0].(*big.Int), err
+}
+
+// UnbondingPeriod is a free data retrieval call binding the contract method 0x6cf6d675.
+func (_Staking *StakingCaller) UnbondingPeriod(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _Staking.contract.Call(opts, &out, "unbondingPeriod")
+	if err != nil {
+		return new(big.Int), err
+	}
+	return out[0].(*big.Int), err
+}
+
+// Slasher is a free data retrieval call binding the contract method 0xb1344271.
+func (_Staking *StakingCaller) Slasher(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _Staking.contract.Call(opts, &out, "slasher")
+	if err != nil {
+		return *new(common.Address), err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), err
+}
+
+// Reports is a free data retrieval call binding the contract method 0xb39c4897.
+func (_Staking *StakingCaller) Reports(opts *bind.CallOpts, arg0 [32]byte) (common.Address, error) {
+	var out []interface{}
+	err := _Staking.contract.Call(opts, &out, "reports", arg0)
+	if err != nil {
+		return *new(common.Address), err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), err
+}
+
+// Stake is a paid mutator transaction binding the contract method 0x46f45b8d.
+func (_Staking *StakingTransactor) Stake(opts *bind.TransactOpts, peerId string) (*types.Transaction, error) {
+	return _Staking.contract.Transact(opts, "stake", peerId)
+}
+
+// RequestUnbond is a paid mutator transaction binding the contract method 0xa52b48a8.
+func (_Staking *StakingTransactor) RequestUnbond(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Staking.contract.Transact(opts, "requestUnbond")
+}
+
+// Withdraw is a paid mutator transaction binding the contract method 0x3ccfd60b.
+func (_Staking *StakingTransactor) Withdraw(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Staking.contract.Transact(opts, "withdraw")
+}
+
+// ReportDoubleSign is a paid mutator transaction binding the contract method 0xbfdd631c.
+func (_Staking *StakingTransactor) ReportDoubleSign(opts *bind.TransactOpts, validator common.Address, evidence []byte) (*types.Transaction, error) {
+	return _Staking.contract.Transact(opts, "reportDoubleSign", validator, evidence)
+}
+
+// Slash is a paid mutator transaction binding the contract method 0xf415ed14.
+func (_Staking *StakingTransactor) Slash(opts *bind.TransactOpts, evidenceHash [32]byte) (*types.Transaction, error) {
+	return _Staking.contract.Transact(opts, "slash", evidenceHash)
+}
+
+// SetSlasher is a paid mutator transaction binding the contract method 0xaabc2496.
+func (_Staking *StakingTransactor) SetSlasher(opts *bind.TransactOpts, newSlasher common.Address) (*types.Transaction, error) {
+	return _Staking.contract.Transact(opts, "setSlasher", newSlasher)
+}
@@ -27,7 +27,10 @@ var (
 )
 
 // ZKRollupABI is the input ABI used to generate the binding from.
-const ZKRollupABI = "[{\"inputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"name\":\"BatchSubmitted\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"bool\",\"name\":\"verified\",\"type\":\"bool\"}],\"name\":\"BatchVerified\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"batches\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"internalType\":\"bool\",\"name\":\"verified\",\"type\":\"bool\"},{\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"currentBatchNumber\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32[]\",\"name\":\"txHashes\",\"type\":\"bytes32[]\"},{\"internalType\":\"bytes\",\"name\":\"proof\",\"type\":\"bytes\"}],\"name\":\"submitBatch\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"}],\"name\":\"verifyBatch\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]"
+const ZKRollupABI = "[{\"inputs\":[{\"internalType\":\"address\",\"name\":\"verifierAddress\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"inputSchema\",\"type\":\"bytes32\"}],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"address\",\"name\":\"verifier\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bytes32\",\"name\":\"inputSchema\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"batchCount\",\"type\":\"uint256\"}],\"name\":\"AggregateVerifierChanged\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"bytes32\",\"name\":\"dataHash\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"bool\",\"name\":\"blob\",\"type\":\"bool\"}],\"name\":\"BatchDataPublished\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"name\":\"BatchSubmitted\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"bool\",\"name\":\"verified\",\"type\":\"bool\"}],\"name\":\"BatchVerified\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"guardian\",\"type\":\"address\"}],\"name\":\"BridgeFrozen\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"guardian\",\"type\":\"address\"}],\"name\":\"BridgeUnfrozen\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"from\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"l2Recipient\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"depositNonce\",\"type\":\"uint256\"}],\"name\":\"Deposit\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"previousGuardian\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"newGuardian\",\"type\":\"address\"}],\"name\":\"GuardianChanged\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"guardian\",\"type\":\"address\"}],\"name\":\"Paused\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"guardian\",\"type\":\"address\"}],\"name\":\"Unpaused\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"string\",\"name\":\"peerId\",\"type\":\"string\"}],\"name\":\"ValidatorAdded\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"string\",\"name\":\"peerId\",\"type\":\"string\"}],\"name\":\"ValidatorRemoved\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"epoch\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"address\",\"name\":\"verifier\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bytes32\",\"name\":\"inputSchema\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint64\",\"name\":\"circuitVersion\",\"type\":\"uint64\"}],\"name\":\"VerifierRegistered\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"account\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"}],\"name\":\"WithdrawalClaimed\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"peerId\",\"type\":\"string\"}],\"name\":\"addValidator\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"aggregateBatchCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"aggregateInputSchema\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"aggregateVerifier\",\"outputs\":[{\"internalType\":\"contract IAggregateVerifier\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"batchDataHash\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"batchEpoch\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"batches\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"internalType\":\"bool\",\"name\":\"verified\",\"type\":\"bool\"},{\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"l1Block\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"bridgeFrozen\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"account\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"amount\",\"type\":\"uint256\"},{\"components\":[{\"internalType\":\"uint256\",\"name\":\"balance\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"nonce\",\"type\":\"uint256\"},{\"internalType\":\"bytes32\",\"name\":\"storageRoot\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32\",\"name\":\"codeHash\",\"type\":\"bytes32\"}],\"internalType\":\"struct ZKRollup.BridgeAccount\",\"name\":\"bridge\",\"type\":\"tuple\"},{\"components\":[{\"internalType\":\"bytes32\",\"name\":\"bitmap\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32[]\",\"name\":\"siblings\",\"type\":\"bytes32[]\"}],\"internalType\":\"struct ZKRollup.MerkleProof\",\"name\":\"accountProof\",\"type\":\"tuple\"},{\"components\":[{\"internalType\":\"bytes32\",\"name\":\"bitmap\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32[]\",\"name\":\"siblings\",\"type\":\"bytes32[]\"}],\"internalType\":\"struct ZKRollup.MerkleProof\",\"name\":\"storageProof\",\"type\":\"tuple\"}],\"name\":\"claimWithdrawal\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"currentBatchNumber\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"l2Recipient\",\"type\":\"address\"}],\"name\":\"deposit\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"depositCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"freezeBridge\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"getValidators\",\"outputs\":[{\"internalType\":\"string[]\",\"name\":\"\",\"type\":\"string[]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"guardian\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"hasRegisteredVerifier\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"lastStateRoot\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"latestEpoch\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"pause\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"paused\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"},{\"internalType\":\"uint256[3]\",\"name\":\"publicInputs\",\"type\":\"uint256[3]\"}],\"name\":\"proveBatch\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"epoch\",\"type\":\"uint256\"},{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"},{\"internalType\":\"uint256[3]\",\"name\":\"publicInputs\",\"type\":\"uint256[3]\"}],\"name\":\"proveBatchWithEpoch\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"publicInputSchema\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"}],\"name\":\"publishBatchBlob\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"bytes\",\"name\":\"data\",\"type\":\"bytes\"}],\"name\":\"publishBatchData\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"epoch\",\"type\":\"uint256\"},{\"internalType\":\"address\",\"name\":\"verifierAddress\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"inputSchema\",\"type\":\"bytes32\"},{\"internalType\":\"uint64\",\"name\":\"circuitVersion\",\"type\":\"uint64\"}],\"name\":\"registerVerifier\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"peerId\",\"type\":\"string\"}],\"name\":\"removeValidator\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"verifierAddress\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"inputSchema\",\"type\":\"bytes32\"},{\"internalType\":\"uint256\",\"name\":\"batchCount\",\"type\":\"uint256\"}],\"name\":\"setAggregateVerifier\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"newGuardian\",\"type\":\"address\"}],\"name\":\"setGuardian\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256[]\",\"name\":\"batchNumbers\",\"type\":\"uint256[]\"},{\"internalType\":\"bytes32[]\",\"name\":\"stateRoots\",\"type\":\"bytes32[]\"},{\"internalType\":\"bytes32[][]\",\"name\":\"txHashes\",\"type\":\"bytes32[][]\"},{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"},{\"internalType\":\"uint256[]\",\"name\":\"publicInputs\",\"type\":\"uint256[]\"}],\"name\":\"submitAggregatedBatches\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32[]\",\"name\":\"txHashes\",\"type\":\"bytes32[]\"},{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"},{\"internalType\":\"uint256[3]\",\"name\":\"publicInputs\",\"type\":\"uint256[3]\"}],\"name\":\"submitBatch\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32[]\",\"name\":\"txHashes\",\"type\":\"bytes32[]\"}],\"name\":\"submitBatchData\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"},{\"internalType\":\"bytes32\",\"name\":\"stateRoot\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32[]\",\"name\":\"txHashes\",\"type\":\"bytes32[]\"},{\"internalType\":\"uint256\",\"name\":\"epoch\",\"type\":\"uint256\"},{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"},{\"internalType\":\"uint256[3]\",\"name\":\"publicInputs\",\"type\":\"uint256[3]\"}],\"name\":\"submitBatchWithEpoch\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"unfreezeBridge\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"unpause\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"verifier\",\"outputs\":[{\"internalType\":\"contract IBatchVerifier\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"verifierRegistry\",\"outputs\":[{\"internalType\":\"contract IBatchVerifier\",\"name\":\"verifier\",\"type\":\"address\"},{\"internalType\":\"bytes32\",\"name\":\"inputSchema\",\"type\":\"bytes32\"},{\"internalType\":\"uint64\",\"name\":\"circuitVersion\",\"type\":\"uint64\"},{\"internalType\":\"bool\",\"name\":\"registered\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"batchNumber\",\"type\":\"uint256\"}],\"name\":\"verifyBatch\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"name\":\"withdrawn\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]"
+
+// ZKRollupBin is the compiled bytecode used for deploying new contracts.
+const ZKRollupBin = "0x60c060405234801561000f575f5ffd5b50604051613a2a380380613a2a83398101604081905261002e916100c7565b5f600a556001600160a01b0382166080819052158061004c57508015155b6100ac5760405162461bcd60e51b815260206004820152602760248201527f56657269666965722072657175697265732061207075626c696320696e70757460448201526620736368656d6160c81b606482015260840160405180910390fd5b60a05250600e80546001600160a01b031916331790556100fe565b5f5f604083850312156100d8575f5ffd5b82516001600160a01b03811681146100ee575f5ffd5b6020939093015192949293505050565b60805160a0516138e86101425f395f6104ed01525f818161028c01528181610bac01528181610cbe015281816111af0152818161126e015261178901526138e85ff3fe608060405260043610610228575f3560e01c80638456cb5911610129578063b5da04f5116100a8578063ed88dbed1161006d578063ed88dbed14610748578063edfcb72614610767578063f0147e9514610786578063f340fa011461079b578063f48fa80b146107ae575f5ffd5b8063b5da04f5146106a3578063b70de0d9146106c2578063b7ab4db5146106d7578063c072bd88146106f8578063dfe3a3ae14610717575f5ffd5b8063a3637810116100ee578063a3637810146105b7578063a84eb569146105d6578063af17ad47146105f5578063b32c4d8d14610614578063b3bf230514610678575f5ffd5b80638456cb59146104a95780638a0dac4a146104bd57806396d7ce66146104dc5780639a336ff91461050f5780639cb118bf146105a2575f5ffd5b80633f52cb34116101b557806349e744571161017a57806349e74457146104005780635b803403146104145780635c975abb1461043f5780635fb6b66f1461045f5780636ef610921461047e575f5ffd5b80633f52cb3414610379578063452a93201461039857806345c5de9a146103b757806345fb3b45146103cb578063494ce991146103eb575f5ffd5b80632f88a737116101fb5780632f88a737146102e957806334ed505d146103085780633c3b9828146103275780633c3f0499146103465780633f4ba83a14610365575f5ffd5b8063182172b01461022c578063200ba30b1461025a5780632b7ac3f31461027b5780632dfdf0b5146102c6575b5f5ffd5b348015610237575f5ffd5b506002546102459060ff1681565b60405190151581526020015b60405180910390f35b348015610265575f5ffd5b50610279610274366004612d75565b6107c3565b005b348015610286575f5ffd5b506102ae7f000000000000000000000000000000000000000000000000000000000000000081565b6040516001600160a01b039091168152602001610251565b3480156102d1575f5ffd5b506102db600b5481565b604051908152602001610251565b3480156102f4575f5ffd5b50610279610303366004612ded565b6109e2565b348015610313575f5ffd5b50610279610322366004612e04565b610af8565b348015610332575f5ffd5b50610279610341366004612e88565b610d84565b348015610351575f5ffd5b50610279610360366004612f68565b611161565b348015610370575f5ffd5b50610279611312565b348015610384575f5ffd5b50610279610393366004613015565b611375565b3480156103a3575f5ffd5b50600e546102ae906001600160a01b031681565b3480156103c2575f5ffd5b506102796115e0565b3480156103d6575f5ffd5b50600e5461024590600160a81b900460ff1681565b3480156103f6575f5ffd5b506102db60055481565b34801561040b575f5ffd5b50610279611643565b34801561041f575f5ffd5b506102db61042e366004612ded565b60076020525f908152604090205481565b34801561044a575f5ffd5b50600e5461024590600160a01b900460ff1681565b34801561046a575f5ffd5b50610279610479366004613084565b6116ac565b348015610489575f5ffd5b506102db610498366004613125565b600d6020525f908152604090205481565b3480156104b4575f5ffd5b50610279611cfd565b3480156104c8575f5ffd5b506102796104d7366004613125565b611d66565b3480156104e7575f5ffd5b506102db7f000000000000000000000000000000000000000000000000000000000000000081565b34801561051a575f5ffd5b50610568610529366004612ded565b5f602081905290815260409020805460018201546002909201546001600160a01b0390911691906001600160401b03811690600160401b900460ff1684565b604080516001600160a01b03909516855260208501939093526001600160401b039091169183019190915215156060820152608001610251565b3480156105ad575f5ffd5b506102db60015481565b3480156105c2575f5ffd5b506102796105d1366004613145565b611e34565b3480156105e1575f5ffd5b506102796105f0366004613190565b611f16565b348015610600575f5ffd5b5061027961060f3660046131d7565b611ff2565b34801561061f575f5ffd5b5061065861062e366004612ded565b60096020525f90815260409020805460018201546002830154600390930154919260ff9091169184565b604080519485529215156020850152918301526060820152608001610251565b348015610683575f5ffd5b506102db610692366004612ded565b60036020525f908152604090205481565b3480156106ae575f5ffd5b506102796106bd366004613015565b6122a3565b3480156106cd575f5ffd5b506102db60085481565b3480156106e2575f5ffd5b506106eb612409565b604051610251919061321e565b348015610703575f5ffd5b506004546102ae906001600160a01b031681565b348015610722575f5ffd5b50610245610731366004612ded565b5f9081526009602052604090206001015460ff1690565b348015610753575f5ffd5b506102796107623660046132a2565b6124dd565b348015610772575f5ffd5b506102796107813660046132f2565b612727565b348015610791575f5ffd5b506102db60065481565b6102796107a9366004613125565b612840565b3480156107b9575f5ffd5b506102db600a5481565b600e54600160a01b900460ff16156107f65760405162461bcd60e51b81526004016107ed90613322565b60405180910390fd5b600a5486116108175760405162461bcd60e51b81526004016107ed90613359565b5f8381526020818152604091829020825160808101845281546001600160a01b03168152600182015492810192909252600201546001600160401b03811692820192909252600160401b90910460ff161515606082018190526108bc5760405162461bcd60e51b815260206004820181905260248201527f4e6f207665726966696572207265676973746572656420666f722065706f636860448201526064016107ed565b600a54156108fe576008546108df905f5160206138735f395f51905f52906133a4565b8235146108fe5760405162461bcd60e51b81526004016107ed906133cb565b6109155f5160206138735f395f51905f52876133a4565b6020830135146109375760405162461bcd60e51b81526004016107ed90613418565b80516040516365c0325960e01b81526001600160a01b03909116906365c0325990610968908690869060040161345b565b5f6040518083038186803b15801561097e575f5ffd5b505afa158015610990573d5f5f3e3d5ffd5b5050505f888152600360205260409020859055506109b087876001612933565b6008869055604051428152869088905f5160206138935f395f51905f529060200160405180910390a350505050505050565b600e54600160a01b900460ff1615610a0c5760405162461bcd60e51b81526004016107ed90613322565b600a548111610a565760405162461bcd60e51b815260206004820152601660248201527510985d18da08185b1c9958591e481858d8d95c1d195960521b60448201526064016107ed565b5f4980610aa55760405162461bcd60e51b815260206004820152601b60248201527f5472616e73616374696f6e2063617272696573206e6f20626c6f62000000000060448201526064016107ed565b5f82815260076020908152604091829020839055815183815260019181019190915283917fc7fcbbf3d01cac8eeb6d51e2f321f8510cd80be357edc506c4ca3e99605c7fef910160405180910390a25050565b600e54600160a01b900460ff1615610b225760405162461bcd60e51b81526004016107ed90613322565b5f838152600c60209081526040918290208251606081018452815460ff80821615158084526101009092041615159382019390935260019091015492810192909252610baa5760405162461bcd60e51b81526020600482015260176024820152762130ba31b4103737ba103832b73234b73390383937b7b360491b60448201526064016107ed565b7f00000000000000000000000000000000000000000000000000000000000000006001600160a01b0316610c195760405162461bcd60e51b8152602060048201526016602482015275139bc81d995c9a599a595c8818dbdb999a59dd5c995960521b60448201526064016107ed565b806020015115610c5f576040810151610c40905f5160206138735f395f51905f52906133a4565b823514610c5f5760405162461bcd60e51b81526004016107ed90613476565b5f84815260096020526040902054610c85905f5160206138735f395f51905f52906133a4565b602083013514610ca75760405162461bcd60e51b81526004016107ed90613418565b6040516365c0325960e01b81526001600160a01b037f000000000000000000000000000000000000000000000000000000000000000016906365c0325990610cf5908690869060040161345b565b5f6040518083038186803b158015610d0b575f5ffd5b505afa158015610d1d573d5f5f3e3d5ffd5b5050505f858152600c60209081526040808320805461ffff19168155600190810184905560099092528083208201805460ff1916831790555190925086917f8fa19318c4280299918d1220c80ba5db06b26b8947b75384ce2d74ba043c026591a350505050565b600e54600160a01b900460ff1615610dae5760405162461bcd60e51b81526004016107ed90613322565b60045488906001600160a01b0316610dff5760405162461bcd60e51b81526020600482015260146024820152731059d9dc9959d85d1a5bdb88191a5cd8589b195960621b60448201526064016107ed565b6006548114610e505760405162461bcd60e51b815260206004820152601760248201527f57726f6e67206e756d626572206f66206261746368657300000000000000000060448201526064016107ed565b8681148015610e5e57508481145b610eaa5760405162461bcd60e51b815260206004820181905260248201527f426174636820617267756d656e74732064696666657220696e206c656e67746860448201526064016107ed565b610eb58160026134d9565b610ec09060016134f0565b8214610f0e5760405162461bcd60e51b815260206004820152601d60248201527f57726f6e67206e756d626572206f66207075626c696320696e7075747300000060448201526064016107ed565b600a5415610f6757600854610f31905f5160206138735f395f51905f52906133a4565b83835f818110610f4357610f436133b7565b9050602002013514610f675760405162461bcd60e51b81526004016107ed906133cb565b5f5b81811015610fec575f5160206138735f395f51905f52898983818110610f9157610f916133b7565b905060200201355f1c610fa491906133a4565b8484610fb18460016134f0565b818110610fc057610fc06133b7565b9050602002013514610fe45760405162461bcd60e51b81526004016107ed90613418565b600101610f69565b5060048054604051632833f41360e11b81526001600160a01b0390911691635067e826916110209188918891889101613503565b5f6040518083038186803b158015611036575f5ffd5b505afa158015611048573d5f5f3e3d5ffd5b505f925050505b8181101561112c57600a548b8b8381811061106c5761106c6133b7565b90506020020135116110905760405162461bcd60e51b81526004016107ed90613359565b6110cc8b8b838181106110a5576110a56133b7565b905060200201358a8a848181106110be576110be6133b7565b905060200201356001612933565b8888828181106110de576110de6133b7565b905060200201358b8b838181106110f7576110f76133b7565b905060200201355f5160206138935f395f51905f524260405161111c91815260200190565b60405180910390a360010161104f565b50878761113a600184613549565b818110611149576111496133b7565b60200291909101356008555050505050505050505050565b600e54600160a01b900460ff161561118b5760405162461bcd60e51b81526004016107ed90613322565b600a5485116111ac5760405162461bcd60e51b81526004016107ed90613359565b5f7f00000000000000000000000000000000000000000000000000000000000000006001600160a01b0316156112d657600a541561121e576008546111ff905f5160206138735f395f51905f52906133a4565b82351461121e5760405162461bcd60e51b81526004016107ed906133cb565b6112355f5160206138735f395f51905f52866133a4565b6020830135146112575760405162461bcd60e51b81526004016107ed90613418565b6040516365c0325960e01b81526001600160a01b037f000000000000000000000000000000000000000000000000000000000000000016906365c03259906112a5908690869060040161345b565b5f6040518083038186803b1580156112bb575f5ffd5b505afa1580156112cd573d5f5f3e3d5ffd5b50505050600190505b6112e1868683612933565b6008859055604051428152859087905f5160206138935f395f51905f529060200160405180910390a3505050505050565b600e546001600160a01b0316331461133c5760405162461bcd60e51b81526004016107ed9061355c565b600e805460ff60a01b1916905560405133907f5db9ee0a495bf2e6ff9c91a7834c1ba4fdd244a5e8aa4e537bd38aeae4b073aa905f90a2565b600e546001600160a01b0316331461139f5760405162461bcd60e51b81526004016107ed9061355c565b5f82826040516113b0929190613593565b60408051918290039091205f8181526010602052918220549092509081900361140d5760405162461bcd60e51b815260206004820152600f60248201526e2737ba1030903b30b634b230ba37b960891b60448201526064016107ed565b600f5460011061145f5760405162461bcd60e51b815260206004820181905260248201527f43616e6e6f742072656d6f766520746865206c6173742076616c696461746f7260448201526064016107ed565b600f80545f919061147290600190613549565b81548110611482576114826133b7565b905f5260205f20018054611495906135a2565b80601f01602080910402602001604051908101604052809291908181526020018280546114c1906135a2565b801561150c5780601f106114e35761010080835404028352916020019161150c565b820191905f5260205f20905b8154815290600101906020018083116114ef57829003601f168201915b5050505050905080600f6001846115239190613549565b81548110611533576115336133b7565b905f5260205f200190816115479190613620565b5080516020808301919091205f908152601090915260409020829055600f805480611574576115746136da565b600190038181905f5260205f20015f61158d9190612c4e565b90555f8381526010602052604080822091909155517f53344ca00b011ca20d3dc9f1bb71ed60e097b598b9f35482879138cc15f28ef9906115d190879087906136ee565b60405180910390a15050505050565b600e546001600160a01b0316331461160a5760405162461bcd60e51b81526004016107ed9061355c565b600e805460ff60a81b1916905560405133907fd09598e9a0d64a97ed1df4fa6d6253078333cea7b3960416be2c6d27917dc916905f90a2565b600e546001600160a01b0316331461166d5760405162461bcd60e51b81526004016107ed9061355c565b600e805460ff60a81b1916600160a81b17905560405133907f50c7f4369b891306988cb41bc1c089e700a61f50c54942dbb548f0a881c4200e905f90a2565b600e54600160a81b900460ff16156116f95760405162461bcd60e51b815260206004820152601060248201526f213934b233b29034b990333937bd32b760811b60448201526064016107ed565b5f868152600960209081526040808320815160808101835281548152600182015460ff16151593810193909352600281015491830191909152600301546060820181905290910361177c5760405162461bcd60e51b815260206004820152600d60248201526c0aadcd6dcdeeedc40c4c2e8c6d609b1b60448201526064016107ed565b8060200151806117b357507f00000000000000000000000000000000000000000000000000000000000000006001600160a01b0316155b6117f45760405162461bcd60e51b815260206004820152601260248201527110985d18da081b9bdd081d995c9a599a595960721b60448201526064016107ed565b6001600160a01b0386165f908152600d6020526040902054851161184d5760405162461bcd60e51b815260206004820152601060248201526f4e6f7468696e6720746f20636c61696d60801b60448201526064016107ed565b604080516001600160a01b03881660208201525f910160408051601f1981840301815282825280516020918201209083018190525f9183018290529250906118f49060029060600160408051601f19818403018152908290526118af9161371c565b602060405180830381855afa1580156118ca573d5f5f3e3d5ffd5b5050506040513d601f19601f820116820180604052508101906118ed9190613732565b88866129c0565b9050856040013581146119415760405162461bcd60e51b815260206004820152601560248201527424b73b30b634b21039ba37b930b3b290383937b7b360591b60448201526064016107ed565b6040805160ee60208201819052883582840152825180830384018152606090920192839052915f9160029182916119779161371c565b602060405180830381855afa158015611992573d5f5f3e3d5ffd5b5050506040513d601f19601f820116820180604052508101906119b59190613732565b6002808b602001355f1b8c604001356040516020016119de929190918252602082015260400190565b60408051601f19818403018152908290526119f89161371c565b602060405180830381855afa158015611a13573d5f5f3e3d5ffd5b5050506040513d601f19601f82011682018060405250810190611a369190613732565b8b60600135604051602001611a55929190918252602082015260400190565b60408051601f1981840301815290829052611a6f9161371c565b602060405180830381855afa158015611a8a573d5f5f3e3d5ffd5b5050506040513d601f19601f82011682018060405250810190611aad9190613732565b60408051602081019390935282015260600160408051601f1981840301815290829052611ad99161371c565b602060405180830381855afa158015611af4573d5f5f3e3d5ffd5b5050506040513d601f19601f82011682018060405250810190611b179190613732565b90505f611b9c6002845f5f1b604051602001611b3d929190918252602082015260400190565b60408051601f1981840301815290829052611b579161371c565b602060405180830381855afa158015611b72573d5f5f3e3d5ffd5b5050506040513d601f19601f82011682018060405250810190611b959190613732565b838a6129c0565b86519091508114611be75760405162461bcd60e51b815260206004820152601560248201527424b73b30b634b21030b1b1b7bab73a10383937b7b360591b60448201526064016107ed565b6001600160a01b038b165f908152600d6020526040812054611c09908c613549565b6001600160a01b038d165f818152600d60205260408082208f9055519293509183908381818185875af1925050503d805f8114611c61576040519150601f19603f3d011682016040523d82523d5f602084013e611c66565b606091505b5050905080611ca95760405162461bcd60e51b815260206004820152600f60248201526e151c985b9cd9995c8819985a5b1959608a1b60448201526064016107ed565b8d8d6001600160a01b03167f2d43eb174787155132b52ddb6b346e2dca99302eac3df4466dbeff953d3c84d184604051611ce591815260200190565b60405180910390a35050505050505050505050505050565b600e546001600160a01b03163314611d275760405162461bcd60e51b81526004016107ed9061355c565b600e805460ff60a01b1916600160a01b17905560405133907f62e78cea01bee320cd4e420270b5ea74000d11b0c9f74754ebdbfc544b05a258905f90a2565b600e546001600160a01b03163314611d905760405162461bcd60e51b81526004016107ed9061355c565b6001600160a01b038116611dd95760405162461bcd60e51b815260206004820152601060248201526f24b73b30b634b21033bab0b93234b0b760811b60448201526064016107ed565b600e546040516001600160a01b038084169216907fa14fc14d8620a708a896fd11392a235647d99385500a295f0d7da2a258b2e967905f90a3600e80546001600160a01b0319166001600160a01b0392909216919091179055565b600e54600160a01b900460ff1615611e5e5760405162461bcd60e51b81526004016107ed90613322565b600a548311611e7f5760405162461bcd60e51b81526004016107ed90613359565b604080516060810182526001808252600a54151560208084019182526008548486019081525f898152600c90925294812093518454925161ffff1990931690151561ff00191617610100921515929092029190911783559251910155611ee89084908490612933565b6008829055604051428152829084905f5160206138935f395f51905f529060200160405180910390a3505050565b600e54600160a01b900460ff1615611f405760405162461bcd60e51b81526004016107ed90613322565b600a548311611f8a5760405162461bcd60e51b815260206004820152601660248201527510985d18da08185b1c9958591e481858d8d95c1d195960521b60448201526064016107ed565b5f8282604051611f9b929190613593565b604080519182900382205f87815260076020908152838220839055828552840152925085917fc7fcbbf3d01cac8eeb6d51e2f321f8510cd80be357edc506c4ca3e99605c7fef91015b60405180910390a250505050565b600e54600160a01b900460ff161561201c5760405162461bcd60e51b81526004016107ed90613322565b5f848152600c60209081526040918290208251606081018452815460ff808216151580845261010090920416151593820193909352600190910154928101929092526120a45760405162461bcd60e51b81526020600482015260176024820152762130ba31b4103737ba103832b73234b73390383937b7b360491b60448201526064016107ed565b5f8481526020818152604091829020825160808101845281546001600160a01b03168152600182015492810192909252600201546001600160401b03811692820192909252600160401b90910460ff161515606082018190526121495760405162461bcd60e51b815260206004820181905260248201527f4e6f207665726966696572207265676973746572656420666f722065706f636860448201526064016107ed565b81602001511561218f576040820151612170905f5160206138735f395f51905f52906133a4565b83351461218f5760405162461bcd60e51b81526004016107ed90613476565b5f868152600960205260409020546121b5905f5160206138735f395f51905f52906133a4565b6020840135146121d75760405162461bcd60e51b81526004016107ed90613418565b80516040516365c0325960e01b81526001600160a01b03909116906365c0325990612208908790879060040161345b565b5f6040518083038186803b15801561221e575f5ffd5b505afa158015612230573d5f5f3e3d5ffd5b5050505f878152600c60209081526040808320805461ffff191681556001908101849055600383528184208a905560099092528083208201805460ff1916831790555190925088917f8fa19318c4280299918d1220c80ba5db06b26b8947b75384ce2d74ba043c026591a3505050505050565b600e546001600160a01b031633146122cd5760405162461bcd60e51b81526004016107ed9061355c565b5f82826040516122de929190613593565b6040519081900390209050816123285760405162461bcd60e51b815260206004820152600f60248201526e125b9d985b1a59081c19595c881251608a1b60448201526064016107ed565b5f81815260106020526040902054156123795760405162461bcd60e51b815260206004820152601360248201527220b63932b0b23c9030903b30b634b230ba37b960691b60448201526064016107ed565b600f80546001810182555f919091527f8d1108e10bcb7c27dddfc02ed9d693a074039d026cf4ea4240b40f7d581ac802016123b5838583613749565b50600f545f828152601060205260409081902091909155517f0eb17eb6d7f643e1f1a79af44e460fffabb6b2a8beff44bff08160d8d3403d3f906123fc90859085906136ee565b60405180910390a1505050565b6060600f805480602002602001604051908101604052809291908181526020015f905b828210156124d4578382905f5260205f20018054612449906135a2565b80601f0160208091040260200160405190810160405280929190818152602001828054612475906135a2565b80156124c05780601f10612497576101008083540402835291602001916124c0565b820191905f5260205f20905b8154815290600101906020018083116124a357829003601f168201915b50505050508152602001906001019061242c565b50505050905090565b600e546001600160a01b031633146125075760405162461bcd60e51b81526004016107ed9061355c565b6001600160a01b03831661255d5760405162461bcd60e51b815260206004820152601960248201527f566572696669657220616464726573732072657175697265640000000000000060448201526064016107ed565b816125ba5760405162461bcd60e51b815260206004820152602760248201527f56657269666965722072657175697265732061207075626c696320696e70757460448201526620736368656d6160c81b60648201526084016107ed565b60025460ff1615806125cd575060015484115b6126335760405162461bcd60e51b815260206004820152603160248201527f45706f636820616c72656164792072656769737465726564206f72206f6c64656044820152701c881d1a185b881d1a19481b185d195cdd607a1b60648201526084016107ed565b604080516080810182526001600160a01b03808616825260208083018681526001600160401b038087168587019081526001606087018181525f8d815295869052948890209651875496166001600160a01b031990961695909517865591518585015590516002948501805493511515600160401b0268ffffffffffffffffff199094169190921617919091179055868155815460ff19161790555184907f908251824edef4e2c1adb61279248d14d3192e107d7c17ac327721b6c06aa65090611fe4908690869086906001600160a01b0393909316835260208301919091526001600160401b0316604082015260600190565b600e546001600160a01b031633146127515760405162461bcd60e51b81526004016107ed9061355c565b6001600160a01b038316158061277257508115801590612772575060028110155b6127db5760405162461bcd60e51b815260206004820152603460248201527f4167677265676174696f6e207265717569726573206120736368656d6120616e60448201527364206174206c656173742032206261746368657360601b60648201526084016107ed565b600480546001600160a01b0319166001600160a01b03851690811790915560058390556006829055604080519182526020820184905281018290527f5b893209dc8af77df7c53708d123b4395aa3b226a41a919c7c762c2212a6a6f3906060016123fc565b600e54600160a81b900460ff161561288d5760405162461bcd60e51b815260206004820152601060248201526f213934b233b29034b990333937bd32b760811b60448201526064016107ed565b5f34116128dc5760405162461bcd60e51b815260206004820152601f60248201527f4465706f73697420616d6f756e74206d75737420626520706f7369746976650060448201526064016107ed565b600b546128ea8160016134f0565b600b5560405134815281906001600160a01b0384169033907fdcbc1c05240f31ff3ad067ef1ee35ce4997762752e3a095284754544f4c709d79060200160405180910390a45050565b6040805160808101825283815282151560208083018281524284860190815243606086019081525f8a8152600990945286842095518655915160018601805460ff191691151591909117905551600285015551600390930192909255600a869055915185917f8fa19318c4280299918d1220c80ba5db06b26b8947b75384ce2d74ba043c026591a3505050565b5f828180805b610100811015612bf557826129dc6008836133a4565b6001901b87356129ed600885613802565b602081106129fd576129fd6133b7565b1a1615612a8657612a116020880188613815565b90508310612a515760405162461bcd60e51b815260206004820152600d60248201526c24b73b30b634b210383937b7b360991b60448201526064016107ed565b612a5e6020880188613815565b84818110612a6e57612a6e6133b7565b9050602002013590508280612a829061385a565b9350505b600189831c81169003612b0957604080516020810183905290810186905260029060600160408051601f1981840301815290829052612ac49161371c565b602060405180830381855afa158015612adf573d5f5f3e3d5ffd5b5050506040513d601f19601f82011682018060405250810190612b029190613732565b9450612b7b565b604080516020810187905290810182905260029060600160408051601f1981840301815290829052612b3a9161371c565b602060405180830381855afa158015612b55573d5f5f3e3d5ffd5b5050506040513d601f19601f82011682018060405250810190612b789190613732565b94505b604080516020810186905290810185905260029060600160408051601f1981840301815290829052612bac9161371c565b602060405180830381855afa158015612bc7573d5f5f3e3d5ffd5b5050506040513d601f19601f82011682018060405250810190612bea9190613732565b9350506001016129c6565b50612c036020860186613815565b90508114612c435760405162461bcd60e51b815260206004820152600d60248201526c24b73b30b634b210383937b7b360991b60448201526064016107ed565b509095945050505050565b508054612c5a906135a2565b5f825580601f10612c69575050565b601f0160209004905f5260205f2090810190612c859190612c88565b50565b5b80821115612c9c575f8155600101612c89565b5090565b634e487b7160e01b5f52604160045260245ffd5b5f82601f830112612cc3575f5ffd5b81356001600160401b03811115612cdc57612cdc612ca0565b8060051b604051601f19603f83011681018181106001600160401b0382111715612d0857612d08612ca0565b604052918252602081850181019290810186841115612d25575f5ffd5b6020860192505b83831015612d44578235815260209283019201612d2c565b5095945050505050565b806101008101831015612d5f575f5ffd5b92915050565b8060608101831015612d5f575f5ffd5b5f5f5f5f5f5f6101e08789031215612d8b575f5ffd5b863595506020870135945060408701356001600160401b03811115612dae575f5ffd5b612dba89828a01612cb4565b94505060608701359250612dd18860808901612d4e565b9150612de1886101808901612d65565b90509295509295509295565b5f60208284031215612dfd575f5ffd5b5035919050565b5f5f5f6101808486031215612e17575f5ffd5b83359250612e288560208601612d4e565b9150612e38856101208601612d65565b90509250925092565b5f5f83601f840112612e51575f5ffd5b5081356001600160401b03811115612e67575f5ffd5b6020830191508360208260051b8501011115612e81575f5ffd5b9250929050565b5f5f5f5f5f5f5f5f5f6101808a8c031215612ea1575f5ffd5b89356001600160401b03811115612eb6575f5ffd5b612ec28c828d01612e41565b909a5098505060208a01356001600160401b03811115612ee0575f5ffd5b612eec8c828d01612e41565b90985096505060408a01356001600160401b03811115612f0a575f5ffd5b612f168c828d01612e41565b9096509450612f2a90508b60608c01612d4e565b92506101608a01356001600160401b03811115612f45575f5ffd5b612f518c828d01612e41565b915080935050809150509295985092959850929598565b5f5f5f5f5f6101c08688031215612f7d575f5ffd5b853594506020860135935060408601356001600160401b03811115612fa0575f5ffd5b612fac88828901612cb4565b935050612fbc8760608801612d4e565b9150612fcc876101608801612d65565b90509295509295909350565b5f5f83601f840112612fe8575f5ffd5b5081356001600160401b03811115612ffe575f5ffd5b602083019150836020828501011115612e81575f5ffd5b5f5f60208385031215613026575f5ffd5b82356001600160401b0381111561303b575f5ffd5b61304785828601612fd8565b90969095509350505050565b80356001600160a01b0381168114613069575f5ffd5b919050565b5f6040828403121561307e575f5ffd5b50919050565b5f5f5f5f5f5f86880361012081121561309b575f5ffd5b873596506130ab60208901613053565b9550604088013594506080605f19820112156130c5575f5ffd5b5060608701925060e08701356001600160401b038111156130e4575f5ffd5b6130f089828a0161306e565b9250506101008701356001600160401b0381111561310c575f5ffd5b61311889828a0161306e565b9150509295509295509295565b5f60208284031215613135575f5ffd5b61313e82613053565b9392505050565b5f5f5f60608486031215613157575f5ffd5b833592506020840135915060408401356001600160401b0381111561317a575f5ffd5b61318686828701612cb4565b9150509250925092565b5f5f5f604084860312156131a2575f5ffd5b8335925060208401356001600160401b038111156131be575f5ffd5b6131ca86828701612fd8565b9497909650939450505050565b5f5f5f5f6101a085870312156131eb575f5ffd5b84359350602085013592506132038660408701612d4e565b9150613213866101408701612d65565b905092959194509250565b5f602082016020835280845180835260408501915060408160051b8601019250602086015f5b8281101561329657603f19878603018452815180518087528060208301602089015e5f602082890101526020601f19601f83011688010196505050602082019150602084019350600181019050613244565b50929695505050505050565b5f5f5f5f608085870312156132b5575f5ffd5b843593506132c560208601613053565b92506040850135915060608501356001600160401b03811681146132e7575f5ffd5b939692955090935050565b5f5f5f60608486031215613304575f5ffd5b61330d84613053565b95602085013595506040909401359392505050565b6020808252601a908201527f426174636820616363657074616e636520697320706175736564000000000000604082015260600190565b6020808252601b908201527f496e76616c696420626174636820636f6e66696775726174696f6e0000000000604082015260600190565b634e487b7160e01b5f52601260045260245ffd5b5f826133b2576133b2613390565b500690565b634e487b7160e01b5f52603260045260245ffd5b6020808252602d908201527f50726f6f6620646f6573206e6f742073746172742066726f6d20746865206c6160408201526c1cdd081cdd185d19481c9bdbdd609a1b606082015260800190565b60208082526023908201527f50726f6f6620646f6573206e6f74206d617463682074686520737461746520726040820152621bdbdd60ea1b606082015260800190565b61016081016101008483376060836101008401379392505050565b6020808252602f908201527f50726f6f6620646f6573206e6f742073746172742066726f6d2074686520706160408201526e1c995b9d081cdd185d19481c9bdbdd608a1b606082015260800190565b634e487b7160e01b5f52601160045260245ffd5b8082028115828204841417612d5f57612d5f6134c5565b80820180821115612d5f57612d5f6134c5565b610100848237610120610100820181905281018290525f6001600160fb1b0383111561352d575f5ffd5b8260051b80856101408501379190910161014001949350505050565b81810381811115612d5f57612d5f6134c5565b6020808252601a908201527f43616c6c6572206973206e6f742074686520677561726469616e000000000000604082015260600190565b818382375f9101908152919050565b600181811c908216806135b657607f821691505b60208210810361307e57634e487b7160e01b5f52602260045260245ffd5b601f82111561361b57805f5260205f20601f840160051c810160208510156135f95750805b601f840160051c820191505b81811015613618575f8155600101613605565b50505b505050565b81516001600160401b0381111561363957613639612ca0565b61364d8161364784546135a2565b846135d4565b6020601f82116001811461367f575f83156136685750848201515b5f19600385901b1c1916600184901b178455613618565b5f84815260208120601f198516915b828110156136ae578785015182556020948501946001909201910161368e565b50848210156136cb57868401515f19600387901b60f8161c191681555b50505050600190811b01905550565b634e487b7160e01b5f52603160045260245ffd5b60208152816020820152818360408301375f818301604090810191909152601f909201601f19160101919050565b5f82518060208501845e5f920191825250919050565b5f60208284031215613742575f5ffd5b5051919050565b6001600160401b0383111561376057613760612ca0565b6137748361376e83546135a2565b836135d4565b5f601f8411600181146137a5575f851561378e5750838201355b5f19600387901b1c1916600186901b178355613618565b5f83815260208120601f198716915b828110156137d457868501358255602094850194600190920191016137b4565b50868210156137f0575f1960f88860031b161c19848701351681555b505060018560011b0183555050505050565b5f8261381057613810613390565b500490565b5f5f8335601e1984360301811261382a575f5ffd5b8301803591506001600160401b03821115613843575f5ffd5b6020019150600581901b3603821315612e81575f5ffd5b5f6001820161386b5761386b6134c5565b506001019056fe30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001f525a8d9efdab7ad1c64f5f012282ae061b163a87f66093d734e25f5d600d50da2646970667358221220cf9f9c662bd52ae78f5bcd96cc64a2ff9664d1c8e9447d9e398a4b5ac3c552e664736f6c634300081e0033"
 
 // ZKRollup is an auto generated Go binding around an Ethereum contract.
 type ZKRollup struct {
@@ -69,9 +72,210 @@ func bindZKRollup(address common.Address, caller bind.ContractCaller, transactor
 	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
 }
 
-// SubmitBatch is a paid mutator transaction binding the contract method 0x8f1d3776.
-func (_ZKRollup *ZKRollupTransactor) SubmitBatch(opts *bind.TransactOpts, batchNumber *big.Int, stateRoot [32]byte, txHashes [][32]byte, proof []byte) (*types.Transaction, error) {
-	return _ZKRollup.contract.Transact(opts, "submitBatch", batchNumber, stateRoot, txHashes, proof)
+// SubmitBatch is a paid mutator transaction binding the contract method 0x3c3f0499.
+func (_ZKRollup *ZKRollupTransactor) SubmitBatch(opts *bind.TransactOpts, batchNumber *big.Int, stateRoot [32]byte, txHashes [][32]byte, proof [8]*big.Int, publicInputs [3]*big.Int) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "submitBatch", batchNumber, stateRoot, txHashes, proof, publicInputs)
+}
+
+// SubmitBatchData is a paid mutator transaction binding the contract method 0xa3637810.
+func (_ZKRollup *ZKRollupTransactor) SubmitBatchData(opts *bind.TransactOpts, batchNumber *big.Int, stateRoot [32]byte, txHashes [][32]byte) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "submitBatchData", batchNumber, stateRoot, txHashes)
+}
+
+// ProveBatch is a paid mutator transaction binding the contract method 0x34ed505d.
+func (_ZKRollup *ZKRollupTransactor) ProveBatch(opts *bind.TransactOpts, batchNumber *big.Int, proof [8]*big.Int, publicInputs [3]*big.Int) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "proveBatch", batchNumber, proof, publicInputs)
+}
+
+// SubmitBatchWithEpoch is a paid mutator transaction binding the contract method 0x200ba30b.
+func (_ZKRollup *ZKRollupTransactor) SubmitBatchWithEpoch(opts *bind.TransactOpts, batchNumber *big.Int, stateRoot [32]byte, txHashes [][32]byte, epoch *big.Int, proof [8]*big.Int, publicInputs [3]*big.Int) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "submitBatchWithEpoch", batchNumber, stateRoot, txHashes, epoch, proof, publicInputs)
+}
+
+// ProveBatchWithEpoch is a paid mutator transaction binding the contract method 0xaf17ad47.
+func (_ZKRollup *ZKRollupTransactor) ProveBatchWithEpoch(opts *bind.TransactOpts, batchNumber *big.Int, epoch *big.Int, proof [8]*big.Int, publicInputs [3]*big.Int) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "proveBatchWithEpoch", batchNumber, epoch, proof, publicInputs)
+}
+
+// RegisterVerifier is a paid mutator transaction binding the contract method 0xed88dbed.
+func (_ZKRollup *ZKRollupTransactor) RegisterVerifier(opts *bind.TransactOpts, epoch *big.Int, verifierAddress common.Address, inputSchema [32]byte, circuitVersion uint64) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "registerVerifier", epoch, verifierAddress, inputSchema, circuitVersion)
+}
+
+// VerifierRegistry is a free data retrieval call binding the contract method 0x9a336ff9.
+func (_ZKRollup *ZKRollupCaller) VerifierRegistry(opts *bind.CallOpts, arg0 *big.Int) (struct {
+	Verifier       common.Address
+	InputSchema    [32]byte
+	CircuitVersion uint64
+	Registered     bool
+}, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "verifierRegistry", arg0)
+
+	outstruct := new(struct {
+		Verifier       common.Address
+		InputSchema    [32]byte
+		CircuitVersion uint64
+		Registered     bool
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.Verifier = *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+	outstruct.InputSchema = *abi.ConvertType(out[1], new([32]byte)).(*[32]byte)
+	outstruct.CircuitVersion = *abi.ConvertType(out[2], new(uint64)).(*uint64)
+	outstruct.Registered = *abi.ConvertType(out[3], new(bool)).(*bool)
+
+	return *outstruct, err
+}
+
+// LatestEpoch is a free data retrieval call binding the contract method 0x9cb118bf.
+func (_ZKRollup *ZKRollupCaller) LatestEpoch(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "latestEpoch")
+	if err != nil {
+		return new(big.Int), err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), err
+}
+
+// HasRegisteredVerifier is a free data retrieval call binding the contract method 0x182172b0.
+func (_ZKRollup *ZKRollupCaller) HasRegisteredVerifier(opts *bind.CallOpts) (bool, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "hasRegisteredVerifier")
+	if err != nil {
+		return false, err
+	}
+	return *abi.ConvertType(out[0], new(bool)).(*bool), err
+}
+
+// BatchEpoch is a free data retrieval call binding the contract method 0xb3bf2305.
+func (_ZKRollup *ZKRollupCaller) BatchEpoch(opts *bind.CallOpts, arg0 *big.Int) (*big.Int, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "batchEpoch", arg0)
+	if err != nil {
+		return new(big.Int), err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), err
+}
+
+// SetAggregateVerifier is a paid mutator transaction binding the contract method 0xedfcb726.
+func (_ZKRollup *ZKRollupTransactor) SetAggregateVerifier(opts *bind.TransactOpts, verifierAddress common.Address, inputSchema [32]byte, batchCount *big.Int) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "setAggregateVerifier", verifierAddress, inputSchema, batchCount)
+}
+
+// SubmitAggregatedBatches is a paid mutator transaction binding the contract method 0x3c3b9828.
+func (_ZKRollup *ZKRollupTransactor) SubmitAggregatedBatches(opts *bind.TransactOpts, batchNumbers []*big.Int, stateRoots [][32]byte, txHashes [][][32]byte, proof [8]*big.Int, publicInputs []*big.Int) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "submitAggregatedBatches", batchNumbers, stateRoots, txHashes, proof, publicInputs)
+}
+
+// AggregateVerifier is a free data retrieval call binding the contract method 0xc072bd88.
+func (_ZKRollup *ZKRollupCaller) AggregateVerifier(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "aggregateVerifier")
+	if err != nil {
+		return common.Address{}, err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), err
+}
+
+// AggregateInputSchema is a free data retrieval call binding the contract method 0x494ce991.
+func (_ZKRollup *ZKRollupCaller) AggregateInputSchema(opts *bind.CallOpts) ([32]byte, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "aggregateInputSchema")
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return *abi.ConvertType(out[0], new([32]byte)).(*[32]byte), err
+}
+
+// AggregateBatchCount is a free data retrieval call binding the contract method 0xf0147e95.
+func (_ZKRollup *ZKRollupCaller) AggregateBatchCount(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "aggregateBatchCount")
+	if err != nil {
+		return new(big.Int), err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), err
+}
+
+// PublishBatchData is a paid mutator transaction binding the contract method 0xa84eb569.
+func (_ZKRollup *ZKRollupTransactor) PublishBatchData(opts *bind.TransactOpts, batchNumber *big.Int, data []byte) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "publishBatchData", batchNumber, data)
+}
+
+// PublishBatchBlob is a paid mutator transaction binding the contract method 0x2f88a737.
+func (_ZKRollup *ZKRollupTransactor) PublishBatchBlob(opts *bind.TransactOpts, batchNumber *big.Int) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "publishBatchBlob", batchNumber)
+}
+
+// BatchDataHash is a free data retrieval call binding the contract method 0x5b803403.
+func (_ZKRollup *ZKRollupCaller) BatchDataHash(opts *bind.CallOpts, arg0 *big.Int) ([32]byte, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "batchDataHash", arg0)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return *abi.ConvertType(out[0], new([32]byte)).(*[32]byte), err
+}
+
+// Batches is a free data retrieval call binding the contract method 0xb32c4d8d.
+func (_ZKRollup *ZKRollupCaller) Batches(opts *bind.CallOpts, arg0 *big.Int) (struct {
+	StateRoot [32]byte
+	Verified  bool
+	Timestamp *big.Int
+	L1Block   *big.Int
+}, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "batches", arg0)
+
+	outstruct := new(struct {
+		StateRoot [32]byte
+		Verified  bool
+		Timestamp *big.Int
+		L1Block   *big.Int
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.StateRoot = *abi.ConvertType(out[0], new([32]byte)).(*[32]byte)
+	outstruct.Verified = *abi.ConvertType(out[1], new(bool)).(*bool)
+	outstruct.Timestamp = *abi.ConvertType(out[2], new(*big.Int)).(**big.Int)
+	outstruct.L1Block = *abi.ConvertType(out[3], new(*big.Int)).(**big.Int)
+
+	return *outstruct, err
+}
+
+// LastStateRoot is a free data retrieval call binding the contract method 0xb70de0d9.
+func (_ZKRollup *ZKRollupCaller) LastStateRoot(opts *bind.CallOpts) ([32]byte, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "lastStateRoot")
+	if err != nil {
+		return *new([32]byte), err
+	}
+	return *abi.ConvertType(out[0], new([32]byte)).(*[32]byte), err
+}
+
+// PublicInputSchema is a free data retrieval call binding the contract method 0x96d7ce66.
+func (_ZKRollup *ZKRollupCaller) PublicInputSchema(opts *bind.CallOpts) ([32]byte, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "publicInputSchema")
+	if err != nil {
+		return *new([32]byte), err
+	}
+	return *abi.ConvertType(out[0], new([32]byte)).(*[32]byte), err
+}
+
+// Verifier is a free data retrieval call binding the contract method 0x2b7ac3f3.
+func (_ZKRollup *ZKRollupCaller) Verifier(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "verifier")
+	if err != nil {
+		return *new(common.Address), err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), err
 }
 
 // VerifyBatch is a free data retrieval call binding the contract method 0x5e8a791d.
@@ -84,16 +288,255 @@ func (_ZKRollup *ZKRollupCaller) VerifyBatch(opts *bind.CallOpts, batchNumber *b
 	return out[0].(bool), err
 }
 
+// CurrentBatchNumber is a free data retrieval call binding the contract method 0xf48fa80b.
+func (_ZKRollup *ZKRollupCaller) CurrentBatchNumber(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "currentBatchNumber")
+	if err != nil {
+		return new(big.Int), err
+	}
+	return out[0].(*big.Int), err
+}
+
 // DeployZKRollup deploys a new Ethereum contract, binding an instance of ZKRollup to it.
-func DeployZKRollup(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *ZKRollup, error) {
+func DeployZKRollup(auth *bind.TransactOpts, backend bind.ContractBackend, verifierAddress common.Address, inputSchema [32]byte) (common.Address, *types.Transaction, *ZKRollup, error) {
 	parsed, err := abi.JSON(strings.NewReader(ZKRollupABI))
 	if err != nil {
 		return common.Address{}, nil, nil, err
 	}
 
-	address, tx, contract, err := bind.DeployContract(auth, parsed, common.FromHex("608060405234801561001057600080fd5b5061057f806100206000396000f3fe608060405234801561001057600080fd5b506004361061004c5760003560e01c80635e8a791d146100515780638f1d3776146100815780639fa6a6e3146100a1578063c5b1d9aa146100bf575b600080fd5b61006b60048036038101906100669190610341565b6100dd565b60405161007891906103a3565b60405180910390f35b61009b600480360381019061009691906103be565b610132565b005b6100a9610261565b6040516100b6919061046a565b60405180910390f35b6100d960048036038101906100d49190610341565b610267565b005b60006001600083815260200190815260200160002060010160009054906101000a900460ff169050919050565b6000805490506000811161017a576040517f08c379a000000000000000000000000000000000000000000000000000000000815260040161017190610502565b60405180910390fd5b60008111801561018c5750600081115b6101cb576040517f08c379a00000000000000000000000000000000000000000000000000000000081526004016101c290610502565b60405180910390fd5b60006001600085815260200190815260200160002060010160009054906101000a900460ff1690506101fd85858585610267565b7f5a978f4f6ea5d4a5a575f1e07f74f7e89a1367e9e09d3f7e9c93237c10c2d46b8583604051610230929190610522565b60405180910390a1505050505050565b60005481565b6040518060600160405280838152602001600115158152602001428152506001600085815260200190815260200160002060008201518160000155602082015181600101600a81111561028f577f4e487b7100000000000000000000000000000000000000000000000000000000600052602160045260246000fd5b60ff1660ff16815260200160408201518160020155905050600160008082825461029a9190610551565b925050819055507f33a88a5e8eeccf39bfcf2b7574032a7564fd793bbaa33371cdb9e5f2f9aef9f2848360405161032f929190610522565b60405180910390a150505050565b60008135905061034b81610565565b92915050565b60006020828403121561036357600080fd5b60006103718482850161033c565b91505092915050565b61038381610485565b82525050565b61039281610491565b82525050565b60006020820190506103ad6000830184610389565b92915050565b600080600080608085870312156103d457600080fd5b60006103e28782880161033c565b94505060206103f38782880161033c565b935050604061040487828801610341565b925050606061041587828801610341565b91505092959194509250565b61042a8161049d565b82525050565b6000610449601c836104a7565b9150610454826104b8565b602082019050919050565b61046881610491565b82525050565b60006020820190506104836000830184610421565b92915050565b60008115159050919050565b6000819050919050565b6000819050919050565b600082825260208201905092915050565b7f496e76616c696420626174636820636f6e66696775726174696f6e000000000060008201525060006104e3826104a7565b91506104ef836104b8565b602082019050919050565b6000602082019050818103600083015261051381610430565b9050919050565b600060408201905061052f600083018561045f565b61053c6020830184610421565b9392505050565b6000819050919050565b600061055c82610542565b915061056783610542565b9250828201905080821115610565576105646104d6565b5b92915050565b600081905091905056fea26469706673582212209a9b9c9a9b9c9a9b9c9a9b9c9a9b9c9a9b9c9a9b9c9a9b9c9a9b9c9a9b9c9a9b64736f6c63430008070033"), nil)
+	address, tx, contract, err := bind.DeployContract(auth, parsed, common.FromHex(ZKRollupBin), backend, verifierAddress, inputSchema)
 	if err != nil {
 		return common.Address{}, nil, nil, err
 	}
 	return address, tx, &ZKRollup{ZKRollupCaller: ZKRollupCaller{contract: contract}, ZKRollupTransactor: ZKRollupTransactor{contract: contract}, ZKRollupFilterer: ZKRollupFilterer{contract: contract}}, nil
 }
+
+// ZKRollupBridgeAccount is an auto generated low-level Go binding around an user-defined struct.
+type ZKRollupBridgeAccount struct {
+	Balance     *big.Int
+	Nonce       *big.Int
+	StorageRoot [32]byte
+	CodeHash    [32]byte
+}
+
+// ZKRollupMerkleProof is an auto generated low-level Go binding around an user-defined struct.
+type ZKRollupMerkleProof struct {
+	Bitmap   [32]byte
+	Siblings [][32]byte
+}
+
+// ClaimWithdrawal is a paid mutator transaction binding the contract method 0x5fb6b66f.
+func (_ZKRollup *ZKRollupTransactor) ClaimWithdrawal(opts *bind.TransactOpts, batchNumber *big.Int, account common.Address, amount *big.Int, bridge ZKRollupBridgeAccount, accountProof ZKRollupMerkleProof, storageProof ZKRollupMerkleProof) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "claimWithdrawal", batchNumber, account, amount, bridge, accountProof, storageProof)
+}
+
+// Withdrawn is a free data retrieval call binding the contract method 0x6ef61092.
+func (_ZKRollup *ZKRollupCaller) Withdrawn(opts *bind.CallOpts, arg0 common.Address) (*big.Int, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "withdrawn", arg0)
+	if err != nil {
+		return new(big.Int), err
+	}
+	return out[0].(*big.Int), err
+}
+
+// Guardian is a free data retrieval call binding the contract method 0x452a9320.
+func (_ZKRollup *ZKRollupCaller) Guardian(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "guardian")
+	if err != nil {
+		return *new(common.Address), err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), err
+}
+
+// Paused is a free data retrieval call binding the contract method 0x5c975abb.
+func (_ZKRollup *ZKRollupCaller) Paused(opts *bind.CallOpts) (bool, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "paused")
+	if err != nil {
+		return false, err
+	}
+	return out[0].(bool), err
+}
+
+// BridgeFrozen is a free data retrieval call binding the contract method 0x45fb3b45.
+func (_ZKRollup *ZKRollupCaller) BridgeFrozen(opts *bind.CallOpts) (bool, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "bridgeFrozen")
+	if err != nil {
+		return false, err
+	}
+	return out[0].(bool), err
+}
+
+// Pause is a paid mutator transaction binding the contract method 0x8456cb59.
+func (_ZKRollup *ZKRollupTransactor) Pause(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "pause")
+}
+
+// Unpause is a paid mutator transaction binding the contract method 0x3f4ba83a.
+func (_ZKRollup *ZKRollupTransactor) Unpause(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "unpause")
+}
+
+// FreezeBridge is a paid mutator transaction binding the contract method 0x49e74457.
+func (_ZKRollup *ZKRollupTransactor) FreezeBridge(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "freezeBridge")
+}
+
+// UnfreezeBridge is a paid mutator transaction binding the contract method 0x45c5de9a.
+func (_ZKRollup *ZKRollupTransactor) UnfreezeBridge(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "unfreezeBridge")
+}
+
+// SetGuardian is a paid mutator transaction binding the contract method 0x8a0dac4a.
+func (_ZKRollup *ZKRollupTransactor) SetGuardian(opts *bind.TransactOpts, newGuardian common.Address) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "setGuardian", newGuardian)
+}
+
+// AddValidator is a paid mutator transaction binding the contract method 0xb5da04f5.
+func (_ZKRollup *ZKRollupTransactor) AddValidator(opts *bind.TransactOpts, peerId string) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "addValidator", peerId)
+}
+
+// RemoveValidator is a paid mutator transaction binding the contract method 0x3f52cb34.
+func (_ZKRollup *ZKRollupTransactor) RemoveValidator(opts *bind.TransactOpts, peerId string) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "removeValidator", peerId)
+}
+
+// GetValidators is a free data retrieval call binding the contract method 0xb7ab4db5.
+func (_ZKRollup *ZKRollupCaller) GetValidators(opts *bind.CallOpts) ([]string, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "getValidators")
+	if err != nil {
+		return *new([]string), err
+	}
+	return *abi.ConvertType(out[0], new([]string)).(*[]string), err
+}
+
+// DepositCount is a free data retrieval call binding the contract method 0x2dfdf0b5.
+func (_ZKRollup *ZKRollupCaller) DepositCount(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _ZKRollup.contract.Call(opts, &out, "depositCount")
+	if err != nil {
+		return new(big.Int), err
+	}
+	return out[0].(*big.Int), err
+}
+
+// Deposit is a paid mutator transaction binding the contract method 0xf340fa01.
+func (_ZKRollup *ZKRollupTransactor) Deposit(opts *bind.TransactOpts, l2Recipient common.Address) (*types.Transaction, error) {
+	return _ZKRollup.contract.Transact(opts, "deposit", l2Recipient)
+}
+
+// ZKRollupDepositIterator is returned from FilterDeposit and is used to iterate over the raw logs and unpacked data for Deposit events raised by the ZKRollup contract.
+type ZKRollupDepositIterator struct {
+	Event *ZKRollupDeposit // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *ZKRollupDepositIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(ZKRollupDeposit)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(ZKRollupDeposit)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *ZKRollupDepositIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *ZKRollupDepositIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// ZKRollupDeposit represents a Deposit event raised by the ZKRollup contract.
+type ZKRollupDeposit struct {
+	From         common.Address
+	L2Recipient  common.Address
+	Amount       *big.Int
+	DepositNonce *big.Int
+	Raw          types.Log // Blockchain specific contextual infos
+}
+
+// FilterDeposit is a free log retrieval operation binding the contract event 0xdcbc1c05240f31ff3ad067ef1ee35ce4997762752e3a095284754544f4c709d7.
+func (_ZKRollup *ZKRollupFilterer) FilterDeposit(opts *bind.FilterOpts, from []common.Address, l2Recipient []common.Address, depositNonce []*big.Int) (*ZKRollupDepositIterator, error) {
+
+	var fromRule []interface{}
+	for _, fromItem := range from {
+		fromRule = append(fromRule, fromItem)
+	}
+	var l2RecipientRule []interface{}
+	for _, l2RecipientItem := range l2Recipient {
+		l2RecipientRule = append(l2RecipientRule, l2RecipientItem)
+	}
+
+	var depositNonceRule []interface{}
+	for _, depositNonceItem := range depositNonce {
+		depositNonceRule = append(depositNonceRule, depositNonceItem)
+	}
+
+	logs, sub, err := _ZKRollup.contract.FilterLogs(opts, "Deposit", fromRule, l2RecipientRule, depositNonceRule)
+	if err != nil {
+		return nil, err
+	}
+	return &ZKRollupDepositIterator{contract: _ZKRollup.contract, event: "Deposit", logs: logs, sub: sub}, nil
+}
+
+// ParseDeposit is a log parse operation binding the contract event 0xdcbc1c05240f31ff3ad067ef1ee35ce4997762752e3a095284754544f4c709d7.
+func (_ZKRollup *ZKRollupFilterer) ParseDeposit(log types.Log) (*ZKRollupDeposit, error) {
+	event := new(ZKRollupDeposit)
+	if err := _ZKRollup.contract.UnpackLog(event, "Deposit", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
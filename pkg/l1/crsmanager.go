@@ -10,6 +10,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// CRSManager wraps the CRSManager contract, which records the participants, commitments and
+// contributions of each CRS round
 type CRSManager struct {
 	address  common.Address
 	client   bind.ContractBackend
@@ -35,6 +37,16 @@ func NewCRSManager(address common.Address, client bind.ContractBackend) (*CRSMan
 	}, nil
 }
 
+// CRSManager binds the CRSManager contract at address on the client's L1 connection
+func (c *Client) CRSManager(address common.Address) (*CRSManager, error) {
+	return NewCRSManager(address, c.ethClient)
+}
+
+// TransactOpts returns options for a transaction signed by the client's signer
+func (c *Client) TransactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	return c.getTransactOpts(ctx)
+}
+
 // Register registers the caller for the current CRS round
 func (c *CRSManager) Register(auth *bind.TransactOpts) error {
 	_, err := c.transact.Register(auth)
@@ -105,3 +117,30 @@ func (c *CRSManager) GetCurrentCRS(ctx context.Context) ([]byte, error) {
 	crs, err := c.caller.GetCurrentCRS(&bind.CallOpts{Context: ctx})
 	return crs, err
 }
+
+// CurrentRound returns the round the contract accepts registrations and contributions for
+func (c *CRSManager) CurrentRound(ctx context.Context) (uint64, error) {
+	round, err := c.caller.CurrentRound(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return 0, err
+	}
+	return round.Uint64(), nil
+}
+
+// ContributorIndex returns the number of contributions made in the current round
+func (c *CRSManager) ContributorIndex(ctx context.Context) (uint64, error) {
+	idx, err := c.caller.GetCurrentContributorIdx(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return 0, err
+	}
+	return idx.Uint64(), nil
+}
+
+// HasCommitted reports whether addr submitted a commitment in round
+func (c *CRSManager) HasCommitted(ctx context.Context, round uint64, addr common.Address) (bool, error) {
+	commitment, err := c.caller.Commitments(&bind.CallOpts{Context: ctx}, new(big.Int).SetUint64(round), addr)
+	if err != nil {
+		return false, err
+	}
+	return commitment.Submitted, nil
+}
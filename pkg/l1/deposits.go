@@ -0,0 +1,187 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+// Deposit is ETH locked in the L1 contract to be credited on L2
+type Deposit struct {
+	Nonce     uint64
+	From      common.Address // L1 sender
+	Recipient common.Address // L2 account credited
+	Amount    *big.Int
+	L1Block   uint64
+	L1TxHash  common.Hash
+}
+
+// Deposit locks amount wei in the rollup contract for recipient on L2
+func (c *Client) Deposit(ctx context.Context, recipient common.Address, amount *big.Int) (common.Hash, error) {
+	if c.rollupContract == nil {
+		return common.Hash{}, fmt.Errorf("rollup contract not initialized")
+	}
+
+	auth, err := c.getTransactOpts(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	auth.Value = amount
+	auth.GasLimit = 100000
+
+	tx, err := c.rollupContract.Deposit(auth, recipient)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to deposit: %v", err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Str("recipient", recipient.Hex()).Str("amount", amount.String()).Msg("Submitted deposit to L1")
+	return tx.Hash(), nil
+}
+
+// DepositCount returns the number of deposits made on L1
+func (c *Client) DepositCount(ctx context.Context) (uint64, error) {
+	if c.rollupContract == nil {
+		return 0, fmt.Errorf("rollup contract not initialized")
+	}
+
+	count, err := c.rollupContract.DepositCount(&bind.CallOpts{
+		Context: ctx,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deposit count: %v", err)
+	}
+
+	return count.Uint64(), nil
+}
+
+// FetchDeposits returns the deposits made in the block range [from, to]
+func (c *Client) FetchDeposits(ctx context.Context, from, to uint64) ([]Deposit, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	it, err := c.rollupContract.FilterDeposit(&bind.FilterOpts{
+		Start:   from,
+		End:     &to,
+		Context: ctx,
+	}, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter deposits: %v", err)
+	}
+	defer it.Close()
+
+	var deposits []Deposit
+	for it.Next() {
+		deposits = append(deposits, Deposit{
+			Nonce:     it.Event.DepositNonce.Uint64(),
+			From:      it.Event.From,
+			Recipient: it.Event.L2Recipient,
+			Amount:    it.Event.Amount,
+			L1Block:   it.Event.Raw.BlockNumber,
+			L1TxHash:  it.Event.Raw.TxHash,
+		})
+	}
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("failed to read deposits: %v", err)
+	}
+
+	return deposits, nil
+}
+
+// BlockNumber returns the latest L1 block number
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	number, err := c.ethClient.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number: %v", err)
+	}
+	return number, nil
+}
+
+// DepositWatcher polls the rollup contract for deposits buried under enough confirmations
+// and delivers them in nonce order, each at most once
+type DepositWatcher struct {
+	client        *Client
+	nextBlock     uint64
+	nextNonce     uint64
+	confirmations uint64
+	pollInterval  time.Duration
+	deposits      chan Deposit
+}
+
+// NewDepositWatcher creates a watcher that scans from startBlock and skips deposits
+// with a nonce below nextNonce, i.e. those already credited on L2
+func NewDepositWatcher(client *Client, startBlock, nextNonce, confirmations uint64, pollInterval time.Duration) *DepositWatcher {
+	return &DepositWatcher{
+		client:        client,
+		nextBlock:     startBlock,
+		nextNonce:     nextNonce,
+		confirmations: confirmations,
+		pollInterval:  pollInterval,
+		deposits:      make(chan Deposit, 100),
+	}
+}
+
+// Deposits returns the channel receiving confirmed deposits
+func (w *DepositWatcher) Deposits() <-chan Deposit {
+	return w.deposits
+}
+
+// Run polls L1 until ctx is cancelled
+func (w *DepositWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to poll L1 deposits")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *DepositWatcher) poll(ctx context.Context) error {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if head < w.confirmations || head-w.confirmations < w.nextBlock {
+		return nil
+	}
+	to := head - w.confirmations
+
+	deposits, err := w.client.FetchDeposits(ctx, w.nextBlock, to)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deposits {
+		// Events are returned in chain order, anything below nextNonce was delivered before
+		if d.Nonce < w.nextNonce {
+			continue
+		}
+		if d.Nonce > w.nextNonce {
+			return fmt.Errorf("missing deposit %d, next seen is %d", w.nextNonce, d.Nonce)
+		}
+
+		select {
+		case w.deposits <- d:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		w.nextNonce++
+		log.Info().Uint64("deposit_nonce", d.Nonce).Str("recipient", d.Recipient.Hex()).Str("amount", d.Amount.String()).Msg("Observed L1 deposit")
+	}
+
+	w.nextBlock = to + 1
+	return nil
+}
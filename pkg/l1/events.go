@@ -0,0 +1,371 @@
+package l1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/l1/contracts"
+)
+
+// maxWatchBackoff caps the wait before resubscribing to batch events
+const maxWatchBackoff = time.Minute
+
+// BatchEventKind is what a BatchEvent reports about a batch
+type BatchEventKind int
+
+const (
+	EventBatchSubmitted BatchEventKind = iota // Accepted by the contract
+	EventBatchVerified                        // Stored, with or without a verified proof
+	EventBatchFinalized                       // Verification buried under the finality depth
+)
+
+// String returns the name of the kind
+func (k BatchEventKind) String() string {
+	switch k {
+	case EventBatchSubmitted:
+		return "submitted"
+	case EventBatchVerified:
+		return "verified"
+	case EventBatchFinalized:
+		return "finalized"
+	default:
+		return "unknown"
+	}
+}
+
+// BatchEvent is the progress of a rollup batch on L1, read from the BatchSubmitted and
+// BatchVerified events of the rollup contract
+type BatchEvent struct {
+	Kind        BatchEventKind
+	BatchNumber uint64      // Rollup numbering, from 0
+	StateRoot   common.Hash // Of submitted batches
+	Verified    bool        // Whether a verified batch's proof was checked, false for data only
+	L1Block     uint64      // Block of the event, for finalized batches the block storing the batch
+	L1TxHash    common.Hash
+}
+
+// batchEventQuery selects the BatchSubmitted and BatchVerified events of the rollup contract
+func (c *Client) batchEventQuery() (ethereum.FilterQuery, abi.ABI, error) {
+	if c.rollupContract == nil {
+		return ethereum.FilterQuery{}, abi.ABI{}, fmt.Errorf("rollup contract not initialized")
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(contracts.ZKRollupABI))
+	if err != nil {
+		return ethereum.FilterQuery{}, abi.ABI{}, fmt.Errorf("failed to parse rollup ABI: %v", err)
+	}
+	return ethereum.FilterQuery{
+		Addresses: []common.Address{c.rollupAddress},
+		Topics:    [][]common.Hash{{parsed.Events["BatchSubmitted"].ID, parsed.Events["BatchVerified"].ID}},
+	}, parsed, nil
+}
+
+// FilterBatchEvents returns the batch events emitted in the block range [from, to] in
+// chain order
+func (c *Client) FilterBatchEvents(ctx context.Context, from, to uint64) ([]types.Log, error) {
+	query, _, err := c.batchEventQuery()
+	if err != nil {
+		return nil, err
+	}
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(to)
+
+	logs, err := c.ethClient.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter batch events: %v", err)
+	}
+	return logs, nil
+}
+
+// SubscribeBatchEvents streams batch events as they are emitted. Endpoints without
+// subscriptions, such as plain HTTP, fail with rpc.ErrNotificationsUnsupported.
+func (c *Client) SubscribeBatchEvents(ctx context.Context, ch chan<- types.Log) (ethereum.Subscription, error) {
+	query, _, err := c.batchEventQuery()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := c.ethClient.SubscribeFilterLogs(ctx, query, ch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to batch events: %w", err)
+	}
+	return sub, nil
+}
+
+// parseBatchEvent decodes a log returned by FilterBatchEvents or SubscribeBatchEvents
+func parseBatchEvent(parsed abi.ABI, l types.Log) (*BatchEvent, error) {
+	if len(l.Topics) != 3 {
+		return nil, fmt.Errorf("batch event with %d topics", len(l.Topics))
+	}
+	number := l.Topics[1].Big()
+	if number.Sign() == 0 || !number.IsUint64() {
+		return nil, fmt.Errorf("batch event for invalid batch %s", number)
+	}
+
+	event := &BatchEvent{
+		BatchNumber: number.Uint64() - 1,
+		L1Block:     l.BlockNumber,
+		L1TxHash:    l.TxHash,
+	}
+	switch l.Topics[0] {
+	case parsed.Events["BatchSubmitted"].ID:
+		event.Kind = EventBatchSubmitted
+		event.StateRoot = l.Topics[2]
+	case parsed.Events["BatchVerified"].ID:
+		event.Kind = EventBatchVerified
+		event.Verified = l.Topics[2].Big().Sign() != 0
+	default:
+		return nil, fmt.Errorf("unknown batch event %s", l.Topics[0].Hex())
+	}
+	return event, nil
+}
+
+// logPosition orders logs within the chain
+type logPosition struct {
+	block uint64
+	index uint
+}
+
+func (p logPosition) after(q logPosition) bool {
+	return p.block > q.block || (p.block == q.block && p.index > q.index)
+}
+
+// BatchWatcher follows the batches of the rollup contract. It subscribes to their events
+// when the L1 endpoint supports it, catching up on missed blocks and resubscribing with
+// backoff when the subscription drops, and otherwise polls. Every event is delivered once
+// in chain order, and a verified batch is reported finalized once its verification is
+// buried under the client's finality depth.
+type BatchWatcher struct {
+	client       *Client
+	abi          abi.ABI
+	nextBlock    uint64
+	last         logPosition // Of the latest delivered event, valid when delivered is set
+	delivered    bool
+	pollInterval time.Duration
+	events       chan BatchEvent
+
+	// Verified batches awaiting finality, by the block of their verification
+	verified map[uint64]uint64
+}
+
+// NewBatchWatcher creates a watcher that reads batch events from startBlock on
+func NewBatchWatcher(client *Client, startBlock uint64, pollInterval time.Duration) *BatchWatcher {
+	return &BatchWatcher{
+		client:       client,
+		nextBlock:    startBlock,
+		pollInterval: pollInterval,
+		events:       make(chan BatchEvent, 100),
+		verified:     make(map[uint64]uint64),
+	}
+}
+
+// Events returns the channel receiving batch events
+func (w *BatchWatcher) Events() <-chan BatchEvent {
+	return w.events
+}
+
+// Run follows L1 until ctx is cancelled
+func (w *BatchWatcher) Run(ctx context.Context) {
+	_, parsed, err := w.client.batchEventQuery()
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot watch L1 batch events")
+		return
+	}
+	w.abi = parsed
+
+	backoff := w.pollInterval
+	for {
+		established, err := w.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			log.Info().Msg("L1 endpoint does not support subscriptions, polling batch events")
+			w.pollLoop(ctx)
+			return
+		}
+
+		if established {
+			backoff = w.pollInterval
+		}
+		log.Warn().Err(err).Dur("retry_in", backoff).Msg("L1 batch event subscription failed")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWatchBackoff)
+	}
+}
+
+// subscribe follows batch events over a subscription until it fails. established reports
+// whether the subscription was set up.
+func (w *BatchWatcher) subscribe(ctx context.Context) (established bool, err error) {
+	logs := make(chan types.Log, 128)
+	sub, err := w.client.SubscribeBatchEvents(ctx, logs)
+	if err != nil {
+		return false, err
+	}
+	defer sub.Unsubscribe()
+
+	// Catch up on the blocks before the subscription, events it delivers again are skipped
+	if err := w.poll(ctx); err != nil {
+		return true, err
+	}
+
+	// Finality advances with the head, which the subscription does not report
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case err := <-sub.Err():
+			if err == nil {
+				err = fmt.Errorf("subscription closed")
+			}
+			return true, err
+		case l := <-logs:
+			if err := w.handleLog(ctx, l); err != nil {
+				return true, err
+			}
+		case <-ticker.C:
+			head, err := w.client.BlockNumber(ctx)
+			if err != nil {
+				return true, err
+			}
+			if err := w.finalize(ctx, head); err != nil {
+				return true, err
+			}
+		}
+	}
+}
+
+// pollLoop polls for batch events until ctx is cancelled
+func (w *BatchWatcher) pollLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to poll L1 batch events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll delivers the events up to the current head
+func (w *BatchWatcher) poll(ctx context.Context) error {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if head >= w.nextBlock {
+		logs, err := w.client.FilterBatchEvents(ctx, w.nextBlock, head)
+		if err != nil {
+			return err
+		}
+		for _, l := range logs {
+			if err := w.handleLog(ctx, l); err != nil {
+				return err
+			}
+		}
+		w.nextBlock = head + 1
+	}
+	return w.finalize(ctx, head)
+}
+
+// handleLog delivers the event of a log not delivered before. A verification reorged
+// out of the chain no longer counts towards finality.
+func (w *BatchWatcher) handleLog(ctx context.Context, l types.Log) error {
+	event, err := parseBatchEvent(w.abi, l)
+	if err != nil {
+		log.Warn().Err(err).Str("tx_hash", l.TxHash.Hex()).Msg("Skipping malformed L1 batch event")
+		return nil
+	}
+	if l.Removed {
+		if event.Kind == EventBatchVerified && w.verified[event.BatchNumber] == l.BlockNumber {
+			delete(w.verified, event.BatchNumber)
+		}
+		return nil
+	}
+
+	pos := logPosition{block: l.BlockNumber, index: l.Index}
+	if w.delivered && !pos.after(w.last) {
+		return nil
+	}
+	if err := w.deliver(ctx, *event); err != nil {
+		return err
+	}
+	w.last, w.delivered = pos, true
+	// Later events of the same block may still come, a catch-up rescans it
+	if l.BlockNumber > w.nextBlock {
+		w.nextBlock = l.BlockNumber
+	}
+
+	if event.Kind == EventBatchVerified && event.Verified {
+		w.verified[event.BatchNumber] = l.BlockNumber
+	}
+	return nil
+}
+
+// finalize reports the verified batches buried under the finality depth at head, in
+// batch order. The contract is asked before a batch is reported, polling does not see
+// verifications reorged out of the chain.
+func (w *BatchWatcher) finalize(ctx context.Context, head uint64) error {
+	var candidates []uint64
+	for n, block := range w.verified {
+		if head >= block && head-block >= w.client.finalityDepth {
+			candidates = append(candidates, n)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	for _, n := range candidates {
+		status, err := w.client.GetBatchStatus(ctx, n)
+		if err != nil {
+			return err
+		}
+		switch status.State {
+		case BatchFinalized:
+			if err := w.deliver(ctx, BatchEvent{Kind: EventBatchFinalized, BatchNumber: n, StateRoot: status.StateRoot, Verified: true, L1Block: status.L1Block}); err != nil {
+				return err
+			}
+			delete(w.verified, n)
+		case BatchVerified:
+			// Stored in a later block after a reorg
+			w.verified[n] = status.L1Block
+		default:
+			// Reorged out, a new verification is reported again
+			delete(w.verified, n)
+		}
+	}
+	return nil
+}
+
+func (w *BatchWatcher) deliver(ctx context.Context, event BatchEvent) error {
+	select {
+	case w.events <- event:
+		log.Debug().Uint64("batch_number", event.BatchNumber).Str("event", event.Kind.String()).Uint64("l1_block", event.L1Block).Msg("Observed L1 batch event")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,56 @@
+package l1
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/l1/contracts"
+)
+
+func TestParseBatchEvent(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(contracts.ZKRollupABI))
+	require.NoError(t, err)
+	root := common.HexToHash("0x1234")
+
+	// Contract batch numbers start at 1
+	submitted, err := parseBatchEvent(parsed, types.Log{
+		Topics:      []common.Hash{parsed.Events["BatchSubmitted"].ID, common.BigToHash(big.NewInt(5)), root},
+		BlockNumber: 10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, EventBatchSubmitted, submitted.Kind)
+	require.Equal(t, uint64(4), submitted.BatchNumber)
+	require.Equal(t, root, submitted.StateRoot)
+	require.Equal(t, uint64(10), submitted.L1Block)
+
+	verified, err := parseBatchEvent(parsed, types.Log{
+		Topics: []common.Hash{parsed.Events["BatchVerified"].ID, common.BigToHash(big.NewInt(5)), common.BigToHash(big.NewInt(1))},
+	})
+	require.NoError(t, err)
+	require.Equal(t, EventBatchVerified, verified.Kind)
+	require.True(t, verified.Verified)
+
+	dataOnly, err := parseBatchEvent(parsed, types.Log{
+		Topics: []common.Hash{parsed.Events["BatchVerified"].ID, common.BigToHash(big.NewInt(6)), {}},
+	})
+	require.NoError(t, err)
+	require.False(t, dataOnly.Verified)
+
+	_, err = parseBatchEvent(parsed, types.Log{
+		Topics: []common.Hash{parsed.Events["BatchVerified"].ID, {}, {}},
+	})
+	require.Error(t, err)
+	_, err = parseBatchEvent(parsed, types.Log{
+		Topics: []common.Hash{parsed.Events["Deposit"].ID, common.BigToHash(big.NewInt(1)), {}},
+	})
+	require.Error(t, err)
+
+	require.True(t, logPosition{block: 2, index: 0}.after(logPosition{block: 1, index: 7}))
+	require.False(t, logPosition{block: 2, index: 1}.after(logPosition{block: 2, index: 1}))
+}
@@ -0,0 +1,128 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// GuardianStatus is the emergency state set by the contract guardian
+type GuardianStatus struct {
+	Guardian     common.Address
+	Paused       bool // No batches or proofs are accepted
+	BridgeFrozen bool // No deposits or withdrawal claims are accepted
+}
+
+// GetGuardianStatus reads the guardian and whether batch acceptance or the bridge is stopped
+func (c *Client) GetGuardianStatus(ctx context.Context) (*GuardianStatus, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	guardian, err := c.rollupContract.Guardian(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guardian: %v", err)
+	}
+	paused, err := c.rollupContract.Paused(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paused state: %v", err)
+	}
+	frozen, err := c.rollupContract.BridgeFrozen(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bridge state: %v", err)
+	}
+
+	return &GuardianStatus{
+		Guardian:     guardian,
+		Paused:       paused,
+		BridgeFrozen: frozen,
+	}, nil
+}
+
+// Pause stops the contract from accepting batches and proofs, only the guardian may call it
+func (c *Client) Pause(ctx context.Context) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "pause", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.Pause(opts)
+	})
+}
+
+// Unpause resumes batch acceptance, only the guardian may call it
+func (c *Client) Unpause(ctx context.Context) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "unpause", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.Unpause(opts)
+	})
+}
+
+// FreezeBridge stops deposits and withdrawal claims, only the guardian may call it
+func (c *Client) FreezeBridge(ctx context.Context) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "freeze bridge", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.FreezeBridge(opts)
+	})
+}
+
+// UnfreezeBridge resumes deposits and withdrawal claims, only the guardian may call it
+func (c *Client) UnfreezeBridge(ctx context.Context) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "unfreeze bridge", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.UnfreezeBridge(opts)
+	})
+}
+
+// SetGuardian hands the guardian role to another account, only the guardian may call it
+func (c *Client) SetGuardian(ctx context.Context, guardian common.Address) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "set guardian", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.SetGuardian(opts, guardian)
+	})
+}
+
+// GetValidators reads the consensus validator set registered on the rollup contract
+func (c *Client) GetValidators(ctx context.Context) ([]string, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	validators, err := c.rollupContract.GetValidators(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validators: %v", err)
+	}
+	return validators, nil
+}
+
+// AddValidator registers a node, by peer ID, as a consensus validator. Only the guardian
+// may call it.
+func (c *Client) AddValidator(ctx context.Context, peerID string) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "add validator", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.AddValidator(opts, peerID)
+	})
+}
+
+// RemoveValidator removes a node from the consensus validators, only the guardian may call it
+func (c *Client) RemoveValidator(ctx context.Context, peerID string) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "remove validator", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.RemoveValidator(opts, peerID)
+	})
+}
+
+// guardianCall sends a guardian transaction built by call
+func (c *Client) guardianCall(ctx context.Context, action string, call func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	auth, err := c.getTransactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := call(auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %v", action, err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Str("action", action).Msg("Sent guardian transaction to L1")
+	return tx, nil
+}
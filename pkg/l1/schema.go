@@ -0,0 +1,66 @@
+package l1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+
+	"zkrollup/pkg/crypto"
+)
+
+// ErrInputSchemaMismatch is returned for a proof whose public inputs are not laid out the
+// way the deployed verifier expects
+var ErrInputSchemaMismatch = errors.New("public input schema mismatch")
+
+// InputSchema returns the hash of the public input schema the contract was deployed with,
+// zero for a contract that does not verify proofs
+func (c *Client) InputSchema(ctx context.Context) ([32]byte, error) {
+	if c.rollupContract == nil {
+		return [32]byte{}, fmt.Errorf("rollup contract not initialized")
+	}
+
+	schema, err := c.rollupContract.PublicInputSchema(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("failed to get public input schema: %v", err)
+	}
+	return schema, nil
+}
+
+// batchProofArgs checks that a proof was generated under the public input schema of the
+// verifier for its key epoch, the contract's own verifier for epoch 0, and splits it into
+// contract arguments. A missing proof needs no schema.
+func (c *Client) batchProofArgs(ctx context.Context, proof, publicInputs []byte, schemaHash [32]byte, epoch uint64) ([8]*big.Int, [3]*big.Int, error) {
+	if len(proof) == 0 {
+		return proofArgs(nil, nil, nil)
+	}
+
+	deployed, err := c.verifierSchema(ctx, epoch)
+	if err != nil {
+		return [8]*big.Int{}, [3]*big.Int{}, err
+	}
+	if deployed != ([32]byte{}) && deployed != schemaHash {
+		return [8]*big.Int{}, [3]*big.Int{}, fmt.Errorf("%w: proof uses %x, contract expects %x", ErrInputSchemaMismatch, schemaHash, deployed)
+	}
+
+	schema, err := crypto.LookupSchema(schemaHash)
+	if err != nil {
+		return [8]*big.Int{}, [3]*big.Int{}, fmt.Errorf("%w: %v", ErrInputSchemaMismatch, err)
+	}
+	return proofArgs(proof, publicInputs, schema)
+}
+
+// verifierSchema returns the public input schema of the verifier checking proofs made with
+// the keys of epoch
+func (c *Client) verifierSchema(ctx context.Context, epoch uint64) ([32]byte, error) {
+	if epoch == 0 {
+		return c.InputSchema(ctx)
+	}
+	entry, err := c.VerifierForEpoch(ctx, epoch)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return entry.InputSchema, nil
+}
@@ -0,0 +1,126 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/l1/contracts"
+)
+
+// StakedValidator is a validator bonded on the Staking contract
+type StakedValidator struct {
+	Address common.Address // Signs the validator's consensus messages
+	PeerID  string
+	Stake   *big.Int
+}
+
+// Staking wraps the Staking contract, which holds the stake of consensus validators and
+// records reports of double signing against them
+type Staking struct {
+	address  common.Address
+	client   *Client
+	contract *contracts.Staking
+}
+
+// Staking binds the Staking contract at address on the client's L1 connection, transactions
+// are signed by the client's signer
+func (c *Client) Staking(address common.Address) (*Staking, error) {
+	contract, err := contracts.NewStaking(address, c.ethClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind staking contract: %v", err)
+	}
+	return &Staking{address: address, client: c, contract: contract}, nil
+}
+
+// Address returns the address of the Staking contract
+func (s *Staking) Address() common.Address {
+	return s.address
+}
+
+// Validators reads the validators in the consensus set: bonded, not slashed and holding at
+// least the minimum stake
+func (s *Staking) Validators(ctx context.Context) ([]StakedValidator, error) {
+	out, err := s.contract.GetValidators(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staked validators: %v", err)
+	}
+	if len(out.PeerIds) != len(out.Addrs) || len(out.Stakes) != len(out.Addrs) {
+		return nil, fmt.Errorf("staking contract returned %d addresses, %d peer IDs and %d stakes", len(out.Addrs), len(out.PeerIds), len(out.Stakes))
+	}
+
+	validators := make([]StakedValidator, len(out.Addrs))
+	for i := range out.Addrs {
+		validators[i] = StakedValidator{
+			Address: out.Addrs[i],
+			PeerID:  out.PeerIds[i],
+			Stake:   out.Stakes[i],
+		}
+	}
+	return validators, nil
+}
+
+// MinStake reads the stake a validator must hold to be in the consensus set
+func (s *Staking) MinStake(ctx context.Context) (*big.Int, error) {
+	minStake, err := s.contract.MinStake(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minimum stake: %v", err)
+	}
+	return minStake, nil
+}
+
+// Stake bonds amount wei to the signer's stake, naming the node's peer ID on the first stake
+func (s *Staking) Stake(ctx context.Context, peerID string, amount *big.Int) (*types.Transaction, error) {
+	return s.send(ctx, "stake", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		opts.Value = amount
+		return s.contract.Stake(opts, peerID)
+	})
+}
+
+// RequestUnbond takes the signer out of the consensus set, its stake is withdrawable after
+// the unbonding period
+func (s *Staking) RequestUnbond(ctx context.Context) (*types.Transaction, error) {
+	return s.send(ctx, "request unbond", s.contract.RequestUnbond)
+}
+
+// Withdraw pays out the signer's stake once the unbonding period passed
+func (s *Staking) Withdraw(ctx context.Context) (*types.Transaction, error) {
+	return s.send(ctx, "withdraw stake", s.contract.Withdraw)
+}
+
+// ReportDoubleSign publishes evidence that validator signed two conflicting consensus
+// messages. Anyone may report, the slasher confirms the report with Slash.
+func (s *Staking) ReportDoubleSign(ctx context.Context, validator common.Address, evidence []byte) (*types.Transaction, error) {
+	return s.send(ctx, "report double sign", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return s.contract.ReportDoubleSign(opts, validator, evidence)
+	})
+}
+
+// Slash confirms the report of the evidence with the given keccak256 hash, forfeiting the
+// validator's stake. Only the slasher may call it.
+func (s *Staking) Slash(ctx context.Context, evidenceHash common.Hash) (*types.Transaction, error) {
+	return s.send(ctx, "slash", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return s.contract.Slash(opts, evidenceHash)
+	})
+}
+
+// send sends a staking transaction built by call
+func (s *Staking) send(ctx context.Context, action string, call func(*bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	auth, err := s.client.getTransactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := call(auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %v", action, err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Str("action", action).Msg("Sent staking transaction to L1")
+	return tx, nil
+}
@@ -0,0 +1,333 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+	"github.com/rs/zerolog/log"
+)
+
+// Replacement defaults. Nodes only accept a replacement raising every fee by 10%, and
+// blob transactions only one doubling them.
+const (
+	defaultStuckAfter = 3 * time.Minute
+	feeBumpPercent    = 12
+	blobFeeBumpFactor = 2
+)
+
+// txManager hands out the nonces of the client's L1 account and remembers the
+// transactions sent with them until they are mined, so stuck ones can be replaced
+type txManager struct {
+	backend Backend // Connection the transactions are sent on
+	address common.Address
+
+	mu       sync.Mutex
+	next     uint64 // Next nonce to try, valid when synced
+	synced   bool
+	reserved map[uint64]struct{}   // Nonces handed out but not sent yet
+	pending  map[uint64]*pendingTx // Sent transactions not known to be mined, by nonce
+}
+
+// pendingTx is a sent transaction and the replacements sent for it
+type pendingTx struct {
+	tx     *types.Transaction // Latest attempt
+	hashes []common.Hash      // Every attempt, any of them may be mined
+	sentAt time.Time          // Of the latest attempt
+}
+
+func newTxManager(backend Backend, address common.Address) *txManager {
+	return &txManager{
+		backend:  backend,
+		address:  address,
+		reserved: make(map[uint64]struct{}),
+		pending:  make(map[uint64]*pendingTx),
+	}
+}
+
+// reserveNonce returns the lowest nonce neither handed out nor pending, starting from the
+// node's pending nonce after a failed send
+func (m *txManager) reserveNonce(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.synced {
+		nonce, err := m.backend.PendingNonceAt(ctx, m.address)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get nonce: %v", err)
+		}
+		m.next = nonce
+		m.synced = true
+	}
+	for {
+		_, reserved := m.reserved[m.next]
+		_, pending := m.pending[m.next]
+		if !reserved && !pending {
+			break
+		}
+		m.next++
+	}
+
+	nonce := m.next
+	m.next++
+	m.reserved[nonce] = struct{}{}
+	return nonce, nil
+}
+
+// release returns a nonce that was not sent, the next reservation asks the node again so
+// no gap is left
+func (m *txManager) release(nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.reserved, nonce)
+	m.synced = false
+}
+
+// sent records the outcome of sending tx
+func (m *txManager) sent(tx *types.Transaction, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.reserved, tx.Nonce())
+	if err != nil {
+		m.synced = false
+		return
+	}
+
+	p, ok := m.pending[tx.Nonce()]
+	if !ok {
+		p = &pendingTx{}
+		m.pending[tx.Nonce()] = p
+	}
+	p.tx = tx
+	p.hashes = append(p.hashes, tx.Hash())
+	p.sentAt = time.Now()
+}
+
+// attempts returns the hashes of every attempt at the transaction tx was sent as
+func (m *txManager) attempts(tx *types.Transaction) []common.Hash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.pending[tx.Nonce()]; ok {
+		for _, hash := range p.hashes {
+			if hash == tx.Hash() {
+				return append([]common.Hash(nil), p.hashes...)
+			}
+		}
+	}
+	return []common.Hash{tx.Hash()}
+}
+
+// mined forgets the transaction sent with nonce
+func (m *txManager) mined(nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, nonce)
+}
+
+// snapshot returns the pending transactions in nonce order
+func (m *txManager) snapshot() []pendingTx {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txs := make([]pendingTx, 0, len(m.pending))
+	for _, p := range m.pending {
+		txs = append(txs, pendingTx{tx: p.tx, hashes: append([]common.Hash(nil), p.hashes...), sentAt: p.sentAt})
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].tx.Nonce() < txs[j].tx.Nonce() })
+	return txs
+}
+
+// trackedBackend is the connection the client and its contract bindings send through.
+// Nonces of the client's account come from its txManager, which records every transaction
+// sent.
+type trackedBackend struct {
+	Backend
+	txs *txManager
+}
+
+// PendingNonceAt reserves the next nonce of the client's account
+func (b *trackedBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if account != b.txs.address {
+		return b.Backend.PendingNonceAt(ctx, account)
+	}
+	return b.txs.reserveNonce(ctx)
+}
+
+// SendTransaction sends tx and records it as pending
+func (b *trackedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	err := b.Backend.SendTransaction(ctx, tx)
+	b.txs.sent(tx, err)
+	return err
+}
+
+// SuggestFees returns the EIP-1559 tip and fee cap for a new transaction: the node's
+// suggested tip on top of twice the base fee, so it stays includable while the base fee
+// rises for a few blocks, held under the configured maximum. Before London only a legacy
+// gas price is returned.
+func (c *Client) SuggestFees(ctx context.Context) (tip, feeCap, gasPrice *big.Int, err error) {
+	head, err := c.ethClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get latest header: %v", err)
+	}
+
+	if head.BaseFee == nil {
+		gasPrice, err = c.ethClient.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to suggest gas price: %v", err)
+		}
+		return nil, nil, c.capFee(gasPrice), nil
+	}
+
+	tip, err = c.ethClient.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to suggest gas tip: %v", err)
+	}
+	feeCap = c.capFee(new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))))
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+	return tip, feeCap, nil, nil
+}
+
+// capFee holds fee under the configured maximum fee per gas
+func (c *Client) capFee(fee *big.Int) *big.Int {
+	if c.maxFeePerGas != nil && fee.Cmp(c.maxFeePerGas) > 0 {
+		return new(big.Int).Set(c.maxFeePerGas)
+	}
+	return fee
+}
+
+// ReplaceStuck resends every transaction pending for longer than the stuck timeout with
+// the same nonce and higher fees, the current suggestion or a bump of the previous fees
+// the node accepts as replacement. Mined transactions are forgotten. It returns the
+// replacements sent.
+func (c *Client) ReplaceStuck(ctx context.Context) ([]*types.Transaction, error) {
+	var replaced []*types.Transaction
+	for _, p := range c.txs.snapshot() {
+		if c.isMined(ctx, p.hashes) {
+			c.txs.mined(p.tx.Nonce())
+			continue
+		}
+		if time.Since(p.sentAt) < c.stuckAfter {
+			continue
+		}
+
+		replacement, err := c.bumpFees(ctx, p.tx)
+		if err != nil {
+			return replaced, err
+		}
+		if replacement == nil {
+			log.Warn().Uint64("nonce", p.tx.Nonce()).Str("tx_hash", p.tx.Hash().Hex()).Msg("L1 transaction stuck at the maximum fee")
+			continue
+		}
+
+		signed, err := c.signer.SignTx(ctx, replacement, c.chainID)
+		if err != nil {
+			return replaced, fmt.Errorf("failed to sign replacement transaction: %v", err)
+		}
+		if err := c.ethClient.SendTransaction(ctx, signed); err != nil {
+			return replaced, fmt.Errorf("failed to replace transaction %s: %v", p.tx.Hash().Hex(), err)
+		}
+		log.Info().Uint64("nonce", signed.Nonce()).Str("stuck_tx", p.tx.Hash().Hex()).Str("tx_hash", signed.Hash().Hex()).Str("fee_cap", signed.GasFeeCap().String()).Msg("Replaced stuck L1 transaction")
+		replaced = append(replaced, signed)
+	}
+	return replaced, nil
+}
+
+// isMined reports whether any of the attempts at a transaction has a receipt
+func (c *Client) isMined(ctx context.Context, hashes []common.Hash) bool {
+	for _, hash := range hashes {
+		if receipt, err := c.ethClient.TransactionReceipt(ctx, hash); err == nil && receipt != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// bumpFees returns tx unsigned with fees raised to the current suggestion or the minimum
+// replacement bump, whichever is higher, nil when that exceeds the maximum fee per gas
+func (c *Client) bumpFees(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	tip, feeCap, gasPrice, err := c.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	percent := int64(100 + feeBumpPercent)
+	if tx.Type() == types.BlobTxType {
+		percent = 100 * blobFeeBumpFactor
+	}
+	bump := func(old, suggested *big.Int) *big.Int {
+		bumped := new(big.Int).Div(new(big.Int).Mul(old, big.NewInt(percent)), big.NewInt(100))
+		if suggested != nil && suggested.Cmp(bumped) > 0 {
+			return suggested
+		}
+		return bumped
+	}
+	exceeds := func(fee *big.Int) bool {
+		return c.maxFeePerGas != nil && fee.Cmp(c.maxFeePerGas) > 0
+	}
+
+	switch tx.Type() {
+	case types.LegacyTxType:
+		price := bump(tx.GasPrice(), gasPrice)
+		if exceeds(price) {
+			return nil, nil
+		}
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: price,
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}), nil
+
+	case types.DynamicFeeTxType:
+		newFeeCap := bump(tx.GasFeeCap(), feeCap)
+		if exceeds(newFeeCap) {
+			return nil, nil
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    c.chainID,
+			Nonce:      tx.Nonce(),
+			GasTipCap:  bump(tx.GasTipCap(), tip),
+			GasFeeCap:  newFeeCap,
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}), nil
+
+	case types.BlobTxType:
+		newFeeCap := bump(tx.GasFeeCap(), feeCap)
+		if exceeds(newFeeCap) {
+			return nil, nil
+		}
+		return types.NewTx(&types.BlobTx{
+			ChainID:    uint256.MustFromBig(c.chainID),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  uint256.MustFromBig(bump(tx.GasTipCap(), tip)),
+			GasFeeCap:  uint256.MustFromBig(newFeeCap),
+			Gas:        tx.Gas(),
+			To:         *tx.To(),
+			Value:      uint256.MustFromBig(tx.Value()),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+			BlobFeeCap: uint256.MustFromBig(bump(tx.BlobGasFeeCap(), c.blobBaseFee(ctx))),
+			BlobHashes: tx.BlobHashes(),
+			Sidecar:    tx.BlobTxSidecar(),
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("cannot replace transaction of type %d", tx.Type())
+	}
+}
@@ -0,0 +1,92 @@
+package l1
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// feeBackend answers the nonce and fee queries of the transaction manager
+type feeBackend struct {
+	Backend
+	nonce   uint64
+	baseFee *big.Int
+	tip     *big.Int
+}
+
+func (b *feeBackend) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	return b.nonce, nil
+}
+
+func (b *feeBackend) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return &types.Header{BaseFee: b.baseFee}, nil
+}
+
+func (b *feeBackend) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return b.tip, nil
+}
+
+func TestTxManagerNonces(t *testing.T) {
+	backend := &feeBackend{nonce: 5}
+	m := newTxManager(backend, common.Address{1})
+	ctx := context.Background()
+
+	first, err := m.reserveNonce(ctx)
+	require.NoError(t, err)
+	second, err := m.reserveNonce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{5, 6}, []uint64{first, second})
+
+	// The first is sent, the second fails, so its nonce is handed out again
+	m.sent(types.NewTx(&types.LegacyTx{Nonce: first}), nil)
+	m.sent(types.NewTx(&types.LegacyTx{Nonce: second}), errors.New("rejected"))
+	backend.nonce = 6
+	next, err := m.reserveNonce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), next)
+
+	// The node does not count the pending transaction yet, its nonce is skipped
+	m.release(next)
+	backend.nonce = 5
+	next, err = m.reserveNonce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), next)
+	require.Len(t, m.snapshot(), 1)
+}
+
+func TestBumpFees(t *testing.T) {
+	backend := &feeBackend{baseFee: big.NewInt(10), tip: big.NewInt(1)}
+	c := &Client{ethClient: backend, chainID: big.NewInt(1)}
+	ctx := context.Background()
+
+	tip, feeCap, gasPrice, err := c.SuggestFees(ctx)
+	require.NoError(t, err)
+	require.Nil(t, gasPrice)
+	require.Equal(t, int64(1), tip.Int64())
+	require.Equal(t, int64(21), feeCap.Int64())
+
+	// A replacement raises the old fees by the bump when the suggestion is lower
+	stuck := types.NewTx(&types.DynamicFeeTx{ChainID: c.chainID, Nonce: 3, GasTipCap: big.NewInt(100), GasFeeCap: big.NewInt(1000), Gas: 21000})
+	replacement, err := c.bumpFees(ctx, stuck)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), replacement.Nonce())
+	require.Equal(t, int64(112), replacement.GasTipCap().Int64())
+	require.Equal(t, int64(1120), replacement.GasFeeCap().Int64())
+
+	// Otherwise it pays the suggestion
+	backend.baseFee = big.NewInt(1000)
+	replacement, err = c.bumpFees(ctx, stuck)
+	require.NoError(t, err)
+	require.Equal(t, int64(2001), replacement.GasFeeCap().Int64())
+
+	// No replacement is built above the maximum fee
+	c.maxFeePerGas = big.NewInt(1100)
+	replacement, err = c.bumpFees(ctx, stuck)
+	require.NoError(t, err)
+	require.Nil(t, replacement)
+}
@@ -0,0 +1,90 @@
+package l1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrVerifierNotRegistered is returned for a proof made with the keys of a CRS epoch that
+// has no verifier in the contract's registry
+var ErrVerifierNotRegistered = errors.New("no verifier registered for epoch")
+
+// VerifierEntry is the verifier the contract's registry holds for one CRS epoch
+type VerifierEntry struct {
+	Epoch          uint64
+	Verifier       common.Address
+	InputSchema    [32]byte
+	CircuitVersion uint64
+}
+
+// VerifierForEpoch returns the verifier registered for the keys of a CRS epoch
+func (c *Client) VerifierForEpoch(ctx context.Context, epoch uint64) (*VerifierEntry, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	entry, err := c.rollupContract.VerifierRegistry(&bind.CallOpts{Context: ctx}, new(big.Int).SetUint64(epoch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verifier for epoch %d: %v", epoch, err)
+	}
+	if !entry.Registered {
+		return nil, fmt.Errorf("%w %d", ErrVerifierNotRegistered, epoch)
+	}
+	return &VerifierEntry{
+		Epoch:          epoch,
+		Verifier:       entry.Verifier,
+		InputSchema:    entry.InputSchema,
+		CircuitVersion: entry.CircuitVersion,
+	}, nil
+}
+
+// LatestVerifierEpoch returns the most recently registered epoch, false when the registry
+// is empty and proofs are verified by the verifier the contract was deployed with
+func (c *Client) LatestVerifierEpoch(ctx context.Context) (uint64, bool, error) {
+	if c.rollupContract == nil {
+		return 0, false, fmt.Errorf("rollup contract not initialized")
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	registered, err := c.rollupContract.HasRegisteredVerifier(opts)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read verifier registry: %v", err)
+	}
+	if !registered {
+		return 0, false, nil
+	}
+	epoch, err := c.rollupContract.LatestEpoch(opts)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get latest verifier epoch: %v", err)
+	}
+	return epoch.Uint64(), true, nil
+}
+
+// BatchVerifierEpoch returns the epoch whose verifier accepted a batch's proof, 0 for a
+// batch verified by the verifier the contract was deployed with
+func (c *Client) BatchVerifierEpoch(ctx context.Context, batchNumber uint64) (uint64, error) {
+	if c.rollupContract == nil {
+		return 0, fmt.Errorf("rollup contract not initialized")
+	}
+
+	epoch, err := c.rollupContract.BatchEpoch(&bind.CallOpts{Context: ctx}, l1BatchNumber(batchNumber))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get verifier epoch of batch %d: %v", batchNumber, err)
+	}
+	return epoch.Uint64(), nil
+}
+
+// RegisterVerifier adds the verifier for the keys of a new CRS epoch to the registry, only
+// the guardian may call it. Epochs must increase and registered entries are permanent, so
+// proofs made with older keys stay verifiable.
+func (c *Client) RegisterVerifier(ctx context.Context, entry VerifierEntry) (*types.Transaction, error) {
+	return c.guardianCall(ctx, "register verifier", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.rollupContract.RegisterVerifier(opts, new(big.Int).SetUint64(entry.Epoch), entry.Verifier, entry.InputSchema, entry.CircuitVersion)
+	})
+}
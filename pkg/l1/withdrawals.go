@@ -0,0 +1,83 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/l1/contracts"
+)
+
+// WithdrawalClaim proves the total an account withdrew on the rollup against the state
+// root of a batch accepted on L1
+type WithdrawalClaim struct {
+	BatchNumber  uint64 // Rollup batch number
+	Account      common.Address
+	Amount       *big.Int // Total withdrawn as of the batch
+	Bridge       contracts.ZKRollupBridgeAccount
+	AccountProof contracts.ZKRollupMerkleProof
+	StorageProof contracts.ZKRollupMerkleProof
+}
+
+// Calldata returns the input of the claimWithdrawal contract call for the claim
+func (w *WithdrawalClaim) Calldata() ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(contracts.ZKRollupABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rollup ABI: %v", err)
+	}
+	return parsed.Pack("claimWithdrawal", l1BatchNumber(w.BatchNumber), w.Account, w.Amount, w.Bridge, w.AccountProof, w.StorageProof)
+}
+
+// ClaimWithdrawal pays out the part of a withdrawal not claimed yet to the withdrawing account
+func (c *Client) ClaimWithdrawal(ctx context.Context, claim *WithdrawalClaim) (*types.Transaction, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	auth, err := c.getTransactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := c.rollupContract.ClaimWithdrawal(auth, l1BatchNumber(claim.BatchNumber), claim.Account, claim.Amount, claim.Bridge, claim.AccountProof, claim.StorageProof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim withdrawal: %v", err)
+	}
+
+	log.Info().Str("tx_hash", tx.Hash().Hex()).Str("account", claim.Account.Hex()).Uint64("batch_number", claim.BatchNumber).Msg("Submitted withdrawal claim to L1")
+	return tx, nil
+}
+
+// Withdrawn returns the total already paid out to account on L1
+func (c *Client) Withdrawn(ctx context.Context, account common.Address) (*big.Int, error) {
+	if c.rollupContract == nil {
+		return nil, fmt.Errorf("rollup contract not initialized")
+	}
+
+	amount, err := c.rollupContract.Withdrawn(&bind.CallOpts{Context: ctx}, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get withdrawn amount: %v", err)
+	}
+	return amount, nil
+}
+
+// HasVerifier reports whether the rollup contract requires batch proofs to be verified
+// before withdrawals against them can be claimed
+func (c *Client) HasVerifier(ctx context.Context) (bool, error) {
+	if c.rollupContract == nil {
+		return false, fmt.Errorf("rollup contract not initialized")
+	}
+
+	verifier, err := c.rollupContract.Verifier(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return false, fmt.Errorf("failed to get verifier: %v", err)
+	}
+	return verifier != (common.Address{}), nil
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AlertSource exposes the node state the alerting gauges are derived from
+type AlertSource interface {
+	// LastBatchTime is when the last batch was finalized locally
+	LastBatchTime() time.Time
+	// L1SubmissionBacklog is the number of finalized batches waiting to be posted to L1
+	L1SubmissionBacklog() int
+	// PendingConsensusRound is how long the oldest undecided consensus round has been open
+	PendingConsensusRound() time.Duration
+	// ProverQueueDepth is the number of finalized batches waiting for their proof
+	ProverQueueDepth() int
+	// ProofDeadlineMisses is the number of batch proofs that were not ready in time
+	ProofDeadlineMisses() uint64
+}
+
+// ExampleAlertRules is a Prometheus rule group built on the alerting gauges, thresholds
+// should be tuned to the batch cadence of the deployment
+const ExampleAlertRules = `groups:
+  - name: zkrollup
+    rules:
+      - alert: ZKRollupNoBatches
+        expr: zkrollup_seconds_since_last_batch > 600
+        for: 1m
+      - alert: ZKRollupL1Backlog
+        expr: zkrollup_l1_submission_backlog > 5
+        for: 10m
+      - alert: ZKRollupConsensusStuck
+        expr: zkrollup_consensus_stuck == 1
+        for: 1m
+      - alert: ZKRollupProverBehind
+        expr: zkrollup_prover_queue_depth > 10
+        for: 10m
+      - alert: ZKRollupProofDeadlineMissed
+        expr: increase(zkrollup_proof_deadline_misses_total[15m]) > 0
+`
+
+// RegisterAlertGauges registers gauges computed from src on every scrape. A consensus
+// round open for longer than stuckAfter sets zkrollup_consensus_stuck.
+func RegisterAlertGauges(reg prometheus.Registerer, src AlertSource, stuckAfter time.Duration) error {
+	gauges := []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "zkrollup_seconds_since_last_batch",
+			Help: "Seconds since this node last finalized a batch.",
+		}, func() float64 {
+			return time.Since(src.LastBatchTime()).Seconds()
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "zkrollup_l1_submission_backlog",
+			Help: "Finalized batches waiting to be submitted to L1.",
+		}, func() float64 {
+			return float64(src.L1SubmissionBacklog())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "zkrollup_consensus_pending_round_seconds",
+			Help: "Age of the oldest undecided consensus round.",
+		}, func() float64 {
+			return src.PendingConsensusRound().Seconds()
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "zkrollup_consensus_stuck",
+			Help: "1 if a consensus round has been undecided for longer than the configured limit.",
+		}, func() float64 {
+			if stuckAfter > 0 && src.PendingConsensusRound() > stuckAfter {
+				return 1
+			}
+			return 0
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "zkrollup_prover_queue_depth",
+			Help: "Finalized batches waiting for their validity proof.",
+		}, func() float64 {
+			return float64(src.ProverQueueDepth())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "zkrollup_proof_deadline_misses_total",
+			Help: "Batch proofs that were not ready within the proof deadline.",
+		}, func() float64 {
+			return float64(src.ProofDeadlineMisses())
+		}),
+	}
+
+	for _, g := range gauges {
+		if err := reg.Register(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
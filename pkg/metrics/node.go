@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NodeSource exposes the node state sampled on every scrape
+type NodeSource interface {
+	// MempoolDepth is the number of transactions waiting in the pool
+	MempoolDepth() int
+	// PeerCount is the number of connected peers
+	PeerCount() int
+}
+
+// NodeMetrics are the operational metrics updated by a running node
+type NodeMetrics struct {
+	BatchLatency   prometheus.Histogram   // Proposal of a batch to its local finalization
+	ProofTime      prometheus.Histogram   // Generation of a batch proof
+	ConsensusRound prometheus.Histogram   // First sight of a PBFT round to its decision
+	StateFlush     prometheus.Histogram   // Write and sync of buffered state changes
+	L1Submissions  *prometheus.CounterVec // L1 submissions by kind and result
+
+	BrokenPreconfirmations prometheus.Counter // Preconfirmations issued by the node and not kept
+	DoubleSigns            prometheus.Counter // Conflicting pre-prepares caught from validators
+}
+
+// NewNodeMetrics creates the node metrics, they are exported once registered
+func NewNodeMetrics() *NodeMetrics {
+	return &NodeMetrics{
+		BatchLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "zkrollup_batch_latency_seconds",
+			Help:    "Time from proposing a batch to finalizing it locally.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+		}),
+		ProofTime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "zkrollup_proof_generation_seconds",
+			Help:    "Time spent generating a batch proof.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+		}),
+		ConsensusRound: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "zkrollup_consensus_round_seconds",
+			Help:    "Time from first seeing a PBFT round to deciding it.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		}),
+		StateFlush: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "zkrollup_state_flush_seconds",
+			Help:    "Time to write and sync buffered state changes to the database.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}),
+		L1Submissions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "zkrollup_l1_submissions_total",
+			Help: "L1 batch submissions by kind and result.",
+		}, []string{"kind", "result"}),
+		BrokenPreconfirmations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "zkrollup_broken_preconfirmations_total",
+			Help: "Preconfirmations issued by the node whose transaction missed the deadline batch.",
+		}),
+		DoubleSigns: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "zkrollup_double_signs_total",
+			Help: "Validators caught signing pre-prepares for two batches with the same number.",
+		}),
+	}
+}
+
+// ObserveL1Submission counts an L1 submission of the given kind, failed if err is set
+func (m *NodeMetrics) ObserveL1Submission(kind string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.L1Submissions.WithLabelValues(kind, result).Inc()
+}
+
+// Register registers the node metrics and gauges sampled from src on every scrape
+func (m *NodeMetrics) Register(reg prometheus.Registerer, src NodeSource) error {
+	collectors := []prometheus.Collector{
+		m.BatchLatency,
+		m.ProofTime,
+		m.ConsensusRound,
+		m.StateFlush,
+		m.L1Submissions,
+		m.BrokenPreconfirmations,
+		m.DoubleSigns,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "zkrollup_mempool_depth",
+			Help: "Transactions waiting in the pool.",
+		}, func() float64 {
+			return float64(src.MempoolDepth())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "zkrollup_peer_count",
+			Help: "Connected peers.",
+		}, func() float64 {
+			return float64(src.PeerCount())
+		}),
+	}
+
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ProofRecord captures the proof size and L1 cost of a single batch
+type ProofRecord struct {
+	BatchNumber  uint64    `json:"batch_number"`
+	Timestamp    time.Time `json:"timestamp"`
+	TxCount      int       `json:"tx_count"`
+	ProofSize    int       `json:"proof_size"`              // Serialized proof size in bytes
+	EstimatedGas uint64    `json:"estimated_gas,omitempty"` // eth_estimateGas for the submission
+	GasUsed      uint64    `json:"gas_used,omitempty"`      // Gas used by the mined submission
+	L1TxHash     string    `json:"l1_tx_hash,omitempty"`
+	Aggregated   int       `json:"aggregated,omitempty"` // Batches covered by an aggregated proof, recorded under the last one
+}
+
+// ProofStats appends proof records to a JSON lines file so trends survive restarts
+type ProofStats struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+// OpenProofStats opens (or creates) the stats file at path for appending
+func OpenProofStats(path string) (*ProofStats, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proof stats file: %v", err)
+	}
+
+	return &ProofStats{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// Path returns the location of the stats file
+func (p *ProofStats) Path() string {
+	return p.path
+}
+
+// Record appends a record to the stats file
+func (p *ProofStats) Record(record ProofRecord) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proof record: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write proof record: %v", err)
+	}
+	return nil
+}
+
+// Close closes the stats file
+func (p *ProofStats) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.file.Close()
+}
+
+// LoadProofRecords reads every record from a stats file. Several records may exist for
+// a batch, e.g. one when it is submitted and one once the submission is mined.
+func LoadProofRecords(path string) ([]ProofRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proof stats file: %v", err)
+	}
+	defer file.Close()
+
+	var records []ProofRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record ProofRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse proof record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read proof stats file: %v", err)
+	}
+
+	return records, nil
+}
+
+// ProofSummary aggregates proof records
+type ProofSummary struct {
+	Batches      int
+	AvgProofSize float64
+	MaxProofSize int
+	AvgGasUsed   float64
+	MaxGasUsed   uint64
+	// GasTrend is the average gas of the most recent half of the batches relative to
+	// the older half, so 1.10 means submissions got 10% more expensive
+	GasTrend float64
+}
+
+// SummarizeProofRecords merges records per batch and aggregates them
+func SummarizeProofRecords(records []ProofRecord) ProofSummary {
+	// Later records for a batch carry more information, keep the latest values
+	var order []uint64
+	merged := make(map[uint64]ProofRecord)
+	for _, r := range records {
+		prev, ok := merged[r.BatchNumber]
+		if !ok {
+			order = append(order, r.BatchNumber)
+			merged[r.BatchNumber] = r
+			continue
+		}
+		if r.ProofSize != 0 {
+			prev.ProofSize = r.ProofSize
+		}
+		if r.EstimatedGas != 0 {
+			prev.EstimatedGas = r.EstimatedGas
+		}
+		if r.GasUsed != 0 {
+			prev.GasUsed = r.GasUsed
+		}
+		if r.L1TxHash != "" {
+			prev.L1TxHash = r.L1TxHash
+		}
+		merged[r.BatchNumber] = prev
+	}
+
+	summary := ProofSummary{Batches: len(order)}
+	if len(order) == 0 {
+		return summary
+	}
+
+	var totalSize int
+	var gas []uint64
+	for _, n := range order {
+		r := merged[n]
+		totalSize += r.ProofSize
+		if r.ProofSize > summary.MaxProofSize {
+			summary.MaxProofSize = r.ProofSize
+		}
+
+		used := r.GasUsed
+		if used == 0 {
+			used = r.EstimatedGas
+		}
+		if used == 0 {
+			continue
+		}
+		gas = append(gas, used)
+		if used > summary.MaxGasUsed {
+			summary.MaxGasUsed = used
+		}
+	}
+	summary.AvgProofSize = float64(totalSize) / float64(len(order))
+
+	if len(gas) > 0 {
+		summary.AvgGasUsed = average(gas)
+	}
+	if len(gas) >= 2 {
+		older, recent := average(gas[:len(gas)/2]), average(gas[len(gas)/2:])
+		if older > 0 {
+			summary.GasTrend = recent / older
+		}
+	}
+
+	return summary
+}
+
+func average(values []uint64) float64 {
+	var total float64
+	for _, v := range values {
+		total += float64(v)
+	}
+	return total / float64(len(values))
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// Server exposes registered metrics on /metrics for Prometheus to scrape
+type Server struct {
+	gatherer prometheus.Gatherer
+	port     int
+	server   *http.Server
+	mu       sync.Mutex
+}
+
+// NewServer creates a metrics server for the metrics of gatherer
+func NewServer(gatherer prometheus.Gatherer, port int) *Server {
+	return &Server{
+		gatherer: gatherer,
+		port:     port,
+	}
+}
+
+// Start starts serving metrics
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(s.gatherer, promhttp.HandlerOpts{}))
+
+	s.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Info().Int("port", s.port).Msg("Starting metrics server")
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Metrics server error")
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops serving metrics
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		log.Info().Msg("Stopping metrics server")
+		return s.server.Close()
+	}
+	return nil
+}
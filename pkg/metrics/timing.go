@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Timing accumulates durations of a repeated operation
+type Timing struct {
+	count uint64
+	total time.Duration
+	last  time.Duration
+	max   time.Duration
+	mu    sync.Mutex
+}
+
+// TimingSnapshot is a point in time view of a Timing
+type TimingSnapshot struct {
+	Count uint64        `json:"count"`
+	Last  time.Duration `json:"last"`
+	Avg   time.Duration `json:"avg"`
+	Max   time.Duration `json:"max"`
+}
+
+// Observe records one duration
+func (t *Timing) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count++
+	t.total += d
+	t.last = d
+	if d > t.max {
+		t.max = d
+	}
+}
+
+// Since records the time elapsed since start and returns it
+func (t *Timing) Since(start time.Time) time.Duration {
+	d := time.Since(start)
+	t.Observe(d)
+	return d
+}
+
+// Snapshot returns the current statistics
+func (t *Timing) Snapshot() TimingSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := TimingSnapshot{
+		Count: t.count,
+		Last:  t.last,
+		Max:   t.max,
+	}
+	if t.count > 0 {
+		snapshot.Avg = t.total / time.Duration(t.count)
+	}
+	return snapshot
+}
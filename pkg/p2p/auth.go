@@ -0,0 +1,169 @@
+package p2p
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/rs/zerolog/log"
+)
+
+// Peer authentication. The secure channel proves the ID of the peer at the other end of a
+// stream and gossipsub signs every message with its author's key, so the sender of each
+// message is known. On top of that only allowlisted peers may send consensus messages and
+// batches, and peers sending malformed or unauthorized payloads are penalized until they
+// are disconnected and refused.
+
+const (
+	// MaxPeerPenalty is the penalty at which a peer is banned
+	MaxPeerPenalty = 100
+
+	penaltyMalformed    = 25 // Payload that does not decode or has the wrong type
+	penaltyUnauthorized = 50 // Consensus message or batch from a peer off the allowlist
+)
+
+// peerGuard tracks the consensus allowlist and the penalties of misbehaving peers. It is
+// the host's connection gater, so banned peers cannot reconnect.
+type peerGuard struct {
+	mu        sync.RWMutex
+	allowlist map[peer.ID]struct{} // Nil lets any peer take part in consensus
+	penalties map[peer.ID]int
+	banned    map[peer.ID]struct{}
+}
+
+func newPeerGuard(allowlist []peer.ID) *peerGuard {
+	g := &peerGuard{
+		penalties: make(map[peer.ID]int),
+		banned:    make(map[peer.ID]struct{}),
+	}
+	g.setAllowlist(allowlist)
+	return g
+}
+
+func (g *peerGuard) setAllowlist(ids []peer.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(ids) == 0 {
+		g.allowlist = nil
+		return
+	}
+	g.allowlist = make(map[peer.ID]struct{}, len(ids))
+	for _, id := range ids {
+		g.allowlist[id] = struct{}{}
+	}
+}
+
+// allowed reports whether a peer may send consensus messages and batches
+func (g *peerGuard) allowed(id peer.ID) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.allowlist == nil {
+		return true
+	}
+	_, ok := g.allowlist[id]
+	return ok
+}
+
+// penalize adds to the penalty of a peer and reports whether it is now banned
+func (g *peerGuard) penalize(id peer.ID, penalty int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.penalties[id] += penalty
+	if g.penalties[id] < MaxPeerPenalty {
+		return false
+	}
+	g.banned[id] = struct{}{}
+	return true
+}
+
+func (g *peerGuard) isBanned(id peer.ID) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	_, ok := g.banned[id]
+	return ok
+}
+
+// InterceptPeerDial refuses to dial banned peers
+func (g *peerGuard) InterceptPeerDial(id peer.ID) bool { return !g.isBanned(id) }
+
+// InterceptAddrDial refuses to dial banned peers
+func (g *peerGuard) InterceptAddrDial(id peer.ID, _ ma.Multiaddr) bool { return !g.isBanned(id) }
+
+// InterceptAccept accepts every inbound connection, the peer is not known yet
+func (g *peerGuard) InterceptAccept(network.ConnMultiaddrs) bool { return true }
+
+// InterceptSecured refuses connections of banned peers once their ID is authenticated
+func (g *peerGuard) InterceptSecured(_ network.Direction, id peer.ID, _ network.ConnMultiaddrs) bool {
+	return !g.isBanned(id)
+}
+
+// InterceptUpgraded accepts every connection that passed InterceptSecured
+func (g *peerGuard) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
+
+// SetConsensusAllowlist restricts consensus messages and batches to the given peers, an
+// empty list accepts them from any peer
+func (n *Node) SetConsensusAllowlist(ids []peer.ID) {
+	n.guard.setAllowlist(ids)
+	log.Info().Int("peers", len(ids)).Msg("Set consensus peer allowlist")
+}
+
+// ConsensusAllowed reports whether a peer may send consensus messages and batches
+func (n *Node) ConsensusAllowed(id peer.ID) bool {
+	return id == n.Host.ID() || n.guard.allowed(id)
+}
+
+// PeerPenalty returns the penalty a peer accumulated by misbehaving
+func (n *Node) PeerPenalty(id peer.ID) int {
+	n.guard.mu.RLock()
+	defer n.guard.mu.RUnlock()
+	return n.guard.penalties[id]
+}
+
+// IsBanned reports whether a peer was banned for misbehaving
+func (n *Node) IsBanned(id peer.ID) bool {
+	return n.guard.isBanned(id)
+}
+
+// penalize records misbehavior of a peer, disconnecting it once it is banned
+func (n *Node) penalize(id peer.ID, penalty int, reason string) {
+	if id == n.Host.ID() {
+		return
+	}
+	log.Warn().Str("peer", id.String()).Int("penalty", penalty).Str("reason", reason).Msg("Penalized peer")
+	if n.guard.penalize(id, penalty) {
+		log.Warn().Str("peer", id.String()).Msg("Banned misbehaving peer")
+		n.Host.Network().ClosePeer(id)
+	}
+}
+
+// admitConsensus checks that the sender of a consensus message or batch is allowlisted,
+// penalizing it otherwise
+func (n *Node) admitConsensus(id peer.ID) bool {
+	if n.ConsensusAllowed(id) {
+		return true
+	}
+	n.penalize(id, penaltyUnauthorized, "not on the consensus allowlist")
+	return false
+}
+
+// ParsePeerIDs decodes a list of peer IDs
+func ParsePeerIDs(ids []string) ([]peer.ID, error) {
+	parsed := make([]peer.ID, 0, len(ids))
+	for _, s := range ids {
+		id, err := peer.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer ID %q: %v", s, err)
+		}
+		parsed = append(parsed, id)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,36 @@
+package p2p
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerGuard(t *testing.T) {
+	ids, err := ParsePeerIDs([]string{
+		"QmQCU2EcMqAqQPR2i9bChDtGNJchTbq5TbXJJ16u19uLTa",
+		"QmNnooDu7bfjPFoTZYxMNLWUQJyrVwtbZg5gBMjTezGAJN",
+	})
+	require.NoError(t, err)
+	_, err = ParsePeerIDs([]string{"not-a-peer"})
+	require.Error(t, err)
+
+	g := newPeerGuard(nil)
+	require.True(t, g.allowed(ids[0]))
+
+	g.setAllowlist(ids[:1])
+	require.True(t, g.allowed(ids[0]))
+	require.False(t, g.allowed(ids[1]))
+
+	require.False(t, g.penalize(ids[1], penaltyUnauthorized))
+	require.True(t, g.InterceptPeerDial(ids[1]))
+	require.True(t, g.penalize(ids[1], penaltyUnauthorized))
+	require.True(t, g.isBanned(ids[1]))
+	require.False(t, g.InterceptPeerDial(ids[1]))
+	require.False(t, g.InterceptSecured(0, ids[1], nil))
+	require.True(t, g.InterceptSecured(0, ids[0], nil))
+
+	g.setAllowlist([]peer.ID{})
+	require.True(t, g.allowed(ids[1]))
+}
@@ -0,0 +1,177 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+const (
+	// FinalizedBatchProtocolID announces batches that passed consensus
+	FinalizedBatchProtocolID = protocol.ID("/zkrollup/finalized/1.0.0")
+	// BatchSyncProtocolID lets a peer pull finalized batches it missed
+	BatchSyncProtocolID = protocol.ID("/zkrollup/batchsync/1.0.0")
+
+	// MaxBatchesPerRequest caps the batches returned by a single sync request
+	MaxBatchesPerRequest = 64
+)
+
+// BatchRequest asks a peer for count finalized batches starting at From
+type BatchRequest struct {
+	From  uint64 `json:"from"`
+	Count uint64 `json:"count"`
+}
+
+// setupBatchSyncProtocols registers the finalized batch and batch sync stream handlers
+func (n *Node) setupBatchSyncProtocols() {
+	n.Host.RemoveStreamHandler(FinalizedBatchProtocolID)
+	n.Host.SetStreamHandler(FinalizedBatchProtocolID, func(s network.Stream) {
+		defer s.Close()
+		if !n.admitConsensus(s.Conn().RemotePeer()) {
+			s.Reset()
+			return
+		}
+		s.SetReadDeadline(time.Now().Add(time.Second * 10))
+
+		var msg Message
+		if err := json.NewDecoder(s).Decode(&msg); err != nil {
+			log.Error().Err(err).Msg("Error decoding finalized batch message")
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "undecodable finalized batch message")
+			return
+		}
+		if msg.Type != MessageFinalizedBatch {
+			log.Error().Int("type", int(msg.Type)).Msg("Invalid message type for finalized batch protocol")
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "wrong message type for finalized batch protocol")
+			return
+		}
+
+		var batch state.Batch
+		if err := json.Unmarshal(msg.Payload, &batch); err != nil {
+			log.Error().Err(err).Msg("Error unmarshaling finalized batch")
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "undecodable finalized batch")
+			return
+		}
+
+		handlers := n.GetProtocolHandlers()
+		if handlers.OnFinalizedBatch == nil {
+			return
+		}
+		if err := handlers.OnFinalizedBatch(s.Conn().RemotePeer(), &batch); err != nil {
+			log.Error().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Error handling finalized batch")
+		}
+	})
+
+	n.Host.RemoveStreamHandler(BatchSyncProtocolID)
+	n.Host.SetStreamHandler(BatchSyncProtocolID, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(time.Second * 30))
+
+		var msg Message
+		if err := json.NewDecoder(s).Decode(&msg); err != nil {
+			log.Error().Err(err).Msg("Error decoding batch request")
+			s.Reset()
+			return
+		}
+		if msg.Type != MessageBatchRequest {
+			log.Error().Int("type", int(msg.Type)).Msg("Invalid message type for batch sync protocol")
+			s.Reset()
+			return
+		}
+
+		var req BatchRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Error().Err(err).Msg("Error unmarshaling batch request")
+			s.Reset()
+			return
+		}
+		if req.Count > MaxBatchesPerRequest {
+			req.Count = MaxBatchesPerRequest
+		}
+
+		handlers := n.GetProtocolHandlers()
+		var batches []state.Batch
+		if handlers.OnBatchRequest != nil {
+			var err error
+			if batches, err = handlers.OnBatchRequest(req.From, req.Count); err != nil {
+				log.Error().Err(err).Msg("Error serving batch request")
+				s.Reset()
+				return
+			}
+		}
+
+		payload, err := json.Marshal(batches)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal batches")
+			s.Reset()
+			return
+		}
+
+		if err := json.NewEncoder(s).Encode(Message{Type: MessageBatchResponse, Payload: payload}); err != nil {
+			log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to send batches")
+		}
+	})
+}
+
+// BroadcastFinalizedBatch announces a finalized batch to all connected peers. It fails only
+// if no peer received it.
+func (n *Node) BroadcastFinalizedBatch(ctx context.Context, batch *state.Batch) error {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %v", err)
+	}
+
+	msg := Message{
+		Type:    MessageFinalizedBatch,
+		Payload: payload,
+	}
+
+	return n.broadcast(ctx, FinalizedBatchProtocolID, msg)
+}
+
+// RequestBatches pulls up to count finalized batches starting at from from a peer
+func (n *Node) RequestBatches(ctx context.Context, peerID peer.ID, from, count uint64) ([]state.Batch, error) {
+	payload, err := json.Marshal(BatchRequest{From: from, Count: count})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %v", err)
+	}
+
+	streamCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	stream, err := n.Host.NewStream(streamCtx, peerID, BatchSyncProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch sync stream: %v", err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(time.Second * 30))
+
+	if err := json.NewEncoder(stream).Encode(Message{Type: MessageBatchRequest, Payload: payload}); err != nil {
+		return nil, fmt.Errorf("failed to send batch request: %v", err)
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return nil, fmt.Errorf("failed to close batch request: %v", err)
+	}
+
+	var resp Message
+	if err := json.NewDecoder(stream).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read batch response: %v", err)
+	}
+	if resp.Type != MessageBatchResponse {
+		return nil, fmt.Errorf("unexpected response type: %d", resp.Type)
+	}
+
+	var batches []state.Batch
+	if err := json.Unmarshal(resp.Payload, &batches); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batches: %v", err)
+	}
+
+	return batches, nil
+}
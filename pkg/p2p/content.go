@@ -0,0 +1,189 @@
+package p2p
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ContentProtocolID serves payloads too large to embed in a message by their hash
+	ContentProtocolID = protocol.ID("/zkrollup/content/1.0.0")
+
+	// MaxInlinePayloadSize is the largest payload gossiped inline, larger ones are announced
+	// by hash and pulled from the announcer over ContentProtocolID
+	MaxInlinePayloadSize = 1 << 20
+
+	// maxContentSize bounds a payload fetched over ContentProtocolID
+	maxContentSize = 256 << 20
+
+	// maxStoredContent caps the payloads a node keeps available for its peers
+	maxStoredContent = 32
+
+	// contentFetchTimeout bounds fetching a payload from one peer
+	contentFetchTimeout = time.Minute * 2
+)
+
+// ContentHash returns the hex SHA256 a payload is addressed by
+func ContentHash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// contentStore holds the payloads a node announced or received by hash, oldest evicted first
+type contentStore struct {
+	lock  sync.RWMutex
+	data  map[string][]byte
+	order []string
+}
+
+func newContentStore() *contentStore {
+	return &contentStore{data: make(map[string][]byte)}
+}
+
+func (c *contentStore) put(hash string, data []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.data[hash]; ok {
+		return
+	}
+	c.data[hash] = data
+	c.order = append(c.order, hash)
+	if len(c.order) > maxStoredContent {
+		delete(c.data, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func (c *contentStore) get(hash string) ([]byte, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	data, ok := c.data[hash]
+	return data, ok
+}
+
+// ProvideContent makes a payload available to peers and returns the hash to announce it by
+func (n *Node) ProvideContent(data []byte) string {
+	hash := ContentHash(data)
+	n.content.put(hash, data)
+	return hash
+}
+
+// FetchContent returns the payload with the given hash, from this node if it has it or else
+// from the first of providers, then any connected peer, that serves it. A fetched payload
+// is provided to other peers in turn.
+func (n *Node) FetchContent(ctx context.Context, hash string, providers ...peer.ID) ([]byte, error) {
+	if data, ok := n.content.get(hash); ok {
+		return data, nil
+	}
+
+	candidates := append(append([]peer.ID(nil), providers...), n.GetPeers()...)
+	tried := make(map[peer.ID]bool)
+	var lastErr error = fmt.Errorf("no peer to fetch from")
+	for _, p := range candidates {
+		if p == "" || p == n.Host.ID() || tried[p] {
+			continue
+		}
+		tried[p] = true
+
+		data, err := n.fetchContentFrom(ctx, p, hash)
+		if err != nil {
+			log.Warn().Err(err).Str("peer", p.String()).Str("hash", hash).Msg("Failed to fetch content")
+			lastErr = err
+			continue
+		}
+		n.content.put(hash, data)
+		return data, nil
+	}
+	return nil, fmt.Errorf("failed to fetch content %s: %v", hash, lastErr)
+}
+
+// fetchContentFrom pulls a payload from one peer and checks it against its hash
+func (n *Node) fetchContentFrom(ctx context.Context, peerID peer.ID, hash string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, contentFetchTimeout)
+	defer cancel()
+
+	stream, err := n.Host.NewStream(ctx, peerID, ContentProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open content stream: %v", err)
+	}
+	defer stream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(stream).Encode(Message{Type: MessageContentRequest, ContentHash: hash}); err != nil {
+		return nil, fmt.Errorf("failed to send content request: %v", err)
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return nil, fmt.Errorf("failed to close content request: %v", err)
+	}
+
+	// The payload is sent raw after its length, without the overhead of a JSON encoding
+	var size uint64
+	if err := binary.Read(stream, binary.BigEndian, &size); err != nil {
+		return nil, fmt.Errorf("failed to read content length: %v", err)
+	}
+	if size > maxContentSize {
+		return nil, fmt.Errorf("content of %d bytes exceeds the limit of %d", size, maxContentSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(stream, data); err != nil {
+		return nil, fmt.Errorf("failed to read content: %v", err)
+	}
+
+	if ContentHash(data) != hash {
+		return nil, fmt.Errorf("content does not match hash %s", hash)
+	}
+	return data, nil
+}
+
+// setupContentProtocol registers the handler serving provided payloads. Peers asking for
+// a payload this node does not have get their stream reset.
+func (n *Node) setupContentProtocol() {
+	n.Host.RemoveStreamHandler(ContentProtocolID)
+	n.Host.SetStreamHandler(ContentProtocolID, func(s network.Stream) {
+		defer s.Close()
+		s.SetReadDeadline(time.Now().Add(time.Second * 10))
+
+		var msg Message
+		if err := json.NewDecoder(s).Decode(&msg); err != nil {
+			log.Error().Err(err).Msg("Error decoding content request")
+			s.Reset()
+			return
+		}
+		if msg.Type != MessageContentRequest {
+			log.Error().Int("type", int(msg.Type)).Msg("Invalid message type for content protocol")
+			s.Reset()
+			return
+		}
+
+		data, ok := n.content.get(msg.ContentHash)
+		if !ok {
+			log.Debug().Str("hash", msg.ContentHash).Str("peer", s.Conn().RemotePeer().String()).Msg("Requested content not available")
+			s.Reset()
+			return
+		}
+
+		s.SetWriteDeadline(time.Now().Add(contentFetchTimeout))
+		if err := binary.Write(s, binary.BigEndian, uint64(len(data))); err != nil {
+			log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to send content")
+			return
+		}
+		if _, err := s.Write(data); err != nil {
+			log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to send content")
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
+)
+
+// Gossipsub topics carrying messages meant for every node
+const (
+	TransactionTopic = "zkrollup/tx/1.0.0"
+	BatchTopic       = "zkrollup/batch/1.0.0"
+	ConsensusTopic   = "zkrollup/consensus/1.0.0"
+)
+
+// maxGossipMessageSize bounds a gossiped message, large enough for a MaxInlinePayloadSize
+// payload in its JSON encoding. Larger payloads are announced by hash and fetched out of band.
+const maxGossipMessageSize = 2 << 20
+
+// topicMessageTypes is the only message type accepted on each topic
+var topicMessageTypes = map[string]MessageType{
+	TransactionTopic:    MessageTransaction,
+	BatchTopic:          MessageBatch,
+	ConsensusTopic:      MessageConsensus,
+	TxAnnouncementTopic: MessageTxAnnouncement,
+}
+
+// restrictedTopics only accept messages authored by peers on the consensus allowlist
+var restrictedTopics = map[string]bool{
+	BatchTopic:     true,
+	ConsensusTopic: true,
+}
+
+// setupGossip joins the broadcast topics and delivers their messages to the protocol
+// handlers until ctx is done
+func (n *Node) setupGossip(ctx context.Context) error {
+	// Every message is signed by its author and unsigned messages are dropped. Messages
+	// this node publishes go to every subscribed peer rather than only its mesh, which is
+	// built on heartbeats: consensus does not resend, so a message published right after
+	// peers connect must not be lost.
+	ps, err := pubsub.NewGossipSub(ctx, n.Host,
+		pubsub.WithMaxMessageSize(maxGossipMessageSize),
+		pubsub.WithMessageSignaturePolicy(pubsub.StrictSign),
+		pubsub.WithFloodPublish(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create gossipsub: %v", err)
+	}
+
+	n.topics = make(map[string]*pubsub.Topic, len(topicMessageTypes))
+	for name, msgType := range topicMessageTypes {
+		if err := ps.RegisterTopicValidator(name, n.gossipValidator(name, msgType)); err != nil {
+			return fmt.Errorf("failed to register validator for topic %s: %v", name, err)
+		}
+		topic, err := ps.Join(name)
+		if err != nil {
+			return fmt.Errorf("failed to join topic %s: %v", name, err)
+		}
+		sub, err := topic.Subscribe()
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to topic %s: %v", name, err)
+		}
+
+		n.topics[name] = topic
+		go n.readTopic(ctx, sub, msgType)
+	}
+
+	log.Info().Int("topics", len(n.topics)).Msg("Joined gossip topics")
+	return nil
+}
+
+// readTopic hands every message published by another node on a topic to its handler
+func (n *Node) readTopic(ctx context.Context, sub *pubsub.Subscription, msgType MessageType) {
+	defer sub.Cancel()
+
+	for {
+		m, err := sub.Next(ctx)
+		if err != nil {
+			// The context is done or the subscription was cancelled
+			return
+		}
+		// Our own messages are delivered to us as well
+		if m.GetFrom() == n.Host.ID() {
+			continue
+		}
+
+		// Decoded and checked by the topic validator
+		msg, ok := m.ValidatorData.(*Message)
+		if !ok {
+			continue
+		}
+
+		// An announced payload is pulled from its publisher without holding up the topic
+		if msg.ContentHash != "" {
+			go n.fetchAndDispatch(ctx, sub.Topic(), *msg, m.GetFrom(), m.ReceivedFrom)
+			continue
+		}
+		if msg.Type == MessageTxAnnouncement {
+			go n.pullAnnouncedTransactions(ctx, *msg, m.GetFrom(), m.ReceivedFrom)
+			continue
+		}
+
+		if err := n.dispatch(m.GetFrom(), *msg); err != nil {
+			log.Error().Err(err).Str("topic", sub.Topic()).Str("peer", m.GetFrom().String()).Msg("Error handling gossip message")
+		}
+	}
+}
+
+// gossipValidator decodes the messages of a topic before they are delivered or forwarded.
+// Messages that do not decode or have the wrong type are rejected and the peer that relayed
+// them is penalized, as is the author of a message on a restricted topic that is not on
+// the consensus allowlist.
+func (n *Node) gossipValidator(topic string, msgType MessageType) pubsub.ValidatorEx {
+	return func(_ context.Context, from peer.ID, m *pubsub.Message) pubsub.ValidationResult {
+		var msg Message
+		if err := json.Unmarshal(m.Data, &msg); err != nil {
+			n.penalize(from, penaltyMalformed, "undecodable gossip message on "+topic)
+			return pubsub.ValidationReject
+		}
+		if msg.Type != msgType {
+			n.penalize(from, penaltyMalformed, fmt.Sprintf("message type %d on %s", msg.Type, topic))
+			return pubsub.ValidationReject
+		}
+		if restrictedTopics[topic] && !n.admitConsensus(m.GetFrom()) {
+			return pubsub.ValidationReject
+		}
+
+		m.ValidatorData = &msg
+		return pubsub.ValidationAccept
+	}
+}
+
+// fetchAndDispatch fetches the payload a gossip message announced by hash and hands the
+// message to its handler
+func (n *Node) fetchAndDispatch(ctx context.Context, topic string, msg Message, providers ...peer.ID) {
+	payload, err := n.FetchContent(ctx, msg.ContentHash, providers...)
+	if err != nil {
+		log.Error().Err(err).Str("topic", topic).Msg("Failed to fetch announced gossip payload")
+		return
+	}
+	msg.Payload, msg.ContentHash = payload, ""
+
+	if err := n.dispatch(providers[0], msg); err != nil {
+		log.Error().Err(err).Str("topic", topic).Str("peer", providers[0].String()).Msg("Error handling gossip message")
+	}
+}
+
+// dispatch passes a transaction, batch or consensus message authored by from to the
+// registered handler. Payloads that do not decode count against from.
+func (n *Node) dispatch(from peer.ID, msg Message) error {
+	handlers := n.GetProtocolHandlers()
+
+	switch msg.Type {
+	case MessageTransaction:
+		if handlers.OnTransaction == nil {
+			return nil
+		}
+		var tx state.Transaction
+		if err := json.Unmarshal(msg.Payload, &tx); err != nil {
+			n.penalize(from, penaltyMalformed, "undecodable transaction")
+			return fmt.Errorf("failed to unmarshal transaction: %v", err)
+		}
+		// Transactions also reach a node pulled after an announcement
+		hash := common.Hash(tx.Hash())
+		if n.seenTxs.has(hash) {
+			return nil
+		}
+		trace.Logger(trace.WithID(context.Background(), tx.TraceID)).Debug().Msg("Received gossiped transaction")
+		if err := handlers.OnTransaction(&tx); err != nil {
+			return err
+		}
+		n.seenTxs.add(hash)
+		return nil
+
+	case MessageBatch:
+		if handlers.OnBatch == nil {
+			return nil
+		}
+		var batch state.Batch
+		if err := json.Unmarshal(msg.Payload, &batch); err != nil {
+			n.penalize(from, penaltyMalformed, "undecodable batch")
+			return fmt.Errorf("failed to unmarshal batch: %v", err)
+		}
+		return handlers.OnBatch(&batch)
+
+	case MessageConsensus:
+		if handlers.OnConsensus == nil {
+			return nil
+		}
+		return handlers.OnConsensus(from, msg.Payload)
+
+	default:
+		return fmt.Errorf("unexpected message type: %d", msg.Type)
+	}
+}
+
+// GossipPeerCount returns how many peers are known to be subscribed to every gossip
+// topic, the peers messages published now reach
+func (n *Node) GossipPeerCount() int {
+	count := -1
+	for _, topic := range n.topics {
+		if peers := len(topic.ListPeers()); count < 0 || peers < count {
+			count = peers
+		}
+	}
+	return max(count, 0)
+}
+
+// publish sends a message to every node subscribed to topic
+func (n *Node) publish(ctx context.Context, topic string, msg Message) error {
+	t, ok := n.topics[topic]
+	if !ok {
+		return fmt.Errorf("not joined to topic %s", topic)
+	}
+
+	if len(msg.Payload) > MaxInlinePayloadSize {
+		msg = Message{Type: msg.Type, ContentHash: n.ProvideContent(msg.Payload)}
+		log.Debug().Str("topic", topic).Str("hash", msg.ContentHash).Msg("Announcing oversized payload by hash")
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	if err := t.Publish(ctx, data); err != nil {
+		return fmt.Errorf("failed to publish to %s: %v", topic, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/state"
+)
+
+func TestGossipReachesNewPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pulled atomic.Int32
+	provider, err := NewNodeWithOptions(ctx, 0, nil, NodeOptions{DisableDiscovery: true})
+	require.NoError(t, err)
+	defer provider.Close()
+	provider.SetupProtocols(&ProtocolHandlers{OnTxRequest: func([]common.Hash) []state.Transaction {
+		pulled.Add(1)
+		return nil
+	}})
+
+	puller, err := NewNodeWithOptions(ctx, 0, nil, NodeOptions{DisableDiscovery: true})
+	require.NoError(t, err)
+	defer puller.Close()
+	puller.SetupProtocols(&ProtocolHandlers{OnTransaction: func(*state.Transaction) error { return nil }})
+	require.NoError(t, puller.Connect(ctx, fmt.Sprintf("%s/p2p/%s", provider.Host.Addrs()[0], provider.Host.ID())))
+
+	// An announcement published as soon as the peers see each other's subscriptions, before
+	// the mesh is built, is still delivered
+	require.Eventually(t, func() bool {
+		return provider.GossipPeerCount() > 0 && puller.GossipPeerCount() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, provider.AnnounceTransactions(ctx, []common.Hash{{1}}))
+	require.Eventually(t, func() bool { return pulled.Load() > 0 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/libp2p/go-libp2p"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	p2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -18,6 +20,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
 )
 
 const (
@@ -35,41 +38,79 @@ type Node struct {
 	peersLock       sync.RWMutex
 	peers           map[peer.ID]peer.AddrInfo
 
+	// Gossipsub topics used for broadcasting, keyed by topic name
+	topics map[string]*pubsub.Topic
+
+	// Payloads served to peers over ContentProtocolID
+	content *contentStore
+
+	// Transactions received from peers, which announcements of them no longer pull
+	seenTxs *txSeenCache
+
+	// Consensus allowlist and penalties of misbehaving peers
+	guard *peerGuard
+
 	// Protocol handlers
 	handlers     *ProtocolHandlers
 	handlersLock sync.RWMutex
 }
 
+// NodeOptions tunes how a node joins the network
+type NodeOptions struct {
+	// Only talk to the bootstrap peers, without a DHT or peer discovery
+	DisableDiscovery bool
+
+	// Peers allowed to send consensus messages and batches, any peer when empty
+	ConsensusAllowlist []peer.ID
+
+	// Key the peer ID is derived from, a random key when nil
+	Identity p2pcrypto.PrivKey
+}
+
 // NewNode creates a new P2P node
 func NewNode(ctx context.Context, port int, bootstrapPeers []string) (*Node, error) {
+	return NewNodeWithOptions(ctx, port, bootstrapPeers, NodeOptions{})
+}
+
+// NewNodeWithOptions creates a new P2P node configured by opts
+func NewNodeWithOptions(ctx context.Context, port int, bootstrapPeers []string, opts NodeOptions) (*Node, error) {
 	// Log the node creation
-	log.Info().Int("port", port).Msg("Creating new P2P node")
+	log.Info().Int("port", port).Bool("discovery", !opts.DisableDiscovery).Msg("Creating new P2P node")
 	// Create multiaddr for listening
 	addr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create multiaddr: %v", err)
 	}
 
-	// Create libp2p host
-	h, err := libp2p.New(
+	// Create libp2p host, refusing connections from banned peers
+	guard := newPeerGuard(opts.ConsensusAllowlist)
+	hostOpts := []libp2p.Option{
 		libp2p.ListenAddrs(addr),
 		libp2p.EnableRelay(),
-	)
+		libp2p.ConnectionGater(guard),
+	}
+	if opts.Identity != nil {
+		hostOpts = append(hostOpts, libp2p.Identity(opts.Identity))
+	}
+	h, err := libp2p.New(hostOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create host: %v", err)
 	}
 
 	// Create DHT for peer discovery
-	kadDHT, err := dht.New(ctx, h, dht.Mode(dht.ModeServer))
-	if err != nil {
-		h.Close()
-		return nil, fmt.Errorf("failed to create DHT: %v", err)
-	}
+	var kadDHT *dht.IpfsDHT
+	if !opts.DisableDiscovery {
+		kadDHT, err = dht.New(ctx, h, dht.Mode(dht.ModeServer))
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("failed to create DHT: %v", err)
+		}
 
-	// Bootstrap the DHT
-	if err = kadDHT.Bootstrap(ctx); err != nil {
-		h.Close()
-		return nil, fmt.Errorf("failed to bootstrap DHT: %v", err)
+		// Bootstrap the DHT
+		if err = kadDHT.Bootstrap(ctx); err != nil {
+			h.Close()
+			return nil, fmt.Errorf("failed to bootstrap DHT: %v", err)
+		}
 	}
 
 	// Connect to bootstrap peers if provided
@@ -110,13 +151,29 @@ func NewNode(ctx context.Context, port int, bootstrapPeers []string) (*Node, err
 		discoveryCancel: discoveryCancel,
 		peers:           make(map[peer.ID]peer.AddrInfo),
 		handlers:        &ProtocolHandlers{}, // Initialize empty handlers
+		content:         newContentStore(),
+		seenTxs:         newTxSeenCache(),
+		guard:           guard,
 	}
 
 	// Register default protocol handlers to ensure basic protocol negotiation works
 	node.registerDefaultProtocolHandlers()
+	node.setupContentProtocol()
+
+	// Join the gossip topics, they live as long as discovery
+	if err := node.setupGossip(discoveryCtx); err != nil {
+		discoveryCancel()
+		if kadDHT != nil {
+			kadDHT.Close()
+		}
+		h.Close()
+		return nil, err
+	}
 
 	// Set up peer discovery
-	node.setupDiscovery()
+	if kadDHT != nil {
+		node.setupDiscovery()
+	}
 
 	// Print node info
 	log.Info().Str("id", h.ID().String()).Msg("Node started")
@@ -283,14 +340,15 @@ func (n *Node) registerDefaultProtocolHandlers() {
 			}
 
 			// Call the transaction handler
-			log.Info().Msg("Calling transaction handler")
+			logger := trace.Logger(trace.WithID(context.Background(), tx.TraceID))
+			logger.Info().Msg("Calling transaction handler")
 			if err := handlers.OnTransaction(&tx); err != nil {
-				log.Error().Err(err).Msg("Error handling transaction")
+				logger.Error().Err(err).Msg("Error handling transaction")
 				s.Reset()
 				return
 			}
 
-			log.Info().Msg("Transaction handled successfully")
+			logger.Info().Msg("Transaction handled successfully")
 		} else {
 			// No handler registered, just log and close
 			log.Info().Str("peer", s.Conn().RemotePeer().String()).Msg("Received transaction stream with default handler")
@@ -308,6 +366,12 @@ func (n *Node) registerDefaultProtocolHandlers() {
 		if handlers != nil && handlers.OnBatch != nil {
 			log.Info().Str("peer", s.Conn().RemotePeer().String()).Msg("Received batch stream, using registered handler")
 
+			// Only allowlisted peers propose batches
+			if !n.admitConsensus(s.Conn().RemotePeer()) {
+				s.Reset()
+				return
+			}
+
 			// Read the batch
 			var msg Message
 			if err := json.NewDecoder(s).Decode(&msg); err != nil {
@@ -355,6 +419,12 @@ func (n *Node) registerDefaultProtocolHandlers() {
 		if handlers != nil && handlers.OnConsensus != nil {
 			log.Info().Str("peer", s.Conn().RemotePeer().String()).Msg("Received consensus stream, using registered handler")
 
+			// Only allowlisted peers take part in consensus
+			if !n.admitConsensus(s.Conn().RemotePeer()) {
+				s.Reset()
+				return
+			}
+
 			// Read the consensus message
 			var msg Message
 			if err := json.NewDecoder(s).Decode(&msg); err != nil {
@@ -371,7 +441,7 @@ func (n *Node) registerDefaultProtocolHandlers() {
 
 			// Call the consensus handler
 			log.Info().Msg("Calling consensus handler")
-			if err := handlers.OnConsensus(msg.Payload); err != nil {
+			if err := handlers.OnConsensus(s.Conn().RemotePeer(), msg.Payload); err != nil {
 				log.Error().Err(err).Msg("Error handling consensus message")
 				s.Reset()
 				return
@@ -395,8 +465,10 @@ func (n *Node) Close() error {
 	n.discoveryCancel()
 
 	// Close DHT
-	if err := n.dht.Close(); err != nil {
-		log.Error().Err(err).Msg("Error closing DHT")
+	if n.dht != nil {
+		if err := n.dht.Close(); err != nil {
+			log.Error().Err(err).Msg("Error closing DHT")
+		}
 	}
 
 	// Close host
@@ -415,8 +487,15 @@ func (n *Node) GetProtocolHandlers() *ProtocolHandlers {
 
 	// Create a new handlers struct with the same function references
 	return &ProtocolHandlers{
-		OnTransaction: n.handlers.OnTransaction,
-		OnBatch:       n.handlers.OnBatch,
-		OnConsensus:   n.handlers.OnConsensus,
+		OnTransaction:     n.handlers.OnTransaction,
+		OnBatch:           n.handlers.OnBatch,
+		OnConsensus:       n.handlers.OnConsensus,
+		OnFinalizedBatch:  n.handlers.OnFinalizedBatch,
+		OnBatchRequest:    n.handlers.OnBatchRequest,
+		OnSnapshotRequest: n.handlers.OnSnapshotRequest,
+		OnKeyInfoRequest:  n.handlers.OnKeyInfoRequest,
+		OnTxRequest:       n.handlers.OnTxRequest,
+
+		OnFinalizedSnapshotRequest: n.handlers.OnFinalizedSnapshotRequest,
 	}
 }
@@ -0,0 +1,133 @@
+package p2p
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	p2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/rs/zerolog/log"
+)
+
+// identityFile is the on-disk form of a node's libp2p key. The key is either stored in the
+// clear or, with a passphrase, encrypted the way go-ethereum's keystore encrypts account
+// keys (scrypt and AES-128-CTR).
+type identityFile struct {
+	PeerID     string               `json:"peerId"`
+	PrivateKey string               `json:"privateKey,omitempty"` // Hex of the protobuf-encoded key
+	Crypto     *keystore.CryptoJSON `json:"crypto,omitempty"`
+}
+
+// LoadOrCreateIdentity returns the libp2p key stored at path, generating and saving a new
+// Ed25519 key when the file does not exist, so the node keeps its peer ID across restarts.
+// A non-empty passphrase encrypts a new key and is required to open an encrypted one.
+func LoadOrCreateIdentity(path, passphrase string) (p2pcrypto.PrivKey, error) {
+	key, err := LoadIdentity(path, passphrase)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, _, err = p2pcrypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity key: %v", err)
+	}
+	if err := SaveIdentity(path, key, passphrase); err != nil {
+		return nil, err
+	}
+	id, _ := peer.IDFromPrivateKey(key)
+	log.Info().Str("path", path).Str("peer_id", id.String()).Bool("encrypted", passphrase != "").Msg("Created node identity")
+	return key, nil
+}
+
+// LoadIdentity reads the libp2p key stored at path
+func LoadIdentity(path, passphrase string) (p2pcrypto.PrivKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity: %w", err)
+	}
+	var file identityFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode identity %s: %v", path, err)
+	}
+
+	var raw []byte
+	switch {
+	case file.Crypto != nil:
+		if passphrase == "" {
+			return nil, fmt.Errorf("identity %s is encrypted, a passphrase is required", path)
+		}
+		if raw, err = keystore.DecryptDataV3(*file.Crypto, passphrase); err != nil {
+			return nil, fmt.Errorf("failed to decrypt identity %s: %v", path, err)
+		}
+	case file.PrivateKey != "":
+		if raw, err = hex.DecodeString(file.PrivateKey); err != nil {
+			return nil, fmt.Errorf("failed to decode identity %s: %v", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("identity %s holds no key", path)
+	}
+
+	key, err := p2pcrypto.UnmarshalPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode identity %s: %v", path, err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive peer ID: %v", err)
+	}
+	if file.PeerID != "" && file.PeerID != id.String() {
+		return nil, fmt.Errorf("identity %s is for peer %s but holds the key of %s", path, file.PeerID, id)
+	}
+	return key, nil
+}
+
+// SaveIdentity writes a libp2p key to path, readable only by the owner, encrypting it when
+// a passphrase is given
+func SaveIdentity(path string, key p2pcrypto.PrivKey, passphrase string) error {
+	raw, err := p2pcrypto.MarshalPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode identity key: %v", err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to derive peer ID: %v", err)
+	}
+
+	file := identityFile{PeerID: id.String()}
+	if passphrase != "" {
+		encrypted, err := keystore.EncryptDataV3(raw, []byte(passphrase), keystore.StandardScryptN, keystore.StandardScryptP)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt identity key: %v", err)
+		}
+		file.Crypto = &encrypted
+	} else {
+		file.PrivateKey = hex.EncodeToString(raw)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode identity: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create identity directory: %v", err)
+	}
+	// Write to a temporary file first so a crash never leaves a truncated key behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write identity: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write identity: %v", err)
+	}
+	return nil
+}
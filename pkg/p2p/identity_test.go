@@ -0,0 +1,39 @@
+package p2p
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "identity.json")
+
+	key, err := LoadOrCreateIdentity(path, "")
+	require.NoError(t, err)
+	reloaded, err := LoadOrCreateIdentity(path, "")
+	require.NoError(t, err)
+	require.True(t, key.Equals(reloaded))
+}
+
+func TestEncryptedIdentity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.json")
+
+	key, err := LoadOrCreateIdentity(path, "secret")
+	require.NoError(t, err)
+
+	_, err = LoadIdentity(path, "")
+	require.Error(t, err)
+	_, err = LoadIdentity(path, "wrong")
+	require.Error(t, err)
+
+	reloaded, err := LoadIdentity(path, "secret")
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(key)
+	require.NoError(t, err)
+	reloadedID, err := peer.IDFromPrivateKey(reloaded)
+	require.NoError(t, err)
+	require.Equal(t, id, reloadedID)
+}
@@ -0,0 +1,100 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/rs/zerolog/log"
+)
+
+// KeyInfoProtocolID lets a peer ask which batch circuit keys a node proves with
+const KeyInfoProtocolID = protocol.ID("/zkrollup/keys/1.0.0")
+
+// KeyInfo describes the batch circuit keys a node proves with
+type KeyInfo struct {
+	Epoch       uint64 `json:"epoch"`       // CRS epoch of the keys, 0 for locally set up keys
+	Capacity    int    `json:"capacity"`    // Transactions per batch the circuit holds
+	Fingerprint string `json:"fingerprint"` // Hex SHA-256 of the verifying key
+}
+
+// setupKeyInfoProtocol registers the handler answering key info requests
+func (n *Node) setupKeyInfoProtocol() {
+	n.Host.RemoveStreamHandler(KeyInfoProtocolID)
+	n.Host.SetStreamHandler(KeyInfoProtocolID, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(time.Second * 10))
+
+		var msg Message
+		if err := json.NewDecoder(s).Decode(&msg); err != nil {
+			log.Error().Err(err).Msg("Error decoding key info request")
+			s.Reset()
+			return
+		}
+		if msg.Type != MessageKeyInfoRequest {
+			log.Error().Int("type", int(msg.Type)).Msg("Invalid message type for key info protocol")
+			s.Reset()
+			return
+		}
+
+		handlers := n.GetProtocolHandlers()
+		if handlers.OnKeyInfoRequest == nil {
+			s.Reset()
+			return
+		}
+		info, err := handlers.OnKeyInfoRequest()
+		if err != nil {
+			log.Error().Err(err).Msg("Error serving key info request")
+			s.Reset()
+			return
+		}
+
+		payload, err := json.Marshal(info)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal key info")
+			s.Reset()
+			return
+		}
+		if err := json.NewEncoder(s).Encode(Message{Type: MessageKeyInfo, Payload: payload}); err != nil {
+			log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to send key info")
+		}
+	})
+}
+
+// RequestKeyInfo asks a peer which batch circuit keys it proves with
+func (n *Node) RequestKeyInfo(ctx context.Context, peerID peer.ID) (*KeyInfo, error) {
+	streamCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	stream, err := n.Host.NewStream(streamCtx, peerID, KeyInfoProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key info stream: %v", err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(time.Second * 10))
+
+	if err := json.NewEncoder(stream).Encode(Message{Type: MessageKeyInfoRequest}); err != nil {
+		return nil, fmt.Errorf("failed to send key info request: %v", err)
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return nil, fmt.Errorf("failed to close key info request: %v", err)
+	}
+
+	var resp Message
+	if err := json.NewDecoder(stream).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read key info: %v", err)
+	}
+	if resp.Type != MessageKeyInfo {
+		return nil, fmt.Errorf("unexpected response type: %d", resp.Type)
+	}
+
+	var info KeyInfo
+	if err := json.Unmarshal(resp.Payload, &info); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal key info: %v", err)
+	}
+	return &info, nil
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
 
 	"zkrollup/pkg/state"
@@ -26,19 +28,49 @@ const (
 	MessageTransaction MessageType = iota
 	MessageBatch
 	MessageConsensus
+	MessageFinalizedBatch
+	MessageBatchRequest
+	MessageBatchResponse
+	MessageStateSyncRequest
+	MessageStateSnapshot
+	MessageStateSyncDone
+	MessageContentRequest
+	MessageKeyInfoRequest
+	MessageKeyInfo
+	MessageTxAnnouncement
+	MessageTxRequest
+	MessageTxResponse
 )
 
 // Message represents a P2P network message
 type Message struct {
 	Type    MessageType `json:"type"`
 	Payload []byte      `json:"payload"`
+
+	// Hash of a payload too large to send inline, fetched over ContentProtocolID instead
+	ContentHash string `json:"content_hash,omitempty"`
 }
 
 // Protocol handlers
 type ProtocolHandlers struct {
 	OnTransaction func(tx *state.Transaction) error
 	OnBatch       func(batch *state.Batch) error
-	OnConsensus   func(msg []byte) error
+	OnConsensus   func(from peer.ID, msg []byte) error // from is the authenticated sender
+
+	// Finalized batch announcements and pulls of missed batches
+	OnFinalizedBatch func(from peer.ID, batch *state.Batch) error
+	OnBatchRequest   func(from, count uint64) ([]state.Batch, error)
+
+	// Account state served to nodes bootstrapping through state sync, and the newest
+	// snapshot at a batch final on L1 served to nodes fast syncing
+	OnSnapshotRequest          func() (*state.Snapshot, error)
+	OnFinalizedSnapshotRequest func() (*state.Snapshot, error)
+
+	// Batch circuit keys, compared by peers to detect incompatible provers
+	OnKeyInfoRequest func() (*KeyInfo, error)
+
+	// Pool transactions pulled by peers after an announcement, those still held are returned
+	OnTxRequest func(hashes []common.Hash) []state.Transaction
 }
 
 // Protocol handlers are stored in the Node struct
@@ -71,22 +103,25 @@ func (n *Node) SetupProtocols(handlers *ProtocolHandlers) {
 		var msg Message
 		if err := json.NewDecoder(s).Decode(&msg); err != nil {
 			fmt.Printf("Error decoding transaction message: %v\n", err)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "undecodable transaction message")
 			return
 		}
 
 		if msg.Type != MessageTransaction {
 			fmt.Printf("Invalid message type for transaction protocol: %d\n", msg.Type)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "wrong message type for transaction protocol")
 			return
 		}
 
 		var tx state.Transaction
 		if err := json.Unmarshal(msg.Payload, &tx); err != nil {
 			fmt.Printf("Error unmarshaling transaction: %v\n", err)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "undecodable transaction")
 			return
 		}
 
 		fmt.Printf("Successfully decoded transaction from %s to %s\n",
-			fmt.Sprintf("%x", tx.From), fmt.Sprintf("%x", tx.To))
+			tx.From.Hex(), tx.To.Hex())
 
 		nonceStr := fmt.Sprintf("%d", tx.Nonce)
 		fmt.Printf("Using nonce string format '%s' for consistent hash computation\n", nonceStr)
@@ -123,23 +158,32 @@ func (n *Node) SetupProtocols(handlers *ProtocolHandlers) {
 		fmt.Printf("Received batch stream from %s\n", s.Conn().RemotePeer().String())
 		defer s.Close()
 
+		// Only allowlisted peers propose batches
+		if !n.admitConsensus(s.Conn().RemotePeer()) {
+			s.Reset()
+			return
+		}
+
 		// Set read deadline
 		s.SetReadDeadline(time.Now().Add(time.Second * 10))
 
 		var msg Message
 		if err := json.NewDecoder(s).Decode(&msg); err != nil {
 			fmt.Printf("Error decoding batch message: %v\n", err)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "undecodable batch message")
 			return
 		}
 
 		if msg.Type != MessageBatch {
 			fmt.Printf("Invalid message type for batch protocol: %d\n", msg.Type)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "wrong message type for batch protocol")
 			return
 		}
 
 		var batch state.Batch
 		if err := json.Unmarshal(msg.Payload, &batch); err != nil {
 			fmt.Printf("Error unmarshaling batch: %v\n", err)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "undecodable batch")
 			return
 		}
 
@@ -172,17 +216,25 @@ func (n *Node) SetupProtocols(handlers *ProtocolHandlers) {
 		fmt.Printf("Received consensus stream from %s\n", s.Conn().RemotePeer().String())
 		defer s.Close()
 
+		// Only allowlisted peers take part in consensus
+		if !n.admitConsensus(s.Conn().RemotePeer()) {
+			s.Reset()
+			return
+		}
+
 		// Set read deadline
 		s.SetReadDeadline(time.Now().Add(time.Second * 10))
 
 		var msg Message
 		if err := json.NewDecoder(s).Decode(&msg); err != nil {
 			fmt.Printf("Error decoding consensus message: %v\n", err)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "undecodable consensus message")
 			return
 		}
 
 		if msg.Type != MessageConsensus {
 			fmt.Printf("Invalid message type for consensus protocol: %d\n", msg.Type)
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "wrong message type for consensus protocol")
 			return
 		}
 
@@ -200,7 +252,7 @@ func (n *Node) SetupProtocols(handlers *ProtocolHandlers) {
 
 		// Call the consensus handler
 		fmt.Printf("Calling consensus handler\n")
-		if err := handlers.OnConsensus(msg.Payload); err != nil {
+		if err := handlers.OnConsensus(s.Conn().RemotePeer(), msg.Payload); err != nil {
 			fmt.Printf("Error handling consensus message: %v\n", err)
 			return
 		}
@@ -209,9 +261,14 @@ func (n *Node) SetupProtocols(handlers *ProtocolHandlers) {
 	})
 
 	fmt.Printf("Consensus protocol handler registered for %s\n", ConsensusProtocolID)
+
+	n.setupBatchSyncProtocols()
+	n.setupStateSyncProtocol()
+	n.setupKeyInfoProtocol()
+	n.setupTxPoolProtocol()
 }
 
-// BroadcastTransaction broadcasts a transaction to all connected peers
+// BroadcastTransaction gossips a transaction to every node
 func (n *Node) BroadcastTransaction(ctx context.Context, tx *state.Transaction) error {
 	payload, err := json.Marshal(tx)
 	if err != nil {
@@ -223,10 +280,10 @@ func (n *Node) BroadcastTransaction(ctx context.Context, tx *state.Transaction)
 		Payload: payload,
 	}
 
-	return n.broadcast(ctx, TransactionProtocolID, msg)
+	return n.publish(ctx, TransactionTopic, msg)
 }
 
-// BroadcastBatch broadcasts a batch to all connected peers
+// BroadcastBatch gossips a batch to every node
 func (n *Node) BroadcastBatch(ctx context.Context, batch *state.Batch) error {
 	payload, err := json.Marshal(batch)
 	if err != nil {
@@ -238,10 +295,10 @@ func (n *Node) BroadcastBatch(ctx context.Context, batch *state.Batch) error {
 		Payload: payload,
 	}
 
-	return n.broadcast(ctx, BatchProtocolID, msg)
+	return n.publish(ctx, BatchTopic, msg)
 }
 
-// BroadcastConsensus broadcasts a consensus message to all connected peers
+// BroadcastConsensus gossips a consensus message to every node
 func (n *Node) BroadcastConsensus(ctx context.Context, payload []byte) error {
 	msg := Message{
 		Type:    MessageConsensus,
@@ -251,16 +308,12 @@ func (n *Node) BroadcastConsensus(ctx context.Context, payload []byte) error {
 	// Log the broadcast for debugging
 	fmt.Printf("Broadcasting consensus message of size %d bytes\n", len(payload))
 
-	// Ensure we have peers to broadcast to
-	peers := n.GetPeers()
-	if len(peers) == 0 {
-		fmt.Printf("No peers available for consensus broadcast\n")
-	}
-
-	return n.broadcast(ctx, ConsensusProtocolID, msg)
+	return n.publish(ctx, ConsensusTopic, msg)
 }
 
-// broadcast sends a message to all connected peers
+// broadcast sends a message to each connected peer over a direct stream. Gossip does not
+// tell whether anyone received a message, so this is kept for messages whose delivery
+// must be confirmed.
 func (n *Node) broadcast(ctx context.Context, protocolID protocol.ID, msg Message) error {
 	peers := n.GetPeers()
 	if len(peers) == 0 {
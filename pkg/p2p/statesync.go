@@ -0,0 +1,228 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+const (
+	// StateSyncProtocolID serves an account state snapshot followed by the batch history
+	// leading up to it, so a new node can catch up without replaying every batch
+	StateSyncProtocolID = protocol.ID("/zkrollup/sync/1.0.0")
+
+	// stateSyncMessageTimeout bounds the wait for each message of a state sync stream
+	stateSyncMessageTimeout = time.Second * 30
+)
+
+// StateSyncRequest asks a peer for its state snapshot and the batches from From up to it.
+// With Finalized it asks for the newest snapshot at a batch final on L1 instead, without
+// any batches.
+type StateSyncRequest struct {
+	From      uint64 `json:"from"`
+	Finalized bool   `json:"finalized,omitempty"`
+}
+
+// setupStateSyncProtocol registers the state sync stream handler. A request is answered
+// with a snapshot message, then the batch history in chunks of MaxBatchesPerRequest and a
+// final done message. Finalized requests get no history.
+func (n *Node) setupStateSyncProtocol() {
+	n.Host.RemoveStreamHandler(StateSyncProtocolID)
+	n.Host.SetStreamHandler(StateSyncProtocolID, func(s network.Stream) {
+		defer s.Close()
+		s.SetReadDeadline(time.Now().Add(stateSyncMessageTimeout))
+
+		var msg Message
+		if err := json.NewDecoder(s).Decode(&msg); err != nil {
+			log.Error().Err(err).Msg("Error decoding state sync request")
+			s.Reset()
+			return
+		}
+		if msg.Type != MessageStateSyncRequest {
+			log.Error().Int("type", int(msg.Type)).Msg("Invalid message type for state sync protocol")
+			s.Reset()
+			return
+		}
+
+		var req StateSyncRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Error().Err(err).Msg("Error unmarshaling state sync request")
+			s.Reset()
+			return
+		}
+
+		handlers := n.GetProtocolHandlers()
+		takeSnapshot := handlers.OnSnapshotRequest
+		if req.Finalized {
+			takeSnapshot = handlers.OnFinalizedSnapshotRequest
+		}
+		if takeSnapshot == nil || handlers.OnBatchRequest == nil {
+			log.Warn().Str("peer", s.Conn().RemotePeer().String()).Bool("finalized", req.Finalized).Msg("State sync requested but no snapshot handler registered")
+			s.Reset()
+			return
+		}
+
+		snap, err := takeSnapshot()
+		if err != nil {
+			log.Warn().Err(err).Bool("finalized", req.Finalized).Msg("Error taking state snapshot")
+			s.Reset()
+			return
+		}
+		if req.Finalized {
+			req.From = snap.BatchNumber
+		}
+
+		enc := json.NewEncoder(s)
+		send := func(msgType MessageType, v interface{}) error {
+			payload, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			s.SetWriteDeadline(time.Now().Add(stateSyncMessageTimeout))
+			return enc.Encode(Message{Type: msgType, Payload: payload})
+		}
+
+		if err := send(MessageStateSnapshot, snap); err != nil {
+			log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to send state snapshot")
+			s.Reset()
+			return
+		}
+
+		// Stream the history up to the snapshot height, later batches reach the peer
+		// through finalized batch announcements
+		for from := req.From; from < snap.BatchNumber; {
+			count := snap.BatchNumber - from
+			if count > MaxBatchesPerRequest {
+				count = MaxBatchesPerRequest
+			}
+
+			batches, err := handlers.OnBatchRequest(from, count)
+			if err != nil || len(batches) == 0 {
+				log.Error().Err(err).Uint64("from", from).Msg("Error reading batches for state sync")
+				s.Reset()
+				return
+			}
+			if err := send(MessageBatchResponse, batches); err != nil {
+				log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to send batches")
+				s.Reset()
+				return
+			}
+			from += uint64(len(batches))
+		}
+
+		if err := send(MessageStateSyncDone, struct{}{}); err != nil {
+			log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to finish state sync")
+			return
+		}
+
+		log.Info().Str("peer", s.Conn().RemotePeer().String()).Uint64("from", req.From).Uint64("batch_number", snap.BatchNumber).Msg("Served state sync")
+	})
+}
+
+// SyncState downloads the state snapshot of a peer along with the batches from from up to
+// the snapshot height
+func (n *Node) SyncState(ctx context.Context, peerID peer.ID, from uint64) (*state.Snapshot, []state.Batch, error) {
+	return n.syncState(ctx, peerID, StateSyncRequest{From: from})
+}
+
+// FastSyncState downloads the newest snapshot a peer holds at a batch final on L1. No
+// batch history comes with it, the caller must check the snapshot against L1 itself.
+func (n *Node) FastSyncState(ctx context.Context, peerID peer.ID) (*state.Snapshot, error) {
+	snap, _, err := n.syncState(ctx, peerID, StateSyncRequest{Finalized: true})
+	return snap, err
+}
+
+// syncState sends a state sync request and reads the snapshot and batches it is answered with
+func (n *Node) syncState(ctx context.Context, peerID peer.ID, req StateSyncRequest) (*state.Snapshot, []state.Batch, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal state sync request: %v", err)
+	}
+
+	streamCtx, cancel := context.WithTimeout(ctx, stateSyncMessageTimeout)
+	defer cancel()
+
+	stream, err := n.Host.NewStream(streamCtx, peerID, StateSyncProtocolID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open state sync stream: %v", err)
+	}
+	defer stream.Close()
+
+	stream.SetWriteDeadline(time.Now().Add(stateSyncMessageTimeout))
+	if err := json.NewEncoder(stream).Encode(Message{Type: MessageStateSyncRequest, Payload: payload}); err != nil {
+		return nil, nil, fmt.Errorf("failed to send state sync request: %v", err)
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return nil, nil, fmt.Errorf("failed to close state sync request: %v", err)
+	}
+
+	dec := json.NewDecoder(stream)
+	read := func() (Message, error) {
+		var msg Message
+		stream.SetReadDeadline(time.Now().Add(stateSyncMessageTimeout))
+		err := dec.Decode(&msg)
+		return msg, err
+	}
+
+	msg, err := read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read state snapshot: %v", err)
+	}
+	if msg.Type != MessageStateSnapshot {
+		return nil, nil, fmt.Errorf("unexpected response type: %d", msg.Type)
+	}
+
+	var snap state.Snapshot
+	if err := json.Unmarshal(msg.Payload, &snap); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal state snapshot: %v", err)
+	}
+
+	// A finalized snapshot comes without history
+	from := req.From
+	if req.Finalized {
+		from = snap.BatchNumber
+	}
+
+	var batches []state.Batch
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		msg, err := read()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read synced batches: %v", err)
+		}
+		if msg.Type == MessageStateSyncDone {
+			break
+		}
+		if msg.Type != MessageBatchResponse {
+			return nil, nil, fmt.Errorf("unexpected response type: %d", msg.Type)
+		}
+
+		var chunk []state.Batch
+		if err := json.Unmarshal(msg.Payload, &chunk); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal synced batches: %v", err)
+		}
+		for _, batch := range chunk {
+			if next := from + uint64(len(batches)); batch.BatchNumber != next {
+				return nil, nil, fmt.Errorf("unexpected synced batch %d, expected %d", batch.BatchNumber, next)
+			}
+			batches = append(batches, batch)
+		}
+	}
+
+	if got := from + uint64(len(batches)); got < snap.BatchNumber {
+		return nil, nil, fmt.Errorf("state sync ended at batch %d before snapshot height %d", got, snap.BatchNumber)
+	}
+
+	return &snap, batches, nil
+}
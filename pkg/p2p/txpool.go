@@ -0,0 +1,268 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// Pool transactions are announced by hash on TxAnnouncementTopic. A node pulls the ones it
+// has not received yet from the announcer over TxPoolProtocolID, and remembers the hashes
+// of the transactions it took so repeated announcements cost nothing.
+
+const (
+	// TxAnnouncementTopic carries the hashes of transactions held in a node's pool
+	TxAnnouncementTopic = "zkrollup/txhashes/1.0.0"
+
+	// TxPoolProtocolID lets a peer pull announced transactions it does not hold
+	TxPoolProtocolID = protocol.ID("/zkrollup/txpool/1.0.0")
+
+	// MaxTxHashesPerAnnouncement caps the hashes of one announcement or pull request
+	MaxTxHashesPerAnnouncement = 4096
+
+	// seenTxCacheSize is how many received transaction hashes a node remembers
+	seenTxCacheSize = 1 << 16
+)
+
+// TxAnnouncement lists transaction hashes, announced to peers or requested from one
+type TxAnnouncement struct {
+	Hashes []common.Hash `json:"hashes"`
+}
+
+// txSeenCache remembers the transactions a node received, oldest forgotten first, and the
+// ones being pulled so concurrent announcements fetch them once
+type txSeenCache struct {
+	lock    sync.Mutex
+	seen    map[common.Hash]struct{}
+	order   []common.Hash
+	pulling map[common.Hash]struct{}
+}
+
+func newTxSeenCache() *txSeenCache {
+	return &txSeenCache{
+		seen:    make(map[common.Hash]struct{}),
+		pulling: make(map[common.Hash]struct{}),
+	}
+}
+
+// has reports whether a transaction was received
+func (c *txSeenCache) has(hash common.Hash) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, ok := c.seen[hash]
+	return ok
+}
+
+// add records a received transaction
+func (c *txSeenCache) add(hash common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.pulling, hash)
+	if _, ok := c.seen[hash]; ok {
+		return
+	}
+	c.seen[hash] = struct{}{}
+	c.order = append(c.order, hash)
+	if len(c.order) > seenTxCacheSize {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// claim returns the hashes neither received nor being pulled, which the caller then pulls
+func (c *txSeenCache) claim(hashes []common.Hash) []common.Hash {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var missing []common.Hash
+	for _, hash := range hashes {
+		if _, ok := c.seen[hash]; ok {
+			continue
+		}
+		if _, ok := c.pulling[hash]; ok {
+			continue
+		}
+		c.pulling[hash] = struct{}{}
+		missing = append(missing, hash)
+	}
+	return missing
+}
+
+// release ends the pull of hashes, those not received may be claimed again
+func (c *txSeenCache) release(hashes []common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, hash := range hashes {
+		delete(c.pulling, hash)
+	}
+}
+
+// setupTxPoolProtocol registers the handler serving pulled transactions
+func (n *Node) setupTxPoolProtocol() {
+	n.Host.RemoveStreamHandler(TxPoolProtocolID)
+	n.Host.SetStreamHandler(TxPoolProtocolID, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(time.Second * 10))
+
+		var msg Message
+		if err := json.NewDecoder(s).Decode(&msg); err != nil {
+			log.Error().Err(err).Msg("Error decoding transaction request")
+			s.Reset()
+			return
+		}
+		if msg.Type != MessageTxRequest {
+			log.Error().Int("type", int(msg.Type)).Msg("Invalid message type for transaction pool protocol")
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "wrong message type for transaction pool protocol")
+			s.Reset()
+			return
+		}
+
+		var req TxAnnouncement
+		if err := json.Unmarshal(msg.Payload, &req); err != nil || len(req.Hashes) > MaxTxHashesPerAnnouncement {
+			n.penalize(s.Conn().RemotePeer(), penaltyMalformed, "invalid transaction request")
+			s.Reset()
+			return
+		}
+
+		handlers := n.GetProtocolHandlers()
+		var txs []state.Transaction
+		if handlers.OnTxRequest != nil {
+			txs = handlers.OnTxRequest(req.Hashes)
+		}
+
+		payload, err := json.Marshal(txs)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal transactions")
+			s.Reset()
+			return
+		}
+		if err := json.NewEncoder(s).Encode(Message{Type: MessageTxResponse, Payload: payload}); err != nil {
+			log.Error().Err(err).Str("peer", s.Conn().RemotePeer().String()).Msg("Failed to send transactions")
+		}
+	})
+}
+
+// AnnounceTransactions gossips the hashes of transactions this node holds, peers missing
+// any of them pull them from it
+func (n *Node) AnnounceTransactions(ctx context.Context, hashes []common.Hash) error {
+	for _, hash := range hashes {
+		n.seenTxs.add(hash)
+	}
+
+	for len(hashes) > 0 {
+		chunk := hashes[:min(len(hashes), MaxTxHashesPerAnnouncement)]
+		hashes = hashes[len(chunk):]
+
+		payload, err := json.Marshal(TxAnnouncement{Hashes: chunk})
+		if err != nil {
+			return fmt.Errorf("failed to marshal transaction announcement: %v", err)
+		}
+		if err := n.publish(ctx, TxAnnouncementTopic, Message{Type: MessageTxAnnouncement, Payload: payload}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestTransactions pulls the transactions with the given hashes from a peer, which
+// returns the ones it still holds
+func (n *Node) RequestTransactions(ctx context.Context, peerID peer.ID, hashes []common.Hash) ([]state.Transaction, error) {
+	payload, err := json.Marshal(TxAnnouncement{Hashes: hashes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transaction request: %v", err)
+	}
+
+	streamCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	stream, err := n.Host.NewStream(streamCtx, peerID, TxPoolProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transaction pool stream: %v", err)
+	}
+	defer stream.Close()
+	stream.SetDeadline(time.Now().Add(time.Second * 10))
+
+	if err := json.NewEncoder(stream).Encode(Message{Type: MessageTxRequest, Payload: payload}); err != nil {
+		return nil, fmt.Errorf("failed to send transaction request: %v", err)
+	}
+	if err := stream.CloseWrite(); err != nil {
+		return nil, fmt.Errorf("failed to close transaction request: %v", err)
+	}
+
+	var resp Message
+	if err := json.NewDecoder(stream).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %v", err)
+	}
+	if resp.Type != MessageTxResponse {
+		return nil, fmt.Errorf("unexpected response type: %d", resp.Type)
+	}
+
+	var txs []state.Transaction
+	if err := json.Unmarshal(resp.Payload, &txs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transactions: %v", err)
+	}
+	return txs, nil
+}
+
+// pullAnnouncedTransactions pulls the announced transactions this node has not received
+// from the first provider that answers, and hands them to the transaction handler
+func (n *Node) pullAnnouncedTransactions(ctx context.Context, msg Message, providers ...peer.ID) {
+	var ann TxAnnouncement
+	if err := json.Unmarshal(msg.Payload, &ann); err != nil || len(ann.Hashes) > MaxTxHashesPerAnnouncement {
+		n.penalize(providers[0], penaltyMalformed, "invalid transaction announcement")
+		return
+	}
+
+	handlers := n.GetProtocolHandlers()
+	if handlers.OnTransaction == nil {
+		return
+	}
+	missing := n.seenTxs.claim(ann.Hashes)
+	if len(missing) == 0 {
+		return
+	}
+	defer n.seenTxs.release(missing)
+
+	requested := make(map[common.Hash]bool, len(missing))
+	for _, hash := range missing {
+		requested[hash] = true
+	}
+
+	for i, provider := range providers {
+		if i > 0 && provider == providers[i-1] {
+			continue
+		}
+		txs, err := n.RequestTransactions(ctx, provider, missing)
+		if err != nil {
+			log.Debug().Err(err).Str("peer", provider.String()).Msg("Failed to pull announced transactions")
+			continue
+		}
+
+		for i := range txs {
+			tx := txs[i]
+			hash := common.Hash(tx.Hash())
+			if !requested[hash] {
+				n.penalize(provider, penaltyMalformed, "unrequested transaction")
+				continue
+			}
+			delete(requested, hash)
+			if err := handlers.OnTransaction(&tx); err != nil {
+				log.Debug().Err(err).Str("hash", hash.Hex()).Msg("Pulled transaction not admitted")
+				continue
+			}
+			n.seenTxs.add(hash)
+		}
+		return
+	}
+}
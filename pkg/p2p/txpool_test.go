@@ -0,0 +1,31 @@
+package p2p
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxSeenCache(t *testing.T) {
+	c := newTxSeenCache()
+	a, b := common.Hash{1}, common.Hash{2}
+
+	// A hash is pulled once while its pull is running
+	require.Equal(t, []common.Hash{a, b}, c.claim([]common.Hash{a, b}))
+	require.Empty(t, c.claim([]common.Hash{a, b}))
+
+	// Received transactions are not pulled again, failed pulls are retried
+	c.add(a)
+	c.release([]common.Hash{a, b})
+	require.True(t, c.has(a))
+	require.Equal(t, []common.Hash{b}, c.claim([]common.Hash{a, b}))
+
+	// The oldest hashes are forgotten first
+	for i := uint64(0); i < seenTxCacheSize; i++ {
+		c.add(common.BigToHash(new(big.Int).SetUint64(i + 3)))
+	}
+	require.False(t, c.has(a))
+	require.True(t, c.has(common.BigToHash(big.NewInt(3))))
+}
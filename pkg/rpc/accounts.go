@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
+)
+
+// registerKeyParams are the parameters of rollup_registerKey
+type registerKeyParams struct {
+	PublicKey hexutil.Bytes `json:"publicKey"` // Compressed EdDSA/BN254 public key
+	Nonce     uint64        `json:"nonce"`
+	Signature hexutil.Bytes `json:"signature"` // EdDSA signature of the registration by the key
+	GasPrice  string        `json:"gasPrice,omitempty"`
+	ChainID   uint64        `json:"chainId,omitempty"`
+}
+
+// handleRegisterKey handles the rollup_registerKey method. It submits a transaction that
+// creates the rollup-native account of an EdDSA public key, signed by that key. Once it is
+// included, the account's transactions are signed with the key instead of secp256k1.
+func (s *Server) handleRegisterKey(ctx context.Context, w http.ResponseWriter, req *JSONRPCRequest) {
+	ctx = trace.WithID(ctx, trace.NewID())
+
+	var params []registerKeyParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	address, err := state.EdDSAAddress(params[0].PublicKey)
+	if err != nil {
+		writeError(w, req, -32602, err.Error())
+		return
+	}
+
+	tx := state.Transaction{
+		Type:      state.TxTypeRegisterKey,
+		From:      address,
+		Amount:    new(big.Int),
+		Nonce:     params[0].Nonce,
+		Data:      params[0].PublicKey,
+		Signature: params[0].Signature,
+		ChainID:   params[0].ChainID,
+	}
+	if params[0].GasPrice != "" {
+		if tx.GasPrice = state.ParseAmount(params[0].GasPrice); tx.GasPrice == nil {
+			writeError(w, req, -32602, "Invalid gas price format")
+			return
+		}
+	}
+
+	s.submitTransaction(ctx, w, req, tx)
+}
+
+// handleGetAuthKey handles the rollup_getAuthKey method. It returns the EdDSA key of a
+// rollup-native account, or null for an account signing with secp256k1.
+func (s *Server) handleGetAuthKey(w http.ResponseWriter, req *JSONRPCRequest) {
+	account, result, ok := s.accountParam(w, req)
+	if !ok {
+		return
+	}
+
+	result["authKey"] = nil
+	if account != nil && len(account.AuthKey) > 0 {
+		result["authKey"] = fmt.Sprintf("0x%x", account.AuthKey)
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
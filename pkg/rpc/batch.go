@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// requestLimits bounds what a single HTTP request may ask of the server
+type requestLimits struct {
+	maxBodyBytes int64
+	maxBatchSize int
+	timeout      time.Duration // Deadline of each call, 0 for none
+}
+
+// defaultRequestLimits apply until SetRequestLimits is called
+var defaultRequestLimits = requestLimits{
+	maxBodyBytes: 5 * 1024 * 1024,
+	maxBatchSize: 100,
+	timeout:      30 * time.Second,
+}
+
+// SetRequestLimits sets the largest request body accepted, the most calls in a batch
+// array, and how long each call may run before it is answered with an error. A zero
+// timeout lets calls run to completion.
+func (s *Server) SetRequestLimits(maxBodyBytes int64, maxBatchSize int, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = requestLimits{
+		maxBodyBytes: maxBodyBytes,
+		maxBatchSize: maxBatchSize,
+		timeout:      timeout,
+	}
+}
+
+// isBatch reports whether a request body is a JSON-RPC batch array
+func isBatch(body []byte) bool {
+	body = bytes.TrimLeft(body, " \t\r\n")
+	return len(body) > 0 && body[0] == '['
+}
+
+// handleBatch runs the calls of a batch array in order and answers with the array of their
+// responses
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request, body []byte, limits requestLimits) {
+	var calls []json.RawMessage
+	if err := json.Unmarshal(body, &calls); err != nil {
+		writeError(w, &JSONRPCRequest{}, -32700, "Parse error")
+		return
+	}
+	if len(calls) == 0 {
+		writeError(w, &JSONRPCRequest{}, -32600, "Invalid Request")
+		return
+	}
+	if len(calls) > limits.maxBatchSize {
+		writeError(w, &JSONRPCRequest{}, -32600, fmt.Sprintf("Batch of %d calls exceeds the limit of %d", len(calls), limits.maxBatchSize))
+		return
+	}
+	if !s.allowRequest(r, len(calls)) {
+		writeError(w, &JSONRPCRequest{}, limitExceededCode, "Limit exceeded: too many requests")
+		return
+	}
+
+	responses := make([]json.RawMessage, len(calls))
+	for i, call := range calls {
+		var req JSONRPCRequest
+		if err := json.Unmarshal(call, &req); err != nil {
+			responses[i] = errorResponse(&JSONRPCRequest{}, -32600, "Invalid Request")
+			continue
+		}
+		responses[i] = s.call(r, &req, limits.timeout)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(responses); err != nil {
+		log.Error().Err(err).Msg("Failed to encode batch response")
+	}
+}
+
+// call runs a single call and returns its response. A call still running at the deadline
+// is answered with an error, its late response is discarded. The deadline cancels the
+// call's context too, which stops contract calls and gas estimates.
+func (s *Server) call(r *http.Request, req *JSONRPCRequest, timeout time.Duration) json.RawMessage {
+	if timeout <= 0 {
+		buf := newResponseBuffer()
+		s.dispatch(buf, r, req)
+		return buf.body.Bytes()
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	buf := newResponseBuffer()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.dispatch(buf, r.WithContext(ctx), req)
+	}()
+
+	select {
+	case <-done:
+		return buf.body.Bytes()
+	case <-ctx.Done():
+		log.Warn().Str("method", req.Method).Dur("timeout", timeout).Msg("RPC call timed out")
+		return errorResponse(req, -32000, "Request timed out")
+	}
+}
+
+// errorResponse encodes a JSON-RPC error response
+func errorResponse(req *JSONRPCRequest, code int, message string) json.RawMessage {
+	buf := newResponseBuffer()
+	writeError(buf, req, code, message)
+	return buf.body.Bytes()
+}
+
+// responseBuffer collects the response a handler writes, so calls can be combined into a
+// batch response or dropped once their deadline passed
+type responseBuffer struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func (b *responseBuffer) WriteHeader(int) {}
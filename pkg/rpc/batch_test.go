@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// post sends a JSON-RPC request body to the server's handler
+func post(s *Server, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.handleRPC(rec, req)
+	return rec
+}
+
+func TestBatchRequests(t *testing.T) {
+	s := NewServer(nil, 0)
+
+	// A single call gets a single response
+	var single JSONRPCResponse
+	require.NoError(t, json.Unmarshal(post(s, `{"jsonrpc":"2.0","method":"rollup_unknown","id":1}`).Body.Bytes(), &single))
+	require.Equal(t, -32601, single.Error.Code)
+	require.EqualValues(t, 1, single.ID)
+
+	// A batch gets an array of responses in call order, invalid calls answered in place
+	rec := post(s, ` [{"jsonrpc":"2.0","method":"rollup_unknown","id":1}, 42, {"jsonrpc":"2.0","method":"admin_emergencyStatus","id":"b"}]`)
+	var batch []JSONRPCResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &batch))
+	require.Len(t, batch, 3)
+	require.Equal(t, -32601, batch[0].Error.Code)
+	require.EqualValues(t, 1, batch[0].ID)
+	require.Equal(t, -32600, batch[1].Error.Code)
+	require.Nil(t, batch[1].ID)
+	require.Equal(t, -32001, batch[2].Error.Code)
+	require.Equal(t, "b", batch[2].ID)
+
+	// An empty batch is an invalid request
+	require.NoError(t, json.Unmarshal(post(s, `[]`).Body.Bytes(), &single))
+	require.Equal(t, -32600, single.Error.Code)
+
+	require.NoError(t, json.Unmarshal(post(s, `[{"jsonrpc"`).Body.Bytes(), &single))
+	require.Equal(t, -32700, single.Error.Code)
+}
+
+func TestRequestLimits(t *testing.T) {
+	s := NewServer(nil, 0)
+	s.SetRequestLimits(256, 2, time.Second)
+
+	call := `{"jsonrpc":"2.0","method":"rollup_unknown","id":1}`
+
+	// Batches above the limit are refused as a whole
+	var resp JSONRPCResponse
+	require.NoError(t, json.Unmarshal(post(s, "["+call+","+call+"]").Body.Bytes(), new([]JSONRPCResponse)))
+	require.NoError(t, json.Unmarshal(post(s, "["+call+","+call+","+call+"]").Body.Bytes(), &resp))
+	require.Equal(t, -32600, resp.Error.Code)
+	require.Contains(t, resp.Error.Message, "limit of 2")
+
+	// So are bodies above the size limit
+	large := `{"jsonrpc":"2.0","method":"rollup_unknown","params":["` + strings.Repeat("a", 300) + `"],"id":1}`
+	require.NoError(t, json.Unmarshal(post(s, large).Body.Bytes(), &resp))
+	require.Equal(t, -32600, resp.Error.Code)
+	require.Contains(t, resp.Error.Message, "256 bytes")
+}
@@ -0,0 +1,134 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// maxBatchRange caps how many batches a single rollup_getBatchRange call returns
+const maxBatchRange = 100
+
+// batchHeader is the JSON form of a finalized batch without its transaction bodies
+type batchHeader struct {
+	BatchNumber uint64   `json:"batchNumber"`
+	StateRoot   string   `json:"stateRoot"`
+	StateHash   string   `json:"stateHash"` // Tree hash the root was computed with, "sha256" or "mimc"
+	Timestamp   uint64   `json:"timestamp"`
+	TxCount     int      `json:"txCount"`
+	TxHashes    []string `json:"txHashes"`
+	L1TxHash    *string  `json:"l1TxHash"`    // Null until the batch is submitted to L1
+	ProofStatus string   `json:"proofStatus"` // "proven", "pending" or "disabled"
+}
+
+// batchRange is a page of batch headers, NextBatch is null on the last page
+type batchRange struct {
+	Batches   []batchHeader `json:"batches"`
+	NextBatch *uint64       `json:"nextBatch"`
+}
+
+// handleGetBatchByNumber handles the rollup_getBatchByNumber method. The only param is a
+// batch number or "latest".
+func (s *Server) handleGetBatchByNumber(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	head := s.sequencer.BatchNumber()
+	var batchNumber uint64
+	if string(params[0]) == `"latest"` {
+		if head == 0 {
+			writeError(w, req, -32000, "No batch finalized yet")
+			return
+		}
+		batchNumber = head - 1
+	} else if err := json.Unmarshal(params[0], &batchNumber); err != nil {
+		writeError(w, req, -32602, "Invalid batch number")
+		return
+	}
+
+	batches := s.sequencer.GetBatches(batchNumber, 1)
+	if len(batches) == 0 {
+		writeError(w, req, -32000, fmt.Sprintf("Batch %d not found", batchNumber))
+		return
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  s.batchHeader(&batches[0]),
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleGetBatchRange handles the rollup_getBatchRange method. Params are the first batch
+// number and the number of batches, at most maxBatchRange.
+func (s *Server) handleGetBatchRange(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []uint64
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 2 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+	from, count := params[0], params[1]
+	if count == 0 || count > maxBatchRange {
+		writeError(w, req, -32602, fmt.Sprintf("Count must be between 1 and %d", maxBatchRange))
+		return
+	}
+
+	batches := s.sequencer.GetBatches(from, count)
+	result := batchRange{Batches: make([]batchHeader, 0, len(batches))}
+	for i := range batches {
+		result.Batches = append(result.Batches, s.batchHeader(&batches[i]))
+	}
+	if next := from + uint64(len(batches)); len(batches) > 0 && next < s.sequencer.BatchNumber() {
+		result.NextBatch = &next
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// batchHeader summarizes a finalized batch
+func (s *Server) batchHeader(batch *state.Batch) batchHeader {
+	header := batchHeader{
+		BatchNumber: batch.BatchNumber,
+		StateRoot:   fmt.Sprintf("0x%x", batch.StateRoot),
+		StateHash:   s.sequencer.RootVersion().String(),
+		Timestamp:   batch.Timestamp,
+		TxCount:     len(batch.Transactions),
+		TxHashes:    make([]string, 0, len(batch.Transactions)),
+	}
+	for i := range batch.Transactions {
+		header.TxHashes = append(header.TxHashes, batch.Transactions[i].HashToEthHash().Hex())
+	}
+	if batch.L1TxHash != ([32]byte{}) {
+		hash := fmt.Sprintf("0x%x", batch.L1TxHash)
+		header.L1TxHash = &hash
+	}
+
+	switch {
+	case len(batch.Proof) > 0:
+		header.ProofStatus = "proven"
+	case s.sequencer.ProofGenerationEnabled():
+		header.ProofStatus = "pending"
+	default:
+		header.ProofStatus = "disabled"
+	}
+	return header
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/types"
+)
+
+// callParams is the JSON form of a read-only contract call
+type callParams struct {
+	From  types.Address `json:"from"`
+	To    types.Address `json:"to"`
+	Value string        `json:"value"` // Decimal wei, defaults to 0
+	Gas   uint64        `json:"gas"`   // Defaults to the node's call gas cap
+	Data  string        `json:"data"`  // Hex call data
+}
+
+// handleCall handles the rollup_call method. The only param is the call object, the call
+// runs against the latest state without changing it and its output is returned as hex.
+func (s *Server) handleCall(ctx context.Context, w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var call callParams
+	if err := json.Unmarshal(params[0], &call); err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid call: %v", err))
+		return
+	}
+	if call.To == (types.Address{}) {
+		writeError(w, req, -32602, "Call target is required")
+		return
+	}
+	value := new(big.Int)
+	if call.Value != "" {
+		if _, ok := value.SetString(call.Value, 10); !ok || value.Sign() < 0 {
+			writeError(w, req, -32602, "Invalid value")
+			return
+		}
+	}
+	var data []byte
+	if call.Data != "" {
+		var err error
+		if data, err = hexutil.Decode(call.Data); err != nil {
+			writeError(w, req, -32602, fmt.Sprintf("Invalid data: %v", err))
+			return
+		}
+	}
+
+	ret, err := s.sequencer.Call(ctx, call.From, call.To, value, call.Gas, data)
+	if err != nil {
+		// Surface the revert reason when the contract gave one
+		if errors.Is(err, vm.ErrExecutionReverted) {
+			if reason, unpackErr := abi.UnpackRevert(ret); unpackErr == nil {
+				writeError(w, req, -32000, fmt.Sprintf("Execution reverted: %s", reason))
+				return
+			}
+		}
+		writeError(w, req, -32000, fmt.Sprintf("Call failed: %v", err))
+		return
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"data": hexutil.Encode(ret),
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
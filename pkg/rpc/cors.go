@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"net/http"
+	"slices"
+)
+
+// corsMaxAge is how long browsers may cache a preflight response, in seconds
+const corsMaxAge = "600"
+
+// SetCORSOrigins lets browser pages served from the given origins call the server, "*"
+// allowing any origin. No origins leaves cross-origin requests to the browser's defaults.
+func (s *Server) SetCORSOrigins(origins []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.corsOrigins = append([]string(nil), origins...)
+}
+
+// allowedOrigin returns the value of the Access-Control-Allow-Origin header for a request
+// from origin, empty when the origin is not allowed
+func (s *Server) allowedOrigin(origin string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if origin == "" {
+		return ""
+	}
+	if slices.Contains(s.corsOrigins, "*") {
+		return "*"
+	}
+	if slices.Contains(s.corsOrigins, origin) {
+		return origin
+	}
+	return ""
+}
+
+// withCORS adds the CORS headers of allowed origins to the responses of next and answers
+// preflight requests itself
+func (s *Server) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		allowed := s.allowedOrigin(r.Header.Get("Origin"))
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
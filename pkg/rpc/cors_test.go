@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	s := NewServer(nil, 0)
+	s.SetCORSOrigins([]string{"https://app.example"})
+	handler := s.withCORS(http.HandlerFunc(s.handleRPC))
+
+	send := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", strings.NewReader(`{"jsonrpc":"2.0","method":"rollup_unknown","id":1}`))
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Preflights of allowed origins list the methods and headers
+	rec := send(http.MethodOptions, "https://app.example")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "https://app.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "Content-Type")
+
+	rec = send(http.MethodPost, "https://app.example")
+	require.Equal(t, "https://app.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, rec.Body.String(), "-32601")
+
+	// Other origins get no CORS headers
+	rec = send(http.MethodOptions, "https://evil.example")
+	require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, rec.Header().Get("Access-Control-Allow-Methods"))
+
+	s.SetCORSOrigins([]string{"*"})
+	require.Equal(t, "*", send(http.MethodPost, "https://evil.example").Header().Get("Access-Control-Allow-Origin"))
+}
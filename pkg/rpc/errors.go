@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"errors"
+
+	"zkrollup/pkg/sequencer"
+)
+
+// JSON-RPC error codes of rejected transactions, in the server error range so clients
+// can tell the reasons apart without parsing messages
+const (
+	invalidNonceCode        = -32010
+	insufficientBalanceCode = -32011
+	gasTooLowCode           = -32012
+	underpricedCode         = -32013
+	alreadyKnownCode        = -32014
+	haltedCode              = -32015
+	invalidChainIDCode      = -32016
+	nodeSyncingCode         = -32000 // The generic server error Ethereum nodes answer with while syncing
+)
+
+// txErrors maps the sequencer's admission errors to their codes, the first match wins
+var txErrors = []struct {
+	err  error
+	code int
+}{
+	{sequencer.ErrInvalidNonce, invalidNonceCode},
+	{sequencer.ErrInsufficientBalance, insufficientBalanceCode},
+	{sequencer.ErrGasTooLow, gasTooLowCode},
+	{sequencer.ErrInvalidChainID, invalidChainIDCode},
+	{sequencer.ErrUnderpriced, underpricedCode},
+	{sequencer.ErrReplacementUnderpriced, underpricedCode},
+	{sequencer.ErrAlreadyKnown, alreadyKnownCode},
+	{sequencer.ErrMempoolFull, limitExceededCode},
+	{sequencer.ErrSenderQueueFull, limitExceededCode},
+	{sequencer.ErrHalted, haltedCode},
+	{sequencer.ErrNodeSyncing, nodeSyncingCode},
+}
+
+// txErrorCode returns the JSON-RPC error code of a rejected transaction, -32603 for
+// errors without a code of their own
+func txErrorCode(err error) int {
+	for _, e := range txErrors {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return -32603
+}
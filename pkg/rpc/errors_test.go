@@ -0,0 +1,23 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/sequencer"
+)
+
+func TestTxErrorCode(t *testing.T) {
+	// Wrapped errors keep their code
+	require.Equal(t, invalidNonceCode, txErrorCode(fmt.Errorf("%w: 1, account nonce is 1", sequencer.ErrInvalidNonce)))
+	require.Equal(t, insufficientBalanceCode, txErrorCode(fmt.Errorf("contract call: %w", fmt.Errorf("%w: have 0, need 1", sequencer.ErrInsufficientBalance))))
+	require.Equal(t, gasTooLowCode, txErrorCode(sequencer.ErrGasTooLow))
+	require.Equal(t, underpricedCode, txErrorCode(sequencer.ErrReplacementUnderpriced))
+	require.Equal(t, limitExceededCode, txErrorCode(sequencer.ErrMempoolFull))
+	require.Equal(t, nodeSyncingCode, txErrorCode(sequencer.ErrNodeSyncing))
+
+	require.Equal(t, -32603, txErrorCode(errors.New("signature mismatch")))
+}
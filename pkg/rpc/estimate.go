@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// estimateParams is the JSON form of a transaction to estimate, the fields of
+// rollup_sendTransaction without nonce and signature, all optional but from
+type estimateParams struct {
+	Type     *uint8        `json:"type"` // Defaults from to and data: transfer, deploy or call
+	From     types.Address `json:"from"`
+	To       types.Address `json:"to"`
+	Amount   string        `json:"amount"`
+	Gas      uint64        `json:"gas"` // Upper bound of the search, defaults to the node's call gas cap
+	GasPrice string        `json:"gasPrice"`
+	Data     string        `json:"data"`
+}
+
+// handleEstimateGas handles the rollup_estimateGas method. The only param is the
+// transaction, the result is the lowest gas limit it succeeds with.
+func (s *Server) handleEstimateGas(ctx context.Context, w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var p estimateParams
+	if err := json.Unmarshal(params[0], &p); err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid transaction: %v", err))
+		return
+	}
+	if p.From == (types.Address{}) {
+		writeError(w, req, -32602, "Invalid from address")
+		return
+	}
+
+	tx := state.Transaction{From: p.From, To: p.To, Gas: p.Gas}
+	if p.Data != "" {
+		data, err := hexutil.Decode(p.Data)
+		if err != nil {
+			writeError(w, req, -32602, fmt.Sprintf("Invalid data: %v", err))
+			return
+		}
+		tx.Data = data
+	}
+	switch {
+	case p.Type != nil:
+		tx.Type = state.TxType(*p.Type)
+	case p.To == (types.Address{}) && len(tx.Data) > 0:
+		tx.Type = state.TxTypeContractDeploy
+	case len(tx.Data) > 0:
+		tx.Type = state.TxTypeContractCall
+	default:
+		tx.Type = state.TxTypeTransfer
+	}
+
+	tx.Amount = new(big.Int)
+	if p.Amount != "" {
+		if tx.Amount = state.ParseAmount(p.Amount); tx.Amount == nil || tx.Amount.Sign() < 0 {
+			writeError(w, req, -32602, "Invalid amount format")
+			return
+		}
+	}
+	if p.GasPrice != "" {
+		if tx.GasPrice = state.ParseAmount(p.GasPrice); tx.GasPrice == nil || tx.GasPrice.Sign() < 0 {
+			writeError(w, req, -32602, "Invalid gas price format")
+			return
+		}
+	}
+
+	gas, err := s.sequencer.EstimateGas(ctx, tx)
+	if err != nil {
+		writeError(w, req, -32000, fmt.Sprintf("Gas estimation failed: %v", err))
+		return
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"gas": gas,
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
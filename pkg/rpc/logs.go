@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// maxLogBatchRange caps how many batches a single rollup_getLogs call scans
+const maxLogBatchRange = 10000
+
+// logFilterParams is the filter object of rollup_getLogs. Address is a single address or
+// a list, each entry of Topics is null, a topic or a list of alternatives.
+type logFilterParams struct {
+	FromBatch *uint64           `json:"fromBatch"`
+	ToBatch   *uint64           `json:"toBatch"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+// logResult is the JSON form of a contract log
+type logResult struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BatchNumber      uint64   `json:"batchNumber"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex uint     `json:"transactionIndex"`
+	LogIndex         uint     `json:"logIndex"`
+}
+
+// handleGetLogs handles the rollup_getLogs method. The batch range defaults to the latest
+// batch.
+func (s *Server) handleGetLogs(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []logFilterParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	filter, err := s.parseLogFilter(&params[0])
+	if err != nil {
+		writeError(w, req, -32602, err.Error())
+		return
+	}
+
+	logs := s.sequencer.GetLogs(filter)
+	results := make([]logResult, 0, len(logs))
+	for i := range logs {
+		results = append(results, newLogResult(&logs[i]))
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  results,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// newLogResult formats a contract log
+func newLogResult(l *state.Log) logResult {
+	result := logResult{
+		Address:          l.Address.Hex(),
+		Topics:           make([]string, 0, len(l.Topics)),
+		Data:             fmt.Sprintf("0x%x", l.Data),
+		BatchNumber:      l.BatchNumber,
+		TransactionHash:  common.Hash(l.TxHash).Hex(),
+		TransactionIndex: l.TxIndex,
+		LogIndex:         l.LogIndex,
+	}
+	for _, topic := range l.Topics {
+		result.Topics = append(result.Topics, common.Hash(topic).Hex())
+	}
+	return result
+}
+
+// parseLogFilter validates the rollup_getLogs filter object
+func (s *Server) parseLogFilter(params *logFilterParams) (state.LogFilter, error) {
+	var filter state.LogFilter
+
+	latest := uint64(0)
+	if n := s.sequencer.BatchNumber(); n > 0 {
+		latest = n - 1
+	}
+	filter.ToBatch = latest
+	if params.ToBatch != nil {
+		filter.ToBatch = *params.ToBatch
+	}
+	filter.FromBatch = filter.ToBatch
+	if params.FromBatch != nil {
+		filter.FromBatch = *params.FromBatch
+	}
+	if filter.FromBatch > filter.ToBatch {
+		return filter, fmt.Errorf("fromBatch %d is after toBatch %d", filter.FromBatch, filter.ToBatch)
+	}
+	if filter.ToBatch-filter.FromBatch >= maxLogBatchRange {
+		return filter, fmt.Errorf("batch range exceeds %d batches", maxLogBatchRange)
+	}
+
+	if len(params.Address) > 0 && string(params.Address) != "null" {
+		var addresses []types.Address
+		var single types.Address
+		if err := json.Unmarshal(params.Address, &single); err == nil {
+			addresses = []types.Address{single}
+		} else if err := json.Unmarshal(params.Address, &addresses); err != nil {
+			return filter, fmt.Errorf("invalid address: %v", err)
+		}
+		filter.Addresses = addresses
+	}
+
+	for i, raw := range params.Topics {
+		var alternatives [][32]byte
+		if len(raw) > 0 && string(raw) != "null" {
+			var single string
+			var list []string
+			if err := json.Unmarshal(raw, &single); err == nil {
+				list = []string{single}
+			} else if err := json.Unmarshal(raw, &list); err != nil {
+				return filter, fmt.Errorf("invalid topic %d: %v", i, err)
+			}
+			for _, t := range list {
+				topic, err := parseTopic(t)
+				if err != nil {
+					return filter, fmt.Errorf("invalid topic %d: %v", i, err)
+				}
+				alternatives = append(alternatives, topic)
+			}
+		}
+		filter.Topics = append(filter.Topics, alternatives)
+	}
+
+	return filter, nil
+}
+
+// parseTopic parses a 0x prefixed 32 byte hex topic
+func parseTopic(s string) ([32]byte, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	if len(b) != 32 {
+		return [32]byte{}, fmt.Errorf("want 32 bytes, got %d", len(b))
+	}
+	return [32]byte(b), nil
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// Mempool stream modes, how much of each pending transaction is published
+const (
+	MempoolStreamOff    = "off"
+	MempoolStreamHashes = "hashes"
+	MempoolStreamFull   = "full"
+)
+
+// streamWriteTimeout bounds how long a slow stream client may hold up a write
+const streamWriteTimeout = 10 * time.Second
+
+// streamPingInterval keeps idle stream connections alive through proxies
+const streamPingInterval = 30 * time.Second
+
+// pendingTx is the published form of a pending transaction, Tx is only set in full mode
+type pendingTx struct {
+	TxHash string             `json:"txHash"`
+	Tx     *state.Transaction `json:"tx,omitempty"`
+}
+
+var streamUpgrader = websocket.Upgrader{
+	// The stream is read-only and public, any page may subscribe
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// SetMempoolStream publishes pending transactions at /mempool and /mempool/stream in the
+// given mode, it must be called before Start
+func (s *Server) SetMempoolStream(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mempoolStream = mode
+}
+
+// publishedTx formats a pending transaction for the stream mode
+func (s *Server) publishedTx(tx state.Transaction) pendingTx {
+	published := pendingTx{TxHash: tx.HashToEthHash().Hex()}
+	if s.mempoolStream == MempoolStreamFull {
+		tx.TraceID = ""
+		published.Tx = &tx
+	}
+	return published
+}
+
+// handleMempool serves the public transactions currently pending
+func (s *Server) handleMempool(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	txs := s.sequencer.PendingTxs()
+	published := make([]pendingTx, len(txs))
+	for i := range txs {
+		published[i] = s.publishedTx(txs[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":        len(published),
+		"transactions": published,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to encode mempool")
+	}
+}
+
+// handleMempoolStream streams public transactions to a WebSocket client as they are
+// admitted to the pool, one JSON message each. Transactions are skipped for a client that
+// falls too far behind.
+func (s *Server) handleMempoolStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to upgrade mempool stream connection")
+		return
+	}
+	defer conn.Close()
+
+	sub := s.sequencer.SubscribePendingTxs()
+	defer sub.Unsubscribe()
+
+	// Incoming messages are ignored, reading detects the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	log.Debug().Str("remote", r.RemoteAddr).Msg("Mempool stream client connected")
+	for {
+		select {
+		case <-closed:
+			log.Debug().Str("remote", r.RemoteAddr).Uint64("dropped", sub.Dropped()).Msg("Mempool stream client disconnected")
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case tx, ok := <-sub.Txs():
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(s.publishedTx(tx)); err != nil {
+				return
+			}
+		}
+	}
+}
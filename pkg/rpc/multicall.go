@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/types"
+)
+
+// maxMulticallAddresses caps how many accounts a single rollup_multicall call reads
+const maxMulticallAddresses = 100
+
+// accountSummary is the JSON form of sequencer.AccountSummary
+type accountSummary struct {
+	Address types.Address `json:"address"`
+	Balance string        `json:"balance"`
+	Nonce   uint64        `json:"nonce"`
+	HasCode bool          `json:"hasCode"`
+}
+
+// handleMulticall handles the rollup_multicall method. The only param is a list of
+// addresses, their balances, nonces and whether they hold code are read as of the same batch.
+func (s *Server) handleMulticall(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var addresses []types.Address
+	if err := json.Unmarshal(params[0], &addresses); err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid addresses: %v", err))
+		return
+	}
+	if len(addresses) > maxMulticallAddresses {
+		writeError(w, req, -32602, fmt.Sprintf("Too many addresses, at most %d per call", maxMulticallAddresses))
+		return
+	}
+
+	summaries, batches, err := s.sequencer.GetAccounts(addresses)
+	if err != nil {
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return
+	}
+
+	accounts := make([]accountSummary, len(summaries))
+	for i, summary := range summaries {
+		balance := "0"
+		if summary.Balance != nil {
+			balance = summary.Balance.String()
+		}
+		accounts[i] = accountSummary{
+			Address: summary.Address,
+			Balance: balance,
+			Nonce:   summary.Nonce,
+			HasCode: summary.HasCode,
+		}
+	}
+
+	// Null before the first batch is finalized
+	var batchNumber *uint64
+	if batches > 0 {
+		latest := batches - 1
+		batchNumber = &latest
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"accounts":    accounts,
+			"batchNumber": batchNumber,
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
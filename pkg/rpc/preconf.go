@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/sequencer"
+)
+
+// preconfCheck is the JSON form of a checked preconfirmation, Evidence is only set when it
+// was broken
+type preconfCheck struct {
+	Status   string                             `json:"status"` // "kept", "pending" or "broken"
+	Evidence *sequencer.PreconfirmationEvidence `json:"evidence,omitempty"`
+}
+
+// handleCheckPreconfirmation handles the rollup_checkPreconfirmation method. The only param
+// is a preconfirmation as returned by rollup_sendTransaction, the result carries the
+// slashing evidence when its deadline passed without the transaction.
+func (s *Server) handleCheckPreconfirmation(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []sequencer.Preconfirmation
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	result := preconfCheck{Status: "kept"}
+	evidence, err := s.sequencer.CheckPreconfirmation(&params[0])
+	switch {
+	case errors.Is(err, sequencer.ErrPreconfirmationPending):
+		result.Status = "pending"
+	case errors.Is(err, sequencer.ErrInvalidPreconfirmation):
+		writeError(w, req, -32602, err.Error())
+		return
+	case err != nil:
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return
+	case evidence != nil:
+		result.Status = "broken"
+		result.Evidence = evidence
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
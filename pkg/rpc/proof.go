@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// maxProofSlots caps the storage slots proven by one rollup_getProof call
+const maxProofSlots = 256
+
+// proofResult is the JSON form of an account proof with storage proofs, like the result
+// of eth_getProof
+type proofResult struct {
+	Address      string               `json:"address"`
+	BatchNumber  uint64               `json:"batchNumber"`
+	StateRoot    string               `json:"stateRoot"`
+	Exists       bool                 `json:"exists"`
+	Balance      string               `json:"balance"` // In wei
+	Nonce        uint64               `json:"nonce"`
+	StorageRoot  string               `json:"storageRoot"`
+	CodeHash     string               `json:"codeHash"`
+	AccountProof merkleProofResult    `json:"accountProof"`
+	StorageProof []storageProofResult `json:"storageProof"`
+}
+
+// storageProofResult proves a storage slot against the account's storage root
+type storageProofResult struct {
+	Key   string            `json:"key"`
+	Value string            `json:"value"`
+	Proof merkleProofResult `json:"proof"`
+}
+
+// handleGetProof handles the rollup_getProof method. Params are the address, an array of
+// hex storage slots and optionally a batch number or "latest".
+func (s *Server) handleGetProof(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var address types.Address
+	if err := json.Unmarshal(params[0], &address); err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid address: %v", err))
+		return
+	}
+
+	var slotStrs []string
+	if len(params) > 1 && string(params[1]) != "null" {
+		if err := json.Unmarshal(params[1], &slotStrs); err != nil {
+			writeError(w, req, -32602, "Invalid storage slots")
+			return
+		}
+	}
+	if len(slotStrs) > maxProofSlots {
+		writeError(w, req, -32602, fmt.Sprintf("At most %d storage slots can be proven at once", maxProofSlots))
+		return
+	}
+	slots := make([][32]byte, 0, len(slotStrs))
+	for _, slotStr := range slotStrs {
+		slot, err := parseSlot(slotStr)
+		if err != nil {
+			writeError(w, req, -32602, fmt.Sprintf("Invalid slot %s: %v", slotStr, err))
+			return
+		}
+		slots = append(slots, slot)
+	}
+
+	// Default to the latest batch
+	n := s.sequencer.BatchNumber()
+	if n == 0 {
+		writeError(w, req, -32000, "No batch finalized yet")
+		return
+	}
+	batchNumber, historic, err := parseBatchParam(params, 2)
+	if err != nil {
+		writeError(w, req, -32602, "Invalid batch number")
+		return
+	}
+	if !historic {
+		batchNumber = n - 1
+	}
+
+	proof, err := s.sequencer.GetProof(address, slots, batchNumber)
+	if err != nil {
+		if errors.Is(err, state.ErrStateNotRetained) {
+			s.writeNotRetained(w, req, batchNumber)
+			return
+		}
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return
+	}
+
+	result := proofResult{
+		Address:      address.Hex(),
+		BatchNumber:  proof.BatchNumber,
+		StateRoot:    fmt.Sprintf("0x%x", proof.StateRoot),
+		Exists:       proof.Account.Exists,
+		Balance:      proof.Account.Balance.String(),
+		Nonce:        proof.Account.Nonce,
+		StorageRoot:  fmt.Sprintf("0x%x", proof.Account.StorageRoot),
+		CodeHash:     fmt.Sprintf("0x%x", proof.Account.CodeHash),
+		AccountProof: formatMerkleProof(proof.Account.Proof),
+		StorageProof: make([]storageProofResult, 0, len(proof.Storage)),
+	}
+	for _, slot := range proof.Storage {
+		result.StorageProof = append(result.StorageProof, storageProofResult{
+			Key:   fmt.Sprintf("0x%x", slot.Key),
+			Value: fmt.Sprintf("0x%x", slot.Value),
+			Proof: formatMerkleProof(slot.Proof),
+		})
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
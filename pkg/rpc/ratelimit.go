@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limitExceededCode is the JSON-RPC error code of calls refused by a rate, concurrency or
+// mempool limit
+const limitExceededCode = -32005
+
+// rateSweepInterval is how often buckets of clients that went quiet are dropped
+const rateSweepInterval = time.Minute
+
+// rateLimiter is a token bucket per client IP. Each bucket holds up to burst calls and
+// refills at rate calls per second.
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		clients: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes n calls from the client's bucket, reporting false and taking nothing when it
+// holds fewer. A nil limiter allows everything.
+func (l *rateLimiter) allow(client string, n int, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateSweepInterval {
+		l.sweepLocked(now)
+	}
+
+	b, ok := l.clients[client]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// sweepLocked drops the buckets that refilled completely, they are recreated full
+func (l *rateLimiter) sweepLocked(now time.Time) {
+	for client, b := range l.clients {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.clients, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// SetRateLimit limits every client IP to rate calls per second with bursts of up to burst
+// calls, each call of a batch array counting. A zero rate disables the limit.
+func (s *Server) SetRateLimit(rate float64, burst int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rate <= 0 {
+		s.rateLimiter = nil
+		return
+	}
+	s.rateLimiter = newRateLimiter(rate, burst)
+}
+
+// SetMethodConcurrency caps how many calls of each listed method run at once, calls past
+// the cap are refused instead of queued
+func (s *Server) SetMethodConcurrency(limits map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methodSlots = make(map[string]chan struct{}, len(limits))
+	for method, n := range limits {
+		if n > 0 {
+			s.methodSlots[method] = make(chan struct{}, n)
+		}
+	}
+}
+
+// allowRequest applies the client's rate limit to a request of n calls
+func (s *Server) allowRequest(r *http.Request, n int) bool {
+	s.mu.RLock()
+	limiter := s.rateLimiter
+	s.mu.RUnlock()
+	return limiter.allow(clientIP(r), n, time.Now())
+}
+
+// acquireMethod takes a concurrency slot for method, returning the function releasing it
+// or false when every slot is taken. Methods without a cap always succeed.
+func (s *Server) acquireMethod(method string) (func(), bool) {
+	s.mu.RLock()
+	slots := s.methodSlots[method]
+	s.mu.RUnlock()
+
+	if slots == nil {
+		return func() {}, true
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// clientIP returns the address a request came from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 4)
+	now := time.Unix(1000, 0)
+
+	// A fresh client may burst, then waits for the bucket to refill
+	require.True(t, l.allow("a", 3, now))
+	require.False(t, l.allow("a", 2, now))
+	require.True(t, l.allow("a", 1, now))
+	require.False(t, l.allow("a", 1, now))
+	require.True(t, l.allow("a", 1, now.Add(500*time.Millisecond)))
+
+	// Clients are limited separately and never refill past the burst
+	require.True(t, l.allow("b", 4, now))
+	require.False(t, l.allow("b", 5, now.Add(time.Hour)))
+
+	// Idle clients are swept
+	l.allow("c", 1, now.Add(2*time.Hour))
+	require.Len(t, l.clients, 1)
+
+	var disabled *rateLimiter
+	require.True(t, disabled.allow("a", 1000, now))
+}
+
+func TestLimitExceeded(t *testing.T) {
+	s := NewServer(nil, 0)
+	s.SetRateLimit(0.001, 2)
+
+	var single JSONRPCResponse
+	require.NoError(t, json.Unmarshal(post(s, `{"jsonrpc":"2.0","method":"rollup_unknown","id":1}`).Body.Bytes(), &single))
+	require.Equal(t, -32601, single.Error.Code)
+
+	// A batch is charged for every call
+	require.NoError(t, json.Unmarshal(post(s, `[{"jsonrpc":"2.0","method":"rollup_unknown","id":1},{"jsonrpc":"2.0","method":"rollup_unknown","id":2}]`).Body.Bytes(), &single))
+	require.Equal(t, limitExceededCode, single.Error.Code)
+
+	require.NoError(t, json.Unmarshal(post(s, `{"jsonrpc":"2.0","method":"rollup_unknown","id":2}`).Body.Bytes(), &single))
+	require.Equal(t, -32601, single.Error.Code)
+	require.NoError(t, json.Unmarshal(post(s, `{"jsonrpc":"2.0","method":"rollup_unknown","id":3}`).Body.Bytes(), &single))
+	require.Equal(t, limitExceededCode, single.Error.Code)
+	require.EqualValues(t, 3, single.ID)
+
+	// Calls past a method's concurrency cap are refused
+	s.SetRateLimit(0, 0)
+	s.SetMethodConcurrency(map[string]int{"rollup_unknown": 1})
+	release, ok := s.acquireMethod("rollup_unknown")
+	require.True(t, ok)
+	require.NoError(t, json.Unmarshal(post(s, `{"jsonrpc":"2.0","method":"rollup_unknown","id":4}`).Body.Bytes(), &single))
+	require.Equal(t, limitExceededCode, single.Error.Code)
+	release()
+	require.NoError(t, json.Unmarshal(post(s, `{"jsonrpc":"2.0","method":"rollup_unknown","id":5}`).Body.Bytes(), &single))
+	require.Equal(t, -32601, single.Error.Code)
+}
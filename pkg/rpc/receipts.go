@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+)
+
+// txResult is the JSON form of a finalized transaction and where it was included
+type txResult struct {
+	Hash             string             `json:"hash"`
+	BatchNumber      uint64             `json:"batchNumber"`
+	TransactionIndex uint               `json:"transactionIndex"`
+	Tx               *state.Transaction `json:"tx"`
+}
+
+// receiptResult is the JSON form of a transaction receipt
+type receiptResult struct {
+	TransactionHash  string      `json:"transactionHash"`
+	BatchNumber      uint64      `json:"batchNumber"`
+	TransactionIndex uint        `json:"transactionIndex"`
+	Status           *uint64     `json:"status"` // 1 success, 0 failure, null when not recorded
+	GasUsed          uint64      `json:"gasUsed"`
+	ContractAddress  *string     `json:"contractAddress"` // Null unless a deployment
+	Error            string      `json:"error,omitempty"`
+	Logs             []logResult `json:"logs"`
+}
+
+// handleGetTransactionByHash handles the rollup_getTransactionByHash method, and
+// rollup_getTransactionReceipt when receipt is set. The only param is the transaction hash.
+func (s *Server) handleGetTransactionByHash(w http.ResponseWriter, req *JSONRPCRequest, receipt bool) {
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+	hashBytes, err := hexutil.Decode(params[0])
+	if err != nil || len(hashBytes) != common.HashLength {
+		writeError(w, req, -32602, "Invalid transaction hash")
+		return
+	}
+
+	tx, rcpt, err := s.sequencer.GetTransactionByHash(common.BytesToHash(hashBytes))
+	if errors.Is(err, sequencer.ErrTransactionNotFound) {
+		writeError(w, req, -32000, "Transaction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return
+	}
+
+	var result interface{}
+	if receipt {
+		result = s.receiptResult(rcpt)
+	} else {
+		tx.TraceID = ""
+		result = txResult{
+			Hash:             common.Hash(rcpt.TxHash).Hex(),
+			BatchNumber:      rcpt.BatchNumber,
+			TransactionIndex: rcpt.TxIndex,
+			Tx:               tx,
+		}
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// receiptResult formats a receipt with the logs its transaction emitted
+func (s *Server) receiptResult(receipt *state.Receipt) receiptResult {
+	result := receiptResult{
+		TransactionHash:  common.Hash(receipt.TxHash).Hex(),
+		BatchNumber:      receipt.BatchNumber,
+		TransactionIndex: receipt.TxIndex,
+		GasUsed:          receipt.GasUsed,
+		Error:            receipt.Error,
+		Logs:             []logResult{},
+	}
+	if receipt.Status != state.ReceiptUnknown {
+		status := uint64(receipt.Status)
+		result.Status = &status
+	}
+	if !receipt.ContractAddress.IsZero() {
+		contract := receipt.ContractAddress.Hex()
+		result.ContractAddress = &contract
+	}
+
+	logs := s.sequencer.GetLogs(state.LogFilter{FromBatch: receipt.BatchNumber, ToBatch: receipt.BatchNumber})
+	for i := range logs {
+		if logs[i].TxHash == receipt.TxHash {
+			result.Logs = append(result.Logs, newLogResult(&logs[i]))
+		}
+	}
+	return result
+}
@@ -1,16 +1,26 @@
 package rpc
 
 import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/rs/zerolog/log"
 
 	"zkrollup/pkg/sequencer"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
+	"zkrollup/pkg/types"
 )
 
 // Server represents the JSON-RPC server for the ZK-Rollup
@@ -19,6 +29,28 @@ type Server struct {
 	port      int
 	server    *http.Server
 	mu        sync.RWMutex
+
+	// Bearer token for admin_* methods, empty disables them
+	adminToken string
+
+	// How pending transactions are published, MempoolStreamOff disables the endpoints
+	mempoolStream string
+
+	// Request body, batch size and per-call deadline limits
+	limits requestLimits
+
+	// Per-IP call rate limit, nil disables it
+	rateLimiter *rateLimiter
+
+	// Concurrency slots of the methods with a cap
+	methodSlots map[string]chan struct{}
+
+	// Origins allowed to call from browsers, "*" for any
+	corsOrigins []string
+
+	// HTTPS certificate, nil serves plain HTTP, and whether HTTP/2 is spoken
+	tlsConfig *tls.Config
+	http2     bool
 }
 
 // JSONRPCRequest represents a JSON-RPC request
@@ -48,7 +80,32 @@ func NewServer(seq *sequencer.Sequencer, port int) *Server {
 	return &Server{
 		sequencer: seq,
 		port:      port,
+		limits:    defaultRequestLimits,
+	}
+}
+
+// SetAdminToken enables the admin_* methods for requests carrying the token as a bearer token
+func (s *Server) SetAdminToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.adminToken = token
+}
+
+// authorizeAdmin checks the request's bearer token against the admin token
+func (s *Server) authorizeAdmin(r *http.Request) bool {
+	s.mu.RLock()
+	token := s.adminToken
+	s.mu.RUnlock()
+
+	if token == "" {
+		return false
 	}
+
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
 }
 
 // Start starts the RPC server
@@ -58,16 +115,31 @@ func (s *Server) Start() error {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleRPC)
+	if s.mempoolStream != "" && s.mempoolStream != MempoolStreamOff {
+		mux.HandleFunc("/mempool", s.handleMempool)
+		mux.HandleFunc("/mempool/stream", s.handleMempoolStream)
+	}
 
 	addr := fmt.Sprintf(":%d", s.port)
 	s.server = &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Addr:      addr,
+		Handler:   s.withCORS(mux),
+		TLSConfig: s.tlsConfig,
+		Protocols: s.protocols(),
 	}
 
+	server := s.server
+	useTLS := s.tlsConfig != nil
 	go func() {
-		log.Info().Int("port", s.port).Msg("Starting RPC server")
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Info().Int("port", s.port).Bool("tls", useTLS).Msg("Starting RPC server")
+		var err error
+		if useTLS {
+			// The certificate is already in TLSConfig
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Error().Err(err).Msg("RPC server error")
 		}
 	}()
@@ -87,60 +159,134 @@ func (s *Server) Stop() error {
 	return nil
 }
 
-// handleRPC handles JSON-RPC requests
+// handleRPC handles JSON-RPC requests, a single call or a batch array of calls
 func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	s.mu.RLock()
+	limits := s.limits
+	s.mu.RUnlock()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limits.maxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, &JSONRPCRequest{}, -32600, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, &JSONRPCRequest{}, -32700, "Parse error")
+		return
+	}
+
+	if isBatch(body) {
+		s.handleBatch(w, r, body, limits)
+		return
+	}
+
 	var req JSONRPCRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		writeError(w, &req, -32700, "Parse error")
 		return
 	}
+	if !s.allowRequest(r, 1) {
+		writeError(w, &req, limitExceededCode, "Limit exceeded: too many requests")
+		return
+	}
 
-	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.call(r, &req, limits.timeout))
+}
+
+// dispatch runs a single JSON-RPC call, writing its response to w
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request, req *JSONRPCRequest) {
+	// Admin methods require the admin token
+	if strings.HasPrefix(req.Method, "admin_") && !s.authorizeAdmin(r) {
+		writeError(w, req, -32001, "Unauthorized")
+		return
+	}
+
+	release, ok := s.acquireMethod(req.Method)
+	if !ok {
+		writeError(w, req, limitExceededCode, fmt.Sprintf("Limit exceeded: too many concurrent %s calls", req.Method))
+		return
+	}
+	defer release()
 
 	// Process request
 	switch req.Method {
 	case "rollup_getNonce":
-		s.handleGetNonce(w, &req)
+		s.handleGetNonce(w, req)
 	case "rollup_sendTransaction":
-		s.handleSendTransaction(w, &req)
+		s.handleSendTransaction(r.Context(), w, req, false)
+	case "rollup_sendRawTransaction":
+		s.handleSendRawTransaction(r.Context(), w, req, false)
+	case "rollup_sendPrivateTransaction":
+		s.handleSendTransaction(r.Context(), w, req, true)
+	case "rollup_sendPrivateRawTransaction":
+		s.handleSendRawTransaction(r.Context(), w, req, true)
 	case "rollup_getBalance":
-		s.handleGetBalance(w, &req)
+		s.handleGetBalance(w, req)
 	case "rollup_getCode":
-		s.handleGetCode(w, &req)
+		s.handleGetCode(w, req)
+	case "rollup_estimateGas":
+		s.handleEstimateGas(r.Context(), w, req)
+	case "rollup_call":
+		s.handleCall(r.Context(), w, req)
+	case "rollup_multicall":
+		s.handleMulticall(w, req)
+	case "rollup_getStorageAt":
+		s.handleGetStorageAt(w, req)
+	case "rollup_getLogs":
+		s.handleGetLogs(w, req)
+	case "rollup_getBatchByNumber":
+		s.handleGetBatchByNumber(w, req)
+	case "rollup_getBatchRange":
+		s.handleGetBatchRange(w, req)
+	case "rollup_getWithdrawalProof":
+		s.handleGetWithdrawalProof(w, req)
+	case "rollup_getProof":
+		s.handleGetProof(w, req)
+	case "rollup_l1Status":
+		s.handleL1Status(w, req)
+	case "rollup_syncing":
+		s.handleSyncing(w, req)
+	case "rollup_metrics":
+		s.handleMetrics(w, req)
+	case "rollup_gasPrice":
+		s.handleGasPrice(w, req)
+	case "rollup_chainId":
+		s.handleChainID(w, req)
+	case "rollup_registerKey":
+		s.handleRegisterKey(r.Context(), w, req)
+	case "rollup_getAuthKey":
+		s.handleGetAuthKey(w, req)
+	case "rollup_getTransactionStatus":
+		s.handleGetTransactionStatus(r.Context(), w, req)
+	case "rollup_getTransactionByHash":
+		s.handleGetTransactionByHash(w, req, false)
+	case "rollup_getTransactionReceipt":
+		s.handleGetTransactionByHash(w, req, true)
+	case "rollup_checkPreconfirmation":
+		s.handleCheckPreconfirmation(w, req)
+	case "admin_setMinGasPrice":
+		s.handleSetMinGasPrice(w, req)
+	case "admin_emergencyStatus":
+		s.handleEmergencyStatus(w, req)
+	case "admin_approveEmergency":
+		s.handleApproveEmergency(w, req)
 	default:
-		writeError(w, &req, -32601, "Method not found")
+		writeError(w, req, -32601, "Method not found")
 	}
 }
 
-// handleGetNonce handles the rollup_getNonce method
+// handleGetNonce handles the rollup_getNonce method. Params are the address and optionally
+// a batch number or "latest".
 func (s *Server) handleGetNonce(w http.ResponseWriter, req *JSONRPCRequest) {
-	var params []string
-	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
-		writeError(w, req, -32602, "Invalid params")
-		return
-	}
-
-	// Parse address
-	addrStr := params[0]
-	if len(addrStr) < 2 || addrStr[:2] != "0x" {
-		writeError(w, req, -32602, "Address must start with 0x")
-		return
-	}
-
-	addr := common.HexToAddress(addrStr)
-	var address [20]byte
-	copy(address[:], addr.Bytes())
-
-	// Get account from state
-	account, err := s.sequencer.GetAccount(address)
-	if err != nil {
-		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+	account, result, ok := s.accountParam(w, req)
+	if !ok {
 		return
 	}
 
@@ -149,13 +295,12 @@ func (s *Server) handleGetNonce(w http.ResponseWriter, req *JSONRPCRequest) {
 	if account != nil {
 		nonce = account.Nonce
 	}
+	result["nonce"] = nonce
 
 	response := JSONRPCResponse{
 		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"nonce": nonce,
-		},
-		ID: req.ID,
+		Result:  result,
+		ID:      req.ID,
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -163,8 +308,12 @@ func (s *Server) handleGetNonce(w http.ResponseWriter, req *JSONRPCRequest) {
 	}
 }
 
-// handleSendTransaction handles the rollup_sendTransaction method
-func (s *Server) handleSendTransaction(w http.ResponseWriter, req *JSONRPCRequest) {
+// handleSendTransaction handles the rollup_sendTransaction method, and
+// rollup_sendPrivateTransaction for a private transaction kept off the mempool stream. Each
+// transaction gets a trace ID that follows it through every node's logs.
+func (s *Server) handleSendTransaction(ctx context.Context, w http.ResponseWriter, req *JSONRPCRequest, private bool) {
+	ctx = trace.WithID(ctx, trace.NewID())
+
 	var params []map[string]interface{}
 	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
 		writeError(w, req, -32602, "Invalid params")
@@ -222,9 +371,19 @@ func (s *Server) handleSendTransaction(w http.ResponseWriter, req *JSONRPCReques
 		return
 	}
 
-	// Convert addresses
-	from := common.HexToAddress(fromStr)
-	to := common.HexToAddress(toStr)
+	// Parse addresses, an empty recipient deploys a contract
+	from, err := types.ParseAddress(fromStr)
+	if err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid from address: %v", err))
+		return
+	}
+	var to types.Address
+	if toStr != "" && toStr != "0x" {
+		if to, err = types.ParseAddress(toStr); err != nil {
+			writeError(w, req, -32602, fmt.Sprintf("Invalid to address: %v", err))
+			return
+		}
+	}
 
 	// Convert data and signature
 	data := common.FromHex(dataStr)
@@ -233,8 +392,8 @@ func (s *Server) handleSendTransaction(w http.ResponseWriter, req *JSONRPCReques
 	// Create transaction
 	tx := state.Transaction{
 		Type:      state.TxType(uint8(typeFloat)),
-		From:      [20]byte{},
-		To:        [20]byte{},
+		From:      from,
+		To:        to,
 		Amount:    nil, // Will be set below
 		Nonce:     uint64(nonceFloat),
 		Data:      data,
@@ -242,10 +401,6 @@ func (s *Server) handleSendTransaction(w http.ResponseWriter, req *JSONRPCReques
 		Signature: signature,
 	}
 
-	// Copy addresses
-	copy(tx.From[:], from.Bytes())
-	copy(tx.To[:], to.Bytes())
-
 	// Parse amount
 	tx.Amount = state.ParseAmount(amountStr)
 	if tx.Amount == nil {
@@ -253,22 +408,83 @@ func (s *Server) handleSendTransaction(w http.ResponseWriter, req *JSONRPCReques
 		return
 	}
 
+	// Gas price is optional, transactions without one are ordered last
+	if gasPriceStr, ok := txParams["gasPrice"].(string); ok {
+		tx.GasPrice = state.ParseAmount(gasPriceStr)
+		if tx.GasPrice == nil {
+			writeError(w, req, -32602, "Invalid gas price format")
+			return
+		}
+	}
+
+	// Chain ID is optional, transactions without one are unprotected
+	if chainID, ok := txParams["chainId"].(float64); ok {
+		tx.ChainID = uint64(chainID)
+	}
+
+	tx.Private = private
+	s.submitTransaction(ctx, w, req, tx)
+}
+
+// handleSendRawTransaction handles the rollup_sendRawTransaction method, which takes a
+// hex encoded RLP transaction, and its private variant rollup_sendPrivateRawTransaction
+func (s *Server) handleSendRawTransaction(ctx context.Context, w http.ResponseWriter, req *JSONRPCRequest, private bool) {
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var tx state.Transaction
+	if err := rlp.DecodeBytes(common.FromHex(params[0]), &tx); err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid transaction encoding: %v", err))
+		return
+	}
+
+	tx.Private = private
+	s.submitTransaction(ctx, w, req, tx)
+}
+
+// submitTransaction hands a decoded transaction to the sequencer and replies with its hash
+func (s *Server) submitTransaction(ctx context.Context, w http.ResponseWriter, req *JSONRPCRequest, tx state.Transaction) {
+	// Refuse transactions until the node has caught up, they would be checked against stale state
+	if !s.sequencer.IsSynced() {
+		writeError(w, req, txErrorCode(sequencer.ErrNodeSyncing), sequencer.ErrNodeSyncing.Error())
+		return
+	}
+
 	// Add transaction to sequencer
-	if err := s.sequencer.AddTransaction(tx); err != nil {
-		writeError(w, req, -32603, fmt.Sprintf("Failed to add transaction: %v", err))
+	if err := s.sequencer.AddTransactionContext(ctx, tx); err != nil {
+		trace.Logger(ctx).Warn().Err(err).Msg("Rejected transaction")
+		code := txErrorCode(err)
+		if code == limitExceededCode {
+			writeError(w, req, code, fmt.Sprintf("Limit exceeded: %v", err))
+			return
+		}
+		writeError(w, req, code, fmt.Sprintf("Failed to add transaction: %v", err))
 		return
 	}
 
 	// Calculate transaction hash
-	txHash := fmt.Sprintf("0x%x", state.CalculateTransactionHash(tx))
+	txHash := tx.HashToEthHash().Hex()
+	trace.Logger(ctx).Info().Str("tx_hash", txHash).Msg("Accepted transaction over RPC")
+
+	// Return transaction hash, with the node's promise to include it when it makes one
+	result := map[string]interface{}{
+		"txHash":  txHash,
+		"traceId": trace.ID(ctx),
+	}
+	preconf, err := s.sequencer.Preconfirm(ctx, &tx)
+	if err == nil {
+		result["preconfirmation"] = preconf
+	} else if !errors.Is(err, sequencer.ErrPreconfirmationsDisabled) {
+		trace.Logger(ctx).Warn().Err(err).Str("tx_hash", txHash).Msg("Failed to preconfirm transaction")
+	}
 
-	// Return transaction hash
 	response := JSONRPCResponse{
 		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"txHash": txHash,
-		},
-		ID: req.ID,
+		Result:  result,
+		ID:      req.ID,
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -276,8 +492,34 @@ func (s *Server) handleSendTransaction(w http.ResponseWriter, req *JSONRPCReques
 	}
 }
 
-// handleGetBalance handles the rollup_getBalance method
+// handleGetBalance handles the rollup_getBalance method. Params are the address and
+// optionally a batch number or "latest".
 func (s *Server) handleGetBalance(w http.ResponseWriter, req *JSONRPCRequest) {
+	account, result, ok := s.accountParam(w, req)
+	if !ok {
+		return
+	}
+
+	// Return balance
+	balance := "0"
+	if account != nil && account.Balance != nil {
+		balance = account.Balance.String()
+	}
+	result["balance"] = balance
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleGetCode handles the rollup_getCode method
+func (s *Server) handleGetCode(w http.ResponseWriter, req *JSONRPCRequest) {
 	var params []string
 	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
 		writeError(w, req, -32602, "Invalid params")
@@ -285,33 +527,46 @@ func (s *Server) handleGetBalance(w http.ResponseWriter, req *JSONRPCRequest) {
 	}
 
 	// Parse address
-	addrStr := params[0]
-	if len(addrStr) < 2 || addrStr[:2] != "0x" {
-		writeError(w, req, -32602, "Address must start with 0x")
+	address, err := types.ParseAddress(params[0])
+	if err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid address: %v", err))
 		return
 	}
 
-	addr := common.HexToAddress(addrStr)
-	var address [20]byte
-	copy(address[:], addr.Bytes())
-
-	// Get account from state
-	account, err := s.sequencer.GetAccount(address)
+	// Get code from state
+	code, err := s.sequencer.GetCode(address)
 	if err != nil {
 		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
 		return
 	}
 
-	// Return balance
-	balance := "0"
-	if account != nil && account.Balance != nil {
-		balance = account.Balance.String()
+	// Return code
+	codeHex := fmt.Sprintf("0x%x", code)
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"code":     codeHex,
+			"codeHash": fmt.Sprintf("0x%x", state.CodeHash(code)),
+		},
+		ID: req.ID,
 	}
 
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleSyncing handles the rollup_syncing method
+func (s *Server) handleSyncing(w http.ResponseWriter, req *JSONRPCRequest) {
+	status := s.sequencer.SyncStatus()
+
 	response := JSONRPCResponse{
 		JSONRPC: "2.0",
 		Result: map[string]interface{}{
-			"balance": balance,
+			"syncing":      status.Syncing,
+			"currentBatch": status.CurrentBatch,
+			"highestBatch": status.HighestBatch,
 		},
 		ID: req.ID,
 	}
@@ -321,39 +576,152 @@ func (s *Server) handleGetBalance(w http.ResponseWriter, req *JSONRPCRequest) {
 	}
 }
 
-// handleGetCode handles the rollup_getCode method
-func (s *Server) handleGetCode(w http.ResponseWriter, req *JSONRPCRequest) {
+// handleL1Status handles the rollup_l1Status method, reporting L1 submission progress and
+// whether the contract guardian paused batch acceptance or froze the bridge
+func (s *Server) handleL1Status(w http.ResponseWriter, req *JSONRPCRequest) {
+	status := s.sequencer.Status().L1
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"enabled":      status.Enabled,
+			"paused":       status.Paused,
+			"bridgeFrozen": status.BridgeFrozen,
+			"backlog":      status.Backlog,
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleMetrics handles the rollup_metrics method
+func (s *Server) handleMetrics(w http.ResponseWriter, req *JSONRPCRequest) {
+	rootTiming := s.sequencer.StateRootTiming()
+
+	proving := make([]map[string]interface{}, 0)
+	for _, p := range s.sequencer.ProofProgress() {
+		proving = append(proving, map[string]interface{}{
+			"batchNumber": p.BatchNumber,
+			"stage":       string(p.Stage),
+			"waitingSecs": time.Since(p.Enqueued).Seconds(),
+		})
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"stateRoot": map[string]interface{}{
+				"count":  rootTiming.Count,
+				"lastMs": float64(rootTiming.Last.Microseconds()) / 1000,
+				"avgMs":  float64(rootTiming.Avg.Microseconds()) / 1000,
+				"maxMs":  float64(rootTiming.Max.Microseconds()) / 1000,
+			},
+			"alerts": map[string]interface{}{
+				"secondsSinceLastBatch":     time.Since(s.sequencer.LastBatchTime()).Seconds(),
+				"l1SubmissionBacklog":       s.sequencer.L1SubmissionBacklog(),
+				"pendingConsensusRoundSecs": s.sequencer.PendingConsensusRound().Seconds(),
+				"proverQueueDepth":          s.sequencer.ProverQueueDepth(),
+				"proofDeadlineMisses":       s.sequencer.ProofDeadlineMisses(),
+			},
+			"proving": proving,
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleGasPrice handles the rollup_gasPrice method
+func (s *Server) handleGasPrice(w http.ResponseWriter, req *JSONRPCRequest) {
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"minGasPrice": s.sequencer.MinGasPrice().String(),
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleChainID handles the rollup_chainId method, returning the chain ID and rollup
+// contract of the EIP-712 domain transactions must be signed in
+func (s *Server) handleChainID(w http.ResponseWriter, req *JSONRPCRequest) {
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"chainId":             s.sequencer.ChainID(),
+			"verifyingContract":   s.sequencer.SigningContract().Hex(),
+			"allowUnprotectedTxs": s.sequencer.AllowsUnprotectedTxs(),
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleSetMinGasPrice handles the admin_setMinGasPrice method
+func (s *Server) handleSetMinGasPrice(w http.ResponseWriter, req *JSONRPCRequest) {
 	var params []string
 	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
 		writeError(w, req, -32602, "Invalid params")
 		return
 	}
 
-	// Parse address
-	addrStr := params[0]
-	if len(addrStr) < 2 || addrStr[:2] != "0x" {
-		writeError(w, req, -32602, "Address must start with 0x")
+	price := state.ParseAmount(params[0])
+	if price == nil {
+		writeError(w, req, -32602, "Invalid gas price format")
 		return
 	}
 
-	addr := common.HexToAddress(addrStr)
-	var address [20]byte
-	copy(address[:], addr.Bytes())
+	if err := s.sequencer.SetMinGasPrice(price); err != nil {
+		writeError(w, req, -32602, err.Error())
+		return
+	}
 
-	// Get code from state
-	code, err := s.sequencer.GetCode(address)
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"minGasPrice": price.String(),
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleEmergencyStatus handles the admin_emergencyStatus method
+func (s *Server) handleEmergencyStatus(w http.ResponseWriter, req *JSONRPCRequest) {
+	nonce, err := s.sequencer.EmergencyNonce()
 	if err != nil {
-		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		writeError(w, req, -32000, err.Error())
+		return
+	}
+	domain, err := s.sequencer.EmergencyDomain()
+	if err != nil {
+		writeError(w, req, -32000, err.Error())
 		return
 	}
 
-	// Return code
-	codeHex := fmt.Sprintf("0x%x", code)
-
+	// Requests must name the chain and contract, they are part of the signed digest
 	response := JSONRPCResponse{
 		JSONRPC: "2.0",
 		Result: map[string]interface{}{
-			"code": codeHex,
+			"nonce":    nonce,
+			"halted":   s.sequencer.IsHalted(),
+			"chainId":  domain.ChainID,
+			"contract": domain.Contract,
 		},
 		ID: req.ID,
 	}
@@ -363,6 +731,45 @@ func (s *Server) handleGetCode(w http.ResponseWriter, req *JSONRPCRequest) {
 	}
 }
 
+// handleApproveEmergency handles the admin_approveEmergency method. Params are the
+// emergency request and one operator's hex signature over its digest.
+func (s *Server) handleApproveEmergency(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 2 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var emergency sequencer.EmergencyRequest
+	if err := json.Unmarshal(params[0], &emergency); err != nil {
+		writeError(w, req, -32602, "Invalid emergency request")
+		return
+	}
+
+	var sigHex string
+	if err := json.Unmarshal(params[1], &sigHex); err != nil {
+		writeError(w, req, -32602, "Invalid signature format")
+		return
+	}
+	signature := common.FromHex(sigHex)
+
+	status, err := s.sequencer.ApproveEmergency(&emergency, signature)
+	if err != nil {
+		writeError(w, req, -32000, err.Error())
+		return
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  status,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
 // writeError writes a JSON-RPC error response
 func writeError(w http.ResponseWriter, req *JSONRPCRequest, code int, message string) {
 	response := JSONRPCResponse{
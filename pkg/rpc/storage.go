@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// handleGetStorageAt handles the rollup_getStorageAt method. Params are the contract
+// address, the slot as hex and optionally a batch number or "latest".
+func (s *Server) handleGetStorageAt(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 2 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var address types.Address
+	if err := json.Unmarshal(params[0], &address); err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid address: %v", err))
+		return
+	}
+
+	var slotStr string
+	if err := json.Unmarshal(params[1], &slotStr); err != nil {
+		writeError(w, req, -32602, "Invalid slot")
+		return
+	}
+	slot, err := parseSlot(slotStr)
+	if err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid slot: %v", err))
+		return
+	}
+
+	// Default to the latest batch
+	n := s.sequencer.BatchNumber()
+	if n == 0 {
+		writeError(w, req, -32000, "No batch finalized yet")
+		return
+	}
+	batchNumber, historic, err := parseBatchParam(params, 2)
+	if err != nil {
+		writeError(w, req, -32602, "Invalid batch number")
+		return
+	}
+	if !historic {
+		batchNumber = n - 1
+	}
+
+	value, err := s.sequencer.GetStorageAt(address, slot, batchNumber)
+	if err != nil {
+		if errors.Is(err, state.ErrStateNotRetained) {
+			s.writeNotRetained(w, req, batchNumber)
+			return
+		}
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"value":       fmt.Sprintf("0x%x", value),
+			"batchNumber": batchNumber,
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// accountParam reads the address and optional batch number params of an account query
+// and looks the account up, writing the error response when it fails. The returned result
+// map carries the batch number of historical queries.
+func (s *Server) accountParam(w http.ResponseWriter, req *JSONRPCRequest) (*state.Account, map[string]interface{}, bool) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return nil, nil, false
+	}
+
+	var addressStr string
+	if err := json.Unmarshal(params[0], &addressStr); err != nil {
+		writeError(w, req, -32602, "Invalid address")
+		return nil, nil, false
+	}
+	address, err := types.ParseAddress(addressStr)
+	if err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid address: %v", err))
+		return nil, nil, false
+	}
+
+	batchNumber, historic, err := parseBatchParam(params, 1)
+	if err != nil {
+		writeError(w, req, -32602, "Invalid batch number")
+		return nil, nil, false
+	}
+
+	result := map[string]interface{}{}
+	var account *state.Account
+	if historic {
+		account, err = s.sequencer.GetAccountAt(address, batchNumber)
+		result["batchNumber"] = batchNumber
+	} else {
+		account, err = s.sequencer.GetAccount(address)
+	}
+	if err != nil {
+		if errors.Is(err, state.ErrStateNotRetained) {
+			s.writeNotRetained(w, req, batchNumber)
+			return nil, nil, false
+		}
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return nil, nil, false
+	}
+	return account, result, true
+}
+
+// parseBatchParam reads the optional batch number at params[i]. historic is false when it
+// is missing, null or "latest".
+func parseBatchParam(params []json.RawMessage, i int) (batchNumber uint64, historic bool, err error) {
+	if len(params) <= i || string(params[i]) == `"latest"` || string(params[i]) == "null" {
+		return 0, false, nil
+	}
+	if err := json.Unmarshal(params[i], &batchNumber); err != nil {
+		return 0, false, err
+	}
+	return batchNumber, true, nil
+}
+
+// writeNotRetained reports a query for state the node no longer holds
+func (s *Server) writeNotRetained(w http.ResponseWriter, req *JSONRPCRequest, batchNumber uint64) {
+	n := s.sequencer.BatchNumber()
+	if n == 0 || batchNumber >= n {
+		writeError(w, req, -32000, fmt.Sprintf("Batch %d is not finalized yet", batchNumber))
+		return
+	}
+	writeError(w, req, -32000, fmt.Sprintf("State at batch %d is not retained, the oldest queryable batch is %d", batchNumber, s.sequencer.OldestRetainedBatch()))
+}
+
+// parseSlot parses a hex storage slot of up to 32 bytes such as "0x0", left padding
+// shorter values
+func parseSlot(s string) ([32]byte, error) {
+	var slot [32]byte
+	digits := strings.TrimPrefix(s, "0x")
+	if len(digits)%2 == 1 {
+		digits = "0" + digits
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return slot, err
+	}
+	if len(b) > 32 {
+		return slot, fmt.Errorf("slot longer than 32 bytes")
+	}
+	copy(slot[32-len(b):], b)
+	return slot, nil
+}
@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// SetTLS serves the RPC endpoint over HTTPS with the given PEM certificate and key. The
+// pair is loaded now, so a bad file fails at startup rather than on the first request.
+func (s *Server) SetTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load RPC TLS certificate: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return nil
+}
+
+// SetHTTP2 enables HTTP/2 alongside HTTP/1.1, negotiated over TLS or spoken in cleartext
+// by clients that know the server supports it (h2c)
+func (s *Server) SetHTTP2(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.http2 = enabled
+}
+
+// protocols returns the HTTP versions the server speaks
+func (s *Server) protocols() *http.Protocols {
+	var p http.Protocols
+	p.SetHTTP1(true)
+	if s.http2 {
+		if s.tlsConfig != nil {
+			p.SetHTTP2(true)
+		} else {
+			p.SetUnencryptedHTTP2(true)
+		}
+	}
+	return &p
+}
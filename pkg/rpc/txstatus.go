@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/sequencer"
+)
+
+// txStatusView is the JSON form of a transaction's progress through the rollup
+type txStatusView struct {
+	Hash        string  `json:"hash"`
+	Status      string  `json:"status"`      // pending, batched, proven, submitted or finalized
+	BatchNumber *uint64 `json:"batchNumber"` // Null while pending
+	L1TxHash    *string `json:"l1TxHash"`    // Null until the batch is submitted to L1
+}
+
+// handleGetTransactionStatus handles the rollup_getTransactionStatus method. The only
+// param is the transaction hash.
+func (s *Server) handleGetTransactionStatus(ctx context.Context, w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []string
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+	hashBytes, err := hexutil.Decode(params[0])
+	if err != nil || len(hashBytes) != common.HashLength {
+		writeError(w, req, -32602, "Invalid transaction hash")
+		return
+	}
+
+	status, err := s.sequencer.GetTransactionStatus(ctx, common.BytesToHash(hashBytes))
+	if errors.Is(err, sequencer.ErrTransactionNotFound) {
+		writeError(w, req, -32000, "Transaction not found")
+		return
+	}
+	if err != nil {
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return
+	}
+
+	view := txStatusView{Hash: status.Hash.Hex(), Status: string(status.Stage)}
+	if status.Stage != sequencer.TxPending {
+		batchNumber := status.BatchNumber
+		view.BatchNumber = &batchNumber
+	}
+	if status.L1TxHash != (common.Hash{}) {
+		l1TxHash := status.L1TxHash.Hex()
+		view.L1TxHash = &l1TxHash
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result:  view,
+		ID:      req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
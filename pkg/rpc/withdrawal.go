@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// merkleProofResult is the JSON form of a sparse Merkle tree proof, siblings are ordered
+// from the leaf upwards and bit i of the bitmap marks a non-empty sibling at height i
+type merkleProofResult struct {
+	Bitmap   string   `json:"bitmap"`
+	Siblings []string `json:"siblings"`
+}
+
+// withdrawalProofResult is the JSON form of a withdrawal proof, laid out like the
+// arguments of the L1 claimWithdrawal method
+type withdrawalProofResult struct {
+	Account     string `json:"account"`
+	Amount      string `json:"amount"` // Total withdrawn, in wei
+	BatchNumber uint64 `json:"batchNumber"`
+	StateRoot   string `json:"stateRoot"`
+
+	// Fields of the withdrawal address account and its proof against the state root
+	BridgeBalance     string            `json:"bridgeBalance"`
+	BridgeNonce       uint64            `json:"bridgeNonce"`
+	BridgeStorageRoot string            `json:"bridgeStorageRoot"`
+	BridgeCodeHash    string            `json:"bridgeCodeHash"`
+	AccountProof      merkleProofResult `json:"accountProof"`
+
+	// Proof of the account's withdrawal slot against the bridge storage root
+	StorageProof merkleProofResult `json:"storageProof"`
+}
+
+// handleGetWithdrawalProof handles the rollup_getWithdrawalProof method. Params are the
+// withdrawing account and optionally a batch number or "latest".
+func (s *Server) handleGetWithdrawalProof(w http.ResponseWriter, req *JSONRPCRequest) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 1 {
+		writeError(w, req, -32602, "Invalid params")
+		return
+	}
+
+	var account types.Address
+	if err := json.Unmarshal(params[0], &account); err != nil {
+		writeError(w, req, -32602, fmt.Sprintf("Invalid address: %v", err))
+		return
+	}
+
+	// Default to the latest batch
+	n := s.sequencer.BatchNumber()
+	if n == 0 {
+		writeError(w, req, -32000, "No batch finalized yet")
+		return
+	}
+	batchNumber := n - 1
+	if len(params) > 1 && string(params[1]) != `"latest"` && string(params[1]) != "null" {
+		if err := json.Unmarshal(params[1], &batchNumber); err != nil {
+			writeError(w, req, -32602, "Invalid batch number")
+			return
+		}
+	}
+
+	proof, err := s.sequencer.GetWithdrawalProof(account, batchNumber)
+	if err != nil {
+		if errors.Is(err, state.ErrStateNotRetained) {
+			writeError(w, req, -32000, fmt.Sprintf("State at batch %d is not retained, only the latest batch %d can be proven", batchNumber, n-1))
+			return
+		}
+		writeError(w, req, -32603, fmt.Sprintf("Internal error: %v", err))
+		return
+	}
+
+	response := JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: withdrawalProofResult{
+			Account:           proof.Account.Hex(),
+			Amount:            proof.Amount.String(),
+			BatchNumber:       proof.BatchNumber,
+			StateRoot:         fmt.Sprintf("0x%x", proof.StateRoot),
+			BridgeBalance:     proof.Bridge.Balance.String(),
+			BridgeNonce:       proof.Bridge.Nonce,
+			BridgeStorageRoot: fmt.Sprintf("0x%x", proof.Bridge.StorageRoot),
+			BridgeCodeHash:    fmt.Sprintf("0x%x", proof.Bridge.CodeHash),
+			AccountProof:      formatMerkleProof(proof.Bridge.Proof),
+			StorageProof:      formatMerkleProof(proof.Slot.Proof),
+		},
+		ID: req.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// formatMerkleProof converts a Merkle proof to its JSON form
+func formatMerkleProof(proof *state.MerkleProof) merkleProofResult {
+	result := merkleProofResult{
+		Bitmap:   fmt.Sprintf("0x%x", proof.Bitmap),
+		Siblings: make([]string, 0, len(proof.Siblings)),
+	}
+	for _, sibling := range proof.Siblings {
+		result.Siblings = append(result.Siblings, fmt.Sprintf("0x%x", sibling))
+	}
+	return result
+}
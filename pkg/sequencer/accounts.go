@@ -0,0 +1,49 @@
+package sequencer
+
+import (
+	"fmt"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
+	"zkrollup/pkg/types"
+)
+
+// authKey returns the EdDSA key registered for an account, nil for secp256k1 accounts
+func authKey(st *state.State, address types.Address) []byte {
+	acc, err := st.GetAccount(address)
+	if err != nil || acc == nil {
+		return nil
+	}
+	return acc.AuthKey
+}
+
+// verifyAuth checks a transaction's signature against the scheme its sender uses in st,
+// signed for the rollup contract rollup
+func verifyAuth(st *state.State, tx *state.Transaction, rollup types.Address) error {
+	return tx.VerifyAuth(authKey(st, tx.From), rollup)
+}
+
+// processKeyRegistration makes the sender a rollup-native account controlled by the EdDSA
+// key in the transaction's data. The key derives the sender's address, so it never changes.
+func (s *Sequencer) processKeyRegistration(st *state.State, tx state.Transaction, sender *state.Account) error {
+	if len(sender.AuthKey) > 0 {
+		return fmt.Errorf("account %s already has a registered key", tx.From)
+	}
+	if tx.Amount != nil && tx.Amount.Sign() != 0 {
+		return fmt.Errorf("key registration cannot transfer funds")
+	}
+	owner, err := state.EdDSAAddress(tx.Data)
+	if err != nil {
+		return err
+	}
+	if owner != tx.From {
+		return fmt.Errorf("key belongs to %s, not %s", owner, tx.From)
+	}
+
+	sender.AuthKey = append([]byte(nil), tx.Data...)
+	sender.Nonce++
+	st.SetAccount(sender)
+
+	trace.Logger(s.txContext(&tx)).Info().Str("account", tx.From.Hex()).Msg("Registered EdDSA key")
+	return nil
+}
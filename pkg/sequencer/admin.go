@@ -0,0 +1,119 @@
+package sequencer
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// PeerInfo is a connected peer and the addresses it is connected on
+type PeerInfo struct {
+	ID    string
+	Addrs []string
+}
+
+// Peers returns the connected peers
+func (s *Sequencer) Peers() []PeerInfo {
+	var peers []PeerInfo
+	for _, p := range s.node.GetPeers() {
+		info := PeerInfo{ID: p.String()}
+		for _, conn := range s.node.Host.Network().ConnsToPeer(p) {
+			info.Addrs = append(info.Addrs, conn.RemoteMultiaddr().String())
+		}
+		peers = append(peers, info)
+	}
+	return peers
+}
+
+// PoolTxs returns every transaction in the pool, including those submitted privately
+func (s *Sequencer) PoolTxs() []state.Transaction {
+	return s.mempool.All()
+}
+
+// PauseBatches stops this node from proposing batches until ResumeBatches. Unlike a halt,
+// transactions are still admitted to the pool and batches proposed by others are applied.
+func (s *Sequencer) PauseBatches() {
+	if !s.batchesPaused.Swap(true) {
+		log.Warn().Msg("Batch production paused by operator")
+	}
+}
+
+// ResumeBatches undoes PauseBatches
+func (s *Sequencer) ResumeBatches() {
+	if s.batchesPaused.Swap(false) {
+		log.Info().Msg("Batch production resumed by operator")
+		s.triggerBatch()
+	}
+}
+
+// BatchesPaused reports whether an operator paused batch production
+func (s *Sequencer) BatchesPaused() bool {
+	return s.batchesPaused.Load()
+}
+
+// ForceBatch has the leader propose a batch of whatever the pool holds right away, without
+// waiting for it to fill up or for the minimum interval. An empty pool yields an empty batch.
+func (s *Sequencer) ForceBatch() error {
+	switch {
+	case !s.isLeader:
+		return fmt.Errorf("only the leader proposes batches")
+	case s.halted.Load():
+		return ErrHalted
+	case s.batchesPaused.Load():
+		return fmt.Errorf("batch production is paused")
+	}
+
+	s.batchForced.Store(true)
+	s.triggerBatch()
+	log.Info().Msg("Batch triggered by operator")
+	return nil
+}
+
+// PauseL1Submission holds batch submissions to L1 until ResumeL1Submission. Finalized
+// batches keep queueing, up to the submission queue's capacity.
+func (s *Sequencer) PauseL1Submission() {
+	if !s.l1Held.Swap(true) {
+		log.Warn().Msg("L1 submission paused by operator")
+	}
+}
+
+// ResumeL1Submission undoes PauseL1Submission
+func (s *Sequencer) ResumeL1Submission() {
+	if s.l1Held.Swap(false) {
+		log.Info().Msg("L1 submission resumed by operator")
+	}
+}
+
+// L1SubmissionPaused reports whether an operator paused L1 submission
+func (s *Sequencer) L1SubmissionPaused() bool {
+	return s.l1Held.Load()
+}
+
+// ResubmitToL1 queues a finalized batch for L1 submission again, for when its earlier
+// submission failed. The batch is sent data-only unless it was proven.
+func (s *Sequencer) ResubmitToL1(batchNumber uint64) error {
+	if !s.l1Enabled || s.l1SubmitChan == nil {
+		return fmt.Errorf("L1 submission is disabled")
+	}
+
+	batches := s.state.GetBatches(batchNumber, 1)
+	if len(batches) == 0 {
+		return fmt.Errorf("batch %d not found", batchNumber)
+	}
+	batch := batches[0]
+
+	kind := submitDataOnly
+	if len(batch.Proof) > 0 {
+		kind = submitWithProof
+	}
+
+	select {
+	case s.l1SubmitChan <- l1Submission{batch: batch, kind: kind}:
+		log.Info().Uint64("batch_number", batchNumber).Str("kind", kind.String()).Msg("Batch requeued for L1 submission by operator")
+		return nil
+	default:
+		return fmt.Errorf("L1 submission queue is full")
+	}
+}
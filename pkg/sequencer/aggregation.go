@@ -0,0 +1,143 @@
+package sequencer
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/crypto"
+	"zkrollup/pkg/metrics"
+	"zkrollup/pkg/state"
+)
+
+// proofAggregator collects consecutive proven batches until there are enough to submit
+// them to L1 under one aggregated proof. It is only used by the L1 submitter.
+type proofAggregator struct {
+	prover  *crypto.Prover // Aggregation circuit over count proofs of the batch circuit
+	count   int
+	pending []state.Batch
+	since   time.Time // When the oldest pending batch arrived
+}
+
+func newProofAggregator(prover *crypto.Prover, count int) *proofAggregator {
+	return &proofAggregator{
+		prover: prover,
+		count:  count,
+	}
+}
+
+// add appends a proven batch and reports whether enough batches are waiting
+func (a *proofAggregator) add(batch state.Batch) bool {
+	if len(a.pending) == 0 {
+		a.since = time.Now()
+	}
+	a.pending = append(a.pending, batch)
+	return len(a.pending) >= a.count
+}
+
+// take removes and returns the waiting batches
+func (a *proofAggregator) take() []state.Batch {
+	batches := a.pending
+	a.pending = nil
+	return batches
+}
+
+// waiting returns how long the oldest pending batch has waited, 0 if there is none
+func (a *proofAggregator) waiting() time.Duration {
+	if len(a.pending) == 0 {
+		return 0
+	}
+	return time.Since(a.since)
+}
+
+// flushAggregation submits the batches waiting for aggregation with their own proofs
+func (s *Sequencer) flushAggregation() {
+	for _, batch := range s.aggregator.take() {
+		s.submitL1(l1Submission{batch: batch, kind: submitWithProof})
+	}
+}
+
+// submitAggregated proves that the proofs of consecutive batches verify and submits the
+// batches to L1 with that single proof. If aggregation fails they are submitted one by one.
+func (s *Sequencer) submitAggregated(batches []state.Batch) {
+	first, last := batches[0].BatchNumber, batches[len(batches)-1].BatchNumber
+
+	proofs := make([][]byte, len(batches))
+	publicInputs := make([][]byte, len(batches))
+	txCount := 0
+	for i := range batches {
+		proofs[i] = batches[i].Proof
+		publicInputs[i] = batches[i].PublicInputs
+		txCount += len(batches[i].Transactions)
+	}
+
+	start := time.Now()
+	assignment, err := crypto.AggregationAssignment(proofs, publicInputs)
+	var proof, inputs []byte
+	if err == nil {
+		proof, inputs, err = s.aggregator.prover.ProveSerialized(assignment)
+	}
+	if err != nil {
+		log.Error().Err(err).Uint64("first_batch", first).Uint64("last_batch", last).Msg("Failed to aggregate batch proofs, submitting them separately")
+		for _, batch := range batches {
+			s.submitL1(l1Submission{batch: batch, kind: submitWithProof})
+		}
+		return
+	}
+	log.Info().Uint64("first_batch", first).Uint64("last_batch", last).Dur("duration", time.Since(start)).Msg("Aggregated batch proofs")
+
+	for i := range batches {
+		if err = s.publishBatchData(&batches[i]); err != nil {
+			break
+		}
+	}
+	var tx *types.Transaction
+	if err == nil {
+		tx, err = s.l1Client.SubmitAggregatedBatches(s.ctx, batches, proof, inputs)
+	}
+	for _, batch := range batches {
+		s.recordL1Outcome(batch.BatchNumber, submitAggregated, err)
+	}
+	if err != nil {
+		log.Error().Err(err).Uint64("first_batch", first).Uint64("last_batch", last).Msg("Failed to submit aggregated batches to L1")
+		return
+	}
+	for _, batch := range batches {
+		if err := s.state.SetBatchL1TxHash(batch.BatchNumber, tx.Hash()); err != nil {
+			log.Warn().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to record L1 submission")
+		}
+	}
+
+	record := metrics.ProofRecord{
+		BatchNumber: last,
+		TxCount:     txCount,
+		ProofSize:   len(proof),
+		L1TxHash:    tx.Hash().Hex(),
+		Aggregated:  len(batches),
+	}
+	s.recordProofStats(record)
+	go s.recordSubmissionGas(tx, record)
+}
+
+// checkAggregateVerifier warns at startup when the contract cannot verify the aggregated
+// proofs this node produces, every aggregated submission would then be refused
+func (s *Sequencer) checkAggregateVerifier() {
+	if s.aggregator == nil {
+		return
+	}
+
+	verifier, err := s.l1Client.GetAggregateVerifier(s.ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read the aggregate verifier of the L1 contract")
+		return
+	}
+	if verifier.Verifier == (common.Address{}) {
+		log.Error().Int("batches", s.aggregator.count).Msg("Contract has no aggregate verifier, aggregated batches will not be accepted")
+		return
+	}
+	if want := crypto.AggregationSchema(s.aggregator.count).Hash(); verifier.BatchCount != s.aggregator.count || verifier.InputSchema != want {
+		log.Error().Int("contract_batches", verifier.BatchCount).Int("batches", s.aggregator.count).Hex("contract_schema", verifier.InputSchema[:]).Hex("circuit_schema", want[:]).Msg("Contract aggregates a different number of batches, aggregated batches will not be accepted")
+	}
+}
@@ -2,16 +2,21 @@ package sequencer
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog/log"
+	"zkrollup/pkg/metrics"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
 )
 
 // GetAccount retrieves an account from the state
-func (s *Sequencer) GetAccount(address [20]byte) (*state.Account, error) {
+func (s *Sequencer) GetAccount(address types.Address) (*state.Account, error) {
 	// Special handling for zero values to ensure consistent message hash computation
-	if isZeroAddress(address) {
+	if address.IsZero() {
 		return &state.Account{
 			Address: address,
 			Balance: big.NewInt(0),
@@ -29,7 +34,7 @@ func (s *Sequencer) GetAccount(address [20]byte) (*state.Account, error) {
 				Nonce:   0,
 			}, nil
 		}
-		log.Error().Err(err).Str("address", formatAddress(address)).Msg("Failed to get account")
+		log.Error().Err(err).Str("address", address.Hex()).Msg("Failed to get account")
 		return nil, err
 	}
 
@@ -37,9 +42,9 @@ func (s *Sequencer) GetAccount(address [20]byte) (*state.Account, error) {
 }
 
 // GetCode retrieves contract code from the state
-func (s *Sequencer) GetCode(address [20]byte) ([]byte, error) {
+func (s *Sequencer) GetCode(address types.Address) ([]byte, error) {
 	// Special handling for zero values
-	if isZeroAddress(address) {
+	if address.IsZero() {
 		return []byte{}, nil
 	}
 
@@ -49,7 +54,7 @@ func (s *Sequencer) GetCode(address [20]byte) ([]byte, error) {
 			// Return empty code if not found
 			return []byte{}, nil
 		}
-		log.Error().Err(err).Str("address", formatAddress(address)).Msg("Failed to get code")
+		log.Error().Err(err).Str("address", address.Hex()).Msg("Failed to get code")
 		return nil, err
 	}
 
@@ -57,9 +62,9 @@ func (s *Sequencer) GetCode(address [20]byte) ([]byte, error) {
 }
 
 // GetStorage retrieves a storage value from the state
-func (s *Sequencer) GetStorage(address [20]byte, key [32]byte) ([32]byte, error) {
+func (s *Sequencer) GetStorage(address types.Address, key [32]byte) ([32]byte, error) {
 	// Special handling for zero values
-	if isZeroAddress(address) {
+	if address.IsZero() {
 		return [32]byte{}, nil
 	}
 
@@ -70,7 +75,7 @@ func (s *Sequencer) GetStorage(address [20]byte, key [32]byte) ([32]byte, error)
 			return [32]byte{}, nil
 		}
 		log.Error().Err(err).
-			Str("address", formatAddress(address)).
+			Str("address", address.Hex()).
 			Str("key", formatBytes(key[:])).
 			Msg("Failed to get storage")
 		return [32]byte{}, err
@@ -79,23 +84,133 @@ func (s *Sequencer) GetStorage(address [20]byte, key [32]byte) ([32]byte, error)
 	return value, nil
 }
 
-// Helper functions
+// GetStorageAt retrieves a storage value as it was after the given batch
+func (s *Sequencer) GetStorageAt(address types.Address, key [32]byte, batchNumber uint64) ([32]byte, error) {
+	return s.state.GetStorageAt(address, key, batchNumber)
+}
+
+// GetAccountAt retrieves an account as it was after the given batch, an empty account if
+// it did not exist then
+func (s *Sequencer) GetAccountAt(address types.Address, batchNumber uint64) (*state.Account, error) {
+	account, err := s.state.GetAccountAt(address, batchNumber)
+	if errors.Is(err, state.ErrAccountNotFound) {
+		return &state.Account{
+			Address: address,
+			Balance: big.NewInt(0),
+		}, nil
+	}
+	return account, err
+}
+
+// OldestRetainedBatch returns the oldest batch whose state can be queried
+func (s *Sequencer) OldestRetainedBatch() uint64 {
+	return s.state.OldestRetainedBatch()
+}
+
+// GetWithdrawalProof proves the total withdrawn by account as of the given batch
+func (s *Sequencer) GetWithdrawalProof(account types.Address, batchNumber uint64) (*state.WithdrawalProof, error) {
+	return s.state.GetWithdrawalProof(account, batchNumber)
+}
+
+// GetProof proves an account and storage slots of it against the state root after the
+// given batch
+func (s *Sequencer) GetProof(address types.Address, keys [][32]byte, batchNumber uint64) (*state.StateProof, error) {
+	return s.state.GetProofAt(address, keys, batchNumber)
+}
+
+// AccountSummary is what a wallet tracks of an account
+type AccountSummary struct {
+	Address types.Address
+	Balance *big.Int
+	Nonce   uint64
+	HasCode bool
+}
 
-// isZeroAddress checks if an address is the zero address
-func isZeroAddress(address [20]byte) bool {
-	for _, b := range address {
-		if b != 0 {
-			return false
+// GetAccounts reads several accounts as of the same batch and returns them with the number
+// of finalized batches they reflect. A batch finalized while reading restarts the read.
+func (s *Sequencer) GetAccounts(addresses []types.Address) ([]AccountSummary, uint64, error) {
+	for {
+		before := s.state.GetBatchNumber()
+
+		summaries := make([]AccountSummary, len(addresses))
+		for i, address := range addresses {
+			account, err := s.GetAccount(address)
+			if err != nil {
+				return nil, 0, err
+			}
+			code, err := s.GetCode(address)
+			if err != nil {
+				return nil, 0, err
+			}
+			summaries[i] = AccountSummary{
+				Address: address,
+				Balance: account.Balance,
+				Nonce:   account.Nonce,
+				HasCode: len(code) > 0,
+			}
+		}
+
+		if s.state.GetBatchNumber() == before {
+			return summaries, before, nil
 		}
 	}
-	return true
 }
 
-// formatAddress formats an address as a hex string
-func formatAddress(address [20]byte) string {
-	return "0x" + formatBytes(address[:])
+// RootVersion returns the hash version of the state roots
+func (s *Sequencer) RootVersion() state.RootVersion {
+	return s.state.RootVersion()
+}
+
+// ChainID returns the chain ID transactions are signed with
+func (s *Sequencer) ChainID() uint64 {
+	return s.config.L2ChainID()
+}
+
+// SigningContract returns the rollup contract named in the EIP-712 domain transactions are
+// signed in, the zero address for nodes without one
+func (s *Sequencer) SigningContract() types.Address {
+	if s.config.ContractAddress == "" {
+		return types.Address{}
+	}
+	return types.FromCommon(common.HexToAddress(s.config.ContractAddress))
+}
+
+// AllowsUnprotectedTxs reports whether transactions signed without a chain ID are admitted
+func (s *Sequencer) AllowsUnprotectedTxs() bool {
+	return s.config.AllowUnprotectedTxs
+}
+
+// BatchNumber returns the number of finalized batches
+func (s *Sequencer) BatchNumber() uint64 {
+	return s.state.GetBatchNumber()
+}
+
+// GetBatches returns up to count finalized batches starting at batch number from
+func (s *Sequencer) GetBatches(from, count uint64) []state.Batch {
+	return s.state.GetBatches(from, count)
+}
+
+// ProofGenerationEnabled reports whether finalized batches are proven
+func (s *Sequencer) ProofGenerationEnabled() bool {
+	return s.proofs != nil
 }
 
+// GetLogs returns the contract logs matching filter
+func (s *Sequencer) GetLogs(filter state.LogFilter) []state.Log {
+	return s.state.GetLogs(filter)
+}
+
+// GetTransactionByHash returns a finalized transaction and its receipt
+func (s *Sequencer) GetTransactionByHash(hash [32]byte) (*state.Transaction, *state.Receipt, error) {
+	tx, receipt, ok := s.state.GetTransactionByHash(hash)
+	if !ok {
+		return nil, nil, ErrTransactionNotFound
+	}
+	return tx, receipt, nil
+}
+
+// Helper functions
+
 // formatBytes formats bytes as a hex string
 func formatBytes(b []byte) string {
 	hex := make([]byte, len(b)*2)
@@ -105,3 +220,72 @@ func formatBytes(b []byte) string {
 	}
 	return string(hex)
 }
+
+// MinGasPrice returns the minimum gas price accepted into the mempool
+func (s *Sequencer) MinGasPrice() *big.Int {
+	return s.mempool.MinGasPrice()
+}
+
+// SetMinGasPrice changes the minimum gas price accepted into the mempool
+func (s *Sequencer) SetMinGasPrice(price *big.Int) error {
+	if price == nil || price.Sign() < 0 {
+		return fmt.Errorf("minimum gas price must not be negative")
+	}
+
+	s.mempool.SetMinGasPrice(price)
+	log.Info().Str("min_gas_price", price.String()).Msg("Updated minimum gas price")
+	return nil
+}
+
+// StateRootTiming returns how long state root computation of finalized batches takes
+func (s *Sequencer) StateRootTiming() metrics.TimingSnapshot {
+	return s.stateRootTiming.Snapshot()
+}
+
+// LastBatchTime returns when the last batch was finalized, or the start time before the first
+func (s *Sequencer) LastBatchTime() time.Time {
+	return time.Unix(0, s.lastBatchTime.Load())
+}
+
+// L1SubmissionBacklog returns the number of batches queued for L1 submission
+func (s *Sequencer) L1SubmissionBacklog() int {
+	if s.l1SubmitChan == nil {
+		return 0
+	}
+	return len(s.l1SubmitChan)
+}
+
+// PendingConsensusRound returns how long the oldest undecided consensus round has been open
+func (s *Sequencer) PendingConsensusRound() time.Duration {
+	return s.consensus.OldestPendingRound()
+}
+
+// MempoolDepth returns the number of transactions waiting in the pool
+func (s *Sequencer) MempoolDepth() int {
+	return s.mempool.Len()
+}
+
+// PeerCount returns the number of connected peers
+func (s *Sequencer) PeerCount() int {
+	return len(s.node.GetPeers())
+}
+
+// GossipPeerCount returns the number of peers reached by messages gossiped on every topic
+func (s *Sequencer) GossipPeerCount() int {
+	return s.node.GossipPeerCount()
+}
+
+// Metrics returns the node's Prometheus metrics, registered by the caller to export them
+func (s *Sequencer) Metrics() *metrics.NodeMetrics {
+	return s.nodeMetrics
+}
+
+// ProverQueueDepth returns the number of finalized batches whose proof is not released yet
+func (s *Sequencer) ProverQueueDepth() int {
+	if s.proofs == nil {
+		return 0
+	}
+	s.proofs.mu.Lock()
+	defer s.proofs.mu.Unlock()
+	return len(s.proofs.order)
+}
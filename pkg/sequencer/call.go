@@ -0,0 +1,32 @@
+package sequencer
+
+import (
+	"context"
+	"math/big"
+
+	"zkrollup/pkg/evm"
+	"zkrollup/pkg/types"
+)
+
+// callGasCap is the gas a read-only call gets when the caller does not set a limit
+const callGasCap = 50000000
+
+// Call executes a contract call against a copy of the current state and returns its
+// output, the live state is never modified. It serves view functions, so the caller
+// does not need a signature or funds beyond the value sent.
+func (s *Sequencer) Call(ctx context.Context, from, to types.Address, value *big.Int, gas uint64, data []byte) ([]byte, error) {
+	if gas == 0 {
+		gas = callGasCap
+	}
+
+	snapshot := s.state.Copy()
+	if sender, funded := faucetAccount(snapshot, from); funded {
+		snapshot.SetAccount(sender)
+	}
+
+	// Nothing executed here reaches a batch, so the wall-clock limit applies
+	ctx, cancel := s.evmExecutor.WithTimeout(ctx)
+	defer cancel()
+	ret, _, err := s.evmExecutor.ExecuteContract(ctx, evm.NewStateAdapter(snapshot), from.Common(), to.Common(), value, gas, data)
+	return ret, err
+}
@@ -0,0 +1,83 @@
+package sequencer
+
+import (
+	"fmt"
+	"time"
+
+	"zkrollup/pkg/l1"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+const depositPollInterval = 5 * time.Second
+
+// collectDeposits records confirmed L1 deposits until they are included in a batch
+func (s *Sequencer) collectDeposits() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case d := <-s.depositWatcher.Deposits():
+			s.depositsMu.Lock()
+			s.deposits[d.Nonce] = d
+			s.depositsMu.Unlock()
+		}
+	}
+}
+
+// pendingDepositTxs returns the observed deposits not yet credited, as transactions in
+// nonce order. Deposits already credited are forgotten.
+func (s *Sequencer) pendingDepositTxs() []state.Transaction {
+	next := s.state.GetDepositNonce()
+
+	s.depositsMu.Lock()
+	defer s.depositsMu.Unlock()
+
+	for nonce := range s.deposits {
+		if nonce < next {
+			delete(s.deposits, nonce)
+		}
+	}
+
+	var txs []state.Transaction
+	for nonce := next; ; nonce++ {
+		d, ok := s.deposits[nonce]
+		if !ok {
+			break
+		}
+		txs = append(txs, depositTransaction(d))
+	}
+	return txs
+}
+
+// checkDeposit verifies a deposit transaction of a proposed batch against the deposits
+// this node observed on L1. Nodes without L1 access rely on the deposit nonce alone.
+func (s *Sequencer) checkDeposit(tx state.Transaction) error {
+	if s.depositWatcher == nil {
+		return nil
+	}
+
+	s.depositsMu.Lock()
+	d, ok := s.deposits[tx.Nonce]
+	s.depositsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("deposit %d not observed on L1", tx.Nonce)
+	}
+
+	expected := depositTransaction(d)
+	if tx.To != expected.To || tx.From != expected.From || tx.Amount == nil || tx.Amount.Cmp(expected.Amount) != 0 {
+		return fmt.Errorf("deposit %d does not match L1", tx.Nonce)
+	}
+	return nil
+}
+
+// depositTransaction converts an L1 deposit to the L2 transaction crediting it
+func depositTransaction(d l1.Deposit) state.Transaction {
+	return state.Transaction{
+		Type:   state.TxTypeDeposit,
+		From:   types.FromCommon(d.From),
+		To:     types.FromCommon(d.Recipient),
+		Amount: d.Amount,
+		Nonce:  d.Nonce,
+	}
+}
@@ -0,0 +1,81 @@
+package sequencer
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// DevAccountBalance is credited to every dev account at genesis, 10000 ether
+var DevAccountBalance = new(big.Int).Mul(big.NewInt(10000), big.NewInt(1e18))
+
+// DevAccount is a pre-funded account of the dev chain
+type DevAccount struct {
+	Address    types.Address
+	PrivateKey *ecdsa.PrivateKey
+}
+
+// DevAccounts derives count deterministic accounts, the same on every run so scripts and
+// tests can hardcode them
+func DevAccounts(count int) []DevAccount {
+	accounts := make([]DevAccount, 0, count)
+	for i := 0; i < count; i++ {
+		seed := crypto.Keccak256([]byte(fmt.Sprintf("zkrollup-dev-account-%d", i)))
+		key, err := crypto.ToECDSA(seed)
+		if err != nil {
+			// Keccak output is a valid secp256k1 scalar except with negligible probability
+			panic(fmt.Sprintf("invalid dev key %d: %v", i, err))
+		}
+		accounts = append(accounts, DevAccount{
+			Address:    types.FromCommon(crypto.PubkeyToAddress(key.PublicKey)),
+			PrivateKey: key,
+		})
+	}
+	return accounts
+}
+
+// fundDevAccounts credits the dev accounts in a fresh state, a state that already has
+// batches keeps its balances
+func fundDevAccounts(st *state.State, accounts []DevAccount) error {
+	if st.GetBatchNumber() > 0 {
+		return nil
+	}
+	for _, dev := range accounts {
+		if acc, err := st.GetAccount(dev.Address); err == nil && acc != nil {
+			continue
+		}
+		st.SetAccount(&state.Account{
+			Address: dev.Address,
+			Balance: new(big.Int).Set(DevAccountBalance),
+		})
+	}
+	if err := st.Flush(); err != nil {
+		return fmt.Errorf("failed to persist dev accounts: %v", err)
+	}
+	log.Info().Int("accounts", len(accounts)).Str("balance", DevAccountBalance.String()).Msg("Funded dev accounts")
+	return nil
+}
+
+// batchTimestamp returns the timestamp of batch number n, fixed in dev mode when a start
+// timestamp is configured
+func (s *Sequencer) batchTimestamp(n uint64) uint64 {
+	if s.config.DevMode && s.config.DevTimestamp > 0 {
+		return s.config.DevTimestamp + n
+	}
+	return uint64(time.Now().Unix())
+}
+
+// triggerBatch asks the batch loop to try creating a batch right away
+func (s *Sequencer) triggerBatch() {
+	select {
+	case s.batchTrigger <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,299 @@
+package sequencer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/p2p"
+)
+
+// EmergencyAction names a destructive operation that needs operator signatures
+type EmergencyAction string
+
+const (
+	EmergencyHalt              EmergencyAction = "halt"              // stop accepting transactions and producing batches
+	EmergencyResume            EmergencyAction = "resume"            // undo a halt
+	EmergencySetValidators     EmergencyAction = "setValidators"     // replace the consensus validator set
+	EmergencyOverrideStateRoot EmergencyAction = "overrideStateRoot" // replace the recorded root of a finalized batch
+)
+
+// ErrHalted is returned for transactions submitted while the chain is halted
+var ErrHalted = errors.New("chain is halted")
+
+// EmergencyDomain identifies the deployment a council acts for, so signatures collected for
+// one rollup cannot be replayed on another
+type EmergencyDomain struct {
+	ChainID  uint64         `json:"chainId"`  // Chain ID of the rollup
+	Contract common.Address `json:"contract"` // Rollup contract on L1, zero without one
+}
+
+// EmergencyRequest is the operation operators sign. It names the deployment it is meant
+// for, Nonce must match the council's next nonce and Expiry bounds how long collected
+// signatures remain usable.
+type EmergencyRequest struct {
+	ChainID     uint64          `json:"chainId"`
+	Contract    common.Address  `json:"contract"`
+	Action      EmergencyAction `json:"action"`
+	Nonce       uint64          `json:"nonce"`
+	Expiry      int64           `json:"expiry"` // Unix seconds
+	Validators  []string        `json:"validators,omitempty"`
+	BatchNumber uint64          `json:"batchNumber,omitempty"`
+	StateRoot   common.Hash     `json:"stateRoot,omitempty"`
+}
+
+// Digest returns the hash operators sign, covering the deployment the request is for
+func (r *EmergencyRequest) Digest() common.Hash {
+	data, _ := json.Marshal(r)
+	return crypto.Keccak256Hash([]byte("zkrollup-emergency:"), data)
+}
+
+// validate checks the request carries the parameters its action needs
+func (r *EmergencyRequest) validate() error {
+	switch r.Action {
+	case EmergencyHalt, EmergencyResume:
+		return nil
+	case EmergencySetValidators:
+		if len(r.Validators) == 0 {
+			return fmt.Errorf("validator set must not be empty")
+		}
+		// Validators are identified by peer ID, a mistyped one must not use up the approvals
+		if _, err := p2p.ParsePeerIDs(r.Validators); err != nil {
+			return fmt.Errorf("invalid validator set: %v", err)
+		}
+		return nil
+	case EmergencyOverrideStateRoot:
+		if r.StateRoot == (common.Hash{}) {
+			return fmt.Errorf("state root must be set")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown emergency action %q", r.Action)
+	}
+}
+
+// SignEmergencyRequest signs a request with an operator's private key
+func SignEmergencyRequest(req *EmergencyRequest, privateKey []byte) ([]byte, error) {
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	digest := req.Digest()
+	return crypto.Sign(digest[:], key)
+}
+
+// EmergencyStatus reports how far a request is from execution
+type EmergencyStatus struct {
+	Digest    common.Hash `json:"digest"`
+	Approvals int         `json:"approvals"`
+	Threshold int         `json:"threshold"`
+	Executed  bool        `json:"executed"`
+}
+
+// EmergencyNonceStore keeps the council nonce across restarts, so executed requests cannot
+// be replayed once the node comes back
+type EmergencyNonceStore interface {
+	EmergencyNonce() uint64
+	SetEmergencyNonce(nonce uint64) error
+}
+
+// EmergencyCouncil collects operator signatures and releases a request once Threshold
+// distinct operators signed it
+type EmergencyCouncil struct {
+	operators map[common.Address]bool
+	threshold int
+	domain    EmergencyDomain
+	nonces    EmergencyNonceStore
+
+	nonce     uint64
+	approvals map[common.Hash]map[common.Address]bool
+	mu        sync.Mutex
+}
+
+// NewEmergencyCouncil creates a council requiring threshold of the given operators for
+// requests of domain, starting from the nonce kept in nonces
+func NewEmergencyCouncil(operators []common.Address, threshold int, domain EmergencyDomain, nonces EmergencyNonceStore) (*EmergencyCouncil, error) {
+	set := make(map[common.Address]bool, len(operators))
+	for _, op := range operators {
+		set[op] = true
+	}
+	if threshold <= 0 || threshold > len(set) {
+		return nil, fmt.Errorf("threshold %d out of range for %d operators", threshold, len(set))
+	}
+
+	return &EmergencyCouncil{
+		operators: set,
+		threshold: threshold,
+		domain:    domain,
+		nonces:    nonces,
+		nonce:     nonces.EmergencyNonce(),
+		approvals: make(map[common.Hash]map[common.Address]bool),
+	}, nil
+}
+
+// Domain returns the deployment requests must name
+func (c *EmergencyCouncil) Domain() EmergencyDomain {
+	return c.domain
+}
+
+// Nonce returns the nonce the next request must carry
+func (c *EmergencyCouncil) Nonce() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nonce
+}
+
+// Approve records an operator signature over req. Once the threshold is reached the
+// nonce advances and is stored, pending approvals are dropped and the status reports
+// Executed.
+func (c *EmergencyCouncil) Approve(req *EmergencyRequest, signature []byte, now time.Time) (EmergencyStatus, error) {
+	if err := req.validate(); err != nil {
+		return EmergencyStatus{}, err
+	}
+
+	digest := req.Digest()
+	status := EmergencyStatus{Digest: digest, Threshold: c.threshold}
+
+	signer, err := recoverSigner(digest, signature)
+	if err != nil {
+		return status, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.operators[signer] {
+		return status, fmt.Errorf("%s is not an emergency operator", signer.Hex())
+	}
+	if req.ChainID != c.domain.ChainID || req.Contract != c.domain.Contract {
+		return status, fmt.Errorf("request is for chain %d contract %s, this council acts for chain %d contract %s", req.ChainID, req.Contract.Hex(), c.domain.ChainID, c.domain.Contract.Hex())
+	}
+	if req.Nonce != c.nonce {
+		return status, fmt.Errorf("invalid nonce %d, expected %d", req.Nonce, c.nonce)
+	}
+	if now.Unix() > req.Expiry {
+		return status, fmt.Errorf("request expired")
+	}
+
+	signers, ok := c.approvals[digest]
+	if !ok {
+		signers = make(map[common.Address]bool)
+		c.approvals[digest] = signers
+	}
+	signers[signer] = true
+	status.Approvals = len(signers)
+
+	if status.Approvals >= c.threshold {
+		// The request is only executed once its nonce is used up on disk
+		if err := c.nonces.SetEmergencyNonce(c.nonce + 1); err != nil {
+			delete(signers, signer)
+			status.Approvals = len(signers)
+			return status, fmt.Errorf("failed to store emergency nonce: %v", err)
+		}
+		c.nonce++
+		c.approvals = make(map[common.Hash]map[common.Address]bool)
+		status.Executed = true
+	}
+	return status, nil
+}
+
+// recoverSigner returns the address that produced a 65 byte [R || S || V] signature
+func recoverSigner(digest common.Hash, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length: got %d, want %d", len(signature), crypto.SignatureLength)
+	}
+
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(digest[:], sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signature: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// IsHalted reports whether the chain was halted by the emergency council
+func (s *Sequencer) IsHalted() bool {
+	return s.halted.Load()
+}
+
+// EmergencyNonce returns the nonce the next emergency request must carry
+func (s *Sequencer) EmergencyNonce() (uint64, error) {
+	if s.emergency == nil {
+		return 0, fmt.Errorf("emergency operations are not configured")
+	}
+	return s.emergency.Nonce(), nil
+}
+
+// EmergencyDomain returns the deployment emergency requests must name
+func (s *Sequencer) EmergencyDomain() (EmergencyDomain, error) {
+	if s.emergency == nil {
+		return EmergencyDomain{}, fmt.Errorf("emergency operations are not configured")
+	}
+	return s.emergency.Domain(), nil
+}
+
+// ApproveEmergency records an operator signature and executes the request once enough
+// operators signed it
+func (s *Sequencer) ApproveEmergency(req *EmergencyRequest, signature []byte) (EmergencyStatus, error) {
+	if s.emergency == nil {
+		return EmergencyStatus{}, fmt.Errorf("emergency operations are not configured")
+	}
+
+	status, err := s.emergency.Approve(req, signature, time.Now())
+	if err != nil {
+		return status, err
+	}
+
+	log.Warn().Str("action", string(req.Action)).Uint64("nonce", req.Nonce).Int("approvals", status.Approvals).Int("threshold", status.Threshold).Msg("Recorded emergency approval")
+	if !status.Executed {
+		return status, nil
+	}
+
+	if err := s.executeEmergency(req); err != nil {
+		return status, fmt.Errorf("approved but failed to execute: %v", err)
+	}
+	return status, nil
+}
+
+// executeEmergency carries out an approved request
+func (s *Sequencer) executeEmergency(req *EmergencyRequest) error {
+	switch req.Action {
+	case EmergencyHalt:
+		s.halted.Store(true)
+		log.Warn().Msg("Chain halted by emergency council")
+	case EmergencyResume:
+		s.halted.Store(false)
+		log.Warn().Msg("Chain resumed by emergency council")
+	case EmergencySetValidators:
+		// An allowlisted network follows the new set, validators are identified by peer ID
+		if len(s.config.ConsensusPeers) > 0 || len(s.config.Validators) > 0 {
+			ids, err := p2p.ParsePeerIDs(req.Validators)
+			if err != nil {
+				return fmt.Errorf("failed to update consensus allowlist: %v", err)
+			}
+			s.node.SetConsensusAllowlist(ids)
+		}
+		s.consensus.SetValidators(req.Validators)
+		log.Warn().Strs("validators", req.Validators).Msg("Validator set replaced by emergency council")
+	case EmergencyOverrideStateRoot:
+		if err := s.state.SetBatchStateRoot(req.BatchNumber, req.StateRoot); err != nil {
+			return err
+		}
+		if err := s.state.Flush(); err != nil {
+			return fmt.Errorf("failed to persist state: %v", err)
+		}
+		log.Warn().Uint64("batch_number", req.BatchNumber).Str("state_root", req.StateRoot.Hex()).Msg("Batch state root overridden by emergency council")
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package sequencer
+
+import "errors"
+
+// Transaction admission errors, matched with errors.Is. The RPC server maps each to its
+// own JSON-RPC error code, the wrapped message carries the details.
+var (
+	ErrInvalidNonce        = errors.New("invalid nonce")
+	ErrInsufficientBalance = errors.New("insufficient balance for amount and gas")
+	ErrGasTooLow           = errors.New("gas too low")
+	ErrInvalidChainID      = errors.New("invalid chain ID")
+	ErrDepositNotAllowed   = errors.New("deposits must be made on L1")
+)
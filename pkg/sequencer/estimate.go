@@ -0,0 +1,79 @@
+package sequencer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/params"
+
+	"zkrollup/pkg/state"
+)
+
+// EstimateGas returns the lowest gas limit with which tx succeeds against the current
+// state, like eth_estimateGas. The transaction is executed on state copies with a binary
+// search between zero and its gas limit, the call gas cap when it sets none, lowered to
+// what the sender can pay for at its gas price. Nonce and signature are not checked. The
+// search stops with the context's error once ctx is done.
+func (s *Sequencer) EstimateGas(ctx context.Context, tx state.Transaction) (uint64, error) {
+	switch tx.Type {
+	case state.TxTypeTransfer:
+		// Transfers run no code, their gas is fixed
+		if err := s.tryGas(ctx, s.state.Copy(), tx, params.TxGas); err != nil {
+			return 0, err
+		}
+		return params.TxGas, nil
+	case state.TxTypeContractDeploy, state.TxTypeContractCreate, state.TxTypeContractCall:
+	default:
+		return 0, fmt.Errorf("gas estimation is not supported for transaction type %d", tx.Type)
+	}
+
+	base := s.state.Copy()
+	hi := tx.Gas
+	if hi == 0 {
+		hi = callGasCap
+	}
+
+	// Cap the search at the gas the sender's balance covers
+	if price := gasPrice(&tx); price.Sign() > 0 {
+		sender, _ := faucetAccount(base, tx.From)
+		available := new(big.Int).Set(sender.Balance)
+		if tx.Amount != nil {
+			available.Sub(available, tx.Amount)
+		}
+		if available.Sign() < 0 {
+			return 0, fmt.Errorf("insufficient balance for amount: have %s, need %s", sender.Balance, tx.Amount)
+		}
+		if allowance := available.Div(available, price); allowance.IsUint64() && allowance.Uint64() < hi {
+			hi = allowance.Uint64()
+		}
+	}
+
+	// A transaction failing with all the gas available fails for another reason
+	if err := s.tryGas(ctx, base, tx, hi); err != nil {
+		return 0, err
+	}
+
+	lo := uint64(0)
+	for lo+1 < hi {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		mid := lo + (hi-lo)/2
+		if s.tryGas(ctx, base, tx, mid) == nil {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
+
+// tryGas executes tx with the given gas limit on a copy of base, as the sender's next
+// transaction
+func (s *Sequencer) tryGas(ctx context.Context, base *state.State, tx state.Transaction, gas uint64) error {
+	sender, _ := faucetAccount(base, tx.From)
+	tx.Nonce = sender.Nonce + 1
+	tx.Gas = gas
+	return s.applyTransaction(ctx, base.Copy(), tx)
+}
@@ -0,0 +1,242 @@
+package sequencer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/consensus"
+	"zkrollup/pkg/evm"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
+	"zkrollup/pkg/types"
+)
+
+// testBalance is credited to senders without funds so test clients work out of the box
+var testBalance = big.NewInt(1000)
+
+// faucetAccount returns the sender account as execution will see it: senders that don't
+// exist yet or have no balance are credited testBalance. The returned bool reports whether
+// the faucet applied, st itself is not modified.
+func faucetAccount(st *state.State, address types.Address) (*state.Account, bool) {
+	acc, err := st.GetAccount(address)
+	if err != nil || acc == nil {
+		return &state.Account{
+			Address: address,
+			Balance: new(big.Int).Set(testBalance),
+		}, true
+	}
+
+	if acc.Balance == nil || acc.Balance.Sign() == 0 {
+		funded := *acc
+		funded.Balance = new(big.Int).Set(testBalance)
+		return &funded, true
+	}
+
+	return acc, false
+}
+
+// nextNonce returns the nonce the next transaction of an account must carry, one past the
+// nonce of its last executed transaction
+func (s *Sequencer) nextNonce(address types.Address) uint64 {
+	acc, _ := faucetAccount(s.state, address)
+	return acc.Nonce + 1
+}
+
+// txContext returns the sequencer context carrying the transaction's trace ID. It is not
+// cancelled when the sequencer stops, batch transactions must execute the same everywhere.
+func (s *Sequencer) txContext(tx *state.Transaction) context.Context {
+	return trace.WithID(context.WithoutCancel(s.ctx), tx.TraceID)
+}
+
+// applyTransaction executes a single transaction against st. Contract code stops running
+// when ctx is done, the transaction then fails with the context's error.
+func (s *Sequencer) applyTransaction(ctx context.Context, st *state.State, tx state.Transaction) error {
+	// Deposits mint funds locked on L1, the sender is an L1 account
+	if tx.Type == state.TxTypeDeposit {
+		return st.ApplyDeposit(tx.Nonce, tx.To, tx.Amount)
+	}
+
+	// Each transaction consumes its sender's next nonce, so none can be applied twice
+	sender, funded := faucetAccount(st, tx.From)
+	if tx.Nonce != sender.Nonce+1 {
+		return fmt.Errorf("%w: %d, account nonce is %d", ErrInvalidNonce, tx.Nonce, sender.Nonce)
+	}
+	if funded {
+		st.SetAccount(sender)
+	}
+
+	switch tx.Type {
+	case state.TxTypeTransfer:
+		return s.processTransferTransaction(st, tx, sender)
+	case state.TxTypeContractDeploy, state.TxTypeContractCreate:
+		return s.processContractDeployment(ctx, st, tx, sender)
+	case state.TxTypeContractCall:
+		return s.processContractCall(ctx, st, tx, sender)
+	case state.TxTypeRegisterKey:
+		return s.processKeyRegistration(st, tx, sender)
+	default:
+		return fmt.Errorf("unknown transaction type: %d", tx.Type)
+	}
+}
+
+// applyBatch executes every transaction of batch against st and records their receipts. In
+// strict mode the first invalid transaction aborts with an error, otherwise failures are
+// logged and skipped. Code that ran and failed is not invalid, it is charged in both modes.
+func (s *Sequencer) applyBatch(st *state.State, batch *state.Batch, strict bool) error {
+	for i, tx := range batch.Transactions {
+		st.SetTxContext(tx.Hash(), i)
+		err := s.applyTransaction(s.txContext(&tx), st, tx)
+		if err != nil {
+			txHash := common.BytesToHash(tx.HashToBytes()).Hex()
+			if strict && !errors.Is(err, evm.ErrExecutionFailed) {
+				return fmt.Errorf("transaction %s is invalid: %w", txHash, err)
+			}
+			trace.Logger(s.txContext(&tx)).Error().Err(err).Str("tx_hash", txHash).Msg("Failed to process transaction")
+		}
+		st.RecordReceipt(err)
+	}
+
+	// Drop accounts left empty, e.g. senders whose transaction failed after the faucet.
+	// Empty accounts are not in the state tree so this does not change the root.
+	if s.config.PruneEmptyAccounts {
+		if pruned := st.PruneEmptyAccounts(); pruned > 0 {
+			log.Debug().Int("pruned", pruned).Msg("Pruned empty accounts")
+		}
+	}
+
+	return nil
+}
+
+// simulateBatch executes batch against a snapshot of the current state and returns the
+// resulting state root without modifying the live state
+func (s *Sequencer) simulateBatch(batch *state.Batch) ([32]byte, error) {
+	snapshot := s.state.Copy()
+	if err := s.applyBatch(snapshot, batch, true); err != nil {
+		return [32]byte{}, err
+	}
+	return snapshot.GetStateRoot(), nil
+}
+
+// ValidateProposedBatch is the consensus hook run before voting on a proposal. It refuses
+// batches containing an invalid transaction or declaring a state root we do not reach.
+func (s *Sequencer) ValidateProposedBatch(batch *state.Batch) error {
+	if s.halted.Load() {
+		return ErrHalted
+	}
+
+	// A proposal can arrive before this node applied the batch before it, consensus keeps it
+	// until then
+	next := s.state.GetBatchNumber()
+	if batch.BatchNumber > next {
+		return fmt.Errorf("%w: batch %d, next batch is %d", consensus.ErrBatchNotReady, batch.BatchNumber, next)
+	}
+	if batch.BatchNumber != next {
+		return fmt.Errorf("unexpected batch number %d, next batch is %d", batch.BatchNumber, next)
+	}
+
+	// The proposer must order the transactions by the network's policy, so it cannot pick
+	// the order that suits it
+	if batch.Ordering != s.config.BatchOrdering {
+		return fmt.Errorf("batch ordered by %q, the network orders by %q", batch.Ordering, s.config.BatchOrdering)
+	}
+	policy, err := NewOrderingPolicy(batch.Ordering, s.state.GetStateRoot())
+	if err != nil {
+		return err
+	}
+	if err := VerifyOrdering(policy, batch.Transactions); err != nil {
+		return err
+	}
+
+	// Keys registered earlier in the batch authorize the transactions after them
+	registered := make(map[types.Address][]byte)
+	rollup := s.SigningContract()
+	for _, tx := range batch.Transactions {
+		if tx.Type == state.TxTypeDeposit {
+			if err := s.checkDeposit(tx); err != nil {
+				return err
+			}
+			continue
+		}
+		// Admission checks the chain ID too, but a leader can propose transactions it never
+		// admitted
+		if err := s.checkChainID(&tx); err != nil {
+			return fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+		}
+		key, ok := registered[tx.From]
+		if !ok {
+			key = authKey(s.state, tx.From)
+		}
+		if err := tx.VerifyAuth(key, rollup); err != nil {
+			return fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+		}
+		if tx.Type == state.TxTypeRegisterKey {
+			registered[tx.From] = tx.Data
+		}
+	}
+
+	root, err := s.simulateBatch(batch)
+	if err != nil {
+		return err
+	}
+
+	if root != batch.StateRoot {
+		return fmt.Errorf("state root mismatch: declared %x, computed %x", batch.StateRoot, root)
+	}
+
+	return nil
+}
+
+// buildBatch assembles a batch from candidate transactions, dropping those that would fail,
+// and declares the state root the batch leads to
+func (s *Sequencer) buildBatch(txs []state.Transaction) (*state.Batch, error) {
+	// Order the candidates by the network's policy, dropping transactions keeps the order of
+	// the remaining ones
+	policy, err := NewOrderingPolicy(s.config.BatchOrdering, s.state.GetStateRoot())
+	if err != nil {
+		return nil, err
+	}
+	txs = OrderTransactions(policy, txs)
+
+	working := s.state.Copy()
+
+	included := make([]state.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		// Undo an invalid transaction so it leaves no partial changes. Code that ran and
+		// failed is included, it still pays for its gas. Code running past the wall-clock
+		// limit is dropped instead, replicas execute without it and could not agree on the
+		// charge.
+		working.Checkpoint()
+		ctx, cancel := s.evmExecutor.WithTimeout(s.txContext(&tx))
+		err := s.applyTransaction(ctx, working, tx)
+		cancel()
+		if err != nil && !errors.Is(err, evm.ErrExecutionFailed) {
+			working.RevertToCheckpoint()
+			trace.Logger(s.txContext(&tx)).Warn().Err(err).Str("tx_hash", common.BytesToHash(tx.HashToBytes()).Hex()).Msg("Dropping invalid transaction from batch")
+			continue
+		}
+		included = append(included, tx)
+	}
+
+	batch := &state.Batch{
+		Transactions: included,
+		BatchNumber:  s.state.GetBatchNumber(),
+		Timestamp:    s.batchTimestamp(s.state.GetBatchNumber()),
+		Ordering:     policy.Name(),
+	}
+	if len(included) == 0 {
+		// Heartbeat batches leave the state unchanged
+		batch.StateRoot = s.state.GetStateRoot()
+		return batch, nil
+	}
+
+	// The working copy executed the included transactions as replicas will. Receipts and
+	// pruning empty accounts do not change the root.
+	batch.StateRoot = working.GetStateRoot()
+
+	return batch, nil
+}
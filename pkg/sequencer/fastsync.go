@@ -0,0 +1,146 @@
+package sequencer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/l1"
+	"zkrollup/pkg/state"
+)
+
+// fastSyncPendingSnapshots caps the snapshots held while waiting for their batch to become
+// final on L1, the oldest is dropped first
+const fastSyncPendingSnapshots = 4
+
+// errNoFinalizedSnapshot is returned to fast syncing peers before any snapshot this node
+// took is final on L1
+var errNoFinalizedSnapshot = errors.New("no snapshot at a batch final on L1")
+
+// finalizedSnapshots holds the snapshots this node serves to fast syncing peers: the newest
+// one whose last batch is final on L1, and the later ones still waiting for it
+type finalizedSnapshots struct {
+	mu      sync.Mutex
+	final   *state.Snapshot
+	pending []*state.Snapshot // Oldest first
+}
+
+// add holds a snapshot until its last batch is final
+func (f *finalizedSnapshots) add(snap *state.Snapshot) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pending = append(f.pending, snap)
+	if len(f.pending) > fastSyncPendingSnapshots {
+		f.pending = f.pending[len(f.pending)-fastSyncPendingSnapshots:]
+	}
+}
+
+// promote serves the newest pending snapshot whose last batch is final, dropping it and
+// every older one from the pending list
+func (f *finalizedSnapshots) promote(final func(batchNumber uint64) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := len(f.pending) - 1; i >= 0; i-- {
+		if snap := f.pending[i]; final(snap.BatchNumber - 1) {
+			f.final = snap
+			f.pending = f.pending[i+1:]
+			return
+		}
+	}
+}
+
+// latest returns the snapshot served to fast syncing peers
+func (f *finalizedSnapshots) latest() (*state.Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.final == nil {
+		return nil, errNoFinalizedSnapshot
+	}
+	return f.final, nil
+}
+
+// takeFastSyncSnapshot snapshots the state every FastSyncSnapshotInterval batches, to be
+// served once L1 finalized the batch it was taken at. Only nodes following L1 batches know
+// when that is.
+func (s *Sequencer) takeFastSyncSnapshot() {
+	interval := s.config.FastSyncSnapshotInterval
+	if s.batchWatcher == nil || interval == 0 {
+		return
+	}
+	n := s.state.GetBatchNumber()
+	if n == 0 || n%interval != 0 {
+		return
+	}
+
+	s.fastSnapshots.add(s.state.Snapshot())
+	s.fastSnapshots.promote(s.l1Finality.finalized)
+}
+
+// handleFinalizedSnapshotRequest serves the newest snapshot at a batch final on L1 to a
+// fast syncing peer
+func (s *Sequencer) handleFinalizedSnapshotRequest() (*state.Snapshot, error) {
+	return s.fastSnapshots.latest()
+}
+
+// fastSyncFromPeers downloads the newest snapshot at an L1 finalized batch from the first
+// peer ahead of us, checks its state root against the one L1 finalized for that batch and
+// hands it to the consensus loop. Batches after the snapshot are then replayed as usual,
+// the ones before it are never downloaded. It reports whether a snapshot was restored.
+func (s *Sequencer) fastSyncFromPeers(from uint64) bool {
+	for _, peerID := range s.node.GetPeers() {
+		snap, err := s.node.FastSyncState(s.ctx, peerID)
+		if err != nil {
+			log.Warn().Err(err).Str("peer", peerID.String()).Msg("Fast sync from peer failed")
+			continue
+		}
+		if snap.BatchNumber <= from {
+			continue
+		}
+		if err := s.verifyFinalizedSnapshot(s.ctx, snap); err != nil {
+			log.Warn().Err(err).Str("peer", peerID.String()).Uint64("batch_number", snap.BatchNumber).Msg("Refused fast sync snapshot from peer")
+			continue
+		}
+
+		restore := &snapshotSync{snapshot: snap, withoutHistory: true, done: make(chan error, 1)}
+		select {
+		case s.syncedSnapshotCh <- restore:
+		case <-s.ctx.Done():
+			return false
+		}
+
+		if err := <-restore.done; err != nil {
+			log.Warn().Err(err).Str("peer", peerID.String()).Msg("Failed to restore fast sync snapshot from peer")
+			continue
+		}
+
+		log.Info().Str("peer", peerID.String()).Uint64("batch_number", snap.BatchNumber).Msg("Fast synced to L1 finalized snapshot from peer")
+		return true
+	}
+	return false
+}
+
+// verifyFinalizedSnapshot checks that the last batch covered by snap is final on L1 with
+// the snapshot's state root. Restoring the snapshot recomputes the root from its contents.
+func (s *Sequencer) verifyFinalizedSnapshot(ctx context.Context, snap *state.Snapshot) error {
+	if snap.BatchNumber == 0 {
+		return fmt.Errorf("snapshot holds no batches")
+	}
+	status, err := s.l1Client.GetBatchStatus(ctx, snap.BatchNumber-1)
+	if err != nil {
+		return err
+	}
+	if status.State != l1.BatchFinalized {
+		return fmt.Errorf("batch %d is %s on L1, not finalized", status.BatchNumber, status.State)
+	}
+	if status.StateRoot != common.Hash(snap.StateRoot) {
+		return fmt.Errorf("snapshot state root %x differs from %s finalized on L1", snap.StateRoot, status.StateRoot.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package sequencer
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/params"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// txGasLimit returns the most gas a transaction can be charged for. Transfers run no code
+// and always use the intrinsic gas of a transaction.
+func txGasLimit(tx *state.Transaction) uint64 {
+	if tx.Type == state.TxTypeTransfer {
+		return params.TxGas
+	}
+	return tx.Gas
+}
+
+// maxCost returns the amount sent plus the fee of a transaction using all of its gas, what
+// the sender must hold for it to run
+func maxCost(tx *state.Transaction) *big.Int {
+	cost := new(big.Int).SetUint64(txGasLimit(tx))
+	cost.Mul(cost, gasPrice(tx))
+	if tx.Amount != nil {
+		cost.Add(cost, tx.Amount)
+	}
+	return cost
+}
+
+// checkCost verifies the sender can pay for the amount and the gas of a transaction
+func checkCost(sender *state.Account, tx *state.Transaction) error {
+	if need := maxCost(tx); sender.Balance.Cmp(need) < 0 {
+		return fmt.Errorf("%w: have %s, need %s", ErrInsufficientBalance, sender.Balance.String(), need.String())
+	}
+	return nil
+}
+
+// feeRecipient returns the account credited with gas fees, false when fees are burned
+func (s *Sequencer) feeRecipient() (types.Address, bool) {
+	if s.config == nil || s.config.FeeRecipient == "" {
+		return types.Address{}, false
+	}
+	addr, err := types.ParseAddress(s.config.FeeRecipient)
+	if err != nil {
+		return types.Address{}, false
+	}
+	return addr, true
+}
+
+// chargeGas deducts gasUsed at the transaction's gas price from the sender and credits it to
+// the fee recipient, and records it for the transaction's receipt. The sender is read back
+// from st, so it must be stored before.
+func (s *Sequencer) chargeGas(st *state.State, tx *state.Transaction, gasUsed uint64) {
+	st.UseGas(gasUsed)
+
+	fee := new(big.Int).SetUint64(gasUsed)
+	fee.Mul(fee, gasPrice(tx))
+	if fee.Sign() == 0 {
+		return
+	}
+
+	sender, err := st.GetAccount(tx.From)
+	if err != nil {
+		return
+	}
+	if sender.Balance.Cmp(fee) < 0 {
+		fee = new(big.Int).Set(sender.Balance)
+	}
+	sender.Balance = new(big.Int).Sub(sender.Balance, fee)
+	st.SetAccount(sender)
+
+	recipient, ok := s.feeRecipient()
+	if !ok {
+		return
+	}
+	acc, err := st.GetAccount(recipient)
+	if err != nil || acc == nil {
+		acc = &state.Account{Address: recipient, Balance: new(big.Int)}
+	}
+	acc.Balance = new(big.Int).Add(acc.Balance, fee)
+	st.SetAccount(acc)
+}
@@ -0,0 +1,82 @@
+package sequencer
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const guardianPollInterval = 15 * time.Second
+
+// watchGuardian tracks whether the contract guardian paused batch acceptance or froze the
+// bridge
+func (s *Sequencer) watchGuardian() {
+	ticker := time.NewTicker(guardianPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.refreshGuardianStatus()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshGuardianStatus reads the guardian state from L1 and logs any change
+func (s *Sequencer) refreshGuardianStatus() {
+	status, err := s.l1Client.GetGuardianStatus(s.ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read L1 guardian status")
+		return
+	}
+
+	if was := s.l1Paused.Swap(status.Paused); was != status.Paused {
+		if status.Paused {
+			log.Warn().Str("guardian", status.Guardian.Hex()).Msg("L1 contract paused, holding batch submissions")
+		} else {
+			log.Info().Msg("L1 contract unpaused, resuming batch submissions")
+		}
+	}
+	if was := s.l1BridgeFrozen.Swap(status.BridgeFrozen); was != status.BridgeFrozen {
+		if status.BridgeFrozen {
+			log.Warn().Str("guardian", status.Guardian.Hex()).Msg("L1 bridge frozen")
+		} else {
+			log.Info().Msg("L1 bridge unfrozen")
+		}
+	}
+}
+
+// waitWhileL1Paused blocks while the contract is paused or an operator paused submission,
+// submissions queued meanwhile stay in the backlog. Returns false if the sequencer stopped
+// while waiting.
+func (s *Sequencer) waitWhileL1Paused() bool {
+	held := func() bool { return s.l1Paused.Load() || s.l1Held.Load() }
+	if !held() {
+		return true
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for held() {
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// L1Paused reports whether the contract guardian paused batch acceptance
+func (s *Sequencer) L1Paused() bool {
+	return s.l1Paused.Load()
+}
+
+// L1BridgeFrozen reports whether the contract guardian froze deposits and withdrawals
+func (s *Sequencer) L1BridgeFrozen() bool {
+	return s.l1BridgeFrozen.Load()
+}
@@ -0,0 +1,55 @@
+package sequencer
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
+)
+
+// handleLeaderChange follows a leader rotation announced by a peer or taken from the
+// proposer schedule. The rotation abandons the round of a batch this node proposed that is
+// still undecided, so its transactions go back to the pool and are gossiped again for the
+// new leader to propose.
+func (s *Sequencer) handleLeaderChange(leader bool) {
+	s.batchMu.Lock()
+	s.isLeader = leader
+	batch := s.currentBatch
+	inFlight := batch != nil && batch.BatchNumber >= s.state.GetBatchNumber()
+	if inFlight {
+		s.mempool.Requeue(batch.Transactions)
+		s.currentBatch = nil
+		s.batchInProgress = false
+		s.batchProposedAt = time.Time{}
+	}
+	s.batchMu.Unlock()
+
+	if inFlight {
+		log.Warn().Uint64("batch_number", batch.BatchNumber).Int("tx_count", len(batch.Transactions)).Strs(trace.BatchField, batch.TraceIDs()).Msg("Leadership rotated before the proposed batch was decided, returned its transactions to the pool")
+		s.regossip(batch)
+	}
+
+	if leader {
+		log.Info().Msg("This node is now the leader and will propose the next batch")
+		s.triggerBatch()
+	}
+}
+
+// regossip announces the transactions of an abandoned batch again so the new leader pulls
+// any the original announcement did not bring it
+func (s *Sequencer) regossip(batch *state.Batch) {
+	s.announceTransactions(batch.Transactions)
+}
+
+// followProposerSchedule takes over or hands off batch production for batches this node
+// applied without deciding them, so it proposes exactly when the schedule gives it the
+// next batch
+func (s *Sequencer) followProposerSchedule() {
+	leader, scheduled := s.consensus.AlignLeader(s.state.GetBatchNumber())
+	if !scheduled || leader == s.isLeader {
+		return
+	}
+	s.handleLeaderChange(leader)
+}
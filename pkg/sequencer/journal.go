@@ -0,0 +1,202 @@
+package sequencer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// TxJournal keeps the transactions admitted to the pool in an append-only file, one JSON
+// transaction per line, so they survive a restart. Transactions that were replaced or
+// included stay in the file until it is rewritten with the pool's contents.
+type TxJournal struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+// OpenTxJournal opens the journal at path for appending, an empty path disables it
+func OpenTxJournal(path string) (*TxJournal, error) {
+	j := &TxJournal{path: path}
+	if path == "" {
+		return j, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mempool journal: %v", err)
+	}
+	j.file = file
+	return j, nil
+}
+
+// Load returns the journaled transactions in the order they were admitted. A line that
+// does not decode, such as one cut short by a crash, is skipped.
+func (j *TxJournal) Load() ([]state.Transaction, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mempool journal: %v", err)
+	}
+	defer file.Close()
+
+	var txs []state.Transaction
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read mempool journal: %v", err)
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var tx state.Transaction
+			if jsonErr := json.Unmarshal(data, &tx); jsonErr != nil {
+				log.Warn().Err(jsonErr).Int("line", line).Msg("Skipping corrupt mempool journal entry")
+			} else {
+				txs = append(txs, tx)
+			}
+		}
+		if err != nil {
+			return txs, nil
+		}
+	}
+}
+
+// Insert appends a transaction to the journal
+func (j *TxJournal) Insert(tx state.Transaction) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to encode journaled transaction: %v", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write mempool journal: %v", err)
+	}
+	return nil
+}
+
+// Rotate atomically replaces the journal with the given transactions, dropping everything
+// no longer pending
+func (j *TxJournal) Rotate(txs []state.Transaction) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, tx := range txs {
+		data, err := json.Marshal(tx)
+		if err != nil {
+			return fmt.Errorf("failed to encode journaled transaction: %v", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write mempool journal: %v", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to write mempool journal: %v", err)
+	}
+
+	// Keep appending to the new file
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open mempool journal: %v", err)
+	}
+	j.file.Close()
+	j.file = file
+	return nil
+}
+
+// Close closes the journal file
+func (j *TxJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// restoreMempool re-admits the journaled transactions once the node is synced, so they
+// are validated against current state like new submissions, then keeps the journal short
+// by rewriting it with the pool's contents every MempoolRejournalSeconds
+func (s *Sequencer) restoreMempool() {
+	txs, err := s.journal.Load()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load mempool journal")
+		return
+	}
+
+	restored := 0
+	for _, tx := range txs {
+		if err := s.addTransaction(tx); err != nil {
+			log.Debug().Err(err).Str("from", tx.From.Hex()).Uint64("nonce", tx.Nonce).Msg("Dropping journaled transaction")
+			continue
+		}
+		restored++
+	}
+	if len(txs) > 0 {
+		log.Info().Int("journaled", len(txs)).Int("restored", restored).Msg("Restored mempool from journal")
+	}
+	s.rejournal()
+
+	if restored > 0 && (s.config.DevMode || s.batchFull()) {
+		s.triggerBatch()
+	}
+
+	interval := time.Duration(s.config.MempoolRejournalSeconds) * time.Second
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.rejournal()
+		}
+	}
+}
+
+// rejournal rewrites the journal with the transactions pending in the pool
+func (s *Sequencer) rejournal() {
+	s.poolMu.Lock()
+	defer s.poolMu.Unlock()
+
+	if err := s.journal.Rotate(s.mempool.All()); err != nil {
+		log.Error().Err(err).Msg("Failed to rewrite mempool journal")
+	}
+}
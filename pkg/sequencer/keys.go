@@ -0,0 +1,254 @@
+package sequencer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/consensys/gnark/constraint"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/consensus"
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/crypto"
+	"zkrollup/pkg/p2p"
+)
+
+// peerKeyCheckInterval is how often the batch circuit keys of peers are compared with ours
+const peerKeyCheckInterval = time.Minute
+
+// KeyStatus describes the batch circuit keys this node proves with
+type KeyStatus struct {
+	Epoch       uint64
+	Fingerprint string
+	Capacity    int
+
+	// Keys of a later CRS epoch waiting for their first batch, nil when none are scheduled
+	Scheduled *ScheduledKeys
+
+	// Peers proving with other keys, by peer ID
+	Mismatches map[string]p2p.KeyInfo
+}
+
+// ScheduledKeys are keys of a later CRS epoch and the batch they take over at
+type ScheduledKeys struct {
+	Epoch       uint64
+	Fingerprint string
+	ActivateAt  uint64
+}
+
+// openBatchProver returns the batch circuit prover for config. Keys of a CRS epoch are
+// loaded from the key files, or from the ceremony directory when those do not exist, and
+// are never set up locally since no other node could verify their proofs. Without an epoch
+// the key files are set up on first start and reused afterwards.
+func openBatchProver(config *core.Config) (*crypto.Prover, error) {
+	capacity := int(config.BatchSize)
+	if config.ProvingKeyEpoch == 0 {
+		prover, created, err := crypto.OpenBatchProver(capacity, config.ProvingKeyFile, config.VerifyingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if created && config.ProvingKeyFile != "" {
+			log.Warn().Str("proving_key", config.ProvingKeyFile).Str("verifying_key", config.VerifyingKeyFile).Msg("Set up new batch circuit keys, copy them to the other nodes or their proofs will not match")
+		}
+		return prover, nil
+	}
+
+	pkPath, vkPath := config.ProvingKeyFile, config.VerifyingKeyFile
+	if _, err := os.Stat(pkPath); pkPath == "" || os.IsNotExist(err) {
+		pkPath, vkPath = crypto.EpochKeyPaths(crsCeremonyDir(config), config.ProvingKeyEpoch, capacity)
+	}
+	cs, err := crypto.CompileBatchCircuit(capacity)
+	if err != nil {
+		return nil, err
+	}
+	prover, err := crypto.LoadProver(cs, pkPath, vkPath)
+	if err != nil {
+		return nil, fmt.Errorf("keys of CRS epoch %d: %v", config.ProvingKeyEpoch, err)
+	}
+	log.Info().Uint64("epoch", config.ProvingKeyEpoch).Str("proving_key", pkPath).Msg("Loaded batch circuit keys")
+	return prover, nil
+}
+
+// provingOptions returns the parallelism and device config proves batches with
+func provingOptions(config *core.Config) crypto.ProvingOptions {
+	return crypto.ProvingOptions{Threads: config.ProverThreads, GPU: config.ProverGPU}
+}
+
+// crsCeremonyDir returns the directory holding the CRS ceremony output
+func crsCeremonyDir(config *core.Config) string {
+	if config.CRSCeremonyDir != "" {
+		return config.CRSCeremonyDir
+	}
+	return consensus.DefaultCRSCeremonyDir()
+}
+
+// RotateKeys schedules the batch circuit keys of CRS epoch to prove batches from activateAt
+// on. The keys are read from the ceremony directory and every validator must schedule the
+// same epoch at the same batch.
+func (s *Sequencer) RotateKeys(epoch, activateAt uint64) (*ScheduledKeys, error) {
+	if s.proofs == nil {
+		return nil, fmt.Errorf("proof generation is disabled")
+	}
+
+	pkPath, vkPath := crypto.EpochKeyPaths(crsCeremonyDir(s.config), epoch, s.proofs.capacity)
+	prover, err := crypto.LoadProver(s.proofs.keys.Current().Prover.R1cs, pkPath, vkPath)
+	if err != nil {
+		return nil, fmt.Errorf("keys of CRS epoch %d: %v", epoch, err)
+	}
+	return s.scheduleKeys(prover, epoch, activateAt)
+}
+
+// scheduleKeys has prover take over batch proofs from activateAt on
+func (s *Sequencer) scheduleKeys(prover *crypto.Prover, epoch, activateAt uint64) (*ScheduledKeys, error) {
+	// The aggregation circuit embeds the batch verifying key
+	if s.aggregator != nil {
+		return nil, fmt.Errorf("keys cannot be rotated while proofs are aggregated")
+	}
+	if current := s.state.GetBatchNumber(); activateAt <= current {
+		return nil, fmt.Errorf("activation batch %d is not after the current batch %d", activateAt, current)
+	}
+
+	prover.Options = provingOptions(s.config)
+	keys, err := s.proofs.keys.Schedule(prover, epoch, activateAt)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Uint64("epoch", epoch).Uint64("activate_at", activateAt).Hex("fingerprint", keys.Fingerprint[:]).Msg("Scheduled batch circuit key rotation")
+	return &ScheduledKeys{Epoch: keys.Epoch, Fingerprint: hex.EncodeToString(keys.Fingerprint[:]), ActivateAt: keys.ActivateAt}, nil
+}
+
+// batchCircuit returns the compiled batch circuit
+func (s *Sequencer) batchCircuit() (constraint.ConstraintSystem, error) {
+	if s.proofs != nil {
+		return s.proofs.keys.Current().Prover.R1cs, nil
+	}
+	return crypto.CompileBatchCircuit(int(s.config.BatchSize))
+}
+
+// ContributePhase2 implements consensus.CRSKeySetup for the batch circuit
+func (s *Sequencer) ContributePhase2(epoch int64, finalPTau string) ([]byte, error) {
+	cs, err := s.batchCircuit()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ContributePhase2(cs, finalPTau)
+}
+
+// ApplyPhase2 implements consensus.CRSKeySetup for the batch circuit. The keys are written
+// to the ceremony directory, where proving_key_epoch finds them after a restart, and are
+// only scheduled on nodes generating proofs.
+func (s *Sequencer) ApplyPhase2(epoch int64, finalPTau string, phase2 []byte, activateAt uint64) (string, error) {
+	cs, err := s.batchCircuit()
+	if err != nil {
+		return "", err
+	}
+	prover, err := crypto.ProverFromPhase2(cs, finalPTau, phase2)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := prover.Fingerprint()
+	if err != nil {
+		return "", err
+	}
+
+	pkPath, vkPath := crypto.EpochKeyPaths(crsCeremonyDir(s.config), uint64(epoch), int(s.config.BatchSize))
+	if err := prover.WriteKeys(pkPath, vkPath); err != nil {
+		return "", err
+	}
+	log.Info().Int64("epoch", epoch).Str("proving_key", pkPath).Hex("fingerprint", fingerprint[:]).Msg("Derived batch circuit keys from CRS ceremony")
+
+	if s.proofs != nil {
+		if _, err := s.scheduleKeys(prover, uint64(epoch), activateAt); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(fingerprint[:]), nil
+}
+
+// KeyStatus returns the batch circuit keys in use, nil when proof generation is disabled
+func (s *Sequencer) KeyStatus() *KeyStatus {
+	if s.proofs == nil {
+		return nil
+	}
+
+	current := s.proofs.keys.Current()
+	status := &KeyStatus{
+		Epoch:       current.Epoch,
+		Fingerprint: hex.EncodeToString(current.Fingerprint[:]),
+		Capacity:    s.proofs.capacity,
+		Mismatches:  make(map[string]p2p.KeyInfo),
+	}
+	if next := s.proofs.keys.Scheduled(); next != nil {
+		status.Scheduled = &ScheduledKeys{Epoch: next.Epoch, Fingerprint: hex.EncodeToString(next.Fingerprint[:]), ActivateAt: next.ActivateAt}
+	}
+
+	s.keyMismatchesMu.Lock()
+	for id, info := range s.keyMismatches {
+		status.Mismatches[id] = info
+	}
+	s.keyMismatchesMu.Unlock()
+	return status
+}
+
+// handleKeyInfoRequest tells a peer which batch circuit keys this node proves with
+func (s *Sequencer) handleKeyInfoRequest() (*p2p.KeyInfo, error) {
+	if s.proofs == nil {
+		return nil, fmt.Errorf("proof generation is disabled")
+	}
+	current := s.proofs.keys.Current()
+	return &p2p.KeyInfo{
+		Epoch:       current.Epoch,
+		Capacity:    s.proofs.capacity,
+		Fingerprint: hex.EncodeToString(current.Fingerprint[:]),
+	}, nil
+}
+
+// checkPeerKeys periodically compares the batch circuit keys of peers with ours, since a
+// node proving with other keys produces proofs the L1 verifier refuses
+func (s *Sequencer) checkPeerKeys() {
+	ticker := time.NewTicker(peerKeyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.comparePeerKeys()
+		}
+	}
+}
+
+// comparePeerKeys asks every peer for its keys and records those that differ from ours
+func (s *Sequencer) comparePeerKeys() {
+	ours, err := s.handleKeyInfoRequest()
+	if err != nil {
+		return
+	}
+
+	mismatches := make(map[string]p2p.KeyInfo)
+	for _, peerID := range s.node.GetPeers() {
+		info, err := s.node.RequestKeyInfo(s.ctx, peerID)
+		if err != nil {
+			// Peers without proof generation do not answer
+			log.Debug().Err(err).Str("peer", peerID.String()).Msg("Failed to get peer key info")
+			continue
+		}
+		if info.Capacity != ours.Capacity || info.Fingerprint == ours.Fingerprint {
+			continue
+		}
+		mismatches[peerID.String()] = *info
+		if info.Epoch == ours.Epoch {
+			log.Error().Str("peer", peerID.String()).Uint64("epoch", info.Epoch).Str("fingerprint", info.Fingerprint).Str("ours", ours.Fingerprint).Msg("Peer proves with different batch circuit keys of the same epoch")
+		} else {
+			log.Warn().Str("peer", peerID.String()).Uint64("epoch", info.Epoch).Uint64("our_epoch", ours.Epoch).Msg("Peer proves with batch circuit keys of another epoch")
+		}
+	}
+
+	s.keyMismatchesMu.Lock()
+	s.keyMismatches = mismatches
+	s.keyMismatchesMu.Unlock()
+}
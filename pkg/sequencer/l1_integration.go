@@ -3,11 +3,54 @@ package sequencer
 import (
 	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 
+	"zkrollup/pkg/l1"
+	"zkrollup/pkg/metrics"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
 )
 
+// gasWarnRatio is the share of the L1 gas limit above which a submission is flagged
+const gasWarnRatio = 0.8
+
+// stuckTxCheckInterval is how often pending L1 transactions are checked for replacement
+const stuckTxCheckInterval = 30 * time.Second
+
+// l1SubmissionKind selects the contract call used for a queued submission
+type l1SubmissionKind int
+
+const (
+	submitWithProof  l1SubmissionKind = iota // Batch and its proof in one call
+	submitDataOnly                           // Batch whose proof missed its deadline
+	submitProofOnly                          // Late proof of a batch posted data-only
+	submitAggregated                         // Consecutive batches under one aggregated proof
+)
+
+// String returns the name shown on the dashboard
+func (k l1SubmissionKind) String() string {
+	switch k {
+	case submitDataOnly:
+		return "data-only"
+	case submitProofOnly:
+		return "proof-only"
+	case submitAggregated:
+		return "aggregated"
+	default:
+		return "with-proof"
+	}
+}
+
+// l1Submission is a batch queued for the L1 submitter
+type l1Submission struct {
+	batch state.Batch
+	kind  l1SubmissionKind
+
+	// Proven by the batch circuit, so its proof can be aggregated with others
+	aggregatable bool
+}
+
 // submitBatchesToL1 processes batches from the l1SubmitChan and submits them to L1
 func (s *Sequencer) submitBatchesToL1() {
 	// Set up ticker for periodic batch submission
@@ -23,18 +66,77 @@ func (s *Sequencer) submitBatchesToL1() {
 			log.Info().Msg("Stopping L1 batch submission process")
 			return
 
-		case batch := <-s.l1SubmitChan:
-			// Process the batch and submit to L1
-			if err := s.submitBatchToL1(batch); err != nil {
-				log.Error().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to submit batch to L1")
-			} else {
-				log.Info().Uint64("batch_number", batch.BatchNumber).Msg("Successfully submitted batch to L1")
+		case sub := <-s.l1SubmitChan:
+			// The contract rejects batches while paused, hold them until it resumes
+			if !s.waitWhileL1Paused() {
+				return
+			}
+
+			// Proven batches wait for enough others to share an aggregated proof. Any other
+			// submission first sends the waiting batches on their own, L1 takes batches in order.
+			if s.aggregator != nil {
+				if sub.kind == submitWithProof && sub.aggregatable {
+					if s.aggregator.add(sub.batch) {
+						s.submitAggregated(s.aggregator.take())
+					}
+					continue
+				}
+				s.flushAggregation()
 			}
+			s.submitL1(sub)
 
 		case <-ticker.C:
 			log.Debug().Msg("Checking for pending batches to submit to L1")
+			if s.aggregator != nil && s.aggregator.waiting() > submitPeriod {
+				if !s.waitWhileL1Paused() {
+					return
+				}
+				s.flushAggregation()
+			}
+		}
+	}
+}
+
+// submitL1 sends a single queued submission to L1
+func (s *Sequencer) submitL1(sub l1Submission) {
+	batch := sub.batch
+
+	var err error
+	if sub.kind != submitProofOnly {
+		err = s.publishBatchData(&batch)
+	}
+	if err == nil {
+		switch sub.kind {
+		case submitDataOnly:
+			var tx *types.Transaction
+			if tx, err = s.l1Client.SubmitBatchData(s.ctx, &batch); err == nil {
+				if err := s.state.SetBatchL1TxHash(batch.BatchNumber, tx.Hash()); err != nil {
+					log.Warn().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to record L1 submission")
+				}
+			}
+		case submitProofOnly:
+			_, err = s.l1Client.ProveBatch(s.ctx, &batch)
+		default:
+			err = s.submitBatchToL1(batch)
 		}
 	}
+	s.recordL1Outcome(batch.BatchNumber, sub.kind, err)
+	if err != nil {
+		log.Error().Err(err).Uint64("batch_number", batch.BatchNumber).Strs(trace.BatchField, batch.TraceIDs()).Msg("Failed to submit batch to L1")
+	} else {
+		log.Info().Uint64("batch_number", batch.BatchNumber).Strs(trace.BatchField, batch.TraceIDs()).Msg("Successfully submitted batch to L1")
+	}
+}
+
+// publishBatchData publishes the transactions of a batch to L1 ahead of the batch itself
+// when data availability is enabled, so the batch is never accepted without its data
+func (s *Sequencer) publishBatchData(batch *state.Batch) error {
+	mode := l1.DataAvailability(s.config.L1DataAvailability)
+	if s.l1Client == nil || mode == "" || mode == l1.DataAvailabilityOff {
+		return nil
+	}
+	_, err := s.l1Client.PublishBatchData(s.ctx, batch, mode)
+	return err
 }
 
 // submitBatchToL1 submits a single batch to L1
@@ -43,24 +145,105 @@ func (s *Sequencer) submitBatchToL1(batch state.Batch) error {
 		return nil
 	}
 
-	log.Info().Uint64("batch_number", batch.BatchNumber).Msg("Submitting batch to L1")
+	log.Info().Uint64("batch_number", batch.BatchNumber).Strs(trace.BatchField, batch.TraceIDs()).Msg("Submitting batch to L1")
+
+	// The proof was generated by the proving workers before the batch was queued. Without
+	// one, only a contract deployed without a verifier accepts the batch.
+	proof, publicInputs := batch.Proof, batch.PublicInputs
+	if !s.config.ProofGeneration {
+		log.Warn().Uint64("batch_number", batch.BatchNumber).Msg("Submitting batch without proof (proof generation disabled)")
+	} else if len(proof) == 0 {
+		log.Warn().Uint64("batch_number", batch.BatchNumber).Msg("Submitting batch without proof, proof generation failed")
+	}
+
+	record := metrics.ProofRecord{
+		BatchNumber: batch.BatchNumber,
+		TxCount:     len(batch.Transactions),
+		ProofSize:   len(proof),
+	}
 
-	// Generate a ZK proof for the batch
-	var proof []byte
-	if s.config.ProofGeneration {
-		proof = batch.Proof
-		log.Info().Uint64("batch_number", batch.BatchNumber).Msg("Generated proof for batch")
+	// Estimate the submission cost first so a circuit change that makes verification
+	// too expensive shows up before the transaction runs out of gas
+	estimated, err := s.l1Client.EstimateSubmitGas(s.ctx, &batch, proof, publicInputs)
+	if err != nil {
+		log.Warn().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to estimate L1 submission gas")
 	} else {
-		// If proof generation is disabled, use a dummy proof
-		proof = []byte("dummy_proof")
-		log.Warn().Uint64("batch_number", batch.BatchNumber).Msg("Using dummy proof for batch (proof generation disabled)")
+		record.EstimatedGas = estimated
+		if float64(estimated) > float64(s.config.L1GasLimit)*gasWarnRatio {
+			log.Warn().Uint64("batch_number", batch.BatchNumber).Uint64("estimated_gas", estimated).Uint64("gas_limit", s.config.L1GasLimit).Msg("L1 submission gas close to the gas limit")
+		}
 	}
 
 	// Submit the batch to L1
-	err := s.l1Client.SubmitBatch(s.ctx, &batch, proof)
+	tx, err := s.l1Client.SubmitBatch(s.ctx, &batch, proof, publicInputs)
 	if err != nil {
 		return err
 	}
+	record.L1TxHash = tx.Hash().Hex()
+	if err := s.state.SetBatchL1TxHash(batch.BatchNumber, tx.Hash()); err != nil {
+		log.Warn().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to record L1 submission")
+	}
+
+	log.Info().Uint64("batch_number", batch.BatchNumber).Int("proof_size", record.ProofSize).Uint64("estimated_gas", record.EstimatedGas).Msg("Batch submission stats")
+	s.recordProofStats(record)
+
+	// Actual gas is only known once the submission is mined
+	go s.recordSubmissionGas(tx, record)
 
 	return nil
 }
+
+// recordSubmissionGas waits for a submission to be mined and records the gas it used
+func (s *Sequencer) recordSubmissionGas(tx *types.Transaction, record metrics.ProofRecord) {
+	receipt, err := s.l1Client.WaitForReceipt(s.ctx, tx)
+	if err != nil {
+		log.Warn().Err(err).Uint64("batch_number", record.BatchNumber).Msg("Failed to get L1 submission receipt")
+		return
+	}
+
+	record.GasUsed = receipt.GasUsed
+	log.Info().Uint64("batch_number", record.BatchNumber).Uint64("gas_used", receipt.GasUsed).Uint64("status", receipt.Status).Msg("L1 submission mined")
+	s.recordProofStats(record)
+
+	// A reverted submission means the contract rejected the batch or its proof
+	if receipt.Status == types.ReceiptStatusFailed {
+		log.Error().Uint64("batch_number", record.BatchNumber).Str("tx_hash", record.L1TxHash).Msg("L1 rejected batch submission")
+		return
+	}
+
+	status, err := s.l1Client.GetBatchStatus(s.ctx, record.BatchNumber)
+	if err != nil {
+		log.Warn().Err(err).Uint64("batch_number", record.BatchNumber).Msg("Failed to get L1 batch status")
+		return
+	}
+	log.Info().Uint64("batch_number", record.BatchNumber).Str("status", status.State.String()).Msg("L1 batch status")
+}
+
+// recordProofStats persists a proof record if stats are enabled
+func (s *Sequencer) recordProofStats(record metrics.ProofRecord) {
+	if s.proofStats == nil {
+		return
+	}
+	if err := s.proofStats.Record(record); err != nil {
+		log.Warn().Err(err).Msg("Failed to record proof stats")
+	}
+}
+
+// replaceStuckL1Txs resends L1 transactions pending past the stuck timeout with higher
+// fees, so submissions waiting on them do not stall
+func (s *Sequencer) replaceStuckL1Txs() {
+	ticker := time.NewTicker(stuckTxCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.l1Client.ReplaceStuck(s.ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to replace stuck L1 transactions")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package sequencer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/l1"
+)
+
+const batchEventPollInterval = 5 * time.Second
+
+// l1Finality tracks the batches final on L1. The contract may verify batches out of
+// order, those above the first unfinalized batch are held until the gap closes.
+type l1Finality struct {
+	mu        sync.Mutex
+	next      uint64 // Batches below are final
+	ahead     map[uint64]struct{}
+	submitted uint64 // Highest batch submitted plus one, 0 before the first
+}
+
+// markSubmitted records that the contract accepted batch n
+func (f *l1Finality) markSubmitted(n uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if n+1 > f.submitted {
+		f.submitted = n + 1
+	}
+}
+
+// markFinalized records that batch n is final on L1
+func (f *l1Finality) markFinalized(n uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if n < f.next {
+		return
+	}
+	if f.ahead == nil {
+		f.ahead = make(map[uint64]struct{})
+	}
+	f.ahead[n] = struct{}{}
+	for {
+		if _, ok := f.ahead[f.next]; !ok {
+			break
+		}
+		delete(f.ahead, f.next)
+		f.next++
+	}
+}
+
+// finalized reports whether batch n is final on L1
+func (f *l1Finality) finalized(n uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if n < f.next {
+		return true
+	}
+	_, ok := f.ahead[n]
+	return ok
+}
+
+// counts returns how many batches from the first are final and the highest submitted plus one
+func (f *l1Finality) counts() (finalized, submitted uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.next, f.submitted
+}
+
+// followL1Batches records the L1 progress of batches reported by the batch watcher
+func (s *Sequencer) followL1Batches() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case event := <-s.batchWatcher.Events():
+			s.handleL1BatchEvent(event)
+		}
+	}
+}
+
+// handleL1BatchEvent records a batch event and warns when L1 holds a state root this node
+// did not finalize
+func (s *Sequencer) handleL1BatchEvent(event l1.BatchEvent) {
+	switch event.Kind {
+	case l1.EventBatchSubmitted:
+		s.l1Finality.markSubmitted(event.BatchNumber)
+		if batches := s.state.GetBatches(event.BatchNumber, 1); len(batches) == 1 && batches[0].StateRoot != [32]byte(event.StateRoot) {
+			log.Error().Uint64("batch_number", event.BatchNumber).Str("l1_state_root", event.StateRoot.Hex()).Hex("local_state_root", batches[0].StateRoot[:]).Msg("L1 accepted a state root this node did not finalize")
+		}
+	case l1.EventBatchVerified:
+		if event.Verified {
+			log.Info().Uint64("batch_number", event.BatchNumber).Uint64("l1_block", event.L1Block).Msg("Batch proof verified on L1")
+		}
+	case l1.EventBatchFinalized:
+		s.l1Finality.markFinalized(event.BatchNumber)
+		s.fastSnapshots.promote(s.l1Finality.finalized)
+		log.Info().Uint64("batch_number", event.BatchNumber).Uint64("l1_block", event.L1Block).Msg("Batch finalized on L1")
+	}
+}
@@ -0,0 +1,515 @@
+package sequencer
+
+import (
+	"container/heap"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// Mempool errors
+var (
+	ErrMempoolFull            = errors.New("mempool full")
+	ErrSenderQueueFull        = errors.New("too many pending transactions from sender")
+	ErrReplacementUnderpriced = errors.New("replacement transaction underpriced")
+	ErrUnderpriced            = errors.New("gas price below minimum")
+	ErrAlreadyKnown           = errors.New("transaction already pending")
+)
+
+// MempoolConfig bounds the size of the mempool
+type MempoolConfig struct {
+	MaxSize          int      // Maximum number of pending transactions
+	MaxPerSender     int      // Maximum number of pending transactions per sender
+	PriceBumpPercent int      // Minimum gas price increase for replacing a transaction
+	MinGasPrice      *big.Int // Fee floor enforced at admission, nil disables it
+}
+
+// poolTx is a pending transaction with its hash and its arrival order, used to break
+// price ties
+type poolTx struct {
+	tx   state.Transaction
+	hash [32]byte
+	seq  uint64
+}
+
+// NonceSource returns the nonce the next transaction of an account must carry
+type NonceSource func(address types.Address) uint64
+
+// Mempool holds pending transactions in per-sender nonce-ordered queues and hands them
+// out highest gas price first, never reordering a sender's own transactions. Transactions
+// after a nonce gap wait in their sender's queue until the missing nonces arrive.
+type Mempool struct {
+	config      MempoolConfig
+	minGasPrice *big.Int
+	senders     map[types.Address][]*poolTx // Sorted by nonce
+	byHash      map[[32]byte]*poolTx        // Every transaction in senders
+	count       int
+	nextSeq     uint64
+	nonces      NonceSource
+	inflight    map[types.Address]uint64 // Next nonce of senders with popped transactions not yet included
+	mu          sync.Mutex
+}
+
+// NewMempool creates an empty mempool
+func NewMempool(config MempoolConfig) *Mempool {
+	minGasPrice := new(big.Int)
+	if config.MinGasPrice != nil {
+		minGasPrice.Set(config.MinGasPrice)
+	}
+
+	return &Mempool{
+		config:      config,
+		minGasPrice: minGasPrice,
+		senders:     make(map[types.Address][]*poolTx),
+		byHash:      make(map[[32]byte]*poolTx),
+		inflight:    make(map[types.Address]uint64),
+	}
+}
+
+// SetNonceSource sets where the pool reads the next nonce of a sender with no transactions
+// in flight. Without one, a sender's lowest pending nonce is taken as its next.
+func (m *Mempool) SetNonceSource(source NonceSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nonces = source
+}
+
+// MinGasPrice returns the current fee floor
+func (m *Mempool) MinGasPrice() *big.Int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return new(big.Int).Set(m.minGasPrice)
+}
+
+// SetMinGasPrice changes the fee floor. Pending transactions below the new floor stay in
+// the pool, only new admissions are checked.
+func (m *Mempool) SetMinGasPrice(price *big.Int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.minGasPrice = new(big.Int).Set(price)
+}
+
+// Len returns the number of pending transactions that are ready to execute
+func (m *Mempool) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ready := 0
+	for sender := range m.senders {
+		_, n := m.readyLocked(sender)
+		ready += n
+	}
+	return ready
+}
+
+// ReadyGas returns the gas limit summed over the transactions Len counts
+func (m *Mempool) ReadyGas() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var gas uint64
+	for sender, queue := range m.senders {
+		start, n := m.readyLocked(sender)
+		for _, ptx := range queue[start : start+n] {
+			gas += ptx.tx.Gas
+		}
+	}
+	return gas
+}
+
+// HasReady reports whether at least n pending transactions are ready to execute. Unlike
+// Len it stops counting at n, so it stays cheap to ask on every admission.
+func (m *Mempool) HasReady(n int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ready := 0
+	for sender := range m.senders {
+		if ready >= n {
+			break
+		}
+		_, count := m.readyLocked(sender)
+		ready += count
+	}
+	return ready >= n
+}
+
+// HasReadyGas reports whether the transactions Len counts have at least gas in limits,
+// stopping once they do
+func (m *Mempool) HasReadyGas(gas uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ready uint64
+	for sender, queue := range m.senders {
+		if ready >= gas {
+			break
+		}
+		start, n := m.readyLocked(sender)
+		for _, ptx := range queue[start : start+n] {
+			ready += ptx.tx.Gas
+		}
+	}
+	return ready >= gas
+}
+
+// Queued returns the number of pending transactions waiting for a lower nonce
+func (m *Mempool) Queued() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued := 0
+	for sender, queue := range m.senders {
+		start, n := m.readyLocked(sender)
+		queued += len(queue) - start - n
+	}
+	return queued
+}
+
+// Pending returns the transactions not submitted privately, ordered by sender and nonce
+func (m *Mempool) Pending() []state.Transaction {
+	return m.list(false)
+}
+
+// All returns every transaction in the pool, ordered by sender and nonce
+func (m *Mempool) All() []state.Transaction {
+	return m.list(true)
+}
+
+// list returns the pool's transactions ordered by sender and nonce, private ones only
+// when includePrivate is set
+func (m *Mempool) list(includePrivate bool) []state.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txs := make([]state.Transaction, 0, m.count)
+	for _, queue := range m.senders {
+		for _, ptx := range queue {
+			if includePrivate || !ptx.tx.Private {
+				txs = append(txs, ptx.tx)
+			}
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].From != txs[j].From {
+			return string(txs[i].From[:]) < string(txs[j].From[:])
+		}
+		return txs[i].Nonce < txs[j].Nonce
+	})
+	return txs
+}
+
+// Contains reports whether a transaction with the given hash is in the pool
+func (m *Mempool) Contains(hash [32]byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.byHash[hash]
+	return ok
+}
+
+// Lookup returns the pool's transactions with the given hashes, skipping those not in the pool
+// and those submitted privately, which are never handed to peers
+func (m *Mempool) Lookup(hashes [][32]byte) []state.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var txs []state.Transaction
+	for _, hash := range hashes {
+		if ptx, ok := m.byHash[hash]; ok && !ptx.tx.Private {
+			txs = append(txs, ptx.tx)
+		}
+	}
+	return txs
+}
+
+// Add inserts a transaction. A transaction with the same sender and nonce as a pending one
+// replaces it if its gas price is at least PriceBumpPercent higher. When the pool is full the
+// cheapest evictable transaction makes room if the new one pays more.
+func (m *Mempool) Add(tx state.Transaction) (replaced bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if gasPrice(&tx).Cmp(m.minGasPrice) < 0 {
+		return false, ErrUnderpriced
+	}
+	tx.ReceivedAt = time.Now().UnixMilli()
+
+	queue := m.senders[tx.From]
+	i := sort.Search(len(queue), func(i int) bool {
+		return queue[i].tx.Nonce >= tx.Nonce
+	})
+
+	// Replacement by fee
+	if i < len(queue) && queue[i].tx.Nonce == tx.Nonce {
+		if _, ok := m.byHash[tx.Hash()]; ok {
+			return false, ErrAlreadyKnown
+		}
+		minPrice := bumpedPrice(gasPrice(&queue[i].tx), m.config.PriceBumpPercent)
+		if gasPrice(&tx).Cmp(minPrice) < 0 {
+			return false, ErrReplacementUnderpriced
+		}
+		m.unindexLocked(queue[i : i+1])
+		queue[i] = m.newPoolTx(tx)
+		return true, nil
+	}
+
+	if m.config.MaxPerSender > 0 && len(queue) >= m.config.MaxPerSender {
+		return false, ErrSenderQueueFull
+	}
+
+	if m.config.MaxSize > 0 && m.count >= m.config.MaxSize {
+		if !m.evictCheaperLocked(gasPrice(&tx)) {
+			return false, ErrMempoolFull
+		}
+		// The eviction may have shortened this sender's queue
+		queue = m.senders[tx.From]
+		i = sort.Search(len(queue), func(i int) bool {
+			return queue[i].tx.Nonce >= tx.Nonce
+		})
+	}
+
+	queue = append(queue, nil)
+	copy(queue[i+1:], queue[i:])
+	queue[i] = m.newPoolTx(tx)
+	m.senders[tx.From] = queue
+	m.count++
+
+	return false, nil
+}
+
+// Pop removes and returns up to n transactions, highest gas price first while keeping each
+// sender's transactions in nonce order
+func (m *Mempool) Pop(n int) []state.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	heads := make(priceHeap, 0, len(m.senders))
+	for sender, queue := range m.senders {
+		start, ready := m.readyLocked(sender)
+
+		// Nonces below the next one were already used and can never execute
+		if start > 0 {
+			m.count -= start
+			m.unindexLocked(queue[:start])
+			queue = queue[start:]
+			if len(queue) == 0 {
+				delete(m.senders, sender)
+				continue
+			}
+			m.senders[sender] = queue
+		}
+		if ready > 0 {
+			heads = append(heads, &senderHead{sender: sender, tx: queue[0], ready: ready})
+		}
+	}
+	heap.Init(&heads)
+
+	var txs []state.Transaction
+	for len(txs) < n && heads.Len() > 0 {
+		head := heap.Pop(&heads).(*senderHead)
+		txs = append(txs, head.tx.tx)
+		m.inflight[head.sender] = head.tx.tx.Nonce + 1
+
+		queue := m.senders[head.sender][1:]
+		m.count--
+		m.unindexLocked([]*poolTx{head.tx})
+		if len(queue) == 0 {
+			delete(m.senders, head.sender)
+			continue
+		}
+		m.senders[head.sender] = queue
+		if head.ready > 1 {
+			heap.Push(&heads, &senderHead{sender: head.sender, tx: queue[0], ready: head.ready - 1})
+		}
+	}
+
+	return txs
+}
+
+// Requeue returns transactions taken by Pop to the pool, e.g. when a proposal failed.
+// Limits are not enforced so nothing popped is lost. Deposits are skipped, they are
+// proposed again from the L1 deposits still pending.
+func (m *Mempool) Requeue(txs []state.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tx := range txs {
+		if tx.Type == state.TxTypeDeposit {
+			continue
+		}
+		delete(m.inflight, tx.From)
+
+		queue := m.senders[tx.From]
+		i := sort.Search(len(queue), func(i int) bool {
+			return queue[i].tx.Nonce >= tx.Nonce
+		})
+		if i < len(queue) && queue[i].tx.Nonce == tx.Nonce {
+			// A replacement arrived in the meantime
+			continue
+		}
+
+		queue = append(queue, nil)
+		copy(queue[i+1:], queue[i:])
+		queue[i] = m.newPoolTx(tx)
+		m.senders[tx.From] = queue
+		m.count++
+	}
+}
+
+// RemoveIncluded drops pending transactions made obsolete by a finalized batch, i.e. every
+// transaction of a sender up to the highest nonce the batch included for it
+func (m *Mempool) RemoveIncluded(txs []state.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tx := range txs {
+		if tx.Type == state.TxTypeDeposit {
+			continue
+		}
+
+		// The nonce source covers everything included from now on
+		if next, ok := m.inflight[tx.From]; ok && next <= tx.Nonce+1 {
+			delete(m.inflight, tx.From)
+		}
+
+		queue := m.senders[tx.From]
+		i := sort.Search(len(queue), func(i int) bool {
+			return queue[i].tx.Nonce > tx.Nonce
+		})
+		if i == 0 {
+			continue
+		}
+
+		m.count -= i
+		m.unindexLocked(queue[:i])
+		if i == len(queue) {
+			delete(m.senders, tx.From)
+			continue
+		}
+		m.senders[tx.From] = queue[i:]
+	}
+}
+
+// readyLocked returns the position of the sender's next executable transaction in its
+// queue and how many transactions follow it without a nonce gap
+func (m *Mempool) readyLocked(sender types.Address) (start, n int) {
+	queue := m.senders[sender]
+	if len(queue) == 0 {
+		return 0, 0
+	}
+
+	next, ok := m.inflight[sender]
+	if !ok {
+		if m.nonces != nil {
+			next = m.nonces(sender)
+		} else {
+			next = queue[0].tx.Nonce
+		}
+	}
+
+	start = sort.Search(len(queue), func(i int) bool {
+		return queue[i].tx.Nonce >= next
+	})
+	for start+n < len(queue) && queue[start+n].tx.Nonce == next+uint64(n) {
+		n++
+	}
+	return start, n
+}
+
+// newPoolTx wraps a transaction entering the pool and indexes it by hash
+func (m *Mempool) newPoolTx(tx state.Transaction) *poolTx {
+	m.nextSeq++
+	ptx := &poolTx{tx: tx, hash: tx.Hash(), seq: m.nextSeq}
+	m.byHash[ptx.hash] = ptx
+	return ptx
+}
+
+// unindexLocked removes transactions leaving the pool from the hash index
+func (m *Mempool) unindexLocked(ptxs []*poolTx) {
+	for _, ptx := range ptxs {
+		delete(m.byHash, ptx.hash)
+	}
+}
+
+// evictCheaperLocked drops the cheapest transaction that is last in its sender's queue, so
+// no nonce gap is created, if it pays less than price
+func (m *Mempool) evictCheaperLocked(price *big.Int) bool {
+	var victim types.Address
+	var victimPrice *big.Int
+	for sender, queue := range m.senders {
+		last := gasPrice(&queue[len(queue)-1].tx)
+		if victimPrice == nil || last.Cmp(victimPrice) < 0 {
+			victim, victimPrice = sender, last
+		}
+	}
+
+	if victimPrice == nil || victimPrice.Cmp(price) >= 0 {
+		return false
+	}
+
+	queue := m.senders[victim]
+	m.unindexLocked(queue[len(queue)-1:])
+	if len(queue) == 1 {
+		delete(m.senders, victim)
+	} else {
+		m.senders[victim] = queue[:len(queue)-1]
+	}
+	m.count--
+	return true
+}
+
+// gasPrice returns the transaction's gas price, treating a missing price as zero
+func gasPrice(tx *state.Transaction) *big.Int {
+	if tx.GasPrice == nil {
+		return new(big.Int)
+	}
+	return tx.GasPrice
+}
+
+// bumpedPrice returns price increased by percent, rounding up so a zero price still needs a bump
+func bumpedPrice(price *big.Int, percent int) *big.Int {
+	bumped := new(big.Int).Mul(price, big.NewInt(int64(100+percent)))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(price) <= 0 {
+		bumped.Add(price, big.NewInt(1))
+	}
+	return bumped
+}
+
+// senderHead is the next executable transaction of a sender
+type senderHead struct {
+	sender types.Address
+	tx     *poolTx
+	ready  int // Executable transactions of the sender starting with tx
+}
+
+// priceHeap orders sender heads by gas price, then by arrival
+type priceHeap []*senderHead
+
+func (h priceHeap) Len() int { return len(h) }
+
+func (h priceHeap) Less(i, j int) bool {
+	if c := gasPrice(&h[i].tx.tx).Cmp(gasPrice(&h[j].tx.tx)); c != 0 {
+		return c > 0
+	}
+	return h[i].tx.seq < h[j].tx.seq
+}
+
+func (h priceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priceHeap) Push(x interface{}) { *h = append(*h, x.(*senderHead)) }
+
+func (h *priceHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
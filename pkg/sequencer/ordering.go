@@ -0,0 +1,169 @@
+package sequencer
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+// Batch ordering policies, named in the Ordering field of every batch
+const (
+	OrderingFee  = "fee"  // Highest gas price first
+	OrderingFIFO = "fifo" // Earliest admission to the proposer's pool first
+	OrderingFair = "fair" // By the transaction hash committed to the parent state root
+)
+
+// OrderingPolicy orders the transactions of a batch. A policy only compares the next
+// transactions of two senders, each sender's transactions always follow in nonce order, and
+// it depends on nothing but the transactions and the state the batch builds on, so every
+// validator can recompute the order a batch declares.
+type OrderingPolicy interface {
+	// Name is declared in the batch header
+	Name() string
+
+	// Less reports whether a goes before b
+	Less(a, b *state.Transaction) bool
+}
+
+// NewOrderingPolicy returns the policy called name for a batch built on parentRoot
+func NewOrderingPolicy(name string, parentRoot [32]byte) (OrderingPolicy, error) {
+	switch name {
+	case OrderingFee:
+		return feeOrdering{}, nil
+	case OrderingFIFO:
+		return fifoOrdering{}, nil
+	case OrderingFair:
+		return fairOrdering{seed: parentRoot}, nil
+	default:
+		return nil, fmt.Errorf("unknown batch ordering policy %q", name)
+	}
+}
+
+// feeOrdering takes the highest gas price first, ties by hash
+type feeOrdering struct{}
+
+func (feeOrdering) Name() string { return OrderingFee }
+
+func (feeOrdering) Less(a, b *state.Transaction) bool {
+	if c := gasPrice(a).Cmp(gasPrice(b)); c != 0 {
+		return c > 0
+	}
+	return lessHash(a, b)
+}
+
+// fifoOrdering takes transactions in the order the proposer admitted them, ties by hash.
+// The admission times are declared with the transactions.
+type fifoOrdering struct{}
+
+func (fifoOrdering) Name() string { return OrderingFIFO }
+
+func (fifoOrdering) Less(a, b *state.Transaction) bool {
+	if a.ReceivedAt != b.ReceivedAt {
+		return a.ReceivedAt < b.ReceivedAt
+	}
+	return lessHash(a, b)
+}
+
+// fairOrdering sorts by the Keccak-256 of the parent state root and the transaction hash,
+// an order the proposer cannot choose once the parent batch is decided
+type fairOrdering struct {
+	seed [32]byte
+}
+
+func (fairOrdering) Name() string { return OrderingFair }
+
+func (o fairOrdering) Less(a, b *state.Transaction) bool {
+	ha, hb := a.Hash(), b.Hash()
+	ka := crypto.Keccak256(o.seed[:], ha[:])
+	kb := crypto.Keccak256(o.seed[:], hb[:])
+	if c := bytes.Compare(ka, kb); c != 0 {
+		return c < 0
+	}
+	return bytes.Compare(ha[:], hb[:]) < 0
+}
+
+func lessHash(a, b *state.Transaction) bool {
+	ha, hb := a.Hash(), b.Hash()
+	return bytes.Compare(ha[:], hb[:]) < 0
+}
+
+// OrderTransactions orders a batch's transactions by policy. Deposits keep their L1 order
+// ahead of everything else so their funds can be spent in the same batch.
+func OrderTransactions(policy OrderingPolicy, txs []state.Transaction) []state.Transaction {
+	ordered := make([]state.Transaction, 0, len(txs))
+	queues := make(map[types.Address][]state.Transaction)
+	var senders []types.Address
+	for _, tx := range txs {
+		if tx.Type == state.TxTypeDeposit {
+			ordered = append(ordered, tx)
+			continue
+		}
+		if _, ok := queues[tx.From]; !ok {
+			senders = append(senders, tx.From)
+		}
+		queues[tx.From] = append(queues[tx.From], tx)
+	}
+
+	heads := &orderingHeap{policy: policy}
+	for _, sender := range senders {
+		queue := queues[sender]
+		sort.Slice(queue, func(i, j int) bool {
+			return queue[i].Nonce < queue[j].Nonce
+		})
+		heads.queues = append(heads.queues, queue)
+	}
+	heap.Init(heads)
+
+	for heads.Len() > 0 {
+		queue := heads.queues[0]
+		ordered = append(ordered, queue[0])
+		if len(queue) == 1 {
+			heap.Pop(heads)
+			continue
+		}
+		heads.queues[0] = queue[1:]
+		heap.Fix(heads, 0)
+	}
+	return ordered
+}
+
+// VerifyOrdering checks that a batch's transactions are in the order policy gives them
+func VerifyOrdering(policy OrderingPolicy, txs []state.Transaction) error {
+	for i, tx := range OrderTransactions(policy, txs) {
+		if tx.Hash() != txs[i].Hash() {
+			return fmt.Errorf("transaction %d is %x, %s ordering puts %x there", i, txs[i].Hash(), policy.Name(), tx.Hash())
+		}
+	}
+	return nil
+}
+
+// orderingHeap holds the remaining transactions of each sender, the sender whose next
+// transaction goes first on top
+type orderingHeap struct {
+	policy OrderingPolicy
+	queues [][]state.Transaction
+}
+
+func (h *orderingHeap) Len() int { return len(h.queues) }
+
+func (h *orderingHeap) Less(i, j int) bool {
+	return h.policy.Less(&h.queues[i][0], &h.queues[j][0])
+}
+
+func (h *orderingHeap) Swap(i, j int) { h.queues[i], h.queues[j] = h.queues[j], h.queues[i] }
+
+func (h *orderingHeap) Push(x interface{}) { h.queues = append(h.queues, x.([]state.Transaction)) }
+
+func (h *orderingHeap) Pop() interface{} {
+	old := h.queues
+	n := len(old)
+	item := old[n-1]
+	h.queues = old[:n-1]
+	return item
+}
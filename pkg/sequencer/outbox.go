@@ -0,0 +1,229 @@
+package sequencer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/p2p"
+	"zkrollup/pkg/state"
+)
+
+const (
+	outboxInitialBackoff = 2 * time.Second
+	outboxMaxBackoff     = 5 * time.Minute
+	outboxCheckInterval  = time.Second
+)
+
+// Outbox tracks finalized batches whose broadcast has not reached any peer yet. Only
+// batch numbers are persisted, the batches themselves are read back from state.
+type Outbox struct {
+	path    string
+	pending map[uint64]*outboxEntry
+	mu      sync.Mutex
+}
+
+type outboxEntry struct {
+	attempts int
+	nextTry  time.Time
+}
+
+// OpenOutbox loads the outbox persisted at path, an empty path keeps it in memory only
+func OpenOutbox(path string) (*Outbox, error) {
+	o := &Outbox{
+		path:    path,
+		pending: make(map[uint64]*outboxEntry),
+	}
+	if path == "" {
+		return o, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %v", err)
+	}
+
+	var numbers []uint64
+	if err := json.Unmarshal(data, &numbers); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox: %v", err)
+	}
+	for _, n := range numbers {
+		o.pending[n] = &outboxEntry{}
+	}
+
+	return o, nil
+}
+
+// Enqueue schedules a batch for immediate delivery
+func (o *Outbox) Enqueue(batchNumber uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending[batchNumber] = &outboxEntry{}
+	return o.saveLocked()
+}
+
+// Due returns the batch numbers whose next attempt is due, lowest first
+func (o *Outbox) Due(now time.Time) []uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var due []uint64
+	for n, entry := range o.pending {
+		if !now.Before(entry.nextTry) {
+			due = append(due, n)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+	return due
+}
+
+// Delivered removes a batch from the outbox
+func (o *Outbox) Delivered(batchNumber uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.pending[batchNumber]; !ok {
+		return nil
+	}
+	delete(o.pending, batchNumber)
+	return o.saveLocked()
+}
+
+// Failed backs off the next attempt for a batch exponentially and returns the delay
+func (o *Outbox) Failed(batchNumber uint64, now time.Time) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry, ok := o.pending[batchNumber]
+	if !ok {
+		return 0
+	}
+
+	backoff := outboxInitialBackoff << entry.attempts
+	if backoff <= 0 || backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	entry.attempts++
+	entry.nextTry = now.Add(backoff)
+	return backoff
+}
+
+// Len returns the number of undelivered batches
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// saveLocked writes the pending batch numbers atomically. Must be called with o.mu held.
+func (o *Outbox) saveLocked() error {
+	if o.path == "" {
+		return nil
+	}
+
+	numbers := make([]uint64, 0, len(o.pending))
+	for n := range o.pending {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	data, err := json.Marshal(numbers)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %v", err)
+	}
+
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write outbox: %v", err)
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		return fmt.Errorf("failed to write outbox: %v", err)
+	}
+	return nil
+}
+
+// deliverOutbox broadcasts finalized batches from the outbox until a peer receives them
+func (s *Sequencer) deliverOutbox() {
+	ticker := time.NewTicker(outboxCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, n := range s.outbox.Due(time.Now()) {
+			batches := s.state.GetBatches(n, 1)
+			if len(batches) == 0 {
+				log.Warn().Uint64("batch_number", n).Msg("Dropping unknown batch from outbox")
+				s.outbox.Delivered(n)
+				continue
+			}
+
+			if err := s.node.BroadcastFinalizedBatch(s.ctx, &batches[0]); err != nil {
+				backoff := s.outbox.Failed(n, time.Now())
+				log.Warn().Err(err).Uint64("batch_number", n).Dur("retry_in", backoff).Msg("Failed to broadcast finalized batch")
+				continue
+			}
+
+			if err := s.outbox.Delivered(n); err != nil {
+				log.Error().Err(err).Msg("Failed to update outbox")
+			}
+			log.Info().Uint64("batch_number", n).Msg("Broadcast finalized batch")
+		}
+	}
+}
+
+// handleFinalizedBatch receives a finalized batch announcement. Batches that extend our
+// state are queued for application, a gap is filled by pulling the missing batches from
+// the sender.
+func (s *Sequencer) handleFinalizedBatch(from peer.ID, batch *state.Batch) error {
+	local := s.state.GetBatchNumber()
+	if batch.BatchNumber < local {
+		return nil
+	}
+
+	if batch.BatchNumber > local {
+		log.Info().Uint64("batch_number", local).Uint64("announced", batch.BatchNumber).Str("peer", from.String()).Msg("Missed finalized batches, requesting them from peer")
+
+		batches, err := s.node.RequestBatches(s.ctx, from, local, batch.BatchNumber-local)
+		if err != nil {
+			return fmt.Errorf("failed to request missed batches: %v", err)
+		}
+		for i := range batches {
+			s.queueSyncedBatch(&batches[i])
+		}
+	}
+
+	s.queueSyncedBatch(batch)
+	return nil
+}
+
+// handleBatchRequest serves finalized batches to a peer that missed them
+func (s *Sequencer) handleBatchRequest(from, count uint64) ([]state.Batch, error) {
+	if count > p2p.MaxBatchesPerRequest {
+		count = p2p.MaxBatchesPerRequest
+	}
+	return s.state.GetBatches(from, count), nil
+}
+
+// queueSyncedBatch hands a batch received from a peer to the consensus loop, which applies
+// batches one at a time
+func (s *Sequencer) queueSyncedBatch(batch *state.Batch) {
+	select {
+	case s.syncedBatchCh <- batch:
+	case <-s.ctx.Done():
+	}
+}
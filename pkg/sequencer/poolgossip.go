@@ -0,0 +1,62 @@
+package sequencer
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// announceTransactions gossips the hashes of pool transactions, peers missing any of them
+// pull them from this node. Deposits are picked up from L1 by every node, private
+// submissions are never announced.
+func (s *Sequencer) announceTransactions(txs []state.Transaction) {
+	hashes := make([]common.Hash, 0, len(txs))
+	for i := range txs {
+		if txs[i].Type == state.TxTypeDeposit || txs[i].Private {
+			continue
+		}
+		hashes = append(hashes, txs[i].Hash())
+	}
+	if len(hashes) == 0 {
+		return
+	}
+	if err := s.node.AnnounceTransactions(s.ctx, hashes); err != nil {
+		log.Warn().Err(err).Int("tx_count", len(hashes)).Msg("Failed to announce transactions")
+	}
+}
+
+// handleTxRequest serves the pool transactions a peer pulls after an announcement, never
+// the ones submitted privately
+func (s *Sequencer) handleTxRequest(hashes []common.Hash) []state.Transaction {
+	wanted := make([][32]byte, len(hashes))
+	for i, hash := range hashes {
+		wanted[i] = hash
+	}
+	return s.mempool.Lookup(wanted)
+}
+
+// announceMempool announces the public part of the pool every MempoolAnnounceSeconds, so a
+// node that missed announcements, such as one that just restarted, pulls what it lacks.
+// Peers holding a transaction already ignore its hash.
+func (s *Sequencer) announceMempool() {
+	interval := time.Duration(s.config.MempoolAnnounceSeconds) * time.Second
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if txs := s.mempool.Pending(); len(txs) > 0 {
+				s.announceTransactions(txs)
+			}
+		}
+	}
+}
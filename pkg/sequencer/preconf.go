@@ -0,0 +1,216 @@
+package sequencer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/signer"
+	"zkrollup/pkg/state"
+)
+
+// preconfDomain separates preconfirmation signatures from every other message signed with
+// the consensus key
+const preconfDomain = "zkrollup-preconfirmation"
+
+// preconfRetain caps the preconfirmations a node tracks until their deadline
+const preconfRetain = 100000
+
+// Preconfirmation errors
+var (
+	ErrPreconfirmationPending   = errors.New("preconfirmation deadline not reached")
+	ErrInvalidPreconfirmation   = errors.New("invalid preconfirmation")
+	ErrPreconfirmationsDisabled = errors.New("preconfirmations disabled")
+)
+
+// Preconfirmation is a sequencer's signed promise that a transaction is included in a batch
+// no later than Deadline. It gives wallets an answer before the batch is finalized, its
+// proof remains what makes the transaction final.
+type Preconfirmation struct {
+	TxHash    common.Hash    `json:"txHash"`
+	ChainID   uint64         `json:"chainId"`
+	Issuer    common.Address `json:"issuer"`   // Consensus signing address of the promising node
+	Batch     uint64         `json:"batch"`    // Next batch when the promise was made
+	Deadline  uint64         `json:"deadline"` // Last batch the transaction may be included in
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// Message returns the bytes the issuer signs as an EIP-191 text message
+func (p *Preconfirmation) Message() []byte {
+	buf := make([]byte, 0, len(preconfDomain)+8+common.HashLength+common.AddressLength+16)
+	buf = append(buf, preconfDomain...)
+	buf = binary.BigEndian.AppendUint64(buf, p.ChainID)
+	buf = append(buf, p.TxHash[:]...)
+	buf = append(buf, p.Issuer[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, p.Batch)
+	buf = binary.BigEndian.AppendUint64(buf, p.Deadline)
+	return crypto.Keccak256(buf)
+}
+
+// VerifySignature checks the preconfirmation was signed by its issuer
+func (p *Preconfirmation) VerifySignature() error {
+	if p.Deadline < p.Batch {
+		return fmt.Errorf("%w: deadline %d before batch %d", ErrInvalidPreconfirmation, p.Deadline, p.Batch)
+	}
+	from, err := signer.RecoverText(p.Message(), p.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPreconfirmation, err)
+	}
+	if from != p.Issuer {
+		return fmt.Errorf("%w: signed by %s, issuer is %s", ErrInvalidPreconfirmation, from.Hex(), p.Issuer.Hex())
+	}
+	return nil
+}
+
+// PreconfirmationEvidence shows a preconfirmation was broken: the deadline batch, whose
+// state root it names, is finalized and the transaction is in no batch up to it. Anyone
+// holding the finalized batches can check it with VerifyPreconfirmationEvidence, and the
+// issuer's signature makes it attributable for slashing.
+type PreconfirmationEvidence struct {
+	Preconfirmation Preconfirmation `json:"preconfirmation"`
+	DeadlineRoot    common.Hash     `json:"deadlineRoot"` // State root of the deadline batch
+	IncludedIn      *uint64         `json:"includedIn"`   // Batch that included the transaction late, null if none did
+}
+
+// preconfTracker remembers the preconfirmations this node issued until their deadline
+// passes, oldest evicted first
+type preconfTracker struct {
+	mu     sync.Mutex
+	issued map[common.Hash]Preconfirmation
+	order  []common.Hash
+	limit  int
+}
+
+func newPreconfTracker(limit int) *preconfTracker {
+	return &preconfTracker{issued: make(map[common.Hash]Preconfirmation), limit: limit}
+}
+
+func (t *preconfTracker) add(p Preconfirmation) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.issued[p.TxHash]; !ok {
+		t.order = append(t.order, p.TxHash)
+	}
+	t.issued[p.TxHash] = p
+	for len(t.order) > t.limit {
+		delete(t.issued, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// expired removes and returns the preconfirmations whose deadline is before batch number
+// next
+func (t *preconfTracker) expired(next uint64) []Preconfirmation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var due []Preconfirmation
+	kept := t.order[:0]
+	for _, hash := range t.order {
+		p, ok := t.issued[hash]
+		if !ok {
+			continue
+		}
+		if p.Deadline < next {
+			due = append(due, p)
+			delete(t.issued, hash)
+			continue
+		}
+		kept = append(kept, hash)
+	}
+	t.order = kept
+	return due
+}
+
+// Preconfirm signs a promise to include an accepted transaction within the configured
+// number of batches. It returns ErrPreconfirmationsDisabled without a consensus key or
+// window.
+func (s *Sequencer) Preconfirm(ctx context.Context, tx *state.Transaction) (*Preconfirmation, error) {
+	if s.preconfSigner == nil || s.config.PreconfirmationBatches == 0 {
+		return nil, ErrPreconfirmationsDisabled
+	}
+
+	next := s.state.GetBatchNumber()
+	p := &Preconfirmation{
+		TxHash:   tx.HashToEthHash(),
+		ChainID:  s.config.L2ChainID(),
+		Issuer:   s.preconfSigner.Address(),
+		Batch:    next,
+		Deadline: next + s.config.PreconfirmationBatches - 1,
+	}
+	signature, err := s.preconfSigner.SignText(ctx, p.Message())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign preconfirmation: %v", err)
+	}
+	p.Signature = signature
+
+	s.preconfs.add(*p)
+	return p, nil
+}
+
+// CheckPreconfirmation checks a preconfirmation against the finalized batches. It returns
+// evidence when the promise was broken, nil when it was kept, and
+// ErrPreconfirmationPending while the deadline batch is not finalized.
+func (s *Sequencer) CheckPreconfirmation(p *Preconfirmation) (*PreconfirmationEvidence, error) {
+	if err := p.VerifySignature(); err != nil {
+		return nil, err
+	}
+	if p.ChainID != s.config.L2ChainID() {
+		return nil, fmt.Errorf("%w: chain ID %d, this rollup is %d", ErrInvalidPreconfirmation, p.ChainID, s.config.L2ChainID())
+	}
+	batches := s.state.GetBatches(p.Deadline, 1)
+	if len(batches) == 0 {
+		return nil, ErrPreconfirmationPending
+	}
+
+	evidence := &PreconfirmationEvidence{Preconfirmation: *p, DeadlineRoot: batches[0].StateRoot}
+	if receipt, ok := s.state.GetReceipt(p.TxHash); ok {
+		if receipt.BatchNumber <= p.Deadline {
+			return nil, nil
+		}
+		included := receipt.BatchNumber
+		evidence.IncludedIn = &included
+	}
+	return evidence, nil
+}
+
+// VerifyPreconfirmationEvidence checks evidence of a broken preconfirmation against this
+// node's finalized batches
+func (s *Sequencer) VerifyPreconfirmationEvidence(evidence *PreconfirmationEvidence) error {
+	expected, err := s.CheckPreconfirmation(&evidence.Preconfirmation)
+	if err != nil {
+		return err
+	}
+	if expected == nil {
+		return fmt.Errorf("%w: transaction was included by batch %d", ErrInvalidPreconfirmation, evidence.Preconfirmation.Deadline)
+	}
+	if expected.DeadlineRoot != evidence.DeadlineRoot {
+		return fmt.Errorf("%w: deadline batch root %s, finalized %s", ErrInvalidPreconfirmation, evidence.DeadlineRoot.Hex(), expected.DeadlineRoot.Hex())
+	}
+	return nil
+}
+
+// settlePreconfirmations checks the preconfirmations this node issued whose deadline
+// passed with the last finalized batch, logging the evidence of every broken one
+func (s *Sequencer) settlePreconfirmations() {
+	for _, p := range s.preconfs.expired(s.state.GetBatchNumber()) {
+		evidence, err := s.CheckPreconfirmation(&p)
+		if err != nil {
+			log.Warn().Err(err).Str("tx_hash", p.TxHash.Hex()).Msg("Failed to settle preconfirmation")
+			continue
+		}
+		if evidence == nil {
+			continue
+		}
+		s.nodeMetrics.BrokenPreconfirmations.Inc()
+		log.Error().Str("tx_hash", p.TxHash.Hex()).Uint64("deadline", p.Deadline).Str("issuer", p.Issuer.Hex()).Msg("Broke preconfirmation, transaction not included by its deadline")
+	}
+}
@@ -0,0 +1,400 @@
+package sequencer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/crypto"
+	"zkrollup/pkg/l1"
+	"zkrollup/pkg/state"
+)
+
+// ProofDeadlinePolicy is the escalation applied to a batch whose proof misses its deadline
+type ProofDeadlinePolicy string
+
+const (
+	// ProofDeadlineAlert only reports the missed deadline
+	ProofDeadlineAlert ProofDeadlinePolicy = "alert"
+	// ProofDeadlineDataOnly posts the batch to L1 pending its proof, which follows once ready
+	ProofDeadlineDataOnly ProofDeadlinePolicy = "data-only"
+	// ProofDeadlineSmallerCircuit reproves the batch with the fallback circuit if it fits,
+	// and otherwise posts it data-only
+	ProofDeadlineSmallerCircuit ProofDeadlinePolicy = "smaller-circuit"
+)
+
+// proofJob is a finalized batch waiting for its proof
+type proofJob struct {
+	batch    state.Batch
+	oldRoot  [32]byte
+	enqueued time.Time
+}
+
+// proofPipeline generates batch proofs on the workers of a proving service and releases
+// proven batches in batch order, since L1 accepts them only sequentially
+type proofPipeline struct {
+	keys     *crypto.KeyManager
+	capacity int
+	workers  int
+
+	service *crypto.ProvingService // Started with the sequencer
+
+	order   []uint64 // Batch numbers in the order they were enqueued
+	pending map[uint64]proofJob
+	done    map[uint64]state.Batch
+	mu      sync.Mutex
+
+	// Escalation of proofs taking longer than deadline, 0 disables it
+	deadline         time.Duration
+	policy           ProofDeadlinePolicy
+	fallback         *crypto.Prover // Smaller circuit, nil unless the policy uses it
+	fallbackCapacity int
+	escalated        map[uint64]bool
+	dataOnly         map[uint64]bool // Posted to L1 without a proof, the proof follows separately
+	fallbackProven   map[uint64]bool // Proven by the fallback circuit, which proofs are not aggregated with
+	misses           uint64
+}
+
+func newProofPipeline(keys *crypto.KeyManager, capacity, workers int) *proofPipeline {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &proofPipeline{
+		keys:           keys,
+		capacity:       capacity,
+		workers:        workers,
+		pending:        make(map[uint64]proofJob),
+		done:           make(map[uint64]state.Batch),
+		policy:         ProofDeadlineAlert,
+		escalated:      make(map[uint64]bool),
+		dataOnly:       make(map[uint64]bool),
+		fallbackProven: make(map[uint64]bool),
+	}
+}
+
+// setDeadline enables escalation of proofs not ready within deadline. fallback is the
+// prover of the smaller circuit used by ProofDeadlineSmallerCircuit.
+func (p *proofPipeline) setDeadline(deadline time.Duration, policy ProofDeadlinePolicy, fallback *crypto.Prover, fallbackCapacity int) {
+	p.deadline = deadline
+	p.policy = policy
+	p.fallback = fallback
+	p.fallbackCapacity = fallbackCapacity
+}
+
+// startProofWorkers starts the proving service the pipeline's workers prove batches on
+func (s *Sequencer) startProofWorkers() {
+	s.proofs.service = crypto.NewProvingService(s.ctx, s.proofs.workers, s.proofs.workers*4)
+}
+
+// enqueueProof schedules proof generation for a batch just applied on top of oldRoot. Its
+// witness is built by the worker proving it; while every worker is busy and the queue is
+// full, this waits for room.
+func (s *Sequencer) enqueueProof(batch state.Batch, oldRoot [32]byte) {
+	p := s.proofs
+	job := proofJob{batch: batch, oldRoot: oldRoot, enqueued: time.Now()}
+	p.mu.Lock()
+	p.order = append(p.order, batch.BatchNumber)
+	p.pending[batch.BatchNumber] = job
+	p.mu.Unlock()
+
+	keys := p.keys.Keys(batch.BatchNumber)
+	err := p.service.Submit(s.ctx, crypto.ProofRequest{
+		BatchNumber: batch.BatchNumber,
+		Prover:      keys.Prover,
+		Witness: func() (frontend.Circuit, error) {
+			return s.batchAssignment(p.capacity, &job.batch, job.oldRoot)
+		},
+		Done: func(result crypto.ProofResult) {
+			s.proofFinished(job, keys.Prover, p.capacity, keys.Epoch, result)
+		},
+	})
+	if err != nil && s.ctx.Err() == nil {
+		// Not proven at all, the batch is handed over like one whose proof failed
+		s.proofFinished(job, keys.Prover, p.capacity, keys.Epoch, crypto.ProofResult{BatchNumber: batch.BatchNumber, Err: err})
+	}
+}
+
+// ProofProgress returns the batches the proving service is working on, with their stage
+func (s *Sequencer) ProofProgress() []crypto.ProofProgress {
+	if s.proofs == nil || s.proofs.service == nil {
+		return nil
+	}
+	return s.proofs.service.Progress()
+}
+
+// proveJob proves a batch with prover, whose keys come from CRS epoch, outside the proving
+// service, and hands the result to proofFinished
+func (s *Sequencer) proveJob(job proofJob, prover *crypto.Prover, capacity int, epoch uint64) {
+	start := time.Now()
+	proof, publicInputs, err := s.proveBatch(prover, capacity, &job.batch, job.oldRoot)
+	s.proofFinished(job, prover, capacity, epoch, crypto.ProofResult{
+		BatchNumber:  job.batch.BatchNumber,
+		Proof:        proof,
+		PublicInputs: publicInputs,
+		Err:          err,
+		ProvingTime:  time.Since(start),
+	})
+}
+
+// proofFinished stores the proof of a batch made with prover, whose keys come from CRS
+// epoch, and hands the batch to proofDone. A failed proof is handed over without one.
+func (s *Sequencer) proofFinished(job proofJob, prover *crypto.Prover, capacity int, epoch uint64, result crypto.ProofResult) {
+	batch := job.batch
+	if result.Err != nil {
+		log.Error().Err(result.Err).Uint64("batch_number", batch.BatchNumber).Int("capacity", capacity).Msg("Failed to generate batch proof")
+	} else {
+		proof, publicInputs := result.Proof, result.PublicInputs
+		batch.Proof = proof
+		batch.PublicInputs = publicInputs
+		batch.InputSchema = crypto.BatchCommitmentSchemaV1.Hash()
+		batch.VerifierEpoch = epoch
+		if err := s.state.SetBatchProof(batch.BatchNumber, proof, publicInputs, batch.InputSchema, batch.VerifierEpoch); err != nil {
+			log.Error().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to store batch proof")
+		}
+		duration := result.WitnessTime + result.ProvingTime
+		s.nodeMetrics.ProofTime.Observe(duration.Seconds())
+		if prover == s.proofs.fallback {
+			s.proofs.mu.Lock()
+			if _, waiting := s.proofs.pending[batch.BatchNumber]; waiting {
+				s.proofs.fallbackProven[batch.BatchNumber] = true
+			}
+			s.proofs.mu.Unlock()
+		}
+		log.Info().Uint64("batch_number", batch.BatchNumber).Int("capacity", capacity).Dur("duration", duration).Dur("witness_time", result.WitnessTime).Int("proof_size", len(proof)).Msg("Generated batch proof")
+	}
+	s.proofDone(batch)
+}
+
+// proveBatch proves the commitment to the batch's transactions and state transition with
+// a circuit of the given capacity and returns the proof with its public inputs
+func (s *Sequencer) proveBatch(prover *crypto.Prover, capacity int, batch *state.Batch, oldRoot [32]byte) ([]byte, []byte, error) {
+	assignment, err := s.batchAssignment(capacity, batch, oldRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proof, publicInputs, err := prover.ProveSerialized(assignment)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prove batch: %v", err)
+	}
+	return proof, publicInputs, nil
+}
+
+// batchAssignment builds the witness of the commitment to the batch's transactions and
+// state transition for a circuit of the given capacity
+func (s *Sequencer) batchAssignment(capacity int, batch *state.Batch, oldRoot [32]byte) (frontend.Circuit, error) {
+	txHashes := make([][32]byte, len(batch.Transactions))
+	for i := range batch.Transactions {
+		txHashes[i] = batch.Transactions[i].Hash()
+	}
+	return crypto.BatchCommitmentAssignment(capacity, oldRoot, batch.StateRoot, txHashes)
+}
+
+// proofDone records a finished batch and forwards every batch that is now next in order.
+// The proof of a batch already posted data-only is submitted on its own, and a second
+// result for a batch that was already settled is dropped.
+func (s *Sequencer) proofDone(batch state.Batch) {
+	p := s.proofs
+	p.mu.Lock()
+	if p.dataOnly[batch.BatchNumber] {
+		if _, waiting := p.pending[batch.BatchNumber]; waiting {
+			// Still behind an earlier batch, so it can be posted with its proof after all
+			if len(batch.Proof) != 0 {
+				delete(p.dataOnly, batch.BatchNumber)
+				p.done[batch.BatchNumber] = batch
+			}
+			p.mu.Unlock()
+			return
+		}
+		delete(p.dataOnly, batch.BatchNumber)
+		p.mu.Unlock()
+
+		if len(batch.Proof) == 0 {
+			log.Error().Uint64("batch_number", batch.BatchNumber).Msg("Batch posted without proof remains unproven on L1")
+			return
+		}
+		s.submitToL1(batch, submitProofOnly)
+		return
+	}
+	if _, ok := p.pending[batch.BatchNumber]; !ok {
+		p.mu.Unlock()
+		return
+	}
+	if _, ok := p.done[batch.BatchNumber]; ok {
+		p.mu.Unlock()
+		return
+	}
+
+	p.done[batch.BatchNumber] = batch
+	ready := p.releaseLocked()
+	p.mu.Unlock()
+
+	for _, sub := range ready {
+		s.submitToL1(sub.batch, sub.kind)
+	}
+}
+
+// releaseLocked removes the finished batches at the head of the order and returns them
+// as L1 submissions. Must be called with p.mu held.
+func (p *proofPipeline) releaseLocked() []l1Submission {
+	var ready []l1Submission
+	for len(p.order) > 0 {
+		n := p.order[0]
+		next, ok := p.done[n]
+		if !ok {
+			break
+		}
+		delete(p.done, n)
+		delete(p.pending, n)
+		delete(p.escalated, n)
+		p.order = p.order[1:]
+
+		kind := submitWithProof
+		if p.dataOnly[n] {
+			kind = submitDataOnly
+		}
+		aggregatable := len(next.Proof) != 0 && !p.fallbackProven[n]
+		delete(p.fallbackProven, n)
+		ready = append(ready, l1Submission{batch: next, kind: kind, aggregatable: aggregatable})
+	}
+	return ready
+}
+
+// watchProofDeadlines escalates every batch whose proof is not ready within the deadline
+func (s *Sequencer) watchProofDeadlines() {
+	p := s.proofs
+	interval := p.deadline / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var overdue []proofJob
+		p.mu.Lock()
+		for _, n := range p.order {
+			job := p.pending[n]
+			if _, proven := p.done[n]; proven || p.escalated[n] || time.Since(job.enqueued) < p.deadline {
+				continue
+			}
+			p.escalated[n] = true
+			p.misses++
+			overdue = append(overdue, job)
+		}
+		p.mu.Unlock()
+
+		for _, job := range overdue {
+			s.escalateProof(job)
+		}
+	}
+}
+
+// escalateProof applies the deadline policy to a batch whose proof is late
+func (s *Sequencer) escalateProof(job proofJob) {
+	p := s.proofs
+	batch := job.batch
+	log.Error().Uint64("batch_number", batch.BatchNumber).Dur("waiting", time.Since(job.enqueued)).Str("policy", string(p.policy)).Msg("Batch proof missed its deadline")
+
+	switch p.policy {
+	case ProofDeadlineSmallerCircuit:
+		if p.fallback != nil && len(batch.Transactions) <= p.fallbackCapacity {
+			go s.proveJob(job, p.fallback, p.fallbackCapacity, p.keys.Current().Epoch)
+			return
+		}
+		log.Warn().Uint64("batch_number", batch.BatchNumber).Int("tx_count", len(batch.Transactions)).Int("fallback_capacity", p.fallbackCapacity).Msg("Batch does not fit the fallback circuit, posting it without proof")
+		s.postDataOnly(batch.BatchNumber)
+	case ProofDeadlineDataOnly:
+		s.postDataOnly(batch.BatchNumber)
+	}
+}
+
+// postDataOnly releases a batch still being proven so it is posted to L1 pending its
+// proof, letting the batches behind it through
+func (s *Sequencer) postDataOnly(batchNumber uint64) {
+	p := s.proofs
+	p.mu.Lock()
+	job, ok := p.pending[batchNumber]
+	if _, proven := p.done[batchNumber]; !ok || proven {
+		// The proof arrived in the meantime
+		p.mu.Unlock()
+		return
+	}
+	p.dataOnly[batchNumber] = true
+	p.done[batchNumber] = job.batch
+	ready := p.releaseLocked()
+	p.mu.Unlock()
+
+	for _, sub := range ready {
+		s.submitToL1(sub.batch, sub.kind)
+	}
+}
+
+// checkConstraintBudget checks a batch circuit against the configured ceremony size. Keys
+// of a CRS epoch cannot be set up for a circuit that does not fit, so this fails startup
+// when proving with them and only warns for locally set up keys.
+func checkConstraintBudget(config *core.Config, prover *crypto.Prover, capacity int) error {
+	if config.CRSPowerSize <= 0 {
+		return nil
+	}
+
+	budget, err := prover.ConstraintBudget(fmt.Sprintf("batch-%d", capacity), config.CRSPowerSize)
+	if err == nil {
+		log.Debug().Str("circuit", budget.Circuit).Int("constraints", budget.Constraints).Int("headroom", budget.Headroom()).Msg("Circuit fits the CRS ceremony")
+		return nil
+	}
+	if config.ProvingKeyEpoch != 0 {
+		return fmt.Errorf("proving key of CRS epoch %d: %w", config.ProvingKeyEpoch, err)
+	}
+	log.Warn().Err(err).Msg("Circuit does not fit the CRS ceremony, its proofs need locally set up keys")
+	return nil
+}
+
+// ProofDeadlineMisses returns how many batch proofs missed their deadline
+func (s *Sequencer) ProofDeadlineMisses() uint64 {
+	if s.proofs == nil {
+		return 0
+	}
+	s.proofs.mu.Lock()
+	defer s.proofs.mu.Unlock()
+	return s.proofs.misses
+}
+
+// checkInputSchema warns at startup when the verifier for the proving key's epoch is missing
+// or checks proofs under a different public input schema than the batch circuit, every
+// proof would then be refused
+func (s *Sequencer) checkInputSchema() {
+	if s.proofs == nil {
+		return
+	}
+
+	epoch := s.proofs.keys.Current().Epoch
+	var deployed [32]byte
+	var err error
+	if epoch == 0 {
+		deployed, err = s.l1Client.InputSchema(s.ctx)
+	} else {
+		var entry *l1.VerifierEntry
+		if entry, err = s.l1Client.VerifierForEpoch(s.ctx, epoch); err == nil {
+			deployed = entry.InputSchema
+			log.Info().Uint64("epoch", epoch).Str("verifier", entry.Verifier.Hex()).Uint64("circuit_version", entry.CircuitVersion).Msg("Proofs will be verified by the registered verifier")
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Uint64("epoch", epoch).Msg("Failed to read the public input schema of the L1 verifier")
+		return
+	}
+	if want := crypto.BatchCommitmentSchemaV1.Hash(); deployed != ([32]byte{}) && deployed != want {
+		log.Error().Hex("contract_schema", deployed[:]).Hex("circuit_schema", want[:]).Str("circuit", crypto.BatchCommitmentSchemaV1.String()).Uint64("epoch", epoch).Msg("Contract expects another public input schema, batch proofs will not be submitted")
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	p2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/rs/zerolog/log"
 
 	"zkrollup/pkg/consensus"
@@ -17,17 +21,22 @@ import (
 	"zkrollup/pkg/crypto"
 	"zkrollup/pkg/evm"
 	"zkrollup/pkg/l1"
+	"zkrollup/pkg/metrics"
 	"zkrollup/pkg/p2p"
+	"zkrollup/pkg/signer"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/trace"
+	"zkrollup/pkg/types"
 )
 
 type Sequencer struct {
 	config *core.Config
 	state  *state.State
 
-	// Transaction pool
-	txPool []state.Transaction
-	poolMu sync.RWMutex
+	// Transaction pool, poolMu serializes admission checks against state
+	mempool *Mempool
+	poolMu  sync.Mutex
+	journal *TxJournal // Admitted transactions kept across restarts
 
 	// EVM executor
 	evmExecutor *evm.EVMExecutor
@@ -35,13 +44,19 @@ type Sequencer struct {
 	// Batch processing
 	currentBatch    *state.Batch
 	batchInProgress bool
+	batchProposedAt time.Time // When this node proposed the batch in progress, zero on followers
 	batchMu         sync.RWMutex
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	// ZK proof generation
-	prover *crypto.Prover
+	proofs     *proofPipeline
+	aggregator *proofAggregator // Nil unless batch proofs are aggregated for L1
+
+	// Peers whose batch circuit keys differ from ours, by peer ID
+	keyMismatches   map[string]p2p.KeyInfo
+	keyMismatchesMu sync.Mutex
 
 	// P2P networking
 	node *p2p.Node
@@ -49,7 +64,48 @@ type Sequencer struct {
 	// L1 integration
 	l1Client     *l1.Client
 	l1Enabled    bool
-	l1SubmitChan chan state.Batch
+	l1SubmitChan chan l1Submission
+	proofStats   *metrics.ProofStats
+
+	// Staking contract the validator set is read from and double signing reported to, nil
+	// without one. Consensus signers are pinned from the configuration and from the staked
+	// validators seen so far.
+	staking           *l1.Staking
+	configuredSigners map[string]common.Address
+	stakedSigners     map[string]common.Address
+
+	// Outcome of the most recent L1 submission
+	l1Last   l1Outcome
+	l1LastMu sync.Mutex
+
+	// Emergency state set by the contract guardian, submissions are held while paused
+	l1Paused       atomic.Bool
+	l1BridgeFrozen atomic.Bool
+
+	// Time spent computing the state root of each finalized batch
+	stateRootTiming metrics.Timing
+
+	// Prometheus metrics, exported once registered by the caller
+	nodeMetrics *metrics.NodeMetrics
+
+	// Confirmed L1 deposits by nonce, waiting to be credited in a batch
+	depositWatcher *l1.DepositWatcher
+	deposits       map[uint64]l1.Deposit
+	depositsMu     sync.Mutex
+
+	// L1 batch events, and the batches they reported final
+	batchWatcher *l1.BatchWatcher
+	l1Finality   l1Finality
+
+	// Snapshots served to fast syncing peers once L1 finalized their batch
+	fastSnapshots finalizedSnapshots
+
+	// Finalized batches awaiting broadcast, and batches received from peers to apply
+	outbox        *Outbox
+	syncedBatchCh chan *state.Batch
+
+	// State snapshots downloaded from a peer, restored by the consensus loop
+	syncedSnapshotCh chan *snapshotSync
 
 	// Consensus
 	consensus *consensus.PBFT
@@ -58,59 +114,337 @@ type Sequencer struct {
 	// Peer tracking
 	peerCount   int
 	peerCountMu sync.RWMutex
+
+	// Set once the node has caught up with the network head
+	synced atomic.Bool
+
+	// Wakes the batch loop before its next tick, used for instant batches in dev mode and
+	// when the pool fills a batch
+	batchTrigger chan struct{}
+
+	// Unix nanoseconds of the last finalized batch, drives heartbeat batches and the minimum
+	// batch interval
+	lastBatchTime atomic.Int64
+	// Set while a timer is waiting out the minimum interval before the next batch
+	batchRetryPending atomic.Bool
+
+	// Subscribers to the transactions admitted to the pool
+	txFeed txFeed
+
+	// Consensus key signing preconfirmations, nil when the node issues none, and the
+	// preconfirmations it issued that are not yet due
+	preconfSigner signer.Signer
+	preconfs      *preconfTracker
+
+	// Operator multisig gating destructive admin operations, nil when not configured
+	emergency *EmergencyCouncil
+	halted    atomic.Bool
+
+	// Operator controls from the admin API: batch production and L1 submission paused,
+	// and the next batch proposed without waiting for the pool to fill
+	batchesPaused atomic.Bool
+	batchForced   atomic.Bool
+	l1Held        atomic.Bool
 }
 
 func NewSequencer(config *core.Config, port int, bootstrapPeers []string, isLeader bool) (*Sequencer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create P2P node
-	node, err := p2p.NewNode(ctx, port, bootstrapPeers)
+	// Open the rollup state, restoring anything persisted by a previous run
+	rollupState, err := state.OpenState(config.StateBackend, config.StateDBPath, false)
 	if err != nil {
 		cancel()
+		return nil, fmt.Errorf("failed to open state: %v", err)
+	}
+	log.Info().Str("backend", config.StateBackend).Uint64("batch_number", rollupState.GetBatchNumber()).Msg("Opened rollup state")
+
+	// Release everything opened so far if the sequencer cannot be set up, a leaked node
+	// keeps listening and the state database stays locked
+	var node *p2p.Node
+	var journal *TxJournal
+	created := false
+	defer func() {
+		if created {
+			return
+		}
+		cancel()
+		if node != nil {
+			node.Close()
+		}
+		if journal != nil {
+			journal.Close()
+		}
+		rollupState.Close()
+	}()
+
+	rootVersion, err := state.ParseRootVersion(config.StateHash)
+	if err == nil {
+		err = rollupState.SetRootVersion(rootVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set state hash: %v", err)
+	}
+	if config.StateArchive {
+		rollupState.EnableArchive(config.StateArchiveRetain)
+	}
+	if err := rollupState.SetCommitPolicy(state.CommitPolicy{Mode: config.StateCommitPolicy, Interval: config.StateCommitInterval}); err != nil {
+		return nil, err
+	}
+
+	// Restore finalized batches whose broadcast never reached a peer
+	outbox, err := OpenOutbox(config.OutboxFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %v", err)
+	}
+
+	// Keys may be held by a remote signer instead of this process
+	signers, err := openSigners(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up signers: %v", err)
+	}
+
+	// A dev chain starts with funded deterministic accounts
+	if config.DevMode {
+		if err := fundDevAccounts(rollupState, DevAccounts(config.DevAccounts)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only validators may send consensus messages unless the allowlist is given separately
+	allowlist := config.ConsensusPeers
+	if len(allowlist) == 0 {
+		allowlist = config.Validators
+	}
+	consensusPeers, err := p2p.ParsePeerIDs(allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse consensus peers: %v", err)
+	}
+
+	// A persisted identity keeps the node's peer ID, and so its consensus node ID, stable
+	var identity p2pcrypto.PrivKey
+	if config.IdentityFile != "" {
+		if identity, err = p2p.LoadOrCreateIdentity(config.IdentityFile, config.IdentityPassphrase); err != nil {
+			return nil, fmt.Errorf("failed to load node identity: %v", err)
+		}
+	}
+
+	// Create P2P node, a dev chain runs alone and does not look for peers
+	node, err = p2p.NewNodeWithOptions(ctx, port, bootstrapPeers, p2p.NodeOptions{
+		DisableDiscovery:   config.DevMode,
+		ConsensusAllowlist: consensusPeers,
+		Identity:           identity,
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create P2P node: %v", err)
 	}
 
-	// Create prover
-	prover, err := crypto.NewProver()
+	// Batch proofs commit to every transaction of a batch, so the circuit holds a full batch
+	var proofs *proofPipeline
+	var aggregator *proofAggregator
+	if config.ProofGeneration {
+		options := provingOptions(config)
+		if err := options.Validate(); err != nil {
+			return nil, err
+		}
+		batchProver, err := openBatchProver(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create batch prover: %v", err)
+		}
+		batchProver.Options = options
+		if err := checkConstraintBudget(config, batchProver, int(config.BatchSize)); err != nil {
+			return nil, err
+		}
+		keys, err := crypto.NewKeyManager(batchProver, config.ProvingKeyEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create key manager: %v", err)
+		}
+		proofs = newProofPipeline(keys, int(config.BatchSize), config.ProverWorkers)
+
+		if config.ProofDeadlineSeconds > 0 {
+			policy := ProofDeadlinePolicy(config.ProofDeadlinePolicy)
+			var fallback *crypto.Prover
+			switch policy {
+			case ProofDeadlineAlert, ProofDeadlineDataOnly:
+			case ProofDeadlineSmallerCircuit:
+				if config.ProofFallbackCapacity <= 0 || config.ProofFallbackCapacity >= int(config.BatchSize) {
+					return nil, fmt.Errorf("fallback circuit capacity %d must be between 1 and the batch size %d", config.ProofFallbackCapacity, config.BatchSize)
+				}
+				if fallback, err = crypto.NewBatchProver(config.ProofFallbackCapacity); err != nil {
+					return nil, fmt.Errorf("failed to create fallback batch prover: %v", err)
+				}
+				fallback.Options = options
+				if err := checkConstraintBudget(config, fallback, config.ProofFallbackCapacity); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, fmt.Errorf("unknown proof deadline policy %q", config.ProofDeadlinePolicy)
+			}
+			proofs.setDeadline(time.Duration(config.ProofDeadlineSeconds)*time.Second, policy, fallback, config.ProofFallbackCapacity)
+		}
+
+		// The aggregation circuit verifies batch proofs in-circuit and is far larger than the
+		// batch circuit, its keys are always set up locally
+		if config.ProofAggregation >= 2 {
+			aggregationProver, err := crypto.NewAggregationProver(batchProver, config.ProofAggregation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create aggregation prover: %v", err)
+			}
+			aggregationProver.Options = options
+			log.Info().Int("batches", config.ProofAggregation).Int("constraints", aggregationProver.R1cs.GetNbConstraints()).Msg("Batch proofs will be aggregated for L1")
+			aggregator = newProofAggregator(aggregationProver, config.ProofAggregation)
+		}
+	}
+
+	// Create EVM executor with the configured resource limits
+	evmExecutor, err := evm.NewEVMExecutorWithLimits(evm.ExecutionLimits{
+		MaxMemoryBytes: config.EVMMaxMemory,
+		MaxStackItems:  config.EVMMaxStack,
+		MaxCallDepth:   config.EVMMaxCallDepth,
+		MaxCodeSize:    config.EVMMaxCodeSize,
+		MaxSteps:       config.EVMMaxSteps,
+		Timeout:        time.Duration(config.EVMTimeoutMs) * time.Millisecond,
+	})
 	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to create prover: %v", err)
+		return nil, fmt.Errorf("failed to create EVM executor: %v", err)
+	}
+	evmExecutor.SetChainID(new(big.Int).SetUint64(config.L2ChainID()))
+
+	// Create the fee-ordered transaction pool
+	mempool := NewMempool(MempoolConfig{
+		MaxSize:          config.MempoolMaxSize,
+		MaxPerSender:     config.MempoolMaxPerSender,
+		PriceBumpPercent: config.MempoolPriceBumpPercent,
+		MinGasPrice:      big.NewInt(config.MinGasPrice),
+	})
+
+	// Destructive admin operations need signatures from several operators
+	var emergency *EmergencyCouncil
+	if len(config.EmergencyOperators) > 0 {
+		operators := make([]common.Address, 0, len(config.EmergencyOperators))
+		for _, op := range config.EmergencyOperators {
+			if !common.IsHexAddress(op) {
+				return nil, fmt.Errorf("invalid emergency operator address %q", op)
+			}
+			operators = append(operators, common.HexToAddress(op))
+		}
+		domain := EmergencyDomain{ChainID: config.L2ChainID()}
+		if config.ContractAddress != "" {
+			domain.Contract = common.HexToAddress(config.ContractAddress)
+		}
+		if emergency, err = NewEmergencyCouncil(operators, config.EmergencyThreshold, domain, rollupState); err != nil {
+			return nil, fmt.Errorf("failed to create emergency council: %v", err)
+		}
+	}
+
+	// Pending transactions are replayed from the journal once the node is synced
+	journal, err = OpenTxJournal(config.MempoolJournal)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create sequencer
 	seq := &Sequencer{
 		config:       config,
-		state:        state.NewState(),
-		txPool:       make([]state.Transaction, 0),
+		state:        rollupState,
+		mempool:      mempool,
+		journal:      journal,
 		ctx:          ctx,
 		cancel:       cancel,
-		prover:       prover,
+		proofs:       proofs,
+		aggregator:   aggregator,
 		node:         node,
 		isLeader:     isLeader,
 		peerCount:    1, // Start with just ourselves
-		evmExecutor:  evm.NewEVMExecutor(),
+		evmExecutor:  evmExecutor,
 		l1Enabled:    config.L1Enabled,
-		l1SubmitChan: make(chan state.Batch, 10),
+		l1SubmitChan: make(chan l1Submission, 10),
+		batchTrigger: make(chan struct{}, 1),
+
+		outbox:           outbox,
+		syncedBatchCh:    make(chan *state.Batch, p2p.MaxBatchesPerRequest),
+		syncedSnapshotCh: make(chan *snapshotSync),
+		deposits:         make(map[uint64]l1.Deposit),
+		emergency:        emergency,
+		nodeMetrics:      metrics.NewNodeMetrics(),
+		keyMismatches:    make(map[string]p2p.KeyInfo),
+		preconfSigner:    signers.consensus,
+		preconfs:         newPreconfTracker(preconfRetain),
+
+		configuredSigners: signers.pinned,
+		stakedSigners:     make(map[string]common.Address),
 	}
 
+	seq.lastBatchTime.Store(time.Now().UnixNano())
+	mempool.SetNonceSource(seq.nextNonce)
+
 	// Create consensus instance
 	nodeID := node.Host.ID().String()
 	seq.consensus = consensus.NewPBFT(node, nodeID, isLeader)
+	seq.consensus.SetBatchValidator(seq.ValidateProposedBatch)
+	seq.consensus.SetLeaderChangeHandler(seq.handleLeaderChange)
+	seq.consensus.SetRoundObserver(func(d time.Duration) {
+		seq.nodeMetrics.ConsensusRound.Observe(d.Seconds())
+	})
+	if signers.consensus != nil {
+		seq.consensus.SetMessageSigner(signers.consensus)
+		log.Info().Str("address", signers.consensus.Address().Hex()).Msg("Signing consensus messages")
+	}
+	seq.consensus.SetMessageSigners(signers.pinned)
+	seq.consensus.SetEvidenceHandler(seq.handleDoubleSign)
+	if len(config.Validators) > 0 {
+		seq.consensus.SetValidators(config.Validators)
+	}
+	seq.consensus.SetCheckpointInterval(uint64(config.ConsensusCheckpointInterval))
+	if config.ConsensusCheckpointFile != "" {
+		if err := seq.consensus.SetCheckpointFile(config.ConsensusCheckpointFile); err != nil {
+			return nil, fmt.Errorf("failed to load consensus checkpoint: %v", err)
+		}
+	}
+	rollupState.SetFlushObserver(func(d time.Duration) {
+		seq.nodeMetrics.StateFlush.Observe(d.Seconds())
+	})
+	if config.CRSCeremonyDir != "" {
+		seq.consensus.SetCRSCeremonyDir(config.CRSCeremonyDir)
+	}
+	if config.CRSPowerSize > 0 {
+		seq.consensus.SetCRSPowerSize(config.CRSPowerSize)
+	}
+	seq.consensus.SetCRSKeySetup(seq)
+	seq.consensus.SetCRSContributionTimeout(time.Duration(config.CRSContributionTimeoutSeconds) * time.Second)
+	seq.consensus.SetCRSRetentionPolicy(consensus.CRSRetentionPolicy{
+		KeepFinalEpochs:     config.CRSRetainEpochs,
+		DeleteIntermediates: config.CRSDeleteIntermediates,
+		VerifyBeforeDelete:  true,
+	})
 
 	// Setup P2P protocol handlers
 	node.SetupProtocols(&p2p.ProtocolHandlers{
-		OnTransaction: seq.handleTransaction,
-		OnBatch:       seq.handleBatch,
-		OnConsensus:   seq.handleConsensus,
+		OnTransaction:     seq.handleTransaction,
+		OnBatch:           seq.handleBatch,
+		OnConsensus:       seq.handleConsensus,
+		OnFinalizedBatch:  seq.handleFinalizedBatch,
+		OnBatchRequest:    seq.handleBatchRequest,
+		OnSnapshotRequest: seq.handleSnapshotRequest,
+		OnKeyInfoRequest:  seq.handleKeyInfoRequest,
+		OnTxRequest:       seq.handleTxRequest,
+
+		OnFinalizedSnapshotRequest: seq.handleFinalizedSnapshotRequest,
 	})
 
 	// Initialize L1 client if enabled
-	if config.L1Enabled && config.L1PrivateKey != "" {
+	if config.L1Enabled && (config.L1PrivateKey != "" || signers.l1 != nil) {
 		l1Config := &l1.Config{
 			EthereumRPC:     config.EthereumRPC,
 			ChainID:         config.ChainID,
 			ContractAddress: config.ContractAddress,
 			PrivateKey:      config.L1PrivateKey,
+			Signer:          signers.l1,
+			FinalityDepth:   config.L1FinalityDepth,
+			GasLimit:        config.L1GasLimit,
+			StuckAfter:      time.Duration(config.L1StuckTxSeconds) * time.Second,
+		}
+		if config.L1MaxFeeGwei > 0 {
+			l1Config.MaxFeePerGas = new(big.Int).Mul(new(big.Int).SetUint64(config.L1MaxFeeGwei), big.NewInt(params.GWei))
 		}
 
 		l1Client, err := l1.NewClient(l1Config)
@@ -118,17 +452,58 @@ func NewSequencer(config *core.Config, port int, bootstrapPeers []string, isLead
 			log.Warn().Err(err).Msg("Failed to initialize L1 client, L1 integration disabled")
 			seq.l1Enabled = false
 		} else {
-			seq.l1Client = l1Client
-			log.Info().Msg("L1 integration enabled")
+			seq.SetL1Client(l1Client)
 		}
 	} else {
 		log.Info().Msg("L1 integration disabled")
 		seq.l1Enabled = false
 	}
 
+	created = true
 	return seq, nil
 }
 
+// SetL1Client enables L1 integration through client, which must be bound to the contract
+// at ContractAddress to watch deposits. It must be called before Start.
+func (s *Sequencer) SetL1Client(client *l1.Client) {
+	s.l1Client = client
+	s.l1Enabled = true
+	log.Info().Msg("L1 integration enabled")
+
+	// Watch for deposits not yet credited in state and for the progress of batches
+	if s.config.ContractAddress != "" {
+		s.depositWatcher = l1.NewDepositWatcher(client, s.config.L1DepositStartBlock, s.state.GetDepositNonce(), s.config.L1DepositConfirmations, depositPollInterval)
+		s.batchWatcher = l1.NewBatchWatcher(client, s.config.L1DepositStartBlock, batchEventPollInterval)
+	}
+
+	// Record CRS ceremonies on L1
+	if s.config.CRSManagerAddress != "" {
+		crsManager, err := client.CRSManager(common.HexToAddress(s.config.CRSManagerAddress))
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to bind CRS manager, ceremonies are not recorded on L1")
+		} else {
+			s.consensus.SetCRSManager(crsManager, client.TransactOpts)
+		}
+	}
+
+	// Take the validator set from the stakes bonded on L1
+	if s.config.StakingContract != "" {
+		staking, err := client.Staking(common.HexToAddress(s.config.StakingContract))
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to bind staking contract, validator set is not read from stakes")
+		} else {
+			s.staking = staking
+		}
+	}
+
+	if s.config.ProofStatsFile != "" && s.proofStats == nil {
+		var err error
+		if s.proofStats, err = metrics.OpenProofStats(s.config.ProofStatsFile); err != nil {
+			log.Warn().Err(err).Msg("Failed to open proof stats file, proof metrics disabled")
+		}
+	}
+}
+
 func (s *Sequencer) Start() error {
 	// Start consensus module
 	s.consensus.Start()
@@ -136,23 +511,65 @@ func (s *Sequencer) Start() error {
 	// Re-register our protocol handlers to ensure they're not overridden by consensus
 	// This is critical because the consensus module might have overridden our transaction handler
 	s.node.SetupProtocols(&p2p.ProtocolHandlers{
-		OnTransaction: s.handleTransaction,
-		OnBatch:       s.handleBatch,
-		OnConsensus:   s.handleConsensus,
+		OnTransaction:     s.handleTransaction,
+		OnBatch:           s.handleBatch,
+		OnConsensus:       s.handleConsensus,
+		OnFinalizedBatch:  s.handleFinalizedBatch,
+		OnBatchRequest:    s.handleBatchRequest,
+		OnSnapshotRequest: s.handleSnapshotRequest,
+		OnKeyInfoRequest:  s.handleKeyInfoRequest,
+		OnTxRequest:       s.handleTxRequest,
+
+		OnFinalizedSnapshotRequest: s.handleFinalizedSnapshotRequest,
 	})
 
 	// Log that we've re-registered our handlers
 	fmt.Printf("Sequencer re-registered protocol handlers after consensus start\n")
 
 	// Start sequencer processes
+	go s.waitForSync()
 	go s.processBatches()
 	go s.participateConsensus()
 	go s.monitorPeerCount()
+	go s.deliverOutbox()
+
+	if s.proofs != nil {
+		s.startProofWorkers()
+		if s.proofs.deadline > 0 {
+			go s.watchProofDeadlines()
+		}
+		go s.checkPeerKeys()
+	}
 
 	// Start L1 batch submission process if enabled
 	if s.l1Enabled && s.l1Client != nil {
 		go s.submitBatchesToL1()
+		go s.replaceStuckL1Txs()
 		log.Info().Msg("Started L1 batch submission process")
+
+		if s.staking != nil {
+			go s.watchStakedValidators()
+		}
+
+		if s.config.ContractAddress != "" {
+			go s.watchGuardian()
+			if s.config.ValidatorRegistry {
+				go s.watchValidatorRegistry()
+			}
+			go s.checkInputSchema()
+			go s.checkAggregateVerifier()
+		}
+	}
+
+	if s.depositWatcher != nil {
+		go s.depositWatcher.Run(s.ctx)
+		go s.collectDeposits()
+		log.Info().Msg("Started L1 deposit watcher")
+	}
+	if s.batchWatcher != nil {
+		go s.batchWatcher.Run(s.ctx)
+		go s.followL1Batches()
+		log.Info().Msg("Started L1 batch event watcher")
 	}
 
 	return nil
@@ -187,63 +604,117 @@ func (s *Sequencer) Stop() {
 	s.cancel()
 	s.node.Close()
 
+	// Flush and close the state database
+	if err := s.state.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to close state")
+	}
+
 	// Close L1 submission channel
 	if s.l1Enabled && s.l1SubmitChan != nil {
 		close(s.l1SubmitChan)
 	}
+
+	if s.proofStats != nil {
+		s.proofStats.Close()
+	}
+
+	if err := s.journal.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to close mempool journal")
+	}
 }
 
+// AddTransaction admits a transaction to the pool under its own trace ID, if any
 func (s *Sequencer) AddTransaction(tx state.Transaction) error {
+	return s.AddTransactionContext(s.ctx, tx)
+}
+
+// AddTransactionContext admits a transaction to the pool and announces it to the other
+// nodes, which pull it so it reaches whichever node leads next. Private submissions are
+// not announced. A transaction without a trace ID takes the one carried by ctx, so it can
+// be followed through the logs of every node.
+func (s *Sequencer) AddTransactionContext(ctx context.Context, tx state.Transaction) error {
+	if tx.TraceID == "" {
+		tx.TraceID = trace.ID(ctx)
+	}
+	if err := s.addTransaction(tx); err != nil {
+		return err
+	}
+
+	s.announceTransactions([]state.Transaction{tx})
+
+	// A dev chain seals every transaction as soon as it arrives, otherwise a batch is cut
+	// once the pool fills one
+	if s.config.DevMode || s.batchFull() {
+		s.triggerBatch()
+	}
+	return nil
+}
+
+// addTransaction validates a transaction against the current state and adds it to the pool
+func (s *Sequencer) addTransaction(tx state.Transaction) error {
+	logger := trace.Logger(s.txContext(&tx))
+
 	s.poolMu.Lock()
 	defer s.poolMu.Unlock()
 
-	// Get or initialize the sender account
-	acc, err := s.state.GetAccount(tx.From)
-	if err != nil || acc == nil {
-		// If this is a new account, initialize it with a balance for testing
-		log.Info().Str("address", fmt.Sprintf("%x", tx.From)).Msg("Initializing new account with test balance")
-		acc = &state.Account{
-			Address: tx.From,
-			Balance: big.NewInt(1000), // Initialize with 1000 units
-			Nonce:   0,
-		}
-		s.state.SetAccount(acc)
-	} else if acc.Balance == nil || acc.Balance.Sign() == 0 {
-		// Ensure account has a balance
-		log.Info().Str("address", fmt.Sprintf("%x", tx.From)).Msg("Setting test balance for account")
-		acc.Balance = big.NewInt(1000) // Initialize with 1000 units
-		s.state.SetAccount(acc)
+	if s.halted.Load() {
+		return ErrHalted
 	}
 
-	// Initialize recipient account if needed
-	recipient, err := s.state.GetAccount(tx.To)
-	if err != nil || recipient == nil || recipient.Balance == nil {
-		log.Info().Str("address", fmt.Sprintf("%x", tx.To)).Msg("Initializing recipient account")
-		recipient = &state.Account{
-			Address: tx.To,
-			Balance: big.NewInt(0),
-			Nonce:   0,
-		}
-		s.state.SetAccount(recipient)
+	// Deposits are only ever created from L1 events
+	if tx.Type == state.TxTypeDeposit {
+		return ErrDepositNotAllowed
 	}
 
+	if err := s.checkChainID(&tx); err != nil {
+		return err
+	}
+
+	if err := validateTxType(&tx); err != nil {
+		return err
+	}
+
+	// Only the owner of the sending account may spend from it, with the scheme it signs with
+	if err := verifyAuth(s.state, &tx, s.SigningContract()); err != nil {
+		return err
+	}
+
+	// Validate against the sender as it will be seen at execution, including the test faucet.
+	// Admission never modifies state, every node must derive the same state from batches alone.
+	acc, _ := faucetAccount(s.state, tx.From)
+
 	// Basic transaction validation
 	if acc.Nonce >= tx.Nonce {
-		return fmt.Errorf("invalid nonce")
+		return fmt.Errorf("%w: %d, account nonce is %d", ErrInvalidNonce, tx.Nonce, acc.Nonce)
 	}
-	if acc.Balance.Cmp(tx.Amount) < 0 {
-		return fmt.Errorf("insufficient balance")
+	if err := checkCost(acc, &tx); err != nil {
+		return err
 	}
 
 	// Add transaction to pool
-	s.txPool = append(s.txPool, tx)
-	log.Info().Str("from", fmt.Sprintf("%x", tx.From)).Str("to", fmt.Sprintf("%x", tx.To)).Str("amount", tx.Amount.String()).Uint64("nonce", tx.Nonce).Msg("Added transaction to pool")
+	replaced, err := s.mempool.Add(tx)
+	if err != nil {
+		return err
+	}
+	if replaced {
+		logger.Info().Str("from", tx.From.Hex()).Uint64("nonce", tx.Nonce).Str("gas_price", gasPrice(&tx).String()).Msg("Replaced pending transaction")
+	}
+	if err := s.journal.Insert(tx); err != nil {
+		logger.Error().Err(err).Msg("Failed to journal transaction")
+	}
+	logger.Info().Str("from", tx.From.Hex()).Str("to", tx.To.Hex()).Str("amount", tx.Amount.String()).Uint64("nonce", tx.Nonce).Bool("private", tx.Private).Msg("Added transaction to pool")
+	s.txFeed.send(tx)
 
 	return nil
 }
 
 func (s *Sequencer) processBatches() {
-	ticker := time.NewTicker(time.Second * 15)
+	interval := time.Duration(s.config.BatchIntervalSeconds) * time.Second
+	if heartbeat := time.Duration(s.config.HeartbeatIntervalSeconds) * time.Second; heartbeat > 0 && heartbeat < interval {
+		interval = heartbeat
+	}
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -252,17 +723,41 @@ func (s *Sequencer) processBatches() {
 			return
 		case <-ticker.C:
 			s.tryCreateBatch()
+		case <-s.batchTrigger:
+			s.tryCreateBatch()
 		}
 	}
 }
 
 func (s *Sequencer) tryCreateBatch() {
-	s.poolMu.RLock()
-	txCount := len(s.txPool)
-	s.poolMu.RUnlock()
+	s.depositsMu.Lock()
+	txCount := s.mempool.Len() + len(s.deposits)
+	s.depositsMu.Unlock()
+
+	// An empty heartbeat batch keeps the chain moving when no transactions arrive, an
+	// operator forcing a batch proposes one the same way
+	heartbeat := s.heartbeatDue() || s.batchForced.Load()
+
+	// Check if we have enough transactions and are not already processing a batch, a dev
+	// chain does not wait for the batch to fill up
+	minTxs := int(s.config.BatchSize / 2)
+	if s.config.DevMode {
+		minTxs = 1
+	}
+	full := s.batchFull()
+	if (txCount < minTxs && !full && !heartbeat) || s.batchInProgress {
+		return
+	}
 
-	// Check if we have enough transactions and are not already processing a batch
-	if txCount < int(s.config.BatchSize/2) || s.batchInProgress {
+	// Batches cut early because the pool filled up keep the minimum spacing, the batch
+	// loop is woken again once it has passed
+	if wait := s.minBatchWait(); wait > 0 && !heartbeat {
+		if s.batchRetryPending.CompareAndSwap(false, true) {
+			time.AfterFunc(wait, func() {
+				s.batchRetryPending.Store(false)
+				s.triggerBatch()
+			})
+		}
 		return
 	}
 
@@ -272,6 +767,16 @@ func (s *Sequencer) tryCreateBatch() {
 		return
 	}
 
+	if s.halted.Load() {
+		log.Debug().Msg("Chain is halted, skipping batch creation")
+		return
+	}
+
+	if s.batchesPaused.Load() {
+		log.Debug().Msg("Batch production is paused, skipping batch creation")
+		return
+	}
+
 	s.batchMu.Lock()
 	defer s.batchMu.Unlock()
 
@@ -282,34 +787,76 @@ func (s *Sequencer) tryCreateBatch() {
 
 	// Mark that we're starting to process a batch
 	s.batchInProgress = true
+	s.batchForced.Store(false)
 
-	// Create a new batch with transactions from the pool
-	s.poolMu.Lock()
-	batchSize := min(txCount, int(s.config.BatchSize))
-	batchTxs := make([]state.Transaction, batchSize)
-	copy(batchTxs, s.txPool[:batchSize])
-	s.txPool = s.txPool[batchSize:]
-	s.poolMu.Unlock()
+	// Credit L1 deposits first so their funds can be spent in the same batch, then fill
+	// the batch with the highest paying transactions from the pool
+	batchTxs := s.pendingDepositTxs()
+	if len(batchTxs) > int(s.config.BatchSize) {
+		batchTxs = batchTxs[:s.config.BatchSize]
+	}
+	batchTxs = append(batchTxs, s.mempool.Pop(int(s.config.BatchSize)-len(batchTxs))...)
 
-	// Create the batch
-	batch := &state.Batch{
-		Transactions: batchTxs,
-		BatchNumber:  s.state.GetBatchNumber() + 1,
-		Timestamp:    uint64(time.Now().Unix()),
+	// Create the batch, dropping transactions that would fail and declaring the resulting state root
+	batch, err := s.buildBatch(batchTxs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build batch")
+		s.mempool.Requeue(batchTxs)
+		s.batchInProgress = false
+		return
+	}
+	if len(batch.Transactions) == 0 {
+		if !heartbeat {
+			s.batchInProgress = false
+			return
+		}
+		log.Info().Uint64("batch_number", batch.BatchNumber).Msg("Proposing heartbeat batch")
+	} else {
+		log.Info().Uint64("batch_number", batch.BatchNumber).Int("tx_count", len(batch.Transactions)).Strs(trace.BatchField, batch.TraceIDs()).Msg("Proposing batch")
 	}
 
 	// Store the current batch
 	s.currentBatch = batch
 
 	// Propose the batch for consensus
+	s.batchProposedAt = time.Now()
 	if err := s.consensus.ProposeBatch(batch); err != nil {
 		log.Error().Err(err).Msg("Failed to propose batch for consensus")
 		// Return transactions to the pool
-		s.poolMu.Lock()
-		s.txPool = append(batchTxs, s.txPool...)
-		s.poolMu.Unlock()
+		s.mempool.Requeue(batch.Transactions)
 		s.batchInProgress = false
+		s.batchProposedAt = time.Time{}
+	}
+}
+
+// batchFull reports whether the pool and pending deposits hold a full batch, or at least
+// BatchGasThreshold gas of transactions
+func (s *Sequencer) batchFull() bool {
+	s.depositsMu.Lock()
+	deposits := len(s.deposits)
+	s.depositsMu.Unlock()
+
+	if s.mempool.HasReady(int(s.config.BatchSize) - deposits) {
+		return true
 	}
+	return s.config.BatchGasThreshold > 0 && s.mempool.HasReadyGas(s.config.BatchGasThreshold)
+}
+
+// minBatchWait returns how long until MinBatchIntervalMillis has passed since the last
+// finalized batch
+func (s *Sequencer) minBatchWait() time.Duration {
+	interval := time.Duration(s.config.MinBatchIntervalMillis) * time.Millisecond
+	return interval - time.Since(time.Unix(0, s.lastBatchTime.Load()))
+}
+
+// heartbeatDue reports whether heartbeats are enabled and no batch was finalized for the
+// heartbeat interval
+func (s *Sequencer) heartbeatDue() bool {
+	if s.config.HeartbeatIntervalSeconds <= 0 {
+		return false
+	}
+	interval := time.Duration(s.config.HeartbeatIntervalSeconds) * time.Second
+	return time.Since(time.Unix(0, s.lastBatchTime.Load())) >= interval
 }
 
 func (s *Sequencer) participateConsensus() {
@@ -321,19 +868,55 @@ func (s *Sequencer) participateConsensus() {
 		case <-s.ctx.Done():
 			return
 		case batch := <-decidedBatchCh:
+			// A batch pulled from a peer may already have applied this decision
+			if batch.BatchNumber < s.state.GetBatchNumber() {
+				log.Info().Uint64("batch_number", batch.BatchNumber).Msg("Decided batch already applied")
+				s.batchMu.Lock()
+				s.batchInProgress = false
+				s.batchProposedAt = time.Time{}
+				s.currentBatch = nil
+				s.batchMu.Unlock()
+				continue
+			}
+
 			// Process batches that have been decided by consensus
 			log.Info().Msg("Received decided batch from consensus")
 			if err := s.processFinalizedBatch(*batch); err != nil {
 				log.Error().Err(err).Msg("Failed to process finalized batch")
 			}
 
+			// The proposer announces the finalized batch so peers that missed the vote catch up
+			if s.isLeader {
+				if err := s.outbox.Enqueue(s.state.GetBatchNumber() - 1); err != nil {
+					log.Error().Err(err).Msg("Failed to add finalized batch to outbox")
+				}
+			}
+
 			// Update leader status from consensus module
 			s.isLeader = s.consensus.IsLeader()
 			if s.isLeader {
 				log.Info().Msg("This node is now the leader and will propose the next batch")
+				s.triggerBatch()
 			} else {
 				log.Info().Msg("This node is not the leader for the next batch")
 			}
+			s.resumeConsensus()
+
+		case batch := <-s.syncedBatchCh:
+			// Apply batches from peers strictly in order, duplicates and gaps are ignored
+			if batch.BatchNumber != s.state.GetBatchNumber() {
+				continue
+			}
+			log.Info().Uint64("batch_number", batch.BatchNumber).Msg("Applying finalized batch received from peer")
+			if err := s.processFinalizedBatch(*batch); err != nil {
+				log.Error().Err(err).Msg("Failed to process finalized batch")
+			}
+			s.followProposerSchedule()
+			s.resumeConsensus()
+
+		case restore := <-s.syncedSnapshotCh:
+			restore.done <- s.restoreSnapshot(restore)
+			s.followProposerSchedule()
 		}
 	}
 }
@@ -341,7 +924,7 @@ func (s *Sequencer) participateConsensus() {
 // P2P message handlers
 func (s *Sequencer) handleTransaction(tx *state.Transaction) error {
 	// Log that we're handling a transaction
-	log.Info().Str("from", fmt.Sprintf("%x", tx.From)).Str("to", fmt.Sprintf("%x", tx.To)).Uint8("type", uint8(tx.Type)).Msg("Handling transaction in sequencer")
+	trace.Logger(s.txContext(tx)).Info().Str("from", tx.From.Hex()).Str("to", tx.To.Hex()).Uint8("type", uint8(tx.Type)).Msg("Handling transaction in sequencer")
 
 	// Special handling for zero values to ensure consistent hash computation
 	if tx.Amount != nil && tx.Amount.Sign() == 0 {
@@ -353,59 +936,62 @@ func (s *Sequencer) handleTransaction(tx *state.Transaction) error {
 	nonceStr := fmt.Sprintf("%d", tx.Nonce)
 	log.Info().Str("nonce_str", nonceStr).Msg("Using nonce string format for consistent hash computation")
 
-	// Verify transaction type-specific requirements
+	// Transactions gossiped before we caught up would be validated against stale state
+	if !s.IsSynced() {
+		return ErrNodeSyncing
+	}
+
+	// Add the transaction to the sequencer's pool. Announcements reach every node, so it is
+	// not passed on again, and copies submitted to several nodes are ignored.
+	if err := s.addTransaction(*tx); err != nil && !errors.Is(err, ErrAlreadyKnown) {
+		return err
+	}
+	return nil
+}
+
+// checkChainID refuses transactions signed for another rollup, and unprotected ones
+// unless the node allows them
+func (s *Sequencer) checkChainID(tx *state.Transaction) error {
+	if tx.ChainID == 0 {
+		if s.config.AllowUnprotectedTxs {
+			return nil
+		}
+		return fmt.Errorf("%w: unprotected transaction, sign with chain ID %d", ErrInvalidChainID, s.config.L2ChainID())
+	}
+	if tx.ChainID != s.config.L2ChainID() {
+		return fmt.Errorf("%w: got %d, want %d", ErrInvalidChainID, tx.ChainID, s.config.L2ChainID())
+	}
+	return nil
+}
+
+// validateTxType checks the requirements specific to a transaction's type
+func validateTxType(tx *state.Transaction) error {
 	switch tx.Type {
-	case state.TxTypeContractDeploy, state.TxTypeContractCall:
+	case state.TxTypeContractDeploy, state.TxTypeContractCreate, state.TxTypeContractCall:
 		// Ensure gas is provided for EVM transactions
 		if tx.Gas == 0 {
-			return errors.New("EVM transactions require gas")
+			return fmt.Errorf("%w: EVM transactions require gas", ErrGasTooLow)
 		}
 
 		// Ensure data is provided for contract deployment
 		if tx.Type == state.TxTypeContractDeploy && len(tx.Data) == 0 {
 			return errors.New("contract deployment requires bytecode")
 		}
+		if tx.Type == state.TxTypeContractCreate && len(tx.Data) <= state.SaltLength {
+			return errors.New("contract deployment requires a salt and bytecode")
+		}
 	}
-
-	// Add the transaction to the sequencer's pool
-	return s.AddTransaction(*tx)
+	return nil
 }
 
+// handleBatch passes a proposed batch body to consensus, pre-prepares only carry its hash
 func (s *Sequencer) handleBatch(batch *state.Batch) error {
-	log.Info().Msg("Received batch from peer")
-
-	if s.isLeader {
-		// Leader should propose the batch for consensus
-		log.Info().Msg("Leader proposing received batch for consensus")
-		s.batchMu.Lock()
-		s.currentBatch = batch
-		s.batchInProgress = true
-		s.batchMu.Unlock()
-
-		// Propose the batch for consensus
-		if err := s.consensus.ProposeBatch(batch); err != nil {
-			log.Error().Err(err).Msg("Failed to propose received batch for consensus")
-			// Reset batch state on error
-			s.batchMu.Lock()
-			s.batchInProgress = false
-			s.currentBatch = nil
-			s.batchMu.Unlock()
-			return err
-		}
-	} else {
-		// Non-leaders should store the batch and wait for consensus
-		log.Info().Msg("Non-leader received batch, storing for consensus")
-		s.batchMu.Lock()
-		s.currentBatch = batch
-		s.batchInProgress = true
-		s.batchMu.Unlock()
-	}
-	return nil
+	return s.consensus.HandleBatchBody(batch)
 }
 
-func (s *Sequencer) handleConsensus(msg []byte) error {
+func (s *Sequencer) handleConsensus(from peer.ID, msg []byte) error {
 	// Forward consensus messages to the PBFT consensus module
-	return s.consensus.HandleMessage(msg)
+	return s.consensus.HandleMessage(from, msg)
 }
 
 // BroadcastConsensusMessage broadcasts a consensus message to the network
@@ -419,54 +1005,63 @@ func (s *Sequencer) BroadcastConsensusMessage(msg interface{}) error {
 }
 
 func (s *Sequencer) processFinalizedBatch(batch state.Batch) error {
-	log.Info().Int("tx_count", len(batch.Transactions)).Msg("Processing finalized batch")
+	log.Info().Int("tx_count", len(batch.Transactions)).Strs(trace.BatchField, batch.TraceIDs()).Msg("Processing finalized batch")
 
-	// Process each transaction in the batch
-	for _, tx := range batch.Transactions {
-		var err error
+	// The proof covers the transition from the current root
+	var oldRoot [32]byte
+	if s.proofs != nil {
+		oldRoot = s.state.GetStateRoot()
+	}
 
-		// Get the sender account
-		sender, err := s.state.GetAccount(tx.From)
-		if err != nil {
-			log.Error().Err(err).Str("from", common.BytesToAddress(tx.From[:]).Hex()).Msg("Failed to get sender account")
-			continue
-		}
+	// Apply the batch, a transaction failing here is skipped like a reverted transaction
+	s.applyBatch(s.state, &batch, false)
 
-		// Process transaction based on type
-		switch tx.Type {
-		case state.TxTypeTransfer:
-			err = s.processTransferTransaction(tx, sender)
-		case state.TxTypeContractDeploy:
-			err = s.processContractDeployment(tx, sender)
-		case state.TxTypeContractCall:
-			err = s.processContractCall(tx, sender)
-		default:
-			err = fmt.Errorf("unknown transaction type: %d", tx.Type)
-		}
-
-		if err != nil {
-			log.Error().Err(err).Str("tx_hash", common.BytesToHash(tx.HashToBytes()).Hex()).Msg("Failed to process transaction")
-			continue
-		}
+	// Commit to the post-batch state so L1 and light clients can check account proofs
+	start := time.Now()
+	root := s.state.GetStateRoot()
+	log.Debug().Dur("duration", s.stateRootTiming.Since(start)).Msg("Computed state root")
+	if batch.StateRoot != ([32]byte{}) && batch.StateRoot != root {
+		log.Error().Uint64("batch_number", batch.BatchNumber).Str("declared", fmt.Sprintf("%x", batch.StateRoot)).Str("computed", fmt.Sprintf("%x", root)).Msg("State root differs from the agreed batch")
 	}
+	batch.StateRoot = root
 
 	// Update batch number in state
 	s.state.AddBatch(&batch)
 
+	// Followers hold the gossiped copies of the transactions the leader included
+	s.mempool.RemoveIncluded(batch.Transactions)
+
+	// Check the promises whose deadline was this batch
+	s.settlePreconfirmations()
+
+	// Persist the batch and every state change it made in one write, as often as the
+	// commit policy asks
+	if err := s.state.Commit(); err != nil {
+		log.Error().Err(err).Uint64("batch_number", batch.BatchNumber).Msg("Failed to persist state")
+	}
+	s.takeFastSyncSnapshot()
+
 	// Mark batch processing as complete
 	s.batchMu.Lock()
+	if !s.batchProposedAt.IsZero() {
+		s.nodeMetrics.BatchLatency.Observe(time.Since(s.batchProposedAt).Seconds())
+		s.batchProposedAt = time.Time{}
+	}
 	s.batchInProgress = false
 	s.currentBatch = nil
 	s.batchMu.Unlock()
+	s.lastBatchTime.Store(time.Now().UnixNano())
 
-	// Submit batch to L1 if enabled
-	if s.l1Enabled && s.l1SubmitChan != nil {
-		select {
-		case s.l1SubmitChan <- batch:
-			log.Info().Msg("Submitted batch to L1 submission queue")
-		default:
-			log.Warn().Msg("L1 submission queue is full, skipping this batch")
-		}
+	// Transactions that arrived while the batch was in consensus go into the next one
+	if (s.config.DevMode && s.mempool.HasReady(1)) || s.batchFull() {
+		s.triggerBatch()
+	}
+
+	// Prove the batch in the background, it is submitted to L1 once proven
+	if s.proofs != nil {
+		s.enqueueProof(batch, oldRoot)
+	} else {
+		s.submitToL1(batch, submitWithProof)
 	}
 
 	// Add a small delay after processing a batch to prevent rapid leader rotation
@@ -476,11 +1071,35 @@ func (s *Sequencer) processFinalizedBatch(batch state.Batch) error {
 	return nil
 }
 
+// resumeConsensus votes on proposals for the next batch that arrived before the batch just
+// applied. It runs once leadership is updated for the applied batch, a round decided earlier
+// would be overridden. Not from the main loop, deciding waits for it to take the batch.
+func (s *Sequencer) resumeConsensus() {
+	go s.consensus.ResumeRounds()
+}
+
+// submitToL1 queues a finalized batch for L1 submission if enabled, heartbeats only when configured
+func (s *Sequencer) submitToL1(batch state.Batch, kind l1SubmissionKind) {
+	if len(batch.Transactions) == 0 && !s.config.HeartbeatPostToL1 {
+		return
+	}
+	if !s.l1Enabled || s.l1SubmitChan == nil {
+		return
+	}
+
+	select {
+	case s.l1SubmitChan <- l1Submission{batch: batch, kind: kind}:
+		log.Info().Uint64("batch_number", batch.BatchNumber).Strs(trace.BatchField, batch.TraceIDs()).Msg("Submitted batch to L1 submission queue")
+	default:
+		log.Warn().Uint64("batch_number", batch.BatchNumber).Strs(trace.BatchField, batch.TraceIDs()).Msg("L1 submission queue is full, skipping this batch")
+	}
+}
+
 // processTransferTransaction processes a simple token transfer transaction
-func (s *Sequencer) processTransferTransaction(tx state.Transaction, sender *state.Account) error {
-	// Verify balance
-	if sender.Balance.Cmp(tx.Amount) < 0 {
-		return fmt.Errorf("insufficient balance: have %s, need %s", sender.Balance.String(), tx.Amount.String())
+func (s *Sequencer) processTransferTransaction(st *state.State, tx state.Transaction, sender *state.Account) error {
+	// Verify balance, including the transfer's gas
+	if err := checkCost(sender, &tx); err != nil {
+		return err
 	}
 
 	// Handle zero values consistently as per memory requirements
@@ -489,12 +1108,13 @@ func (s *Sequencer) processTransferTransaction(tx state.Transaction, sender *sta
 		tx.Amount = big.NewInt(0)
 	}
 
-	// Update sender balance
+	// Update sender balance and consume its nonce
 	sender.Balance = new(big.Int).Sub(sender.Balance, tx.Amount)
-	s.state.SetAccount(sender)
+	sender.Nonce++
+	st.SetAccount(sender)
 
 	// Update recipient account
-	recipient, err := s.state.GetAccount(tx.To)
+	recipient, err := st.GetAccount(tx.To)
 	if err != nil || recipient == nil {
 		// Create recipient account if it doesn't exist
 		recipient = &state.Account{
@@ -506,17 +1126,25 @@ func (s *Sequencer) processTransferTransaction(tx state.Transaction, sender *sta
 		// Add amount to existing balance
 		recipient.Balance = new(big.Int).Add(recipient.Balance, tx.Amount)
 	}
-	s.state.SetAccount(recipient)
+	st.SetAccount(recipient)
+
+	// Funds sent to the withdrawal address can be claimed on L1 by the sender
+	if tx.To == state.WithdrawalAddress && tx.Amount.Sign() > 0 {
+		st.RecordWithdrawal(tx.From, tx.Amount)
+		trace.Logger(s.txContext(&tx)).Info().Str("account", tx.From.Hex()).Str("amount", tx.Amount.String()).Msg("Recorded withdrawal")
+	}
+
+	s.chargeGas(st, &tx, params.TxGas)
 
-	log.Info().Str("from", formatAddress(tx.From)).Str("to", formatAddress(tx.To)).Str("amount", tx.Amount.String()).Msg("Applied transfer transaction")
+	trace.Logger(s.txContext(&tx)).Info().Str("from", tx.From.Hex()).Str("to", tx.To.Hex()).Str("amount", tx.Amount.String()).Msg("Applied transfer transaction")
 	return nil
 }
 
 // processContractDeployment processes a contract deployment transaction
-func (s *Sequencer) processContractDeployment(tx state.Transaction, sender *state.Account) error {
-	// Verify balance for the value being sent with contract creation
-	if sender.Balance.Cmp(tx.Amount) < 0 {
-		return fmt.Errorf("insufficient balance for contract deployment: have %s, need %s", sender.Balance.String(), tx.Amount.String())
+func (s *Sequencer) processContractDeployment(ctx context.Context, st *state.State, tx state.Transaction, sender *state.Account) error {
+	// Verify balance for the value being sent with contract creation and its gas
+	if err := checkCost(sender, &tx); err != nil {
+		return fmt.Errorf("contract deployment: %w", err)
 	}
 
 	// Special handling for zero values to ensure consistent message hash computation
@@ -526,45 +1154,63 @@ func (s *Sequencer) processContractDeployment(tx state.Transaction, sender *stat
 	}
 
 	// Create EVM state adapter
-	stateAdapter := evm.NewStateAdapter(s.state)
+	stateAdapter := evm.NewStateAdapter(st)
 
 	// Convert addresses to Ethereum format
-	callerAddr := common.BytesToAddress(tx.From[:])
+	callerAddr := tx.From.Common()
 
-	// Deploy the contract
-	contractAddr, remainingGas, err := s.evmExecutor.DeployContract(
-		stateAdapter,
-		callerAddr,
-		tx.Amount,
-		tx.Gas,
-		tx.Data,
+	// Deploy the contract, at the address of the salt leading the data for CREATE2
+	var (
+		contractAddr common.Address
+		remainingGas uint64
+		err          error
 	)
+	if tx.Type == state.TxTypeContractCreate {
+		if len(tx.Data) <= state.SaltLength {
+			return errors.New("contract deployment requires a salt and bytecode")
+		}
+		var salt [32]byte
+		copy(salt[:], tx.Data[:state.SaltLength])
+		contractAddr, remainingGas, err = s.evmExecutor.DeployContract2(
+			ctx,
+			stateAdapter,
+			callerAddr,
+			tx.Amount,
+			tx.Gas,
+			tx.Data[state.SaltLength:],
+			salt,
+		)
+	} else {
+		contractAddr, remainingGas, err = s.evmExecutor.DeployContract(
+			ctx,
+			stateAdapter,
+			callerAddr,
+			tx.Amount,
+			tx.Gas,
+			tx.Data,
+		)
+	}
 
 	if err != nil {
+		s.consumeFailedCall(st, &tx, sender, tx.Gas-remainingGas, err)
 		return fmt.Errorf("contract deployment failed: %w", err)
 	}
 
-	// Convert contract address back to rollup format
-	var contractRollupAddr [20]byte
-	copy(contractRollupAddr[:], contractAddr.Bytes())
-
-	// Update sender account
-	sender.Balance = new(big.Int).Sub(sender.Balance, tx.Amount)
-	sender.Nonce++
-	s.state.SetAccount(sender)
-
-	// Apply all state changes from the EVM execution
+	// Apply all state changes from the EVM execution, which include the value sent and
+	// the sender's nonce, then charge the gas it used
 	stateAdapter.ApplyChanges()
+	s.chargeGas(st, &tx, tx.Gas-remainingGas)
+	st.SetTxContract(types.FromCommon(contractAddr))
 
-	log.Info().Str("from", formatAddress(tx.From)).Str("contract", contractAddr.Hex()).Str("gas_used", fmt.Sprintf("%d", tx.Gas-remainingGas)).Msg("Deployed contract")
+	trace.Logger(ctx).Info().Str("from", tx.From.Hex()).Str("contract", contractAddr.Hex()).Str("gas_used", fmt.Sprintf("%d", tx.Gas-remainingGas)).Msg("Deployed contract")
 	return nil
 }
 
 // processContractCall processes a contract call transaction
-func (s *Sequencer) processContractCall(tx state.Transaction, sender *state.Account) error {
-	// Verify balance for the value being sent with the call
-	if sender.Balance.Cmp(tx.Amount) < 0 {
-		return fmt.Errorf("insufficient balance for contract call: have %s, need %s", sender.Balance.String(), tx.Amount.String())
+func (s *Sequencer) processContractCall(ctx context.Context, st *state.State, tx state.Transaction, sender *state.Account) error {
+	// Verify balance for the value being sent with the call and its gas
+	if err := checkCost(sender, &tx); err != nil {
+		return fmt.Errorf("contract call: %w", err)
 	}
 
 	// Special handling for zero values to ensure consistent message hash computation
@@ -574,14 +1220,15 @@ func (s *Sequencer) processContractCall(tx state.Transaction, sender *state.Acco
 	}
 
 	// Create EVM state adapter
-	stateAdapter := evm.NewStateAdapter(s.state)
+	stateAdapter := evm.NewStateAdapter(st)
 
 	// Convert addresses to Ethereum format
-	callerAddr := common.BytesToAddress(tx.From[:])
-	contractAddr := common.BytesToAddress(tx.To[:])
+	callerAddr := tx.From.Common()
+	contractAddr := tx.To.Common()
 
 	// Execute the contract call
 	returnData, remainingGas, err := s.evmExecutor.ExecuteContract(
+		ctx,
 		stateAdapter,
 		callerAddr,
 		contractAddr,
@@ -591,17 +1238,28 @@ func (s *Sequencer) processContractCall(tx state.Transaction, sender *state.Acco
 	)
 
 	if err != nil {
+		s.consumeFailedCall(st, &tx, sender, tx.Gas-remainingGas, err)
 		return fmt.Errorf("contract call failed: %w", err)
 	}
 
-	// Update sender account
-	sender.Balance = new(big.Int).Sub(sender.Balance, tx.Amount)
-	sender.Nonce++
-	s.state.SetAccount(sender)
-
-	// Apply all state changes from the EVM execution
+	// Apply all state changes from the EVM execution, which include the value sent and
+	// the sender's nonce, then charge the gas it used
 	stateAdapter.ApplyChanges()
+	s.chargeGas(st, &tx, tx.Gas-remainingGas)
 
-	log.Info().Str("from", formatAddress(tx.From)).Str("contract", contractAddr.Hex()).Str("gas_used", fmt.Sprintf("%d", tx.Gas-remainingGas)).Int("return_data_size", len(returnData)).Msg("Called contract")
+	trace.Logger(ctx).Info().Str("from", tx.From.Hex()).Str("contract", contractAddr.Hex()).Str("gas_used", fmt.Sprintf("%d", tx.Gas-remainingGas)).Int("return_data_size", len(returnData)).Msg("Called contract")
 	return nil
 }
+
+// consumeFailedCall charges a call that ran and failed. Its state changes were reverted, but
+// it still consumes the sender's nonce and the gas it used, all of it when it ran out of gas
+// or exceeded an execution limit. A call stopped by the wall-clock limit is not charged, it
+// is not part of any batch.
+func (s *Sequencer) consumeFailedCall(st *state.State, tx *state.Transaction, sender *state.Account, gasUsed uint64, err error) {
+	if !errors.Is(err, evm.ErrExecutionFailed) {
+		return
+	}
+	sender.Nonce++
+	st.SetAccount(sender)
+	s.chargeGas(st, tx, gasUsed)
+}
@@ -0,0 +1,82 @@
+package sequencer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/signer"
+)
+
+// nodeSigners are the keys a node signs with, nil when a key is not configured
+type nodeSigners struct {
+	consensus signer.Signer
+	l1        signer.Signer
+	pinned    map[string]common.Address // Signing address of each validator by node ID
+}
+
+// openSigners sets up the consensus and L1 signers. Keys with an address are held by the
+// remote signer at SignerURL, the consensus key may instead be given in the config. The L1
+// key may also be read from a keystore file or held by AWS KMS.
+func openSigners(ctx context.Context, config *core.Config) (*nodeSigners, error) {
+	signers := &nodeSigners{pinned: make(map[string]common.Address, len(config.ConsensusSigners))}
+
+	remote := func(address string) (signer.Signer, error) {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid signer address %q", address)
+		}
+		if config.SignerAPI == "clef" {
+			return signer.NewClefSigner(ctx, config.SignerURL, common.HexToAddress(address))
+		}
+		return signer.NewRemoteSigner(ctx, config.SignerURL, common.HexToAddress(address))
+	}
+
+	var err error
+	switch {
+	case config.ConsensusSignerAddress != "":
+		if signers.consensus, err = remote(config.ConsensusSignerAddress); err != nil {
+			return nil, err
+		}
+	case config.ConsensusPrivateKey != "":
+		if signers.consensus, err = signer.NewLocalSignerFromHex(config.ConsensusPrivateKey); err != nil {
+			return nil, fmt.Errorf("invalid consensus key: %v", err)
+		}
+	}
+	switch {
+	case config.L1SignerAddress != "":
+		if signers.l1, err = remote(config.L1SignerAddress); err != nil {
+			return nil, err
+		}
+	case config.L1KeystoreFile != "":
+		var password []byte
+		if config.L1KeystorePasswordFile != "" {
+			if password, err = os.ReadFile(config.L1KeystorePasswordFile); err != nil {
+				return nil, fmt.Errorf("failed to read keystore password file: %v", err)
+			}
+		}
+		if signers.l1, err = signer.NewKeystoreSigner(config.L1KeystoreFile, strings.TrimRight(string(password), "\r\n")); err != nil {
+			return nil, err
+		}
+	case config.L1KMSKeyID != "":
+		signers.l1, err = signer.NewKMSSigner(ctx, signer.KMSConfig{
+			KeyID:    config.L1KMSKeyID,
+			Region:   config.KMSRegion,
+			Endpoint: config.KMSEndpoint,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for nodeID, address := range config.ConsensusSigners {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid signer address %q for node %s", address, nodeID)
+		}
+		signers.pinned[nodeID] = common.HexToAddress(address)
+	}
+	return signers, nil
+}
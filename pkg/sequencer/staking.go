@@ -0,0 +1,78 @@
+package sequencer
+
+import (
+	"encoding/json"
+	"maps"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/consensus"
+)
+
+// watchStakedValidators follows the validators bonded on the Staking contract. Like the
+// validator registry a changed set takes over once the next batch is decided.
+func (s *Sequencer) watchStakedValidators() {
+	ticker := time.NewTicker(validatorRegistryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.refreshStakedValidators()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshStakedValidators reads the staked validators, schedules them on the consensus and
+// pins the address each must sign with. Validators that left stay pinned, so their
+// messages are still checked until the new set takes over.
+func (s *Sequencer) refreshStakedValidators() {
+	validators, err := s.staking.Validators(s.ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read staked validators")
+		return
+	}
+	if len(validators) == 0 {
+		log.Warn().Msg("No validators staked, keeping the current validator set")
+		return
+	}
+
+	peerIDs := make([]string, len(validators))
+	for i, v := range validators {
+		peerIDs[i] = v.PeerID
+		s.stakedSigners[v.PeerID] = v.Address
+	}
+	signers := maps.Clone(s.stakedSigners)
+	maps.Copy(signers, s.configuredSigners)
+	s.consensus.SetMessageSigners(signers)
+	s.consensus.ScheduleValidators(peerIDs)
+}
+
+// handleDoubleSign is told of every validator caught signing conflicting pre-prepares and
+// reports it on the Staking contract when one is configured
+func (s *Sequencer) handleDoubleSign(evidence *consensus.DoubleSignEvidence) {
+	s.nodeMetrics.DoubleSigns.Inc()
+	if s.staking == nil {
+		log.Error().Str("node", evidence.First.NodeID).Str("signer", evidence.Signer.Hex()).Msg("Validator double signed, no staking contract to report it to")
+		return
+	}
+
+	data, err := json.Marshal(evidence)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode double sign evidence")
+		return
+	}
+	go func(validator common.Address) {
+		tx, err := s.staking.ReportDoubleSign(s.ctx, validator, data)
+		if err != nil {
+			log.Error().Err(err).Str("signer", validator.Hex()).Msg("Failed to report double signing")
+			return
+		}
+		log.Info().Str("signer", validator.Hex()).Str("tx_hash", tx.Hash().Hex()).Msg("Reported double signing to the staking contract")
+	}(evidence.Signer)
+}
@@ -0,0 +1,153 @@
+package sequencer
+
+import (
+	"time"
+
+	"zkrollup/pkg/crypto"
+	"zkrollup/pkg/state"
+)
+
+// NodeStatus is an operational overview of the node
+type NodeStatus struct {
+	NodeID           string
+	BatchNumber      uint64 // Batches finalized locally
+	StateRoot        [32]byte
+	Sync             SyncStatus
+	Halted           bool
+	BatchesPaused    bool // Batch production paused by an operator
+	PoolSize         int  // Transactions ready to execute
+	PoolQueued       int  // Transactions waiting for a lower nonce
+	ProverQueueDepth int
+	ProverProgress   []crypto.ProofProgress // Batches queued or being proven, with their stage
+	Peers            []string
+	Consensus        ConsensusStatus
+	L1               L1Status
+}
+
+// ConsensusStatus summarizes this node's view of consensus
+type ConsensusStatus struct {
+	Leader           bool
+	Validators       int    // Nodes counted towards the quorum
+	Quorum           int    // Votes needed to decide a batch
+	HighestSeenBatch uint64 // Highest batch number proposed by any peer
+	PendingRound     time.Duration
+	PendingRounds    int // Consensus rounds held in memory
+
+	// Batch number of the latest stable checkpoint, nil before the first one
+	StableCheckpoint *uint64
+
+	// Node IDs of the validators, and of the set taking over after the next decided batch
+	ValidatorSet        []string
+	ScheduledValidators []string
+}
+
+// L1Status summarizes batch submission to L1
+type L1Status struct {
+	Enabled bool
+	Backlog int // Submissions queued but not sent yet
+
+	// Emergency state set by the contract guardian
+	Paused       bool
+	BridgeFrozen bool
+
+	// Submission paused by an operator
+	SubmissionPaused bool
+
+	// Batches from the first that are final on L1, and the highest batch accepted by the
+	// contract plus one, as reported by its events
+	FinalizedBatches uint64
+	SubmittedBatches uint64
+
+	// Most recent submission, LastAt is zero before the first one
+	LastBatch uint64
+	LastKind  string
+	LastAt    time.Time
+	LastError string
+}
+
+// l1Outcome records the result of an L1 submission
+type l1Outcome struct {
+	batch uint64
+	kind  l1SubmissionKind
+	at    time.Time
+	err   error
+}
+
+// recordL1Outcome remembers the result of the latest L1 submission
+func (s *Sequencer) recordL1Outcome(batchNumber uint64, kind l1SubmissionKind, err error) {
+	s.l1LastMu.Lock()
+	defer s.l1LastMu.Unlock()
+	s.l1Last = l1Outcome{batch: batchNumber, kind: kind, at: time.Now(), err: err}
+	s.nodeMetrics.ObserveL1Submission(kind.String(), err)
+}
+
+// Status returns an overview of the node's batches, pool, peers, consensus and L1 state
+func (s *Sequencer) Status() NodeStatus {
+	status := NodeStatus{
+		NodeID:           s.node.Host.ID().String(),
+		BatchNumber:      s.state.GetBatchNumber(),
+		StateRoot:        s.state.GetStateRoot(),
+		Sync:             s.SyncStatus(),
+		Halted:           s.IsHalted(),
+		BatchesPaused:    s.BatchesPaused(),
+		PoolSize:         s.mempool.Len(),
+		PoolQueued:       s.mempool.Queued(),
+		ProverQueueDepth: s.ProverQueueDepth(),
+		ProverProgress:   s.ProofProgress(),
+		Consensus: ConsensusStatus{
+			Leader:           s.consensus.IsLeader(),
+			Validators:       s.consensus.TotalNodes(),
+			Quorum:           s.consensus.Quorum(),
+			HighestSeenBatch: s.consensus.HighestSeenBatch(),
+			PendingRound:     s.PendingConsensusRound(),
+			PendingRounds:    s.consensus.PendingRounds(),
+		},
+		L1: L1Status{
+			Enabled:      s.l1Enabled,
+			Backlog:      s.L1SubmissionBacklog(),
+			Paused:       s.L1Paused(),
+			BridgeFrozen: s.L1BridgeFrozen(),
+
+			SubmissionPaused: s.L1SubmissionPaused(),
+		},
+	}
+
+	status.L1.FinalizedBatches, status.L1.SubmittedBatches = s.l1Finality.counts()
+	status.Consensus.ValidatorSet, status.Consensus.ScheduledValidators = s.consensus.Validators()
+	if cp := s.consensus.LatestCheckpoint(); cp != nil {
+		status.Consensus.StableCheckpoint = &cp.BatchNumber
+	}
+
+	for _, p := range s.node.GetPeers() {
+		status.Peers = append(status.Peers, p.String())
+	}
+
+	s.l1LastMu.Lock()
+	last := s.l1Last
+	s.l1LastMu.Unlock()
+	if !last.at.IsZero() {
+		status.L1.LastBatch = last.batch
+		status.L1.LastKind = last.kind.String()
+		status.L1.LastAt = last.at
+		if last.err != nil {
+			status.L1.LastError = last.err.Error()
+		}
+	}
+
+	return status
+}
+
+// RecentBatches returns up to count of the most recently finalized batches, newest first
+func (s *Sequencer) RecentBatches(count int) []state.Batch {
+	head := s.state.GetBatchNumber()
+	from := uint64(0)
+	if head > uint64(count) {
+		from = head - uint64(count)
+	}
+
+	batches := s.state.GetBatches(from, head-from)
+	for i, j := 0, len(batches)-1; i < j; i, j = i+1, j-1 {
+		batches[i], batches[j] = batches[j], batches[i]
+	}
+	return batches
+}
@@ -0,0 +1,198 @@
+package sequencer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/state"
+)
+
+// ErrNodeSyncing is returned for transactions submitted before the node has caught up with the network
+var ErrNodeSyncing = errors.New("node syncing")
+
+const syncCheckInterval = 2 * time.Second
+
+// SyncStatus describes how far the local state is behind the network
+type SyncStatus struct {
+	Syncing      bool
+	CurrentBatch uint64 // Batches applied locally
+	HighestBatch uint64 // Best known network head
+}
+
+// IsSynced reports whether the node has caught up and may accept transactions
+func (s *Sequencer) IsSynced() bool {
+	return s.synced.Load()
+}
+
+// SyncStatus returns the current sync progress
+func (s *Sequencer) SyncStatus() SyncStatus {
+	current := s.state.GetBatchNumber()
+	highest := s.networkHead()
+	if highest < current {
+		highest = current
+	}
+
+	return SyncStatus{
+		Syncing:      !s.IsSynced(),
+		CurrentBatch: current,
+		HighestBatch: highest,
+	}
+}
+
+// networkHead returns the number of batches the network is known to have finalized,
+// taken from proposals seen in consensus and, when enabled, the L1 contract
+func (s *Sequencer) networkHead() uint64 {
+	// Batches are numbered from zero, so a proposal for batch n means n batches were finalized before it
+	head := s.consensus.HighestSeenBatch()
+
+	if s.l1Enabled && s.l1Client != nil {
+		ctx, cancel := context.WithTimeout(s.ctx, syncCheckInterval)
+		defer cancel()
+
+		l1Head, err := s.l1Client.CurrentBatchNumber(ctx)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to read batch number from L1")
+		} else if l1Head > head {
+			head = l1Head
+		}
+	}
+
+	return head
+}
+
+// waitForSync holds the node in syncing mode for the warm-up period and then until the
+// local state has reached the network head
+func (s *Sequencer) waitForSync() {
+	warmup := time.Duration(s.config.SyncWarmupSeconds) * time.Second
+	log.Info().Dur("warmup", warmup).Msg("Node syncing, not accepting transactions yet")
+
+	select {
+	case <-s.ctx.Done():
+		return
+	case <-time.After(warmup):
+	}
+
+	ticker := time.NewTicker(syncCheckInterval)
+	defer ticker.Stop()
+
+	fastSync := s.config.FastSync && s.l1Client != nil
+	snapshotSynced := !s.config.StateSyncEnabled && !fastSync
+	for {
+		current := s.state.GetBatchNumber()
+		head := s.networkHead()
+		if current < head && !snapshotSynced {
+			// Fast sync needs a peer with a snapshot L1 finalized, state sync serves any peer
+			if fastSync {
+				snapshotSynced = s.fastSyncFromPeers(current)
+			}
+			if !snapshotSynced && s.config.StateSyncEnabled {
+				snapshotSynced = s.syncStateFromPeers(current)
+			}
+			current = s.state.GetBatchNumber()
+		}
+		if current >= head {
+			s.synced.Store(true)
+			log.Info().Uint64("batch_number", current).Uint64("network_head", head).Msg("Node synced, accepting transactions")
+			go s.restoreMempool()
+			go s.announceMempool()
+			s.followProposerSchedule()
+			return
+		}
+
+		log.Info().Uint64("batch_number", current).Uint64("network_head", head).Msg("Node behind network head, still syncing")
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// snapshotSync is a state snapshot and the batch history leading up to it, downloaded
+// from a peer and waiting to be restored. A fast synced snapshot comes without history.
+type snapshotSync struct {
+	snapshot       *state.Snapshot
+	batches        []state.Batch
+	withoutHistory bool
+	done           chan error
+}
+
+// syncStateFromPeers downloads the state snapshot of the first peer ahead of us and hands
+// it to the consensus loop for restoring. It reports whether a snapshot was restored.
+func (s *Sequencer) syncStateFromPeers(from uint64) bool {
+	for _, peerID := range s.node.GetPeers() {
+		snap, batches, err := s.node.SyncState(s.ctx, peerID, from)
+		if err != nil {
+			log.Warn().Err(err).Str("peer", peerID.String()).Msg("State sync from peer failed")
+			continue
+		}
+		if snap.BatchNumber <= from {
+			continue
+		}
+
+		restore := &snapshotSync{snapshot: snap, batches: batches, done: make(chan error, 1)}
+		select {
+		case s.syncedSnapshotCh <- restore:
+		case <-s.ctx.Done():
+			return false
+		}
+
+		if err := <-restore.done; err != nil {
+			log.Warn().Err(err).Str("peer", peerID.String()).Msg("Failed to restore state snapshot from peer")
+			continue
+		}
+
+		log.Info().Str("peer", peerID.String()).Uint64("batch_number", snap.BatchNumber).Int("synced_batches", len(batches)).Msg("Restored state snapshot from peer")
+		return true
+	}
+	return false
+}
+
+// restoreSnapshot replaces the local state with a snapshot from a peer. Batches applied
+// while the snapshot was downloading are kept, a snapshot we have already passed is ignored.
+// A fast synced snapshot replaces the local batch history instead.
+func (s *Sequencer) restoreSnapshot(restore *snapshotSync) error {
+	snap, batches := restore.snapshot, restore.batches
+	local := s.state.GetBatchNumber()
+	if snap.BatchNumber <= local {
+		return nil
+	}
+	if len(batches) > 0 && batches[0].BatchNumber < local {
+		skip := local - batches[0].BatchNumber
+		if skip > uint64(len(batches)) {
+			skip = uint64(len(batches))
+		}
+		batches = batches[skip:]
+	}
+
+	var err error
+	if restore.withoutHistory {
+		err = s.state.RestoreSnapshotWithoutHistory(snap)
+	} else {
+		err = s.state.RestoreSnapshot(snap, batches)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.state.Flush(); err != nil {
+		return fmt.Errorf("failed to persist state snapshot: %v", err)
+	}
+
+	s.batchMu.Lock()
+	s.batchInProgress = false
+	s.batchProposedAt = time.Time{}
+	s.currentBatch = nil
+	s.batchMu.Unlock()
+	s.lastBatchTime.Store(time.Now().UnixNano())
+	return nil
+}
+
+// handleSnapshotRequest serves the current account state to a peer bootstrapping through state sync
+func (s *Sequencer) handleSnapshotRequest() (*state.Snapshot, error) {
+	return s.state.Snapshot(), nil
+}
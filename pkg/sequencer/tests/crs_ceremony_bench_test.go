@@ -111,11 +111,13 @@ func TestCRSCeremonyPerformance(t *testing.T) {
 		t.Logf("CRS ceremony with %d participants took %.2fs", n, elapsed)
 	}
 
-	f, err := os.Create("crs_ceremony_performance.csv")
+	csvFile := benchResultPath(t, "crs_ceremony_performance.csv")
+	f, err := os.Create(csvFile)
 	require.NoError(t, err)
 	defer f.Close()
 	fmt.Fprintln(f, "participants,duration_seconds")
 	for _, r := range results {
 		fmt.Fprintf(f, "%d,%.4f\n", r.Participants, r.Duration)
 	}
+	t.Logf("CRS ceremony results written to %s", csvFile)
 }
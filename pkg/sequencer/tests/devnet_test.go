@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/devnet"
+	"zkrollup/pkg/evm"
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+func TestDevAccountsAreDeterministic(t *testing.T) {
+	accounts := sequencer.DevAccounts(3)
+	require.Len(t, accounts, 3)
+	require.Equal(t, accounts, sequencer.DevAccounts(3))
+
+	seen := make(map[types.Address]bool)
+	for _, acc := range accounts {
+		require.Equal(t, acc.Address, types.FromCommon(crypto.PubkeyToAddress(acc.PrivateKey.PublicKey)))
+		require.False(t, seen[acc.Address])
+		seen[acc.Address] = true
+	}
+
+	// Asking for more accounts extends the list without changing the first ones
+	require.Equal(t, accounts, sequencer.DevAccounts(5)[:3])
+}
+
+func TestDevnetNodesAgreeOnBatches(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs three nodes against a simulated L1")
+	}
+
+	d, err := devnet.New(devnet.Options{Nodes: 3})
+	require.NoError(t, err)
+	defer d.Close()
+
+	// Transfers sent to different nodes end up in batches every node finalizes
+	from, to := d.Accounts[0], d.Accounts[1]
+	for nonce := uint64(1); nonce <= 3; nonce++ {
+		node := d.Nodes[int(nonce)%len(d.Nodes)]
+		_, err := node.Transfer(from, to.Address, big.NewInt(1000), nonce)
+		require.NoError(t, err)
+	}
+
+	want := new(big.Int).Add(sequencer.DevAccountBalance, big.NewInt(3000))
+	for _, node := range d.Nodes {
+		require.Eventually(t, func() bool {
+			acc, err := node.Sequencer.GetAccount(to.Address)
+			return err == nil && acc.Balance.Cmp(want) == 0
+		}, time.Minute, 100*time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, d.WaitForBatch(ctx, d.Nodes[0].Sequencer.BatchNumber()))
+	require.NoError(t, d.CheckStateRoots())
+}
+
+func TestDevnetNodesAgreeOnOverLimitCalls(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs three nodes against a simulated L1")
+	}
+
+	config := core.DevConfig()
+	config.EVMMaxSteps = 1000
+	d, err := devnet.New(devnet.Options{Config: config})
+	require.NoError(t, err)
+	defer d.Close()
+
+	leader, replica := d.Nodes[0], d.Nodes[1]
+	from := d.Accounts[0]
+	send := func(tx state.Transaction) *state.Receipt {
+		tx.GasPrice = big.NewInt(1)
+		hash, err := devnet.Send(leader.RPCURL, leader.Config.L2ChainID(), leader.Sequencer.SigningContract(), from, tx)
+		require.NoError(t, err)
+
+		var receipt *state.Receipt
+		require.Eventually(t, func() bool {
+			_, receipt, err = replica.Sequencer.GetTransactionByHash(hash)
+			return err == nil
+		}, time.Minute, 100*time.Millisecond)
+		return receipt
+	}
+
+	deployed := send(state.Transaction{Type: state.TxTypeContractDeploy, Nonce: 1, Gas: 200000, Data: gasProbeInit})
+	require.Equal(t, state.ReceiptSuccess, deployed.Status)
+
+	// The loop runs out of steps long before gas. The step budget is the same on every node,
+	// so the replica charges the call exactly as the leader did.
+	looped := send(state.Transaction{Type: state.TxTypeContractCall, To: deployed.ContractAddress, Nonce: 2, Gas: 1000000, Data: []byte{0x02}})
+	require.Equal(t, state.ReceiptFailed, looped.Status)
+	require.Equal(t, uint64(1000000), looped.GasUsed)
+	require.Contains(t, looped.Error, evm.ErrStepLimit.Error())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, d.WaitForBatch(ctx, replica.Sequencer.BatchNumber()))
+	require.NoError(t, d.CheckStateRoots())
+
+	_, leaderReceipt, err := leader.Sequencer.GetTransactionByHash(looped.TxHash)
+	require.NoError(t, err)
+	require.Equal(t, *looped, *leaderReceipt)
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/e2e"
+	"zkrollup/pkg/metrics"
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+)
+
+func TestEndToEndStateRootsReachL1(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a node against a simulated L1")
+	}
+
+	config := core.DevConfig()
+	config.ProofStatsFile = filepath.Join(t.TempDir(), "proofstats.jsonl")
+	h, err := e2e.New(e2e.Options{Config: config})
+	require.NoError(t, err)
+	defer h.Close()
+
+	// The first transaction of an account has nonce 1
+	from, to := h.Accounts[0], h.Accounts[1]
+	for nonce := uint64(1); nonce <= 3; nonce++ {
+		_, err := h.Transfer(from, to.Address, big.NewInt(1000), nonce)
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Every transfer is sealed into a batch of its own on a dev chain
+	want := new(big.Int).Add(sequencer.DevAccountBalance, big.NewInt(3000))
+	require.Eventually(t, func() bool {
+		acc, err := h.Sequencer.GetAccount(to.Address)
+		return err == nil && acc.Balance.Cmp(want) == 0
+	}, 30*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, h.CheckStateRoots(ctx))
+
+	submitted, err := h.L1.CurrentBatchNumber(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, submitted, uint64(3))
+
+	// Submissions are recorded again with the gas they used once mined
+	require.Eventually(t, func() bool {
+		records, err := metrics.LoadProofRecords(config.ProofStatsFile)
+		if err != nil {
+			return false
+		}
+		for _, record := range records {
+			if record.GasUsed > 0 {
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestTransfersConsumeNonces(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a node against a simulated L1")
+	}
+
+	h, err := e2e.New(e2e.Options{})
+	require.NoError(t, err)
+	defer h.Close()
+
+	// Several transfers from one sender are all batched, each taking the next nonce
+	from, to := h.Accounts[0], h.Accounts[1]
+	received := func(n int64) func() bool {
+		want := new(big.Int).Add(sequencer.DevAccountBalance, big.NewInt(1000*n))
+		return func() bool {
+			acc, err := h.Sequencer.GetAccount(to.Address)
+			return err == nil && acc.Balance.Cmp(want) == 0
+		}
+	}
+	for nonce := uint64(1); nonce <= 3; nonce++ {
+		_, err := h.Transfer(from, to.Address, big.NewInt(1000), nonce)
+		require.NoError(t, err)
+	}
+	require.Eventually(t, received(3), 30*time.Second, 100*time.Millisecond)
+
+	acc, err := h.Sequencer.GetAccount(from.Address)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), acc.Nonce)
+
+	// An included transfer cannot be replayed
+	_, err = h.Transfer(from, to.Address, big.NewInt(1000), 2)
+	require.Error(t, err)
+
+	_, err = h.Transfer(from, to.Address, big.NewInt(1000), 4)
+	require.NoError(t, err)
+	require.Eventually(t, received(4), 30*time.Second, 100*time.Millisecond)
+	require.Never(t, received(5), time.Second, 100*time.Millisecond)
+}
+
+func TestValidatorsRefuseForeignChainTransactions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a node against a simulated L1")
+	}
+
+	h, err := e2e.New(e2e.Options{})
+	require.NoError(t, err)
+	defer h.Close()
+
+	// Keep the batch number still while proposals are validated
+	h.Sequencer.PauseBatches()
+
+	from, to := h.Accounts[0], h.Accounts[1]
+	propose := func(chainID uint64) error {
+		tx := state.Transaction{
+			Type:    state.TxTypeTransfer,
+			From:    from.Address,
+			To:      to.Address,
+			Amount:  big.NewInt(1000),
+			Nonce:   1,
+			ChainID: chainID,
+		}
+		signature, err := state.SignTransaction(&tx, crypto.FromECDSA(from.PrivateKey), h.Sequencer.SigningContract())
+		require.NoError(t, err)
+		tx.Signature = signature
+
+		return h.Sequencer.ValidateProposedBatch(&state.Batch{
+			Transactions: []state.Transaction{tx},
+			BatchNumber:  h.Sequencer.BatchNumber(),
+			Ordering:     core.DevConfig().BatchOrdering,
+		})
+	}
+
+	chainID := core.DevConfig().L2ChainID()
+	require.ErrorIs(t, propose(chainID+1), sequencer.ErrInvalidChainID)
+	require.ErrorIs(t, propose(0), sequencer.ErrInvalidChainID)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+)
+
+var testDomain = sequencer.EmergencyDomain{ChainID: 1337, Contract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")}
+
+func TestEmergencyCouncilRequiresThreshold(t *testing.T) {
+	var keys [][]byte
+	var operators []common.Address
+	for i := 0; i < 3; i++ {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		keys = append(keys, crypto.FromECDSA(key))
+		operators = append(operators, crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	council, err := sequencer.NewEmergencyCouncil(operators, 2, testDomain, state.NewState())
+	require.NoError(t, err)
+
+	now := time.Now()
+	req := &sequencer.EmergencyRequest{ChainID: testDomain.ChainID, Contract: testDomain.Contract, Action: sequencer.EmergencyHalt, Nonce: 0, Expiry: now.Add(time.Hour).Unix()}
+
+	sig, err := sequencer.SignEmergencyRequest(req, keys[0])
+	require.NoError(t, err)
+	status, err := council.Approve(req, sig, now)
+	require.NoError(t, err)
+	require.Equal(t, 1, status.Approvals)
+	require.False(t, status.Executed)
+
+	// A second signature from the same operator does not count twice
+	status, err = council.Approve(req, sig, now)
+	require.NoError(t, err)
+	require.False(t, status.Executed)
+
+	// Signatures from outside the operator set are rejected
+	outsider, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sig, err = sequencer.SignEmergencyRequest(req, crypto.FromECDSA(outsider))
+	require.NoError(t, err)
+	_, err = council.Approve(req, sig, now)
+	require.Error(t, err)
+
+	sig, err = sequencer.SignEmergencyRequest(req, keys[2])
+	require.NoError(t, err)
+	status, err = council.Approve(req, sig, now)
+	require.NoError(t, err)
+	require.True(t, status.Executed)
+	require.Equal(t, uint64(1), council.Nonce())
+
+	// The executed request cannot be replayed
+	_, err = council.Approve(req, sig, now)
+	require.Error(t, err)
+}
+
+func TestEmergencyCouncilRejectsExpiredRequests(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	council, err := sequencer.NewEmergencyCouncil([]common.Address{crypto.PubkeyToAddress(key.PublicKey)}, 1, testDomain, state.NewState())
+	require.NoError(t, err)
+
+	now := time.Now()
+	req := &sequencer.EmergencyRequest{ChainID: testDomain.ChainID, Contract: testDomain.Contract, Action: sequencer.EmergencyResume, Expiry: now.Add(-time.Minute).Unix()}
+	sig, err := sequencer.SignEmergencyRequest(req, crypto.FromECDSA(key))
+	require.NoError(t, err)
+
+	_, err = council.Approve(req, sig, now)
+	require.Error(t, err)
+
+	_, err = sequencer.NewEmergencyCouncil([]common.Address{crypto.PubkeyToAddress(key.PublicKey)}, 2, testDomain, state.NewState())
+	require.Error(t, err)
+}
+
+func TestEmergencyNonceSurvivesRestart(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	operators := []common.Address{crypto.PubkeyToAddress(key.PublicKey)}
+	path := filepath.Join(t.TempDir(), "state")
+
+	st, err := state.OpenState(state.BackendLevelDB, path, false)
+	require.NoError(t, err)
+	council, err := sequencer.NewEmergencyCouncil(operators, 1, testDomain, st)
+	require.NoError(t, err)
+
+	now := time.Now()
+	req := &sequencer.EmergencyRequest{ChainID: testDomain.ChainID, Contract: testDomain.Contract, Action: sequencer.EmergencyHalt, Expiry: now.Add(time.Hour).Unix()}
+	sig, err := sequencer.SignEmergencyRequest(req, crypto.FromECDSA(key))
+	require.NoError(t, err)
+	status, err := council.Approve(req, sig, now)
+	require.NoError(t, err)
+	require.True(t, status.Executed)
+	require.NoError(t, st.Close())
+
+	// After a restart the executed request cannot be replayed while it has not expired
+	st, err = state.OpenState(state.BackendLevelDB, path, false)
+	require.NoError(t, err)
+	defer st.Close()
+	council, err = sequencer.NewEmergencyCouncil(operators, 1, testDomain, st)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), council.Nonce())
+	_, err = council.Approve(req, sig, now)
+	require.Error(t, err)
+}
+
+func TestEmergencyRequestBoundToDeployment(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	council, err := sequencer.NewEmergencyCouncil([]common.Address{crypto.PubkeyToAddress(key.PublicKey)}, 1, testDomain, state.NewState())
+	require.NoError(t, err)
+
+	// A request signed for another chain or contract is refused
+	now := time.Now()
+	for _, req := range []*sequencer.EmergencyRequest{
+		{ChainID: testDomain.ChainID + 1, Contract: testDomain.Contract, Action: sequencer.EmergencyHalt, Expiry: now.Add(time.Hour).Unix()},
+		{ChainID: testDomain.ChainID, Contract: common.Address{1}, Action: sequencer.EmergencyHalt, Expiry: now.Add(time.Hour).Unix()},
+	} {
+		sig, err := sequencer.SignEmergencyRequest(req, crypto.FromECDSA(key))
+		require.NoError(t, err)
+		_, err = council.Approve(req, sig, now)
+		require.Error(t, err)
+	}
+	require.Equal(t, uint64(0), council.Nonce())
+}
+
+func TestEmergencyRejectsInvalidValidatorSet(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	council, err := sequencer.NewEmergencyCouncil([]common.Address{crypto.PubkeyToAddress(key.PublicKey)}, 1, testDomain, state.NewState())
+	require.NoError(t, err)
+
+	// A mistyped peer ID is refused before the signature counts
+	now := time.Now()
+	req := &sequencer.EmergencyRequest{ChainID: testDomain.ChainID, Contract: testDomain.Contract, Action: sequencer.EmergencySetValidators, Validators: []string{"not-a-peer-id"}, Expiry: now.Add(time.Hour).Unix()}
+	sig, err := sequencer.SignEmergencyRequest(req, crypto.FromECDSA(key))
+	require.NoError(t, err)
+	status, err := council.Approve(req, sig, now)
+	require.Error(t, err)
+	require.False(t, status.Executed)
+	require.Equal(t, uint64(0), council.Nonce())
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/e2e"
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+)
+
+// gasProbeRuntime stores 1 in slot 0 when called without data, reverts when called with
+// 0x01 and loops until it runs out of gas when called with 0x02
+var gasProbeRuntime = common.FromHex("60003560f81c80600114601957600214601f576001600055005b60006000fd5b601f56")
+
+// gasProbeInit returns gasProbeRuntime as the code of the new contract
+var gasProbeInit = append(common.FromHex("602380600b6000396000f3"), gasProbeRuntime...)
+
+func TestContractCallsPayForGas(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a node against a simulated L1")
+	}
+
+	h, err := e2e.New(e2e.Options{})
+	require.NoError(t, err)
+	defer h.Close()
+
+	from := h.Accounts[0]
+	price := big.NewInt(7)
+	send := func(tx state.Transaction) *state.Receipt {
+		tx.GasPrice = price
+		hash, err := h.Send(from, tx)
+		require.NoError(t, err)
+
+		var receipt *state.Receipt
+		require.Eventually(t, func() bool {
+			_, receipt, err = h.Sequencer.GetTransactionByHash(hash)
+			return err == nil
+		}, 30*time.Second, 100*time.Millisecond)
+		return receipt
+	}
+
+	deployed := send(state.Transaction{Type: state.TxTypeContractDeploy, Nonce: 1, Gas: 200000, Data: gasProbeInit})
+	require.Equal(t, state.ReceiptSuccess, deployed.Status)
+	contract := deployed.ContractAddress
+
+	// A successful call keeps its changes and pays for the gas it used
+	succeeded := send(state.Transaction{Type: state.TxTypeContractCall, To: contract, Nonce: 2, Gas: 100000})
+	require.Equal(t, state.ReceiptSuccess, succeeded.Status)
+	slot, err := h.Sequencer.GetStorage(contract, [32]byte{})
+	require.NoError(t, err)
+	require.Equal(t, common.BigToHash(big.NewInt(1)), common.Hash(slot))
+
+	// A reverted call is included and pays for the gas used up to the revert
+	reverted := send(state.Transaction{Type: state.TxTypeContractCall, To: contract, Nonce: 3, Gas: 100000, Data: []byte{0x01}})
+	require.Equal(t, state.ReceiptFailed, reverted.Status)
+	require.Positive(t, reverted.GasUsed)
+	require.Less(t, reverted.GasUsed, uint64(100000))
+
+	// A call running out of gas pays for all of it
+	exhausted := send(state.Transaction{Type: state.TxTypeContractCall, To: contract, Nonce: 4, Gas: 50000, Data: []byte{0x02}})
+	require.Equal(t, state.ReceiptFailed, exhausted.Status)
+	require.Equal(t, uint64(50000), exhausted.GasUsed)
+
+	acc, err := h.Sequencer.GetAccount(from.Address)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), acc.Nonce)
+
+	gasUsed := deployed.GasUsed + succeeded.GasUsed + reverted.GasUsed + exhausted.GasUsed
+	fees := new(big.Int).Mul(price, new(big.Int).SetUint64(gasUsed))
+	require.Equal(t, new(big.Int).Sub(sequencer.DevAccountBalance, fees), acc.Balance)
+}
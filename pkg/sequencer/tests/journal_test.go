@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+func TestTxJournalReplaysAdmittedTransactions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mempool.journal")
+	from := types.Address{1}
+
+	tx := func(nonce uint64) state.Transaction {
+		return state.Transaction{From: from, To: types.Address{2}, Amount: big.NewInt(int64(nonce)), Nonce: nonce}
+	}
+
+	journal, err := sequencer.OpenTxJournal(path)
+	require.NoError(t, err)
+	require.NoError(t, journal.Insert(tx(1)))
+	require.NoError(t, journal.Insert(tx(2)))
+	require.NoError(t, journal.Close())
+
+	// A write cut short by a crash is skipped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"From":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	reopened, err := sequencer.OpenTxJournal(path)
+	require.NoError(t, err)
+	txs, err := reopened.Load()
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	first, second := tx(1), tx(2)
+	require.Equal(t, first.Hash(), txs[0].Hash())
+	require.Equal(t, second.Hash(), txs[1].Hash())
+
+	// Rotation keeps only what is still pending, and appends continue after it
+	require.NoError(t, reopened.Rotate([]state.Transaction{tx(2)}))
+	require.NoError(t, reopened.Insert(tx(3)))
+	txs, err = reopened.Load()
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	require.Equal(t, uint64(2), txs[0].Nonce)
+	require.Equal(t, uint64(3), txs[1].Nonce)
+	require.NoError(t, reopened.Close())
+}
+
+func TestTxJournalDisabled(t *testing.T) {
+	journal, err := sequencer.OpenTxJournal("")
+	require.NoError(t, err)
+	require.NoError(t, journal.Insert(state.Transaction{Nonce: 1}))
+	txs, err := journal.Load()
+	require.NoError(t, err)
+	require.Empty(t, txs)
+	require.NoError(t, journal.Rotate(nil))
+	require.NoError(t, journal.Close())
+}
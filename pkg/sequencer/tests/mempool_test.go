@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+func mempoolTx(from byte, nonce uint64, price int64) state.Transaction {
+	return state.Transaction{
+		Type:     state.TxTypeTransfer,
+		From:     types.Address{from},
+		To:       types.Address{0xff},
+		Amount:   big.NewInt(1),
+		Nonce:    nonce,
+		Gas:      21000,
+		GasPrice: big.NewInt(price),
+	}
+}
+
+func TestMempoolPriorityAndNonceOrder(t *testing.T) {
+	pool := sequencer.NewMempool(sequencer.MempoolConfig{MaxSize: 10, MaxPerSender: 10, PriceBumpPercent: 10})
+
+	for _, tx := range []state.Transaction{
+		mempoolTx(1, 2, 50), // Sender 1 pays more for its second transaction
+		mempoolTx(1, 1, 5),
+		mempoolTx(2, 1, 10),
+	} {
+		_, err := pool.Add(tx)
+		require.NoError(t, err)
+	}
+
+	// Sender 2's head outbids sender 1's head, sender 1 stays in nonce order
+	txs := pool.Pop(3)
+	require.Len(t, txs, 3)
+	require.Equal(t, types.Address{2}, txs[0].From)
+	require.Equal(t, uint64(1), txs[1].Nonce)
+	require.Equal(t, uint64(2), txs[2].Nonce)
+	require.Equal(t, 0, pool.Len())
+}
+
+func TestMempoolReplacementAndEviction(t *testing.T) {
+	pool := sequencer.NewMempool(sequencer.MempoolConfig{MaxSize: 2, MaxPerSender: 2, PriceBumpPercent: 10})
+
+	_, err := pool.Add(mempoolTx(1, 1, 100))
+	require.NoError(t, err)
+
+	// Replacement needs a 10% bump
+	_, err = pool.Add(mempoolTx(1, 1, 105))
+	require.ErrorIs(t, err, sequencer.ErrReplacementUnderpriced)
+	replaced, err := pool.Add(mempoolTx(1, 1, 110))
+	require.NoError(t, err)
+	require.True(t, replaced)
+	require.Equal(t, 1, pool.Len())
+
+	_, err = pool.Add(mempoolTx(2, 1, 20))
+	require.NoError(t, err)
+
+	// Full pool: a cheaper transaction is rejected, a better paying one evicts the cheapest
+	_, err = pool.Add(mempoolTx(3, 1, 10))
+	require.ErrorIs(t, err, sequencer.ErrMempoolFull)
+	_, err = pool.Add(mempoolTx(3, 1, 30))
+	require.NoError(t, err)
+
+	txs := pool.Pop(10)
+	require.Len(t, txs, 2)
+	require.Equal(t, types.Address{1}, txs[0].From)
+	require.Equal(t, types.Address{3}, txs[1].From)
+}
+
+func TestMempoolDedupAndRemoveIncluded(t *testing.T) {
+	pool := sequencer.NewMempool(sequencer.MempoolConfig{MaxSize: 10, MaxPerSender: 10, PriceBumpPercent: 10})
+
+	for _, tx := range []state.Transaction{
+		mempoolTx(1, 1, 10),
+		mempoolTx(1, 2, 10),
+		mempoolTx(1, 3, 10),
+		mempoolTx(2, 1, 10),
+	} {
+		_, err := pool.Add(tx)
+		require.NoError(t, err)
+	}
+
+	// The same transaction gossiped back is recognised
+	_, err := pool.Add(mempoolTx(1, 2, 10))
+	require.ErrorIs(t, err, sequencer.ErrAlreadyKnown)
+
+	// A batch including sender 1's second transaction obsoletes its first two
+	pool.RemoveIncluded([]state.Transaction{mempoolTx(1, 2, 10), mempoolTx(2, 1, 10)})
+	require.Equal(t, 1, pool.Len())
+
+	txs := pool.Pop(10)
+	require.Len(t, txs, 1)
+	require.Equal(t, uint64(3), txs[0].Nonce)
+}
+
+func TestMempoolHoldsNonceGaps(t *testing.T) {
+	pool := sequencer.NewMempool(sequencer.MempoolConfig{MaxSize: 10, MaxPerSender: 10, PriceBumpPercent: 10})
+	pool.SetNonceSource(func(types.Address) uint64 { return 1 })
+
+	// Nonces 2 and 3 wait for nonce 1
+	for _, nonce := range []uint64{3, 2} {
+		_, err := pool.Add(mempoolTx(1, nonce, 10))
+		require.NoError(t, err)
+	}
+	require.Equal(t, 0, pool.Len())
+	require.Equal(t, 2, pool.Queued())
+	require.Zero(t, pool.ReadyGas())
+	require.False(t, pool.HasReady(1))
+	require.False(t, pool.HasReadyGas(21000))
+	require.Empty(t, pool.Pop(10))
+
+	// The missing nonce promotes the whole run
+	_, err := pool.Add(mempoolTx(1, 1, 10))
+	require.NoError(t, err)
+	require.Equal(t, 3, pool.Len())
+	require.Equal(t, 0, pool.Queued())
+	require.Equal(t, uint64(3*21000), pool.ReadyGas())
+	require.True(t, pool.HasReady(3))
+	require.False(t, pool.HasReady(4))
+	require.True(t, pool.HasReadyGas(3*21000))
+	require.False(t, pool.HasReadyGas(3*21000+1))
+
+	txs := pool.Pop(2)
+	require.Len(t, txs, 2)
+	require.Equal(t, uint64(1), txs[0].Nonce)
+	require.Equal(t, uint64(2), txs[1].Nonce)
+
+	// Nonces follow the popped transactions until they are included, a gap after them waits
+	_, err = pool.Add(mempoolTx(1, 5, 10))
+	require.NoError(t, err)
+	require.Equal(t, 1, pool.Len())
+	require.Equal(t, 1, pool.Queued())
+
+	// A failed proposal returns the popped transactions ahead of the rest
+	pool.Requeue(txs)
+	require.Equal(t, 3, pool.Len())
+	txs = pool.Pop(10)
+	require.Len(t, txs, 3)
+	require.Equal(t, uint64(3), txs[2].Nonce)
+	require.Equal(t, 1, pool.Queued())
+}
+
+func TestMempoolPendingSkipsPrivate(t *testing.T) {
+	pool := sequencer.NewMempool(sequencer.MempoolConfig{MaxSize: 10, MaxPerSender: 10, PriceBumpPercent: 10})
+
+	private := mempoolTx(1, 1, 10)
+	private.Private = true
+	for _, tx := range []state.Transaction{mempoolTx(2, 2, 10), mempoolTx(2, 1, 10), private} {
+		_, err := pool.Add(tx)
+		require.NoError(t, err)
+	}
+
+	// Private transactions stay in the pool but are not listed
+	pending := pool.Pending()
+	require.Len(t, pending, 2)
+	require.Equal(t, uint64(1), pending[0].Nonce)
+	require.Equal(t, uint64(2), pending[1].Nonce)
+	require.Len(t, pool.Pop(3), 3)
+}
+
+func TestMempoolContains(t *testing.T) {
+	pool := sequencer.NewMempool(sequencer.MempoolConfig{MaxSize: 10, MaxPerSender: 10, PriceBumpPercent: 10})
+
+	tx := mempoolTx(1, 1, 10)
+	require.False(t, pool.Contains(tx.Hash()))
+	_, err := pool.Add(tx)
+	require.NoError(t, err)
+	require.True(t, pool.Contains(tx.Hash()))
+
+	pool.Pop(1)
+	require.False(t, pool.Contains(tx.Hash()))
+
+	// Replaced and included transactions leave the pool
+	first, second := mempoolTx(2, 1, 10), mempoolTx(2, 2, 10)
+	for _, tx := range []state.Transaction{first, second} {
+		_, err := pool.Add(tx)
+		require.NoError(t, err)
+	}
+	bumped := mempoolTx(2, 2, 20)
+	replaced, err := pool.Add(bumped)
+	require.NoError(t, err)
+	require.True(t, replaced)
+	require.False(t, pool.Contains(second.Hash()))
+	require.True(t, pool.Contains(bumped.Hash()))
+
+	pool.RemoveIncluded([]state.Transaction{bumped})
+	require.False(t, pool.Contains(first.Hash()))
+	require.False(t, pool.Contains(bumped.Hash()))
+}
+
+func TestMempoolLookup(t *testing.T) {
+	pool := sequencer.NewMempool(sequencer.MempoolConfig{MaxSize: 10, MaxPerSender: 10, PriceBumpPercent: 10})
+
+	held, missing, private := mempoolTx(1, 1, 10), mempoolTx(2, 1, 10), mempoolTx(3, 1, 10)
+	private.Private = true
+	for _, tx := range []state.Transaction{held, private} {
+		_, err := pool.Add(tx)
+		require.NoError(t, err)
+	}
+
+	// Only public transactions still in the pool are served to peers pulling them
+	txs := pool.Lookup([][32]byte{held.Hash(), missing.Hash(), private.Hash()})
+	require.Len(t, txs, 1)
+	require.Equal(t, held.Hash(), txs[0].Hash())
+}
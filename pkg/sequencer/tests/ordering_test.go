@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
+)
+
+func TestOrderTransactions(t *testing.T) {
+	deposit := state.Transaction{Type: state.TxTypeDeposit, To: types.Address{1}, Amount: big.NewInt(1), Nonce: 7}
+	a1, a2 := mempoolTx(1, 1, 5), mempoolTx(1, 2, 50) // Sender 1 pays more for its second transaction
+	b1 := mempoolTx(2, 1, 10)
+	a1.ReceivedAt, a2.ReceivedAt, b1.ReceivedAt = 300, 100, 200
+	txs := []state.Transaction{a2, b1, deposit, a1}
+
+	fee, err := sequencer.NewOrderingPolicy(sequencer.OrderingFee, [32]byte{})
+	require.NoError(t, err)
+	ordered := sequencer.OrderTransactions(fee, txs)
+	require.Equal(t, []uint64{7, 1, 1, 2}, nonces(ordered))
+	require.Equal(t, types.Address{2}, ordered[1].From)
+
+	// Admission times order first come first served, still in nonce order
+	fifo, err := sequencer.NewOrderingPolicy(sequencer.OrderingFIFO, [32]byte{})
+	require.NoError(t, err)
+	ordered = sequencer.OrderTransactions(fifo, txs)
+	require.Equal(t, types.Address{2}, ordered[1].From)
+	require.NoError(t, sequencer.VerifyOrdering(fifo, ordered))
+
+	// Fair ordering depends on the parent root only
+	fair, err := sequencer.NewOrderingPolicy(sequencer.OrderingFair, [32]byte{1})
+	require.NoError(t, err)
+	ordered = sequencer.OrderTransactions(fair, txs)
+	require.Equal(t, ordered, sequencer.OrderTransactions(fair, []state.Transaction{a1, deposit, b1, a2}))
+	require.Equal(t, deposit.Hash(), ordered[0].Hash())
+
+	// Validators refuse any other order
+	require.NoError(t, sequencer.VerifyOrdering(fee, []state.Transaction{deposit, b1, a1, a2}))
+	require.Error(t, sequencer.VerifyOrdering(fee, []state.Transaction{deposit, a1, b1, a2}))
+	require.Error(t, sequencer.VerifyOrdering(fee, []state.Transaction{b1, deposit, a1, a2}))
+
+	_, err = sequencer.NewOrderingPolicy("random", [32]byte{})
+	require.Error(t, err)
+}
+
+func nonces(txs []state.Transaction) []uint64 {
+	var ns []uint64
+	for _, tx := range txs {
+		ns = append(ns, tx.Nonce)
+	}
+	return ns
+}
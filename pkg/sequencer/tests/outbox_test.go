@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/sequencer"
+)
+
+func TestOutboxPersistsUndeliveredBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+
+	outbox, err := sequencer.OpenOutbox(path)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Enqueue(3))
+	require.NoError(t, outbox.Enqueue(1))
+	require.NoError(t, outbox.Enqueue(2))
+	require.NoError(t, outbox.Delivered(2))
+
+	reopened, err := sequencer.OpenOutbox(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, reopened.Len())
+	require.Equal(t, []uint64{1, 3}, reopened.Due(time.Now()))
+}
+
+func TestOutboxBacksOffFailedDeliveries(t *testing.T) {
+	outbox, err := sequencer.OpenOutbox("")
+	require.NoError(t, err)
+	require.NoError(t, outbox.Enqueue(7))
+
+	now := time.Now()
+	first := outbox.Failed(7, now)
+	require.Empty(t, outbox.Due(now))
+	require.Equal(t, []uint64{7}, outbox.Due(now.Add(first)))
+
+	second := outbox.Failed(7, now)
+	require.Equal(t, 2*first, second)
+
+	// Backoff is capped
+	for i := 0; i < 20; i++ {
+		outbox.Failed(7, now)
+	}
+	require.Equal(t, []uint64{7}, outbox.Due(now.Add(5*time.Minute)))
+}
@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/sequencer"
+	"zkrollup/pkg/signer"
+)
+
+func TestPreconfirmationSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	issuer := signer.NewLocalSigner(key)
+
+	p := &sequencer.Preconfirmation{
+		TxHash:   common.Hash{1},
+		ChainID:  1337,
+		Issuer:   issuer.Address(),
+		Batch:    4,
+		Deadline: 6,
+	}
+	p.Signature, err = issuer.SignText(context.Background(), p.Message())
+	require.NoError(t, err)
+	require.NoError(t, p.VerifySignature())
+
+	// The deadline is covered by the signature, a stretched promise no longer verifies
+	stretched := *p
+	stretched.Deadline = 10
+	require.True(t, errors.Is(stretched.VerifySignature(), sequencer.ErrInvalidPreconfirmation))
+
+	// So is the issuer, the promise cannot be pinned on another node
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	forged := *p
+	forged.Issuer = crypto.PubkeyToAddress(other.PublicKey)
+	require.True(t, errors.Is(forged.VerifySignature(), sequencer.ErrInvalidPreconfirmation))
+}
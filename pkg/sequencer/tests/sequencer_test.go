@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/sequencer"
+)
+
+func TestNewSequencerReleasesResourcesOnError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	config := core.DevConfig()
+	config.StateBackend = "leveldb"
+	config.StateDBPath = filepath.Join(t.TempDir(), "state")
+	config.EmergencyOperators = []string{"not-an-address"}
+
+	// The failed setup closes the P2P node and the state database, nothing keeps listening
+	// and the database can be opened again
+	_, err = sequencer.NewSequencer(config, port, nil, true)
+	require.Error(t, err)
+	_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Error(t, err)
+
+	config.EmergencyOperators = nil
+	seq, err := sequencer.NewSequencer(config, port, nil, true)
+	require.NoError(t, err)
+	seq.Stop()
+}
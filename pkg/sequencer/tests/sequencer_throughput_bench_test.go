@@ -1,31 +1,54 @@
 package tests
 
 import (
+	"crypto/ecdsa"
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
+
 	"zkrollup/pkg/core"
 	"zkrollup/pkg/sequencer"
 	"zkrollup/pkg/state"
+	"zkrollup/pkg/types"
 )
 
-func generateRandomAddress() [20]byte {
-	var addr [20]byte
+// benchResults is where the benchmarks write their CSV results. Pass -bench-results=. to
+// refresh the checked-in results, by default they go to a temporary directory.
+var benchResults = flag.String("bench-results", "", "directory the benchmark results are written to")
+
+// benchResultPath returns where a benchmark writes the results file name
+func benchResultPath(t *testing.T, name string) string {
+	dir := *benchResults
+	if dir == "" {
+		dir = t.TempDir()
+	}
+	return filepath.Join(dir, name)
+}
+
+func generateRandomAddress() types.Address {
+	var addr types.Address
 	rand.Read(addr[:])
 	return addr
 }
 
-// generateTransactions generates n transactions, ensuring each sender's nonce starts at 1 (not 0) and increments by 1.
-func generateTransactions(n int, senderCount int) []state.Transaction {
+// generateTransactions generates n signed transactions, ensuring each sender's nonce starts at 1 (not 0) and increments by 1.
+func generateTransactions(n int, senderCount int, chainID uint64) []state.Transaction {
 	txs := make([]state.Transaction, 0, n)
-	senders := make([][20]byte, senderCount)
+	senders := make([]*ecdsa.PrivateKey, senderCount)
 	for i := 0; i < senderCount; i++ {
-		senders[i] = generateRandomAddress()
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			panic(err)
+		}
+		senders[i] = key
 	}
 	// Distribute transactions as evenly as possible among senders
 	txsPerSender := n / senderCount
@@ -36,15 +59,24 @@ func generateTransactions(n int, senderCount int) []state.Transaction {
 			numTx++
 		}
 		for nonce := uint64(1); nonce <= uint64(numTx); nonce++ {
-			txs = append(txs, state.Transaction{
-				Type:   state.TxTypeTransfer,
-				From:   sender,
-				To:     generateRandomAddress(),
-				Amount: big.NewInt(int64(rand.Intn(1000) + 1)),
-				Nonce:  nonce,
-				Data:   nil,
-				Gas:    21000,
-			})
+			tx := state.Transaction{
+				Type:    state.TxTypeTransfer,
+				From:    types.FromCommon(crypto.PubkeyToAddress(sender.PublicKey)),
+				To:      generateRandomAddress(),
+				Amount:  big.NewInt(int64(rand.Intn(1000) + 1)),
+				Nonce:   nonce,
+				Data:    nil,
+				Gas:     21000,
+				ChainID: chainID,
+			}
+			// The default config names no rollup contract to sign for
+			hash := tx.SigningHash(types.Address{})
+			sig, err := crypto.Sign(hash[:], sender)
+			if err != nil {
+				panic(err)
+			}
+			tx.Signature = sig
+			txs = append(txs, tx)
 		}
 	}
 	return txs
@@ -59,6 +91,15 @@ func TestSequencerThroughput(t *testing.T) {
 	}, 0, len(transactionCounts))
 
 	config := core.DefaultConfig()
+	// The pool is never drained here, so lift the mempool limits
+	config.MempoolMaxSize = 0
+	config.MempoolMaxPerSender = 0
+	// Admitted transactions are journaled, keep the journal out of the source tree
+	config.MempoolJournal = filepath.Join(t.TempDir(), "mempool.journal")
+	// The batch circuit keys set up on start are written out too
+	keys := t.TempDir()
+	config.ProvingKeyFile = filepath.Join(keys, "batch.pk")
+	config.VerifyingKeyFile = filepath.Join(keys, "batch.vk")
 	seq, err := sequencer.NewSequencer(config, config.SequencerPort, nil, true)
 	if err != nil {
 		t.Fatalf("failed to initialize sequencer: %v", err)
@@ -67,7 +108,7 @@ func TestSequencerThroughput(t *testing.T) {
 	senderCount := 1000 // Number of unique senders
 
 	for _, txCount := range transactionCounts {
-		txs := generateTransactions(txCount, senderCount)
+		txs := generateTransactions(txCount, senderCount, config.L2ChainID())
 
 		t.Logf("Processing %d transactions...", txCount)
 		start := time.Now()
@@ -92,7 +133,7 @@ func TestSequencerThroughput(t *testing.T) {
 		})
 	}
 
-	csvFile := "sequencer_throughput.csv"
+	csvFile := benchResultPath(t, "sequencer_throughput.csv")
 	f, err := os.Create(csvFile)
 	if err != nil {
 		t.Fatalf("failed to create csv: %v", err)
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/consensus"
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/devnet"
+	"zkrollup/pkg/p2p"
+	"zkrollup/pkg/rpc"
+	"zkrollup/pkg/sequencer"
+)
+
+// startSyncingNode starts a follower holding back transactions for warmup seconds
+func startSyncingNode(t *testing.T, warmup int, bootstrapPeers []string) *sequencer.Sequencer {
+	config := core.DevConfig()
+	config.SyncWarmupSeconds = warmup
+	seq, err := sequencer.NewSequencer(config, 0, bootstrapPeers, false)
+	require.NoError(t, err)
+	require.NoError(t, seq.Start())
+	t.Cleanup(seq.Stop)
+	return seq
+}
+
+func TestNodeWithoutPeersSyncsAfterWarmup(t *testing.T) {
+	seq := startSyncingNode(t, 1, nil)
+
+	// Transactions are refused during the warm-up, alone the node is then at the head
+	require.False(t, seq.IsSynced())
+	require.Equal(t, sequencer.SyncStatus{Syncing: true}, seq.SyncStatus())
+	require.Eventually(t, seq.IsSynced, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, sequencer.SyncStatus{}, seq.SyncStatus())
+}
+
+func TestNodeBehindPeersStaysSyncing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A peer proposing batch 5 shows the network finalized 5 batches this node lacks. It
+	// serves neither batches nor snapshots, so the node cannot catch up.
+	peer, err := p2p.NewNodeWithOptions(ctx, 0, nil, p2p.NodeOptions{DisableDiscovery: true})
+	require.NoError(t, err)
+	defer peer.Close()
+	peer.SetupProtocols(&p2p.ProtocolHandlers{})
+
+	seq := startSyncingNode(t, 2, []string{fmt.Sprintf("%s/p2p/%s", peer.Host.Addrs()[0], peer.Host.ID())})
+	require.Eventually(t, func() bool { return peer.GossipPeerCount() > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	proposal, err := json.Marshal(&consensus.ConsensusMessage{
+		Type:        consensus.PrePrepare,
+		NodeID:      peer.Host.ID().String(),
+		BatchHash:   "proposal",
+		BatchNumber: 5,
+		Timestamp:   time.Now(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, peer.BroadcastConsensus(ctx, proposal))
+	require.Eventually(t, func() bool { return seq.SyncStatus().HighestBatch == 5 }, 5*time.Second, 10*time.Millisecond)
+
+	// The node keeps refusing transactions past the warm-up and reports its progress
+	require.Never(t, seq.IsSynced, 4*time.Second, 100*time.Millisecond)
+
+	port, err := devnet.FreePort()
+	require.NoError(t, err)
+	server := rpc.NewServer(seq, port)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	var status struct {
+		Syncing      bool   `json:"syncing"`
+		CurrentBatch uint64 `json:"currentBatch"`
+		HighestBatch uint64 `json:"highestBatch"`
+	}
+	// The server binds in the background, so retry until it answers
+	url := fmt.Sprintf("http://127.0.0.1:%d", port)
+	require.Eventually(t, func() bool {
+		return devnet.Call(url, "rollup_syncing", []interface{}{}, &status) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	require.True(t, status.Syncing)
+	require.Equal(t, uint64(0), status.CurrentBatch)
+	require.Equal(t, uint64(5), status.HighestBatch)
+}
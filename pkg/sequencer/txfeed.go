@@ -0,0 +1,86 @@
+package sequencer
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"zkrollup/pkg/state"
+)
+
+// txFeedBuffer is the number of transactions a subscriber may fall behind before new ones
+// are dropped for it
+const txFeedBuffer = 256
+
+// TxSubscription receives the transactions admitted to the pool after it was created,
+// except those submitted privately
+type TxSubscription struct {
+	ch      chan state.Transaction
+	feed    *txFeed
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// txFeed fans admitted transactions out to subscribers without ever blocking admission
+type txFeed struct {
+	mu   sync.Mutex
+	subs map[*TxSubscription]struct{}
+}
+
+// SubscribePendingTxs returns a subscription to the public transactions admitted to the
+// pool, local or gossiped. It must be unsubscribed when no longer read.
+func (s *Sequencer) SubscribePendingTxs() *TxSubscription {
+	sub := &TxSubscription{
+		ch:   make(chan state.Transaction, txFeedBuffer),
+		feed: &s.txFeed,
+	}
+
+	s.txFeed.mu.Lock()
+	defer s.txFeed.mu.Unlock()
+	if s.txFeed.subs == nil {
+		s.txFeed.subs = make(map[*TxSubscription]struct{})
+	}
+	s.txFeed.subs[sub] = struct{}{}
+	return sub
+}
+
+// PendingTxs returns the public transactions currently in the pool
+func (s *Sequencer) PendingTxs() []state.Transaction {
+	return s.mempool.Pending()
+}
+
+// Txs returns the channel transactions are delivered on, closed by Unsubscribe
+func (sub *TxSubscription) Txs() <-chan state.Transaction {
+	return sub.ch
+}
+
+// Dropped returns the number of transactions skipped because the subscriber fell behind
+func (sub *TxSubscription) Dropped() uint64 {
+	return sub.dropped.Load()
+}
+
+// Unsubscribe stops delivery and closes the channel
+func (sub *TxSubscription) Unsubscribe() {
+	sub.once.Do(func() {
+		sub.feed.mu.Lock()
+		defer sub.feed.mu.Unlock()
+		delete(sub.feed.subs, sub)
+		close(sub.ch)
+	})
+}
+
+// send delivers a transaction to every subscriber with room for it
+func (f *txFeed) send(tx state.Transaction) {
+	if tx.Private {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subs {
+		select {
+		case sub.ch <- tx:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package sequencer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"zkrollup/pkg/l1"
+)
+
+// ErrTransactionNotFound is returned for a transaction that is neither pending nor in a
+// finalized batch
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// TxStage is how far a transaction has progressed through the rollup
+type TxStage string
+
+const (
+	TxPending   TxStage = "pending"   // In the pool or in a batch still in consensus
+	TxBatched   TxStage = "batched"   // In a finalized batch
+	TxProven    TxStage = "proven"    // The batch's proof was generated
+	TxSubmitted TxStage = "submitted" // The batch was submitted to L1
+	TxFinalized TxStage = "finalized" // The batch's proof was verified on L1 and is past the finality depth
+)
+
+// TxStatus is where a transaction is in the pipeline. BatchNumber is set from TxBatched
+// on and L1TxHash from TxSubmitted on.
+type TxStatus struct {
+	Hash        common.Hash
+	Stage       TxStage
+	BatchNumber uint64
+	L1TxHash    common.Hash
+}
+
+// GetTransactionStatus reports how far a transaction has progressed, from the pool to a
+// batch finalized on L1
+func (s *Sequencer) GetTransactionStatus(ctx context.Context, hash common.Hash) (*TxStatus, error) {
+	status := &TxStatus{Hash: hash}
+
+	receipt, ok := s.state.GetReceipt(hash)
+	if !ok {
+		if s.mempool.Contains(hash) || s.inCurrentBatch(hash) {
+			status.Stage = TxPending
+			return status, nil
+		}
+		return nil, ErrTransactionNotFound
+	}
+
+	batchNumber := receipt.BatchNumber
+	batches := s.state.GetBatches(batchNumber, 1)
+	if len(batches) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	batch := &batches[0]
+	status.BatchNumber = batchNumber
+	status.Stage = TxBatched
+	if len(batch.Proof) != 0 {
+		status.Stage = TxProven
+	}
+	if batch.L1TxHash == ([32]byte{}) {
+		return status, nil
+	}
+	status.Stage = TxSubmitted
+	status.L1TxHash = batch.L1TxHash
+
+	// Finality is reported by the batch watcher or read from the contract, a node without
+	// L1 access reports the submission
+	if s.l1Finality.finalized(batchNumber) {
+		status.Stage = TxFinalized
+		return status, nil
+	}
+	if s.l1Client == nil {
+		return status, nil
+	}
+	l1Status, err := s.l1Client.GetBatchStatus(ctx, batchNumber)
+	if err != nil {
+		return nil, err
+	}
+	if l1Status.State == l1.BatchFinalized {
+		status.Stage = TxFinalized
+	}
+	return status, nil
+}
+
+// inCurrentBatch reports whether the batch in consensus holds a transaction
+func (s *Sequencer) inCurrentBatch(hash [32]byte) bool {
+	s.batchMu.RLock()
+	defer s.batchMu.RUnlock()
+
+	if s.currentBatch == nil {
+		return false
+	}
+	for i := range s.currentBatch.Transactions {
+		if s.currentBatch.Transactions[i].Hash() == hash {
+			return true
+		}
+	}
+	return false
+}
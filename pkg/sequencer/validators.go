@@ -0,0 +1,40 @@
+package sequencer
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const validatorRegistryPollInterval = 15 * time.Second
+
+// watchValidatorRegistry follows the validator set registered on the rollup contract. A
+// changed set takes over once the next batch is decided, so all validators switch together.
+func (s *Sequencer) watchValidatorRegistry() {
+	ticker := time.NewTicker(validatorRegistryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.refreshValidators()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshValidators reads the registered validators and schedules them on the consensus
+func (s *Sequencer) refreshValidators() {
+	validators, err := s.l1Client.GetValidators(s.ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read L1 validator registry")
+		return
+	}
+	if len(validators) == 0 {
+		log.Warn().Msg("L1 validator registry is empty, keeping the current validator set")
+		return
+	}
+	s.consensus.ScheduleValidators(validators)
+}
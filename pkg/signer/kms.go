@@ -0,0 +1,268 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// secp256k1 group order and half of it, KMS signatures are normalized to the lower half
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// KMSConfig locates an AWS KMS key. Credentials are read from the standard AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+type KMSConfig struct {
+	KeyID    string // Key ID, ARN or alias of an ECC_SECG_P256K1 signing key
+	Region   string // AWS region, AWS_REGION when empty
+	Endpoint string // Overrides https://kms.<region>.amazonaws.com, for VPC endpoints or local emulators
+}
+
+// KMSSigner signs with a secp256k1 key held by AWS KMS, the key never leaves the HSM
+type KMSSigner struct {
+	keyID        string
+	region       string
+	endpoint     string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+	publicKey    []byte // Uncompressed, to pick the recovery ID of signatures
+	address      common.Address
+}
+
+// kmsSignature is the DER encoding of the signatures returned by KMS
+type kmsSignature struct {
+	R, S *big.Int
+}
+
+// kmsPublicKey is the DER encoded SubjectPublicKeyInfo returned by KMS
+type kmsPublicKey struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// NewKMSSigner connects to the KMS key described by config and derives its address
+func NewKMSSigner(ctx context.Context, config KMSConfig) (*KMSSigner, error) {
+	region := config.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("no AWS region configured for KMS")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", region)
+	}
+
+	s := &KMSSigner{
+		keyID:        config.KeyID,
+		region:       region,
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for KMS")
+	}
+
+	var result struct {
+		PublicKey string `json:"PublicKey"`
+		KeySpec   string `json:"KeySpec"`
+	}
+	if err := s.call(ctx, "GetPublicKey", map[string]string{"KeyId": s.keyID}, &result); err != nil {
+		return nil, fmt.Errorf("failed to get KMS public key: %v", err)
+	}
+	if result.KeySpec != "" && result.KeySpec != "ECC_SECG_P256K1" {
+		return nil, fmt.Errorf("KMS key %s is %s, expected ECC_SECG_P256K1", s.keyID, result.KeySpec)
+	}
+	der, err := base64.StdEncoding.DecodeString(result.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode KMS public key: %v", err)
+	}
+	var info kmsPublicKey
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse KMS public key: %v", err)
+	}
+	pub, err := crypto.UnmarshalPubkey(info.PublicKey.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("KMS key is not a secp256k1 key: %v", err)
+	}
+
+	s.publicKey = crypto.FromECDSAPub(pub)
+	s.address = crypto.PubkeyToAddress(*pub)
+	return s, nil
+}
+
+// Address returns the address of the KMS key
+func (s *KMSSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx has KMS sign the hash of tx for chainID
+func (s *KMSSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	txSigner := types.LatestSignerForChainID(chainID)
+	signature, err := s.sign(ctx, txSigner.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(txSigner, signature)
+}
+
+// SignText has KMS sign data as an EIP-191 personal message
+func (s *KMSSigner) SignText(ctx context.Context, data []byte) ([]byte, error) {
+	return s.sign(ctx, accounts.TextHash(data))
+}
+
+// sign has KMS sign a 32 byte digest and converts the DER signature to [R || S || V]
+func (s *KMSSigner) sign(ctx context.Context, digest []byte) ([]byte, error) {
+	var result struct {
+		Signature string `json:"Signature"`
+	}
+	request := map[string]string{
+		"KeyId":            s.keyID,
+		"Message":          base64.StdEncoding.EncodeToString(digest),
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": "ECDSA_SHA_256",
+	}
+	if err := s.call(ctx, "Sign", request, &result); err != nil {
+		return nil, fmt.Errorf("KMS failed to sign: %v", err)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(result.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode KMS signature: %v", err)
+	}
+	var sig kmsSignature
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("failed to parse KMS signature: %v", err)
+	}
+	return recoverableSignature(digest, sig.R, sig.S, s.publicKey)
+}
+
+// recoverableSignature turns an ECDSA signature by publicKey into the [R || S || V] form
+// Ethereum expects: S in the lower half of the group order and V the recovery ID
+func recoverableSignature(digest []byte, r, sigS *big.Int, publicKey []byte) ([]byte, error) {
+	if sigS.Cmp(secp256k1HalfN) > 0 {
+		sigS = new(big.Int).Sub(secp256k1N, sigS)
+	}
+
+	signature := make([]byte, crypto.SignatureLength)
+	r.FillBytes(signature[:32])
+	sigS.FillBytes(signature[32:64])
+	for v := byte(0); v < 2; v++ {
+		signature[crypto.RecoveryIDOffset] = v
+		if pub, err := crypto.Ecrecover(digest, signature); err == nil && bytes.Equal(pub, publicKey) {
+			return signature, nil
+		}
+	}
+	return nil, fmt.Errorf("signature does not recover to the signing key")
+}
+
+// call invokes a KMS action with a request signed by AWS Signature Version 4
+func (s *KMSSigner) call(ctx context.Context, action string, request, result any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	s.signRequest(req, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, result)
+}
+
+// signRequest adds the AWS Signature Version 4 headers for the kms service to req
+func (s *KMSSigner) signRequest(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + s.region + "/kms/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "kms", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
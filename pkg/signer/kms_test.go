@@ -0,0 +1,85 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS answers GetPublicKey and Sign like AWS KMS for key, returning high S values
+// half of the time as KMS does
+func fakeKMS(t *testing.T, key *ecdsa.PrivateKey) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test/"))
+
+		var request map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			der, err := asn1.Marshal(kmsPublicKey{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}},
+				PublicKey: asn1.BitString{Bytes: crypto.FromECDSAPub(&key.PublicKey), BitLength: 65 * 8},
+			})
+			require.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(der), "KeySpec": "ECC_SECG_P256K1"})
+		case "TrentService.Sign":
+			digest, err := base64.StdEncoding.DecodeString(request["Message"])
+			require.NoError(t, err)
+			sig, err := crypto.Sign(digest, key)
+			require.NoError(t, err)
+			s := new(big.Int).SetBytes(sig[32:64])
+			if digest[0]%2 == 0 {
+				s.Sub(secp256k1N, s)
+			}
+			der, err := asn1.Marshal(kmsSignature{R: new(big.Int).SetBytes(sig[:32]), S: s})
+			require.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string]string{"Signature": base64.StdEncoding.EncodeToString(der)})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestKMSSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	server := fakeKMS(t, key)
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	ctx := context.Background()
+	s, err := NewKMSSigner(ctx, KMSConfig{KeyID: "alias/sequencer", Region: "eu-west-1", Endpoint: server.URL})
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), s.Address())
+
+	for _, message := range []string{"prepare", "commit", "view-change", "checkpoint"} {
+		signature, err := s.SignText(ctx, []byte(message))
+		require.NoError(t, err)
+		require.True(t, new(big.Int).SetBytes(signature[32:64]).Cmp(secp256k1HalfN) <= 0)
+		from, err := RecoverText([]byte(message), signature)
+		require.NoError(t, err)
+		require.Equal(t, s.Address(), from)
+	}
+
+	chainID := big.NewInt(1337)
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 1, To: &common.Address{1}, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Value: big.NewInt(0)})
+	signed, err := s.SignTx(ctx, tx, chainID)
+	require.NoError(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, s.Address(), sender)
+}
@@ -0,0 +1,142 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RemoteSigner signs through an external signing service, either one speaking the Ethereum
+// JSON-RPC signing methods eth_sign and eth_signTransaction, such as Web3Signer in eth1
+// mode, or Clef through its account_signData and account_signTransaction methods. The key
+// never enters this process.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+	clef    bool
+}
+
+// signTxResult is the response of eth_signTransaction
+type signTxResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+// NewRemoteSigner connects to the signing service at url, which must hold the key of address
+func NewRemoteSigner(ctx context.Context, url string, address common.Address) (*RemoteSigner, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to signer at %s: %v", url, err)
+	}
+	return &RemoteSigner{client: client, address: address}, nil
+}
+
+// NewClefSigner connects to the Clef instance at url, which must hold the key of address.
+// Clef asks its operator or rules file to approve every request.
+func NewClefSigner(ctx context.Context, url string, address common.Address) (*RemoteSigner, error) {
+	s, err := NewRemoteSigner(ctx, url, address)
+	if err != nil {
+		return nil, err
+	}
+	s.clef = true
+	return s, nil
+}
+
+// Address returns the address of the remote key
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx has the service sign tx and checks that the signature is from the expected key
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := map[string]interface{}{
+		"from":    s.address,
+		"gas":     hexutil.Uint64(tx.Gas()),
+		"value":   (*hexutil.Big)(tx.Value()),
+		"nonce":   hexutil.Uint64(tx.Nonce()),
+		"data":    hexutil.Bytes(tx.Data()),
+		"chainId": (*hexutil.Big)(chainID),
+	}
+	if tx.To() != nil {
+		args["to"] = tx.To()
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+		args["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args["gasPrice"] = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	method := "eth_signTransaction"
+	if s.clef {
+		method = "account_signTransaction"
+	}
+	var result signTxResult
+	if err := s.client.CallContext(ctx, &result, method, args); err != nil {
+		return nil, fmt.Errorf("remote signer failed to sign transaction: %v", err)
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(result.Raw); err != nil {
+		return nil, fmt.Errorf("failed to decode signed transaction: %v", err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover transaction signer: %v", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s, expected %s", sender.Hex(), s.address.Hex())
+	}
+	if !sameUnsigned(signed, tx) {
+		return nil, fmt.Errorf("remote signer changed the transaction")
+	}
+	return signed, nil
+}
+
+// SignText has the service sign data as an EIP-191 personal message
+func (s *RemoteSigner) SignText(ctx context.Context, data []byte) ([]byte, error) {
+	var signature hexutil.Bytes
+	var err error
+	if s.clef {
+		err = s.client.CallContext(ctx, &signature, "account_signData", accounts.MimetypeTextPlain, s.address, hexutil.Bytes(data))
+	} else {
+		err = s.client.CallContext(ctx, &signature, "eth_sign", s.address, hexutil.Bytes(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("remote signer failed to sign message: %v", err)
+	}
+
+	signer, err := RecoverText(data, signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s, expected %s", signer.Hex(), s.address.Hex())
+	}
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+	return signature, nil
+}
+
+// Close disconnects from the signing service
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// sameUnsigned reports whether two transactions carry the same payload, ignoring signatures
+func sameUnsigned(a, b *types.Transaction) bool {
+	return a.Type() == b.Type() &&
+		a.Nonce() == b.Nonce() &&
+		a.Gas() == b.Gas() &&
+		a.GasPrice().Cmp(b.GasPrice()) == 0 &&
+		a.GasTipCap().Cmp(b.GasTipCap()) == 0 &&
+		a.Value().Cmp(b.Value()) == 0 &&
+		((a.To() == nil && b.To() == nil) || (a.To() != nil && b.To() != nil && *a.To() == *b.To())) &&
+		string(a.Data()) == string(b.Data())
+}
@@ -0,0 +1,80 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs with a secp256k1 key that may live outside the process, such as in an HSM
+// behind a signing service
+type Signer interface {
+	// Address returns the Ethereum address of the signing key
+	Address() common.Address
+
+	// SignTx signs an L1 transaction for the given chain
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+	// SignText signs data as an EIP-191 personal message, returning a 65 byte [R || S || V]
+	// signature with V 0 or 1
+	SignText(ctx context.Context, data []byte) ([]byte, error)
+}
+
+// LocalSigner signs with a private key held in memory
+type LocalSigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewLocalSigner creates a signer for key
+func NewLocalSigner(key *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// NewLocalSignerFromHex creates a signer for a hex encoded private key
+func NewLocalSignerFromHex(hexKey string) (*LocalSigner, error) {
+	key, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private key: %v", err)
+	}
+	return NewLocalSigner(key), nil
+}
+
+// Address returns the address of the key
+func (s *LocalSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs tx for chainID
+func (s *LocalSigner) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// SignText signs data as an EIP-191 personal message
+func (s *LocalSigner) SignText(_ context.Context, data []byte) ([]byte, error) {
+	return crypto.Sign(accounts.TextHash(data), s.key)
+}
+
+// RecoverText returns the address that signed data as an EIP-191 personal message. V may
+// be 0, 1, 27 or 28.
+func RecoverText(data, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d", len(signature))
+	}
+	sig := append([]byte(nil), signature...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(accounts.TextHash(data), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,64 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	s := NewLocalSigner(key)
+	ctx := context.Background()
+
+	signature, err := s.SignText(ctx, []byte("prepare"))
+	require.NoError(t, err)
+	from, err := RecoverText([]byte("prepare"), signature)
+	require.NoError(t, err)
+	require.Equal(t, s.Address(), from)
+
+	// Signatures with V of 27 or 28 recover the same address
+	signature[64] += 27
+	from, err = RecoverText([]byte("prepare"), signature)
+	require.NoError(t, err)
+	require.Equal(t, s.Address(), from)
+
+	from, err = RecoverText([]byte("commit"), signature)
+	require.NoError(t, err)
+	require.NotEqual(t, s.Address(), from)
+
+	chainID := big.NewInt(1337)
+	tx := types.NewTransaction(1, common.Address{1}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	signed, err := s.SignTx(ctx, tx, chainID)
+	require.NoError(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, s.Address(), sender)
+	require.True(t, sameUnsigned(tx, signed))
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	data, err := keystore.EncryptKey(&keystore.Key{Address: address, PrivateKey: key}, "hunter2", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	s, err := NewKeystoreSigner(path, "hunter2")
+	require.NoError(t, err)
+	require.Equal(t, address, s.Address())
+
+	_, err = NewKeystoreSigner(path, "wrong")
+	require.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"zkrollup/pkg/types"
+)
+
+// archive keeps past versions of accounts and storage slots so they can be read as of any
+// retained batch. Each key holds its value when the archive was enabled followed by one
+// version per batch that changed it. History is kept in memory and starts over when the
+// node restarts.
+type archive struct {
+	retain    uint64 // Batches kept behind the latest, 0 keeps all
+	oldest    uint64 // Oldest batch that can be queried
+	lastSweep uint64 // Cutoff of the last sweep dropping versions no longer needed
+
+	accounts map[types.Address][]accountVersion
+	slots    map[types.Address]map[[32]byte][]slotVersion
+
+	// Keys changed since the last finalized batch
+	changedAccounts map[types.Address]struct{}
+	changedSlots    map[types.Address]map[[32]byte]struct{}
+}
+
+type accountVersion struct {
+	batch   uint64
+	account *Account // nil when the account did not exist
+}
+
+type slotVersion struct {
+	batch uint64
+	value [32]byte
+}
+
+// EnableArchive keeps the state after each of the last retain batches queryable, 0 keeps
+// every batch finalized from now on. It must be called before the state is shared.
+func (s *State) EnableArchive(retain uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.archive = &archive{retain: retain}
+	s.seedArchiveLocked()
+}
+
+// seedArchiveLocked records the current state as the first version of every key. Must be
+// called with s.mu held.
+func (s *State) seedArchiveLocked() {
+	a := s.archive
+	a.oldest = 0
+	if s.batchNumber > 0 {
+		a.oldest = s.batchNumber - 1
+	}
+	a.lastSweep = a.oldest
+	a.accounts = make(map[types.Address][]accountVersion, len(s.accounts))
+	a.slots = make(map[types.Address]map[[32]byte][]slotVersion, len(s.storage))
+	a.changedAccounts = nil
+	a.changedSlots = nil
+
+	for addr, account := range s.accounts {
+		a.accounts[addr] = []accountVersion{{batch: a.oldest, account: copyAccount(account)}}
+	}
+	for addr, slots := range s.storage {
+		versions := make(map[[32]byte][]slotVersion, len(slots))
+		for key, value := range slots {
+			versions[key] = []slotVersion{{batch: a.oldest, value: value}}
+		}
+		a.slots[addr] = versions
+	}
+}
+
+// OldestRetainedBatch returns the oldest batch whose state can still be queried, the latest
+// batch when no archive is kept
+func (s *State) OldestRetainedBatch() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.archive != nil {
+		return s.archive.oldest
+	}
+	if s.batchNumber == 0 {
+		return 0
+	}
+	return s.batchNumber - 1
+}
+
+// GetAccountAt returns an account as it was after batch batchNumber
+func (s *State) GetAccountAt(address types.Address, batchNumber uint64) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.retainedLocked(batchNumber); err != nil {
+		return nil, err
+	}
+
+	var account *Account
+	if batchNumber == s.batchNumber-1 {
+		account = s.accounts[address]
+	} else {
+		account = s.archive.accountAt(address, batchNumber)
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+	return copyAccount(account), nil
+}
+
+// retainedLocked checks that the state after batchNumber can be read. Must be called with
+// s.mu held.
+func (s *State) retainedLocked(batchNumber uint64) error {
+	if s.batchNumber == 0 || batchNumber >= s.batchNumber {
+		return fmt.Errorf("%w: batch %d is not finalized", ErrStateNotRetained, batchNumber)
+	}
+	if batchNumber == s.batchNumber-1 {
+		return nil
+	}
+	if s.archive == nil || batchNumber < s.archive.oldest {
+		return fmt.Errorf("%w: batch %d", ErrStateNotRetained, batchNumber)
+	}
+	return nil
+}
+
+// markAccount records that an account changed in the batch being executed
+func (a *archive) markAccount(address types.Address) {
+	if a == nil {
+		return
+	}
+	if a.changedAccounts == nil {
+		a.changedAccounts = make(map[types.Address]struct{})
+	}
+	a.changedAccounts[address] = struct{}{}
+}
+
+// markSlot records that a storage slot changed in the batch being executed
+func (a *archive) markSlot(address types.Address, key [32]byte) {
+	if a == nil {
+		return
+	}
+	if a.changedSlots == nil {
+		a.changedSlots = make(map[types.Address]map[[32]byte]struct{})
+	}
+	if a.changedSlots[address] == nil {
+		a.changedSlots[address] = make(map[[32]byte]struct{})
+	}
+	a.changedSlots[address][key] = struct{}{}
+}
+
+// recordArchiveLocked stores a version of every key changed by batch n and drops versions
+// that fell out of retention. Must be called with s.mu held.
+func (s *State) recordArchiveLocked(n uint64) {
+	a := s.archive
+	if a == nil {
+		return
+	}
+
+	for addr := range a.changedAccounts {
+		a.accounts[addr] = appendAccountVersion(a.accounts[addr], accountVersion{batch: n, account: copyAccount(s.accounts[addr])})
+	}
+	for addr, keys := range a.changedSlots {
+		if a.slots[addr] == nil {
+			a.slots[addr] = make(map[[32]byte][]slotVersion, len(keys))
+		}
+		for key := range keys {
+			a.slots[addr][key] = appendSlotVersion(a.slots[addr][key], slotVersion{batch: n, value: s.storage[addr][key]})
+		}
+	}
+	a.changedAccounts = nil
+	a.changedSlots = nil
+
+	if a.retain == 0 || n < a.retain {
+		return
+	}
+	cutoff := n - a.retain
+	if cutoff > a.oldest {
+		a.oldest = cutoff
+	}
+
+	// Sweeping every key is amortized over retain batches, extra old versions do not
+	// change what queries return
+	if cutoff >= a.lastSweep+a.retain {
+		a.sweep(cutoff)
+		a.lastSweep = cutoff
+	}
+}
+
+// sweep drops the versions of every key that no batch from cutoff on can read
+func (a *archive) sweep(cutoff uint64) {
+	for addr, versions := range a.accounts {
+		i := sort.Search(len(versions), func(i int) bool { return versions[i].batch > cutoff })
+		if i > 1 {
+			versions = append(versions[:0], versions[i-1:]...)
+		}
+		if len(versions) == 1 && versions[0].account == nil && versions[0].batch <= cutoff {
+			delete(a.accounts, addr)
+			continue
+		}
+		a.accounts[addr] = versions
+	}
+	for addr, keys := range a.slots {
+		for key, versions := range keys {
+			i := sort.Search(len(versions), func(i int) bool { return versions[i].batch > cutoff })
+			if i > 1 {
+				versions = append(versions[:0], versions[i-1:]...)
+			}
+			if len(versions) == 1 && versions[0].value == ([32]byte{}) && versions[0].batch <= cutoff {
+				delete(keys, key)
+				continue
+			}
+			keys[key] = versions
+		}
+		if len(keys) == 0 {
+			delete(a.slots, addr)
+		}
+	}
+}
+
+// accountAt returns the account as it was after batch n, nil if it did not exist
+func (a *archive) accountAt(address types.Address, n uint64) *Account {
+	versions := a.accounts[address]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].batch > n })
+	if i == 0 {
+		return nil
+	}
+	return versions[i-1].account
+}
+
+// slotAt returns the value of a storage slot after batch n, zero if it was never written
+func (a *archive) slotAt(address types.Address, key [32]byte, n uint64) [32]byte {
+	versions := a.slots[address][key]
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].batch > n })
+	if i == 0 {
+		return [32]byte{}
+	}
+	return versions[i-1].value
+}
+
+// appendAccountVersion appends v, replacing a version of the same batch
+func appendAccountVersion(versions []accountVersion, v accountVersion) []accountVersion {
+	if last := len(versions) - 1; last >= 0 && versions[last].batch == v.batch {
+		versions[last] = v
+		return versions
+	}
+	return append(versions, v)
+}
+
+// appendSlotVersion appends v, replacing a version of the same batch
+func appendSlotVersion(versions []slotVersion, v slotVersion) []slotVersion {
+	if last := len(versions) - 1; last >= 0 && versions[last].batch == v.batch {
+		versions[last] = v
+		return versions
+	}
+	return append(versions, v)
+}
+
+// copyAccount returns a deep copy of account, nil for nil
+func copyAccount(account *Account) *Account {
+	if account == nil {
+		return nil
+	}
+	acc := *account
+	if account.Balance != nil {
+		acc.Balance = new(big.Int).Set(account.Balance)
+	}
+	if account.AuthKey != nil {
+		acc.AuthKey = append([]byte(nil), account.AuthKey...)
+	}
+	return &acc
+}
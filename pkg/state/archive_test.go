@@ -0,0 +1,119 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestArchiveServesPastBatches(t *testing.T) {
+	s := NewState()
+	s.EnableArchive(2)
+
+	addr, contract := types.Address{1}, types.Address{2}
+	for n := int64(0); n < 5; n++ {
+		s.SetAccount(&Account{Address: addr, Balance: big.NewInt(100 + n), Nonce: uint64(n)})
+		s.SetStorage(contract, [32]byte{1}, [32]byte{byte(n)})
+		if n == 3 {
+			s.DeleteAccount(addr)
+		}
+		s.AddBatch(&Batch{Timestamp: uint64(n)})
+	}
+
+	// Batches 2 to 4 are retained, the account was deleted in batch 3
+	acc, err := s.GetAccountAt(addr, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(102), acc.Balance.Int64())
+	_, err = s.GetAccountAt(addr, 3)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	acc, err = s.GetAccountAt(addr, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), acc.Nonce)
+
+	value, err := s.GetStorageAt(contract, [32]byte{1}, 2)
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{2}, value)
+	value, err = s.GetStorageAt(contract, [32]byte{9}, 3)
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{}, value)
+
+	_, err = s.GetAccountAt(addr, 1)
+	require.ErrorIs(t, err, ErrStateNotRetained)
+	_, err = s.GetStorageAt(contract, [32]byte{1}, 5)
+	require.ErrorIs(t, err, ErrStateNotRetained)
+	require.Equal(t, uint64(2), s.OldestRetainedBatch())
+
+	// Later changes do not leak into returned versions
+	acc.Balance.SetInt64(0)
+	again, err := s.GetAccountAt(addr, 4)
+	require.NoError(t, err)
+	require.Equal(t, int64(104), again.Balance.Int64())
+}
+
+func TestArchiveDisabledKeepsLatestOnly(t *testing.T) {
+	s := NewState()
+	s.SetAccount(&Account{Address: types.Address{1}, Balance: big.NewInt(1)})
+	s.AddBatch(&Batch{})
+	s.AddBatch(&Batch{})
+
+	_, err := s.GetAccountAt(types.Address{1}, 0)
+	require.ErrorIs(t, err, ErrStateNotRetained)
+	acc, err := s.GetAccountAt(types.Address{1}, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), acc.Balance.Int64())
+}
+
+func TestArchiveProvesPastBatches(t *testing.T) {
+	s := NewState()
+	s.EnableArchive(0)
+
+	addr, contract, late := types.Address{1}, types.Address{2}, types.Address{3}
+	var roots [][32]byte
+	for n := int64(0); n < 4; n++ {
+		s.SetAccount(&Account{Address: addr, Balance: big.NewInt(100 + n), Nonce: uint64(n)})
+		s.SetStorage(contract, [32]byte{byte(n)}, [32]byte{byte(n + 1)})
+		if n == 2 {
+			s.SetCode(late, []byte{0x60, 0x00})
+			s.SetAccount(&Account{Address: late, Nonce: 1})
+		}
+		s.AddBatch(&Batch{})
+		roots = append(roots, s.GetStateRoot())
+	}
+	// Changes of the batch being executed are not part of any finalized batch
+	s.SetStorage(contract, [32]byte{0}, [32]byte{9})
+
+	for n := uint64(0); n < 4; n++ {
+		proof, err := s.GetProofAt(contract, [][32]byte{{0}, {1}, {7}}, n)
+		require.NoError(t, err)
+		require.Equal(t, roots[n], proof.StateRoot)
+		require.True(t, proof.Verify(nil))
+		require.Equal(t, [32]byte{1}, proof.Storage[0].Value)
+		if n == 0 {
+			require.Equal(t, [32]byte{}, proof.Storage[1].Value)
+		} else {
+			require.Equal(t, [32]byte{2}, proof.Storage[1].Value)
+		}
+
+		proof, err = s.GetProofAt(addr, nil, n)
+		require.NoError(t, err)
+		require.True(t, proof.Verify(nil))
+		require.Equal(t, int64(100+n), proof.Account.Balance.Int64())
+
+		proof, err = s.GetProofAt(late, nil, n)
+		require.NoError(t, err)
+		require.True(t, proof.Verify(nil))
+		require.Equal(t, n >= 2, proof.Account.Exists)
+	}
+
+	// A tampered value no longer verifies
+	proof, err := s.GetProofAt(contract, [][32]byte{{1}}, 1)
+	require.NoError(t, err)
+	proof.Storage[0].Value = [32]byte{5}
+	require.False(t, proof.Verify(nil))
+
+	_, err = s.GetProofAt(addr, nil, 4)
+	require.ErrorIs(t, err, ErrStateNotRetained)
+}
@@ -0,0 +1,174 @@
+package state
+
+import (
+	"math/big"
+	"sync"
+
+	"zkrollup/pkg/types"
+)
+
+// A checkpoint lets a caller try changes on a state and undo them without copying the
+// whole state, as the batch builder does with each candidate transaction. Every account,
+// code and storage slot is recorded as it was at the checkpoint the first time it is
+// touched afterwards. Accounts are recorded when read too, since callers change the
+// accounts GetAccount returns in place before storing them back.
+type checkpoint struct {
+	mu       sync.Mutex                               // Accounts are recorded under s.mu held for reading
+	accounts map[types.Address]*Account               // Nil for accounts that did not exist
+	code     map[types.Address][]byte                 // Nil for addresses without code
+	slots    map[types.Address]map[[32]byte]*[32]byte // Nil for slots never written
+	storage  map[types.Address]bool                   // Whether the address had storage
+
+	depositNonce uint64
+	logs         int
+	receipts     int
+	txHash       [32]byte
+	txIndex      uint
+	txGasUsed    uint64
+	txContract   types.Address
+}
+
+// Checkpoint starts recording changes so RevertToCheckpoint can undo them, replacing any
+// earlier checkpoint. It is meant for in-memory copies, writes already buffered for a
+// persistent backend are not undone.
+func (s *State) Checkpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoint = &checkpoint{
+		accounts:     make(map[types.Address]*Account),
+		code:         make(map[types.Address][]byte),
+		slots:        make(map[types.Address]map[[32]byte]*[32]byte),
+		storage:      make(map[types.Address]bool),
+		depositNonce: s.depositNonce,
+		logs:         len(s.pendingLogs),
+		receipts:     len(s.pendingReceipts),
+		txHash:       s.txHash,
+		txIndex:      s.txIndex,
+		txGasUsed:    s.txGasUsed,
+		txContract:   s.txContract,
+	}
+}
+
+// RevertToCheckpoint undoes every change made since Checkpoint and stops recording
+func (s *State) RevertToCheckpoint() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp := s.checkpoint
+	if cp == nil {
+		return
+	}
+	s.checkpoint = nil
+
+	for addr, account := range cp.accounts {
+		if account == nil {
+			delete(s.accounts, addr)
+		} else {
+			s.accounts[addr] = account
+		}
+		s.markAccountLocked(addr)
+	}
+	for addr, code := range cp.code {
+		if code == nil {
+			delete(s.code, addr)
+		} else {
+			s.code[addr] = code
+		}
+		s.markAccountLocked(addr)
+	}
+	for addr, slots := range cp.slots {
+		if s.storage[addr] == nil {
+			s.storage[addr] = make(map[[32]byte][32]byte, len(slots))
+		}
+		for key, value := range slots {
+			if value == nil {
+				delete(s.storage[addr], key)
+			} else {
+				s.storage[addr][key] = *value
+			}
+			s.markSlotLocked(addr, key)
+		}
+	}
+	for addr, had := range cp.storage {
+		if !had {
+			delete(s.storage, addr)
+		}
+	}
+
+	s.depositNonce = cp.depositNonce
+	s.pendingLogs = s.pendingLogs[:cp.logs]
+	s.pendingReceipts = s.pendingReceipts[:cp.receipts]
+	s.txHash = cp.txHash
+	s.txIndex = cp.txIndex
+	s.txGasUsed = cp.txGasUsed
+	s.txContract = cp.txContract
+}
+
+// touchAccountLocked records the account at address before it is first changed. Must be
+// called with s.mu held, for reading at least.
+func (s *State) touchAccountLocked(address types.Address) {
+	cp := s.checkpoint
+	if cp == nil {
+		return
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if _, ok := cp.accounts[address]; ok {
+		return
+	}
+	var saved *Account
+	if account, ok := s.accounts[address]; ok {
+		acc := *account
+		if account.Balance != nil {
+			acc.Balance = new(big.Int).Set(account.Balance)
+		}
+		acc.AuthKey = append([]byte(nil), account.AuthKey...)
+		saved = &acc
+	}
+	cp.accounts[address] = saved
+}
+
+// touchCodeLocked records the code at address before it is first changed. Must be called
+// with s.mu held for writing.
+func (s *State) touchCodeLocked(address types.Address) {
+	cp := s.checkpoint
+	if cp == nil {
+		return
+	}
+	if _, ok := cp.code[address]; ok {
+		return
+	}
+	code, ok := s.code[address]
+	if ok && code == nil {
+		code = []byte{}
+	}
+	cp.code[address] = code
+}
+
+// touchSlotLocked records a storage slot before it is first changed. Must be called with
+// s.mu held for writing.
+func (s *State) touchSlotLocked(address types.Address, key [32]byte) {
+	cp := s.checkpoint
+	if cp == nil {
+		return
+	}
+	if _, ok := cp.storage[address]; !ok {
+		_, had := s.storage[address]
+		cp.storage[address] = had
+	}
+	slots := cp.slots[address]
+	if slots == nil {
+		slots = make(map[[32]byte]*[32]byte)
+		cp.slots[address] = slots
+	}
+	if _, ok := slots[key]; ok {
+		return
+	}
+	var saved *[32]byte
+	if value, ok := s.storage[address][key]; ok {
+		saved = &value
+	}
+	slots[key] = saved
+}
@@ -0,0 +1,74 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestRevertToCheckpoint(t *testing.T) {
+	st := NewState()
+	sender, contract, fresh := types.Address{1}, types.Address{2}, types.Address{3}
+	st.SetAccount(&Account{Address: sender, Balance: big.NewInt(100), Nonce: 1})
+	st.SetAccount(&Account{Address: contract, Balance: big.NewInt(5)})
+	st.SetCode(contract, []byte{0x60, 0x00})
+	st.SetStorage(contract, [32]byte{1}, [32]byte{2})
+	require.NoError(t, st.ApplyDeposit(0, sender, big.NewInt(1)))
+	st.SetTxContext([32]byte{9}, 0)
+	st.RecordReceipt(nil)
+
+	root := st.GetStateRoot()
+	before := st.Copy()
+
+	st.Checkpoint()
+	// Accounts read before a change are restored even when changed in place
+	acc, err := st.GetAccount(sender)
+	require.NoError(t, err)
+	acc.Nonce++
+	acc.Balance.Sub(acc.Balance, big.NewInt(10))
+	st.SetAccount(acc)
+	st.SetAccount(&Account{Address: fresh, Balance: big.NewInt(7)})
+	st.SetStorage(contract, [32]byte{1}, [32]byte{3})
+	st.SetStorage(fresh, [32]byte{4}, [32]byte{5})
+	st.SetCode(fresh, []byte{0x00})
+	require.NoError(t, st.ApplyDeposit(1, fresh, big.NewInt(1)))
+	st.DestroyAccount(contract)
+	st.SetTxContext([32]byte{8}, 1)
+	st.UseGas(21000)
+	st.RecordReceipt(nil)
+	require.NotEqual(t, root, st.GetStateRoot())
+
+	st.RevertToCheckpoint()
+	require.Equal(t, root, st.GetStateRoot())
+	require.Equal(t, before.GetDepositNonce(), st.GetDepositNonce())
+
+	acc, err = st.GetAccount(sender)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), acc.Nonce)
+	require.Equal(t, "101", acc.Balance.String())
+	_, err = st.GetAccount(fresh)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = st.GetCode(fresh)
+	require.ErrorIs(t, err, ErrCodeNotFound)
+	_, err = st.GetStorage(fresh, [32]byte{4})
+	require.ErrorIs(t, err, ErrStorageNotFound)
+	code, err := st.GetCode(contract)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x60, 0x00}, code)
+	slot, err := st.GetStorage(contract, [32]byte{1})
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{2}, slot)
+
+	// Only the receipts recorded before the checkpoint are kept
+	require.Len(t, st.pendingReceipts, 1)
+	require.Equal(t, [32]byte{9}, st.pendingReceipts[0].TxHash)
+
+	// Without a checkpoint there is nothing to revert
+	st.SetAccount(&Account{Address: fresh, Balance: big.NewInt(7)})
+	st.RevertToCheckpoint()
+	_, err = st.GetAccount(fresh)
+	require.NoError(t, err)
+}
@@ -0,0 +1,219 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Commit policies, deciding when the writes buffered for finalized batches reach the database
+const (
+	CommitAlways  = "always"  // Every Commit writes and syncs before returning
+	CommitBatches = "batches" // Every Interval-th Commit writes and syncs the batches buffered so far
+	CommitAsync   = "async"   // Every Commit hands its writes to a background writer, without syncing
+)
+
+// asyncQueueSize is the number of commits the background writer may fall behind before
+// Commit blocks
+const asyncQueueSize = 64
+
+// CommitPolicy decides how often Commit flushes buffered writes to the database. Whatever
+// the policy, the writes of one flush are applied atomically.
+type CommitPolicy struct {
+	Mode     string
+	Interval int // Commits per flush in CommitBatches mode
+}
+
+// FlushObserver is called with the time each flush took to write and sync
+type FlushObserver func(time.Duration)
+
+// keyValueSyncer is implemented by databases that can force written data to disk. Writes
+// to a database without it are synced whenever the database flushes its log.
+type keyValueSyncer interface {
+	SyncKeyValue() error
+}
+
+// asyncWriter applies committed batches in order on a background goroutine
+type asyncWriter struct {
+	queue   chan ethdb.Batch
+	pending sync.WaitGroup
+	done    chan struct{}
+
+	mu  sync.Mutex
+	err error // First failed write, reported by the next Commit
+}
+
+// SetCommitPolicy sets when Commit flushes buffered writes, the default flushes on every
+// Commit. It must be called before the state is shared.
+func (s *State) SetCommitPolicy(policy CommitPolicy) error {
+	switch policy.Mode {
+	case "", CommitAlways, CommitAsync:
+	case CommitBatches:
+		if policy.Interval <= 0 {
+			return fmt.Errorf("commit interval must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown commit policy %q", policy.Mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policy = policy
+	if policy.Mode == CommitAsync && s.writer == nil && s.db != nil && !s.readOnly {
+		s.writer = &asyncWriter{
+			queue: make(chan ethdb.Batch, asyncQueueSize),
+			done:  make(chan struct{}),
+		}
+		go s.writeAsync(s.writer)
+	}
+	return nil
+}
+
+// SetFlushObserver registers a function called with the duration of every flush
+func (s *State) SetFlushObserver(observer FlushObserver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flushObserver = observer
+}
+
+// Commit persists the writes buffered since the last Commit according to the commit
+// policy. It is a no-op for an in-memory state.
+func (s *State) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if s.pending.ValueSize() == 0 {
+		return nil
+	}
+
+	switch s.policy.Mode {
+	case CommitBatches:
+		s.unflushed++
+		if s.unflushed < s.policy.Interval {
+			return nil
+		}
+	case CommitAsync:
+		return s.commitAsyncLocked()
+	}
+	return s.flushLocked()
+}
+
+// Flush writes and syncs every buffered write regardless of the commit policy, for changes
+// that must be durable before the caller goes on
+func (s *State) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flushLocked()
+}
+
+// flushLocked writes the pending batch after any queued background writes and syncs the
+// database. Must be called with s.mu held.
+func (s *State) flushLocked() error {
+	if s.db == nil {
+		return nil
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if s.writer != nil {
+		s.writer.pending.Wait()
+		if err := s.writer.takeErr(); err != nil {
+			return err
+		}
+	}
+	if s.pending.ValueSize() == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	if err := s.pending.Write(); err != nil {
+		return fmt.Errorf("failed to write state batch: %v", err)
+	}
+	s.pending.Reset()
+	s.unflushed = 0
+
+	if db, ok := s.db.(keyValueSyncer); ok {
+		if err := db.SyncKeyValue(); err != nil {
+			return fmt.Errorf("failed to sync state database: %v", err)
+		}
+	}
+	s.observeFlush(time.Since(start))
+	return nil
+}
+
+// commitAsyncLocked queues the pending batch for the background writer, reporting an
+// earlier failed write. Must be called with s.mu held.
+func (s *State) commitAsyncLocked() error {
+	if err := s.writer.takeErr(); err != nil {
+		return err
+	}
+
+	batch := s.pending
+	s.pending = s.db.NewBatch()
+	s.writer.pending.Add(1)
+	s.writer.queue <- batch
+	return nil
+}
+
+// writeAsync applies queued batches until the queue is closed
+func (s *State) writeAsync(w *asyncWriter) {
+	defer close(w.done)
+
+	for batch := range w.queue {
+		start := time.Now()
+		if err := batch.Write(); err != nil {
+			w.setErr(fmt.Errorf("failed to write state batch: %v", err))
+		} else {
+			s.observeFlush(time.Since(start))
+		}
+		w.pending.Done()
+	}
+}
+
+// stopWriterLocked stops the background writer once its queue is drained. Must be called
+// with s.mu held.
+func (s *State) stopWriterLocked() {
+	if s.writer == nil {
+		return
+	}
+	close(s.writer.queue)
+	<-s.writer.done
+	s.writer = nil
+}
+
+// observeFlush reports a flush duration to the observer, which is set before the state
+// is shared
+func (s *State) observeFlush(d time.Duration) {
+	if s.flushObserver != nil {
+		s.flushObserver(d)
+	}
+}
+
+func (w *asyncWriter) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *asyncWriter) takeErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.err
+	w.err = nil
+	return err
+}
@@ -0,0 +1,63 @@
+package state
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"zkrollup/pkg/types"
+)
+
+// Deposit errors
+var (
+	ErrDepositReplayed   = errors.New("deposit already credited")
+	ErrDepositOutOfOrder = errors.New("deposit nonce out of order")
+)
+
+var depositNonceKey = []byte("m:depositNonce")
+
+// GetDepositNonce returns the nonce of the next L1 deposit to credit, which is also the
+// number of deposits credited so far
+func (s *State) GetDepositNonce() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.depositNonce
+}
+
+// ApplyDeposit credits amount to recipient for the L1 deposit with the given nonce.
+// Deposits must be applied in nonce order and each exactly once.
+func (s *State) ApplyDeposit(nonce uint64, recipient types.Address, amount *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if nonce < s.depositNonce {
+		return fmt.Errorf("%w: nonce %d", ErrDepositReplayed, nonce)
+	}
+	if nonce > s.depositNonce {
+		return fmt.Errorf("%w: expected %d, got %d", ErrDepositOutOfOrder, s.depositNonce, nonce)
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return fmt.Errorf("invalid deposit amount")
+	}
+
+	s.touchAccountLocked(recipient)
+	account := &Account{Address: recipient, Balance: new(big.Int).Set(amount)}
+	if existing, ok := s.accounts[recipient]; ok {
+		account.Nonce = existing.Nonce
+		if existing.Balance != nil {
+			account.Balance.Add(account.Balance, existing.Balance)
+		}
+	}
+	account.CodeHash = CodeHash(s.code[recipient])
+	s.accounts[recipient] = account
+	s.markAccountLocked(recipient)
+	s.persistAccount(account)
+
+	s.depositNonce++
+	var value [8]byte
+	binary.BigEndian.PutUint64(value[:], s.depositNonce)
+	s.persist(depositNonceKey, value[:])
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestApplyDepositReplayProtection(t *testing.T) {
+	db := memorydb.New()
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+
+	recipient := types.Address{7}
+	s.SetAccount(&Account{Address: recipient, Balance: big.NewInt(5), Nonce: 2})
+
+	require.ErrorIs(t, s.ApplyDeposit(1, recipient, big.NewInt(10)), ErrDepositOutOfOrder)
+	require.NoError(t, s.ApplyDeposit(0, recipient, big.NewInt(10)))
+	require.ErrorIs(t, s.ApplyDeposit(0, recipient, big.NewInt(10)), ErrDepositReplayed)
+	require.NoError(t, s.ApplyDeposit(1, recipient, big.NewInt(1)))
+
+	acc, err := s.GetAccount(recipient)
+	require.NoError(t, err)
+	require.Equal(t, int64(16), acc.Balance.Int64())
+	require.Equal(t, uint64(2), acc.Nonce)
+
+	// The deposit nonce survives a restart so deposits are never credited twice
+	require.NoError(t, s.Commit())
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), reopened.GetDepositNonce())
+	require.ErrorIs(t, reopened.ApplyDeposit(1, recipient, big.NewInt(1)), ErrDepositReplayed)
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/types"
+)
+
+const (
+	// EdDSAPublicKeyLength is the size of a compressed EdDSA/BN254 public key
+	EdDSAPublicKeyLength = 32
+	// EdDSASignatureLength is the size of a compressed EdDSA/BN254 signature
+	EdDSASignatureLength = 64
+)
+
+// EdDSAAddress returns the address of the rollup-native account owning an EdDSA/BN254
+// public key: the last 20 bytes of the Keccak-256 hash of the compressed key
+func EdDSAAddress(publicKey []byte) (types.Address, error) {
+	if _, err := parseEdDSAKey(publicKey); err != nil {
+		return types.Address{}, err
+	}
+	return types.BytesToAddress(crypto.Keccak256(publicKey)[12:]), nil
+}
+
+// parseEdDSAKey decodes a compressed EdDSA/BN254 public key
+func parseEdDSAKey(publicKey []byte) (*eddsa.PublicKey, error) {
+	if len(publicKey) != EdDSAPublicKeyLength {
+		return nil, fmt.Errorf("invalid EdDSA public key length: got %d bytes, want %d", len(publicKey), EdDSAPublicKeyLength)
+	}
+	var pub eddsa.PublicKey
+	if _, err := pub.SetBytes(publicKey); err != nil {
+		return nil, fmt.Errorf("invalid EdDSA public key: %v", err)
+	}
+	return &pub, nil
+}
+
+// eddsaMessage is the signing hash reduced to a BN254 scalar, the message EdDSA signs with
+// MiMC as the circuit does
+func (tx *Transaction) eddsaMessage(rollup types.Address) []byte {
+	hash := tx.SigningHash(rollup)
+	var e fr.Element
+	e.SetBytes(hash[:])
+	msg := e.Bytes()
+	return msg[:]
+}
+
+// VerifyEdDSA checks that the transaction was signed by the EdDSA/BN254 key publicKey for
+// the rollup contract rollup
+func (tx *Transaction) VerifyEdDSA(publicKey []byte, rollup types.Address) error {
+	if len(tx.Signature) != EdDSASignatureLength {
+		return fmt.Errorf("%w: got %d bytes, want %d for EdDSA", ErrInvalidSignature, len(tx.Signature), EdDSASignatureLength)
+	}
+	pub, err := parseEdDSAKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	ok, err := pub.Verify(tx.Signature, tx.eddsaMessage(rollup), mimc.NewMiMC())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: EdDSA signature does not match key %x", ErrInvalidSignature, publicKey)
+	}
+	return nil
+}
+
+// VerifyAuth checks the transaction's signature against the scheme of its sender. Accounts
+// with a registered EdDSA key, passed as authKey, must sign with it, as must the key
+// registration creating such an account. Every other account signs with secp256k1. The
+// signatures must be made for the rollup contract rollup.
+func (tx *Transaction) VerifyAuth(authKey []byte, rollup types.Address) error {
+	if tx.Type == TxTypeRegisterKey {
+		if len(authKey) > 0 {
+			return fmt.Errorf("account %s already has a registered key", tx.From)
+		}
+		owner, err := EdDSAAddress(tx.Data)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		if owner != tx.From {
+			return fmt.Errorf("%w: key belongs to %s, sender is %s", ErrInvalidSignature, owner, tx.From)
+		}
+		return tx.VerifyEdDSA(tx.Data, rollup)
+	}
+
+	if len(authKey) > 0 {
+		return tx.VerifyEdDSA(authKey, rollup)
+	}
+	return tx.VerifySignature(rollup)
+}
+
+// SignTransactionEdDSA signs a transaction's signing hash for the rollup contract rollup
+// with an EdDSA/BN254 private key
+func SignTransactionEdDSA(tx *Transaction, privateKey *eddsa.PrivateKey, rollup types.Address) ([]byte, error) {
+	signature, err := privateKey.Sign(tx.eddsaMessage(rollup), mimc.NewMiMC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %v", err)
+	}
+	return signature, nil
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestEdDSAAuth(t *testing.T) {
+	key, err := eddsa.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pub := key.PublicKey.Bytes()
+
+	address, err := EdDSAAddress(pub)
+	require.NoError(t, err)
+
+	// The registration is signed by the key it registers
+	register := &Transaction{
+		Type:   TxTypeRegisterKey,
+		From:   address,
+		Amount: new(big.Int),
+		Nonce:  1,
+		Data:   pub,
+	}
+	register.Signature, err = SignTransactionEdDSA(register, key, testRollup)
+	require.NoError(t, err)
+	require.NoError(t, register.VerifyAuth(nil, testRollup))
+
+	// A key can only be registered once
+	require.Error(t, register.VerifyAuth(pub, testRollup))
+
+	// The key must derive the sender's address
+	stolen := *register
+	stolen.From = types.Address{1}
+	require.ErrorIs(t, stolen.VerifyAuth(nil, testRollup), ErrInvalidSignature)
+
+	// Once registered, the account signs with the key
+	transfer := &Transaction{
+		Type:   TxTypeTransfer,
+		From:   address,
+		To:     types.Address{2},
+		Amount: big.NewInt(10),
+		Nonce:  2,
+	}
+	transfer.Signature, err = SignTransactionEdDSA(transfer, key, testRollup)
+	require.NoError(t, err)
+	require.NoError(t, transfer.VerifyAuth(pub, testRollup))
+
+	tampered := *transfer
+	tampered.Amount = big.NewInt(11)
+	require.ErrorIs(t, tampered.VerifyAuth(pub, testRollup), ErrInvalidSignature)
+
+	// Other accounts keep signing with secp256k1
+	ecdsaKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	legacy := &Transaction{
+		Type:   TxTypeTransfer,
+		From:   types.FromCommon(crypto.PubkeyToAddress(ecdsaKey.PublicKey)),
+		To:     types.Address{2},
+		Amount: big.NewInt(10),
+		Nonce:  1,
+	}
+	legacy.Signature, err = SignTransaction(legacy, crypto.FromECDSA(ecdsaKey), testRollup)
+	require.NoError(t, err)
+	require.NoError(t, legacy.VerifyAuth(nil, testRollup))
+	require.ErrorIs(t, legacy.VerifyAuth(pub, testRollup), ErrInvalidSignature)
+
+	_, err = EdDSAAddress([]byte{1, 2, 3})
+	require.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The emergency council nonce is node metadata kept outside the state tree
+var emergencyNonceKey = []byte("m:emergencyNonce")
+
+// EmergencyNonce returns the emergency council nonce stored in the database, 0 if none was
+// stored
+func (s *State) EmergencyNonce() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return 0
+	}
+	value, err := s.db.Get(emergencyNonceKey)
+	if err != nil || len(value) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(value)
+}
+
+// SetEmergencyNonce writes the emergency council nonce straight to the database, apart
+// from the buffered changes of the batch in progress
+func (s *State) SetEmergencyNonce(nonce uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	var value [8]byte
+	binary.BigEndian.PutUint64(value[:], nonce)
+	if err := s.db.Put(emergencyNonceKey, value[:]); err != nil {
+		return fmt.Errorf("failed to write emergency nonce: %v", err)
+	}
+	return nil
+}
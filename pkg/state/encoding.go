@@ -0,0 +1,186 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"zkrollup/pkg/types"
+)
+
+// txSigningRLP is the RLP layout of the fields a transaction's signature covers
+type txSigningRLP struct {
+	Type     uint8
+	From     types.Address
+	To       types.Address
+	Amount   *big.Int
+	Nonce    uint64
+	Gas      uint64
+	GasPrice *big.Int
+	Data     []byte
+	ChainID  uint64 `rlp:"optional"` // Left out when zero, unprotected transactions hash as before
+}
+
+// txRLP is the RLP layout of a signed transaction. The trace ID is local metadata and
+// is not encoded.
+type txRLP struct {
+	Type      uint8
+	From      types.Address
+	To        types.Address
+	Amount    *big.Int
+	Nonce     uint64
+	Gas       uint64
+	GasPrice  *big.Int
+	Data      []byte
+	Signature []byte
+	ChainID   uint64 `rlp:"optional"`
+}
+
+// bigOrZero returns v, or zero for nil so unset amounts encode like explicit zeros
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+// legacySigningHash returns the Keccak-256 hash of the RLP encoding of every signed field,
+// the message unprotected transactions are signed with. A transaction with a negative
+// amount or gas price has no encoding and hashes to zero.
+func (tx *Transaction) legacySigningHash() [32]byte {
+	payload, err := rlp.EncodeToBytes(&txSigningRLP{
+		Type:     uint8(tx.Type),
+		From:     tx.From,
+		To:       tx.To,
+		Amount:   bigOrZero(tx.Amount),
+		Nonce:    tx.Nonce,
+		Gas:      tx.Gas,
+		GasPrice: bigOrZero(tx.GasPrice),
+		Data:     tx.Data,
+		ChainID:  tx.ChainID,
+	})
+	if err != nil {
+		return [32]byte{}
+	}
+	return crypto.Keccak256Hash(payload)
+}
+
+// EncodeRLP implements rlp.Encoder
+func (tx *Transaction) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, &txRLP{
+		Type:      uint8(tx.Type),
+		From:      tx.From,
+		To:        tx.To,
+		Amount:    bigOrZero(tx.Amount),
+		Nonce:     tx.Nonce,
+		Gas:       tx.Gas,
+		GasPrice:  bigOrZero(tx.GasPrice),
+		Data:      tx.Data,
+		Signature: tx.Signature,
+		ChainID:   tx.ChainID,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder. A zero gas price decodes as unset.
+func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
+	var dec txRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+
+	*tx = Transaction{
+		Type:      TxType(dec.Type),
+		From:      dec.From,
+		To:        dec.To,
+		Amount:    dec.Amount,
+		Nonce:     dec.Nonce,
+		Data:      dec.Data,
+		Gas:       dec.Gas,
+		Signature: dec.Signature,
+		ChainID:   dec.ChainID,
+	}
+	if dec.GasPrice != nil && dec.GasPrice.Sign() > 0 {
+		tx.GasPrice = dec.GasPrice
+	}
+	return nil
+}
+
+// txAlias has the fields of Transaction without its JSON methods
+type txAlias Transaction
+
+// MarshalJSON encodes amounts as decimal strings so they survive JSON decoders that
+// parse numbers as floats. A nil amount encodes as "0".
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	if tx.Amount != nil && tx.Amount.Sign() < 0 {
+		return nil, fmt.Errorf("negative transaction amount %s", tx.Amount)
+	}
+	if tx.GasPrice != nil && tx.GasPrice.Sign() < 0 {
+		return nil, fmt.Errorf("negative gas price %s", tx.GasPrice)
+	}
+
+	enc := struct {
+		txAlias
+		Amount   string
+		GasPrice string `json:",omitempty"`
+	}{
+		txAlias: txAlias(tx),
+		Amount:  bigOrZero(tx.Amount).String(),
+	}
+	if tx.GasPrice != nil {
+		enc.GasPrice = tx.GasPrice.String()
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes amounts from decimal strings. Plain JSON numbers are accepted
+// for transactions stored before amounts were encoded as strings.
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	dec := struct {
+		*txAlias
+		Amount   json.RawMessage
+		GasPrice json.RawMessage
+	}{txAlias: (*txAlias)(tx)}
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+
+	amount, err := decodeAmountJSON(dec.Amount)
+	if err != nil {
+		return fmt.Errorf("invalid amount: %v", err)
+	}
+	tx.Amount = bigOrZero(amount)
+
+	tx.GasPrice, err = decodeAmountJSON(dec.GasPrice)
+	if err != nil {
+		return fmt.Errorf("invalid gas price: %v", err)
+	}
+	return nil
+}
+
+// decodeAmountJSON parses a non-negative integer from a JSON string or number. A
+// missing or null value decodes as nil.
+func decodeAmountJSON(raw json.RawMessage) (*big.Int, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	text := string(raw)
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+	}
+	value, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a decimal integer", text)
+	}
+	if value.Sign() < 0 {
+		return nil, fmt.Errorf("negative value %s", value)
+	}
+	return value, nil
+}
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// exportFormat is the version of the export file layout
+const exportFormat = 1
+
+// exportFile is the serialized form of a state: the snapshot at its height and the batch
+// history leading to it. Entries are sorted, so equal states export byte for byte equal.
+type exportFile struct {
+	Format      int
+	RootVersion RootVersion
+	Snapshot    *Snapshot
+	Batches     []Batch
+}
+
+// Export writes the full state, accounts, code, storage and batches, as canonical JSON.
+// Trace IDs are local to the node that received a transaction and are left out.
+func (s *State) Export(w io.Writer) error {
+	s.mu.Lock()
+	file := exportFile{
+		Format:      exportFormat,
+		RootVersion: s.rootVersion,
+		Snapshot:    s.snapshotLocked(),
+		Batches:     append([]Batch(nil), s.batches...),
+	}
+	s.mu.Unlock()
+
+	for i := range file.Batches {
+		batch := &file.Batches[i]
+		batch.Transactions = append([]Transaction(nil), batch.Transactions...)
+		for j := range batch.Transactions {
+			batch.Transactions[j].TraceID = ""
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(&file); err != nil {
+		return fmt.Errorf("failed to write state export: %v", err)
+	}
+	return nil
+}
+
+// Import replaces the state with an export. The local batch history must be empty or a
+// prefix of the exported one, and the import is rejected if the accounts do not hash to
+// the exported state root. Changes are flushed to the database before returning.
+func (s *State) Import(r io.Reader) error {
+	var file exportFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("failed to read state export: %v", err)
+	}
+	if file.Format != exportFormat {
+		return fmt.Errorf("unsupported state export format %d", file.Format)
+	}
+	if file.Snapshot == nil {
+		return fmt.Errorf("state export holds no snapshot")
+	}
+
+	if err := s.SetRootVersion(file.RootVersion); err != nil {
+		return err
+	}
+
+	local := s.GetBatches(0, uint64(len(file.Batches)))
+	if uint64(len(local)) != s.GetBatchNumber() {
+		return fmt.Errorf("local state has %d batches, more than the %d exported", s.GetBatchNumber(), len(file.Batches))
+	}
+	for i := range local {
+		if local[i].StateRoot != file.Batches[i].StateRoot {
+			return fmt.Errorf("local batch %d has state root %x, the export has %x", i, local[i].StateRoot, file.Batches[i].StateRoot)
+		}
+	}
+
+	if err := s.RestoreSnapshot(file.Snapshot, file.Batches[len(local):]); err != nil {
+		return err
+	}
+	return s.Flush()
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestExportImport(t *testing.T) {
+	src := NewState()
+	addr := types.Address{1}
+	src.SetAccount(&Account{Address: addr, Balance: big.NewInt(42), Nonce: 3})
+	src.SetCode(addr, []byte{0x60, 0x00})
+	src.SetStorage(addr, [32]byte{1}, [32]byte{2})
+	src.AddBatch(&Batch{Timestamp: 1, StateRoot: src.GetStateRoot(), Transactions: []Transaction{{Amount: big.NewInt(1), TraceID: "local"}}})
+
+	var out bytes.Buffer
+	require.NoError(t, src.Export(&out))
+	require.NotContains(t, out.String(), "local")
+
+	// Exports are canonical
+	var again bytes.Buffer
+	require.NoError(t, src.Copy().Export(&again))
+	require.Equal(t, out.String(), again.String())
+
+	db := memorydb.New()
+	dst, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	require.NoError(t, dst.Import(bytes.NewReader(out.Bytes())))
+
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, src.GetStateRoot(), reopened.GetStateRoot())
+	require.Equal(t, uint64(1), reopened.GetBatchNumber())
+	value, err := reopened.GetStorage(addr, [32]byte{1})
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{2}, value)
+
+	// A diverging local history is refused
+	other := NewState()
+	other.AddBatch(&Batch{StateRoot: [32]byte{9}})
+	require.Error(t, other.Import(bytes.NewReader(out.Bytes())))
+}
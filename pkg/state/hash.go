@@ -0,0 +1,108 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+)
+
+// RootVersion identifies the node hash of the state tree, so a root is only ever compared
+// with roots computed the same way. It is persisted with the state.
+type RootVersion uint8
+
+const (
+	RootVersionSHA256 RootVersion = 1 // SHA-256, the hash the L1 escape hatch verifies
+	RootVersionMiMC   RootVersion = 2 // MiMC over BN254, cheap to constrain in a circuit
+)
+
+// ParseRootVersion parses a hash name as used in configuration
+func ParseRootVersion(name string) (RootVersion, error) {
+	switch name {
+	case "", "sha256":
+		return RootVersionSHA256, nil
+	case "mimc":
+		return RootVersionMiMC, nil
+	default:
+		return 0, fmt.Errorf("unknown state hash %q, expected sha256 or mimc", name)
+	}
+}
+
+// String returns the hash name of the version
+func (v RootVersion) String() string {
+	switch v {
+	case RootVersionSHA256:
+		return "sha256"
+	case RootVersionMiMC:
+		return "mimc"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(v))
+	}
+}
+
+// HashFunc returns the node hash of the version, nil for an unknown version
+func (v RootVersion) HashFunc() HashFunc {
+	switch v {
+	case RootVersionSHA256:
+		return SHA256Hash
+	case RootVersionMiMC:
+		return MiMCHash
+	default:
+		return nil
+	}
+}
+
+// MiMCHash hashes two nodes with MiMC over the BN254 scalar field. Each 32-byte input is
+// absorbed as two 128-bit field elements, high half first, so every input is hashed
+// without reduction. The output is a field element in big-endian form and is below the
+// field modulus. pkg/crypto constrains the same hash in circuits.
+func MiMCHash(left, right [32]byte) [32]byte {
+	h := mimc.NewMiMC()
+	for _, word := range [][32]byte{left, right} {
+		for _, half := range [][]byte{word[:16], word[16:]} {
+			var e fr.Element
+			e.SetBytes(half)
+			b := e.Bytes()
+			h.Write(b[:])
+		}
+	}
+
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// RootVersion returns the version of the node hash the state roots are computed with
+func (s *State) RootVersion() RootVersion {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rootVersion
+}
+
+// SetRootVersion switches the node hash of the state tree, the trees are rebuilt for the
+// next root. Roots recorded in batches stay comparable only with their own version, so a
+// state holding batches cannot switch.
+func (s *State) SetRootVersion(v RootVersion) error {
+	hash := v.HashFunc()
+	if hash == nil {
+		return fmt.Errorf("unknown root version %d", uint8(v))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v == s.rootVersion {
+		return nil
+	}
+	if s.batchNumber > 0 {
+		return fmt.Errorf("state holds %d batches with %s roots, cannot switch to %s", s.batchNumber, s.rootVersion, v)
+	}
+
+	s.rootVersion = v
+	s.hash = hash
+	s.accountTree = nil
+	s.storageTrees = nil
+	s.treesShared = false
+	s.persist(rootVersionKey, []byte{byte(v)})
+	return nil
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestMiMCStateRoot(t *testing.T) {
+	alice := types.Address{0xaa}
+	contract := types.Address{0xcc}
+	populate := func(s *State) {
+		s.SetAccount(&Account{Address: alice, Balance: big.NewInt(100), Nonce: 2})
+		s.SetCode(contract, []byte{0x60, 0x00})
+		s.SetStorage(contract, [32]byte{1}, [32]byte{0xff, 0xff})
+	}
+
+	legacy := NewState()
+	populate(legacy)
+
+	s := NewState()
+	populate(s)
+	shaRoot := s.GetStateRoot()
+	require.Equal(t, legacy.GetStateRoot(), shaRoot)
+
+	// Switching rebuilds the trees with the new hash
+	require.NoError(t, s.SetRootVersion(RootVersionMiMC))
+	require.Equal(t, RootVersionMiMC, s.RootVersion())
+	root := s.GetStateRoot()
+	require.NotEqual(t, shaRoot, root)
+
+	// MiMC roots are field elements, the circuit can take them as they are
+	require.Less(t, new(big.Int).SetBytes(root[:]).Cmp(big.NewInt(0).Lsh(big.NewInt(1), 254)), 0)
+
+	proof := s.GetAccountProof(alice)
+	require.True(t, proof.Verify(root, MiMCHash))
+	require.False(t, proof.Verify(root, SHA256Hash))
+	accountProof, storageProof := s.GetStorageProof(contract, [32]byte{1})
+	require.True(t, accountProof.Verify(root, MiMCHash))
+	require.True(t, storageProof.Verify(accountProof.StorageRoot, MiMCHash))
+
+	// Copies keep the version
+	require.Equal(t, root, s.Copy().GetStateRoot())
+}
+
+func TestRootVersionPersisted(t *testing.T) {
+	db := memorydb.New()
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	require.Equal(t, RootVersionSHA256, s.RootVersion())
+	require.NoError(t, s.SetRootVersion(RootVersionMiMC))
+	s.SetAccount(&Account{Address: types.Address{1}, Balance: big.NewInt(5)})
+	s.AddBatch(&Batch{BatchNumber: 0, StateRoot: s.GetStateRoot()})
+	require.NoError(t, s.Flush())
+
+	// Roots recorded in batches pin the version
+	require.Error(t, s.SetRootVersion(RootVersionSHA256))
+
+	reopened, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	require.Equal(t, RootVersionMiMC, reopened.RootVersion())
+	require.Equal(t, s.GetStateRoot(), reopened.GetStateRoot())
+
+	_, err = ParseRootVersion("poseidon")
+	require.Error(t, err)
+}
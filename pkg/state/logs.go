@@ -0,0 +1,178 @@
+package state
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"zkrollup/pkg/types"
+)
+
+// logPrefix + batch number (uint64 big endian) -> JSON logs emitted by the batch
+var logPrefix = []byte("l")
+
+// Log is an event emitted by a contract
+type Log struct {
+	Address types.Address
+	Topics  [][32]byte
+	Data    []byte
+
+	// Position of the log, filled in when it is recorded
+	BatchNumber uint64
+	TxHash      [32]byte
+	TxIndex     uint
+	LogIndex    uint // Index within the batch
+}
+
+// LogFilter selects logs by batch range, emitting contract and topics
+type LogFilter struct {
+	FromBatch uint64
+	ToBatch   uint64 // Inclusive
+
+	// Any of the addresses matches, none matches every address
+	Addresses []types.Address
+
+	// Topics[i] lists the accepted values of the log's i-th topic, an empty list accepts
+	// any value. Logs with fewer topics than the filter never match.
+	Topics [][][32]byte
+}
+
+// Matches reports whether log passes the address and topic criteria of f
+func (f *LogFilter) Matches(log *Log) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, addr := range f.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, accepted := range f.Topics {
+		if len(accepted) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range accepted {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SetTxContext sets the transaction that logs, gas and receipts recorded next are
+// attributed to
+func (s *State) SetTxContext(txHash [32]byte, txIndex int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txHash = txHash
+	s.txIndex = uint(txIndex)
+	s.txGasUsed = 0
+	s.txContract = types.Address{}
+}
+
+// AddLog records a log emitted by the current transaction. Logs are kept with the next
+// batch added to the state.
+func (s *State) AddLog(log Log) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Topics = append([][32]byte(nil), log.Topics...)
+	log.Data = append([]byte(nil), log.Data...)
+	log.TxHash = s.txHash
+	log.TxIndex = s.txIndex
+	log.LogIndex = uint(len(s.pendingLogs))
+	s.pendingLogs = append(s.pendingLogs, log)
+}
+
+// GetBatchLogs returns the logs emitted by a batch
+func (s *State) GetBatchLogs(batchNumber uint64) []Log {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Log(nil), s.logs[batchNumber]...)
+}
+
+// GetLogs returns the logs matching filter in batch and log order
+func (s *State) GetLogs(filter LogFilter) []Log {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var logs []Log
+	for n := filter.FromBatch; n <= filter.ToBatch && n < s.batchNumber; n++ {
+		for i := range s.logs[n] {
+			if filter.Matches(&s.logs[n][i]) {
+				logs = append(logs, s.logs[n][i])
+			}
+		}
+	}
+	return logs
+}
+
+// takePendingLogsLocked assigns the pending logs to batch number n. Must be called with
+// s.mu held.
+func (s *State) takePendingLogsLocked(n uint64) {
+	if len(s.pendingLogs) == 0 {
+		return
+	}
+	for i := range s.pendingLogs {
+		s.pendingLogs[i].BatchNumber = n
+	}
+	if s.logs == nil {
+		s.logs = make(map[uint64][]Log)
+	}
+	s.logs[n] = s.pendingLogs
+	s.pendingLogs = nil
+	s.persistLogs(n)
+}
+
+func (s *State) persistLogs(n uint64) {
+	if s.pending == nil {
+		return
+	}
+	data, err := json.Marshal(s.logs[n])
+	if err != nil {
+		return
+	}
+	s.persist(logKey(n), data)
+}
+
+// loadLogs reads the logs of every batch from the database
+func (s *State) loadLogs() error {
+	it := s.db.NewIterator(logPrefix, nil)
+	for it.Next() {
+		key := it.Key()[len(logPrefix):]
+		if len(key) != 8 {
+			continue
+		}
+		var logs []Log
+		if err := json.Unmarshal(it.Value(), &logs); err != nil {
+			it.Release()
+			return fmt.Errorf("failed to decode logs of batch %d: %v", binary.BigEndian.Uint64(key), err)
+		}
+		if s.logs == nil {
+			s.logs = make(map[uint64][]Log)
+		}
+		s.logs[binary.BigEndian.Uint64(key)] = logs
+	}
+	return iteratorDone(it)
+}
+
+func logKey(number uint64) []byte {
+	k := make([]byte, len(logPrefix)+8)
+	copy(k, logPrefix)
+	binary.BigEndian.PutUint64(k[len(logPrefix):], number)
+	return k
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestLogsRecordedPerBatch(t *testing.T) {
+	db := memorydb.New()
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+
+	token, other := types.Address{1}, types.Address{2}
+	transfer, approval := [32]byte{0xaa}, [32]byte{0xbb}
+
+	s.SetTxContext([32]byte{7}, 0)
+	s.AddLog(Log{Address: token, Topics: [][32]byte{transfer, {1}}, Data: []byte{1}})
+	s.SetTxContext([32]byte{8}, 1)
+	s.AddLog(Log{Address: other, Topics: [][32]byte{approval}})
+
+	// Logs of a discarded copy never reach the original
+	cpy := s.Copy()
+	cpy.AddLog(Log{Address: token})
+	cpy.AddBatch(&Batch{})
+
+	s.AddBatch(&Batch{})
+	s.SetTxContext([32]byte{9}, 0)
+	s.AddLog(Log{Address: token, Topics: [][32]byte{approval}})
+	s.AddBatch(&Batch{})
+	require.NoError(t, s.Commit())
+
+	logs := s.GetBatchLogs(0)
+	require.Len(t, logs, 2)
+	require.Equal(t, [32]byte{8}, logs[1].TxHash)
+	require.Equal(t, uint(1), logs[1].TxIndex)
+	require.Equal(t, uint(1), logs[1].LogIndex)
+
+	all := LogFilter{FromBatch: 0, ToBatch: 1}
+	require.Len(t, s.GetLogs(all), 3)
+
+	byAddress := all
+	byAddress.Addresses = []types.Address{token}
+	require.Len(t, s.GetLogs(byAddress), 2)
+
+	byTopic := all
+	byTopic.Topics = [][][32]byte{{approval}}
+	require.Len(t, s.GetLogs(byTopic), 2)
+
+	// An empty position is a wildcard, but the log needs that many topics
+	bySecond := all
+	bySecond.Topics = [][][32]byte{nil, {{1}}}
+	got := s.GetLogs(bySecond)
+	require.Len(t, got, 1)
+	require.Equal(t, token, got[0].Address)
+
+	latest := LogFilter{FromBatch: 1, ToBatch: 1}
+	got = s.GetLogs(latest)
+	require.Len(t, got, 1)
+	require.Equal(t, uint64(1), got[0].BatchNumber)
+
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, s.GetLogs(all), reopened.GetLogs(all))
+}
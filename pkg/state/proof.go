@@ -0,0 +1,144 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/types"
+)
+
+// AccountProof proves an account's fields against the state root. For an absent
+// account Exists is false and the proof shows the account's leaf is empty.
+type AccountProof struct {
+	Address     types.Address
+	Exists      bool
+	Balance     *big.Int
+	Nonce       uint64
+	StorageRoot [32]byte
+	CodeHash    [32]byte
+	Proof       *MerkleProof
+}
+
+// StorageProof proves a storage slot against an account's storage root
+type StorageProof struct {
+	Key   [32]byte
+	Value [32]byte
+	Proof *MerkleProof
+}
+
+// GetAccountProof returns an inclusion proof for the account at address, or a proof of
+// non-inclusion if it does not exist
+func (s *State) GetAccountProof(address types.Address) *AccountProof {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flushTreesLocked()
+	return s.accountProofLocked(address)
+}
+
+// GetStorageProof returns the account proof for address together with a proof of the
+// storage slot at key against the account's storage root
+func (s *State) GetStorageProof(address types.Address, key [32]byte) (*AccountProof, *StorageProof) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flushTreesLocked()
+	tree := s.storageTreeLocked(address)
+	storageProof := &StorageProof{
+		Key:   key,
+		Value: s.storage[address][key],
+		Proof: tree.Prove(storageKeyHash(s.hash, key)),
+	}
+
+	return s.accountProofLocked(address), storageProof
+}
+
+// Verify checks the account proof against a state root using hash for tree nodes
+func (p *AccountProof) Verify(root [32]byte, hash HashFunc) bool {
+	if hash == nil {
+		hash = SHA256Hash
+	}
+	if p.Proof == nil || p.Proof.Key != accountKeyHash(hash, p.Address) {
+		return false
+	}
+
+	var leaf [32]byte
+	if p.Exists {
+		leaf = accountLeaf(hash, p.Address, p.Balance, p.Nonce, p.StorageRoot, p.CodeHash)
+	}
+	if p.Proof.Leaf != leaf {
+		return false
+	}
+
+	return p.Proof.Verify(root, hash)
+}
+
+// Verify checks the storage proof against an account's storage root
+func (p *StorageProof) Verify(storageRoot [32]byte, hash HashFunc) bool {
+	if hash == nil {
+		hash = SHA256Hash
+	}
+	if p.Proof == nil || p.Proof.Key != storageKeyHash(hash, p.Key) || p.Proof.Leaf != p.Value {
+		return false
+	}
+
+	return p.Proof.Verify(storageRoot, hash)
+}
+
+// accountProofLocked builds an account proof from the up to date account tree
+func (s *State) accountProofLocked(address types.Address) *AccountProof {
+	proof := &AccountProof{
+		Address: address,
+		Balance: big.NewInt(0),
+		Proof:   s.accountTree.Prove(accountKeyHash(s.hash, address)),
+	}
+
+	if account, ok := s.accounts[address]; ok {
+		if account.Balance != nil {
+			proof.Balance = new(big.Int).Set(account.Balance)
+		}
+		proof.Nonce = account.Nonce
+	}
+	if !s.isEmptyLocked(address) {
+		proof.Exists = true
+		proof.StorageRoot = s.storageRootLocked(address)
+		proof.CodeHash = CodeHash(s.code[address])
+	}
+
+	return proof
+}
+
+// accountLeaf commits to every field of an account
+func accountLeaf(hash HashFunc, address types.Address, balance *big.Int, nonce uint64, storageRoot, codeDigest [32]byte) [32]byte {
+	var addrWord, balanceWord, nonceWord [32]byte
+	copy(addrWord[12:], address[:])
+	if balance != nil && balance.Sign() > 0 && balance.BitLen() <= 256 {
+		balance.FillBytes(balanceWord[:])
+	}
+	new(big.Int).SetUint64(nonce).FillBytes(nonceWord[:])
+
+	return hash(
+		hash(addrWord, balanceWord),
+		hash(hash(nonceWord, storageRoot), codeDigest),
+	)
+}
+
+// accountKeyHash spreads account addresses uniformly over the tree
+func accountKeyHash(hash HashFunc, address types.Address) [32]byte {
+	var addrWord [32]byte
+	copy(addrWord[12:], address[:])
+	return hash(addrWord, [32]byte{})
+}
+
+func storageKeyHash(hash HashFunc, key [32]byte) [32]byte {
+	return hash(key, [32]byte{})
+}
+
+// CodeHash returns the keccak256 hash of code, zero for accounts without code
+func CodeHash(code []byte) [32]byte {
+	if len(code) == 0 {
+		return [32]byte{}
+	}
+	return crypto.Keccak256Hash(code)
+}
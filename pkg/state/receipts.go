@@ -0,0 +1,190 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"zkrollup/pkg/types"
+)
+
+// receiptPrefix + transaction hash -> JSON receipt
+var receiptPrefix = []byte("r")
+
+// ReceiptStatus is the outcome of a transaction in a finalized batch
+type ReceiptStatus uint8
+
+const (
+	ReceiptFailed  ReceiptStatus = 0 // Reverted or rejected, only its nonce and gas were consumed
+	ReceiptSuccess ReceiptStatus = 1
+
+	// The transaction was finalized before receipts were recorded, or restored from a
+	// snapshot, so only its position is known
+	ReceiptUnknown ReceiptStatus = 2
+)
+
+// Receipt locates a transaction in the batch that finalized it and records its outcome
+type Receipt struct {
+	TxHash      [32]byte
+	BatchNumber uint64
+	TxIndex     uint // Index within the batch
+	Status      ReceiptStatus
+	GasUsed     uint64
+
+	// Address of the contract a deployment created, zero otherwise
+	ContractAddress types.Address `json:",omitzero"`
+
+	// Why a failed transaction failed
+	Error string `json:",omitempty"`
+}
+
+// UseGas records gas consumed by the transaction set by SetTxContext, for its receipt
+func (s *State) UseGas(gas uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txGasUsed += gas
+}
+
+// SetTxContract records the contract deployed by the transaction set by SetTxContext
+func (s *State) SetTxContract(addr types.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txContract = addr
+}
+
+// RecordReceipt records the outcome of the transaction set by SetTxContext, with the gas
+// and contract recorded for it. Receipts are indexed with the next batch added to the
+// state.
+func (s *State) RecordReceipt(txErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	receipt := Receipt{
+		TxHash:          s.txHash,
+		TxIndex:         s.txIndex,
+		Status:          ReceiptSuccess,
+		GasUsed:         s.txGasUsed,
+		ContractAddress: s.txContract,
+	}
+	if txErr != nil {
+		receipt.Status = ReceiptFailed
+		receipt.Error = txErr.Error()
+	}
+	s.pendingReceipts = append(s.pendingReceipts, receipt)
+}
+
+// GetReceipt returns the receipt of a finalized transaction by hash
+func (s *State) GetReceipt(txHash [32]byte) (*Receipt, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	receipt, ok := s.receipts[txHash]
+	if !ok {
+		return nil, false
+	}
+	cpy := *receipt
+	return &cpy, true
+}
+
+// GetTransactionByHash returns a finalized transaction and its receipt by hash
+func (s *State) GetTransactionByHash(txHash [32]byte) (*Transaction, *Receipt, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	receipt, ok := s.receipts[txHash]
+	if !ok {
+		return nil, nil, false
+	}
+	batch, ok := s.batchLocked(receipt.BatchNumber)
+	if !ok {
+		return nil, nil, false
+	}
+	txs := batch.Transactions
+	if receipt.TxIndex >= uint(len(txs)) {
+		return nil, nil, false
+	}
+	tx := txs[receipt.TxIndex]
+	cpy := *receipt
+	return &tx, &cpy, true
+}
+
+// takePendingReceiptsLocked indexes the transactions of batch, with the receipts recorded
+// while it was executed. Must be called with s.mu held.
+func (s *State) takePendingReceiptsLocked(batch *Batch) {
+	recorded := make(map[[32]byte]Receipt, len(s.pendingReceipts))
+	for _, receipt := range s.pendingReceipts {
+		recorded[receipt.TxHash] = receipt
+	}
+	s.pendingReceipts = nil
+
+	for i := range batch.Transactions {
+		hash := batch.Transactions[i].Hash()
+		receipt, ok := recorded[hash]
+		if !ok {
+			receipt = Receipt{TxHash: hash, Status: ReceiptUnknown}
+		}
+		receipt.BatchNumber = batch.BatchNumber
+		receipt.TxIndex = uint(i)
+		s.indexReceiptLocked(receipt)
+		s.persistReceipt(&receipt)
+	}
+}
+
+// indexBatchLocked indexes the transactions of a batch that have no receipt, as batches
+// finalized before receipts were recorded or restored from a snapshot. Must be called
+// with s.mu held.
+func (s *State) indexBatchLocked(batch *Batch) {
+	for i := range batch.Transactions {
+		hash := batch.Transactions[i].Hash()
+		if _, ok := s.receipts[hash]; ok {
+			continue
+		}
+		s.indexReceiptLocked(Receipt{TxHash: hash, BatchNumber: batch.BatchNumber, TxIndex: uint(i), Status: ReceiptUnknown})
+	}
+}
+
+func (s *State) indexReceiptLocked(receipt Receipt) {
+	if s.receipts == nil {
+		s.receipts = make(map[[32]byte]*Receipt)
+	}
+	s.receipts[receipt.TxHash] = &receipt
+}
+
+func (s *State) persistReceipt(receipt *Receipt) {
+	if s.pending == nil {
+		return
+	}
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return
+	}
+	s.persist(receiptKey(receipt.TxHash), data)
+}
+
+// loadReceipts reads the receipts from the database and indexes the transactions of
+// loaded batches that have none
+func (s *State) loadReceipts() error {
+	it := s.db.NewIterator(receiptPrefix, nil)
+	for it.Next() {
+		if len(it.Key()) != len(receiptPrefix)+32 {
+			continue
+		}
+		var receipt Receipt
+		if err := json.Unmarshal(it.Value(), &receipt); err != nil {
+			it.Release()
+			return fmt.Errorf("failed to decode receipt %x: %v", it.Key()[len(receiptPrefix):], err)
+		}
+		s.indexReceiptLocked(receipt)
+	}
+	if err := iteratorDone(it); err != nil {
+		return err
+	}
+
+	for i := range s.batches {
+		s.indexBatchLocked(&s.batches[i])
+	}
+	return nil
+}
+
+func receiptKey(txHash [32]byte) []byte {
+	return append(append([]byte(nil), receiptPrefix...), txHash[:]...)
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestTransactionIndexSurvivesRestart(t *testing.T) {
+	db := memorydb.New()
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+
+	deploy := Transaction{Type: TxTypeContractDeploy, From: types.Address{1}, Amount: big.NewInt(0), Data: []byte{0x60}}
+	call := Transaction{Type: TxTypeContractCall, From: types.Address{1}, To: types.Address{9}, Amount: big.NewInt(0), Nonce: 1}
+	unrecorded := Transaction{Type: TxTypeTransfer, From: types.Address{2}, Amount: big.NewInt(1)}
+
+	s.SetTxContext(deploy.Hash(), 0)
+	s.UseGas(50000)
+	s.SetTxContract(types.Address{9})
+	s.RecordReceipt(nil)
+	s.SetTxContext(call.Hash(), 1)
+	s.UseGas(21000)
+	s.RecordReceipt(errors.New("execution reverted"))
+	s.AddBatch(&Batch{Transactions: []Transaction{deploy, call}})
+
+	// Receipts of a discarded copy never reach the original
+	cpy := s.Copy()
+	cpy.SetTxContext(unrecorded.Hash(), 0)
+	cpy.RecordReceipt(nil)
+
+	s.AddBatch(&Batch{Transactions: []Transaction{unrecorded}})
+	require.NoError(t, s.Commit())
+
+	reopened, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+
+	tx, receipt, ok := reopened.GetTransactionByHash(CalculateTransactionHash(&call))
+	require.True(t, ok)
+	require.Equal(t, call.Nonce, tx.Nonce)
+	require.Equal(t, uint64(0), receipt.BatchNumber)
+	require.Equal(t, uint(1), receipt.TxIndex)
+	require.Equal(t, ReceiptFailed, receipt.Status)
+	require.Equal(t, "execution reverted", receipt.Error)
+	require.Equal(t, uint64(21000), receipt.GasUsed)
+	require.True(t, receipt.ContractAddress.IsZero())
+
+	receipt, ok = reopened.GetReceipt(deploy.Hash())
+	require.True(t, ok)
+	require.Equal(t, ReceiptSuccess, receipt.Status)
+	require.Equal(t, types.Address{9}, receipt.ContractAddress)
+	require.Equal(t, uint64(50000), receipt.GasUsed)
+
+	receipt, ok = reopened.GetReceipt(unrecorded.Hash())
+	require.True(t, ok)
+	require.Equal(t, uint64(1), receipt.BatchNumber)
+	require.Equal(t, ReceiptUnknown, receipt.Status)
+
+	_, ok = reopened.GetReceipt([32]byte{0xff})
+	require.False(t, ok)
+}
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"math/big"
+
+	"zkrollup/pkg/types"
+)
+
+// Tree maintenance. The account tree and per-account storage trees are kept between
+// root computations; mutations only record what changed and flushTreesLocked updates
+// the touched leaves before a root or proof is read.
+
+// markAccountLocked records that the leaf of address must be recomputed
+func (s *State) markAccountLocked(address types.Address) {
+	if s.dirtyAccounts == nil {
+		s.dirtyAccounts = make(map[types.Address]struct{})
+	}
+	s.dirtyAccounts[address] = struct{}{}
+	s.archive.markAccount(address)
+}
+
+// markSlotLocked records that a storage slot of address changed
+func (s *State) markSlotLocked(address types.Address, key [32]byte) {
+	if s.dirtySlots == nil {
+		s.dirtySlots = make(map[types.Address]map[[32]byte]struct{})
+	}
+	if s.dirtySlots[address] == nil {
+		s.dirtySlots[address] = make(map[[32]byte]struct{})
+	}
+	s.dirtySlots[address][key] = struct{}{}
+	s.archive.markSlot(address, key)
+	s.markAccountLocked(address)
+}
+
+// flushTreesLocked brings the trees up to date with the state. Must be called with s.mu
+// held for writing.
+func (s *State) flushTreesLocked() {
+	if s.accountTree == nil {
+		s.rebuildTreesLocked()
+		return
+	}
+	if len(s.dirtyAccounts) == 0 {
+		return
+	}
+
+	// Trees shared with a copy are cloned before the first change
+	if s.treesShared {
+		s.accountTree = s.accountTree.Copy()
+		storageTrees := make(map[types.Address]*SparseMerkleTree, len(s.storageTrees))
+		for addr, tree := range s.storageTrees {
+			storageTrees[addr] = tree.Copy()
+		}
+		s.storageTrees = storageTrees
+		s.treesShared = false
+	}
+
+	for addr, keys := range s.dirtySlots {
+		tree, ok := s.storageTrees[addr]
+		if !ok {
+			tree = NewSparseMerkleTree(s.hash)
+			s.storageTrees[addr] = tree
+		}
+		for key := range keys {
+			tree.Update(storageKeyHash(s.hash, key), s.storage[addr][key])
+		}
+		if tree.Len() == 0 {
+			delete(s.storageTrees, addr)
+		}
+	}
+
+	for addr := range s.dirtyAccounts {
+		s.accountTree.Update(accountKeyHash(s.hash, addr), s.accountLeafLocked(addr))
+	}
+
+	s.dirtyAccounts = nil
+	s.dirtySlots = nil
+}
+
+// rebuildTreesLocked builds every tree from scratch. Contracts that only have code or
+// storage are included with a zero balance, empty accounts are treated as absent.
+func (s *State) rebuildTreesLocked() {
+	s.accountTree = NewSparseMerkleTree(s.hash)
+	s.storageTrees = make(map[types.Address]*SparseMerkleTree, len(s.storage))
+	for addr, slots := range s.storage {
+		tree := NewSparseMerkleTree(s.hash)
+		for key, value := range slots {
+			tree.Update(storageKeyHash(s.hash, key), value)
+		}
+		if tree.Len() > 0 {
+			s.storageTrees[addr] = tree
+		}
+	}
+
+	addresses := make(map[types.Address]struct{}, len(s.accounts))
+	for addr := range s.accounts {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range s.code {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range s.storage {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range addresses {
+		s.accountTree.Update(accountKeyHash(s.hash, addr), s.accountLeafLocked(addr))
+	}
+
+	s.treesShared = false
+	s.dirtyAccounts = nil
+	s.dirtySlots = nil
+}
+
+// accountLeafLocked computes the account tree leaf of address, zero for an empty account.
+// The storage tree of address must be up to date.
+func (s *State) accountLeafLocked(address types.Address) [32]byte {
+	if s.isEmptyLocked(address) {
+		return [32]byte{}
+	}
+
+	var balance *big.Int
+	var nonce uint64
+	if account, ok := s.accounts[address]; ok {
+		balance = account.Balance
+		nonce = account.Nonce
+	}
+
+	return accountLeaf(s.hash, address, balance, nonce, s.storageRootLocked(address), CodeHash(s.code[address]))
+}
+
+// storageRootLocked returns the storage root of address from its up to date storage tree
+func (s *State) storageRootLocked(address types.Address) [32]byte {
+	if tree, ok := s.storageTrees[address]; ok {
+		return tree.Root()
+	}
+	// Every tree shares the same defaults, the top one is the empty root
+	return s.accountTree.defaults[SMTDepth]
+}
+
+// storageTreeLocked returns the up to date storage tree of address, which must not be modified
+func (s *State) storageTreeLocked(address types.Address) *SparseMerkleTree {
+	if tree, ok := s.storageTrees[address]; ok {
+		return tree
+	}
+	return NewSparseMerkleTree(s.hash)
+}
@@ -0,0 +1,226 @@
+package state
+
+import (
+	"crypto/sha256"
+	"errors"
+)
+
+// SMTDepth is the number of levels below the root of a sparse Merkle tree, one per key bit
+const SMTDepth = 256
+
+// ErrInvalidProof is returned when a Merkle proof is malformed
+var ErrInvalidProof = errors.New("invalid merkle proof")
+
+// HashFunc hashes two child nodes into their parent
+type HashFunc func(left, right [32]byte) [32]byte
+
+// SHA256Hash is the default node hash
+func SHA256Hash(left, right [32]byte) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], left[:])
+	copy(buf[32:], right[:])
+	return sha256.Sum256(buf[:])
+}
+
+// SparseMerkleTree is a fixed depth Merkle tree over 256-bit keys in which every absent
+// leaf is the zero hash. Interior nodes are cached and Update rehashes only the path from
+// the changed leaf to the root; nodes equal to the default of their height are not stored.
+type SparseMerkleTree struct {
+	hash     HashFunc
+	leaves   map[[32]byte][32]byte
+	nodes    map[smtNode][32]byte
+	defaults [SMTDepth + 1][32]byte // defaults[h] is the root of an empty subtree of height h
+}
+
+// smtNode identifies an interior node by its height and the key prefix leading to it,
+// with the bits below the node cleared
+type smtNode struct {
+	height int
+	path   [32]byte
+}
+
+// MerkleProof proves the value of a leaf against a tree root. Siblings are ordered from
+// the leaf upwards and only non-default siblings are included; bit i of Bitmap is set
+// when the sibling at height i is present in Siblings.
+type MerkleProof struct {
+	Key      [32]byte
+	Leaf     [32]byte // Zero for a proof of non-inclusion
+	Bitmap   [32]byte
+	Siblings [][32]byte
+}
+
+// NewSparseMerkleTree creates an empty tree using hash for interior nodes
+func NewSparseMerkleTree(hash HashFunc) *SparseMerkleTree {
+	if hash == nil {
+		hash = SHA256Hash
+	}
+
+	t := &SparseMerkleTree{
+		hash:   hash,
+		leaves: make(map[[32]byte][32]byte),
+		nodes:  make(map[smtNode][32]byte),
+	}
+	for h := 1; h <= SMTDepth; h++ {
+		t.defaults[h] = hash(t.defaults[h-1], t.defaults[h-1])
+	}
+	return t
+}
+
+// Copy returns an independent copy of the tree
+func (t *SparseMerkleTree) Copy() *SparseMerkleTree {
+	cpy := &SparseMerkleTree{
+		hash:     t.hash,
+		leaves:   make(map[[32]byte][32]byte, len(t.leaves)),
+		nodes:    make(map[smtNode][32]byte, len(t.nodes)),
+		defaults: t.defaults,
+	}
+	for k, v := range t.leaves {
+		cpy.leaves[k] = v
+	}
+	for k, v := range t.nodes {
+		cpy.nodes[k] = v
+	}
+	return cpy
+}
+
+// Update sets the leaf at key, a zero leaf removes it. Only the SMTDepth nodes on the
+// path to the root are rehashed.
+func (t *SparseMerkleTree) Update(key, leaf [32]byte) {
+	if t.leaves[key] == leaf {
+		return
+	}
+	if leaf == ([32]byte{}) {
+		delete(t.leaves, key)
+	} else {
+		t.leaves[key] = leaf
+	}
+
+	node := leaf
+	for height := 0; height < SMTDepth; height++ {
+		sibling := t.node(height, siblingPath(key, height))
+		if bit(key, SMTDepth-1-height) {
+			node = t.hash(sibling, node)
+		} else {
+			node = t.hash(node, sibling)
+		}
+
+		id := smtNode{height: height + 1, path: nodePath(key, height+1)}
+		if node == t.defaults[height+1] {
+			delete(t.nodes, id)
+		} else {
+			t.nodes[id] = node
+		}
+	}
+}
+
+// Get returns the leaf at key, or the zero hash if it is absent
+func (t *SparseMerkleTree) Get(key [32]byte) [32]byte {
+	return t.leaves[key]
+}
+
+// Root returns the tree root
+func (t *SparseMerkleTree) Root() [32]byte {
+	return t.node(SMTDepth, [32]byte{})
+}
+
+// Len returns the number of non-empty leaves
+func (t *SparseMerkleTree) Len() int {
+	return len(t.leaves)
+}
+
+// Prove builds a proof for key, which may be absent from the tree
+func (t *SparseMerkleTree) Prove(key [32]byte) *MerkleProof {
+	proof := &MerkleProof{
+		Key:  key,
+		Leaf: t.leaves[key],
+	}
+
+	for height := 0; height < SMTDepth; height++ {
+		sibling := t.node(height, siblingPath(key, height))
+		if sibling != t.defaults[height] {
+			proof.Bitmap[height/8] |= 1 << (height % 8)
+			proof.Siblings = append(proof.Siblings, sibling)
+		}
+	}
+
+	return proof
+}
+
+// ComputeRoot returns the root implied by the proof
+func (p *MerkleProof) ComputeRoot(hash HashFunc) ([32]byte, error) {
+	if hash == nil {
+		hash = SHA256Hash
+	}
+
+	var defaults [SMTDepth + 1][32]byte
+	for h := 1; h <= SMTDepth; h++ {
+		defaults[h] = hash(defaults[h-1], defaults[h-1])
+	}
+
+	node := p.Leaf
+	next := 0
+	for height := 0; height < SMTDepth; height++ {
+		sibling := defaults[height]
+		if p.Bitmap[height/8]&(1<<(height%8)) != 0 {
+			if next >= len(p.Siblings) {
+				return [32]byte{}, ErrInvalidProof
+			}
+			sibling = p.Siblings[next]
+			next++
+		}
+
+		if bit(p.Key, SMTDepth-1-height) {
+			node = hash(sibling, node)
+		} else {
+			node = hash(node, sibling)
+		}
+	}
+
+	if next != len(p.Siblings) {
+		return [32]byte{}, ErrInvalidProof
+	}
+	return node, nil
+}
+
+// Verify checks the proof against root
+func (p *MerkleProof) Verify(root [32]byte, hash HashFunc) bool {
+	computed, err := p.ComputeRoot(hash)
+	return err == nil && computed == root
+}
+
+// node returns the node of the given height on path, falling back to the empty subtree default
+func (t *SparseMerkleTree) node(height int, path [32]byte) [32]byte {
+	if height == 0 {
+		return t.leaves[path]
+	}
+	if node, ok := t.nodes[smtNode{height: height, path: path}]; ok {
+		return node
+	}
+	return t.defaults[height]
+}
+
+// nodePath clears the lowest height bits of key, leaving the path to its ancestor at that height
+func nodePath(key [32]byte, height int) [32]byte {
+	for pos := SMTDepth - height; pos < SMTDepth; pos++ {
+		if pos%8 == 0 && pos+8 <= SMTDepth {
+			key[pos/8] = 0
+			pos += 7
+			continue
+		}
+		key[pos/8] &^= 0x80 >> (pos % 8)
+	}
+	return key
+}
+
+// siblingPath returns the path of the sibling of key's ancestor at the given height
+func siblingPath(key [32]byte, height int) [32]byte {
+	path := nodePath(key, height)
+	pos := SMTDepth - 1 - height
+	path[pos/8] ^= 0x80 >> (pos % 8)
+	return path
+}
+
+// bit returns the key bit at pos, counting from the most significant bit
+func bit(key [32]byte, pos int) bool {
+	return key[pos/8]&(0x80>>(pos%8)) != 0
+}
@@ -0,0 +1,143 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestSparseMerkleTreeProofs(t *testing.T) {
+	tree := NewSparseMerkleTree(nil)
+	emptyRoot := tree.Root()
+
+	keys := [][32]byte{{0x01}, {0x80}, {0x81, 0x02}, {0xff, 0xff}}
+	for i, key := range keys {
+		tree.Update(key, [32]byte{byte(i + 1)})
+	}
+	root := tree.Root()
+	require.NotEqual(t, emptyRoot, root)
+
+	for i, key := range keys {
+		proof := tree.Prove(key)
+		require.Equal(t, [32]byte{byte(i + 1)}, proof.Leaf)
+		require.True(t, proof.Verify(root, nil))
+	}
+
+	// Non-inclusion
+	proof := tree.Prove([32]byte{0x40})
+	require.Equal(t, [32]byte{}, proof.Leaf)
+	require.True(t, proof.Verify(root, nil))
+
+	// A forged leaf must not verify
+	proof.Leaf = [32]byte{0x09}
+	require.False(t, proof.Verify(root, nil))
+
+	// Removing every leaf restores the empty root
+	for _, key := range keys {
+		tree.Update(key, [32]byte{})
+	}
+	require.Equal(t, emptyRoot, tree.Root())
+}
+
+func TestAccountAndStorageProofs(t *testing.T) {
+	s := NewState()
+
+	alice := types.Address{0xaa}
+	contract := types.Address{0xcc}
+	s.SetAccount(&Account{Address: alice, Balance: big.NewInt(100), Nonce: 2})
+	s.SetCode(contract, []byte{0x60, 0x00})
+	s.SetStorage(contract, [32]byte{1}, [32]byte{7})
+
+	root := s.GetStateRoot()
+
+	proof := s.GetAccountProof(alice)
+	require.True(t, proof.Exists)
+	require.Equal(t, int64(100), proof.Balance.Int64())
+	require.True(t, proof.Verify(root, nil))
+
+	// Tampering with a field breaks the proof
+	proof.Balance = big.NewInt(1000)
+	require.False(t, proof.Verify(root, nil))
+
+	accountProof, storageProof := s.GetStorageProof(contract, [32]byte{1})
+	require.True(t, accountProof.Verify(root, nil))
+	require.Equal(t, [32]byte{7}, storageProof.Value)
+	require.True(t, storageProof.Verify(accountProof.StorageRoot, nil))
+
+	missing := s.GetAccountProof(types.Address{0xbb})
+	require.False(t, missing.Exists)
+	require.True(t, missing.Verify(root, nil))
+
+	// Any account change moves the root
+	s.SetAccount(&Account{Address: alice, Balance: big.NewInt(99), Nonce: 3})
+	require.NotEqual(t, root, s.GetStateRoot())
+}
+
+func TestPruneEmptyAccounts(t *testing.T) {
+	s := NewState()
+
+	funded := types.Address{0x01}
+	s.SetAccount(&Account{Address: funded, Balance: big.NewInt(5)})
+	root := s.GetStateRoot()
+
+	// Empty accounts are not part of the tree
+	empty := types.Address{0x02}
+	s.SetAccount(&Account{Address: empty, Balance: big.NewInt(0)})
+	require.Equal(t, root, s.GetStateRoot())
+	require.False(t, s.GetAccountProof(empty).Exists)
+
+	// Contracts are never empty even without balance
+	contract := types.Address{0x03}
+	s.SetAccount(&Account{Address: contract, Balance: big.NewInt(0)})
+	s.SetCode(contract, []byte{0x00})
+	root = s.GetStateRoot()
+
+	require.Equal(t, 1, s.PruneEmptyAccounts())
+	require.Equal(t, root, s.GetStateRoot())
+
+	_, err := s.GetAccount(empty)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = s.GetAccount(contract)
+	require.NoError(t, err)
+}
+
+// benchmarkState returns a state holding n funded accounts
+func benchmarkState(n int) *State {
+	s := NewState()
+	for i := 0; i < n; i++ {
+		var addr types.Address
+		addr[0], addr[1], addr[2] = byte(i>>16), byte(i>>8), byte(i)
+		s.SetAccount(&Account{Address: addr, Balance: big.NewInt(int64(i + 1))})
+	}
+	return s
+}
+
+// BenchmarkStateRootFull rebuilds the trees from scratch on every root
+func BenchmarkStateRootFull(b *testing.B) {
+	s := benchmarkState(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.mu.Lock()
+		s.rebuildTreesLocked()
+		s.accountTree.Root()
+		s.mu.Unlock()
+	}
+}
+
+// BenchmarkStateRootIncremental updates a batch-sized set of accounts between roots
+func BenchmarkStateRootIncremental(b *testing.B) {
+	s := benchmarkState(1000)
+	s.GetStateRoot()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 16; j++ {
+			var addr types.Address
+			addr[2] = byte(i*16 + j)
+			s.SetAccount(&Account{Address: addr, Balance: big.NewInt(int64(i + 2)), Nonce: uint64(i)})
+		}
+		s.GetStateRoot()
+	}
+}
@@ -0,0 +1,262 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"zkrollup/pkg/types"
+)
+
+// Snapshot is the full account state at a batch height, used to bootstrap a node that
+// joins late without replaying every batch
+type Snapshot struct {
+	BatchNumber  uint64 // Batches finalized before the snapshot was taken
+	DepositNonce uint64
+	StateRoot    [32]byte
+	Accounts     []Account
+	Contracts    []ContractSnapshot
+}
+
+// ContractSnapshot holds the code and storage of a single address
+type ContractSnapshot struct {
+	Address types.Address
+	Code    []byte        `json:",omitempty"`
+	Storage []StorageSlot `json:",omitempty"`
+}
+
+// StorageSlot is a single storage key and value
+type StorageSlot struct {
+	Key   [32]byte
+	Value [32]byte
+}
+
+// Snapshot captures the current account state. Entries are sorted by address so equal
+// states produce equal snapshots.
+func (s *State) Snapshot() *Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+// snapshotLocked captures the current account state. Must be called with s.mu held for
+// writing.
+func (s *State) snapshotLocked() *Snapshot {
+	s.flushTreesLocked()
+	snap := &Snapshot{
+		BatchNumber:  s.batchNumber,
+		DepositNonce: s.depositNonce,
+		StateRoot:    s.accountTree.Root(),
+		Accounts:     make([]Account, 0, len(s.accounts)),
+	}
+
+	for _, account := range s.accounts {
+		acc := *account
+		if account.Balance != nil {
+			acc.Balance = new(big.Int).Set(account.Balance)
+		}
+		snap.Accounts = append(snap.Accounts, acc)
+	}
+	sort.Slice(snap.Accounts, func(i, j int) bool {
+		return lessAddress(snap.Accounts[i].Address, snap.Accounts[j].Address)
+	})
+
+	contracts := make(map[types.Address]*ContractSnapshot)
+	contract := func(addr types.Address) *ContractSnapshot {
+		if c, ok := contracts[addr]; ok {
+			return c
+		}
+		c := &ContractSnapshot{Address: addr}
+		contracts[addr] = c
+		return c
+	}
+	for addr, code := range s.code {
+		contract(addr).Code = append([]byte(nil), code...)
+	}
+	for addr, slots := range s.storage {
+		c := contract(addr)
+		for key, value := range slots {
+			c.Storage = append(c.Storage, StorageSlot{Key: key, Value: value})
+		}
+		sort.Slice(c.Storage, func(i, j int) bool {
+			return string(c.Storage[i].Key[:]) < string(c.Storage[j].Key[:])
+		})
+	}
+	for _, c := range contracts {
+		snap.Contracts = append(snap.Contracts, *c)
+	}
+	sort.Slice(snap.Contracts, func(i, j int) bool {
+		return lessAddress(snap.Contracts[i].Address, snap.Contracts[j].Address)
+	})
+
+	return snap
+}
+
+// RestoreSnapshot replaces the account state with snap. batches must continue the local
+// batch history up to the snapshot height; they are stored as history without being
+// executed. The snapshot is rejected if its contents do not hash to its state root.
+func (s *State) RestoreSnapshot(snap *Snapshot, batches []Batch) error {
+	restored, err := s.buildSnapshotState(snap)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	local := s.batchNumber
+	if local+uint64(len(batches)) != snap.BatchNumber {
+		return fmt.Errorf("snapshot at batch %d does not follow %d local and %d synced batches", snap.BatchNumber, local, len(batches))
+	}
+	for i := range batches {
+		if batches[i].BatchNumber != local+uint64(i) {
+			return fmt.Errorf("unexpected synced batch %d, expected %d", batches[i].BatchNumber, local+uint64(i))
+		}
+	}
+
+	s.installSnapshotLocked(restored)
+	for i := range batches {
+		s.batches = append(s.batches, batches[i])
+		s.batchNumber++
+		s.persistBatch(&s.batches[len(s.batches)-1])
+		s.indexBatchLocked(&s.batches[len(s.batches)-1])
+	}
+	s.finishRestoreLocked(snap)
+	return nil
+}
+
+// RestoreSnapshotWithoutHistory replaces the account state with snap and drops the local
+// batch history, which then starts at the snapshot height. Fast sync uses it once the
+// snapshot's state root was checked against L1, the batches before it are never
+// downloaded. The snapshot is rejected if its contents do not hash to its state root or it
+// is not ahead of the local state.
+func (s *State) RestoreSnapshotWithoutHistory(snap *Snapshot) error {
+	restored, err := s.buildSnapshotState(snap)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if snap.BatchNumber <= s.batchNumber {
+		return fmt.Errorf("snapshot at batch %d is not ahead of the %d local batches", snap.BatchNumber, s.batchNumber)
+	}
+
+	s.installSnapshotLocked(restored)
+	for i := range s.batches {
+		s.persistDelete(batchKey(s.batches[i].BatchNumber))
+	}
+	s.batches = nil
+	s.batchNumber = snap.BatchNumber
+	s.historyStart = snap.BatchNumber
+
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], s.batchNumber)
+	s.persist(batchCountKey, count[:])
+
+	s.finishRestoreLocked(snap)
+	return nil
+}
+
+// buildSnapshotState builds the account state of snap aside, so a bad snapshot leaves the
+// current one untouched, and checks it against the snapshot's state root
+func (s *State) buildSnapshotState(snap *Snapshot) (*State, error) {
+	s.mu.RLock()
+	hash := s.hash
+	s.mu.RUnlock()
+
+	restored := NewState()
+	restored.hash = hash
+	for i := range snap.Accounts {
+		acc := snap.Accounts[i]
+		if acc.Balance != nil {
+			acc.Balance = new(big.Int).Set(acc.Balance)
+		}
+		restored.accounts[acc.Address] = &acc
+	}
+	for _, c := range snap.Contracts {
+		if len(c.Code) > 0 {
+			restored.code[c.Address] = append([]byte(nil), c.Code...)
+		}
+		if len(c.Storage) > 0 {
+			slots := make(map[[32]byte][32]byte, len(c.Storage))
+			for _, slot := range c.Storage {
+				slots[slot.Key] = slot.Value
+			}
+			restored.storage[c.Address] = slots
+		}
+	}
+	for addr, account := range restored.accounts {
+		account.CodeHash = CodeHash(restored.code[addr])
+	}
+	restored.rebuildTreesLocked()
+	if root := restored.accountTree.Root(); root != snap.StateRoot {
+		return nil, fmt.Errorf("snapshot state root mismatch: declared %x, computed %x", snap.StateRoot, root)
+	}
+	return restored, nil
+}
+
+// installSnapshotLocked swaps in the account state built by buildSnapshotState and
+// persists it. Must be called with s.mu held for writing.
+func (s *State) installSnapshotLocked(restored *State) {
+	// Remove persisted entries the snapshot no longer contains
+	for addr := range s.accounts {
+		if _, ok := restored.accounts[addr]; !ok {
+			s.persistDelete(accountKey(addr))
+		}
+	}
+	for addr := range s.code {
+		if _, ok := restored.code[addr]; !ok {
+			s.persistDelete(codeKey(addr))
+		}
+	}
+	for addr, slots := range s.storage {
+		for key := range slots {
+			if _, ok := restored.storage[addr][key]; !ok {
+				s.persistDelete(storageKey(addr, key))
+			}
+		}
+	}
+
+	s.accounts = restored.accounts
+	s.code = restored.code
+	s.storage = restored.storage
+	s.accountTree = restored.accountTree
+	s.storageTrees = restored.storageTrees
+	s.treesShared = false
+	s.dirtyAccounts = nil
+	s.dirtySlots = nil
+
+	for _, account := range s.accounts {
+		s.persistAccount(account)
+	}
+	for addr, code := range s.code {
+		s.persistCode(addr, code)
+	}
+	for addr, slots := range s.storage {
+		for key, value := range slots {
+			s.persist(storageKey(addr, key), value[:])
+		}
+	}
+}
+
+// finishRestoreLocked takes the deposit nonce of snap and restarts the archive from the
+// restored state. Must be called with s.mu held for writing.
+func (s *State) finishRestoreLocked(snap *Snapshot) {
+	// History from before the snapshot does not apply to the restored state
+	if s.archive != nil {
+		s.seedArchiveLocked()
+	}
+
+	s.depositNonce = snap.DepositNonce
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], s.depositNonce)
+	s.persist(depositNonceKey, nonce[:])
+}
+
+// lessAddress orders addresses bytewise
+func lessAddress(a, b types.Address) bool {
+	return string(a[:]) < string(b[:])
+}
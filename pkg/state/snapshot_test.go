@@ -0,0 +1,89 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestRestoreSnapshot(t *testing.T) {
+	src := NewState()
+	addr := types.Address{1}
+	src.SetAccount(&Account{Address: addr, Balance: big.NewInt(42), Nonce: 3})
+	src.SetCode(addr, []byte{0x60, 0x00})
+	src.SetStorage(addr, [32]byte{1}, [32]byte{2})
+	src.AddBatch(&Batch{Timestamp: 1})
+	src.AddBatch(&Batch{Timestamp: 2})
+
+	snap := src.Snapshot()
+	require.Equal(t, uint64(2), snap.BatchNumber)
+	require.Equal(t, src.GetStateRoot(), snap.StateRoot)
+
+	db := memorydb.New()
+	dst, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	dst.SetAccount(&Account{Address: types.Address{9}, Balance: big.NewInt(1)})
+
+	// The synced history must reach the snapshot height
+	require.Error(t, dst.RestoreSnapshot(snap, src.GetBatches(0, 1)))
+
+	tampered := *snap
+	tampered.StateRoot = [32]byte{0xff}
+	require.Error(t, dst.RestoreSnapshot(&tampered, src.GetBatches(0, 2)))
+
+	require.NoError(t, dst.RestoreSnapshot(snap, src.GetBatches(0, 2)))
+	require.NoError(t, dst.Commit())
+
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, src.GetStateRoot(), reopened.GetStateRoot())
+	require.Equal(t, uint64(2), reopened.GetBatchNumber())
+
+	_, err = reopened.GetAccount(types.Address{9})
+	require.ErrorIs(t, err, ErrAccountNotFound)
+
+	value, err := reopened.GetStorage(addr, [32]byte{1})
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{2}, value)
+}
+
+func TestRestoreSnapshotWithoutHistory(t *testing.T) {
+	src := NewState()
+	src.SetAccount(&Account{Address: types.Address{1}, Balance: big.NewInt(42)})
+	for i := 0; i < 3; i++ {
+		src.AddBatch(&Batch{Timestamp: uint64(i)})
+	}
+	snap := src.Snapshot()
+
+	db := memorydb.New()
+	dst, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	dst.AddBatch(&Batch{Timestamp: 7})
+
+	require.NoError(t, dst.RestoreSnapshotWithoutHistory(snap))
+	require.Equal(t, uint64(3), dst.GetBatchNumber())
+	require.Equal(t, uint64(3), dst.HistoryStart())
+	require.Empty(t, dst.GetBatches(0, 3))
+
+	// Batches after the snapshot are replayed as usual
+	dst.AddBatch(&Batch{Timestamp: 3})
+	require.NoError(t, dst.SetBatchStateRoot(3, [32]byte{3}))
+	require.Error(t, dst.SetBatchStateRoot(0, [32]byte{3}))
+	require.NoError(t, dst.Commit())
+
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), reopened.GetBatchNumber())
+	require.Equal(t, uint64(3), reopened.HistoryStart())
+	batches := reopened.GetBatches(3, 10)
+	require.Len(t, batches, 1)
+	require.Equal(t, [32]byte{3}, batches[0].StateRoot)
+	require.Equal(t, src.GetStateRoot(), reopened.GetStateRoot())
+
+	// A snapshot the state already passed is refused
+	require.Error(t, reopened.RestoreSnapshotWithoutHistory(snap))
+}
@@ -2,8 +2,13 @@ package state
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 	"sync"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"zkrollup/pkg/types"
 )
 
 // Error types
@@ -13,6 +18,7 @@ var (
 	ErrStorageNotFound   = errors.New("storage not found")
 	ErrInvalidSignature  = errors.New("invalid signature")
 	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrStateNotRetained  = errors.New("state not retained")
 )
 
 // TxType represents the type of transaction
@@ -22,59 +28,209 @@ const (
 	TxTypeTransfer       TxType = 0
 	TxTypeContractDeploy TxType = 1
 	TxTypeContractCall   TxType = 2
+	TxTypeDeposit        TxType = 3 // Credits an L1 deposit, Nonce is the deposit nonce
+	TxTypeRegisterKey    TxType = 4 // Registers the EdDSA public key in Data for the account it derives
+	TxTypeContractCreate TxType = 5 // Deploys Data[32:] with CREATE2, Data[:32] is the salt
 )
 
+// SaltLength is the size of the CREATE2 salt leading the data of TxTypeContractCreate
+const SaltLength = 32
+
 // Transaction represents a transaction in the ZK-Rollup
 type Transaction struct {
 	Type      TxType
-	From      [20]byte
-	To        [20]byte
+	From      types.Address
+	To        types.Address
 	Amount    *big.Int
 	Nonce     uint64
 	Data      []byte
 	Gas       uint64
+	GasPrice  *big.Int `json:",omitempty"` // Fee per unit of gas, orders the mempool
 	Signature []byte
+
+	// Rollup the transaction is valid on, covered by the signature so it cannot be replayed
+	// on another deployment. Zero marks an unprotected transaction signed without one.
+	ChainID uint64 `json:",omitempty"`
+
+	// Correlation ID assigned at RPC ingestion to follow the transaction through the logs of
+	// every node, not part of the hash or signature
+	TraceID string `json:",omitempty"`
+
+	// Submitted through the private endpoint, every node keeps it off the public mempool
+	// stream. Not part of the hash or signature.
+	Private bool `json:",omitempty"`
+
+	// Unix milliseconds at which the node holding the transaction admitted it to its pool,
+	// which batches ordered first come first served declare. Not part of the hash or signature.
+	ReceivedAt int64 `json:",omitempty"`
 }
 
 // Account represents an account in the ZK-Rollup
 type Account struct {
-	Address [20]byte
+	Address types.Address
 	Balance *big.Int
 	Nonce   uint64
+
+	// EdDSA/BN254 public key of a rollup-native account, whose transactions must be signed
+	// with it instead of secp256k1. Not part of the account leaf, registering it bumps the nonce.
+	AuthKey []byte `json:",omitempty"`
+
+	// Keccak256 hash of the account's contract code, zero without code. Maintained by the
+	// state from its code store, a value set by the caller is ignored.
+	CodeHash [32]byte `json:",omitzero"`
 }
 
 // Batch represents a batch of transactions in the ZK-Rollup
 type Batch struct {
-	BatchNumber  uint64
-	Transactions []Transaction
-	StateRoot    [32]byte
-	Timestamp    uint64
-	Proof        []byte // ZK proof data
+	BatchNumber   uint64
+	Transactions  []Transaction
+	StateRoot     [32]byte
+	Timestamp     uint64
+	Proof         []byte   // ZK proof data
+	PublicInputs  []byte   // Public inputs the proof was generated for, 32 bytes each
+	InputSchema   [32]byte // Hash of the schema ordering PublicInputs, zero without a proof
+	VerifierEpoch uint64   // CRS epoch of the keys that made Proof, 0 for the contract's own verifier
+	L1TxHash      [32]byte // L1 transaction that submitted the batch, zero until submitted
+
+	// Policy the proposer ordered the transactions by, which validators check the order
+	// against. Empty for batches from before ordering policies.
+	Ordering string `json:",omitempty"`
+}
+
+// TraceIDs returns the trace IDs of the batch's transactions that carry one
+func (b *Batch) TraceIDs() []string {
+	var ids []string
+	for i := range b.Transactions {
+		if id := b.Transactions[i].TraceID; id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
 // State represents the state of the ZK-Rollup
 type State struct {
-	accounts    map[[20]byte]*Account
-	code        map[[20]byte][]byte
-	storage     map[[20]byte]map[[32]byte][32]byte
-	batches     []Batch
-	batchNumber uint64
-	mu          sync.RWMutex
+	accounts     map[types.Address]*Account
+	code         map[types.Address][]byte
+	storage      map[types.Address]map[[32]byte][32]byte
+	batches      []Batch
+	batchNumber  uint64
+	historyStart uint64      // Number of batches[0], above 0 after a fast sync
+	depositNonce uint64      // Next L1 deposit to credit
+	hash         HashFunc    // Node hash of the state tree
+	rootVersion  RootVersion // Version of hash, persisted with the state
+	mu           sync.RWMutex
+
+	// Cached state trees, updated from the dirty sets when a root is needed. Trees are
+	// shared after Copy and cloned by whichever side changes them first.
+	accountTree   *SparseMerkleTree
+	storageTrees  map[types.Address]*SparseMerkleTree
+	treesShared   bool
+	dirtyAccounts map[types.Address]struct{}
+	dirtySlots    map[types.Address]map[[32]byte]struct{}
+
+	// Contract logs by batch number, and logs of the batch being executed attributed to
+	// the transaction set by SetTxContext
+	logs        map[uint64][]Log
+	pendingLogs []Log
+	txHash      [32]byte
+	txIndex     uint
+
+	// Receipts of finalized transactions by hash, receipts of the batch being executed and
+	// what the current transaction consumed and deployed so far
+	receipts        map[[32]byte]*Receipt
+	pendingReceipts []Receipt
+	txGasUsed       uint64
+	txContract      types.Address
+
+	// Past versions of accounts and storage, nil when only the latest state is kept
+	archive *archive
+
+	// Values changed since Checkpoint, nil when no checkpoint is open
+	checkpoint *checkpoint
+
+	// Optional persistent backend, writes are buffered in pending until Commit flushes
+	// them as the commit policy allows
+	db            ethdb.KeyValueStore
+	pending       ethdb.Batch
+	readOnly      bool
+	policy        CommitPolicy
+	unflushed     int // Commits buffered in pending under CommitBatches
+	writer        *asyncWriter
+	flushObserver FlushObserver
 }
 
 // NewState creates a new state
 func NewState() *State {
 	return &State{
-		accounts:    make(map[[20]byte]*Account),
-		code:        make(map[[20]byte][]byte),
-		storage:     make(map[[20]byte]map[[32]byte][32]byte),
+		accounts:    make(map[types.Address]*Account),
+		code:        make(map[types.Address][]byte),
+		storage:     make(map[types.Address]map[[32]byte][32]byte),
 		batches:     make([]Batch, 0),
 		batchNumber: 0,
+		hash:        SHA256Hash,
+		rootVersion: RootVersionSHA256,
 	}
 }
 
+// Copy returns an in-memory snapshot of the state. Changes to the copy are never
+// persisted and do not affect the original.
+func (s *State) Copy() *State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cpy := NewState()
+	cpy.hash = s.hash
+	cpy.rootVersion = s.rootVersion
+	cpy.batchNumber = s.batchNumber
+	cpy.historyStart = s.historyStart
+	cpy.depositNonce = s.depositNonce
+	cpy.batches = append(cpy.batches, s.batches...)
+	cpy.pendingLogs = append(cpy.pendingLogs, s.pendingLogs...)
+	cpy.pendingReceipts = append(cpy.pendingReceipts, s.pendingReceipts...)
+	cpy.txHash = s.txHash
+	cpy.txIndex = s.txIndex
+	cpy.txGasUsed = s.txGasUsed
+	cpy.txContract = s.txContract
+
+	for addr, account := range s.accounts {
+		acc := *account
+		if account.Balance != nil {
+			acc.Balance = new(big.Int).Set(account.Balance)
+		}
+		cpy.accounts[addr] = &acc
+	}
+	for addr, code := range s.code {
+		cpy.code[addr] = append([]byte(nil), code...)
+	}
+	for addr, slots := range s.storage {
+		cpy.storage[addr] = make(map[[32]byte][32]byte, len(slots))
+		for key, value := range slots {
+			cpy.storage[addr][key] = value
+		}
+	}
+
+	// Share the trees and pending changes instead of rehashing them in the copy
+	if s.accountTree != nil {
+		cpy.accountTree = s.accountTree
+		cpy.storageTrees = s.storageTrees
+		cpy.treesShared = true
+		s.treesShared = true
+	}
+	for addr := range s.dirtyAccounts {
+		cpy.markAccountLocked(addr)
+	}
+	for addr, keys := range s.dirtySlots {
+		for key := range keys {
+			cpy.markSlotLocked(addr, key)
+		}
+	}
+
+	return cpy
+}
+
 // GetAccount retrieves an account from the state
-func (s *State) GetAccount(address [20]byte) (*Account, error) {
+func (s *State) GetAccount(address types.Address) (*Account, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -82,6 +238,7 @@ func (s *State) GetAccount(address [20]byte) (*Account, error) {
 	if !ok {
 		return nil, ErrAccountNotFound
 	}
+	s.touchAccountLocked(address)
 
 	return account, nil
 }
@@ -91,11 +248,107 @@ func (s *State) SetAccount(account *Account) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.touchAccountLocked(account.Address)
+	account.CodeHash = CodeHash(s.code[account.Address])
 	s.accounts[account.Address] = account
+	s.markAccountLocked(account.Address)
+	s.persistAccount(account)
+}
+
+// DeleteAccount removes an account from the state
+func (s *State) DeleteAccount(address types.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.touchAccountLocked(address)
+	delete(s.accounts, address)
+	s.markAccountLocked(address)
+	s.persistDelete(accountKey(address))
+}
+
+// DestroyAccount removes an account together with its code and storage, as when a
+// contract self-destructs
+func (s *State) DestroyAccount(address types.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slots := s.storage[address]
+	for key := range slots {
+		s.touchSlotLocked(address, key)
+	}
+	delete(s.storage, address)
+	for key := range slots {
+		s.markSlotLocked(address, key)
+		s.persistDelete(storageKey(address, key))
+	}
+	if _, ok := s.code[address]; ok {
+		s.touchCodeLocked(address)
+		delete(s.code, address)
+		s.persistDelete(codeKey(address))
+	}
+	s.touchAccountLocked(address)
+	delete(s.accounts, address)
+	s.markAccountLocked(address)
+	s.persistDelete(accountKey(address))
+}
+
+// StorageRoot returns the root of the storage tree of address, false when the account
+// has no storage
+func (s *State) StorageRoot(address types.Address) ([32]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flushTreesLocked()
+	tree, ok := s.storageTrees[address]
+	if !ok {
+		return [32]byte{}, false
+	}
+	return tree.Root(), true
+}
+
+// IsEmpty reports whether the account has no balance and has never sent a transaction
+func (a *Account) IsEmpty() bool {
+	return (a.Balance == nil || a.Balance.Sign() == 0) && a.Nonce == 0
+}
+
+// PruneEmptyAccounts deletes every empty account that has no code or storage and returns
+// how many were removed. Such accounts are not part of the state tree, so pruning never
+// changes the state root.
+func (s *State) PruneEmptyAccounts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for addr := range s.accounts {
+		if s.isEmptyLocked(addr) {
+			s.touchAccountLocked(addr)
+			delete(s.accounts, addr)
+			s.persistDelete(accountKey(addr))
+			s.archive.markAccount(addr)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// isEmptyLocked reports whether address holds nothing that the state tree commits to
+func (s *State) isEmptyLocked(address types.Address) bool {
+	if account, ok := s.accounts[address]; ok && !account.IsEmpty() {
+		return false
+	}
+	if len(s.code[address]) > 0 {
+		return false
+	}
+	for _, value := range s.storage[address] {
+		if value != ([32]byte{}) {
+			return false
+		}
+	}
+	return true
 }
 
 // GetCode retrieves contract code from the state
-func (s *State) GetCode(address [20]byte) ([]byte, error) {
+func (s *State) GetCode(address types.Address) ([]byte, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -108,15 +361,38 @@ func (s *State) GetCode(address [20]byte) ([]byte, error) {
 }
 
 // SetCode sets contract code in the state
-func (s *State) SetCode(address [20]byte, code []byte) {
+func (s *State) SetCode(address types.Address, code []byte) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.touchCodeLocked(address)
+	s.touchAccountLocked(address)
 	s.code[address] = code
+	s.markAccountLocked(address)
+	s.persistCode(address, code)
+	s.syncCodeHashLocked(address)
+}
+
+// syncCodeHashLocked records the hash of the code of address in its account, if any. The
+// account is replaced rather than modified, callers may hold the previous one. Must be
+// called with s.mu held.
+func (s *State) syncCodeHashLocked(address types.Address) {
+	account, ok := s.accounts[address]
+	if !ok {
+		return
+	}
+	hash := CodeHash(s.code[address])
+	if account.CodeHash == hash {
+		return
+	}
+	updated := *account
+	updated.CodeHash = hash
+	s.accounts[address] = &updated
+	s.persistAccount(&updated)
 }
 
 // GetStorage retrieves a storage value from the state
-func (s *State) GetStorage(address [20]byte, key [32]byte) ([32]byte, error) {
+func (s *State) GetStorage(address types.Address, key [32]byte) ([32]byte, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -133,56 +409,83 @@ func (s *State) GetStorage(address [20]byte, key [32]byte) ([32]byte, error) {
 	return value, nil
 }
 
+// GetStorageAt returns a storage slot as it was after batch batchNumber, zero for a slot
+// never written. Only the latest batch is retained unless the archive is enabled.
+func (s *State) GetStorageAt(address types.Address, key [32]byte, batchNumber uint64) ([32]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.retainedLocked(batchNumber); err != nil {
+		return [32]byte{}, err
+	}
+	if batchNumber == s.batchNumber-1 {
+		return s.storage[address][key], nil
+	}
+	return s.archive.slotAt(address, key, batchNumber), nil
+}
+
 // SetStorage sets a storage value in the state
-func (s *State) SetStorage(address [20]byte, key [32]byte, value [32]byte) {
+func (s *State) SetStorage(address types.Address, key [32]byte, value [32]byte) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.touchSlotLocked(address, key)
 	if _, ok := s.storage[address]; !ok {
 		s.storage[address] = make(map[[32]byte][32]byte)
 	}
 
 	s.storage[address][key] = value
+	s.markSlotLocked(address, key)
+	s.persist(storageKey(address, key), value[:])
 }
 
-// GetStateRoot computes and returns the state root hash
-// This is a simplified implementation for the ZK-Rollup
+// GetStateRoot returns the root of the sparse Merkle tree over all accounts,
+// their code and storage. Only accounts changed since the last call are rehashed.
 func (s *State) GetStateRoot() [32]byte {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// In a real implementation, this would compute a Merkle root of the state
-	// For now, we'll use a simple hash of account addresses and balances
-	var stateRoot [32]byte
+	s.flushTreesLocked()
+	return s.accountTree.Root()
+}
 
-	// Create a simple hash based on the number of accounts and their balances
-	// This is just a placeholder - a real implementation would use a proper Merkle tree
-	for addr, acc := range s.accounts {
-		// XOR the address bytes with the state root
-		for i, b := range addr {
-			stateRoot[i%32] ^= b
-		}
+// GetBatchNumber returns the current batch number
+func (s *State) GetBatchNumber() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.batchNumber
+}
 
-		// If the account has a balance, incorporate it into the hash
-		if acc.Balance != nil {
-			balanceBytes := acc.Balance.Bytes()
-			for i, b := range balanceBytes {
-				stateRoot[(i+16)%32] ^= b
-			}
-		}
+// GetBatches returns up to count finalized batches starting at batch number from. Nothing
+// is returned from before the start of the held history.
+func (s *State) GetBatches(from, count uint64) []Batch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-		// Incorporate nonce into the hash
-		stateRoot[acc.Nonce%32] ^= byte(acc.Nonce % 256)
+	if from < s.historyStart || from >= s.batchNumber {
+		return nil
+	}
+	to := from + count
+	if to > s.batchNumber {
+		to = s.batchNumber
 	}
 
-	return stateRoot
+	return append([]Batch(nil), s.batches[from-s.historyStart:to-s.historyStart]...)
 }
 
-// GetBatchNumber returns the current batch number
-func (s *State) GetBatchNumber() uint64 {
+// HistoryStart returns the number of the oldest batch held, 0 unless the node fast synced
+func (s *State) HistoryStart() uint64 {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.batchNumber
+	return s.historyStart
+}
+
+// batchLocked returns the held batch with the given number. Must be called with s.mu held.
+func (s *State) batchLocked(batchNumber uint64) (*Batch, bool) {
+	if batchNumber < s.historyStart || batchNumber >= s.batchNumber {
+		return nil, false
+	}
+	return &s.batches[batchNumber-s.historyStart], true
 }
 
 // AddBatch adds a batch to the state
@@ -198,4 +501,61 @@ func (s *State) AddBatch(batch *Batch) {
 
 	// Increment the batch number
 	s.batchNumber++
+
+	s.persistBatch(batch)
+	s.takePendingLogsLocked(batch.BatchNumber)
+	s.takePendingReceiptsLocked(batch)
+	s.recordArchiveLocked(batch.BatchNumber)
+}
+
+// SetBatchProof attaches a proof and its public inputs, laid out by the schema with the
+// given hash, generated with the keys of a CRS epoch after the batch was finalized. It is
+// persisted with the next Commit.
+func (s *State) SetBatchProof(batchNumber uint64, proof, publicInputs []byte, schema [32]byte, epoch uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch, ok := s.batchLocked(batchNumber)
+	if !ok {
+		return fmt.Errorf("unknown batch %d", batchNumber)
+	}
+
+	batch.Proof = proof
+	batch.PublicInputs = publicInputs
+	batch.InputSchema = schema
+	batch.VerifierEpoch = epoch
+	s.persistBatch(batch)
+	return nil
+}
+
+// SetBatchL1TxHash records the L1 transaction that submitted a finalized batch. It is
+// persisted with the next Commit.
+func (s *State) SetBatchL1TxHash(batchNumber uint64, txHash [32]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch, ok := s.batchLocked(batchNumber)
+	if !ok {
+		return fmt.Errorf("unknown batch %d", batchNumber)
+	}
+
+	batch.L1TxHash = txHash
+	s.persistBatch(batch)
+	return nil
+}
+
+// SetBatchStateRoot replaces the recorded state root of a finalized batch. It is persisted
+// with the next Commit.
+func (s *State) SetBatchStateRoot(batchNumber uint64, root [32]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch, ok := s.batchLocked(batchNumber)
+	if !ok {
+		return fmt.Errorf("unknown batch %d", batchNumber)
+	}
+
+	batch.StateRoot = root
+	s.persistBatch(batch)
+	return nil
 }
@@ -0,0 +1,176 @@
+package state
+
+import (
+	"math/big"
+
+	"zkrollup/pkg/types"
+)
+
+// StateProof proves an account and some of its storage slots against the state root after
+// a batch, like eth_getProof. Past batches are proven from the archive: the current trees
+// are copied and every key changed since the batch is set back to its archived value.
+type StateProof struct {
+	BatchNumber uint64
+	StateRoot   [32]byte
+	Account     *AccountProof
+	Storage     []*StorageProof // Against Account.StorageRoot, in the order requested
+}
+
+// GetProofAt proves the account at address and its storage slots at keys after the given
+// batch. Without an archive only the latest batch can be proven.
+func (s *State) GetProofAt(address types.Address, keys [][32]byte, batchNumber uint64) (*StateProof, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.retainedLocked(batchNumber); err != nil {
+		return nil, err
+	}
+	s.flushTreesLocked()
+
+	if s.archive == nil {
+		proof := &StateProof{
+			BatchNumber: batchNumber,
+			StateRoot:   s.accountTree.Root(),
+			Account:     s.accountProofLocked(address),
+		}
+		tree := s.storageTreeLocked(address)
+		for _, key := range keys {
+			proof.Storage = append(proof.Storage, &StorageProof{
+				Key:   key,
+				Value: s.storage[address][key],
+				Proof: tree.Prove(storageKeyHash(s.hash, key)),
+			})
+		}
+		return proof, nil
+	}
+
+	accountTree := s.pastAccountTreeLocked(batchNumber)
+	storageTree := s.pastStorageTreeLocked(address, batchNumber)
+	proof := &StateProof{
+		BatchNumber: batchNumber,
+		StateRoot:   accountTree.Root(),
+		Account: &AccountProof{
+			Address: address,
+			Balance: big.NewInt(0),
+			Proof:   accountTree.Prove(accountKeyHash(s.hash, address)),
+		},
+	}
+
+	account := s.archive.accountAt(address, batchNumber)
+	if account != nil {
+		if account.Balance != nil {
+			proof.Account.Balance = new(big.Int).Set(account.Balance)
+		}
+		proof.Account.Nonce = account.Nonce
+	}
+	if !pastAccountEmpty(account, storageTree) {
+		proof.Account.Exists = true
+		proof.Account.StorageRoot = storageTree.Root()
+		if account != nil {
+			proof.Account.CodeHash = account.CodeHash
+		}
+	}
+	for _, key := range keys {
+		proof.Storage = append(proof.Storage, &StorageProof{
+			Key:   key,
+			Value: s.archive.slotAt(address, key, batchNumber),
+			Proof: storageTree.Prove(storageKeyHash(s.hash, key)),
+		})
+	}
+	return proof, nil
+}
+
+// Verify checks the account proof against the state root and every storage proof against
+// the proven storage root, that of an empty tree for an absent account
+func (p *StateProof) Verify(hash HashFunc) bool {
+	if hash == nil {
+		hash = SHA256Hash
+	}
+	if p.Account == nil || !p.Account.Verify(p.StateRoot, hash) {
+		return false
+	}
+
+	storageRoot := p.Account.StorageRoot
+	if !p.Account.Exists {
+		storageRoot = NewSparseMerkleTree(hash).Root()
+	}
+	for _, slot := range p.Storage {
+		if slot == nil || !slot.Verify(storageRoot, hash) {
+			return false
+		}
+	}
+	return true
+}
+
+// pastAccountTreeLocked returns the account tree after batch n, the current tree itself
+// when nothing changed since. The trees must be up to date and s.mu held.
+func (s *State) pastAccountTreeLocked(n uint64) *SparseMerkleTree {
+	var changed []types.Address
+	for addr, versions := range s.archive.accounts {
+		if _, pending := s.archive.changedAccounts[addr]; pending || versions[len(versions)-1].batch > n {
+			changed = append(changed, addr)
+		}
+	}
+	for addr := range s.archive.changedAccounts {
+		if _, ok := s.archive.accounts[addr]; !ok {
+			changed = append(changed, addr)
+		}
+	}
+	if len(changed) == 0 {
+		return s.accountTree
+	}
+
+	tree := s.accountTree.Copy()
+	for _, addr := range changed {
+		var leaf [32]byte
+		account := s.archive.accountAt(addr, n)
+		storageTree := s.pastStorageTreeLocked(addr, n)
+		if !pastAccountEmpty(account, storageTree) {
+			var balance *big.Int
+			var nonce uint64
+			var codeHash [32]byte
+			if account != nil {
+				balance, nonce, codeHash = account.Balance, account.Nonce, account.CodeHash
+			}
+			leaf = accountLeaf(s.hash, addr, balance, nonce, storageTree.Root(), codeHash)
+		}
+		tree.Update(accountKeyHash(s.hash, addr), leaf)
+	}
+	return tree
+}
+
+// pastStorageTreeLocked returns the storage tree of address after batch n, which must not
+// be modified. The trees must be up to date and s.mu held.
+func (s *State) pastStorageTreeLocked(address types.Address, n uint64) *SparseMerkleTree {
+	pending := s.archive.changedSlots[address]
+	var changed [][32]byte
+	for key, versions := range s.archive.slots[address] {
+		if _, ok := pending[key]; ok || versions[len(versions)-1].batch > n {
+			changed = append(changed, key)
+		}
+	}
+	for key := range pending {
+		if _, ok := s.archive.slots[address][key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+
+	tree := s.storageTreeLocked(address)
+	if len(changed) == 0 {
+		return tree
+	}
+	tree = tree.Copy()
+	for _, key := range changed {
+		tree.Update(storageKeyHash(s.hash, key), s.archive.slotAt(address, key, n))
+	}
+	return tree
+}
+
+// pastAccountEmpty reports whether an archived account with the given storage tree is
+// absent from the state tree
+func pastAccountEmpty(account *Account, storageTree *SparseMerkleTree) bool {
+	if account != nil && (!account.IsEmpty() || account.CodeHash != ([32]byte{})) {
+		return false
+	}
+	return storageTree.Len() == 0
+}
@@ -0,0 +1,315 @@
+package state
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+
+	"zkrollup/pkg/types"
+)
+
+// Storage backends
+const (
+	BackendMemory  = "memory"
+	BackendLevelDB = "leveldb"
+	BackendPebble  = "pebble"
+)
+
+const (
+	dbCacheMB   = 16
+	dbHandles   = 16
+	dbNamespace = "zkrollup/state/"
+)
+
+// Key prefixes of the on-disk layout
+var (
+	accountPrefix  = []byte("a") // accountPrefix + address -> JSON account
+	codePrefix     = []byte("c") // codePrefix + address -> code hash
+	codeHashPrefix = []byte("k") // codeHashPrefix + code hash -> code
+	storagePrefix  = []byte("s") // storagePrefix + address + key -> value
+	batchPrefix    = []byte("b") // batchPrefix + batch number (uint64 big endian) -> JSON batch
+	batchCountKey  = []byte("m:batchNumber")
+	rootVersionKey = []byte("m:rootVersion")
+)
+
+// ErrReadOnly is returned when committing to a state opened read-only
+var ErrReadOnly = errors.New("state is read-only")
+
+// OpenDatabase opens the key-value store for the given backend. The memory backend ignores path.
+func OpenDatabase(backend, path string, readOnly bool) (ethdb.KeyValueStore, error) {
+	switch backend {
+	case "", BackendMemory:
+		return memorydb.New(), nil
+	case BackendLevelDB:
+		db, err := leveldb.New(path, dbCacheMB, dbHandles, dbNamespace, readOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open leveldb at %s: %v", path, err)
+		}
+		return db, nil
+	case BackendPebble:
+		db, err := pebble.New(path, dbCacheMB, dbHandles, dbNamespace, readOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open pebble at %s: %v", path, err)
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unknown state backend: %s", backend)
+	}
+}
+
+// OpenState opens a state persisted with the given backend, loading everything written
+// by a previous run. Tools should pass readOnly to inspect a live sequencer's database.
+func OpenState(backend, path string, readOnly bool) (*State, error) {
+	db, err := OpenDatabase(backend, path, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := NewStateWithDB(db, readOnly)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStateWithDB creates a state backed by db and loads its contents into memory
+func NewStateWithDB(db ethdb.KeyValueStore, readOnly bool) (*State, error) {
+	s := NewState()
+	s.db = db
+	s.readOnly = readOnly
+	if !readOnly {
+		s.pending = db.NewBatch()
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads every account, contract, storage slot and batch from the database
+func (s *State) load() error {
+	it := s.db.NewIterator(accountPrefix, nil)
+	for it.Next() {
+		var account Account
+		if err := json.Unmarshal(it.Value(), &account); err != nil {
+			it.Release()
+			return fmt.Errorf("failed to decode account %x: %v", it.Key()[len(accountPrefix):], err)
+		}
+		s.accounts[account.Address] = &account
+	}
+	if err := iteratorDone(it); err != nil {
+		return err
+	}
+
+	it = s.db.NewIterator(codePrefix, nil)
+	for it.Next() {
+		var addr types.Address
+		copy(addr[:], it.Key()[len(codePrefix):])
+
+		if len(it.Value()) != 32 {
+			it.Release()
+			return fmt.Errorf("invalid code hash of %x", addr)
+		}
+		code, err := s.db.Get(codeHashKey([32]byte(it.Value())))
+		if err != nil {
+			it.Release()
+			return fmt.Errorf("failed to read code of %x: %v", addr, err)
+		}
+		s.code[addr] = append([]byte(nil), code...)
+	}
+	if err := iteratorDone(it); err != nil {
+		return err
+	}
+	for addr, account := range s.accounts {
+		account.CodeHash = CodeHash(s.code[addr])
+	}
+
+	it = s.db.NewIterator(storagePrefix, nil)
+	for it.Next() {
+		key := it.Key()[len(storagePrefix):]
+		if len(key) != 20+32 {
+			continue
+		}
+		var addr types.Address
+		var slot, value [32]byte
+		copy(addr[:], key[:20])
+		copy(slot[:], key[20:])
+		copy(value[:], it.Value())
+		if _, ok := s.storage[addr]; !ok {
+			s.storage[addr] = make(map[[32]byte][32]byte)
+		}
+		s.storage[addr][slot] = value
+	}
+	if err := iteratorDone(it); err != nil {
+		return err
+	}
+
+	// Batch keys are big endian so iteration yields them in order
+	it = s.db.NewIterator(batchPrefix, nil)
+	for it.Next() {
+		var batch Batch
+		if err := json.Unmarshal(it.Value(), &batch); err != nil {
+			it.Release()
+			return fmt.Errorf("failed to decode batch: %v", err)
+		}
+		s.batches = append(s.batches, batch)
+	}
+	if err := iteratorDone(it); err != nil {
+		return err
+	}
+
+	if err := s.loadLogs(); err != nil {
+		return err
+	}
+	if err := s.loadReceipts(); err != nil {
+		return err
+	}
+
+	count, err := s.db.Get(batchCountKey)
+	if err == nil && len(count) == 8 {
+		s.batchNumber = binary.BigEndian.Uint64(count)
+	}
+	// A fast synced state holds no batches from before its snapshot
+	if uint64(len(s.batches)) > s.batchNumber {
+		return fmt.Errorf("stored batch count %d is below the %d stored batches", s.batchNumber, len(s.batches))
+	}
+	s.historyStart = s.batchNumber - uint64(len(s.batches))
+	if len(s.batches) > 0 && s.batches[0].BatchNumber != s.historyStart {
+		return fmt.Errorf("stored batch history starts at %d, expected %d", s.batches[0].BatchNumber, s.historyStart)
+	}
+
+	nonce, err := s.db.Get(depositNonceKey)
+	if err == nil && len(nonce) == 8 {
+		s.depositNonce = binary.BigEndian.Uint64(nonce)
+	}
+
+	// States written before roots were versioned all used SHA-256
+	version, err := s.db.Get(rootVersionKey)
+	if err == nil && len(version) == 1 {
+		v := RootVersion(version[0])
+		if v.HashFunc() == nil {
+			return fmt.Errorf("state was written with unknown root version %d", version[0])
+		}
+		s.rootVersion = v
+		s.hash = v.HashFunc()
+	}
+
+	return nil
+}
+
+// iteratorDone releases it and reports any error it hit
+func iteratorDone(it ethdb.Iterator) error {
+	defer it.Release()
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("failed to iterate state database: %v", err)
+	}
+	return nil
+}
+
+// Close flushes pending writes and closes the underlying database
+func (s *State) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+
+	var commitErr error
+	if !s.readOnly {
+		commitErr = s.flushLocked()
+		s.stopWriterLocked()
+	}
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close state database: %v", err)
+	}
+	s.db = nil
+	return commitErr
+}
+
+// persist buffers a write in the pending batch. Must be called with s.mu held.
+func (s *State) persist(key, value []byte) {
+	if s.pending == nil {
+		return
+	}
+	// Writes to an in-memory batch cannot fail
+	_ = s.pending.Put(key, value)
+}
+
+// persistDelete buffers a delete in the pending batch. Must be called with s.mu held.
+func (s *State) persistDelete(key []byte) {
+	if s.pending == nil {
+		return
+	}
+	_ = s.pending.Delete(key)
+}
+
+func (s *State) persistAccount(account *Account) {
+	if s.pending == nil {
+		return
+	}
+	data, err := json.Marshal(account)
+	if err != nil {
+		return
+	}
+	s.persist(accountKey(account.Address), data)
+}
+
+// persistCode stores code under its hash, so contracts deployed with the same code share
+// it, and points address at it. Code is never deleted from the store, the same hash always
+// names the same code. Must be called with s.mu held.
+func (s *State) persistCode(address types.Address, code []byte) {
+	if s.pending == nil {
+		return
+	}
+	hash := CodeHash(code)
+	s.persist(codeHashKey(hash), code)
+	s.persist(codeKey(address), hash[:])
+}
+
+func (s *State) persistBatch(batch *Batch) {
+	if s.pending == nil {
+		return
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return
+	}
+	s.persist(batchKey(batch.BatchNumber), data)
+
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], s.batchNumber)
+	s.persist(batchCountKey, count[:])
+}
+
+func accountKey(addr types.Address) []byte {
+	return append(append([]byte{}, accountPrefix...), addr[:]...)
+}
+
+func codeKey(addr types.Address) []byte {
+	return append(append([]byte{}, codePrefix...), addr[:]...)
+}
+
+func codeHashKey(hash [32]byte) []byte {
+	return append(append([]byte{}, codeHashPrefix...), hash[:]...)
+}
+
+func storageKey(addr types.Address, key [32]byte) []byte {
+	k := append(append([]byte{}, storagePrefix...), addr[:]...)
+	return append(k, key[:]...)
+}
+
+func batchKey(number uint64) []byte {
+	k := make([]byte, len(batchPrefix)+8)
+	copy(k, batchPrefix)
+	binary.BigEndian.PutUint64(k[len(batchPrefix):], number)
+	return k
+}
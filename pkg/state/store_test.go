@@ -0,0 +1,216 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestStateSurvivesReopen(t *testing.T) {
+	db := memorydb.New()
+
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+
+	addr := types.Address{1}
+	s.SetAccount(&Account{Address: addr, Balance: big.NewInt(42), Nonce: 3})
+	s.SetCode(addr, []byte{0x60, 0x00})
+	s.SetStorage(addr, [32]byte{1}, [32]byte{2})
+	s.AddBatch(&Batch{Timestamp: 1})
+
+	// Nothing reaches the database before Commit
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	_, err = reopened.GetAccount(addr)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+
+	require.NoError(t, s.Commit())
+
+	reopened, err = NewStateWithDB(db, true)
+	require.NoError(t, err)
+
+	acc, err := reopened.GetAccount(addr)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), acc.Balance.Int64())
+	require.Equal(t, uint64(3), acc.Nonce)
+
+	code, err := reopened.GetCode(addr)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x60, 0x00}, code)
+
+	value, err := reopened.GetStorage(addr, [32]byte{1})
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{2}, value)
+
+	require.Equal(t, uint64(1), reopened.GetBatchNumber())
+	require.ErrorIs(t, reopened.Commit(), ErrReadOnly)
+}
+
+func TestCodeStoredByHash(t *testing.T) {
+	db := memorydb.New()
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+
+	code := []byte{0x60, 0x01, 0x60, 0x00, 0x55}
+	hash := crypto.Keccak256Hash(code)
+	first, second := types.Address{1}, types.Address{2}
+	s.SetAccount(&Account{Address: first, Nonce: 1})
+	s.SetCode(first, code)
+	s.SetCode(second, code)
+	s.SetAccount(&Account{Address: second, Nonce: 1})
+	require.NoError(t, s.Commit())
+
+	// The account tracks its code hash whichever was written first
+	for _, addr := range []types.Address{first, second} {
+		acc, err := s.GetAccount(addr)
+		require.NoError(t, err)
+		require.Equal(t, [32]byte(hash), acc.CodeHash)
+	}
+
+	// Both contracts point at a single copy of the code
+	stored, err := db.Get(codeHashKey(hash))
+	require.NoError(t, err)
+	require.Equal(t, code, stored)
+	ref, err := db.Get(codeKey(second))
+	require.NoError(t, err)
+	require.Equal(t, hash[:], ref)
+
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	for _, addr := range []types.Address{first, second} {
+		got, err := reopened.GetCode(addr)
+		require.NoError(t, err)
+		require.Equal(t, code, got)
+		acc, err := reopened.GetAccount(addr)
+		require.NoError(t, err)
+		require.Equal(t, [32]byte(hash), acc.CodeHash)
+	}
+	// Code references are always hashes, a value that is not one is not loaded as code
+	require.NoError(t, db.Put(codeKey(types.Address{3}), []byte{0x00}))
+	_, err = NewStateWithDB(db, true)
+	require.Error(t, err)
+}
+
+func TestBatchL1TxHashSurvivesReopen(t *testing.T) {
+	db := memorydb.New()
+
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	s.AddBatch(&Batch{Timestamp: 1})
+	require.Error(t, s.SetBatchL1TxHash(1, [32]byte{9}))
+	require.NoError(t, s.SetBatchL1TxHash(0, [32]byte{9}))
+	require.NoError(t, s.Commit())
+
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	batches := reopened.GetBatches(0, 1)
+	require.Len(t, batches, 1)
+	require.Equal(t, [32]byte{9}, batches[0].L1TxHash)
+}
+
+func TestBatchProofSurvivesReopen(t *testing.T) {
+	db := memorydb.New()
+
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	s.AddBatch(&Batch{Timestamp: 1})
+	require.Error(t, s.SetBatchProof(1, []byte{1}, []byte{2}, [32]byte{3}, 4))
+	require.NoError(t, s.SetBatchProof(0, []byte{1}, []byte{2}, [32]byte{3}, 4))
+	require.NoError(t, s.Commit())
+
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	batches := reopened.GetBatches(0, 1)
+	require.Len(t, batches, 1)
+	require.Equal(t, []byte{1}, batches[0].Proof)
+	require.Equal(t, []byte{2}, batches[0].PublicInputs)
+	require.Equal(t, [32]byte{3}, batches[0].InputSchema)
+	require.Equal(t, uint64(4), batches[0].VerifierEpoch)
+}
+
+func TestGetStorageAt(t *testing.T) {
+	s := NewState()
+	addr := types.Address{1}
+
+	_, err := s.GetStorageAt(addr, [32]byte{1}, 0)
+	require.ErrorIs(t, err, ErrStateNotRetained)
+
+	s.SetStorage(addr, [32]byte{1}, [32]byte{2})
+	s.AddBatch(&Batch{})
+
+	value, err := s.GetStorageAt(addr, [32]byte{1}, 0)
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{2}, value)
+
+	value, err = s.GetStorageAt(addr, [32]byte{3}, 0)
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{}, value)
+
+	s.AddBatch(&Batch{})
+	_, err = s.GetStorageAt(addr, [32]byte{1}, 0)
+	require.ErrorIs(t, err, ErrStateNotRetained)
+}
+
+func TestCommitPolicyBatches(t *testing.T) {
+	db := memorydb.New()
+
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	require.Error(t, s.SetCommitPolicy(CommitPolicy{Mode: CommitBatches}))
+	require.NoError(t, s.SetCommitPolicy(CommitPolicy{Mode: CommitBatches, Interval: 2}))
+
+	flushes := 0
+	s.SetFlushObserver(func(time.Duration) { flushes++ })
+
+	// The first batch waits for the second, both are written together
+	s.AddBatch(&Batch{Timestamp: 1})
+	require.NoError(t, s.Commit())
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), reopened.GetBatchNumber())
+
+	s.AddBatch(&Batch{Timestamp: 2})
+	require.NoError(t, s.Commit())
+	reopened, err = NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), reopened.GetBatchNumber())
+	require.Equal(t, 1, flushes)
+
+	// Flush does not wait for the interval
+	s.AddBatch(&Batch{Timestamp: 3})
+	require.NoError(t, s.Flush())
+	reopened, err = NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), reopened.GetBatchNumber())
+}
+
+func TestCommitPolicyAsync(t *testing.T) {
+	db := memorydb.New()
+
+	s, err := NewStateWithDB(db, false)
+	require.NoError(t, err)
+	require.NoError(t, s.SetCommitPolicy(CommitPolicy{Mode: CommitAsync}))
+	defer s.Close()
+
+	addr := types.Address{1}
+	for i := uint64(1); i <= 10; i++ {
+		s.SetAccount(&Account{Address: addr, Balance: big.NewInt(int64(i)), Nonce: i})
+		s.AddBatch(&Batch{Timestamp: i})
+		require.NoError(t, s.Commit())
+	}
+
+	// Flush waits for the background writes, which land in commit order
+	require.NoError(t, s.Flush())
+	reopened, err := NewStateWithDB(db, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), reopened.GetBatchNumber())
+	acc, err := reopened.GetAccount(addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), acc.Nonce)
+}
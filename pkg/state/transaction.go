@@ -1,46 +1,26 @@
 package state
 
 import (
-	"crypto/sha256"
-	"encoding/binary"
 	"fmt"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/types"
 )
 
-// Hash computes the hash of a transaction
+// Hash returns the Keccak-256 hash of the RLP encoding of the signed fields. Every field
+// is length-prefixed, so transactions only share a hash if all their fields are equal.
 func (tx *Transaction) Hash() [32]byte {
-	// Create a buffer to hold all transaction data
-	var buffer []byte
-
-	// Add transaction type
-	buffer = append(buffer, byte(tx.Type))
-
-	// Add from and to addresses
-	buffer = append(buffer, tx.From[:]...)
-	buffer = append(buffer, tx.To[:]...)
-
-	// Add amount (convert to bytes)
-	amountBytes := tx.Amount.Bytes()
-	buffer = append(buffer, amountBytes...)
-
-	// Add nonce (convert to bytes)
-	nonceBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(nonceBytes, tx.Nonce)
-	buffer = append(buffer, nonceBytes...)
-
-	// Add data
-	buffer = append(buffer, tx.Data...)
-
-	// Add gas (convert to bytes)
-	gasBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(gasBytes, tx.Gas)
-	buffer = append(buffer, gasBytes...)
+	return tx.legacySigningHash()
+}
 
-	// Compute hash
-	hash := sha256.Sum256(buffer)
-	return hash
+// CalculateTransactionHash returns the hash a transaction is indexed under. It covers the
+// signed fields only, so it is the same on every node and known before the transaction is
+// batched.
+func CalculateTransactionHash(tx *Transaction) [32]byte {
+	return tx.Hash()
 }
 
 // HashToBytes converts a transaction hash to bytes
@@ -55,10 +35,53 @@ func (tx *Transaction) HashToEthHash() common.Hash {
 	return common.BytesToHash(hash[:])
 }
 
-// SignTransaction signs a transaction with the given private key
-func SignTransaction(tx *Transaction, privateKey []byte) ([]byte, error) {
-	// Compute the hash of the transaction
-	hash := tx.Hash()
+// Sender recovers the address that signed the transaction for the rollup contract rollup.
+// Both 0/1 and 27/28 recovery IDs are accepted, high s values are rejected as malleable.
+func (tx *Transaction) Sender(rollup types.Address) (types.Address, error) {
+	if len(tx.Signature) != crypto.SignatureLength {
+		return types.Address{}, fmt.Errorf("%w: got %d bytes, want %d", ErrInvalidSignature, len(tx.Signature), crypto.SignatureLength)
+	}
+
+	if (tx.Amount != nil && tx.Amount.Sign() < 0) || (tx.GasPrice != nil && tx.GasPrice.Sign() < 0) {
+		return types.Address{}, fmt.Errorf("%w: negative amount or gas price", ErrInvalidSignature)
+	}
+
+	sig := append([]byte(nil), tx.Signature...)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	sv := new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[64], r, sv, true) {
+		return types.Address{}, fmt.Errorf("%w: malformed signature values", ErrInvalidSignature)
+	}
+
+	hash := tx.SigningHash(rollup)
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return types.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return types.FromCommon(crypto.PubkeyToAddress(*pub)), nil
+}
+
+// VerifySignature checks that the transaction was signed by its sender for the rollup
+// contract rollup
+func (tx *Transaction) VerifySignature(rollup types.Address) error {
+	signer, err := tx.Sender(rollup)
+	if err != nil {
+		return err
+	}
+	if signer != tx.From {
+		return fmt.Errorf("%w: signed by %s, sender is %s", ErrInvalidSignature, signer, tx.From)
+	}
+	return nil
+}
+
+// SignTransaction signs a transaction's signing hash for the rollup contract rollup with
+// the given private key
+func SignTransaction(tx *Transaction, privateKey []byte, rollup types.Address) ([]byte, error) {
+	// Compute the hash the signature covers
+	hash := tx.SigningHash(rollup)
 
 	// Parse private key
 	privKey, err := crypto.ToECDSA(privateKey)
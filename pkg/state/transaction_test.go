@@ -0,0 +1,182 @@
+package state
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestTransactionSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	tx := &Transaction{
+		Type:   TxTypeTransfer,
+		From:   types.FromCommon(crypto.PubkeyToAddress(key.PublicKey)),
+		To:     types.Address{2},
+		Amount: big.NewInt(10),
+		Nonce:  1,
+		Gas:    21000,
+	}
+	tx.Signature, err = SignTransaction(tx, crypto.FromECDSA(key), testRollup)
+	require.NoError(t, err)
+	require.NoError(t, tx.VerifySignature(testRollup))
+
+	// Wallets commonly use 27/28 recovery IDs
+	legacy := *tx
+	legacy.Signature = append([]byte(nil), tx.Signature...)
+	legacy.Signature[64] += 27
+	require.NoError(t, legacy.VerifySignature(testRollup))
+
+	// Any change to a signed field invalidates the signature
+	tampered := *tx
+	tampered.Amount = big.NewInt(11)
+	require.ErrorIs(t, tampered.VerifySignature(testRollup), ErrInvalidSignature)
+
+	// The trace ID is not signed
+	traced := *tx
+	traced.TraceID = "abc"
+	require.NoError(t, traced.VerifySignature(testRollup))
+
+	forged := *tx
+	forged.From = types.Address{1}
+	require.ErrorIs(t, forged.VerifySignature(testRollup), ErrInvalidSignature)
+
+	unsigned := *tx
+	unsigned.Signature = nil
+	require.ErrorIs(t, unsigned.VerifySignature(testRollup), ErrInvalidSignature)
+}
+
+func TestSigningHashIsUnambiguous(t *testing.T) {
+	// Moving a byte between the amount and the data must change the hash
+	a := &Transaction{Amount: big.NewInt(0x0102), Data: []byte{0x03}}
+	b := &Transaction{Amount: big.NewInt(0x01), Data: []byte{0x02, 0x03}}
+	require.NotEqual(t, a.SigningHash(testRollup), b.SigningHash(testRollup))
+}
+
+func TestHashIsUnambiguous(t *testing.T) {
+	// Concatenated without length prefixes these are the same bytes: the amount's second
+	// byte moves into the nonce, whose last byte moves into the data
+	a := &Transaction{Amount: big.NewInt(0x0102), Nonce: 0}
+	b := &Transaction{Amount: big.NewInt(0x01), Nonce: 0x0200000000000000, Data: []byte{0x00}}
+	require.NotEqual(t, a.Hash(), b.Hash())
+
+	// Transactions differing only in their nonce hash differently
+	c := &Transaction{Amount: big.NewInt(0x0102), Nonce: 1}
+	require.NotEqual(t, a.Hash(), c.Hash())
+	require.Equal(t, a.Hash(), CalculateTransactionHash(a))
+}
+
+func TestTransactionRLPRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	tx := &Transaction{
+		Type:     TxTypeContractCall,
+		From:     types.FromCommon(crypto.PubkeyToAddress(key.PublicKey)),
+		To:       types.Address{3},
+		Amount:   big.NewInt(5),
+		Nonce:    7,
+		Data:     []byte{0xde, 0xad},
+		Gas:      50000,
+		GasPrice: big.NewInt(2),
+		TraceID:  "abc",
+	}
+	tx.Signature, err = SignTransaction(tx, crypto.FromECDSA(key), testRollup)
+	require.NoError(t, err)
+
+	encoded, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+
+	var decoded Transaction
+	require.NoError(t, rlp.DecodeBytes(encoded, &decoded))
+	require.Equal(t, tx.SigningHash(testRollup), decoded.SigningHash(testRollup))
+	require.Equal(t, tx.Signature, decoded.Signature)
+	require.Empty(t, decoded.TraceID)
+	require.NoError(t, decoded.VerifySignature(testRollup))
+
+	// Negative amounts have no encoding and are never accepted as signed
+	negative := *tx
+	negative.Amount = big.NewInt(-1)
+	_, err = rlp.EncodeToBytes(&negative)
+	require.Error(t, err)
+	require.ErrorIs(t, negative.VerifySignature(testRollup), ErrInvalidSignature)
+}
+
+func TestTransactionJSONAmounts(t *testing.T) {
+	huge, ok := new(big.Int).SetString("123456789012345678901234567890123456789", 10)
+	require.True(t, ok)
+
+	for _, amount := range []*big.Int{big.NewInt(0), big.NewInt(1), huge} {
+		tx := &Transaction{Amount: amount, GasPrice: huge, Data: []byte{1}}
+		encoded, err := json.Marshal(tx)
+		require.NoError(t, err)
+		require.Contains(t, string(encoded), `"Amount":"`+amount.String()+`"`)
+
+		var decoded Transaction
+		require.NoError(t, json.Unmarshal(encoded, &decoded))
+		require.Zero(t, amount.Cmp(decoded.Amount))
+		require.Zero(t, huge.Cmp(decoded.GasPrice))
+		require.Equal(t, tx.Data, decoded.Data)
+	}
+
+	// A nil amount encodes as zero rather than null, an unset gas price is omitted
+	encoded, err := json.Marshal(&Transaction{})
+	require.NoError(t, err)
+	require.Contains(t, string(encoded), `"Amount":"0"`)
+	require.NotContains(t, string(encoded), "GasPrice")
+
+	var decoded Transaction
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.NotNil(t, decoded.Amount)
+	require.Nil(t, decoded.GasPrice)
+
+	// Batches stored with numeric amounts still decode
+	require.NoError(t, json.Unmarshal([]byte(`{"Amount":`+huge.String()+`}`), &decoded))
+	require.Zero(t, huge.Cmp(decoded.Amount))
+
+	_, err = json.Marshal(&Transaction{Amount: big.NewInt(-1)})
+	require.Error(t, err)
+	require.Error(t, json.Unmarshal([]byte(`{"Amount":"-1"}`), &decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"Amount":"1.5"}`), &decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"Amount":"0x10"}`), &decoded))
+}
+
+func TestChainIDReplayProtection(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	tx := &Transaction{
+		Type:    TxTypeTransfer,
+		From:    types.FromCommon(crypto.PubkeyToAddress(key.PublicKey)),
+		To:      types.Address{2},
+		Amount:  big.NewInt(10),
+		Nonce:   1,
+		ChainID: 42,
+	}
+	tx.Signature, err = SignTransaction(tx, crypto.FromECDSA(key), testRollup)
+	require.NoError(t, err)
+	require.NoError(t, tx.VerifySignature(testRollup))
+
+	// The signature does not carry over to another chain, or to no chain at all
+	other := *tx
+	other.ChainID = 43
+	require.ErrorIs(t, other.VerifySignature(testRollup), ErrInvalidSignature)
+	require.NotEqual(t, tx.Hash(), other.Hash())
+	unprotected := *tx
+	unprotected.ChainID = 0
+	require.ErrorIs(t, unprotected.VerifySignature(testRollup), ErrInvalidSignature)
+
+	encoded, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+	var decoded Transaction
+	require.NoError(t, rlp.DecodeBytes(encoded, &decoded))
+	require.Equal(t, uint64(42), decoded.ChainID)
+	require.NoError(t, decoded.VerifySignature(testRollup))
+}
@@ -0,0 +1,134 @@
+package state
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"zkrollup/pkg/types"
+)
+
+const (
+	// SigningDomainName and SigningDomainVersion name the EIP-712 domain transactions are
+	// signed in, shown by wallets next to the chain ID and rollup contract
+	SigningDomainName    = "zkrollup"
+	SigningDomainVersion = "1"
+
+	// txPrimaryType is the EIP-712 struct a transaction is signed as
+	txPrimaryType = "Transaction"
+)
+
+var (
+	// domainTypeHash is the EIP-712 type hash of the signing domain
+	domainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+
+	// txTypeHash is the EIP-712 type hash of a transaction. The chain ID is part of the domain.
+	txTypeHash = crypto.Keccak256Hash([]byte("Transaction(uint8 txType,address from,address to,uint256 amount,uint64 nonce,uint64 gas,uint256 gasPrice,bytes data)"))
+)
+
+// txTypedFields are the EIP-712 fields of a transaction, in type hash order
+var txTypedFields = []apitypes.Type{
+	{Name: "txType", Type: "uint8"},
+	{Name: "from", Type: "address"},
+	{Name: "to", Type: "address"},
+	{Name: "amount", Type: "uint256"},
+	{Name: "nonce", Type: "uint64"},
+	{Name: "gas", Type: "uint64"},
+	{Name: "gasPrice", Type: "uint256"},
+	{Name: "data", Type: "bytes"},
+}
+
+// SigningDomain is the EIP-712 domain a transaction is signed in: the chain ID of the
+// rollup and its contract on L1, so a signature is valid for exactly one rollup
+type SigningDomain struct {
+	ChainID           uint64
+	VerifyingContract types.Address
+}
+
+// Separator returns the EIP-712 domain separator
+func (d SigningDomain) Separator() common.Hash {
+	var chainID [32]byte
+	binary.BigEndian.PutUint64(chainID[24:], d.ChainID)
+	return crypto.Keccak256Hash(
+		domainTypeHash[:],
+		crypto.Keccak256([]byte(SigningDomainName)),
+		crypto.Keccak256([]byte(SigningDomainVersion)),
+		chainID[:],
+		common.LeftPadBytes(d.VerifyingContract[:], 32),
+	)
+}
+
+// Domain returns the signing domain of a transaction for the rollup contract rollup
+func (tx *Transaction) Domain(rollup types.Address) SigningDomain {
+	return SigningDomain{ChainID: tx.ChainID, VerifyingContract: rollup}
+}
+
+// StructHash returns the EIP-712 hash of the transaction's signed fields
+func (tx *Transaction) StructHash() common.Hash {
+	return crypto.Keccak256Hash(
+		txTypeHash[:],
+		math.U256Bytes(new(big.Int).SetUint64(uint64(tx.Type))),
+		common.LeftPadBytes(tx.From[:], 32),
+		common.LeftPadBytes(tx.To[:], 32),
+		math.U256Bytes(new(big.Int).Set(bigOrZero(tx.Amount))),
+		math.U256Bytes(new(big.Int).SetUint64(tx.Nonce)),
+		math.U256Bytes(new(big.Int).SetUint64(tx.Gas)),
+		math.U256Bytes(new(big.Int).Set(bigOrZero(tx.GasPrice))),
+		crypto.Keccak256(tx.Data),
+	)
+}
+
+// SigningHash returns the message wallets, tools and the sequencer sign and verify for a
+// transaction sent to the rollup contract rollup. Protected transactions are signed as
+// EIP-712 typed data in the domain of their chain ID and rollup, so hardware wallets can
+// show what they sign. Unprotected ones have no domain and keep the hash of their RLP
+// encoding. A transaction with a negative amount or gas price hashes to zero.
+func (tx *Transaction) SigningHash(rollup types.Address) [32]byte {
+	if (tx.Amount != nil && tx.Amount.Sign() < 0) || (tx.GasPrice != nil && tx.GasPrice.Sign() < 0) {
+		return [32]byte{}
+	}
+	if tx.ChainID == 0 {
+		return tx.legacySigningHash()
+	}
+	separator := tx.Domain(rollup).Separator()
+	structHash := tx.StructHash()
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, separator[:], structHash[:])
+}
+
+// TypedData returns a protected transaction as EIP-712 typed data for the rollup contract
+// rollup, the request eth_signTypedData_v4 takes. Its hash is SigningHash.
+func (tx *Transaction) TypedData(rollup types.Address) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			txPrimaryType: txTypedFields,
+		},
+		PrimaryType: txPrimaryType,
+		Domain: apitypes.TypedDataDomain{
+			Name:              SigningDomainName,
+			Version:           SigningDomainVersion,
+			ChainId:           (*math.HexOrDecimal256)(new(big.Int).SetUint64(tx.ChainID)),
+			VerifyingContract: rollup.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"txType":   new(big.Int).SetUint64(uint64(tx.Type)).String(),
+			"from":     tx.From.Hex(),
+			"to":       tx.To.Hex(),
+			"amount":   bigOrZero(tx.Amount).String(),
+			"nonce":    new(big.Int).SetUint64(tx.Nonce).String(),
+			"gas":      new(big.Int).SetUint64(tx.Gas).String(),
+			"gasPrice": bigOrZero(tx.GasPrice).String(),
+			"data":     hexutil.Encode(tx.Data),
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+// testRollup is the rollup contract test transactions are signed for
+var testRollup = types.MustParseAddress("0x00000000000000000000000000000000000000aa")
+
+func TestTypedDataSigningHash(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	tx := &Transaction{
+		Type:     TxTypeContractCall,
+		From:     types.FromCommon(crypto.PubkeyToAddress(key.PublicKey)),
+		To:       types.Address{3},
+		Amount:   big.NewInt(5),
+		Nonce:    7,
+		Data:     []byte{0xde, 0xad},
+		Gas:      50000,
+		GasPrice: big.NewInt(2),
+		ChainID:  42,
+	}
+
+	// The hash is the one a wallet signing the typed data with eth_signTypedData_v4 computes
+	hash, _, err := apitypes.TypedDataAndHash(tx.TypedData(testRollup))
+	require.NoError(t, err)
+	signingHash := tx.SigningHash(testRollup)
+	require.Equal(t, hash, signingHash[:])
+
+	tx.Signature, err = SignTransaction(tx, crypto.FromECDSA(key), testRollup)
+	require.NoError(t, err)
+	require.NoError(t, tx.VerifySignature(testRollup))
+
+	// A signature is only valid for the rollup contract of its domain
+	require.ErrorIs(t, tx.VerifySignature(types.Address{0xbb}), ErrInvalidSignature)
+
+	// Unprotected transactions have no domain and are signed over their RLP encoding
+	unprotected := *tx
+	unprotected.ChainID = 0
+	require.Equal(t, unprotected.legacySigningHash(), unprotected.SigningHash(testRollup))
+	require.Equal(t, unprotected.SigningHash(testRollup), unprotected.SigningHash(types.Address{0xbb}))
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"zkrollup/pkg/types"
+)
+
+// WithdrawalAddress receives funds leaving the rollup. Its storage records the total each
+// account withdrew, which the L1 contract pays out against a storage proof.
+var WithdrawalAddress = types.MustParseAddress("0x00000000000000000000000000000000000000ee")
+
+// WithdrawalSlot is the storage slot of WithdrawalAddress holding the total withdrawn by
+// account, the keccak256 hash of the left padded address like a Solidity mapping key
+func WithdrawalSlot(account types.Address) [32]byte {
+	var word [32]byte
+	copy(word[12:], account[:])
+	return crypto.Keccak256Hash(word[:])
+}
+
+// WithdrawalProof proves the total withdrawn by an account against a batch's state root
+type WithdrawalProof struct {
+	Account     types.Address
+	Amount      *big.Int
+	BatchNumber uint64
+	StateRoot   [32]byte
+	Bridge      *AccountProof // WithdrawalAddress against StateRoot
+	Slot        *StorageProof // WithdrawalSlot(Account) against the bridge storage root
+}
+
+// GetWithdrawn returns the total account withdrew from the rollup
+func (s *State) GetWithdrawn(account types.Address) *big.Int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value := s.storage[WithdrawalAddress][WithdrawalSlot(account)]
+	return new(big.Int).SetBytes(value[:])
+}
+
+// RecordWithdrawal adds amount to the total withdrawn by account. The funds themselves
+// must already have been moved to WithdrawalAddress.
+func (s *State) RecordWithdrawal(account types.Address, amount *big.Int) {
+	total := new(big.Int).Add(s.GetWithdrawn(account), amount)
+
+	var value [32]byte
+	total.FillBytes(value[:])
+	s.SetStorage(WithdrawalAddress, WithdrawalSlot(account), value)
+}
+
+// GetWithdrawalProof proves the total withdrawn by account after the given batch. Only
+// the latest batch is retained.
+func (s *State) GetWithdrawalProof(account types.Address, batchNumber uint64) (*WithdrawalProof, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.batchNumber == 0 || batchNumber != s.batchNumber-1 {
+		return nil, fmt.Errorf("%w: batch %d", ErrStateNotRetained, batchNumber)
+	}
+
+	s.flushTreesLocked()
+	slot := WithdrawalSlot(account)
+	value := s.storage[WithdrawalAddress][slot]
+
+	return &WithdrawalProof{
+		Account:     account,
+		Amount:      new(big.Int).SetBytes(value[:]),
+		BatchNumber: batchNumber,
+		StateRoot:   s.accountTree.Root(),
+		Bridge:      s.accountProofLocked(WithdrawalAddress),
+		Slot: &StorageProof{
+			Key:   slot,
+			Value: value,
+			Proof: s.storageTreeLocked(WithdrawalAddress).Prove(storageKeyHash(s.hash, slot)),
+		},
+	}, nil
+}
+
+// Verify checks the proof against its state root using hash for tree nodes
+func (p *WithdrawalProof) Verify(hash HashFunc) bool {
+	if p.Bridge == nil || p.Slot == nil || p.Bridge.Address != WithdrawalAddress || p.Slot.Key != WithdrawalSlot(p.Account) {
+		return false
+	}
+	var value [32]byte
+	if p.Amount == nil || p.Amount.Sign() < 0 || p.Amount.BitLen() > 256 {
+		return false
+	}
+	p.Amount.FillBytes(value[:])
+	if p.Slot.Value != value {
+		return false
+	}
+	return p.Bridge.Verify(p.StateRoot, hash) && p.Slot.Verify(p.Bridge.StorageRoot, hash)
+}
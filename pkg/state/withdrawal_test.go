@@ -0,0 +1,39 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"zkrollup/pkg/types"
+)
+
+func TestWithdrawalProof(t *testing.T) {
+	s := NewState()
+	alice := types.Address{0xaa}
+
+	_, err := s.GetWithdrawalProof(alice, 0)
+	require.ErrorIs(t, err, ErrStateNotRetained)
+
+	s.SetAccount(&Account{Address: WithdrawalAddress, Balance: big.NewInt(30)})
+	s.RecordWithdrawal(alice, big.NewInt(10))
+	s.RecordWithdrawal(alice, big.NewInt(20))
+	s.RecordWithdrawal(types.Address{0xbb}, big.NewInt(5))
+	s.AddBatch(&Batch{})
+	require.Equal(t, int64(30), s.GetWithdrawn(alice).Int64())
+
+	// The slot matches the contract's keccak256(abi.encode(account))
+	require.Equal(t, [32]byte(crypto.Keccak256Hash(make([]byte, 12), alice[:])), WithdrawalSlot(alice))
+
+	proof, err := s.GetWithdrawalProof(alice, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(30), proof.Amount.Int64())
+	require.Equal(t, s.GetStateRoot(), proof.StateRoot)
+	require.True(t, proof.Verify(nil))
+
+	// Claiming more than was withdrawn breaks the proof
+	proof.Amount = big.NewInt(31)
+	require.False(t, proof.Verify(nil))
+}
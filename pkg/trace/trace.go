@@ -0,0 +1,53 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Field is the log field carrying a transaction's trace ID
+const Field = "trace_id"
+
+// BatchField is the log field listing the trace IDs of a batch's transactions
+const BatchField = "trace_ids"
+
+type idKey struct{}
+
+// NewID returns a random correlation ID
+func NewID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// WithID returns a copy of ctx carrying the trace ID
+func WithID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// ID returns the trace ID carried by ctx, or an empty string
+func ID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// Logger returns the global logger annotated with the trace ID carried by ctx
+func Logger(ctx context.Context) *zerolog.Logger {
+	logger := log.Logger
+	if id := ID(ctx); id != "" {
+		logger = logger.With().Str(Field, id).Logger()
+	}
+	return &logger
+}
@@ -0,0 +1,21 @@
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextCarriesID(t *testing.T) {
+	id := NewID()
+	require.Len(t, id, 16)
+	require.NotEqual(t, id, NewID())
+
+	ctx := WithID(context.Background(), id)
+	require.Equal(t, id, ID(ctx))
+
+	// An empty ID leaves the context untouched
+	require.Equal(t, id, ID(WithID(ctx, "")))
+	require.Empty(t, ID(context.Background()))
+}
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// AddressLength is the length of an address in bytes
+const AddressLength = 20
+
+// ErrInvalidAddress is returned when a string is not a valid hex address
+var ErrInvalidAddress = errors.New("invalid address")
+
+// Address is a 20 byte account or contract address. It encodes to JSON and text as an
+// EIP-55 checksummed hex string.
+type Address [AddressLength]byte
+
+// ParseAddress parses a 0x prefixed or bare hex address. Mixed-case input must carry a
+// valid EIP-55 checksum, all-lowercase and all-uppercase input is accepted as is.
+func ParseAddress(s string) (Address, error) {
+	var addr Address
+
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(digits) != 2*AddressLength {
+		return addr, fmt.Errorf("%w %q: want %d hex digits, got %d", ErrInvalidAddress, s, 2*AddressLength, len(digits))
+	}
+	if _, err := hex.Decode(addr[:], []byte(digits)); err != nil {
+		return addr, fmt.Errorf("%w %q: %v", ErrInvalidAddress, s, err)
+	}
+
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && addr.Hex()[2:] != digits {
+		return addr, fmt.Errorf("%w %q: bad checksum", ErrInvalidAddress, s)
+	}
+	return addr, nil
+}
+
+// MustParseAddress is like ParseAddress but panics on invalid input. It is meant for
+// constants and tests.
+func MustParseAddress(s string) Address {
+	addr, err := ParseAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
+// BytesToAddress returns the address held in the last 20 bytes of b, left padding
+// shorter input with zeros
+func BytesToAddress(b []byte) Address {
+	var addr Address
+	if len(b) > AddressLength {
+		b = b[len(b)-AddressLength:]
+	}
+	copy(addr[AddressLength-len(b):], b)
+	return addr
+}
+
+// FromCommon converts a go-ethereum address
+func FromCommon(addr common.Address) Address {
+	return Address(addr)
+}
+
+// Common converts the address to a go-ethereum address
+func (a Address) Common() common.Address {
+	return common.Address(a)
+}
+
+// Bytes returns a copy of the address bytes
+func (a Address) Bytes() []byte {
+	return append([]byte(nil), a[:]...)
+}
+
+// IsZero reports whether the address is all zeros
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// Hex returns the EIP-55 checksummed hex encoding of the address
+func (a Address) Hex() string {
+	lower := hex.EncodeToString(a[:])
+	hash := crypto.Keccak256([]byte(lower))
+
+	buf := []byte(lower)
+	for i, c := range buf {
+		if c < 'a' {
+			continue
+		}
+		// Uppercase a letter when the matching nibble of the hash is 8 or more
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if nibble&0x0f >= 8 {
+			buf[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(buf)
+}
+
+// String implements fmt.Stringer
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *Address) UnmarshalText(text []byte) error {
+	addr, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = addr
+	return nil
+}
+
+// UnmarshalJSON accepts a hex string, or the array of byte values addresses were
+// encoded as before they had a JSON form of their own
+func (a *Address) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var raw [AddressLength]byte
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+		}
+		*a = raw
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	return a.UnmarshalText([]byte(s))
+}
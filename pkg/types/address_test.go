@@ -0,0 +1,66 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressChecksum(t *testing.T) {
+	// Test vectors from EIP-55
+	for _, want := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		addr, err := ParseAddress(want)
+		require.NoError(t, err)
+		require.Equal(t, want, addr.Hex())
+
+		lower, err := ParseAddress(strings.ToLower(want))
+		require.NoError(t, err)
+		require.Equal(t, addr, lower)
+
+		_, err = ParseAddress("0x" + strings.ToUpper(want[2:]))
+		require.NoError(t, err)
+	}
+
+	_, err := ParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+	_, err = ParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+	_, err = ParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beazz")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+	_, err = ParseAddress("0x")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestAddressJSON(t *testing.T) {
+	addr := MustParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+
+	encoded, err := json.Marshal(map[Address]Address{addr: addr})
+	require.NoError(t, err)
+	require.Equal(t, `{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}`, string(encoded))
+
+	var decoded map[Address]Address
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Equal(t, addr, decoded[addr])
+
+	// Addresses stored as byte arrays still decode
+	legacy, err := json.Marshal([AddressLength]byte(addr))
+	require.NoError(t, err)
+	var fromLegacy Address
+	require.NoError(t, json.Unmarshal(legacy, &fromLegacy))
+	require.Equal(t, addr, fromLegacy)
+
+	require.Error(t, json.Unmarshal([]byte(`"0x1234"`), &fromLegacy))
+}
+
+func TestBytesToAddress(t *testing.T) {
+	require.Equal(t, Address{19: 0x01}, BytesToAddress([]byte{0x01}))
+	require.Equal(t, Address{0: 0x02}, BytesToAddress(append([]byte{0xff, 0x02}, make([]byte, 19)...)))
+	require.True(t, Address{}.IsZero())
+}
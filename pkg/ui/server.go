@@ -0,0 +1,202 @@
+package ui
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"zkrollup/pkg/sequencer"
+)
+
+// maxBatches caps how many batches a single request lists
+const maxBatches = 100
+
+//go:embed index.html
+var indexHTML []byte
+
+// Server serves a read-only dashboard of the node's batches, pool, peers, consensus and
+// L1 submission status
+type Server struct {
+	sequencer *sequencer.Sequencer
+	port      int
+	server    *http.Server
+	mu        sync.Mutex
+}
+
+// statusView is the JSON form of sequencer.NodeStatus
+type statusView struct {
+	NodeID           string   `json:"nodeId"`
+	BatchNumber      uint64   `json:"batchNumber"`
+	StateRoot        string   `json:"stateRoot"`
+	Syncing          bool     `json:"syncing"`
+	HighestBatch     uint64   `json:"highestBatch"`
+	Halted           bool     `json:"halted"`
+	PoolSize         int      `json:"poolSize"`
+	PoolQueued       int      `json:"poolQueued"`
+	ProverQueueDepth int      `json:"proverQueueDepth"`
+	Peers            []string `json:"peers"`
+	Consensus        struct {
+		Leader           bool    `json:"leader"`
+		Validators       int     `json:"validators"`
+		HighestSeenBatch uint64  `json:"highestSeenBatch"`
+		PendingRoundSecs float64 `json:"pendingRoundSecs"`
+	} `json:"consensus"`
+	L1 struct {
+		Enabled      bool   `json:"enabled"`
+		Backlog      int    `json:"backlog"`
+		Paused       bool   `json:"paused"`
+		BridgeFrozen bool   `json:"bridgeFrozen"`
+		LastBatch    uint64 `json:"lastBatch"`
+		LastKind     string `json:"lastKind,omitempty"`
+		LastAt       int64  `json:"lastAt,omitempty"` // Unix seconds
+		LastError    string `json:"lastError,omitempty"`
+		Submitted    uint64 `json:"submitted"` // Highest batch accepted by the contract plus one
+		Finalized    uint64 `json:"finalized"` // Batches final on L1 from the first
+	} `json:"l1"`
+}
+
+// batchView summarizes a finalized batch
+type batchView struct {
+	BatchNumber uint64 `json:"batchNumber"`
+	Timestamp   uint64 `json:"timestamp"`
+	TxCount     int    `json:"txCount"`
+	StateRoot   string `json:"stateRoot"`
+	Proven      bool   `json:"proven"`
+}
+
+// NewServer creates a dashboard server
+func NewServer(seq *sequencer.Sequencer, port int) *Server {
+	return &Server{
+		sequencer: seq,
+		port:      port,
+	}
+}
+
+// Start starts serving the dashboard
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/batches", s.handleBatches)
+
+	s.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Info().Int("port", s.port).Msg("Starting explorer")
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Explorer server error")
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the dashboard server
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		log.Info().Msg("Stopping explorer")
+		return s.server.Close()
+	}
+	return nil
+}
+
+// handleIndex serves the dashboard page
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+// handleStatus reports the node status
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := s.sequencer.Status()
+
+	view := statusView{
+		NodeID:           status.NodeID,
+		BatchNumber:      status.BatchNumber,
+		StateRoot:        fmt.Sprintf("0x%x", status.StateRoot),
+		Syncing:          status.Sync.Syncing,
+		HighestBatch:     status.Sync.HighestBatch,
+		Halted:           status.Halted,
+		PoolSize:         status.PoolSize,
+		PoolQueued:       status.PoolQueued,
+		ProverQueueDepth: status.ProverQueueDepth,
+		Peers:            status.Peers,
+	}
+	if view.Peers == nil {
+		view.Peers = []string{}
+	}
+	view.Consensus.Leader = status.Consensus.Leader
+	view.Consensus.Validators = status.Consensus.Validators
+	view.Consensus.HighestSeenBatch = status.Consensus.HighestSeenBatch
+	view.Consensus.PendingRoundSecs = status.Consensus.PendingRound.Seconds()
+	view.L1.Enabled = status.L1.Enabled
+	view.L1.Backlog = status.L1.Backlog
+	view.L1.Paused = status.L1.Paused
+	view.L1.BridgeFrozen = status.L1.BridgeFrozen
+	view.L1.LastBatch = status.L1.LastBatch
+	view.L1.LastKind = status.L1.LastKind
+	if !status.L1.LastAt.IsZero() {
+		view.L1.LastAt = status.L1.LastAt.Unix()
+	}
+	view.L1.LastError = status.L1.LastError
+	view.L1.Submitted = status.L1.SubmittedBatches
+	view.L1.Finalized = status.L1.FinalizedBatches
+
+	writeJSON(w, view)
+}
+
+// handleBatches lists the most recent batches, newest first. The optional limit query
+// parameter defaults to 20.
+func (s *Server) handleBatches(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxBatches)
+	}
+
+	batches := s.sequencer.RecentBatches(limit)
+	views := make([]batchView, 0, len(batches))
+	for _, b := range batches {
+		views = append(views, batchView{
+			BatchNumber: b.BatchNumber,
+			Timestamp:   b.Timestamp,
+			TxCount:     len(b.Transactions),
+			StateRoot:   fmt.Sprintf("0x%x", b.StateRoot),
+			Proven:      len(b.Proof) > 0,
+		})
+	}
+
+	writeJSON(w, views)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to encode explorer response")
+	}
+}
@@ -0,0 +1,413 @@
+package verifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"text/template"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/solidity"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrBatchInvalid is returned by VerifyProofs when the batched pairing check fails, at
+// least one of the proofs is invalid
+var ErrBatchInvalid = errors.New("batched proof verification failed")
+
+// ExportSolidity writes the Solidity verifier of vk as gnark exports it, with verifyProofs
+// added. verifyProofs checks several proofs in one call: each is weighted by a random
+// scalar derived from all of them and the weighted proofs are checked with one pairing
+// product, n proofs taking n + 3 pairings instead of 4n. Keys with commitments are not
+// supported.
+func ExportSolidity(vk groth16.VerifyingKey, w io.Writer, opts ...solidity.ExportOption) error {
+	key, ok := vk.(*groth16bn254.VerifyingKey)
+	if !ok {
+		return fmt.Errorf("batched verification needs a BN254 verifying key, got %s", vk.CurveID())
+	}
+	if len(key.PublicAndCommitmentCommitted) > 0 {
+		return fmt.Errorf("batched verification does not support verifying keys with commitments")
+	}
+
+	var src bytes.Buffer
+	if err := key.ExportSolidity(&src, opts...); err != nil {
+		return fmt.Errorf("failed to export verifier: %v", err)
+	}
+	out, err := injectBatchVerifier(src.Bytes(), len(key.G1.K)-1)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// injectBatchVerifier adds verifyProofs for nbPublic public inputs before the closing
+// brace of the verifier contract src
+func injectBatchVerifier(src []byte, nbPublic int) ([]byte, error) {
+	end := bytes.LastIndexByte(src, '}')
+	if end < 0 {
+		return nil, fmt.Errorf("verifier source has no contract body")
+	}
+
+	var batch bytes.Buffer
+	if err := batchVerifierTemplate.Execute(&batch, nbPublic); err != nil {
+		return nil, fmt.Errorf("failed to generate batched verifier: %v", err)
+	}
+
+	out := make([]byte, 0, len(src)+batch.Len())
+	out = append(out, bytes.TrimRight(src[:end], "\n")...)
+	out = append(out, '\n')
+	out = append(out, batch.Bytes()...)
+	out = append(out, src[end:]...)
+	return out, nil
+}
+
+// BatchWeights returns the scalars verifyProofs weights each proof with: one for the first
+// and keccak256(seed, i) mod R for the others, where the seed is the keccak256 hash of the
+// ABI encoded proofs and inputs. Changing any proof or input changes every weight.
+func BatchWeights(proofs [][8]*big.Int, inputs [][]*big.Int) []*big.Int {
+	// abi.encode(uint256[8][] proofs, uint256[N][] input): two offsets, then each array
+	// as its length followed by its flattened elements
+	nbPublic := 0
+	if len(inputs) > 0 {
+		nbPublic = len(inputs[0])
+	}
+	word := func(v *big.Int) []byte { return math.U256Bytes(new(big.Int).Set(v)) }
+	n := big.NewInt(int64(len(proofs)))
+
+	var enc []byte
+	enc = append(enc, word(big.NewInt(0x40))...)
+	enc = append(enc, word(big.NewInt(int64(0x60+len(proofs)*8*32)))...)
+	enc = append(enc, word(n)...)
+	for _, proof := range proofs {
+		for _, v := range proof {
+			enc = append(enc, word(v)...)
+		}
+	}
+	enc = append(enc, word(big.NewInt(int64(len(inputs))))...)
+	for _, input := range inputs {
+		for j := 0; j < nbPublic; j++ {
+			enc = append(enc, word(input[j])...)
+		}
+	}
+	seed := crypto.Keccak256(enc)
+
+	weights := make([]*big.Int, len(proofs))
+	for i := range weights {
+		if i == 0 {
+			weights[i] = big.NewInt(1)
+			continue
+		}
+		r := new(big.Int).SetBytes(crypto.Keccak256(seed, word(big.NewInt(int64(i)))))
+		weights[i] = r.Mod(r, fr.Modulus())
+	}
+	return weights
+}
+
+// ProofCalldata returns a proof as the uint256[8] verifyProof and verifyProofs take: the
+// points (A, B, C) in EIP-197 format
+func ProofCalldata(proof *groth16bn254.Proof) [8]*big.Int {
+	raw := proof.MarshalSolidity()
+	var out [8]*big.Int
+	for i := range out {
+		out[i] = new(big.Int).SetBytes(raw[i*fr.Bytes : (i+1)*fr.Bytes])
+	}
+	return out
+}
+
+// VerifyProofs checks proofs against vk off-chain with the batched pairing check of the
+// contract's verifyProofs, so a batch can be checked before paying for the call. Proofs
+// are in verifyProof's EIP-197 layout and inputs[i] are the public inputs of proofs[i].
+func VerifyProofs(vk *groth16bn254.VerifyingKey, proofs [][8]*big.Int, inputs [][]*big.Int) error {
+	if len(proofs) == 0 || len(inputs) != len(proofs) {
+		return fmt.Errorf("got %d proofs and %d inputs", len(proofs), len(inputs))
+	}
+	if len(vk.PublicAndCommitmentCommitted) > 0 {
+		return fmt.Errorf("batched verification does not support verifying keys with commitments")
+	}
+	nbPublic := len(vk.G1.K) - 1
+	for i, input := range inputs {
+		if len(input) != nbPublic {
+			return fmt.Errorf("proof %d has %d public inputs, the key takes %d", i, len(input), nbPublic)
+		}
+		for j, v := range input {
+			if v.Sign() < 0 || v.Cmp(fr.Modulus()) >= 0 {
+				return fmt.Errorf("public input %d of proof %d is not in the scalar field", j, i)
+			}
+		}
+	}
+
+	weights := BatchWeights(proofs, inputs)
+
+	// e(r₀⋅A₀, B₀)⋯e(rₙ⋅Aₙ, Bₙ) ⋅ e(Σ rᵢ⋅Cᵢ, -δ) ⋅ e((Σ rᵢ)⋅α, -β) ⋅ e(Σ rᵢ⋅Lᵢ, -γ) = 1
+	g1 := make([]bn254.G1Affine, 0, len(proofs)+3)
+	g2 := make([]bn254.G2Affine, 0, len(proofs)+3)
+	var c bn254.G1Jac
+	weightSum := new(big.Int)
+	scalars := make([]*big.Int, nbPublic)
+	for j := range scalars {
+		scalars[j] = new(big.Int)
+	}
+	for i, proof := range proofs {
+		a, b, cPoint, err := parseProof(proof)
+		if err != nil {
+			return fmt.Errorf("proof %d: %v", i, err)
+		}
+		r := weights[i]
+
+		var ra bn254.G1Affine
+		ra.ScalarMultiplication(&a, r)
+		g1 = append(g1, ra)
+		g2 = append(g2, b)
+
+		var rc bn254.G1Jac
+		rc.FromAffine(&cPoint)
+		rc.ScalarMultiplication(&rc, r)
+		c.AddAssign(&rc)
+
+		weightSum.Add(weightSum, r)
+		for j, v := range inputs[i] {
+			scalars[j].Add(scalars[j], new(big.Int).Mul(r, v))
+		}
+	}
+
+	weightSum.Mod(weightSum, fr.Modulus())
+	var l, term bn254.G1Jac
+	l.FromAffine(&vk.G1.K[0])
+	l.ScalarMultiplication(&l, weightSum)
+	for j, s := range scalars {
+		term.FromAffine(&vk.G1.K[j+1])
+		term.ScalarMultiplication(&term, s.Mod(s, fr.Modulus()))
+		l.AddAssign(&term)
+	}
+
+	var alpha bn254.G1Affine
+	alpha.ScalarMultiplication(&vk.G1.Alpha, weightSum)
+
+	var cAff, lAff bn254.G1Affine
+	cAff.FromJacobian(&c)
+	lAff.FromJacobian(&l)
+
+	var deltaNeg, betaNeg, gammaNeg bn254.G2Affine
+	deltaNeg.Neg(&vk.G2.Delta)
+	betaNeg.Neg(&vk.G2.Beta)
+	gammaNeg.Neg(&vk.G2.Gamma)
+
+	g1 = append(g1, cAff, alpha, lAff)
+	g2 = append(g2, deltaNeg, betaNeg, gammaNeg)
+
+	ok, err := bn254.PairingCheck(g1, g2)
+	if err != nil {
+		return fmt.Errorf("failed to check pairing: %v", err)
+	}
+	if !ok {
+		return ErrBatchInvalid
+	}
+	return nil
+}
+
+// parseProof decodes the points (A, B, C) of a proof in EIP-197 layout, with the Fp2
+// coefficients of B in (a₁, a₀) order, rejecting unreduced coordinates and points off
+// their curve or subgroup as the precompiles do
+func parseProof(proof [8]*big.Int) (a bn254.G1Affine, b bn254.G2Affine, c bn254.G1Affine, err error) {
+	coords := make([]fp.Element, 8)
+	for i, v := range proof {
+		if v == nil || v.Sign() < 0 || v.Cmp(fp.Modulus()) >= 0 {
+			return a, b, c, fmt.Errorf("coordinate %d is not in the base field", i)
+		}
+		coords[i].SetBigInt(v)
+	}
+
+	a.X, a.Y = coords[0], coords[1]
+	b.X.A1, b.X.A0, b.Y.A1, b.Y.A0 = coords[2], coords[3], coords[4], coords[5]
+	c.X, c.Y = coords[6], coords[7]
+
+	if !a.IsOnCurve() || !c.IsOnCurve() {
+		return a, b, c, fmt.Errorf("G1 point not on curve")
+	}
+	if !b.IsOnCurve() || !b.IsInSubGroup() {
+		return a, b, c, fmt.Errorf("G2 point not in subgroup")
+	}
+	return a, b, c, nil
+}
+
+// batchVerifierTemplate is the Solidity added to gnark's verifier, executed with the
+// number of public inputs. It uses the constants and errors of the exported contract.
+var batchVerifierTemplate = template.Must(template.New("batch").Funcs(template.FuncMap{
+	"seq": func(n int) []int {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = i
+		}
+		return s
+	},
+}).Parse(`
+    /// Multiply a G1 point by a scalar.
+    /// @notice Reverts with ProofInvalid if the point is not on the curve
+    /// or its coordinates are not reduced.
+    /// @param x The X coordinate in Fp.
+    /// @param y The Y coordinate in Fp.
+    /// @param s The scalar.
+    /// @return rx The X coordinate of the product.
+    /// @return ry The Y coordinate of the product.
+    function g1Mul(uint256 x, uint256 y, uint256 s) internal view returns (uint256 rx, uint256 ry) {
+        bool success;
+        assembly ("memory-safe") {
+            let f := mload(0x40)
+            mstore(f, x)
+            mstore(add(f, 0x20), y)
+            mstore(add(f, 0x40), s)
+            success := staticcall(gas(), PRECOMPILE_MUL, f, 0x60, f, 0x40)
+            rx := mload(f)
+            ry := mload(add(f, 0x20))
+        }
+        if (!success) {
+            revert ProofInvalid();
+        }
+    }
+
+    /// Add two G1 points.
+    /// @notice Reverts with ProofInvalid if a point is not on the curve.
+    /// @return rx The X coordinate of the sum.
+    /// @return ry The Y coordinate of the sum.
+    function g1Add(uint256 x1, uint256 y1, uint256 x2, uint256 y2) internal view returns (uint256 rx, uint256 ry) {
+        bool success;
+        assembly ("memory-safe") {
+            let f := mload(0x40)
+            mstore(f, x1)
+            mstore(add(f, 0x20), y1)
+            mstore(add(f, 0x40), x2)
+            mstore(add(f, 0x60), y2)
+            success := staticcall(gas(), PRECOMPILE_ADD, f, 0x80, f, 0x40)
+            rx := mload(f)
+            ry := mload(add(f, 0x20))
+        }
+        if (!success) {
+            revert ProofInvalid();
+        }
+    }
+
+    /// Compute the weighted sum of the public input linear combinations of a batch.
+    /// @notice The constant term is weighted by the sum of the proof weights, the
+    /// public input points by the weighted sums of their inputs.
+    /// @param weightSum The sum of the proof weights in Fr.
+    /// @param scalars The weighted sums of each public input in Fr.
+    /// @return x The X coordinate of the resulting G1 point.
+    /// @return y The Y coordinate of the resulting G1 point.
+    function batchInputMSM(uint256 weightSum, uint256[{{.}}] memory scalars)
+    internal view returns (uint256 x, uint256 y) {
+        uint256 px;
+        uint256 py;
+        (x, y) = g1Mul(CONSTANT_X, CONSTANT_Y, weightSum);
+{{- range $i := seq .}}
+        (px, py) = g1Mul(PUB_{{$i}}_X, PUB_{{$i}}_Y, scalars[{{$i}}]);
+        (x, y) = g1Add(x, y, px, py);
+{{- end}}
+    }
+
+    /// Add the weighted public inputs of a proof to the batch sums.
+    /// @notice Reverts with PublicInputNotInField if an input is not reduced.
+    /// @param input The public inputs of the proof.
+    /// @param r The weight of the proof.
+    /// @param scalars The weighted sums of each public input.
+    function batchInputs(uint256[{{.}}] calldata input, uint256 r, uint256[{{.}}] memory scalars) internal pure {
+        for (uint256 j = 0; j < {{.}}; j++) {
+            uint256 s = input[j];
+            if (s >= R) {
+                revert PublicInputNotInField();
+            }
+            scalars[j] = addmod(scalars[j], mulmod(r, s, R), R);
+        }
+    }
+
+    /// Write the pairing e(r⋅A, B) of a proof and add r⋅C to the batch sum.
+    /// @param proof The points (A, B, C) in EIP-197 format.
+    /// @param r The weight of the proof.
+    /// @param pairings The pairing input of the batch.
+    /// @param o The offset of the proof's pairing in pairings.
+    /// @param c The weighted sum of the C points.
+    function batchProof(uint256[8] calldata proof, uint256 r, uint256[] memory pairings, uint256 o, uint256[2] memory c)
+    internal view {
+        (pairings[o], pairings[o + 1]) = g1Mul(proof[0], proof[1], r);
+        pairings[o + 2] = proof[2];
+        pairings[o + 3] = proof[3];
+        pairings[o + 4] = proof[4];
+        pairings[o + 5] = proof[5];
+        (uint256 x, uint256 y) = g1Mul(proof[6], proof[7], r);
+        (c[0], c[1]) = g1Add(c[0], c[1], x, y);
+    }
+
+    /// Verify several uncompressed Groth16 proofs in one call.
+    /// @notice Reverts with InvalidProof if any proof is invalid or
+    /// with PublicInputNotInField if a public input is not reduced.
+    /// @notice There is no return value. If the function does not revert, every
+    /// proof was successfully verified.
+    /// @notice Each proof is weighted by a scalar derived from the hash of all
+    /// proofs and inputs, the first by one, and the weighted proofs are checked
+    /// with a single pairing product: n proofs take n + 3 pairings instead of 4n.
+    /// @param proofs the points (A, B, C) of each proof in EIP-197 format.
+    /// @param input the public inputs of each proof, in the order of proofs.
+    /// Elements must be reduced.
+    function verifyProofs(
+        uint256[8][] calldata proofs,
+        uint256[{{.}}][] calldata input
+    ) public view {
+        uint256 n = proofs.length;
+        if (n == 0 || input.length != n) {
+            revert ProofInvalid();
+        }
+
+        uint256 seed = uint256(keccak256(abi.encode(proofs, input)));
+        uint256[] memory pairings = new uint256[](6 * (n + 3));
+        uint256[{{.}}] memory scalars;
+        uint256[2] memory c;
+        uint256 weightSum;
+        for (uint256 i = 0; i < n; i++) {
+            uint256 r = i == 0 ? 1 : uint256(keccak256(abi.encode(seed, i))) % R;
+            weightSum = addmod(weightSum, r, R);
+            batchInputs(input[i], r, scalars);
+            batchProof(proofs[i], r, pairings, 6 * i, c);
+        }
+
+        // Note: The precompile expects the F2 coefficients in big-endian order.
+        uint256 o = 6 * n;
+        // e(Σ rᵢ⋅Cᵢ, -δ)
+        pairings[o] = c[0];
+        pairings[o + 1] = c[1];
+        pairings[o + 2] = DELTA_NEG_X_1;
+        pairings[o + 3] = DELTA_NEG_X_0;
+        pairings[o + 4] = DELTA_NEG_Y_1;
+        pairings[o + 5] = DELTA_NEG_Y_0;
+        // e((Σ rᵢ)⋅α, -β)
+        (pairings[o + 6], pairings[o + 7]) = g1Mul(ALPHA_X, ALPHA_Y, weightSum);
+        pairings[o + 8] = BETA_NEG_X_1;
+        pairings[o + 9] = BETA_NEG_X_0;
+        pairings[o + 10] = BETA_NEG_Y_1;
+        pairings[o + 11] = BETA_NEG_Y_0;
+        // e(Σ rᵢ⋅L_pubᵢ, -γ)
+        (pairings[o + 12], pairings[o + 13]) = batchInputMSM(weightSum, scalars);
+        pairings[o + 14] = GAMMA_NEG_X_1;
+        pairings[o + 15] = GAMMA_NEG_X_0;
+        pairings[o + 16] = GAMMA_NEG_Y_1;
+        pairings[o + 17] = GAMMA_NEG_Y_0;
+
+        // Check pairing equation.
+        bool success;
+        assembly ("memory-safe") {
+            success := staticcall(gas(), PRECOMPILE_VERIFY, add(pairings, 0x20), mul(mload(pairings), 0x20), 0x00, 0x20)
+            success := and(success, mload(0x00))
+        }
+        if (!success) {
+            // Either a proof or the verification key is invalid.
+            // We assume the contract is correctly generated, so the verification key is valid.
+            revert ProofInvalid();
+        }
+    }
+`))
@@ -0,0 +1,90 @@
+package verifier
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// squareCircuit proves knowledge of the square root of Y, bound to a public tag
+type squareCircuit struct {
+	X   frontend.Variable
+	Y   frontend.Variable `gnark:",public"`
+	Tag frontend.Variable `gnark:",public"`
+}
+
+func (c *squareCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	api.AssertIsDifferent(c.Tag, 0)
+	return nil
+}
+
+func TestVerifyProofs(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := groth16.Setup(ccs)
+	require.NoError(t, err)
+
+	var proofs [][8]*big.Int
+	var inputs [][]*big.Int
+	for i := int64(1); i <= 3; i++ {
+		assignment := &squareCircuit{X: i + 1, Y: (i + 1) * (i + 1), Tag: i}
+		w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+		require.NoError(t, err)
+		proof, err := groth16.Prove(ccs, pk, w)
+		require.NoError(t, err)
+
+		proofs = append(proofs, ProofCalldata(proof.(*groth16bn254.Proof)))
+		inputs = append(inputs, []*big.Int{big.NewInt((i + 1) * (i + 1)), big.NewInt(i)})
+	}
+
+	key := vk.(*groth16bn254.VerifyingKey)
+	require.NoError(t, VerifyProofs(key, proofs, inputs))
+	require.NoError(t, VerifyProofs(key, proofs[1:2], inputs[1:2]))
+
+	// Swapping the inputs of two proofs breaks the batch
+	swapped := [][]*big.Int{inputs[1], inputs[0], inputs[2]}
+	require.ErrorIs(t, VerifyProofs(key, proofs, swapped), ErrBatchInvalid)
+
+	// As does a single wrong input among valid proofs
+	wrong := [][]*big.Int{inputs[0], inputs[1], {big.NewInt(16), big.NewInt(4)}}
+	require.ErrorIs(t, VerifyProofs(key, proofs, wrong), ErrBatchInvalid)
+
+	// The weights depend on every proof
+	require.NotEqual(t, BatchWeights(proofs, inputs)[1], BatchWeights(proofs, swapped)[1])
+
+	// and are seeded with abi.encode(proofs, input) as the contract does
+	proofsType, err := abi.NewType("uint256[8][]", "", nil)
+	require.NoError(t, err)
+	inputsType, err := abi.NewType("uint256[2][]", "", nil)
+	require.NoError(t, err)
+	fixed := make([][2]*big.Int, len(inputs))
+	for i := range inputs {
+		fixed[i] = [2]*big.Int{inputs[i][0], inputs[i][1]}
+	}
+	encoded, err := abi.Arguments{{Type: proofsType}, {Type: inputsType}}.Pack(proofs, fixed)
+	require.NoError(t, err)
+	seed := crypto.Keccak256(encoded)
+	second := new(big.Int).SetBytes(crypto.Keccak256(seed, math.U256Bytes(big.NewInt(1))))
+	require.Equal(t, second.Mod(second, fr.Modulus()), BatchWeights(proofs, inputs)[1])
+
+	require.Error(t, VerifyProofs(key, proofs, inputs[:2]))
+
+	// The exported verifier carries verifyProofs for the key's public inputs
+	var src strings.Builder
+	require.NoError(t, ExportSolidity(vk, &src))
+	require.Contains(t, src.String(), "function verifyProof(")
+	require.Contains(t, src.String(), "uint256[2][] calldata input")
+	require.True(t, strings.HasSuffix(strings.TrimSpace(src.String()), "}"))
+}
@@ -31,8 +31,8 @@ var (
 
 // VerifierMetaData contains all meta data concerning the Verifier contract.
 var VerifierMetaData = &bind.MetaData{
-	ABI: "[{\"inputs\":[],\"name\":\"ProofInvalid\",\"type\":\"error\"},{\"inputs\":[],\"name\":\"PublicInputNotInField\",\"type\":\"error\"},{\"inputs\":[{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"}],\"name\":\"compressProof\",\"outputs\":[{\"internalType\":\"uint256[4]\",\"name\":\"compressed\",\"type\":\"uint256[4]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256[4]\",\"name\":\"compressedProof\",\"type\":\"uint256[4]\"},{\"internalType\":\"uint256[6]\",\"name\":\"input\",\"type\":\"uint256[6]\"}],\"name\":\"verifyCompressedProof\",\"outputs\":[],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"},{\"internalType\":\"uint256[6]\",\"name\":\"input\",\"type\":\"uint256[6]\"}],\"name\":\"verifyProof\",\"outputs\":[],\"stateMutability\":\"view\",\"type\":\"function\"}]",
-	Bin: "0x608060405234801561001057600080fd5b5061244a806100206000396000f3fe608060405234801561001057600080fd5b50600436106100415760003560e01c806344f63692146100465780639cb2865814610076578063f716918914610092575b600080fd5b610060600480360381019061005b919061218e565b6100ae565b60405161006d9190612271565b60405180910390f35b610090600480360381019061008b91906122ae565b61020f565b005b6100ac60048036038101906100a79190612312565b6104ac565b005b6100b66120fb565b6100f0826000600881106100cd576100cc612353565b5b6020020135836001600881106100e6576100e5612353565b5b6020020135610a71565b8160006004811061010457610103612353565b5b6020020181815250506101798260036008811061012457610123612353565b5b60200201358360026008811061013d5761013c612353565b5b60200201358460056008811061015657610155612353565b5b60200201358560046008811061016f5761016e612353565b5b6020020135610c25565b8260026004811061018d5761018c612353565b5b60200201836001600481106101a5576101a4612353565b5b60200201828152508281525050506101ed826006600881106101ca576101c9612353565b5b6020020135836007600881106101e3576101e2612353565b5b6020020135610a71565b8160036004811061020157610200612353565b5b602002018181525050919050565b60008061021b836111fc565b9150915060006040516101008682377f2c0fdecd882466cdafaeb7781bda3b99349b600ee4f735935d331c29519cff296101008201527f1c41f205d474a6ce66f6a7fcbc5aab6ab5d09e208d243e970ceb4197f42b8d0c6101208201527f1d462b4ea92b2e188826b822ec55c117d42aa316f9e946ed528d7498e48393db6101408201527f0e6594af1adb0315a5c8434e611445059beca3c7a9c3cb984585ae55ecd7c0616101608201527f0ed787326eff44b81836cff1abf9c7b7b1289a7cfc55cacea7c44db860748fc46101808201527f101b1e52d0a903b80c63769e44e766b6204a3cebc9009c269dfb9f278551fb386101a08201527f0636886069d0f92b944ecdb3faba35fb71f3dfa70459ca6106a070fcb78937d56101c08201527f2f12fb45b7635d8fb4f8a62dec7d29d3a90b77e6b67b11dc7559e70f0a526ff66101e08201527f2be257f691fda536174f9dd1b409a821fa2f76c21980ad9f02bb85955ef467e56102008201527f20c7c97931cf1e7cba344adba11b02356a3e015bc40455838187135f99b533a561022082015283610240820152826102608201527f253b146c3e383686d40f8ad599f2c8325671c19fb15ab91506fb09ce5258a4de6102808201527f04c2a98631f518ba36e49a6c8d8e8d4298e0c0ef2244a98606c9441e736f978f6102a08201527f126e37f083bdee69b72546d7d564b302899418765d5861973d42b162920181356102c08201527f0abd39eaeb0f622605274a4e1a0de5d64ae7f81dbef7bd27ce86b3a7cd771ba86102e08201526020816103008360085afa915080518216915050806104a5576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b5050505050565b6104b461211d565b6000806104d8856000600481106104ce576104cd612353565b5b6020020135611635565b9150915060008060008061051c896002600481106104f9576104f8612353565b5b60200201358a60016004811061051257610511612353565b5b602002013561176e565b93509350935093506000806105488b60036004811061053e5761053d612353565b5b6020020135611635565b915091506000806105588c6111fc565b91509150898b60006018811061057157610570612353565b5b602002018181525050888b60016018811061058f5761058e612353565b5b602002018181525050868b6002601881106105ad576105ac612353565b5b602002018181525050878b6003601881106105cb576105ca612353565b5b602002018181525050848b6004601881106105e9576105e8612353565b5b602002018181525050858b60056018811061060757610606612353565b5b602002018181525050838b60066018811061062557610624612353565b5b602002018181525050828b60076018811061064357610642612353565b5b6020020181815250507f2c0fdecd882466cdafaeb7781bda3b99349b600ee4f735935d331c29519cff298b60086018811061068157610680612353565b5b6020020181815250507f1c41f205d474a6ce66f6a7fcbc5aab6ab5d09e208d243e970ceb4197f42b8d0c8b6009601881106106bf576106be612353565b5b6020020181815250507f1d462b4ea92b2e188826b822ec55c117d42aa316f9e946ed528d7498e48393db8b600a601881106106fd576106fc612353565b5b6020020181815250507f0e6594af1adb0315a5c8434e611445059beca3c7a9c3cb984585ae55ecd7c0618b600b6018811061073b5761073a612353565b5b6020020181815250507f0ed787326eff44b81836cff1abf9c7b7b1289a7cfc55cacea7c44db860748fc48b600c6018811061077957610778612353565b5b6020020181815250507f101b1e52d0a903b80c63769e44e766b6204a3cebc9009c269dfb9f278551fb388b600d601881106107b7576107b6612353565b5b6020020181815250507f0636886069d0f92b944ecdb3faba35fb71f3dfa70459ca6106a070fcb78937d58b600e601881106107f5576107f4612353565b5b6020020181815250507f2f12fb45b7635d8fb4f8a62dec7d29d3a90b77e6b67b11dc7559e70f0a526ff68b600f6018811061083357610832612353565b5b6020020181815250507f2be257f691fda536174f9dd1b409a821fa2f76c21980ad9f02bb85955ef467e58b60106018811061087157610870612353565b5b6020020181815250507f20c7c97931cf1e7cba344adba11b02356a3e015bc40455838187135f99b533a58b6011601881106108af576108ae612353565b5b602002018181525050818b6012601881106108cd576108cc612353565b5b602002018181525050808b6013601881106108eb576108ea612353565b5b6020020181815250507f253b146c3e383686d40f8ad599f2c8325671c19fb15ab91506fb09ce5258a4de8b60146018811061092957610928612353565b5b6020020181815250507f04c2a98631f518ba36e49a6c8d8e8d4298e0c0ef2244a98606c9441e736f978f8b60156018811061096757610966612353565b5b6020020181815250507f126e37f083bdee69b72546d7d564b302899418765d5861973d42b162920181358b6016601881106109a5576109a4612353565b5b6020020181815250507f0abd39eaeb0f622605274a4e1a0de5d64ae7f81dbef7bd27ce86b3a7cd771ba88b6017601881106109e3576109e2612353565b5b60200201818152505060006109f6612140565b6020816103008f60085afa9150811580610a295750600181600060018110610a2157610a20612353565b5b602002015114155b15610a60576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b505050505050505050505050505050565b60007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4783101580610ac257507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478210155b15610af9576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b600083148015610b095750600082145b15610b175760009050610c1f565b6000610bb67f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610b4b57610b4a612382565b5b60037f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610b7c57610b7b612382565b5b877f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610bac57610bab612382565b5b898a090908611b54565b9050808303610bcf576000600185901b17915050610c1f565b610bd881611bf1565b8303610bed5760018085901b17915050610c1f565b6040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b92915050565b6000807f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4786101580610c7757507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478510155b80610ca257507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478410155b80610ccd57507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478310155b15610d04576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b60008385878917171703610d1e57600080915091506111f3565b60008060007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610d5257610d51612382565b5b60037f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47610d7f91906123e0565b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610dae57610dad612382565b5b8a8c0909905060007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610de557610de4612382565b5b8a7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610e1557610e14612382565b5b8c8d0909905060007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610e4c57610e4b612382565b5b8a7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610e7c57610e7b612382565b5b8c8d090990507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610eb157610eb0612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610ee057610edf612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610f0f57610f0e612382565b5b8c860984087f2b149d40ceb8aaae81be18991be06ac3b5b4c5e559dbefa33267e6dc24a138e5089450610ff47f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610f6a57610f69612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610f9957610f98612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780610fc857610fc7612382565b5b8e870984087f2fcd3ac2a640a154eb23960892a85a68f031ca0c8344b23a577dcf1052b9e77508611bf1565b93505050506000806110987f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061102e5761102d612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061105d5761105c612382565b5b8586097f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061108f5761108e612382565b5b87880908611b54565b90506111257f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47806110cc576110cb612382565b5b7f183227397098d014dc2822db40c0ac2ecbc0b548b438e5469e10460b6c3e7ea47f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061111c5761111b612382565b5b84880809611c5d565b15915050611134838383611cc8565b8093508194505050828714801561114a57508186145b156111745760008161115d576000611160565b60025b60ff1660028b901b171794508793506111ef565b61117d83611bf1565b87148015611192575061118f82611bf1565b86145b156111bc576001816111a55760006111a8565b60025b60ff1660028b901b171794508793506111ee565b6040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b5b5050505b94509492505050565b6000806000600190506040516040810160007f19db6a7db91bc8c3957bf8f99032c36e105bad7182b617d7160181270f02f55783527f15c96066bd10fd7ebdbee509ae25861844b91d87b264e62f21b9d165cd7dadb560208401527f2f0a6f1d3e719314f7e569ab7b827611ae61ae403a3da3d6b17bef47b838b05c82527f198ccfafae4fe617302bd58bb2b75000ee5a27fb392eafce5176a56b11a24a516020830152863590508060408301527f30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f000000181108416935060408260608460075afa8416935060408360808560065afa841693507f21be3e7024a6cfb39c56a02f36b977017778fb2a26604dc07f1cfdcad2b1801d82527f305ffc7569ceb88e75f5b112f420d4ece2daaf572b251a402ec601c566d87b5e6020830152602087013590508060408301527f30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f000000181108416935060408260608460075afa8416935060408360808560065afa841693507f1472d5ac3cdc3631b240e72d81f762ba447fc5f966ed26511717415ae22218fe82527f2971c894a4866520835d3a7ed545730a97008cba23b42379e0f70a78d388dbc36020830152604087013590508060408301527f30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f000000181108416935060408260608460075afa8416935060408360808560065afa841693507f0cc3b84f6385431880bfef0642af4e6645d7db153a2cea2f4293319d17c1bbd782527f09373e42bfa273760a46b4150cb3dcc5107aa9f84bb1e6a7b5e8578ade796ccb6020830152606087013590508060408301527f30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f000000181108416935060408260608460075afa8416935060408360808560065afa841693507f13d407fec5eb550fce26919cd5c3058c1baaf8afdb39eb6fa7ab3eff9e6cfb3582527f073c8e948261f63675a3e90de6bf5ce87b5bd13538b5de2373e1eeb7c4150d586020830152608087013590508060408301527f30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f000000181108416935060408260608460075afa8416935060408360808560065afa841693507f0a56ee42ce8c246334950bd08bd1f5c175ca7dba5c6cfe0cdfe3a066062e0c3682527f2b5aa949daaec9ad5a9c04218d2f473e2eaf57dec3095fc5f49a4e4235777bce602083015260a087013590508060408301527f30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f000000181108416935060408260608460075afa8416935060408360808560065afa8416935082519550602083015194505050508061162f576040517fa54f8e2700000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b50915091565b6000806000830361164c5760008091509150611769565b60006001808516149050600184901c92507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4783106116b6576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b6117537f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47806116e8576116e7612382565b5b60037f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061171957611718612382565b5b867f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061174957611748612382565b5b8889090908611b54565b915080156117675761176482611bf1565b91505b505b915091565b6000806000806000861480156117845750600085145b1561179c576000806000809350935093509350611b4b565b6000600180881614905060006002808916149050600288901c95508694507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478610158061180957507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478510155b15611840576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b60007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061187157611870612382565b5b60037f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4761189e91906123e0565b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47806118cd576118cc612382565b5b888a0909905060007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061190457611903612382565b5b887f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061193457611933612382565b5b8a8b0909905060007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061196b5761196a612382565b5b887f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd478061199b5761199a612382565b5b8a8b090990507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47806119d0576119cf612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47806119ff576119fe612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611a2e57611a2d612382565b5b8a860984087f2b149d40ceb8aaae81be18991be06ac3b5b4c5e559dbefa33267e6dc24a138e5089650611b137f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611a8957611a88612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611ab857611ab7612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611ae757611ae6612382565b5b8c870984087f2fcd3ac2a640a154eb23960892a85a68f031ca0c8344b23a577dcf1052b9e77508611bf1565b9550611b20878786611cc8565b80975081985050508415611b4557611b3787611bf1565b9650611b4286611bf1565b95505b50505050505b92959194509250565b6000611b80827f0c19139cb84c680a6e14116da060561765e05aa45a1c72a34f082305b61f3f52611fc5565b9050817f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611bb257611bb1612382565b5b82830914611bec576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b919050565b60007f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47808381611c2457611c23612382565b5b067f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd470381611c5557611c54612382565b5b069050919050565b600080611c8a837f0c19139cb84c680a6e14116da060561765e05aa45a1c72a34f082305b61f3f52611fc5565b9050827f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611cbc57611cbb612382565b5b82830914915050919050565b6000806000611d697f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611cff57611cfe612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611d2e57611d2d612382565b5b8788097f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611d6057611d5f612382565b5b898a0908611b54565b90508315611d7d57611d7a81611bf1565b90505b611e087f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611daf57611dae612382565b5b7f183227397098d014dc2822db40c0ac2ecbc0b548b438e5469e10460b6c3e7ea47f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611dff57611dfe612382565b5b848a0809611b54565b92507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611e3957611e38612382565b5b611e747f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611e6b57611e6a612382565b5b6002860961205d565b860991507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611ea757611ea6612382565b5b611ee17f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611ed957611ed8612382565b5b848509611bf1565b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611f1057611f0f612382565b5b8586090886141580611f8557507f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611f4c57611f4b612382565b5b7f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4780611f7b57611f7a612382565b5b8385096002098514155b15611fbc576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b50935093915050565b60008060405160208152602080820152602060408201528460608201528360808201527f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4760a082015260208160c08360055afa9150805192505080612056576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b5092915050565b6000612089827f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd45611fc5565b905060017f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47806120bc576120bb612382565b5b828409146120f6576040517f7fcdd1f400000000000000000000000000000000000000000000000000000000815260040160405180910390fd5b919050565b6040518060800160405280600490602082028036833780820191505090505090565b604051806103000160405280601890602082028036833780820191505090505090565b6040518060200160405280600190602082028036833780820191505090505090565b600080fd5b600080fd5b60008190508260206008028201111561218857612187612167565b5b92915050565b600061010082840312156121a5576121a4612162565b5b60006121b38482850161216c565b91505092915050565b600060049050919050565b600081905092915050565b6000819050919050565b6000819050919050565b6121ef816121dc565b82525050565b600061220183836121e6565b60208301905092915050565b6000602082019050919050565b612223816121bc565b61222d81846121c7565b9250612238826121d2565b8060005b8381101561226957815161225087826121f5565b965061225b8361220d565b92505060018101905061223c565b505050505050565b6000608082019050612286600083018461221a565b92915050565b6000819050826020600602820111156122a8576122a7612167565b5b92915050565b6000806101c083850312156122c6576122c5612162565b5b60006122d48582860161216c565b9250506101006122e68582860161228c565b9150509250929050565b60008190508260206004028201111561230c5761230b612167565b5b92915050565b600080610140838503121561232a57612329612162565b5b6000612338858286016122f0565b92505060806123498582860161228c565b9150509250929050565b7f4e487b7100000000000000000000000000000000000000000000000000000000600052603260045260246000fd5b7f4e487b7100000000000000000000000000000000000000000000000000000000600052601260045260246000fd5b7f4e487b7100000000000000000000000000000000000000000000000000000000600052601160045260246000fd5b60006123eb826121dc565b91506123f6836121dc565b925082820390508181111561240e5761240d6123b1565b5b9291505056fea26469706673582212202fed2037a3fba955e8380715412c0543a664829ff4377f67cbb0b5ab1f9f791364736f6c63430008130033",
+	ABI: "[{\"inputs\":[],\"name\":\"ProofInvalid\",\"type\":\"error\"},{\"inputs\":[],\"name\":\"PublicInputNotInField\",\"type\":\"error\"},{\"inputs\":[{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"}],\"name\":\"compressProof\",\"outputs\":[{\"internalType\":\"uint256[4]\",\"name\":\"compressed\",\"type\":\"uint256[4]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256[4]\",\"name\":\"compressedProof\",\"type\":\"uint256[4]\"},{\"internalType\":\"uint256[6]\",\"name\":\"input\",\"type\":\"uint256[6]\"}],\"name\":\"verifyCompressedProof\",\"outputs\":[],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256[8]\",\"name\":\"proof\",\"type\":\"uint256[8]\"},{\"internalType\":\"uint256[6]\",\"name\":\"input\",\"type\":\"uint256[6]\"}],\"name\":\"verifyProof\",\"outputs\":[],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256[8][]\",\"name\":\"proofs\",\"type\":\"uint256[8][]\"},{\"internalType\":\"uint256[6][]\",\"name\":\"input\",\"type\":\"uint256[6][]\"}],\"name\":\"verifyProofs\",\"outputs\":[],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "0x6080604052348015600e575f5ffd5b506123088061001c5f395ff3fe608060405234801561000f575f5ffd5b506004361061004a575f3560e01c806344f636921461004e5780637de76681146100775780639cb286581461008c578063f71691891461009f575b5f5ffd5b61006161005c366004611fbc565b6100b2565b60405161006e9190611fde565b60405180910390f35b61008a610085366004612056565b61010f565b005b61008a61009a366004612101565b61079f565b61008a6100ad366004612136565b610a1d565b6100ba611f14565b6100cd82358360015b6020020135610d37565b81526100eb6060830135604084013560a08501356080860135610e24565b6020830152604082015261010560c08301358360076100c3565b6060820152919050565b8280158061011d5750818114155b1561013b57604051631ff3747d60e21b815260040160405180910390fd5b5f858585856040516020016101539493929190612183565b60408051601f19818403018152919052805160209091012090505f61017983600361220e565b610184906006612221565b67ffffffffffffffff81111561019c5761019c612238565b6040519080825280602002602001820160405280156101c5578160200160208202803683370190505b5090506101d0611f32565b6101d8611f50565b5f805b868110156102a3575f811561022f576040805160208082018a90528183018590528251808303840181526060909201909252805191012061022a905f5160206122b35f395f51905f5290612260565b610232565b60015b90505f5160206122b35f395f51905f5281840892506102698a8a8481811061025c5761025c61216f565b905060c0020182876110fd565b61029a8c8c8481811061027e5761027e61216f565b9050610100020182888560066102949190612221565b8861119f565b506001016101db565b505f6102b0876006612221565b9050825f60200201518582815181106102cb576102cb61216f565b60209081029190910101528260016020020151856102ea83600161220e565b815181106102fa576102fa61216f565b60209081029190910101527f2c0fdecd882466cdafaeb7781bda3b99349b600ee4f735935d331c29519cff298561033283600261220e565b815181106103425761034261216f565b60209081029190910101527f1c41f205d474a6ce66f6a7fcbc5aab6ab5d09e208d243e970ceb4197f42b8d0c8561037a83600361220e565b8151811061038a5761038a61216f565b60209081029190910101527f1d462b4ea92b2e188826b822ec55c117d42aa316f9e946ed528d7498e48393db856103c283600461220e565b815181106103d2576103d261216f565b60209081029190910101527f0e6594af1adb0315a5c8434e611445059beca3c7a9c3cb984585ae55ecd7c0618561040a83600561220e565b8151811061041a5761041a61216f565b6020026020010181815250506104717f0ed787326eff44b81836cff1abf9c7b7b1289a7cfc55cacea7c44db860748fc47f101b1e52d0a903b80c63769e44e766b6204a3cebc9009c269dfb9f278551fb38846112e2565b8661047d84600661220e565b8151811061048d5761048d61216f565b60200260200101878460076104a2919061220e565b815181106104b2576104b261216f565b6020908102919091010191909152527f0636886069d0f92b944ecdb3faba35fb71f3dfa70459ca6106a070fcb78937d5856104ee83600861220e565b815181106104fe576104fe61216f565b60209081029190910101527f2f12fb45b7635d8fb4f8a62dec7d29d3a90b77e6b67b11dc7559e70f0a526ff68561053683600961220e565b815181106105465761054661216f565b60209081029190910101527f2be257f691fda536174f9dd1b409a821fa2f76c21980ad9f02bb85955ef467e58561057e83600a61220e565b8151811061058e5761058e61216f565b60209081029190910101527f20c7c97931cf1e7cba344adba11b02356a3e015bc40455838187135f99b533a5856105c683600b61220e565b815181106105d6576105d661216f565b6020026020010181815250506105ec8285611336565b866105f884600c61220e565b815181106106085761060861216f565b602002602001018784600d61061d919061220e565b8151811061062d5761062d61216f565b6020908102919091010191909152527f253b146c3e383686d40f8ad599f2c8325671c19fb15ab91506fb09ce5258a4de8561066983600e61220e565b815181106106795761067961216f565b60209081029190910101527f04c2a98631f518ba36e49a6c8d8e8d4298e0c0ef2244a98606c9441e736f978f856106b183600f61220e565b815181106106c1576106c161216f565b60209081029190910101527f126e37f083bdee69b72546d7d564b302899418765d5861973d42b16292018135856106f983601061220e565b815181106107095761070961216f565b60209081029190910101527f0abd39eaeb0f622605274a4e1a0de5d64ae7f81dbef7bd27ce86b3a7cd771ba88561074183601161220e565b815181106107515761075161216f565b6020026020010181815250505f60205f60208851026020890160085afa5f511690508061079157604051631ff3747d60e21b815260040160405180910390fd5b505050505050505050505050565b5f5f6107aa836115e9565b915091505f6040516101008682377f2c0fdecd882466cdafaeb7781bda3b99349b600ee4f735935d331c29519cff296101008201527f1c41f205d474a6ce66f6a7fcbc5aab6ab5d09e208d243e970ceb4197f42b8d0c6101208201527f1d462b4ea92b2e188826b822ec55c117d42aa316f9e946ed528d7498e48393db6101408201527f0e6594af1adb0315a5c8434e611445059beca3c7a9c3cb984585ae55ecd7c0616101608201527f0ed787326eff44b81836cff1abf9c7b7b1289a7cfc55cacea7c44db860748fc46101808201527f101b1e52d0a903b80c63769e44e766b6204a3cebc9009c269dfb9f278551fb386101a08201527f0636886069d0f92b944ecdb3faba35fb71f3dfa70459ca6106a070fcb78937d56101c08201527f2f12fb45b7635d8fb4f8a62dec7d29d3a90b77e6b67b11dc7559e70f0a526ff66101e08201527f2be257f691fda536174f9dd1b409a821fa2f76c21980ad9f02bb85955ef467e56102008201527f20c7c97931cf1e7cba344adba11b02356a3e015bc40455838187135f99b533a561022082015283610240820152826102608201527f253b146c3e383686d40f8ad599f2c8325671c19fb15ab91506fb09ce5258a4de6102808201527f04c2a98631f518ba36e49a6c8d8e8d4298e0c0ef2244a98606c9441e736f978f6102a08201527f126e37f083bdee69b72546d7d564b302899418765d5861973d42b162920181356102c08201527f0abd39eaeb0f622605274a4e1a0de5d64ae7f81dbef7bd27ce86b3a7cd771ba86102e08201526020816103008360085afa905116905080610a1657604051631ff3747d60e21b815260040160405180910390fd5b5050505050565b610a25611f6e565b5f80610a3785825b6020020135611991565b90925090505f808080610a5260408a013560208b0135611a2f565b929650909450925090505f80610a698b6003610a2d565b915091505f5f610a788c6115e9565b8b8d5260208d018b905260408d0189905260608d018a905260808d0187905260a08d0188905260c08d0186905260e08d018590527f2c0fdecd882466cdafaeb7781bda3b99349b600ee4f735935d331c29519cff296101008e01527f1c41f205d474a6ce66f6a7fcbc5aab6ab5d09e208d243e970ceb4197f42b8d0c6101208e01527f1d462b4ea92b2e188826b822ec55c117d42aa316f9e946ed528d7498e48393db6101408e01527f0e6594af1adb0315a5c8434e611445059beca3c7a9c3cb984585ae55ecd7c0616101608e01527f0ed787326eff44b81836cff1abf9c7b7b1289a7cfc55cacea7c44db860748fc46101808e01527f101b1e52d0a903b80c63769e44e766b6204a3cebc9009c269dfb9f278551fb386101a08e01527f0636886069d0f92b944ecdb3faba35fb71f3dfa70459ca6106a070fcb78937d56101c08e01527f2f12fb45b7635d8fb4f8a62dec7d29d3a90b77e6b67b11dc7559e70f0a526ff66101e08e01527f2be257f691fda536174f9dd1b409a821fa2f76c21980ad9f02bb85955ef467e56102008e01527f20c7c97931cf1e7cba344adba11b02356a3e015bc40455838187135f99b533a56102208e01526102408d018290526102608d018190527f253b146c3e383686d40f8ad599f2c8325671c19fb15ab91506fb09ce5258a4de6102808e01527f04c2a98631f518ba36e49a6c8d8e8d4298e0c0ef2244a98606c9441e736f978f6102a08e01527f126e37f083bdee69b72546d7d564b302899418765d5861973d42b162920181356102c08e01527f0abd39eaeb0f622605274a4e1a0de5d64ae7f81dbef7bd27ce86b3a7cd771ba86102e08e015290925090505f610cec611f8d565b6020816103008f60085afa9150811580610d0857508051600114155b15610d2657604051631ff3747d60e21b815260040160405180910390fd5b505050505050505050505050505050565b5f5f5160206122935f395f51905f5283101580610d6157505f5160206122935f395f51905f528210155b15610d7f57604051631ff3747d60e21b815260040160405180910390fd5b82158015610d8b575081155b15610d9757505f610e1e565b5f610dd25f5160206122935f395f51905f5260035f5160206122935f395f51905f52875f5160206122935f395f51905f52898a090908611c03565b9050808303610de7575050600182901b610e1e565b610df081611c65565b8303610e03575050600182811b17610e1e565b604051631ff3747d60e21b815260040160405180910390fd5b505b92915050565b5f5f5f5160206122935f395f51905f5286101580610e4f57505f5160206122935f395f51905f528510155b80610e6757505f5160206122935f395f51905f528410155b80610e7f57505f5160206122935f395f51905f528310155b15610e9d57604051631ff3747d60e21b815260040160405180910390fd5b828486881717175f03610eb457505f9050806110f4565b5f80805f5160206122935f395f51905f52610edd60035f5160206122935f395f51905f5261227f565b5f5160206122935f395f51905f528a8c090990505f5f5160206122935f395f51905f528a5f5160206122935f395f51905f528c8d090990505f5f5160206122935f395f51905f528a5f5160206122935f395f51905f528c8d090990505f5160206122935f395f51905f52805f5160206122935f395f51905f528c860984087f2b149d40ceb8aaae81be18991be06ac3b5b4c5e559dbefa33267e6dc24a138e5089450610fcb5f5160206122935f395f51905f52805f5160206122935f395f51905f528e870984087f2fcd3ac2a640a154eb23960892a85a68f031ca0c8344b23a577dcf1052b9e77508611c65565b93505050505f5f6110185f5160206122935f395f51905f5280610ff057610ff061224c565b5f5160206122935f395f51905f528586095f5160206122935f395f51905f5287880908611c03565b90506110635f5160206122935f395f51905f527f183227397098d014dc2822db40c0ac2ecbc0b548b438e5469e10460b6c3e7ea45f5160206122935f395f51905f5284880809611c7d565b15915050611072838383611cc5565b9093509150868314801561108557508186145b156110ad5780611095575f611098565b60025b60ff1660028a901b175f1794508793506110f0565b6110b683611c65565b871480156110cb57506110c882611c65565b86145b15610e0357806110db575f6110de565b60025b60ff1660028a901b1760011794508793505b5050505b94509492505050565b5f5b6006811015611199575f84826006811061111b5761111b61216f565b602002013590505f5160206122b35f395f51905f52811061114f5760405163a54f8e2760e01b815260040160405180910390fd5b5f5160206122b35f395f51905f52808286098484600681106111735761117361216f565b60200201510883836006811061118b5761118b61216f565b6020020152506001016110ff565b50505050565b6111af85356020870135866112e2565b8484815181106111c1576111c161216f565b60200260200101858560016111d6919061220e565b815181106111e6576111e661216f565b60209081029190910101919091525260408501358361120684600261220e565b815181106112165761121661216f565b602090810291909101015260608501358361123284600361220e565b815181106112425761124261216f565b602090810291909101015260808501358361125e84600461220e565b8151811061126e5761126e61216f565b602090810291909101015260a08501358361128a84600561220e565b8151811061129a5761129a61216f565b60209081029190910101525f806112ba60c088013560e0890135886112e2565b845160208601519294509092506112d2918484611df8565b6020850152909252505050505050565b5f5f5f60405186815285602082015284604082015260408160608360075afa91508051935060208101519250508061132d57604051631ff3747d60e21b815260040160405180910390fd5b50935093915050565b5f5f5f5f6113857f19db6a7db91bc8c3957bf8f99032c36e105bad7182b617d7160181270f02f5577f15c96066bd10fd7ebdbee509ae25861844b91d87b264e62f21b9d165cd7dadb5886112e2565b90945092506113dc7f2f0a6f1d3e719314f7e569ab7b827611ae61ae403a3da3d6b17bef47b838b05c7f198ccfafae4fe617302bd58bb2b75000ee5a27fb392eafce5176a56b11a24a51875f5b60200201516112e2565b90925090506113ed84848484611df8565b909450925061143f7f21be3e7024a6cfb39c56a02f36b977017778fb2a26604dc07f1cfdcad2b1801d7f305ffc7569ceb88e75f5b112f420d4ece2daaf572b251a402ec601c566d87b5e8760016113d2565b909250905061145084848484611df8565b90945092506114a27f1472d5ac3cdc3631b240e72d81f762ba447fc5f966ed26511717415ae22218fe7f2971c894a4866520835d3a7ed545730a97008cba23b42379e0f70a78d388dbc38760026113d2565b90925090506114b384848484611df8565b90945092506115057f0cc3b84f6385431880bfef0642af4e6645d7db153a2cea2f4293319d17c1bbd77f09373e42bfa273760a46b4150cb3dcc5107aa9f84bb1e6a7b5e8578ade796ccb8760036113d2565b909250905061151684848484611df8565b90945092506115687f13d407fec5eb550fce26919cd5c3058c1baaf8afdb39eb6fa7ab3eff9e6cfb357f073c8e948261f63675a3e90de6bf5ce87b5bd13538b5de2373e1eeb7c4150d588760046113d2565b909250905061157984848484611df8565b90945092506115cb7f0a56ee42ce8c246334950bd08bd1f5c175ca7dba5c6cfe0cdfe3a066062e0c367f2b5aa949daaec9ad5a9c04218d2f473e2eaf57dec3095fc5f49a4e4235777bce8760056113d2565b90925090506115dc84848484611df8565b9097909650945050505050565b5f5f5f60019050604051604081015f7f19db6a7db91bc8c3957bf8f99032c36e105bad7182b617d7160181270f02f55783527f15c96066bd10fd7ebdbee509ae25861844b91d87b264e62f21b9d165cd7dadb560208401527f2f0a6f1d3e719314f7e569ab7b827611ae61ae403a3da3d6b17bef47b838b05c82527f198ccfafae4fe617302bd58bb2b75000ee5a27fb392eafce5176a56b11a24a516020830152863590508060408301525f5160206122b35f395f51905f5281108416935060408260608460075afa8416935060408360808560065afa841693507f21be3e7024a6cfb39c56a02f36b977017778fb2a26604dc07f1cfdcad2b1801d82527f305ffc7569ceb88e75f5b112f420d4ece2daaf572b251a402ec601c566d87b5e6020830152602087013590508060408301525f5160206122b35f395f51905f5281108416935060408260608460075afa8416935060408360808560065afa841693507f1472d5ac3cdc3631b240e72d81f762ba447fc5f966ed26511717415ae22218fe82527f2971c894a4866520835d3a7ed545730a97008cba23b42379e0f70a78d388dbc36020830152604087013590508060408301525f5160206122b35f395f51905f5281108416935060408260608460075afa8416935060408360808560065afa841693507f0cc3b84f6385431880bfef0642af4e6645d7db153a2cea2f4293319d17c1bbd782527f09373e42bfa273760a46b4150cb3dcc5107aa9f84bb1e6a7b5e8578ade796ccb6020830152606087013590508060408301525f5160206122b35f395f51905f5281108416935060408260608460075afa8416935060408360808560065afa841693507f13d407fec5eb550fce26919cd5c3058c1baaf8afdb39eb6fa7ab3eff9e6cfb3582527f073c8e948261f63675a3e90de6bf5ce87b5bd13538b5de2373e1eeb7c4150d586020830152608087013590508060408301525f5160206122b35f395f51905f5281108416935060408260608460075afa8416935060408360808560065afa7f0a56ee42ce8c246334950bd08bd1f5c175ca7dba5c6cfe0cdfe3a066062e0c3683527f2b5aa949daaec9ad5a9c04218d2f473e2eaf57dec3095fc5f49a4e4235777bce602084015260a088013560408085018290525f5160206122b35f395f51905f5290911091909516169390508160608160075afa831692505060408160808360065afa8151602090920151919450909250168061198b5760405163a54f8e2760e01b815260040160405180910390fd5b50915091565b5f5f825f036119a457505f928392509050565b600183811c9250808416145f5160206122935f395f51905f5283106119dc57604051631ff3747d60e21b815260040160405180910390fd5b611a165f5160206122935f395f51905f5260035f5160206122935f395f51905f52865f5160206122935f395f51905f528889090908611c03565b9150801561198b57611a2782611c65565b915050915091565b5f80808085158015611a3f575084155b15611a5457505f925082915081905080611bfa565b600286811c945085935060018088161490808816145f5160206122935f395f51905f5286101580611a9257505f5160206122935f395f51905f528510155b15611ab057604051631ff3747d60e21b815260040160405180910390fd5b5f5f5160206122935f395f51905f52611ad760035f5160206122935f395f51905f5261227f565b5f5160206122935f395f51905f52888a090990505f5f5160206122935f395f51905f52885f5160206122935f395f51905f528a8b090990505f5f5160206122935f395f51905f52885f5160206122935f395f51905f528a8b090990505f5160206122935f395f51905f52805f5160206122935f395f51905f528a860984087f2b149d40ceb8aaae81be18991be06ac3b5b4c5e559dbefa33267e6dc24a138e5089650611bc55f5160206122935f395f51905f52805f5160206122935f395f51905f528c870984087f2fcd3ac2a640a154eb23960892a85a68f031ca0c8344b23a577dcf1052b9e77508611c65565b9550611bd2878786611cc5565b90975095508415611bf457611be687611c65565b9650611bf186611c65565b95505b50505050505b92959194509250565b5f611c2e827f0c19139cb84c680a6e14116da060561765e05aa45a1c72a34f082305b61f3f52611e53565b9050815f5160206122935f395f51905f5282830914611c6057604051631ff3747d60e21b815260040160405180910390fd5b919050565b5f5160206122935f395f51905f529081900681030690565b5f5f611ca9837f0c19139cb84c680a6e14116da060561765e05aa45a1c72a34f082305b61f3f52611e53565b9050825f5160206122935f395f51905f52828309149392505050565b5f8080611cf45f5160206122935f395f51905f52808788095f5160206122935f395f51905f52898a0908611c03565b90508315611d0857611d0581611c65565b90505b611d515f5160206122935f395f51905f527f183227397098d014dc2822db40c0ac2ecbc0b548b438e5469e10460b6c3e7ea45f5160206122935f395f51905f52848a0809611c03565b92505f5160206122935f395f51905f52611d7b5f5160206122935f395f51905f5260028609611eb6565b860991505f5160206122935f395f51905f52611da65f5160206122935f395f51905f52848509611c65565b5f5160206122935f395f51905f528586090886141580611dda57505f5160206122935f395f51905f52808385096002098514155b1561132d57604051631ff3747d60e21b815260040160405180910390fd5b5f5f5f60405187815286602082015285604082015284606082015260408160808360065afa915080519350602081015192505080611e4957604051631ff3747d60e21b815260040160405180910390fd5b5094509492505050565b5f5f60405160208152602080820152602060408201528460608201528360808201525f5160206122935f395f51905f5260a082015260208160c08360055afa90519250905080610e1c57604051631ff3747d60e21b815260040160405180910390fd5b5f611ee1827f30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd45611e53565b90505f5160206122935f395f51905f52818309600114611c6057604051631ff3747d60e21b815260040160405180910390fd5b60405180608001604052806004906020820280368337509192915050565b6040518060c001604052806006906020820280368337509192915050565b60405180604001604052806002906020820280368337509192915050565b6040518061030001604052806018906020820280368337509192915050565b60405180602001604052806001906020820280368337509192915050565b806101008101831015610e1e575f5ffd5b5f6101008284031215611fcd575f5ffd5b611fd78383611fab565b9392505050565b6080810181835f5b6004811015612005578151835260209283019290910190600101611fe6565b50505092915050565b5f5f83601f84011261201e575f5ffd5b50813567ffffffffffffffff811115612035575f5ffd5b60208301915083602060c08302850101111561204f575f5ffd5b9250929050565b5f5f5f5f60408587031215612069575f5ffd5b843567ffffffffffffffff81111561207f575f5ffd5b8501601f8101871361208f575f5ffd5b803567ffffffffffffffff8111156120a5575f5ffd5b8760208260081b84010111156120b9575f5ffd5b60209182019550935085013567ffffffffffffffff8111156120d9575f5ffd5b6120e58782880161200e565b95989497509550505050565b8060c08101831015610e1e575f5ffd5b5f5f6101c08385031215612113575f5ffd5b61211d8484611fab565b915061212d8461010085016120f1565b90509250929050565b5f5f6101408385031215612148575f5ffd5b6080830184811115612158575f5ffd5b83925061216585826120f1565b9150509250929050565b634e487b7160e01b5f52603260045260245ffd5b604080825281018490525f8560608301825b878110156121b757610100838337610100928301929190910190600101612195565b50838103602080860191909152858252019050845f5b858110156121ed5760c082843760c09283019291909101906001016121cd565b5090979650505050505050565b634e487b7160e01b5f52601160045260245ffd5b80820180821115610e1e57610e1e6121fa565b8082028115828204841417610e1e57610e1e6121fa565b634e487b7160e01b5f52604160045260245ffd5b634e487b7160e01b5f52601260045260245ffd5b5f8261227a57634e487b7160e01b5f52601260045260245ffd5b500690565b81810381811115610e1e57610e1e6121fa56fe30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd4730644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001a26469706673582212208334d7d9fc658a5ba7fe1f2e96de5fa5cf52b4f9d58b28c98c14c8e0e8118a1d64736f6c634300081e0033",
 }
 
 // VerifierABI is the input ABI used to generate the binding from.
@@ -290,3 +290,32 @@ func (_Verifier *VerifierSession) VerifyProof(proof [8]*big.Int, input [6]*big.I
 func (_Verifier *VerifierCallerSession) VerifyProof(proof [8]*big.Int, input [6]*big.Int) error {
 	return _Verifier.Contract.VerifyProof(&_Verifier.CallOpts, proof, input)
 }
+
+// VerifyProofs is a free data retrieval call binding the contract method 0x7de76681.
+//
+// Solidity: function verifyProofs(uint256[8][] proofs, uint256[6][] input) view returns()
+func (_Verifier *VerifierCaller) VerifyProofs(opts *bind.CallOpts, proofs [][8]*big.Int, input [][6]*big.Int) error {
+	var out []interface{}
+	err := _Verifier.contract.Call(opts, &out, "verifyProofs", proofs, input)
+
+	if err != nil {
+		return err
+	}
+
+	return err
+
+}
+
+// VerifyProofs is a free data retrieval call binding the contract method 0x7de76681.
+//
+// Solidity: function verifyProofs(uint256[8][] proofs, uint256[6][] input) view returns()
+func (_Verifier *VerifierSession) VerifyProofs(proofs [][8]*big.Int, input [][6]*big.Int) error {
+	return _Verifier.Contract.VerifyProofs(&_Verifier.CallOpts, proofs, input)
+}
+
+// VerifyProofs is a free data retrieval call binding the contract method 0x7de76681.
+//
+// Solidity: function verifyProofs(uint256[8][] proofs, uint256[6][] input) view returns()
+func (_Verifier *VerifierCallerSession) VerifyProofs(proofs [][8]*big.Int, input [][6]*big.Int) error {
+	return _Verifier.Contract.VerifyProofs(&_Verifier.CallOpts, proofs, input)
+}
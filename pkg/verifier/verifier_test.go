@@ -0,0 +1,114 @@
+package verifier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	edwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	gnarkEddsa "github.com/consensys/gnark/std/signature/eddsa"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+
+	zkCrypto "zkrollup/pkg/crypto"
+)
+
+// The keys the generated Verifier contract was exported from
+const keysDir = "../../cmd/benchmark/generate/"
+
+// transferProof proves a signed transfer of amount with the transaction circuit and returns
+// it with its public inputs as the Verifier contract takes them
+func transferProof(t *testing.T, prover *zkCrypto.Prover, from, to *eddsa.PrivateKey, amount int64) ([8]*big.Int, [6]*big.Int) {
+	balance, nonce := big.NewInt(1000), big.NewInt(amount)
+
+	// The circuit signs the recipient key, amount, balance and nonce hashed as field elements
+	h := mimc.NewMiMC()
+	for _, v := range []*big.Int{to.PublicKey.A.X.BigInt(new(big.Int)), to.PublicKey.A.Y.BigInt(new(big.Int)), big.NewInt(amount), balance, nonce} {
+		var e fr.Element
+		e.SetBigInt(v)
+		b := e.Bytes()
+		h.Write(b[:])
+	}
+	sig, err := from.Sign(h.Sum(nil), mimc.NewMiMC())
+	require.NoError(t, err)
+
+	var fromKey, toKey gnarkEddsa.PublicKey
+	fromKey.Assign(edwards.BN254, from.PublicKey.Bytes())
+	toKey.Assign(edwards.BN254, to.PublicKey.Bytes())
+	var signature gnarkEddsa.Signature
+	signature.Assign(edwards.BN254, sig)
+
+	assignment, err := prover.CreateWitness(fromKey, toKey, big.NewInt(amount), nonce, signature, balance)
+	require.NoError(t, err)
+	proof, public, err := prover.GenerateProof(assignment)
+	require.NoError(t, err)
+
+	var inputs [6]*big.Int
+	values := public.Vector().(fr.Vector)
+	require.Len(t, values, len(inputs))
+	for i := range values {
+		inputs[i] = values[i].BigInt(new(big.Int))
+	}
+	return ProofCalldata(proof.(*groth16bn254.Proof)), inputs
+}
+
+func TestVerifierContractVerifyProofs(t *testing.T) {
+	pk := groth16.NewProvingKey(ecc.BN254)
+	f, err := os.Open(keysDir + "zkrollup.pk")
+	require.NoError(t, err)
+	_, err = pk.ReadFrom(f)
+	f.Close()
+	require.NoError(t, err)
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	f, err = os.Open(keysDir + "zkrollup.vk")
+	require.NoError(t, err)
+	_, err = vk.ReadFrom(f)
+	f.Close()
+	require.NoError(t, err)
+
+	prover, err := zkCrypto.NewProverWithKeys(pk, vk)
+	require.NoError(t, err)
+
+	from, err := eddsa.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	to, err := eddsa.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var proofs [][8]*big.Int
+	var inputs [][6]*big.Int
+	for amount := int64(1); amount <= 3; amount++ {
+		proof, input := transferProof(t, prover, from, to, amount)
+		proofs = append(proofs, proof)
+		inputs = append(inputs, input)
+	}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	require.NoError(t, err)
+	backend := simulated.NewBackend(types.GenesisAlloc{auth.From: {Balance: big.NewInt(1e18)}})
+	defer backend.Close()
+
+	_, _, contract, err := DeployVerifier(auth, backend.Client())
+	require.NoError(t, err)
+	backend.Commit()
+
+	opts := &bind.CallOpts{}
+	require.NoError(t, contract.VerifyProofs(opts, proofs, inputs))
+	require.NoError(t, contract.VerifyProofs(opts, proofs[1:2], inputs[1:2]))
+	require.NoError(t, contract.VerifyProof(opts, proofs[0], inputs[0]))
+
+	// Swapping the inputs of two proofs breaks the batch
+	swapped := [][6]*big.Int{inputs[1], inputs[0], inputs[2]}
+	require.Error(t, contract.VerifyProofs(opts, proofs, swapped))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"zkrollup/pkg/core"
+	"zkrollup/pkg/state"
+)
+
+// runStateCommand handles "state export" and "state import", which dump the node's state
+// to a file and restore it. The node must be stopped, the database is opened directly.
+func runStateCommand(args []string) {
+	if len(args) < 1 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprintln(os.Stderr, "Usage: zkrollup state export|import [-config file] [-backend name] [-db path] [-file path]")
+		os.Exit(2)
+	}
+	export := args[0] == "export"
+
+	fs := flag.NewFlagSet("state "+args[0], flag.ExitOnError)
+	configFile := fs.String("config", "", "YAML config file naming the state backend and database")
+	backend := fs.String("backend", "", "State backend, overrides the config")
+	dbPath := fs.String("db", "", "State database path, overrides the config")
+	file := fs.String("file", "-", "Export file, - for stdout or stdin")
+	fs.Parse(args[1:])
+
+	config := core.DefaultConfig()
+	if *configFile != "" {
+		if err := config.LoadFile(*configFile); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+	if env := os.Getenv("STATE_BACKEND"); env != "" {
+		config.StateBackend = env
+	}
+	if env := os.Getenv("STATE_DB_PATH"); env != "" {
+		config.StateDBPath = env
+	}
+	if *backend != "" {
+		config.StateBackend = *backend
+	}
+	if *dbPath != "" {
+		config.StateDBPath = *dbPath
+	}
+	if config.StateBackend == state.BackendMemory {
+		log.Fatalf("The memory backend keeps no state to %s, set -backend and -db", args[0])
+	}
+
+	st, err := state.OpenState(config.StateBackend, config.StateDBPath, export)
+	if err != nil {
+		log.Fatalf("Failed to open state: %v", err)
+	}
+	defer st.Close()
+
+	if export {
+		var w io.Writer = os.Stdout
+		if *file != "-" {
+			f, err := os.Create(*file)
+			if err != nil {
+				log.Fatalf("Failed to create export file: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := st.Export(w); err != nil {
+			log.Fatalf("Failed to export state: %v", err)
+		}
+		log.Printf("Exported state at batch %d, root %x", st.GetBatchNumber(), st.GetStateRoot())
+		return
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("Failed to open export file: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := st.Import(r); err != nil {
+		log.Fatalf("Failed to import state: %v", err)
+	}
+	log.Printf("Imported state at batch %d, root %x", st.GetBatchNumber(), st.GetStateRoot())
+}